@@ -176,6 +176,10 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 		DefaultStackSize:   config.StackSize(),
 		NeedsStackObjects:  config.NeedsStackObjects(),
 		Debug:              !config.Options.SkipDWARF, // emit DWARF except when -internal-nodwarf is passed
+		NoTypeStrings:      config.Options.NoTypeStrings,
+		PanicTrace:         config.PanicStrategy() == "trace",
+		Race:               config.Options.Race,
+		CoverMode:          config.Options.TestConfig.CoverMode,
 	}
 
 	// Load the target machine, which is the LLVM object that contains all
@@ -203,6 +207,18 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 		// If there is no module root, just the regular root.
 		result.ModuleRoot = lprogram.MainPkg().Root
 	}
+	if compilerConfig.CoverMode != "" {
+		// Only the package under test is instrumented, like with go test.
+		compilerConfig.CoverPackage = strings.TrimSuffix(lprogram.MainPkg().ImportPath, ".test")
+	}
+	if pkg, ok := lprogram.Packages["tinygo/buildinfo"]; ok {
+		// The constants of this package are generated for the target.
+		src, err := buildInfoSource(config)
+		if err != nil {
+			return result, err
+		}
+		pkg.AddGeneratedFile("zbuildinfo.go", src)
+	}
 	err = lprogram.Parse()
 	if err != nil {
 		return result, err
@@ -212,6 +228,14 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 	// program so it's pretty fast and doesn't need to be parallelized.
 	program := lprogram.LoadSSA()
 
+	if compilerConfig.CoverMode != "" {
+		// The markers for code coverage are inserted in the AST, so this
+		// must be done before any SSA is built.
+		if pkg := lprogram.Packages[compilerConfig.CoverPackage]; pkg != nil {
+			compiler.AddCoverMarkers(program, pkg)
+		}
+	}
+
 	// Add jobs to compile each package.
 	// Packages that have a cache hit will not be compiled again.
 	var packageJobs []*compileJob
@@ -230,6 +254,16 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 		}
 		config.Options.GlobalValues["runtime"]["buildVersion"] = version
 	}
+	if compilerConfig.CoverMode != "" && config.Options.GlobalValues["runtime"]["coverMode"] == "" {
+		// Let the testing package know which coverage mode is used.
+		if config.Options.GlobalValues == nil {
+			config.Options.GlobalValues = make(map[string]map[string]string)
+		}
+		if config.Options.GlobalValues["runtime"] == nil {
+			config.Options.GlobalValues["runtime"] = make(map[string]string)
+		}
+		config.Options.GlobalValues["runtime"]["coverMode"] = compilerConfig.CoverMode
+	}
 
 	var embedFileObjects []*compileJob
 	for _, pkg := range lprogram.Sorted() {
@@ -357,6 +391,7 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 				mod, errs := compiler.CompilePackage(pkg.ImportPath, pkg, program.Package(pkg.Pkg), machine, compilerConfig, config.DumpSSA())
 				defer mod.Context().Dispose()
 				defer mod.Dispose()
+				errs = printWarnings(errs)
 				if errs != nil {
 					return newMultiError(errs)
 				}
@@ -566,6 +601,27 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 				return err
 			}
 
+			// Check for //go:linkname directives and Go assembly functions
+			// that refer to functions or globals that don't exist, now that
+			// it's known which of them are still used.
+			isUndefined := func(name string) bool {
+				if fn := mod.NamedFunction(name); !fn.IsNil() {
+					return fn.IsDeclaration()
+				}
+				if global := mod.NamedGlobal(name); !global.IsNil() {
+					return global.IsDeclaration()
+				}
+				return false
+			}
+			err = lprogram.CheckLinknames(isUndefined)
+			if err != nil {
+				return err
+			}
+			err = lprogram.CheckAssembly(isUndefined)
+			if err != nil {
+				return err
+			}
+
 			// Make sure stack sizes are loaded from a separate section so they can be
 			// modified after linking.
 			if config.AutomaticStackSize() {
@@ -631,6 +687,20 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 	result.Binary = result.Executable // final file
 	ldflags := append(config.LDFlags(), "-o", result.Executable)
 
+	// Move the FLASH_TEXT and RAM memory regions with -code-offset and
+	// -ram-offset by linking with a modified copy of the linker script.
+	if config.Options.CodeOffset != 0 || config.Options.RAMOffset != 0 {
+		script, err := writeMemoryOffsetLinkerScript(config, tmpdir)
+		if err != nil {
+			return result, err
+		}
+		for i := 0; i+1 < len(ldflags); i++ {
+			if ldflags[i] == "-T" && ldflags[i+1] == config.Target.LinkerScript {
+				ldflags[i+1] = script
+			}
+		}
+	}
+
 	// Add compiler-rt dependency if needed. Usually this is a simple load from
 	// a cache.
 	if config.Target.RTLib == "compiler-rt" {
@@ -663,12 +733,31 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 	// bitcode files together.
 	for _, pkg := range lprogram.Sorted() {
 		pkg := pkg
+		cflags := pkg.CFlags
+		if pkg.CGoExport != "" && len(pkg.CFiles) != 0 {
+			// Make _cgo_export.h available to the C files of this package,
+			// so that they can call the functions exported with //export.
+			// The directory is named after the contents of the header to
+			// keep the flags (and therefore the cache key of the C files)
+			// the same across builds.
+			sum := sha256.Sum256([]byte(pkg.CGoExport))
+			dir := filepath.Join(cacheDir, "cgo-export-"+hex.EncodeToString(sum[:16]))
+			err := os.MkdirAll(dir, 0777)
+			if err != nil {
+				return BuildResult{}, err
+			}
+			err = os.WriteFile(filepath.Join(dir, "_cgo_export.h"), []byte(pkg.CGoExport), 0666)
+			if err != nil {
+				return BuildResult{}, err
+			}
+			cflags = append(append([]string(nil), cflags...), "-I"+dir)
+		}
 		for _, filename := range pkg.CFiles {
 			abspath := filepath.Join(pkg.Dir, filename)
 			job := &compileJob{
 				description: "compile CGo file " + abspath,
 				run: func(job *compileJob) error {
-					result, err := compileAndCacheCFile(abspath, tmpdir, pkg.CFlags, config.Options.PrintCommands)
+					result, err := compileAndCacheCFile(abspath, tmpdir, cflags, config.Options.PrintCommands)
 					job.result = result
 					return err
 				},
@@ -715,6 +804,17 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 		}
 	}
 
+	// Write a linker map with -map.
+	var mapHasRelocs bool
+	if config.Options.MapFile != "" {
+		flags, keepsRelocs, err := linkerMapFlags(config, config.Options.MapFile)
+		if err != nil {
+			return result, err
+		}
+		ldflags = append(ldflags, flags...)
+		mapHasRelocs = keepsRelocs
+	}
+
 	// Create a linker job, which links all object files together and does some
 	// extra stuff that can only be done after linking.
 	linkJob := &compileJob{
@@ -763,6 +863,19 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 			if err != nil {
 				return &commandError{"failed to link", result.Executable, err}
 			}
+			if len(config.Options.ExtFlashPackages) != 0 {
+				err = checkExternalFlashPlacement(result.Executable, config.Options.Target)
+				if err != nil {
+					return err
+				}
+			}
+			if mapHasRelocs {
+				// Add the symbol references to the linker map.
+				err = appendSymbolReferences(result.Executable, config.Options.MapFile)
+				if err != nil {
+					return err
+				}
+			}
 
 			var calculatedStacks []string
 			var stackSizes map[string]functionStackSize
@@ -881,6 +994,13 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 
 	// Get an Intel .hex file or .bin file from the .elf file.
 	outputBinaryFormat := config.BinaryFormat(outext)
+	if config.Options.MCUbootKey != "" {
+		switch outputBinaryFormat {
+		case "hex", "bin", "uf2":
+		default:
+			return result, fmt.Errorf("cannot sign %s file for MCUboot, use a .bin, .hex or .uf2 output file instead", outputBinaryFormat)
+		}
+	}
 	switch outputBinaryFormat {
 	case "elf":
 		// do nothing, file is already in ELF format
@@ -888,14 +1008,14 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 		// Extract raw binary, either encoding it as a hex file or as a raw
 		// firmware file.
 		result.Binary = filepath.Join(tmpdir, "main"+outext)
-		err := objcopy(result.Executable, result.Binary, outputBinaryFormat)
+		err := objcopy(config, result.Executable, result.Binary, outputBinaryFormat)
 		if err != nil {
 			return result, err
 		}
 	case "uf2":
 		// Get UF2 from the .elf file.
 		result.Binary = filepath.Join(tmpdir, "main"+outext)
-		err := convertELFFileToUF2File(result.Executable, result.Binary, config.Target.UF2FamilyID)
+		err := convertELFFileToUF2File(config, result.Executable, result.Binary)
 		if err != nil {
 			return result, err
 		}
@@ -903,7 +1023,7 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 		// Special format for the ESP family of chips (parsed by the ROM
 		// bootloader).
 		result.Binary = filepath.Join(tmpdir, "main"+outext)
-		err := makeESPFirmareImage(result.Executable, result.Binary, outputBinaryFormat)
+		err := makeESPFirmareImage(config.Options, result.Executable, result.Binary, outputBinaryFormat)
 		if err != nil {
 			return result, err
 		}
@@ -1031,7 +1151,14 @@ func createEmbedObjectFile(data, hexSum, sourceFile, sourceDir, tmpdir string, c
 // needed to convert a program to its final form. Some transformations are not
 // optional and must be run as the compiler expects them to run.
 func optimizeProgram(mod llvm.Module, config *compileopts.Config) error {
-	err := interp.Run(mod, config.Options.InterpTimeout, config.DumpSSA())
+	// Fill in the //go:registry registries before package initializers are
+	// run, so that they can be used there.
+	err := transform.LowerRegistries(mod)
+	if err != nil {
+		return err
+	}
+
+	err = interp.Run(mod, config.Options.InterpTimeout, config.DumpSSA())
 	if err != nil {
 		return err
 	}
@@ -1072,6 +1199,11 @@ func optimizeProgram(mod llvm.Module, config *compileopts.Config) error {
 	if len(errs) > 0 {
 		return newMultiError(errs)
 	}
+
+	// Move constant data of the -extflash packages to external flash.
+	if len(config.Options.ExtFlashPackages) != 0 {
+		transform.PlaceExternalFlashData(mod, config.Options.ExtFlashPackages)
+	}
 	if err := llvm.VerifyModule(mod, llvm.PrintMessageAction); err != nil {
 		return errors.New("verification failure after LLVM optimization passes")
 	}
@@ -0,0 +1,181 @@
+package builder
+
+// This file generates the constants of the tinygo/buildinfo package, which
+// describe the target the program is compiled for.
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tinygo-org/tinygo/compileopts"
+	"github.com/tinygo-org/tinygo/goenv"
+)
+
+// Matches a symbol assignment in a linker script, like this one:
+//
+//	__flash_size = 0x8000;
+var linkerScriptSymbolRegexp = regexp.MustCompile(`(?m)^\s*([A-Za-z_.][A-Za-z0-9_.]*)\s*=\s*([^;\n]+);`)
+
+// buildInfoSource returns the source of the Go file that is added to the
+// tinygo/buildinfo package.
+func buildInfoSource(config *compileopts.Config) ([]byte, error) {
+	target := config.Options.Target
+	if target == "" {
+		target = config.GOOS() + "/" + config.GOARCH()
+	} else if strings.HasSuffix(target, ".json") {
+		// Custom target file.
+		target = strings.TrimSuffix(filepath.Base(target), ".json")
+	}
+	flashSize, ramSize, err := targetMemorySizes(config)
+	if err != nil {
+		return nil, err
+	}
+	src := "// Code generated by tinygo. DO NOT EDIT.\n\n"
+	src += "package buildinfo\n\n"
+	src += "const (\n"
+	src += fmt.Sprintf("\tTarget    = %q\n", target)
+	src += fmt.Sprintf("\tCPU       = %q\n", config.CPU())
+	src += fmt.Sprintf("\tGC        = %q\n", config.GC())
+	src += fmt.Sprintf("\tScheduler = %q\n", config.Scheduler())
+	src += fmt.Sprintf("\tFlashSize = %d\n", flashSize)
+	src += fmt.Sprintf("\tRAMSize   = %d\n", ramSize)
+	src += ")\n"
+	return []byte(src), nil
+}
+
+// targetMemorySizes returns the size of the FLASH_TEXT and RAM memory regions
+// in the target linker script, minus -code-offset and -ram-offset. A size is 0
+// if it isn't known.
+func targetMemorySizes(config *compileopts.Config) (flashSize, ramSize uint64, err error) {
+	if config.Target.LinkerScript == "" {
+		return 0, 0, nil
+	}
+	root := goenv.Get("TINYGOROOT")
+	readFile := func(path string) (string, error) {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) && !filepath.IsAbs(path) {
+			data, err = os.ReadFile(filepath.Join(root, path))
+		}
+		return string(data), err
+	}
+	regions, symbols, err := linkerScriptMemory(readFile, config.Target.LinkerScript)
+	if err != nil {
+		return 0, 0, err
+	}
+	// Symbols defined on the command line, like --defsym=__flash_size=2048K.
+	for _, flag := range config.LDFlags() {
+		if def := strings.TrimPrefix(flag, "--defsym="); def != flag {
+			if name, value, ok := strings.Cut(def, "="); ok {
+				symbols[name] = value
+			}
+		}
+	}
+	size := func(name string, offset uint64) uint64 {
+		length, ok := evalLinkerExpr(regions[name], symbols, 0)
+		if !ok || length < offset {
+			return 0
+		}
+		return length - offset
+	}
+	return size("FLASH_TEXT", config.Options.CodeOffset), size("RAM", config.Options.RAMOffset), nil
+}
+
+// linkerScriptMemory returns the length expressions of the FLASH_TEXT and RAM
+// memory regions in the given linker script and the symbols it assigns,
+// including those in included linker scripts.
+func linkerScriptMemory(readFile func(string) (string, error), path string) (regions, symbols map[string]string, err error) {
+	regions = make(map[string]string)
+	_, err = offsetMemoryRegionsInFile(readFile, path, nil, regions)
+	if err != nil {
+		return nil, nil, err
+	}
+	symbols = make(map[string]string)
+	err = linkerScriptSymbols(readFile, path, symbols)
+	if err != nil {
+		return nil, nil, err
+	}
+	return regions, symbols, nil
+}
+
+// linkerScriptSymbols adds the symbols assigned in the given linker script and
+// the linker scripts it includes to the symbols map.
+func linkerScriptSymbols(readFile func(string) (string, error), path string, symbols map[string]string) error {
+	script, err := readFile(path)
+	if err != nil {
+		return err
+	}
+	for _, m := range linkerScriptSymbolRegexp.FindAllStringSubmatch(script, -1) {
+		symbols[m[1]] = strings.TrimSpace(m[2])
+	}
+	for _, m := range linkerScriptIncludeRegexp.FindAllStringSubmatch(script, -1) {
+		err := linkerScriptSymbols(readFile, m[1], symbols)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// evalLinkerExpr evaluates a simple linker script expression: a sum or
+// difference of numbers (optionally with a K or M suffix) and symbols. It
+// returns false if the expression can't be evaluated.
+func evalLinkerExpr(expr string, symbols map[string]string, depth int) (uint64, bool) {
+	if depth > 10 {
+		// Probably a symbol that refers to itself.
+		return 0, false
+	}
+	expr = strings.TrimSpace(expr)
+	for strings.HasPrefix(expr, "(") && strings.HasSuffix(expr, ")") && !strings.Contains(expr[1:], "(") {
+		expr = strings.TrimSpace(expr[1 : len(expr)-1])
+	}
+	if expr == "" {
+		return 0, false
+	}
+
+	// Split the expression at the last + or - operator, as they are
+	// left-associative.
+	if i := strings.LastIndexAny(expr, "+-"); i > 0 {
+		if strings.Count(expr[:i], "(") != strings.Count(expr[:i], ")") {
+			// The operator is inside parentheses.
+			return 0, false
+		}
+		left, ok := evalLinkerExpr(expr[:i], symbols, depth)
+		if !ok {
+			return 0, false
+		}
+		right, ok := evalLinkerExpr(expr[i+1:], symbols, depth)
+		if !ok {
+			return 0, false
+		}
+		if expr[i] == '+' {
+			return left + right, true
+		}
+		if right > left {
+			return 0, false
+		}
+		return left - right, true
+	}
+
+	if value, ok := symbols[expr]; ok {
+		return evalLinkerExpr(value, symbols, depth+1)
+	}
+	multiplier := uint64(1)
+	switch expr[len(expr)-1] {
+	case 'K', 'k':
+		multiplier = 1024
+	case 'M', 'm':
+		multiplier = 1024 * 1024
+	}
+	if multiplier != 1 {
+		expr = expr[:len(expr)-1]
+	}
+	n, err := strconv.ParseUint(expr, 0, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n * multiplier, true
+}
@@ -0,0 +1,72 @@
+package builder
+
+import (
+	"os"
+	"testing"
+)
+
+func TestLinkerScriptMemory(t *testing.T) {
+	files := map[string]string{
+		"chip.ld": `
+__flash_size = 0x8000;
+__ram_size   = 0x800;
+
+INCLUDE "targets/avr.ld"
+`,
+		"targets/avr.ld": `
+MEMORY
+{
+    FLASH_TEXT (rw) : ORIGIN = 0,                      LENGTH = __flash_size - _bootloader_size
+    RAM (xrw)       : ORIGIN = 0x800000 + __ram_start, LENGTH = __ram_size
+}
+`,
+	}
+	readFile := func(path string) (string, error) {
+		if data, ok := files[path]; ok {
+			return data, nil
+		}
+		return "", os.ErrNotExist
+	}
+
+	regions, symbols, err := linkerScriptMemory(readFile, "chip.ld")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if ram, ok := evalLinkerExpr(regions["RAM"], symbols, 0); !ok || ram != 0x800 {
+		t.Errorf("unexpected RAM size: %d (ok=%v)", ram, ok)
+	}
+	if _, ok := evalLinkerExpr(regions["FLASH_TEXT"], symbols, 0); ok {
+		t.Errorf("expected FLASH_TEXT size to be unknown without _bootloader_size")
+	}
+	symbols["_bootloader_size"] = "512"
+	if flash, ok := evalLinkerExpr(regions["FLASH_TEXT"], symbols, 0); !ok || flash != 0x8000-512 {
+		t.Errorf("unexpected FLASH_TEXT size: %d (ok=%v)", flash, ok)
+	}
+}
+
+func TestEvalLinkerExpr(t *testing.T) {
+	symbols := map[string]string{
+		"__flash_size": "2048K",
+		"loop":         "loop",
+	}
+	for _, tc := range []struct {
+		expr  string
+		value uint64
+		ok    bool
+	}{
+		{"256K", 256 * 1024, true},
+		{"256k", 256 * 1024, true},
+		{"1M - 0x4000", 1024*1024 - 0x4000, true},
+		{"(1M - 0x4000) - 0x1000", 1024*1024 - 0x5000, true},
+		{"__flash_size - 256", 2048*1024 - 256, true},
+		{"0x100 - 0x200", 0, false},
+		{"__ram_size", 0, false},
+		{"loop", 0, false},
+		{"", 0, false},
+	} {
+		value, ok := evalLinkerExpr(tc.expr, symbols, 0)
+		if value != tc.value || ok != tc.ok {
+			t.Errorf("evalLinkerExpr(%q) = %d, %v; expected %d, %v", tc.expr, value, ok, tc.value, tc.ok)
+		}
+	}
+}
@@ -41,11 +41,30 @@ func NewConfig(options *compileopts.Options) (*compileopts.Config, error) {
 
 	clangHeaderPath := getClangHeaderPath(goenv.Get("TINYGOROOT"))
 
-	return &compileopts.Config{
+	config := &compileopts.Config{
 		Options:        options,
 		Target:         spec,
 		GoMinorVersion: minor,
 		ClangHeaders:   clangHeaderPath,
 		TestConfig:     options.TestConfig,
-	}, nil
+	}
+
+	if options.Race {
+		// The race detector keeps its state in the heap of a hosted program
+		// and tracks goroutines of the tasks scheduler.
+		hosted := spec.GOOS == "linux" || spec.GOOS == "darwin"
+		for _, tag := range spec.BuildTags {
+			if tag == "baremetal" {
+				hosted = false
+			}
+		}
+		if !hosted || spec.GOARCH == "wasm" {
+			return nil, fmt.Errorf("-race is only supported on linux and darwin, not on target %s", spec.Triple)
+		}
+		if config.Scheduler() == "asyncify" {
+			return nil, errors.New("-race is not supported with -scheduler=asyncify")
+		}
+	}
+
+	return config, nil
 }
@@ -1,5 +1,11 @@
 package builder
 
+import (
+	"fmt"
+	"go/types"
+	"os"
+)
+
 // MultiError is a list of multiple errors (actually: diagnostics) returned
 // during LLVM IR generation.
 type MultiError struct {
@@ -26,6 +32,20 @@ func newMultiError(errs []error) error {
 	}
 }
 
+// printWarnings prints the soft errors in errs to stderr as warnings and
+// returns the remaining errors, or nil if there are none.
+func printWarnings(errs []error) []error {
+	var remaining []error
+	for _, err := range errs {
+		if err, ok := err.(types.Error); ok && err.Soft {
+			fmt.Fprintln(os.Stderr, err.Fset.Position(err.Pos).String()+": warning: "+err.Msg)
+			continue
+		}
+		remaining = append(remaining, err)
+	}
+	return remaining
+}
+
 // commandError is an error type to wrap os/exec.Command errors. This provides
 // some more information regarding what went wrong while running a command.
 type commandError struct {
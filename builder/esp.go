@@ -9,13 +9,21 @@ package builder
 
 import (
 	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
 	"crypto/sha256"
 	"debug/elf"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"hash/crc32"
+	"math/big"
 	"os"
 	"sort"
 	"strings"
+
+	"github.com/tinygo-org/tinygo/compileopts"
 )
 
 type espImageSegment struct {
@@ -31,7 +39,26 @@ type espImageSegment struct {
 // https://github.com/espressif/esptool/wiki/Firmware-Image-Format
 // https://github.com/espressif/esp-idf/blob/8fbb63c2a701c22ccf4ce249f43aded73e134a34/components/bootloader_support/include/esp_image_format.h#L58
 // https://github.com/espressif/esptool/blob/master/esptool.py
-func makeESPFirmareImage(infile, outfile, format string) error {
+//
+// If a secure boot key is set in the options, the image is signed for secure
+// boot v2 (see signESPImage).
+func makeESPFirmareImage(options *compileopts.Options, infile, outfile, format string) error {
+	var secureBootKey *rsa.PrivateKey
+	if options.ESPSecureBootKey != "" {
+		if format == "esp8266" {
+			return errors.New("secure boot is not supported on the esp8266")
+		}
+		key, err := readPEMPrivateKey(options.ESPSecureBootKey)
+		if err != nil {
+			return err
+		}
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok || rsaKey.N.BitLen() != 3072 {
+			return fmt.Errorf("%s: secure boot v2 requires an RSA-3072 key", options.ESPSecureBootKey)
+		}
+		secureBootKey = rsaKey
+	}
+
 	inf, err := elf.Open(infile)
 	if err != nil {
 		return err
@@ -94,6 +121,13 @@ func makeESPFirmareImage(infile, outfile, format string) error {
 		// application in the bootloader location.
 		outf.Write(make([]byte, 4096))
 	}
+	imageStart := outf.Len()
+
+	// Secure boot v2 on the ESP32 is only supported from chip revision 3.
+	minChipRev := uint8(0)
+	if secureBootKey != nil && chip == "esp32" {
+		minChipRev = 3
+	}
 
 	// Chip IDs. Source:
 	// https://github.com/espressif/esp-idf/blob/v4.3/components/bootloader_support/include/esp_app_format.h#L22
@@ -130,6 +164,7 @@ func makeESPFirmareImage(infile, outfile, format string) error {
 			entry_addr:     uint32(inf.Entry),
 			wp_pin:         0xEE, // disable WP pin
 			chip_id:        chip_id,
+			min_chip_rev:   minChipRev,
 			hash_appended:  true, // add a SHA256 hash
 		})
 	case "esp8266":
@@ -178,6 +213,14 @@ func makeESPFirmareImage(infile, outfile, format string) error {
 		outf.Write(hash[:])
 	}
 
+	if secureBootKey != nil {
+		signature, err := signESPImage(secureBootKey, outf.Bytes()[imageStart:])
+		if err != nil {
+			return err
+		}
+		outf.Write(signature)
+	}
+
 	// QEMU (or more precisely, qemu-system-xtensa from Espressif) expects the
 	// image to be a certain size.
 	if makeImage {
@@ -191,3 +234,55 @@ func makeESPFirmareImage(infile, outfile, format string) error {
 	// Write the image to the output file.
 	return os.WriteFile(outfile, outf.Bytes(), 0666)
 }
+
+// signESPImage returns the padding and the signature sector to append to the
+// given image for secure boot v2 with an RSA-3072 key. This is equivalent to
+// `espsecure.py sign_data --version 2`. The format is documented here:
+// https://docs.espressif.com/projects/esp-idf/en/v5.0/esp32/security/secure-boot-v2.html#signature-block-format
+func signESPImage(key *rsa.PrivateKey, image []byte) ([]byte, error) {
+	const sectorSize = 4096
+
+	// The signature sector starts at the next sector boundary, the image is
+	// padded with 0xff (like erased flash).
+	padding := bytes.Repeat([]byte{0xff}, (sectorSize-len(image)%sectorSize)%sectorSize)
+	digest := sha256.Sum256(append(image[:len(image):len(image)], padding...))
+
+	signature, err := rsa.SignPSS(rand.Reader, key, crypto.SHA256, digest[:], &rsa.PSSOptions{SaltLength: 32})
+	if err != nil {
+		return nil, fmt.Errorf("could not sign image: %w", err)
+	}
+
+	// Values the ROM needs to verify the signature using Montgomery
+	// multiplication: R^2 mod N (with R = 2^3072) and -N^-1 mod 2^32.
+	n := key.N
+	rr := new(big.Int).Lsh(big.NewInt(1), 3072*2)
+	rinv := rr.Mod(rr, n)
+	m := new(big.Int).Lsh(big.NewInt(1), 32)
+	mprime := new(big.Int).ModInverse(n, m)
+	mprime.Sub(m, mprime)
+
+	// All big numbers are stored in little endian byte order.
+	littleEndian := func(x *big.Int) []byte {
+		buf := x.FillBytes(make([]byte, 3072/8))
+		for i, j := 0, len(buf)-1; i < j; i, j = i+1, j-1 {
+			buf[i], buf[j] = buf[j], buf[i]
+		}
+		return buf
+	}
+
+	block := &bytes.Buffer{}
+	block.Write([]byte{0xe7, 0x02, 0, 0}) // magic, version, padding
+	block.Write(digest[:])
+	block.Write(littleEndian(n))
+	binary.Write(block, binary.LittleEndian, uint32(key.E))
+	block.Write(littleEndian(rinv))
+	binary.Write(block, binary.LittleEndian, uint32(mprime.Uint64()))
+	block.Write(littleEndian(new(big.Int).SetBytes(signature)))
+	binary.Write(block, binary.LittleEndian, crc32.ChecksumIEEE(block.Bytes()))
+	block.Write(make([]byte, 16))
+
+	// The rest of the signature sector is unused (only one key is supported).
+	block.Write(bytes.Repeat([]byte{0xff}, sectorSize-block.Len()))
+
+	return append(padding, block.Bytes()...), nil
+}
@@ -0,0 +1,70 @@
+package builder
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash/crc32"
+	"math/big"
+	"testing"
+)
+
+func TestSignESPImage(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 3072)
+	if err != nil {
+		t.Fatal("could not generate key:", err)
+	}
+	image := bytes.Repeat([]byte{0xe9, 1, 2, 3}, 1000)
+	sig, err := signESPImage(key, image)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	signed := append(image, sig...)
+	if len(signed) != 8192 {
+		t.Fatalf("expected image and signature sector of 8192 bytes, got %d", len(signed))
+	}
+	if !bytes.Equal(signed[len(image):4096], bytes.Repeat([]byte{0xff}, 4096-len(image))) {
+		t.Errorf("image is not padded with 0xff")
+	}
+
+	block := signed[4096:]
+	if block[0] != 0xe7 || block[1] != 0x02 {
+		t.Errorf("unexpected signature block magic and version: %x", block[:2])
+	}
+	digest := sha256.Sum256(signed[:4096])
+	if !bytes.Equal(block[4:36], digest[:]) {
+		t.Errorf("signature block does not contain the image digest")
+	}
+	if crc := binary.LittleEndian.Uint32(block[1196:]); crc != crc32.ChecksumIEEE(block[:1196]) {
+		t.Errorf("signature block CRC does not match")
+	}
+	if !bytes.Equal(block[1216:], bytes.Repeat([]byte{0xff}, 4096-1216)) {
+		t.Errorf("rest of the signature sector is not erased")
+	}
+
+	// Read back the little endian numbers.
+	readBig := func(b []byte) *big.Int {
+		buf := make([]byte, len(b))
+		for i := range b {
+			buf[len(b)-1-i] = b[i]
+		}
+		return new(big.Int).SetBytes(buf)
+	}
+	if n := readBig(block[36:420]); n.Cmp(key.N) != 0 {
+		t.Errorf("signature block does not contain the public key modulus")
+	}
+	if e := binary.LittleEndian.Uint32(block[420:]); int(e) != key.E {
+		t.Errorf("expected exponent %d, got %d", key.E, e)
+	}
+	mprime := binary.LittleEndian.Uint32(block[808:])
+	if low := uint32(key.N.Uint64()); low*mprime != 0xffffffff {
+		t.Errorf("M' is not -N^-1 mod 2^32")
+	}
+	signature := readBig(block[812:1196]).FillBytes(make([]byte, 384))
+	if err := rsa.VerifyPSS(&key.PublicKey, crypto.SHA256, digest[:], signature, &rsa.PSSOptions{SaltLength: 32}); err != nil {
+		t.Errorf("signature does not verify: %v", err)
+	}
+}
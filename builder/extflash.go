@@ -0,0 +1,27 @@
+package builder
+
+import (
+	"debug/elf"
+	"fmt"
+	"strings"
+
+	"github.com/tinygo-org/tinygo/transform"
+)
+
+// checkExternalFlashPlacement checks whether the linker script placed the
+// constant data of the -extflash packages in external flash. If the linker
+// script doesn't mention these sections, the linker puts them in orphan
+// sections with the same name somewhere in internal flash.
+func checkExternalFlashPlacement(executable, target string) error {
+	file, err := elf.Open(executable)
+	if err != nil {
+		return fmt.Errorf("-extflash is only supported for ELF files: %w", err)
+	}
+	defer file.Close()
+	for _, section := range file.Sections {
+		if strings.HasPrefix(section.Name, transform.ExternalFlashSection+".") {
+			return fmt.Errorf("-extflash: the linker script of target %s does not place %s sections in external flash (found section %s)", target, transform.ExternalFlashSection, section.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package builder
+
+// This file writes image headers into firmware images, as described by the
+// image-header property in the target JSON file. Many ROM bootloaders (and
+// second stage bootloaders) check the length or checksum of the image before
+// running it.
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+
+	"github.com/tinygo-org/tinygo/compileopts"
+)
+
+// extractImage extracts the firmware image and load address from the given ELF
+// file, like extractROM, and adds the image header if the target defines one.
+// If an MCUboot key is given, the image is also wrapped in a signed MCUboot
+// image.
+func extractImage(config *compileopts.Config, path string) (uint64, []byte, error) {
+	addr, data, err := extractROM(path)
+	if err != nil {
+		return 0, nil, err
+	}
+	if config.Target.ImageHeader != nil {
+		addr, data, err = addImageHeader(config.Target.ImageHeader, config.Options.ImageVersion, addr, data)
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+	if config.Options.MCUbootKey != "" {
+		key, err := readPEMPrivateKey(config.Options.MCUbootKey)
+		if err != nil {
+			return 0, nil, err
+		}
+		version, err := parseMCUbootVersion(config.Options.MCUbootVersion)
+		if err != nil {
+			return 0, nil, err
+		}
+		addr, data, err = signMCUbootImage(key, config.Target.MCUbootHeaderSize, version, addr, data)
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+	return addr, data, nil
+}
+
+// addImageHeader makes room for the header (if needed), pads the image and
+// writes the header fields. It returns the new load address and image.
+func addImageHeader(header *compileopts.ImageHeader, version uint64, addr uint64, data []byte) (uint64, []byte, error) {
+	padByte := uint8(0xff)
+	if header.PadByte != nil {
+		padByte = *header.PadByte
+	}
+
+	if header.Prepend != 0 {
+		if addr < uint64(header.Prepend) {
+			return 0, nil, fmt.Errorf("image header: cannot prepend %d bytes to an image at address %#x", header.Prepend, addr)
+		}
+		addr -= uint64(header.Prepend)
+		data = append(make([]byte, header.Prepend), data...)
+	}
+
+	if header.Align > 1 {
+		for len(data)%int(header.Align) != 0 {
+			data = append(data, padByte)
+		}
+	}
+
+	for _, field := range header.Fields {
+		size := field.Size
+		if size == 0 {
+			size = 4
+		}
+
+		var value []byte
+		switch field.Type {
+		case "magic", "length", "version":
+			var n uint64
+			switch field.Type {
+			case "magic":
+				n = field.Value
+			case "length":
+				if int(field.Start) > len(data) {
+					return 0, nil, fmt.Errorf("image header: length start %#x is outside the image", field.Start)
+				}
+				n = uint64(len(data) - int(field.Start))
+			case "version":
+				n = version
+			}
+			switch size {
+			case 1, 2, 4, 8:
+			default:
+				return 0, nil, fmt.Errorf("image header: invalid size %d for %s field", size, field.Type)
+			}
+			if size < 8 && n>>(size*8) != 0 {
+				return 0, nil, fmt.Errorf("image header: %s value %#x does not fit in %d bytes", field.Type, n, size)
+			}
+			value = make([]byte, 8)
+			if field.BigEndian {
+				binary.BigEndian.PutUint64(value, n)
+				value = value[8-size:]
+			} else {
+				binary.LittleEndian.PutUint64(value, n)
+				value = value[:size]
+			}
+		case "crc32", "sha256":
+			if int(field.Start) > len(data) {
+				return 0, nil, fmt.Errorf("image header: %s start %#x is outside the image", field.Type, field.Start)
+			}
+			sumSize := uint32(sha256.Size)
+			if field.Type == "crc32" {
+				sumSize = 4
+			}
+			if field.Offset+sumSize > field.Start {
+				return 0, nil, fmt.Errorf("image header: %s field at %#x is inside the range it covers", field.Type, field.Offset)
+			}
+			if field.Type == "crc32" {
+				value = make([]byte, 4)
+				sum := crc32.ChecksumIEEE(data[field.Start:])
+				if field.BigEndian {
+					binary.BigEndian.PutUint32(value, sum)
+				} else {
+					binary.LittleEndian.PutUint32(value, sum)
+				}
+			} else {
+				sum := sha256.Sum256(data[field.Start:])
+				value = sum[:]
+			}
+		default:
+			return 0, nil, fmt.Errorf("image header: unknown field type %q", field.Type)
+		}
+
+		if int(field.Offset)+len(value) > len(data) {
+			return 0, nil, fmt.Errorf("image header: %s field at %#x is outside the image", field.Type, field.Offset)
+		}
+		copy(data[field.Offset:], value)
+	}
+
+	return addr, data, nil
+}
@@ -0,0 +1,80 @@
+package builder
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+
+	"github.com/tinygo-org/tinygo/compileopts"
+)
+
+func TestAddImageHeader(t *testing.T) {
+	header := &compileopts.ImageHeader{
+		Prepend: 16,
+		Align:   8,
+		Fields: []compileopts.ImageHeaderField{
+			{Type: "magic", Offset: 0, Value: 0x12345678},
+			{Type: "length", Offset: 4, Start: 16},
+			{Type: "version", Offset: 8, Size: 2, BigEndian: true},
+			{Type: "crc32", Offset: 12, Start: 16},
+		},
+	}
+	addr, data, err := addImageHeader(header, 0x0102, 0x1010, []byte{1, 2, 3})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if addr != 0x1000 {
+		t.Errorf("expected load address 0x1000, got %#x", addr)
+	}
+	if len(data) != 24 {
+		t.Fatalf("expected padded image of 24 bytes, got %d", len(data))
+	}
+	if !bytes.Equal(data[16:], []byte{1, 2, 3, 0xff, 0xff, 0xff, 0xff, 0xff}) {
+		t.Errorf("unexpected image contents: %x", data[16:])
+	}
+	if magic := binary.LittleEndian.Uint32(data[0:]); magic != 0x12345678 {
+		t.Errorf("expected magic 0x12345678, got %#x", magic)
+	}
+	if length := binary.LittleEndian.Uint32(data[4:]); length != 8 {
+		t.Errorf("expected length 8, got %d", length)
+	}
+	if !bytes.Equal(data[8:12], []byte{0x01, 0x02, 0, 0}) {
+		t.Errorf("unexpected version bytes: %x", data[8:12])
+	}
+	if sum := binary.LittleEndian.Uint32(data[12:]); sum != crc32.ChecksumIEEE(data[16:]) {
+		t.Errorf("unexpected CRC32 %#x", sum)
+	}
+
+	// SHA256 of the image after a header that is already reserved in the
+	// image.
+	data = make([]byte, 64)
+	data[40] = 1
+	header = &compileopts.ImageHeader{
+		Fields: []compileopts.ImageHeaderField{
+			{Type: "sha256", Offset: 0, Start: 32},
+		},
+	}
+	_, data, err = addImageHeader(header, 0, 0, data)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if sum := sha256.Sum256(data[32:]); !bytes.Equal(data[:32], sum[:]) {
+		t.Errorf("unexpected SHA256: %x", data[:32])
+	}
+
+	// Invalid headers.
+	for _, field := range []compileopts.ImageHeaderField{
+		{Type: "crc32", Offset: 0, Start: 2},
+		{Type: "magic", Offset: 0, Size: 1, Value: 0x100},
+		{Type: "magic", Offset: 0, Size: 3},
+		{Type: "magic", Offset: 62},
+		{Type: "checksum"},
+	} {
+		header := &compileopts.ImageHeader{Fields: []compileopts.ImageHeaderField{field}}
+		if _, _, err := addImageHeader(header, 0, 0, make([]byte, 64)); err == nil {
+			t.Errorf("expected an error for field %+v", field)
+		}
+	}
+}
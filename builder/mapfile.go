@@ -0,0 +1,251 @@
+package builder
+
+// This file implements the -map flag. The linker writes a map file with the
+// placement of every symbol and the size of every section, and for ELF files
+// a table of symbol references is appended that shows which symbols refer to
+// (and therefore pulled in) which other symbols.
+
+import (
+	"debug/elf"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/tinygo-org/tinygo/compileopts"
+)
+
+// linkerMapFlags returns the linker flags needed to write a linker map to the
+// given path. For ELF files, relocations are kept in the output so that symbol
+// references can be added to the map afterwards: keepsRelocs is true in that
+// case.
+func linkerMapFlags(config *compileopts.Config, path string) (flags []string, keepsRelocs bool, err error) {
+	switch {
+	case config.GOOS() == "windows":
+		return []string{"--Map=" + path}, false, nil
+	case config.GOOS() == "darwin":
+		return []string{"-map", path}, false, nil
+	case config.Target.Linker == "wasm-ld":
+		return []string{"--Map=" + path}, false, nil
+	case config.Target.Linker == "ld.lld":
+		return []string{"-Map=" + path, "--cref", "--emit-relocs"}, true, nil
+	default:
+		return nil, false, errors.New("cannot write linker map: unknown linker: " + config.Target.Linker)
+	}
+}
+
+// appendSymbolReferences reads the relocations that were kept in the given ELF
+// file and appends a table of symbol references to the linker map.
+func appendSymbolReferences(executable, mapfile string) error {
+	file, err := elf.Open(executable)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	references, err := readSymbolReferences(file)
+	if err != nil {
+		return fmt.Errorf("could not read symbol references from %s: %w", executable, err)
+	}
+
+	f, err := os.OpenFile(mapfile, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(references))
+	for name := range references {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(f, "\nSymbol references (each symbol is followed by the symbols that refer to it)\n\n")
+	for _, name := range names {
+		fmt.Fprintf(f, "%s\n", name)
+		for _, from := range references[name] {
+			fmt.Fprintf(f, "\t%s\n", from)
+		}
+	}
+	return f.Close()
+}
+
+// symbolRange is a symbol with its address range in a section.
+type symbolRange struct {
+	name  string
+	start uint64
+	end   uint64
+}
+
+// readSymbolReferences returns, for each symbol, the sorted list of symbols
+// that refer to it. It relies on relocation sections that were kept in the
+// output file with --emit-relocs.
+func readSymbolReferences(file *elf.File) (map[string][]string, error) {
+	symbols, err := file.Symbols()
+	if err != nil {
+		return nil, err
+	}
+
+	// Make a sorted list of the symbols in each section, to find the symbol
+	// that contains a given address.
+	sectionSymbols := make(map[elf.SectionIndex][]symbolRange)
+	for _, symbol := range symbols {
+		symType := elf.ST_TYPE(symbol.Info)
+		if symbol.Size == 0 || symbol.Name == "" {
+			continue
+		}
+		if symType != elf.STT_FUNC && symType != elf.STT_OBJECT && symType != elf.STT_NOTYPE {
+			continue
+		}
+		if symbol.Section == elf.SHN_UNDEF || symbol.Section >= elf.SHN_LORESERVE {
+			continue
+		}
+		start := symbol.Value
+		if file.Machine == elf.EM_ARM && symType == elf.STT_FUNC {
+			// Clear the Thumb bit.
+			start &^= 1
+		}
+		sectionSymbols[symbol.Section] = append(sectionSymbols[symbol.Section], symbolRange{
+			name:  symbol.Name,
+			start: start,
+			end:   start + symbol.Size,
+		})
+	}
+	for _, list := range sectionSymbols {
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].start < list[j].start
+		})
+	}
+	lookup := func(section elf.SectionIndex, addr uint64) string {
+		list := sectionSymbols[section]
+		i := sort.Search(len(list), func(i int) bool {
+			return list[i].start > addr
+		})
+		if i == 0 || addr >= list[i-1].end {
+			return ""
+		}
+		return list[i-1].name
+	}
+
+	references := make(map[string]map[string]struct{})
+	for _, section := range file.Sections {
+		if section.Type != elf.SHT_REL && section.Type != elf.SHT_RELA {
+			continue
+		}
+		if int(section.Info) >= len(file.Sections) || file.Sections[section.Info].Flags&elf.SHF_ALLOC == 0 {
+			// Relocations for debug information and such.
+			continue
+		}
+		target := elf.SectionIndex(section.Info)
+		data, err := section.Data()
+		if err != nil {
+			return nil, err
+		}
+		relocs, err := readRelocations(file, data, section.Type == elf.SHT_RELA)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", section.Name, err)
+		}
+		for _, rel := range relocs {
+			if rel.symbol == 0 || int(rel.symbol) > len(symbols) {
+				continue
+			}
+			from := lookup(target, rel.offset)
+			if from == "" {
+				continue
+			}
+			symbol := symbols[rel.symbol-1] // Symbols() skips the null symbol
+			to := symbol.Name
+			if elf.ST_TYPE(symbol.Info) == elf.STT_SECTION {
+				// Relocations to local data (such as strings) often refer
+				// to the section instead of the symbol itself.
+				if section.Type != elf.SHT_RELA {
+					// The addend is stored in the instruction, so the
+					// referenced symbol isn't known.
+					continue
+				}
+				addend := rel.addend
+				if file.Machine == elf.EM_X86_64 && (elf.R_X86_64(rel.typ) == elf.R_X86_64_PC32 || elf.R_X86_64(rel.typ) == elf.R_X86_64_PLT32) {
+					// The addend is relative to the end of the 4 byte
+					// displacement, not to its start.
+					addend += 4
+				}
+				to = lookup(symbol.Section, uint64(int64(symbol.Value)+addend))
+			}
+			if to == "" || to == from {
+				continue
+			}
+			if references[to] == nil {
+				references[to] = make(map[string]struct{})
+			}
+			references[to][from] = struct{}{}
+		}
+	}
+
+	result := make(map[string][]string, len(references))
+	for to, fromSet := range references {
+		list := make([]string, 0, len(fromSet))
+		for from := range fromSet {
+			list = append(list, from)
+		}
+		sort.Strings(list)
+		result[to] = list
+	}
+	return result, nil
+}
+
+// relocation is a single entry of a SHT_REL or SHT_RELA section. The addend
+// is always zero for SHT_REL sections, where it is stored in the relocated
+// data instead.
+type relocation struct {
+	offset uint64
+	symbol uint32
+	typ    uint32
+	addend int64
+}
+
+// readRelocations parses the contents of a SHT_REL or SHT_RELA section.
+func readRelocations(file *elf.File, data []byte, hasAddend bool) ([]relocation, error) {
+	var relocs []relocation
+	switch file.Class {
+	case elf.ELFCLASS32:
+		size := 8
+		if hasAddend {
+			size = 12
+		}
+		if len(data)%size != 0 {
+			return nil, errors.New("invalid relocation section size")
+		}
+		for ; len(data) != 0; data = data[size:] {
+			info := file.ByteOrder.Uint32(data[4:])
+			rel := relocation{
+				offset: uint64(file.ByteOrder.Uint32(data[0:])),
+				symbol: info >> 8,
+				typ:    info & 0xff,
+			}
+			if hasAddend {
+				rel.addend = int64(int32(file.ByteOrder.Uint32(data[8:])))
+			}
+			relocs = append(relocs, rel)
+		}
+	case elf.ELFCLASS64:
+		size := 16
+		if hasAddend {
+			size = 24
+		}
+		if len(data)%size != 0 {
+			return nil, errors.New("invalid relocation section size")
+		}
+		for ; len(data) != 0; data = data[size:] {
+			info := file.ByteOrder.Uint64(data[8:])
+			rel := relocation{
+				offset: file.ByteOrder.Uint64(data[0:]),
+				symbol: uint32(info >> 32),
+				typ:    uint32(info),
+			}
+			if hasAddend {
+				rel.addend = int64(file.ByteOrder.Uint64(data[16:]))
+			}
+			relocs = append(relocs, rel)
+		}
+	default:
+		return nil, fmt.Errorf("unknown ELF class: %s", file.Class)
+	}
+	return relocs, nil
+}
@@ -0,0 +1,56 @@
+package builder
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tinygo-org/tinygo/compileopts"
+)
+
+// Test whether the linker map contains the symbol placement and the symbol
+// references read from the ELF relocations.
+func TestLinkerMap(t *testing.T) {
+	t.Parallel()
+
+	mapFile := filepath.Join(t.TempDir(), "out.map")
+	options := compileopts.Options{
+		Target:        "microbit",
+		Opt:           "z",
+		Semaphore:     sema,
+		InterpTimeout: 60 * time.Second,
+		Debug:         true,
+		MapFile:       mapFile,
+	}
+	target, err := compileopts.LoadTarget(&options)
+	if err != nil {
+		t.Fatal("could not load target:", err)
+	}
+	config := &compileopts.Config{
+		Options: &options,
+		Target:  target,
+	}
+	_, err = Build("examples/serial", "", t.TempDir(), config)
+	if err != nil {
+		t.Fatal("could not build:", err)
+	}
+
+	data, err := os.ReadFile(mapFile)
+	if err != nil {
+		t.Fatal("could not read linker map:", err)
+	}
+	linkerMap := string(data)
+	if !strings.Contains(linkerMap, "__isr_vector") {
+		t.Error("linker map does not contain the placement of __isr_vector")
+	}
+	_, references, ok := strings.Cut(linkerMap, "\nSymbol references")
+	if !ok {
+		t.Fatal("linker map does not contain symbol references")
+	}
+	// The interrupt vector refers to the reset handler.
+	if !strings.Contains(references, "\nReset_Handler\n\t__isr_vector\n") {
+		t.Error("symbol references do not show that __isr_vector refers to Reset_Handler")
+	}
+}
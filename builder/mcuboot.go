@@ -0,0 +1,213 @@
+package builder
+
+// This file converts a firmware image into a signed MCUboot image, see:
+// https://docs.mcuboot.com/design.html#image-format
+// The result is equivalent to what `imgtool sign --pad-header` produces.
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	mcubootImageMagic   = 0x96f3b83d
+	mcubootTLVInfoMagic = 0x6907
+	mcubootHeaderSize   = 0x200 // default, see the mcuboot-header-size target property
+
+	mcubootTLVKeyHash   = 0x01
+	mcubootTLVSHA256    = 0x10
+	mcubootTLVRSA2048   = 0x20
+	mcubootTLVECDSASig  = 0x22
+	mcubootTLVRSA3072   = 0x23
+	mcubootTLVEd25519   = 0x24
+	mcubootHeaderLength = 32 // size of struct image_header
+)
+
+// mcubootVersion is the image version stored in the MCUboot image header.
+type mcubootVersion struct {
+	Major    uint8
+	Minor    uint8
+	Revision uint16
+	Build    uint32
+}
+
+// parseMCUbootVersion parses a version in the form major.minor.revision+build,
+// where all but the major version are optional. An empty string is version
+// 0.0.0+0.
+func parseMCUbootVersion(s string) (mcubootVersion, error) {
+	var version mcubootVersion
+	if s == "" {
+		return version, nil
+	}
+	semver, build, hasBuild := strings.Cut(s, "+")
+	if hasBuild {
+		n, err := strconv.ParseUint(build, 10, 32)
+		if err != nil {
+			return version, fmt.Errorf("invalid MCUboot image version %q: %w", s, err)
+		}
+		version.Build = uint32(n)
+	}
+	parts := strings.Split(semver, ".")
+	if len(parts) > 3 {
+		return version, fmt.Errorf("invalid MCUboot image version %q: expected major.minor.revision+build", s)
+	}
+	for i, part := range parts {
+		bits := 8
+		if i == 2 {
+			bits = 16
+		}
+		n, err := strconv.ParseUint(part, 10, bits)
+		if err != nil {
+			return version, fmt.Errorf("invalid MCUboot image version %q: %w", s, err)
+		}
+		switch i {
+		case 0:
+			version.Major = uint8(n)
+		case 1:
+			version.Minor = uint8(n)
+		case 2:
+			version.Revision = uint16(n)
+		}
+	}
+	return version, nil
+}
+
+// readPEMPrivateKey reads a PEM encoded private key, as generated by
+// `imgtool keygen` or `espsecure.py generate_signing_key`. It supports PKCS #8,
+// SEC 1 (EC) and PKCS #1 (RSA) keys.
+func readPEMPrivateKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM encoded private key found", path)
+	}
+	var key any
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block type %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s: unsupported key type %T", path, key)
+	}
+	return signer, nil
+}
+
+// signMCUbootImage prepends an MCUboot image header to the firmware image and
+// appends the TLV area with the hash of the image, the hash of the public key
+// and the signature. The image must be linked headerSize bytes after the start
+// of the slot. It returns the new load address and image.
+func signMCUbootImage(key crypto.Signer, headerSize uint32, version mcubootVersion, addr uint64, data []byte) (uint64, []byte, error) {
+	if headerSize == 0 {
+		headerSize = mcubootHeaderSize
+	}
+	if headerSize < mcubootHeaderLength {
+		return 0, nil, fmt.Errorf("MCUboot header size must be at least %d bytes, got %d", mcubootHeaderLength, headerSize)
+	}
+	if addr < uint64(headerSize) {
+		return 0, nil, fmt.Errorf("MCUboot: cannot prepend %d byte header to an image at address %#x", headerSize, addr)
+	}
+
+	// Write the header, padded to headerSize bytes.
+	image := make([]byte, headerSize, int(headerSize)+len(data)+512)
+	binary.LittleEndian.PutUint32(image[0:], mcubootImageMagic)
+	binary.LittleEndian.PutUint32(image[4:], 0) // load address, only used for RAM loading
+	binary.LittleEndian.PutUint16(image[8:], uint16(headerSize))
+	binary.LittleEndian.PutUint16(image[10:], 0) // no protected TLVs
+	binary.LittleEndian.PutUint32(image[12:], uint32(len(data)))
+	binary.LittleEndian.PutUint32(image[16:], 0) // flags
+	image[20] = version.Major
+	image[21] = version.Minor
+	binary.LittleEndian.PutUint16(image[22:], version.Revision)
+	binary.LittleEndian.PutUint32(image[24:], version.Build)
+	image = append(image, data...)
+
+	// The hash and signature cover the header and the image.
+	digest := sha256.Sum256(image)
+
+	var pubkey []byte
+	var sigType uint16
+	var sig []byte
+	var err error
+	switch k := key.(type) {
+	case ed25519.PrivateKey:
+		// Like imgtool, sign the hash of the image instead of the image itself.
+		pubkey, err = x509.MarshalPKIXPublicKey(k.Public())
+		sigType = mcubootTLVEd25519
+		sig = ed25519.Sign(k, digest[:])
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return 0, nil, errors.New("MCUboot: only ECDSA keys on the P-256 curve are supported")
+		}
+		pubkey, err = x509.MarshalPKIXPublicKey(k.Public())
+		sigType = mcubootTLVECDSASig
+		if err == nil {
+			sig, err = ecdsa.SignASN1(rand.Reader, k, digest[:])
+		}
+	case *rsa.PrivateKey:
+		switch k.N.BitLen() {
+		case 2048:
+			sigType = mcubootTLVRSA2048
+		case 3072:
+			sigType = mcubootTLVRSA3072
+		default:
+			return 0, nil, fmt.Errorf("MCUboot: unsupported RSA key size %d", k.N.BitLen())
+		}
+		pubkey = x509.MarshalPKCS1PublicKey(&k.PublicKey)
+		sig, err = rsa.SignPSS(rand.Reader, k, crypto.SHA256, digest[:], &rsa.PSSOptions{SaltLength: 32})
+	default:
+		return 0, nil, fmt.Errorf("MCUboot: unsupported key type %T", key)
+	}
+	if err != nil {
+		return 0, nil, fmt.Errorf("MCUboot: could not sign image: %w", err)
+	}
+	keyHash := sha256.Sum256(pubkey)
+
+	// Append the TLV area.
+	var tlvs bytes.Buffer
+	for _, tlv := range []struct {
+		kind  uint16
+		value []byte
+	}{
+		{mcubootTLVSHA256, digest[:]},
+		{mcubootTLVKeyHash, keyHash[:]},
+		{sigType, sig},
+	} {
+		binary.Write(&tlvs, binary.LittleEndian, tlv.kind)
+		binary.Write(&tlvs, binary.LittleEndian, uint16(len(tlv.value)))
+		tlvs.Write(tlv.value)
+	}
+	info := make([]byte, 4)
+	binary.LittleEndian.PutUint16(info[0:], mcubootTLVInfoMagic)
+	binary.LittleEndian.PutUint16(info[2:], uint16(len(info)+tlvs.Len()))
+	image = append(image, info...)
+	image = append(image, tlvs.Bytes()...)
+
+	return addr - uint64(headerSize), image, nil
+}
@@ -0,0 +1,83 @@
+package builder
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"testing"
+)
+
+func TestSignMCUbootImage(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal("could not generate key:", err)
+	}
+	version, err := parseMCUbootVersion("1.2.3+4")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	firmware := []byte{1, 2, 3, 4, 5}
+	addr, image, err := signMCUbootImage(priv, 0x20, version, 0x10020, firmware)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if addr != 0x10000 {
+		t.Errorf("expected load address 0x10000, got %#x", addr)
+	}
+
+	// Check the header.
+	if magic := binary.LittleEndian.Uint32(image[0:]); magic != mcubootImageMagic {
+		t.Errorf("unexpected header magic %#x", magic)
+	}
+	if hdrSize := binary.LittleEndian.Uint16(image[8:]); hdrSize != 0x20 {
+		t.Errorf("expected header size 0x20, got %#x", hdrSize)
+	}
+	if imgSize := binary.LittleEndian.Uint32(image[12:]); imgSize != uint32(len(firmware)) {
+		t.Errorf("expected image size %d, got %d", len(firmware), imgSize)
+	}
+	if !bytes.Equal(image[20:28], []byte{1, 2, 3, 0, 4, 0, 0, 0}) {
+		t.Errorf("unexpected image version: %x", image[20:28])
+	}
+	if !bytes.Equal(image[0x20:0x20+len(firmware)], firmware) {
+		t.Errorf("firmware not found after the header")
+	}
+
+	// Parse the TLV area.
+	signed := image[:0x20+len(firmware)]
+	tlvArea := image[len(signed):]
+	if magic := binary.LittleEndian.Uint16(tlvArea[0:]); magic != mcubootTLVInfoMagic {
+		t.Fatalf("unexpected TLV info magic %#x", magic)
+	}
+	if total := binary.LittleEndian.Uint16(tlvArea[2:]); int(total) != len(tlvArea) {
+		t.Fatalf("TLV area is %d bytes, header says %d", len(tlvArea), total)
+	}
+	tlvs := make(map[uint16][]byte)
+	for p := tlvArea[4:]; len(p) != 0; {
+		kind := binary.LittleEndian.Uint16(p[0:])
+		length := binary.LittleEndian.Uint16(p[2:])
+		tlvs[kind] = p[4 : 4+length]
+		p = p[4+length:]
+	}
+
+	digest := sha256.Sum256(signed)
+	if !bytes.Equal(tlvs[mcubootTLVSHA256], digest[:]) {
+		t.Errorf("SHA256 TLV does not match the image")
+	}
+	pubkey, _ := x509.MarshalPKIXPublicKey(pub)
+	keyHash := sha256.Sum256(pubkey)
+	if !bytes.Equal(tlvs[mcubootTLVKeyHash], keyHash[:]) {
+		t.Errorf("key hash TLV does not match the public key")
+	}
+	if !ed25519.Verify(pub, digest[:], tlvs[mcubootTLVEd25519]) {
+		t.Errorf("signature TLV does not verify")
+	}
+
+	// Invalid versions.
+	for _, s := range []string{"256", "1.2.3.4", "1.2+x", "1.2.65536"} {
+		if _, err := parseMCUbootVersion(s); err == nil {
+			t.Errorf("expected an error for version %q", s)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package builder
+
+// This file implements the -code-offset and -ram-offset flags. They move the
+// start of the FLASH_TEXT and RAM memory regions of the target linker script,
+// so that the same target can be used to build images for different slots
+// (for example the two banks of a dual-bank OTA layout).
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/tinygo-org/tinygo/compileopts"
+	"github.com/tinygo-org/tinygo/goenv"
+)
+
+// Matches a FLASH_TEXT or RAM line in the MEMORY command of a linker script,
+// like this one:
+//
+//	FLASH_TEXT (rw) : ORIGIN = 0x00000000 + 0x4000, LENGTH = 1M - 0x4000 /* comment */
+var memoryRegionRegexp = regexp.MustCompile(`(?m)^(\s*(FLASH_TEXT|RAM)\s*\([a-zA-Z!]*\)\s*:\s*ORIGIN\s*=\s*)([^,\n]*?)(\s*,\s*LENGTH\s*=\s*)([^/\n]*?)(\s*(?:/\*.*)?)$`)
+
+// Matches an INCLUDE command on a single line.
+var linkerScriptIncludeRegexp = regexp.MustCompile(`(?m)^\s*INCLUDE\s+"?([^"\s]+)"?\s*;?\s*$`)
+
+// writeMemoryOffsetLinkerScript writes a copy of the target linker script to
+// tmpdir with the memory regions moved by -code-offset and -ram-offset, and
+// returns the path of the new linker script.
+func writeMemoryOffsetLinkerScript(config *compileopts.Config, tmpdir string) (string, error) {
+	if config.Target.LinkerScript == "" {
+		return "", fmt.Errorf("-code-offset and -ram-offset are not supported for target %s: it has no linker script", config.Options.Target)
+	}
+	offsets := map[string]uint64{
+		"FLASH_TEXT": config.Options.CodeOffset,
+		"RAM":        config.Options.RAMOffset,
+	}
+	root := goenv.Get("TINYGOROOT")
+	readFile := func(path string) (string, error) {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) && !filepath.IsAbs(path) {
+			// Like the linker, also look for linker scripts relative to
+			// TINYGOROOT (see the -L flag in Config.LDFlags).
+			data, err = os.ReadFile(filepath.Join(root, path))
+		}
+		return string(data), err
+	}
+	script, err := offsetMemoryRegions(readFile, config.Target.LinkerScript, offsets)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(tmpdir, "memory-offset.ld")
+	return path, os.WriteFile(path, []byte(script), 0666)
+}
+
+// offsetMemoryRegions reads the given linker script and returns it with the
+// origin of the given memory regions moved forward and their length reduced by
+// the given offset. Included linker scripts are inlined if they define one of
+// these memory regions. The returned linker script asserts that each offset
+// is inside its memory region.
+func offsetMemoryRegions(readFile func(string) (string, error), path string, offsets map[string]uint64) (string, error) {
+	found := make(map[string]string) // region name => original length
+	script, err := offsetMemoryRegionsInFile(readFile, path, offsets, found)
+	if err != nil {
+		return "", err
+	}
+	for _, name := range []string{"FLASH_TEXT", "RAM"} {
+		offset := offsets[name]
+		if offset == 0 {
+			continue
+		}
+		length, ok := found[name]
+		if !ok {
+			return "", fmt.Errorf("linker script %s: cannot apply offset: no %s memory region found", path, name)
+		}
+		script += fmt.Sprintf("\n/* Added by tinygo: check the %s offset against the memory map. */\nASSERT((%s) > %#x, \"offset %#x is outside the %s memory region\");\n", name, length, offset, offset, name)
+	}
+	return script, nil
+}
+
+func offsetMemoryRegionsInFile(readFile func(string) (string, error), path string, offsets map[string]uint64, found map[string]string) (string, error) {
+	script, err := readFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	var regionErr error
+	script = memoryRegionRegexp.ReplaceAllStringFunc(script, func(line string) string {
+		m := memoryRegionRegexp.FindStringSubmatch(line)
+		name, origin, length := m[2], m[3], m[5]
+		if _, ok := found[name]; ok {
+			regionErr = fmt.Errorf("linker script %s: %s memory region defined more than once", path, name)
+		}
+		found[name] = length
+		offset := offsets[name]
+		if offset == 0 {
+			return line
+		}
+		return fmt.Sprintf("%s(%s) + %#x%s(%s) - %#x%s", m[1], origin, offset, m[4], length, offset, m[6])
+	})
+	if regionErr != nil {
+		return "", regionErr
+	}
+
+	// Inline included linker scripts that define one of the memory regions.
+	var includeErr error
+	script = linkerScriptIncludeRegexp.ReplaceAllStringFunc(script, func(line string) string {
+		if includeErr != nil {
+			return line
+		}
+		includePath := linkerScriptIncludeRegexp.FindStringSubmatch(line)[1]
+		numFound := len(found)
+		included, err := offsetMemoryRegionsInFile(readFile, includePath, offsets, found)
+		if err != nil {
+			includeErr = err
+			return line
+		}
+		if len(found) == numFound {
+			// Nothing to change in this file.
+			return line
+		}
+		return strings.TrimRight(included, "\n")
+	})
+	if includeErr != nil {
+		return "", includeErr
+	}
+	return script, nil
+}
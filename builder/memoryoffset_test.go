@@ -0,0 +1,59 @@
+package builder
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestOffsetMemoryRegions(t *testing.T) {
+	files := map[string]string{
+		"board.ld": `
+MEMORY
+{
+    FLASH_TEXT (rw) : ORIGIN = 0x00000000 + 0x4000, LENGTH = 1M - 0x4000 /* bootloader */
+    RAM (xrw)       : ORIGIN = 0x20000000, LENGTH = 256K
+}
+
+_stack_size = 4K;
+
+INCLUDE "targets/arm.ld"
+`,
+		"chip.ld": `
+INCLUDE "board.ld"
+`,
+		"targets/arm.ld": `SECTIONS { }
+`,
+	}
+	readFile := func(path string) (string, error) {
+		if data, ok := files[path]; ok {
+			return data, nil
+		}
+		return "", os.ErrNotExist
+	}
+
+	script, err := offsetMemoryRegions(readFile, "chip.ld", map[string]uint64{"FLASH_TEXT": 0x80000})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	for _, want := range []string{
+		"    FLASH_TEXT (rw) : ORIGIN = (0x00000000 + 0x4000) + 0x80000, LENGTH = (1M - 0x4000) - 0x80000 /* bootloader */\n",
+		"    RAM (xrw)       : ORIGIN = 0x20000000, LENGTH = 256K\n",
+		"_stack_size = 4K;\n",
+		"INCLUDE \"targets/arm.ld\"\n",
+		"ASSERT((1M - 0x4000) > 0x80000, ",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("linker script does not contain %q:\n%s", want, script)
+		}
+	}
+	if strings.Contains(script, `INCLUDE "board.ld"`) {
+		t.Errorf("linker script with the memory regions was not inlined:\n%s", script)
+	}
+
+	// Only the regions in the MEMORY command can be moved.
+	_, err = offsetMemoryRegions(readFile, "targets/arm.ld", map[string]uint64{"RAM": 0x1000})
+	if err == nil || !strings.Contains(err.Error(), "no RAM memory region") {
+		t.Errorf("expected an error for a missing memory region, got %v", err)
+	}
+}
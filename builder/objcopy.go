@@ -7,6 +7,7 @@ import (
 	"sort"
 
 	"github.com/marcinbor85/gohex"
+	"github.com/tinygo-org/tinygo/compileopts"
 )
 
 // maxPadBytes is the maximum allowed bytes to be padded in a rom extraction
@@ -107,7 +108,7 @@ func extractROM(path string) (uint64, []byte, error) {
 
 // objcopy converts an ELF file to a different (simpler) output file format:
 // .bin or .hex. It extracts only the .text section.
-func objcopy(infile, outfile, binaryFormat string) error {
+func objcopy(config *compileopts.Config, infile, outfile, binaryFormat string) error {
 	f, err := os.OpenFile(outfile, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
 	if err != nil {
 		return err
@@ -115,7 +116,7 @@ func objcopy(infile, outfile, binaryFormat string) error {
 	defer f.Close()
 
 	// Read the .text segment.
-	addr, data, err := extractROM(infile)
+	addr, data, err := extractImage(config, infile)
 	if err != nil {
 		return err
 	}
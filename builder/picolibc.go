@@ -47,6 +47,7 @@ var Picolibc = Library{
 
 var picolibcSources = []string{
 	"../../picolibc-stdio.c",
+	"../../picolibc-string.c", // memcpy, memmove, memset
 
 	// srcs_tinystdio
 	"libc/tinystdio/asprintf.c",
@@ -140,12 +141,9 @@ var picolibcSources = []string{
 	"libc/string/memccpy.c",
 	"libc/string/memchr.c",
 	"libc/string/memcmp.c",
-	"libc/string/memcpy.c",
 	"libc/string/memmem.c",
-	"libc/string/memmove.c",
 	"libc/string/mempcpy.c",
 	"libc/string/memrchr.c",
-	"libc/string/memset.c",
 	"libc/string/rawmemchr.c",
 	"libc/string/rindex.c",
 	"libc/string/stpcpy.c",
@@ -90,3 +90,50 @@ func TestBinarySize(t *testing.T) {
 		})
 	}
 }
+
+// Test whether type codes can be read back from a linked binary.
+func TestTypeCodes(t *testing.T) {
+	t.Parallel()
+
+	options := compileopts.Options{
+		Target:        "cortex-m-qemu",
+		Opt:           "z",
+		Semaphore:     sema,
+		InterpTimeout: 60 * time.Second,
+		Debug:         true,
+	}
+	target, err := compileopts.LoadTarget(&options)
+	if err != nil {
+		t.Fatal("could not load target:", err)
+	}
+	config := &compileopts.Config{
+		Options: &options,
+		Target:  target,
+	}
+	result, err := Build("../testdata/interface.go", "", t.TempDir(), config)
+	if err != nil {
+		t.Fatal("could not build:", err)
+	}
+
+	typecodes, err := LoadTypeCodes(result.Executable)
+	if err != nil {
+		t.Fatal("could not read type codes:", err)
+	}
+	found := make(map[string]TypeCodeInfo)
+	for _, typecode := range typecodes {
+		if typecode.Size == 0 {
+			t.Errorf("type code %s has size zero", typecode.Name)
+		}
+		found[typecode.Name] = typecode
+	}
+	for _, name := range []string{"basic:int", "named:main.Thing", "pointer:named:main.Thing"} {
+		if _, ok := found[name]; !ok {
+			t.Errorf("type code %s not found in binary", name)
+		}
+	}
+	if _, ok := found["struct:{name:basic:string}"]; ok {
+		if _, ok := found["structfield:name"]; !ok {
+			t.Errorf("struct field data for field name not found in binary")
+		}
+	}
+}
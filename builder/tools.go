@@ -4,6 +4,8 @@ import (
 	"errors"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 
 	"github.com/tinygo-org/tinygo/goenv"
 )
@@ -27,6 +29,22 @@ func runCCompiler(flags ...string) error {
 	return execCommand("clang", flags...)
 }
 
+// LookupTool returns the command that is used during a build for the given
+// tool, such as a linker (the Linker field of a target). Tools that are built
+// into TinyGo are reported as the TinyGo executable itself, with builtin set.
+func LookupTool(name string) (path string, builtin bool, err error) {
+	if hasBuiltinTools && (name == "clang" || name == "ld.lld" || name == "wasm-ld") {
+		path, err = os.Executable()
+		return path, true, err
+	}
+	if _, ok := commands[name]; ok {
+		path, err = LookupCommand(name)
+		return path, false, err
+	}
+	path, err = exec.LookPath(name)
+	return path, false, err
+}
+
 // link invokes a linker with the given name and flags.
 func link(linker string, flags ...string) error {
 	if hasBuiltinTools && (linker == "ld.lld" || linker == "wasm-ld") {
@@ -39,7 +57,15 @@ func link(linker string, flags ...string) error {
 
 	// Fall back to external command.
 	if _, ok := commands[linker]; ok {
-		return execCommand(linker, flags...)
+		name, err := LookupCommand(linker)
+		if err != nil {
+			return err
+		}
+		flags = lldFlavorFlags(name, linker, flags)
+		cmd := exec.Command(name, flags...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		return cmd.Run()
 	}
 
 	cmd := exec.Command(linker, flags...)
@@ -48,3 +74,19 @@ func link(linker string, flags ...string) error {
 	cmd.Dir = goenv.Get("TINYGOROOT")
 	return cmd.Run()
 }
+
+// lldFlavorFlags adds the -flavor flag when the linker was found as the generic
+// lld driver (as installed by the LLVM installer on Windows, including Windows
+// on ARM64), which doesn't know what kind of linker to act as. The ld.lld and
+// wasm-ld commands infer this from their name instead.
+func lldFlavorFlags(command, linker string, flags []string) []string {
+	base := strings.TrimSuffix(strings.ToLower(filepath.Base(command)), ".exe")
+	if base != "lld" || (len(flags) != 0 && flags[0] == "-flavor") {
+		return flags
+	}
+	flavor := "gnu"
+	if linker == "wasm-ld" {
+		flavor = "wasm"
+	}
+	return append([]string{"-flavor", flavor}, flags...)
+}
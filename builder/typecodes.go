@@ -0,0 +1,124 @@
+package builder
+
+// This file reads the type codes (the type structs used by interfaces and the
+// reflect package) that are retained in a linked binary. This is useful to
+// audit which types are kept alive because of reflection.
+
+import (
+	"debug/elf"
+	"encoding/hex"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Prefixes of the symbol names of type code globals, as emitted by the
+// compiler (see compiler/interface.go).
+const (
+	typeCodeSymbolPrefix    = "reflect/types.type:"
+	pkgPathSymbolPrefix     = "reflect/types.type.pkgpath"
+	structFieldSymbolPrefix = "reflect/types.structfield:"
+	structTagsSymbol        = "reflect.structTags"
+)
+
+// TypeCodeInfo describes a single type code that was found in a binary.
+type TypeCodeInfo struct {
+	// Name of the type code, such as "named:main.Foo" or "pointer:basic:int".
+	// Data that is stored outside of the type struct is listed as its own
+	// entry: package path strings with a "pkgpath:" prefix and struct field
+	// data (names and tags) with a "structfield:" prefix followed by the
+	// field name. If StructTag.Lookup is used, the data of fields with a tag
+	// in the conventional format is moved into a single table, which is
+	// listed as "structtags".
+	Name string `json:"name"`
+
+	// Size in bytes of the global that holds the type code or data.
+	Size uint64 `json:"size"`
+}
+
+// LoadTypeCodes reads all type codes from the symbol table of the given
+// binary, sorted by name. Only ELF files are supported at the moment, and the
+// binary must not be stripped of its symbol table.
+func LoadTypeCodes(path string) ([]TypeCodeInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	file, err := elf.NewFile(f)
+	if err != nil {
+		return nil, errors.New("could not read type codes: only ELF files are supported")
+	}
+	symbols, err := file.Symbols()
+	if err != nil {
+		return nil, err
+	}
+
+	// Collect all relevant symbols. The same symbol may in rare cases appear
+	// multiple times (for example, for local types), so sum them.
+	types := make(map[string]*TypeCodeInfo)
+	for _, symbol := range symbols {
+		if elf.ST_TYPE(symbol.Info) != elf.STT_OBJECT && elf.ST_TYPE(symbol.Info) != elf.STT_NOTYPE {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(symbol.Name, typeCodeSymbolPrefix):
+			name := symbol.Name[len(typeCodeSymbolPrefix):]
+			if t, ok := types[name]; ok {
+				t.Size += symbol.Size
+			} else {
+				types[name] = &TypeCodeInfo{Name: name, Size: symbol.Size}
+			}
+		case strings.HasPrefix(symbol.Name, pkgPathSymbolPrefix):
+			name := "pkgpath:" + strings.TrimPrefix(symbol.Name[len(pkgPathSymbolPrefix):], ":")
+			if name == "pkgpath:.empty" {
+				name = "pkgpath:"
+			}
+			types[name] = &TypeCodeInfo{Name: name, Size: symbol.Size}
+		case strings.HasPrefix(symbol.Name, structFieldSymbolPrefix):
+			// Struct field data is shared between all struct types with the
+			// same field, so it can't be attributed to a single type. Sum it
+			// per field name instead.
+			name := "structfield:" + structFieldName(symbol.Name[len(structFieldSymbolPrefix):])
+			if t, ok := types[name]; ok {
+				t.Size += symbol.Size
+			} else {
+				types[name] = &TypeCodeInfo{Name: name, Size: symbol.Size}
+			}
+		case symbol.Name == structTagsSymbol:
+			types["structtags"] = &TypeCodeInfo{Name: "structtags", Size: symbol.Size}
+		}
+	}
+
+	result := make([]TypeCodeInfo, 0, len(types))
+	for _, t := range types {
+		result = append(result, *t)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// structFieldName returns the field name from the hex encoded struct field data
+// in a symbol name. The data starts with a flags byte and the field offset as a
+// uvarint, followed by the zero terminated field name.
+func structFieldName(encoded string) string {
+	data, err := hex.DecodeString(encoded)
+	if err != nil || len(data) == 0 {
+		return ""
+	}
+	data = data[1:]
+	for len(data) != 0 && data[0]&0x80 != 0 {
+		data = data[1:]
+	}
+	if len(data) != 0 {
+		data = data[1:]
+	}
+	if end := strings.IndexByte(string(data), 0); end >= 0 {
+		data = data[:end]
+	}
+	return string(data)
+}
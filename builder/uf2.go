@@ -12,17 +12,19 @@ import (
 	"encoding/binary"
 	"os"
 	"strconv"
+
+	"github.com/tinygo-org/tinygo/compileopts"
 )
 
 // convertELFFileToUF2File converts an ELF file to a UF2 file.
-func convertELFFileToUF2File(infile, outfile string, uf2FamilyID string) error {
+func convertELFFileToUF2File(config *compileopts.Config, infile, outfile string) error {
 	// Read the .text segment.
-	targetAddress, data, err := extractROM(infile)
+	targetAddress, data, err := extractImage(config, infile)
 	if err != nil {
 		return err
 	}
 
-	output, _, err := convertBinToUF2(data, uint32(targetAddress), uf2FamilyID)
+	output, _, err := convertBinToUF2(data, uint32(targetAddress), config.Target.UF2FamilyID)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,183 @@
+package cgo
+
+// This file generates the _cgo_export.h header, which declares the Go
+// functions that are exported to C with //export. C files in the package can
+// include this header to call these functions directly or to pass them as a
+// callback (function pointer) to a C library.
+
+import (
+	"go/ast"
+	"strings"
+)
+
+// cTypeNames maps the names of C types in Go (like C.uint) to the type in C,
+// for names that differ between the two.
+var cTypeNames = map[string]string{
+	"schar":     "signed char",
+	"uchar":     "unsigned char",
+	"ushort":    "unsigned short",
+	"uint":      "unsigned int",
+	"ulong":     "unsigned long",
+	"longlong":  "long long",
+	"ulonglong": "unsigned long long",
+}
+
+// goTypeNames maps Go basic types to the equivalent C type. The int and uint
+// types have the size of a pointer in TinyGo.
+var goTypeNames = map[string]string{
+	"bool":    "_Bool",
+	"int":     "intptr_t",
+	"int8":    "int8_t",
+	"int16":   "int16_t",
+	"int32":   "int32_t",
+	"int64":   "int64_t",
+	"uint":    "uintptr_t",
+	"uint8":   "uint8_t",
+	"byte":    "uint8_t",
+	"uint16":  "uint16_t",
+	"uint32":  "uint32_t",
+	"uint64":  "uint64_t",
+	"uintptr": "uintptr_t",
+	"float32": "float",
+	"float64": "double",
+}
+
+// ExportHeader returns the contents of the _cgo_export.h header for the given
+// files, or the empty string if none of the files export a function. It must
+// be called before the files are modified by Process.
+//
+// Like with the gc toolchain, the header includes the preamble of the files
+// that export functions, so that the C types used in the exported functions
+// are declared. This means that these preambles must only contain
+// declarations if the header is included from a C file.
+func ExportHeader(files []*ast.File) string {
+	var preambles, declarations []string
+	for _, file := range files {
+		var exports []string
+		for _, decl := range file.Decls {
+			decl, ok := decl.(*ast.FuncDecl)
+			if !ok || decl.Doc == nil || decl.Recv != nil {
+				continue
+			}
+			for _, comment := range decl.Doc.List {
+				text := comment.Text
+				if !strings.HasPrefix(text, "//export ") && !strings.HasPrefix(text, "//go:export ") {
+					continue
+				}
+				fields := strings.Fields(text)
+				if len(fields) != 2 {
+					continue
+				}
+				exports = append(exports, exportDeclaration(fields[1], decl.Type))
+			}
+		}
+		if len(exports) == 0 {
+			continue
+		}
+		declarations = append(declarations, exports...)
+		for _, decl := range file.Decls {
+			decl, ok := decl.(*ast.GenDecl)
+			if !ok || decl.Doc == nil || len(decl.Specs) != 1 {
+				continue
+			}
+			if spec, ok := decl.Specs[0].(*ast.ImportSpec); ok && spec.Path.Value == `"C"` {
+				preambles = append(preambles, cgoPreamble(decl.Doc))
+			}
+		}
+	}
+	if len(declarations) == 0 {
+		return ""
+	}
+
+	header := "/* Code generated by TinyGo cgo. DO NOT EDIT. */\n\n"
+	header += "#pragma once\n\n"
+	header += "#include <stdint.h>\n\n"
+	for _, preamble := range preambles {
+		header += preamble + "\n"
+	}
+	for _, declaration := range declarations {
+		header += declaration + "\n"
+	}
+	return header
+}
+
+// cgoPreamble returns the C code in the comment above an `import "C"` line,
+// without the #cgo lines.
+func cgoPreamble(doc *ast.CommentGroup) string {
+	var lines []string
+	for _, line := range strings.Split(doc.Text(), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#cgo ") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// exportDeclaration returns the C declaration of the exported function with
+// the given name and type. Functions with types that have no equivalent in C
+// get a comment instead.
+func exportDeclaration(name string, fn *ast.FuncType) string {
+	result := "void"
+	if fn.Results != nil && len(fn.Results.List) != 0 {
+		if len(fn.Results.List) != 1 || len(fn.Results.List[0].Names) > 1 {
+			return "/* " + name + ": cannot export multiple return values to C */"
+		}
+		typ, ok := cTypeName(fn.Results.List[0].Type)
+		if !ok {
+			return "/* " + name + ": cannot export result type to C */"
+		}
+		result = typ
+	}
+	var params []string
+	for _, field := range fn.Params.List {
+		typ, ok := cTypeName(field.Type)
+		if !ok {
+			return "/* " + name + ": cannot export parameter type to C */"
+		}
+		if len(field.Names) == 0 {
+			params = append(params, typ)
+		}
+		for _, paramName := range field.Names {
+			params = append(params, typ+" "+paramName.Name)
+		}
+	}
+	if len(params) == 0 {
+		params = append(params, "void")
+	}
+	return "extern " + result + " " + name + "(" + strings.Join(params, ", ") + ");"
+}
+
+// cTypeName returns the C type for the given Go type expression, if there is
+// one.
+func cTypeName(expr ast.Expr) (string, bool) {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		name, ok := goTypeNames[expr.Name]
+		return name, ok
+	case *ast.StarExpr:
+		elem, ok := cTypeName(expr.X)
+		return elem + "*", ok
+	case *ast.SelectorExpr:
+		x, ok := expr.X.(*ast.Ident)
+		if !ok {
+			return "", false
+		}
+		switch x.Name {
+		case "unsafe":
+			return "void*", expr.Sel.Name == "Pointer"
+		case "C":
+			name := expr.Sel.Name
+			if cname, ok := cTypeNames[name]; ok {
+				return cname, true
+			}
+			for _, prefix := range []string{"struct_", "union_", "enum_"} {
+				if strings.HasPrefix(name, prefix) {
+					return prefix[:len(prefix)-1] + " " + name[len(prefix):], true
+				}
+			}
+			return name, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,70 @@
+package cgo
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+func TestExportHeader(t *testing.T) {
+	const source = `package main
+
+/*
+#cgo CFLAGS: -DFOO
+typedef int (*callback_t)(int);
+struct point { int x, y; };
+*/
+import "C"
+
+import "unsafe"
+
+//export add
+func add(a, b C.int) C.int {
+	return a + b
+}
+
+//export move
+func move(p *C.struct_point, dx int32, data unsafe.Pointer) {
+}
+
+//export pair
+func pair() (int, int) {
+	return 0, 0
+}
+
+// Not exported.
+func helper() {
+}
+`
+	const expected = `/* Code generated by TinyGo cgo. DO NOT EDIT. */
+
+#pragma once
+
+#include <stdint.h>
+
+typedef int (*callback_t)(int);
+struct point { int x, y; };
+
+extern int add(int a, int b);
+extern void move(struct point* p, int32_t dx, void* data);
+/* pair: cannot export multiple return values to C */
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "main.go", source, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := ExportHeader([]*ast.File{f})
+	if header != expected {
+		t.Errorf("unexpected header:\n%s\nexpected:\n%s", header, expected)
+	}
+
+	f, err = parser.ParseFile(fset, "main.go", "package main\n\nimport \"C\"\n\nfunc main() {}\n", parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header := ExportHeader([]*ast.File{f}); header != "" {
+		t.Errorf("expected no header, got:\n%s", header)
+	}
+}
@@ -74,11 +74,25 @@ func (c *Config) GOARM() string {
 }
 
 // BuildTags returns the complete list of build tags used during this build.
+//
+// The purego tag is the conventional way for packages to select their generic
+// Go code instead of Go assembly (golang.org/x/crypto, for example), which
+// TinyGo can't compile. Packages that don't have such a fallback are reported
+// by loader.Program.CheckAssembly, but only if their assembly is actually used.
 func (c *Config) BuildTags() []string {
-	tags := append(c.Target.BuildTags, []string{"tinygo", "math_big_pure_go", "gc." + c.GC(), "scheduler." + c.Scheduler(), "serial." + c.Serial()}...)
+	tags := append(c.Target.BuildTags, []string{"tinygo", "purego", "math_big_pure_go", "gc." + c.GC(), "scheduler." + c.Scheduler(), "serial." + c.Serial()}...)
 	for i := 1; i <= c.GoMinorVersion; i++ {
 		tags = append(tags, fmt.Sprintf("go1.%d", i))
 	}
+	if c.MemoryProtection() {
+		tags = append(tags, "memory_protection")
+	}
+	if c.PanicStrategy() == "trace" {
+		tags = append(tags, "panic_trace")
+	}
+	if c.Options.Race {
+		tags = append(tags, "race")
+	}
 	tags = append(tags, c.Options.Tags...)
 	return tags
 }
@@ -165,8 +179,8 @@ func (c *Config) OptLevels() (optLevel, sizeLevel int, inlinerThreshold uint) {
 }
 
 // PanicStrategy returns the panic strategy selected for this target. Valid
-// values are "print" (print the panic value, then exit) or "trap" (issue a trap
-// instruction).
+// values are "print" (print the panic value, then exit), "trace" (like print,
+// but also print a stack trace) or "trap" (issue a trap instruction).
 func (c *Config) PanicStrategy() string {
 	return c.Options.PanicStrategy
 }
@@ -190,6 +204,16 @@ func (c *Config) StackSize() uint64 {
 	return c.Target.DefaultStackSize
 }
 
+// MemoryProtection returns whether the runtime should configure the memory
+// protection unit at startup, to trap null pointer dereferences and writes to
+// code. This is only supported on Cortex-M and 32-bit RISC-V chips.
+func (c *Config) MemoryProtection() bool {
+	if c.Target.MemoryProtection != nil {
+		return *c.Target.MemoryProtection
+	}
+	return false
+}
+
 // RP2040BootPatch returns whether the RP2040 boot patch should be applied that
 // calculates and patches in the checksum for the 2nd stage bootloader.
 func (c *Config) RP2040BootPatch() bool {
@@ -550,4 +574,6 @@ type TestConfig struct {
 	BenchTime         string
 	BenchMem          bool
 	Shuffle           string
+	CoverMode         string // "set" or "count" when code coverage is enabled
+	CoverProfile      string // file to write the coverage profile to
 }
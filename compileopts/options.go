@@ -12,47 +12,68 @@ var (
 	validSchedulerOptions     = []string{"none", "tasks", "asyncify"}
 	validSerialOptions        = []string{"none", "uart", "usb"}
 	validPrintSizeOptions     = []string{"none", "short", "full"}
-	validPanicStrategyOptions = []string{"print", "trap"}
+	validPanicStrategyOptions = []string{"print", "trace", "trap"}
 	validOptOptions           = []string{"none", "0", "1", "2", "s", "z"}
+	validCoverModeOptions     = []string{"set", "count"}
 )
 
 // Options contains extra options to give to the compiler. These options are
 // usually passed from the command line, but can also be passed in environment
 // variables for example.
 type Options struct {
-	GOOS            string // environment variable
-	GOARCH          string // environment variable
-	GOARM           string // environment variable (only used with GOARCH=arm)
-	Target          string
-	Opt             string
-	GC              string
-	PanicStrategy   string
-	Scheduler       string
-	StackSize       uint64 // goroutine stack size (if none could be automatically determined)
-	Serial          string
-	Work            bool // -work flag to print temporary build directory
-	InterpTimeout   time.Duration
-	PrintIR         bool
-	DumpSSA         bool
-	VerifyIR        bool
-	SkipDWARF       bool
-	PrintCommands   func(cmd string, args ...string) `json:"-"`
-	Semaphore       chan struct{}                    `json:"-"` // -p flag controls cap
-	Debug           bool
-	PrintSizes      string
-	PrintAllocs     *regexp.Regexp // regexp string
-	PrintStacks     bool
-	Tags            []string
-	GlobalValues    map[string]map[string]string // map[pkgpath]map[varname]value
-	TestConfig      TestConfig
-	Programmer      string
-	OpenOCDCommands []string
-	LLVMFeatures    string
-	Directory       string
-	PrintJSON       bool
-	Monitor         bool
-	BaudRate        int
-	Timeout         time.Duration
+	GOOS               string // environment variable
+	GOARCH             string // environment variable
+	GOARM              string // environment variable (only used with GOARCH=arm)
+	Target             string
+	StrictTarget       bool // unknown properties in target files are errors instead of warnings
+	Opt                string
+	GC                 string
+	PanicStrategy      string
+	Race               bool // detect data races between goroutines (-race)
+	Scheduler          string
+	StackSize          uint64 // goroutine stack size (if none could be automatically determined)
+	Serial             string
+	Work               bool // -work flag to print temporary build directory
+	InterpTimeout      time.Duration
+	PrintIR            bool
+	DumpSSA            bool
+	VerifyIR           bool
+	SkipDWARF          bool
+	NoTypeStrings      bool
+	PrintCommands      func(cmd string, args ...string) `json:"-"`
+	Semaphore          chan struct{}                    `json:"-"` // -p flag controls cap
+	Debug              bool
+	PrintSizes         string
+	MapFile            string         // -map flag to write a linker map
+	PrintAllocs        *regexp.Regexp // regexp string
+	PrintFloat64       *regexp.Regexp // regexp string
+	ReflectInclude     *regexp.Regexp // types for which reflect metadata is always kept (-reflect-include)
+	PrintStacks        bool
+	Tags               []string
+	GlobalValues       map[string]map[string]string // map[pkgpath]map[varname]value
+	TestConfig         TestConfig
+	Programmer         string
+	OpenOCDCommands    []string
+	ExtFlashPackages   []string
+	LLVMFeatures       string
+	Directory          string
+	PrintJSON          bool
+	Monitor            bool
+	BaudRate           int
+	Timeout            time.Duration
+	ImageVersion       uint64   // version number for the image header, see ImageHeader
+	MCUbootKey         string   // private key to sign an MCUboot image with
+	MCUbootVersion     string   // MCUboot image version, like 1.2.3+4
+	ESPSecureBootKey   string   // RSA-3072 key to sign ESP32 images for secure boot v2
+	ESPFlashEncryption bool     // flash ESP32 images with flash encryption
+	CodeOffset         uint64   // move the start of the FLASH_TEXT memory region (-code-offset)
+	RAMOffset          uint64   // move the start of the RAM memory region (-ram-offset)
+	WarnStackFrame     uint64   // warn about stack frames larger than this (-warn-stack-frame)
+	WarnAllocInLoop    bool     // warn about heap allocations in loops (-Wheap-alloc-in-loop)
+	AllocInLoopErrors  []string // packages in which heap allocations in loops are errors
+	RunEnv             []string // extra environment variables for run and test (-env)
+	RunArgs            []string // extra command line arguments for run and test (-arg)
+	RunDirs            []string // host directories made available to WASI programs (-dir)
 }
 
 // Verify performs a validation on the given options, raising an error if options are not valid.
@@ -108,6 +129,12 @@ func (o *Options) Verify() error {
 		}
 	}
 
+	if o.TestConfig.CoverMode != "" {
+		if !isInArray(validCoverModeOptions, o.TestConfig.CoverMode) {
+			return fmt.Errorf("invalid -covermode=%s: valid values are %s", o.TestConfig.CoverMode, strings.Join(validCoverModeOptions, ", "))
+		}
+	}
+
 	return nil
 }
 
@@ -12,7 +12,8 @@ func TestVerifyOptions(t *testing.T) {
 	expectedGCError := errors.New(`invalid gc option 'incorrect': valid values are none, leaking, conservative, custom, precise`)
 	expectedSchedulerError := errors.New(`invalid scheduler option 'incorrect': valid values are none, tasks, asyncify`)
 	expectedPrintSizeError := errors.New(`invalid size option 'incorrect': valid values are none, short, full`)
-	expectedPanicStrategyError := errors.New(`invalid panic option 'incorrect': valid values are print, trap`)
+	expectedPanicStrategyError := errors.New(`invalid panic option 'incorrect': valid values are print, trace, trap`)
+	expectedCoverModeError := errors.New(`invalid -covermode=atomic: valid values are set, count`)
 
 	testCases := []struct {
 		name          string
@@ -111,12 +112,31 @@ func TestVerifyOptions(t *testing.T) {
 				PanicStrategy: "print",
 			},
 		},
+		{
+			name: "PanicOptionTrace",
+			opts: compileopts.Options{
+				PanicStrategy: "trace",
+			},
+		},
 		{
 			name: "PanicOptionTrap",
 			opts: compileopts.Options{
 				PanicStrategy: "trap",
 			},
 		},
+		{
+			name: "InvalidCoverModeOption",
+			opts: compileopts.Options{
+				TestConfig: compileopts.TestConfig{CoverMode: "atomic"},
+			},
+			expectedError: expectedCoverModeError,
+		},
+		{
+			name: "CoverModeOptionCount",
+			opts: compileopts.Options{
+				TestConfig: compileopts.TestConfig{CoverMode: "count"},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
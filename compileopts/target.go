@@ -12,6 +12,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"sort"
 	"strings"
 
 	"github.com/tinygo-org/tinygo/goenv"
@@ -19,53 +20,117 @@ import (
 
 // Target specification for a given target. Used for bare metal targets.
 //
+// Target specifications are loaded from JSON files, see targets/target.schema.json
+// for a description of all properties. A target may inherit from other targets
+// using the "inherits" property, see overrideProperties for how properties are
+// combined.
+//
 // The target specification is mostly inspired by Rust:
 // https://doc.rust-lang.org/nightly/nightly-rustc/rustc_target/spec/struct.TargetOptions.html
 // https://github.com/shepmaster/rust-arduino-blink-led-no-core-with-cargo/blob/master/blink/arduino.json
 type TargetSpec struct {
-	Inherits         []string `json:"inherits"`
-	Triple           string   `json:"llvm-target"`
-	CPU              string   `json:"cpu"`
-	ABI              string   `json:"target-abi"` // rougly equivalent to -mabi= flag
-	Features         string   `json:"features"`
-	GOOS             string   `json:"goos"`
-	GOARCH           string   `json:"goarch"`
-	BuildTags        []string `json:"build-tags"`
-	GC               string   `json:"gc"`
-	Scheduler        string   `json:"scheduler"`
-	Serial           string   `json:"serial"` // which serial output to use (uart, usb, none)
-	Linker           string   `json:"linker"`
-	RTLib            string   `json:"rtlib"` // compiler runtime library (libgcc, compiler-rt)
-	Libc             string   `json:"libc"`
-	AutoStackSize    *bool    `json:"automatic-stack-size"` // Determine stack size automatically at compile time.
-	DefaultStackSize uint64   `json:"default-stack-size"`   // Default stack size if the size couldn't be determined at compile time.
-	CFlags           []string `json:"cflags"`
-	LDFlags          []string `json:"ldflags"`
-	LinkerScript     string   `json:"linkerscript"`
-	ExtraFiles       []string `json:"extra-files"`
-	RP2040BootPatch  *bool    `json:"rp2040-boot-patch"` // Patch RP2040 2nd stage bootloader checksum
-	Emulator         string   `json:"emulator"`
-	FlashCommand     string   `json:"flash-command"`
-	GDB              []string `json:"gdb"`
-	PortReset        string   `json:"flash-1200-bps-reset"`
-	SerialPort       []string `json:"serial-port"` // serial port IDs in the form "vid:pid"
-	FlashMethod      string   `json:"flash-method"`
-	FlashVolume      []string `json:"msd-volume-name"`
-	FlashFilename    string   `json:"msd-firmware-name"`
-	UF2FamilyID      string   `json:"uf2-family-id"`
-	BinaryFormat     string   `json:"binary-format"`
-	OpenOCDInterface string   `json:"openocd-interface"`
-	OpenOCDTarget    string   `json:"openocd-target"`
-	OpenOCDTransport string   `json:"openocd-transport"`
-	OpenOCDCommands  []string `json:"openocd-commands"`
-	OpenOCDVerify    *bool    `json:"openocd-verify"` // enable verify when flashing with openocd
-	JLinkDevice      string   `json:"jlink-device"`
-	CodeModel        string   `json:"code-model"`
-	RelocationModel  string   `json:"relocation-model"`
-	WasmAbi          string   `json:"wasm-abi"`
+	Inherits          []string     `json:"inherits"`
+	Triple            string       `json:"llvm-target"`
+	CPU               string       `json:"cpu"`
+	ABI               string       `json:"target-abi"` // rougly equivalent to -mabi= flag
+	Features          string       `json:"features"`
+	GOOS              string       `json:"goos"`
+	GOARCH            string       `json:"goarch"`
+	BuildTags         []string     `json:"build-tags"`
+	GC                string       `json:"gc"`
+	Scheduler         string       `json:"scheduler"`
+	Serial            string       `json:"serial"` // which serial output to use (uart, usb, none)
+	Linker            string       `json:"linker"`
+	RTLib             string       `json:"rtlib"` // compiler runtime library (libgcc, compiler-rt)
+	Libc              string       `json:"libc"`
+	AutoStackSize     *bool        `json:"automatic-stack-size"` // Determine stack size automatically at compile time.
+	DefaultStackSize  uint64       `json:"default-stack-size"`   // Default stack size if the size couldn't be determined at compile time.
+	CFlags            []string     `json:"cflags"`
+	LDFlags           []string     `json:"ldflags"`
+	LinkerScript      string       `json:"linkerscript"`
+	ExtraFiles        []string     `json:"extra-files"`
+	RP2040BootPatch   *bool        `json:"rp2040-boot-patch"` // Patch RP2040 2nd stage bootloader checksum
+	MemoryProtection  *bool        `json:"memory-protection"` // Guard the null page and make code read-only using the MPU/PMP
+	Emulator          string       `json:"emulator"`
+	FlashCommand      string       `json:"flash-command"`
+	GDB               []string     `json:"gdb"`
+	PortReset         string       `json:"flash-1200-bps-reset"`
+	SerialPort        []string     `json:"serial-port"` // serial port IDs in the form "vid:pid"
+	FlashMethod       string       `json:"flash-method"`
+	FlashVolume       []string     `json:"msd-volume-name"`
+	FlashFilename     string       `json:"msd-firmware-name"`
+	UF2FamilyID       string       `json:"uf2-family-id"`
+	BinaryFormat      string       `json:"binary-format"`
+	OpenOCDInterface  string       `json:"openocd-interface"`
+	OpenOCDTarget     string       `json:"openocd-target"`
+	OpenOCDTransport  string       `json:"openocd-transport"`
+	OpenOCDCommands   []string     `json:"openocd-commands"`
+	OpenOCDVerify     *bool        `json:"openocd-verify"` // enable verify when flashing with openocd
+	JLinkDevice       string       `json:"jlink-device"`
+	CodeModel         string       `json:"code-model"`
+	RelocationModel   string       `json:"relocation-model"`
+	WasmAbi           string       `json:"wasm-abi"`
+	ImageHeader       *ImageHeader `json:"image-header"`        // header written into .bin, .hex and .uf2 images
+	MCUbootHeaderSize uint32       `json:"mcuboot-header-size"` // space reserved for the MCUboot image header (0x200 by default)
+
+	// Warnings found while loading the target specification, such as unknown
+	// properties. They are not printed by LoadTarget, that is left to the
+	// caller.
+	Warnings []string `json:"-"`
+}
+
+// ImageHeader describes a header that is written into the firmware image, for
+// ROM bootloaders (or second stage bootloaders) that check the image before
+// running it. It is applied to .bin, .hex and .uf2 output files, but not to ELF
+// files.
+type ImageHeader struct {
+	// Number of bytes inserted at the start of the image, to make room for the
+	// header. The image must be linked at this offset from the load address
+	// expected by the bootloader. If zero, the header is written into space
+	// that is already reserved by the linker script, like in the vector table.
+	Prepend uint32 `json:"prepend"`
+
+	// Pad the image to a multiple of this many bytes, using PadByte (0xff by
+	// default, which is what erased flash reads as).
+	Align   uint32 `json:"align"`
+	PadByte *uint8 `json:"pad-byte"`
+
+	// Fields written into the header, in order. A checksum can include fields
+	// that are listed before it.
+	Fields []ImageHeaderField `json:"fields"`
+}
+
+// ImageHeaderField is a single field in an image header.
+type ImageHeaderField struct {
+	// Type of the field: "magic" (a constant value), "length" (the length of
+	// the image from Start), "version" (the value of the -image-version flag),
+	// "crc32" (IEEE CRC-32 of the image from Start) or "sha256".
+	Type string `json:"type"`
+
+	// Offset of the field from the start of the image (including prepended
+	// bytes).
+	Offset uint32 `json:"offset"`
+
+	// Size in bytes of an integer field (1, 2, 4 or 8). The default is 4.
+	Size uint32 `json:"size"`
+
+	// Value of a magic field.
+	Value uint64 `json:"value"`
+
+	// Start of the range of the image that a length or checksum field covers.
+	// The range extends to the end of the (padded) image and must not include
+	// the field itself.
+	Start uint32 `json:"start"`
+
+	// Store integer fields in big endian byte order instead of little endian.
+	BigEndian bool `json:"big-endian"`
 }
 
 // overrideProperties overrides all properties that are set in child into itself using reflection.
+// Strings and integers are only replaced if they are non-empty or non-zero,
+// pointers (booleans and objects like ImageHeader) are replaced if they are
+// set, and slices are appended to. It is an error for a slice to contain the
+// same value twice, so a target cannot remove values that it inherits.
 func (spec *TargetSpec) overrideProperties(child *TargetSpec) error {
 	specType := reflect.TypeOf(spec).Elem()
 	specValue := reflect.ValueOf(spec).Elem()
@@ -108,14 +173,117 @@ func (spec *TargetSpec) overrideProperties(child *TargetSpec) error {
 }
 
 // load reads a target specification from the JSON in the given io.Reader. It
-// may load more targets specified using the "inherits" property.
-func (spec *TargetSpec) load(r io.Reader) error {
-	err := json.NewDecoder(r).Decode(spec)
+// may load more targets specified using the "inherits" property. It returns a
+// warning for each unknown property, which is most likely a typo.
+func (spec *TargetSpec) load(r io.Reader) ([]string, error) {
+	data, err := io.ReadAll(r)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	err = json.Unmarshal(data, spec)
+	if err != nil {
+		return nil, err
 	}
+	return checkTargetProperties(data, reflect.TypeOf(spec), ""), nil
+}
 
-	return nil
+// checkTargetProperties returns a warning for each property in the JSON data
+// that doesn't match a field of the given type, recursively. The data must
+// already have been successfully decoded into this type.
+func checkTargetProperties(data []byte, typ reflect.Type, prefix string) []string {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	var warnings []string
+	switch typ.Kind() {
+	case reflect.Struct:
+		var properties map[string]json.RawMessage
+		json.Unmarshal(data, &properties) // can't fail, data was already decoded into typ
+		fields := make(map[string]reflect.Type)
+		var names []string
+		for i := 0; i < typ.NumField(); i++ {
+			name := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+			if name != "" && name != "-" {
+				fields[name] = typ.Field(i).Type
+				names = append(names, name)
+			}
+		}
+		keys := make([]string, 0, len(properties))
+		for key := range properties {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fieldType, ok := fields[key]
+			if !ok {
+				if prefix == "" && key == "$schema" {
+					// Allowed in the top level object, for editor support.
+					continue
+				}
+				warning := fmt.Sprintf("unknown property %q", prefix+key)
+				if suggestion := closestName(key, names); suggestion != "" {
+					warning += fmt.Sprintf(" (did you mean %q?)", suggestion)
+				}
+				warnings = append(warnings, warning)
+				continue
+			}
+			warnings = append(warnings, checkTargetProperties(properties[key], fieldType, prefix+key+".")...)
+		}
+	case reflect.Slice:
+		var elements []json.RawMessage
+		json.Unmarshal(data, &elements) // can't fail, see above
+		for i, element := range elements {
+			elementPrefix := fmt.Sprintf("%s[%d].", strings.TrimSuffix(prefix, "."), i)
+			warnings = append(warnings, checkTargetProperties(element, typ.Elem(), elementPrefix)...)
+		}
+	}
+	return warnings
+}
+
+// closestName returns the name that is most similar to s, or the empty string
+// if none of the names look like a misspelling of s.
+func closestName(s string, names []string) string {
+	best := ""
+	bestDistance := len(s)/3 + 1
+	for _, name := range names {
+		if d := editDistance(s, name); d < bestDistance {
+			best = name
+			bestDistance = d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	row := make([]int, len(b)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		prev := row[0]
+		row[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			next := row[j]
+			row[j] = min3(row[j]+1, row[j-1]+1, prev+cost)
+			prev = next
+		}
+	}
+	return row[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
 }
 
 // loadFromGivenStr loads the TargetSpec from the given string that could be:
@@ -123,7 +291,7 @@ func (spec *TargetSpec) load(r io.Reader) error {
 //   - a relative or absolute path to custom (project specific) target specification .json file;
 //     the Inherits[] could contain the files from target folder (ex. stm32f4disco)
 //     as well as path to custom files (ex. myAwesomeProject.json)
-func (spec *TargetSpec) loadFromGivenStr(str string) error {
+func (spec *TargetSpec) loadFromGivenStr(str string) ([]string, error) {
 	path := ""
 	if strings.HasSuffix(str, ".json") {
 		path, _ = filepath.Abs(str)
@@ -132,40 +300,51 @@ func (spec *TargetSpec) loadFromGivenStr(str string) error {
 	}
 	fp, err := os.Open(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer fp.Close()
-	return spec.load(fp)
+	warnings, err := spec.load(fp)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i, warning := range warnings {
+		warnings[i] = path + ": " + warning
+	}
+	return warnings, nil
 }
 
-// resolveInherits loads inherited targets, recursively.
-func (spec *TargetSpec) resolveInherits() error {
+// resolveInherits loads inherited targets, recursively. It returns the
+// warnings of all inherited target files.
+func (spec *TargetSpec) resolveInherits() ([]string, error) {
 	// First create a new spec with all the inherited properties.
 	newSpec := &TargetSpec{}
+	var warnings []string
 	for _, name := range spec.Inherits {
 		subtarget := &TargetSpec{}
-		err := subtarget.loadFromGivenStr(name)
+		subWarnings, err := subtarget.loadFromGivenStr(name)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		err = subtarget.resolveInherits()
+		warnings = append(warnings, subWarnings...)
+		subWarnings, err = subtarget.resolveInherits()
 		if err != nil {
-			return err
+			return nil, err
 		}
+		warnings = append(warnings, subWarnings...)
 		err = newSpec.overrideProperties(subtarget)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
 	// When all properties are loaded, make sure they are properly inherited.
 	err := newSpec.overrideProperties(spec)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	*spec = *newSpec
 
-	return nil
+	return warnings, nil
 }
 
 // Load a target specification.
@@ -224,16 +403,23 @@ func LoadTarget(options *Options) (*TargetSpec, error) {
 	// See whether there is a target specification for this target (e.g.
 	// Arduino).
 	spec := &TargetSpec{}
-	err := spec.loadFromGivenStr(options.Target)
+	warnings, err := spec.loadFromGivenStr(options.Target)
 	if err != nil {
 		return nil, err
 	}
 	// Successfully loaded this target from a built-in .json file. Make sure
 	// it includes all parents as specified in the "inherits" key.
-	err = spec.resolveInherits()
+	inheritedWarnings, err := spec.resolveInherits()
 	if err != nil {
 		return nil, fmt.Errorf("%s : %w", options.Target, err)
 	}
+	warnings = append(warnings, inheritedWarnings...)
+	if len(warnings) != 0 {
+		if options.StrictTarget {
+			return nil, errors.New(strings.Join(warnings, "\n"))
+		}
+		spec.Warnings = warnings
+	}
 
 	if spec.Scheduler == "asyncify" {
 		spec.ExtraFiles = append(spec.ExtraFiles, "src/internal/task/task_asyncify_wasm.S")
@@ -1,9 +1,14 @@
 package compileopts
 
 import (
+	"encoding/json"
 	"errors"
 	"io/fs"
+	"os"
+	"path/filepath"
 	"reflect"
+	"sort"
+	"strings"
 	"testing"
 )
 
@@ -81,3 +86,112 @@ func TestOverrideProperties(t *testing.T) {
 	}
 
 }
+
+func TestCheckTargetProperties(t *testing.T) {
+	spec := &TargetSpec{}
+	warnings, err := spec.load(strings.NewReader(`{
+		"$schema": "target.schema.json",
+		"cpu": "cortex-m4",
+		"cflag": ["-Os"],
+		"image-header": {"fields": [{"type": "magic", "ofset": 4}]},
+		"foobar": true
+	}`))
+	if err != nil {
+		t.Fatal("could not load target:", err)
+	}
+	expected := []string{
+		`unknown property "cflag" (did you mean "cflags"?)`,
+		`unknown property "foobar"`,
+		`unknown property "image-header.fields[0].ofset" (did you mean "offset"?)`,
+	}
+	if !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("unexpected warnings:\n got: %q\nwant: %q", warnings, expected)
+	}
+	if spec.CPU != "cortex-m4" || spec.ImageHeader.Fields[0].Type != "magic" {
+		t.Errorf("known properties were not loaded: %+v", spec)
+	}
+}
+
+func TestLoadTargetWarnings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.json")
+	err := os.WriteFile(path, []byte(`{"goos": "linux", "goarch": "arm", "foobar": true}`), 0o666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := LoadTarget(&Options{Target: path})
+	if err != nil {
+		t.Fatal("could not load target:", err)
+	}
+	expected := []string{path + `: unknown property "foobar"`}
+	if !reflect.DeepEqual(spec.Warnings, expected) {
+		t.Errorf("unexpected warnings:\n got: %q\nwant: %q", spec.Warnings, expected)
+	}
+	_, err = LoadTarget(&Options{Target: path, StrictTarget: true})
+	if err == nil || err.Error() != expected[0] {
+		t.Errorf("expected error %q with StrictTarget, got %v", expected[0], err)
+	}
+}
+
+func TestTargetFiles(t *testing.T) {
+	paths, err := filepath.Glob("../targets/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		if strings.HasSuffix(path, ".schema.json") {
+			continue
+		}
+		warnings, err := (&TargetSpec{}).loadFromGivenStr(path)
+		if err != nil {
+			t.Error("could not load target:", err)
+		}
+		for _, warning := range warnings {
+			t.Error(warning)
+		}
+	}
+}
+
+// Check that the JSON schema describes exactly the properties of the target
+// specification.
+func TestTargetSchema(t *testing.T) {
+	data, err := os.ReadFile("../targets/target.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	type object struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	var schema struct {
+		object
+		Definitions map[string]object `json:"definitions"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal("could not parse schema:", err)
+	}
+	for _, tc := range []struct {
+		name   string
+		object object
+		typ    reflect.Type
+	}{
+		{"target", schema.object, reflect.TypeOf(TargetSpec{})},
+		{"imageHeader", schema.Definitions["imageHeader"], reflect.TypeOf(ImageHeader{})},
+		{"imageHeaderField", schema.Definitions["imageHeaderField"], reflect.TypeOf(ImageHeaderField{})},
+	} {
+		var fields, properties []string
+		for i := 0; i < tc.typ.NumField(); i++ {
+			if name := tc.typ.Field(i).Tag.Get("json"); name != "-" {
+				fields = append(fields, name)
+			}
+		}
+		for name := range tc.object.Properties {
+			if name != "$schema" {
+				properties = append(properties, name)
+			}
+		}
+		sort.Strings(fields)
+		sort.Strings(properties)
+		if !reflect.DeepEqual(fields, properties) {
+			t.Errorf("%s: schema properties don't match struct fields:\n  schema: %v\n  fields: %v", tc.name, properties, fields)
+		}
+	}
+}
@@ -252,6 +252,7 @@ func (b *builder) createRuntimeAssert(assert llvm.Value, blockPrefix, assertFunc
 
 	// Fail: the assert triggered so panic.
 	b.SetInsertPointAtEnd(faultBlock)
+	b.createTraceSite()
 	b.createRuntimeCall(assertFunc, nil, "")
 	b.CreateUnreachable()
 
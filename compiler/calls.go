@@ -77,6 +77,7 @@ func (b *builder) createCall(fnType llvm.Type, fn llvm.Value, args []llvm.Value,
 // createInvoke is like createCall but continues execution at the landing pad if
 // the call resulted in a panic.
 func (b *builder) createInvoke(fnType llvm.Type, fn llvm.Value, args []llvm.Value, name string) llvm.Value {
+	b.createTraceSite()
 	if b.hasDeferFrame() {
 		b.createInvokeCheckpoint()
 	}
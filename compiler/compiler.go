@@ -54,7 +54,12 @@ type Config struct {
 	AutomaticStackSize bool
 	DefaultStackSize   uint64
 	NeedsStackObjects  bool
-	Debug              bool // Whether to emit debug information in the LLVM module.
+	Debug              bool   // Whether to emit debug information in the LLVM module.
+	NoTypeStrings      bool   // Don't emit reflect.Type.String() of function and interface types.
+	PanicTrace         bool   // Keep track of call sites to print stack traces on panic.
+	Race               bool   // Instrument memory accesses to detect data races.
+	CoverMode          string // Code coverage mode ("set" or "count"), or empty if disabled.
+	CoverPackage       string // Import path of the package to instrument for code coverage.
 }
 
 // compilerContext contains function-independent data that should still be
@@ -85,6 +90,11 @@ type compilerContext struct {
 	functionInfos    map[*ssa.Function]functionInfo
 	astComments      map[string]*ast.CommentGroup
 	embedGlobals     map[string][]*loader.EmbedFile
+	loaderPkg        *loader.Package // used to look up //go:linkname directives
+	traceFuncs       map[string]llvm.Value
+	constStrings     map[string]llvm.Value
+	coverBlocks      []coverBlock
+	coverGlobal      llvm.Value
 	pkg              *types.Package
 	packageDir       string // directory for this package
 	runtimePkg       *types.Package
@@ -102,6 +112,8 @@ func newCompilerContext(moduleName string, machine llvm.TargetMachine, config *C
 		targetData:    machine.CreateTargetData(),
 		functionInfos: map[*ssa.Function]functionInfo{},
 		astComments:   map[string]*ast.CommentGroup{},
+		traceFuncs:    make(map[string]llvm.Value),
+		constStrings:  make(map[string]llvm.Value),
 	}
 
 	c.ctx = llvm.NewContext()
@@ -157,11 +169,17 @@ type builder struct {
 	deferPtr          llvm.Value
 	deferFrame        llvm.Value
 	stackChainAlloca  llvm.Value
+	traceFrame        llvm.Value
+	tracePos          token.Pos // position of the instruction being compiled
+	traceSites        map[string]llvm.Value
+	raceInstrument    bool // check memory accesses in this function for data races
 	landingpad        llvm.BasicBlock
 	difunc            llvm.Metadata
 	dilocals          map[*types.Var]llvm.Metadata
-	initInlinedAt     llvm.Metadata            // fake inlinedAt position
-	initPseudoFuncs   map[string]llvm.Metadata // fake "inlined" functions for proper init debug locations
+	discopes          map[*types.Scope]llvm.Metadata // lexical blocks in the function
+	funcScope         *types.Scope                   // scope of the function body (for debug info)
+	initInlinedAt     llvm.Metadata                  // fake inlinedAt position
+	initPseudoFuncs   map[string]llvm.Metadata       // fake "inlined" functions for proper init debug locations
 	allDeferFuncs     []interface{}
 	deferFuncs        map[*ssa.Function]int
 	deferInvokeFuncs  map[string]int
@@ -184,6 +202,7 @@ func newBuilder(c *compilerContext, irbuilder llvm.Builder, f *ssa.Function) *bu
 		info:            c.getFunctionInfo(f),
 		locals:          make(map[ssa.Value]llvm.Value),
 		dilocals:        make(map[*types.Var]llvm.Metadata),
+		discopes:        make(map[*types.Scope]llvm.Metadata),
 		blockEntries:    make(map[*ssa.BasicBlock]llvm.BasicBlock),
 		blockExits:      make(map[*ssa.BasicBlock]llvm.BasicBlock),
 	}
@@ -271,12 +290,15 @@ func Sizes(machine llvm.TargetMachine) types.Sizes {
 	}
 }
 
-// CompilePackage compiles a single package to a LLVM module.
+// CompilePackage compiles a single package to a LLVM module. Warnings are
+// returned as soft errors (types.Error with Soft set), which don't prevent the
+// module from being used.
 func CompilePackage(moduleName string, pkg *loader.Package, ssaPkg *ssa.Package, machine llvm.TargetMachine, config *Config, dumpSSA bool) (llvm.Module, []error) {
 	c := newCompilerContext(moduleName, machine, config, dumpSSA)
 	defer c.dispose()
 	c.packageDir = pkg.OriginalDir()
 	c.embedGlobals = pkg.EmbedGlobals
+	c.loaderPkg = pkg
 	c.pkg = pkg.Pkg
 	c.runtimePkg = ssaPkg.Prog.ImportedPackage("runtime").Pkg
 	c.program = ssaPkg.Prog
@@ -298,6 +320,11 @@ func CompilePackage(moduleName string, pkg *loader.Package, ssaPkg *ssa.Package,
 	// Load comments such as //go:extern on globals.
 	c.loadASTComments(pkg)
 
+	// Find the blocks of code to instrument for code coverage.
+	if c.CoverMode != "" && pkg.ImportPath == c.CoverPackage {
+		c.createCoverBlocks(pkg)
+	}
+
 	// Predeclare the runtime.alloc function, which is used by the wordpack
 	// functionality.
 	c.getFunction(c.program.ImportedPackage("runtime").Members["alloc"].(*ssa.Function))
@@ -716,7 +743,47 @@ func (b *builder) setDebugLocation(pos token.Pos) {
 	}
 
 	// Regular debug information.
-	b.SetCurrentDebugLocation(uint(position.Line), uint(position.Column), b.difunc, llvm.Metadata{})
+	var scope *types.Scope
+	if b.funcScope != nil {
+		scope = b.funcScope.Innermost(pos)
+	}
+	b.SetCurrentDebugLocation(uint(position.Line), uint(position.Column), b.getDIScope(scope), llvm.Metadata{})
+}
+
+// getDIScope returns the debug info scope for the given Go scope: a lexical
+// block for a block inside the function, so that debuggers know where a local
+// variable is visible, or the function itself otherwise.
+func (b *builder) getDIScope(scope *types.Scope) llvm.Metadata {
+	if scope == nil || b.funcScope == nil || scope == b.funcScope || !b.funcScope.Contains(scope.Pos()) {
+		return b.difunc
+	}
+	if discope, ok := b.discopes[scope]; ok {
+		return discope
+	}
+	pos := b.program.Fset.Position(scope.Pos())
+	discope := b.dibuilder.CreateLexicalBlock(b.getDIScope(scope.Parent()), llvm.DILexicalBlock{
+		File:   b.getDIFile(pos.Filename),
+		Line:   pos.Line,
+		Column: pos.Column,
+	})
+	b.discopes[scope] = discope
+	return discope
+}
+
+// getFuncScope returns the scope of the body of the current function, or nil
+// if it can't be found.
+func (b *builder) getFuncScope() *types.Scope {
+	var body *ast.BlockStmt
+	switch syntax := b.fn.Syntax().(type) {
+	case *ast.FuncDecl:
+		body = syntax.Body
+	case *ast.FuncLit:
+		body = syntax.Body
+	}
+	if body == nil || b.fn.Pkg == nil {
+		return nil
+	}
+	return b.fn.Pkg.Pkg.Scope().Innermost(body.Pos())
 }
 
 // getLocalVariable returns a debug info entry for a local variable, which may
@@ -748,7 +815,7 @@ func (b *builder) getLocalVariable(variable *types.Var) llvm.Metadata {
 	}
 
 	// No, it's not a parameter. Create a regular (auto) variable.
-	dilocal := b.dibuilder.CreateAutoVariable(b.difunc, llvm.DIAutoVariable{
+	dilocal := b.dibuilder.CreateAutoVariable(b.getDIScope(variable.Parent()), llvm.DIAutoVariable{
 		Name:           variable.Name(),
 		File:           b.getDIFile(pos.Filename),
 		Line:           pos.Line,
@@ -841,6 +908,9 @@ func (c *compilerContext) createPackage(irbuilder llvm.Builder, pkg *ssa.Package
 				// Do not try to build generic (non-instantiated) functions.
 				continue
 			}
+			if info := c.getFunctionInfo(member); info.registry != "" {
+				c.createRegistryEntry(member, info.registry)
+			}
 			// Create the function definition.
 			b := newBuilder(c, irbuilder, member)
 			if _, ok := mathToLLVMMapping[member.RelString(nil)]; ok {
@@ -864,6 +934,9 @@ func (c *compilerContext) createPackage(irbuilder llvm.Builder, pkg *ssa.Package
 			}
 			b.createFunction()
 		case *ssa.Type:
+			if c.hasReflectPragma(member) {
+				c.createReflectKeep(member)
+			}
 			if types.IsInterface(member.Type()) {
 				// Interfaces don't have concrete methods.
 				continue
@@ -913,6 +986,9 @@ func (c *compilerContext) createPackage(irbuilder llvm.Builder, pkg *ssa.Package
 					global.SetSection(info.section)
 				}
 			}
+			if info.registry {
+				c.createRegistry(member, info)
+			}
 		}
 	}
 
@@ -1154,11 +1230,25 @@ func (b *builder) createFunctionStart(intrinsic bool) {
 	if b.info.section != "" {
 		b.llvmFn.SetSection(b.info.section)
 	}
+	if b.info.ramfunc && (strings.HasPrefix(b.Triple, "arm") || strings.HasPrefix(b.Triple, "thumb")) {
+		// Code in RAM is usually too far away from code in flash for a bl
+		// instruction, so call other functions through a register instead.
+		features := "+long-calls"
+		if b.Features != "" {
+			features = b.Features + "," + features
+		}
+		b.llvmFn.AddFunctionAttr(b.ctx.CreateStringAttribute("target-features", features))
+	}
 	if b.info.exported && strings.HasPrefix(b.Triple, "wasm") {
 		// Set the exported name. This is necessary for WebAssembly because
 		// otherwise the function is not exported.
 		functionAttr := b.ctx.CreateStringAttribute("wasm-export-name", b.info.linkName)
 		b.llvmFn.AddFunctionAttr(functionAttr)
+		if b.info.wasmExport != "" {
+			// Keep the signature as-is in the wasm-abi pass: 64-bit integers
+			// are passed as i64 values.
+			b.llvmFn.AddFunctionAttr(b.ctx.CreateStringAttribute("tinygo-wasmexport", ""))
+		}
 		// Unlike most targets, exported functions are actually visible in
 		// WebAssembly (even if it's not called from within the WebAssembly
 		// module). But LTO generally optimizes such functions away. Therefore,
@@ -1187,6 +1277,7 @@ func (b *builder) createFunctionStart(intrinsic bool) {
 		} else {
 			b.addError(b.fn.Pos(), "//go:interrupt not supported on this architecture")
 		}
+		b.checkInterruptHandler(b.fn)
 	}
 
 	// Add debug info, if needed.
@@ -1198,6 +1289,7 @@ func (b *builder) createFunctionStart(intrinsic bool) {
 		} else if b.fn.Syntax() != nil {
 			// Create debug info file if needed.
 			b.difunc = b.attachDebugInfo(b.fn)
+			b.funcScope = b.getFuncScope()
 		}
 		b.setDebugLocation(b.fn.Pos())
 	}
@@ -1299,6 +1391,25 @@ func (b *builder) createFunctionStart(intrinsic bool) {
 		// because runtime.trackPointer is replaced by an alloca store.
 		b.stackChainAlloca = b.CreateAlloca(b.ctx.Int8Type(), "stackalloc")
 	}
+
+	if !intrinsic && b.needsTraceFrame() {
+		// Keep track of calls in this function for stack traces.
+		b.createTraceFrame()
+	}
+
+	if !intrinsic && b.needsRaceInstrumentation() {
+		// Check loads and stores in this function for data races.
+		b.raceInstrument = true
+		if b.traceSites == nil {
+			b.traceSites = make(map[string]llvm.Value)
+		}
+	}
+
+	if b.info.wasmExport != "" {
+		// The host may call this function without calling _start first, so
+		// make sure the runtime is initialized.
+		b.createRuntimeCall("wasmExportInit", nil, "")
+	}
 }
 
 // createFunction builds the LLVM IR implementation for this function. The
@@ -1308,6 +1419,7 @@ func (b *builder) createFunction() {
 	b.createFunctionStart(false)
 
 	// Fill blocks with instructions.
+	coverCounters := b.findCoverCounters()
 	for _, block := range b.fn.DomPreorder() {
 		if b.DumpSSA {
 			fmt.Printf("%d: %s:\n", block.Index, block.Comment)
@@ -1315,6 +1427,13 @@ func (b *builder) createFunction() {
 		b.SetInsertPointAtEnd(b.blockEntries[block])
 		b.currentBlock = block
 		for _, instr := range block.Instrs {
+			if counters := coverCounters[block]; counters != nil {
+				if _, ok := instr.(*ssa.Phi); !ok {
+					// Increment the coverage counters after the phi nodes.
+					b.createCoverCounters(counters)
+					delete(coverCounters, block)
+				}
+			}
 			if instr, ok := instr.(*ssa.DebugRef); ok {
 				if !b.Debug {
 					continue
@@ -1325,17 +1444,23 @@ func (b *builder) createFunction() {
 					// Not a local variable.
 					continue
 				}
+				var expr []uint64
 				if instr.IsAddr {
-					// TODO, this may happen for *ssa.Alloc and *ssa.FieldAddr
-					// for example.
-					continue
+					if _, ok := instr.X.(*ssa.Alloc); !ok {
+						// TODO: this may happen for *ssa.FieldAddr for
+						// example.
+						continue
+					}
+					// The variable lives in memory (on the stack or on the
+					// heap), so describe it as a pointer to the value.
+					expr = []uint64{0x06} // DW_OP_deref
 				}
 				dbgVar := b.getLocalVariable(variable)
 				pos := b.program.Fset.Position(instr.Pos())
-				b.dibuilder.InsertValueAtEnd(b.getValue(instr.X, getPos(instr)), dbgVar, b.dibuilder.CreateExpression(nil), llvm.DebugLoc{
+				b.dibuilder.InsertValueAtEnd(b.getValue(instr.X, getPos(instr)), dbgVar, b.dibuilder.CreateExpression(expr), llvm.DebugLoc{
 					Line:  uint(pos.Line),
 					Col:   uint(pos.Column),
-					Scope: b.difunc,
+					Scope: b.getDIScope(variable.Parent()),
 				}, b.GetInsertBlock())
 				continue
 			}
@@ -1346,6 +1471,9 @@ func (b *builder) createFunction() {
 					fmt.Printf("\t%s\n", instr.String())
 				}
 			}
+			if !b.traceFrame.IsNil() {
+				b.tracePos = getPos(instr)
+			}
 			b.createInstruction(instr)
 		}
 		if b.fn.Name() == "init" && len(block.Instrs) == 0 {
@@ -1502,6 +1630,9 @@ func (b *builder) createInstruction(instr ssa.Instruction) {
 		if b.hasDeferFrame() {
 			b.createRuntimeCall("destroyDeferFrame", []llvm.Value{b.deferFrame}, "")
 		}
+		if !b.traceFrame.IsNil() {
+			b.createRuntimeCall("tracePop", []llvm.Value{b.traceFrame}, "")
+		}
 		if len(instr.Results) == 0 {
 			b.CreateRetVoid()
 		} else if len(instr.Results) == 1 {
@@ -1534,6 +1665,7 @@ func (b *builder) createInstruction(instr ssa.Instruction) {
 			// nothing to store
 			return
 		}
+		b.createRaceAccess(instr.Addr, llvmAddr, llvmVal.Type(), true, getPos(instr))
 		b.CreateStore(llvmVal, llvmAddr)
 	default:
 		b.addError(instr.Pos(), "unknown instruction: "+instr.String())
@@ -3231,6 +3363,7 @@ func (b *builder) createUnOp(unop *ssa.UnOp) (llvm.Value, error) {
 			return b.CreateBitCast(fn, b.i8ptrType, ""), nil
 		} else {
 			b.createNilCheck(unop.X, x, "deref")
+			b.createRaceAccess(unop.X, x, valueType, false, getPos(unop))
 			load := b.CreateLoad(valueType, x, "")
 			return load, nil
 		}
@@ -2,8 +2,10 @@ package compiler
 
 import (
 	"flag"
+	"go/token"
 	"go/types"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
 	"testing"
@@ -180,6 +182,7 @@ func TestCompilerErrors(t *testing.T) {
 	t.Parallel()
 
 	// Read expected errors from the test file.
+	// Warnings are listed as "// WARNING: " and are returned as soft errors.
 	var expectedErrors []string
 	errorsFile, err := os.ReadFile("testdata/errors.go")
 	if err != nil {
@@ -189,6 +192,8 @@ func TestCompilerErrors(t *testing.T) {
 	for _, line := range strings.Split(errorsFileString, "\n") {
 		if strings.HasPrefix(line, "// ERROR: ") {
 			expectedErrors = append(expectedErrors, strings.TrimPrefix(line, "// ERROR: "))
+		} else if strings.HasPrefix(line, "// WARNING: ") {
+			expectedErrors = append(expectedErrors, "warning: "+strings.TrimPrefix(line, "// WARNING: "))
 		}
 	}
 
@@ -204,14 +209,117 @@ func TestCompilerErrors(t *testing.T) {
 		err := err.(types.Error)
 		position := err.Fset.Position(err.Pos)
 		position.Filename = "errors.go" // don't use a full path
-		if expectedErrorsIdx >= len(expectedErrors) || expectedErrors[expectedErrorsIdx] != err.Msg {
-			t.Errorf("unexpected compiler error: %s: %s", position.String(), err.Msg)
+		msg := err.Msg
+		if err.Soft {
+			msg = "warning: " + msg
+		}
+		if expectedErrorsIdx >= len(expectedErrors) || expectedErrors[expectedErrorsIdx] != msg {
+			t.Errorf("unexpected compiler error: %s: %s", position.String(), msg)
 			continue
 		}
 		expectedErrorsIdx++
 	}
 }
 
+// Check that memory accesses are instrumented with -race, except in functions
+// marked //go:norace.
+func TestCompilerRace(t *testing.T) {
+	t.Parallel()
+
+	options := &compileopts.Options{
+		Target: "wasm",
+		Race:   true,
+	}
+	mod, errs := testCompilePackage(t, options, "race.go")
+	if errs != nil {
+		for _, err := range errs {
+			t.Error(err)
+		}
+		return
+	}
+
+	tests := []struct {
+		fn           string
+		instrumented bool
+	}{
+		{"main.increment", true},
+		{"main.incrementNoRace", false},
+	}
+	for _, tc := range tests {
+		fn := mod.NamedFunction(tc.fn)
+		if fn.IsNil() {
+			t.Errorf("function %s not found", tc.fn)
+			continue
+		}
+		var calls []string
+		for bb := fn.FirstBasicBlock(); !bb.IsNil(); bb = llvm.NextBasicBlock(bb) {
+			for inst := bb.FirstInstruction(); !inst.IsNil(); inst = llvm.NextInstruction(inst) {
+				if inst.IsACallInst().IsNil() {
+					continue
+				}
+				switch name := inst.CalledValue().Name(); name {
+				case "runtime.raceread", "runtime.racewrite":
+					calls = append(calls, name)
+				}
+			}
+		}
+		if instrumented := len(calls) != 0; instrumented != tc.instrumented {
+			t.Errorf("%s: got race detector calls %v, want instrumented=%v", tc.fn, calls, tc.instrumented)
+		}
+	}
+}
+
+// Check the debug information of local variables: variables declared in a
+// block are scoped to a lexical block, and variables that live in memory are
+// described with a DW_OP_deref expression on their address.
+func TestCompilerDebugInfo(t *testing.T) {
+	t.Parallel()
+
+	options := &compileopts.Options{
+		Target: "wasm",
+		Debug:  true,
+	}
+	mod, errs := testCompilePackage(t, options, "debug.go")
+	if errs != nil {
+		for _, err := range errs {
+			t.Error(err)
+		}
+		return
+	}
+	ir := mod.String()
+
+	// Find the metadata IDs of the lexical blocks and local variables.
+	blocks := make(map[string]bool)
+	for _, m := range regexp.MustCompile(`(?m)^(![0-9]+) = distinct !DILexicalBlock\(`).FindAllStringSubmatch(ir, -1) {
+		blocks[m[1]] = true
+	}
+	variables := make(map[string]string) // variable name => metadata ID
+	scopes := make(map[string]string)    // variable name => scope metadata ID
+	for _, m := range regexp.MustCompile(`(?m)^(![0-9]+) = !DILocalVariable\(name: "([^"]+)", scope: (![0-9]+)`).FindAllStringSubmatch(ir, -1) {
+		variables[m[2]] = m[1]
+		scopes[m[2]] = m[3]
+	}
+
+	for _, name := range []string{"i", "inner"} {
+		if scope, ok := scopes[name]; !ok {
+			t.Errorf("no debug info for variable %s", name)
+		} else if !blocks[scope] {
+			t.Errorf("variable %s is not scoped to a lexical block but to %s", name, scope)
+		}
+	}
+	if scope, ok := scopes["sum"]; !ok {
+		t.Error("no debug info for variable sum")
+	} else if blocks[scope] {
+		t.Error("variable sum is scoped to a lexical block, expected the function")
+	}
+
+	if id, ok := variables["x"]; !ok {
+		t.Error("no debug info for address-taken variable x")
+	} else if !regexp.MustCompile(`metadata ` + id + `, metadata !DIExpression\(DW_OP_deref\)`).MatchString(ir) {
+		t.Errorf("variable x (%s) is not described with DW_OP_deref", id)
+	}
+}
+
 // Build a package given a number of compiler options and a file.
 func testCompilePackage(t *testing.T, options *compileopts.Options, file string) (llvm.Module, []error) {
 	target, err := compileopts.LoadTarget(options)
@@ -234,6 +342,8 @@ func testCompilePackage(t *testing.T, options *compileopts.Options, file string)
 		AutomaticStackSize: config.AutomaticStackSize(),
 		DefaultStackSize:   config.StackSize(),
 		NeedsStackObjects:  config.NeedsStackObjects(),
+		Debug:              options.Debug,
+		Race:               options.Race,
 	}
 	machine, err := NewTargetMachine(compilerConfig)
 	if err != nil {
@@ -258,3 +368,83 @@ func testCompilePackage(t *testing.T, options *compileopts.Options, file string)
 	pkg := lprogram.MainPkg()
 	return CompilePackage(file, pkg, program.Package(pkg.Pkg), machine, compilerConfig, false)
 }
+
+// Test that instantiated generic types get the same type code name, no matter
+// which package instantiated them or how the type arguments were spelled.
+func TestTypeCodeNameGenerics(t *testing.T) {
+	t.Parallel()
+
+	// Define a generic type: type Box[T any] struct{ Value T }
+	anyType := types.Universe.Lookup("any").Type().Underlying()
+	pkg := types.NewPackage("example.com/box", "box")
+	tparam := types.NewTypeParam(types.NewTypeName(token.NoPos, pkg, "T", nil), anyType)
+	obj := types.NewTypeName(token.NoPos, pkg, "Box", nil)
+	pkg.Scope().Insert(obj)
+	box := types.NewNamed(obj, nil, nil)
+	box.SetTypeParams([]*types.TypeParam{tparam})
+	box.SetUnderlying(types.NewStruct([]*types.Var{
+		types.NewField(token.NoPos, pkg, "Value", tparam, false),
+	}, nil))
+
+	instantiate := func(arg types.Type) types.Type {
+		// Use a fresh context for every instantiation, like two separate
+		// packages would.
+		inst, err := types.Instantiate(types.NewContext(), box, []types.Type{arg}, true)
+		if err != nil {
+			t.Fatal("could not instantiate:", err)
+		}
+		return inst
+	}
+
+	tests := []struct {
+		a, b types.Type
+	}{
+		{instantiate(types.Typ[types.Int]), instantiate(types.Typ[types.Int])},
+		{instantiate(anyType), instantiate(types.NewInterfaceType(nil, nil))},
+		{types.NewPointer(instantiate(types.Typ[types.String])), types.NewPointer(instantiate(types.Typ[types.String]))},
+	}
+	for _, tc := range tests {
+		nameA, localA := getTypeCodeName(tc.a)
+		nameB, localB := getTypeCodeName(tc.b)
+		if nameA != nameB || localA || localB {
+			t.Errorf("type code names differ for identical types: %q (local=%v) != %q (local=%v)", nameA, localA, nameB, localB)
+		}
+		if msA, msB := getTypeMethodSetName(tc.a), getTypeMethodSetName(tc.b); msA != msB {
+			t.Errorf("method set names differ for identical types: %q != %q", msA, msB)
+		}
+	}
+
+	// Different type arguments must of course still result in different
+	// names.
+	nameInt, _ := getTypeCodeName(instantiate(types.Typ[types.Int]))
+	nameString, _ := getTypeCodeName(instantiate(types.Typ[types.String]))
+	if nameInt == nameString {
+		t.Errorf("type code names are equal for different types: %q", nameInt)
+	}
+}
+
+// Test that struct types with unexported fields from different packages get
+// different type code names, as they are different types with a different
+// PkgPath for these fields.
+func TestTypeCodeNameUnexportedFields(t *testing.T) {
+	t.Parallel()
+
+	newStruct := func(pkg *types.Package, name string) types.Type {
+		return types.NewStruct([]*types.Var{
+			types.NewField(token.NoPos, pkg, name, types.Typ[types.Int], false),
+		}, nil)
+	}
+	pkgA := types.NewPackage("example.com/a", "a")
+	pkgB := types.NewPackage("example.com/b", "b")
+
+	nameA, _ := getTypeCodeName(newStruct(pkgA, "x"))
+	nameB, _ := getTypeCodeName(newStruct(pkgB, "x"))
+	if nameA == nameB {
+		t.Errorf("type code names are equal for structs with unexported fields in different packages: %q", nameA)
+	}
+	nameA, _ = getTypeCodeName(newStruct(pkgA, "X"))
+	nameB, _ = getTypeCodeName(newStruct(pkgB, "X"))
+	if nameA != nameB {
+		t.Errorf("type code names differ for identical structs: %q != %q", nameA, nameB)
+	}
+}
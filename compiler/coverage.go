@@ -0,0 +1,526 @@
+package compiler
+
+// This file implements code coverage for tinygo test -cover. The source code
+// of the package under test is split into blocks of statements in the same way
+// as the go tool cover command does it, so that the resulting profiles can be
+// used with the regular Go tools (go tool cover -html for example).
+//
+// Where the go tool inserts a statement that increments a counter at the start
+// of every block, a marker statement that refers to runtime.coverMarker is
+// inserted before the SSA form is built. It only results in a DebugRef
+// instruction, so it doesn't generate any code, but it tells in which SSA basic
+// block the counter must be incremented. Without it, blocks that have no
+// instructions of their own (such as an empty if body) would be merged with
+// other basic blocks by the SSA builder. The counters and the positions of all
+// blocks are stored in a global that is added to runtime.coverBlocks, which
+// is a //go:registry variable.
+
+import (
+	"go/ast"
+	"go/token"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tinygo-org/tinygo/loader"
+	"golang.org/x/tools/go/ssa"
+	"tinygo.org/x/go-llvm"
+)
+
+// coverBlock is a block of statements in the package under test.
+type coverBlock struct {
+	start, end token.Pos
+	numStmt    int
+}
+
+// AddCoverMarkers inserts a marker statement at the start of every coverage
+// block of the package. It must be called before the SSA form of any package
+// is built, because generic functions of the package may be instantiated while
+// building other packages.
+func AddCoverMarkers(program *ssa.Program, pkg *loader.Package) {
+	marker := program.ImportedPackage("runtime").Func("coverMarker").Object()
+	newMarker := func(pos token.Pos) ast.Stmt {
+		// The statement is "_ = coverMarker". The missing position of the
+		// assignment token distinguishes it from statements in the source.
+		ident := &ast.Ident{NamePos: pos, Name: marker.Name()}
+		pkg.RecordUse(ident, marker)
+		return &ast.AssignStmt{
+			Lhs: []ast.Expr{&ast.Ident{NamePos: pos, Name: "_"}},
+			Tok: token.ASSIGN,
+			Rhs: []ast.Expr{ident},
+		}
+	}
+	for _, file := range pkg.Files {
+		if !coverFile(pkg, program.Fset.File(file.Package).Name()) {
+			continue
+		}
+		var blocks []coverBlock
+		ast.Walk(&coverVisitor{blocks: &blocks, newMarker: newMarker}, file)
+	}
+}
+
+// createCoverBlocks finds all coverage blocks in the package and creates the
+// global that holds the counters for these blocks.
+func (c *compilerContext) createCoverBlocks(pkg *loader.Package) {
+	for _, file := range pkg.Files {
+		filename := c.program.Fset.File(file.Package).Name()
+		if !coverFile(pkg, filename) {
+			continue
+		}
+		ast.Walk(&coverVisitor{blocks: &c.coverBlocks}, file)
+	}
+	sort.Slice(c.coverBlocks, func(i, j int) bool {
+		return c.coverBlocks[i].start < c.coverBlocks[j].start
+	})
+
+	blockType := c.getLLVMRuntimeType("coverBlock")
+	blocks := make([]llvm.Value, len(c.coverBlocks))
+	for i, block := range c.coverBlocks {
+		start := c.program.Fset.Position(block.start)
+		end := c.program.Fset.Position(block.end)
+		// The file name is the import path followed by the base name of the
+		// file, like in profiles created by the go tool.
+		file := path.Join(pkg.ImportPath, filepath.Base(start.Filename))
+		blocks[i] = llvm.ConstNamedStruct(blockType, []llvm.Value{
+			c.createConstString(file),
+			llvm.ConstInt(c.ctx.Int32Type(), uint64(start.Line), false),
+			llvm.ConstInt(c.ctx.Int32Type(), uint64(start.Column), false),
+			llvm.ConstInt(c.ctx.Int32Type(), uint64(end.Line), false),
+			llvm.ConstInt(c.ctx.Int32Type(), uint64(end.Column), false),
+			llvm.ConstInt(c.ctx.Int32Type(), uint64(block.numStmt), false),
+			llvm.ConstInt(c.ctx.Int32Type(), 0, false), // count
+		})
+	}
+	initializer := llvm.ConstArray(blockType, blocks)
+	c.coverGlobal = llvm.AddGlobal(c.mod, initializer.Type(), pkg.ImportPath+"$coverage")
+	c.coverGlobal.SetInitializer(initializer)
+	c.coverGlobal.SetLinkage(llvm.InternalLinkage)
+
+	// Add the blocks to runtime.coverBlocks. See createRegistryEntry for how
+	// registry entries are combined.
+	zero := llvm.ConstInt(c.ctx.Int32Type(), 0, false)
+	length := llvm.ConstInt(c.uintptrType, uint64(len(blocks)), false)
+	slice := c.ctx.ConstStruct([]llvm.Value{
+		llvm.ConstInBoundsGEP(initializer.Type(), c.coverGlobal, []llvm.Value{zero, zero}),
+		length,
+		length,
+	}, false)
+	entries := llvm.ConstArray(slice.Type(), []llvm.Value{slice})
+	global := llvm.AddGlobal(c.mod, entries.Type(), "runtime.coverBlocks$registry.entries")
+	global.SetInitializer(entries)
+	global.SetLinkage(llvm.AppendingLinkage)
+}
+
+// coverFile returns whether the given file of the package should be covered.
+// Like with the go tool, test files aren't covered. Neither are CGo files, as
+// their AST has been modified by the CGo preprocessor.
+func coverFile(pkg *loader.Package, filename string) bool {
+	if strings.HasSuffix(filename, "_test.go") {
+		return false
+	}
+	for _, name := range pkg.GoFiles {
+		if name == filepath.Base(filename) {
+			return true
+		}
+	}
+	return false
+}
+
+// findCoverBlock returns the index of the coverage block that contains the
+// given position, or -1 if there is none. Empty blocks of case clauses have no
+// width, so they only contain their start position.
+func (c *compilerContext) findCoverBlock(pos token.Pos) int {
+	index := sort.Search(len(c.coverBlocks), func(i int) bool {
+		return c.coverBlocks[i].end >= pos
+	})
+	for ; index < len(c.coverBlocks) && c.coverBlocks[index].start <= pos; index++ {
+		block := c.coverBlocks[index]
+		if pos < block.end || block.start == block.end {
+			return index
+		}
+	}
+	return -1
+}
+
+// findCoverCounters determines in which basic block of the current function
+// the counter of each coverage block must be incremented: the block that
+// contains the marker inserted by AddCoverMarkers. Markers in unreachable code
+// have been removed by the SSA builder, so their counters are never
+// incremented.
+func (b *builder) findCoverCounters() map[*ssa.BasicBlock][]int {
+	if b.coverGlobal.IsNil() || b.fn.Synthetic != "" {
+		return nil
+	}
+	marker := b.program.ImportedPackage("runtime").Func("coverMarker").Object()
+	counters := make(map[*ssa.BasicBlock][]int)
+	for _, block := range b.fn.Blocks {
+		for _, instr := range block.Instrs {
+			ref, ok := instr.(*ssa.DebugRef)
+			if !ok || ref.Object() != marker {
+				continue
+			}
+			if index := b.findCoverBlock(ref.Pos()); index >= 0 {
+				counters[block] = append(counters[block], index)
+			}
+		}
+	}
+	return counters
+}
+
+// createCoverCounters increments the counters of the given coverage blocks.
+func (b *builder) createCoverCounters(indices []int) {
+	for _, index := range indices {
+		counter := b.CreateInBoundsGEP(b.coverGlobal.GlobalValueType(), b.coverGlobal, []llvm.Value{
+			llvm.ConstInt(b.ctx.Int32Type(), 0, false),
+			llvm.ConstInt(b.ctx.Int32Type(), uint64(index), false),
+			llvm.ConstInt(b.ctx.Int32Type(), 6, false), // count
+		}, "cover.counter")
+		value := llvm.ConstInt(b.ctx.Int32Type(), 1, false)
+		if b.CoverMode == "count" {
+			count := b.CreateLoad(b.ctx.Int32Type(), counter, "cover.count")
+			value = b.CreateAdd(count, value, "")
+		}
+		b.CreateStore(value, counter)
+	}
+}
+
+// coverVisitor finds the coverage blocks in a file. Blocks are split at the
+// same places as in the go tool cover command.
+type coverVisitor struct {
+	blocks *[]coverBlock
+
+	// If set, a marker created by newMarker is inserted at the start of
+	// every block. See AddCoverMarkers.
+	newMarker func(pos token.Pos) ast.Stmt
+}
+
+func (v *coverVisitor) Visit(node ast.Node) ast.Visitor {
+	switch n := node.(type) {
+	case *ast.BlockStmt:
+		if len(n.List) > 0 {
+			switch n.List[0].(type) {
+			case *ast.CaseClause:
+				for _, stmt := range n.List {
+					clause := stmt.(*ast.CaseClause)
+					v.addBlocks(clause.Colon+1, clause.Colon+1, coverClauseEnd(clause.Colon, clause.Body), &clause.Body, false)
+				}
+				return v
+			case *ast.CommClause:
+				for _, stmt := range n.List {
+					clause := stmt.(*ast.CommClause)
+					v.addBlocks(clause.Colon+1, clause.Colon+1, coverClauseEnd(clause.Colon, clause.Body), &clause.Body, false)
+				}
+				return v
+			}
+		}
+		v.addBlocks(n.Lbrace, n.Lbrace+1, n.Rbrace+1, &n.List, true)
+	case *ast.IfStmt:
+		if n.Init != nil {
+			ast.Walk(v, n.Init)
+		}
+		ast.Walk(v, n.Cond)
+		ast.Walk(v, n.Body)
+		if n.Else == nil {
+			return nil
+		}
+		// The go tool adds braces around an "else if" so that the condition
+		// gets a block of its own, and lets the else block start right after
+		// the "else" keyword. The position of this keyword isn't stored in the
+		// AST, so assume the code is formatted with gofmt.
+		// When inserting markers, the braces around an "else if" are kept in
+		// the AST to hold the marker. The resulting block is the same when
+		// the blocks are found again.
+		pos := n.Body.End() + token.Pos(len(" else"))
+		switch stmt := n.Else.(type) {
+		case *ast.IfStmt:
+			block := &ast.BlockStmt{
+				Lbrace: pos,
+				List:   []ast.Stmt{stmt},
+				Rbrace: stmt.End(),
+			}
+			ast.Walk(v, block)
+			if v.newMarker != nil {
+				n.Else = block
+			}
+		case *ast.BlockStmt:
+			block := *stmt
+			block.Lbrace = pos
+			ast.Walk(v, &block)
+			if v.newMarker != nil {
+				stmt.List = block.List
+			}
+		}
+		return nil
+	case *ast.SelectStmt:
+		if n.Body == nil || len(n.Body.List) == 0 {
+			return nil
+		}
+	case *ast.SwitchStmt:
+		if n.Body == nil || len(n.Body.List) == 0 {
+			// Only the init statement and tag may contain blocks (in
+			// function literals).
+			if n.Init != nil {
+				ast.Walk(v, n.Init)
+			}
+			if n.Tag != nil {
+				ast.Walk(v, n.Tag)
+			}
+			return nil
+		}
+	case *ast.TypeSwitchStmt:
+		if n.Body == nil || len(n.Body.List) == 0 {
+			if n.Init != nil {
+				ast.Walk(v, n.Init)
+			}
+			ast.Walk(v, n.Assign)
+			return nil
+		}
+	case *ast.FuncDecl:
+		if n.Name.Name == "_" || n.Body == nil {
+			return nil
+		}
+	}
+	return v
+}
+
+// addBlocks adds the blocks for a list of statements that starts at pos and
+// ends at blockEnd. The list is split at every statement that ends a block,
+// such as a branch or an if statement. An empty list gets an empty block that
+// starts at insertPos. When inserting markers, the list is replaced with one
+// that has a marker at the start of every block.
+func (v *coverVisitor) addBlocks(pos, insertPos, blockEnd token.Pos, stmts *[]ast.Stmt, extendToClosingBrace bool) {
+	list := coverStatements(*stmts)
+	if len(list) == 0 {
+		*v.blocks = append(*v.blocks, coverBlock{start: insertPos, end: blockEnd})
+		if v.newMarker != nil {
+			*stmts = []ast.Stmt{v.newMarker(insertPos)}
+		}
+		return
+	}
+	start := pos
+	firstBlock := len(*v.blocks)
+	for {
+		last := 0
+		end := blockEnd
+		for last < len(list) {
+			stmt := list[last]
+			end = coverStatementBoundary(stmt)
+			if coverEndsBlock(stmt) {
+				if label, ok := stmt.(*ast.LabeledStmt); ok && !coverIsControl(label.Stmt) {
+					// The label may be the target of a goto, so the
+					// statement after it starts a new block. The previous
+					// block ends before the label.
+					end = label.Pos()
+					rest := append([]ast.Stmt{label.Stmt}, list[last+1:]...)
+					list = append(list[:last:last], &ast.EmptyStmt{Semicolon: label.Stmt.Pos(), Implicit: true})
+					list = append(list, rest...)
+				}
+				last++
+				extendToClosingBrace = false
+				break
+			}
+			last++
+		}
+		if extendToClosingBrace {
+			end = blockEnd
+		}
+		if pos != end {
+			*v.blocks = append(*v.blocks, coverBlock{start: pos, end: end, numStmt: last})
+		}
+		list = list[last:]
+		if len(list) == 0 {
+			break
+		}
+		pos = list[0].Pos()
+	}
+	if v.newMarker == nil {
+		return
+	}
+
+	// Insert the markers where the go tool inserts its counters: at
+	// insertPos for the first block, before the first statement of the other
+	// blocks, or after the label for blocks that start at a labeled
+	// statement. In the last case "L: stmt" becomes "L: marker; stmt".
+	starts := make(map[token.Pos]bool)
+	for _, block := range (*v.blocks)[firstBlock:] {
+		starts[block.start] = true
+	}
+	var marked []ast.Stmt
+	for i, stmt := range coverStatements(*stmts) {
+		if i == 0 && starts[start] {
+			marked = append(marked, v.newMarker(insertPos))
+		} else if i != 0 && starts[stmt.Pos()] {
+			marked = append(marked, v.newMarker(stmt.Pos()))
+		}
+		marked = append(marked, stmt)
+		for label, ok := stmt.(*ast.LabeledStmt); ok; label, ok = label.Stmt.(*ast.LabeledStmt) {
+			if starts[label.Stmt.Pos()] {
+				marked = append(marked, label.Stmt)
+				label.Stmt = v.newMarker(label.Stmt.Pos())
+				break
+			}
+		}
+	}
+	*stmts = marked
+}
+
+// isCoverMarker returns whether the statement is a marker inserted by
+// AddCoverMarkers.
+func isCoverMarker(stmt ast.Stmt) bool {
+	assign, ok := stmt.(*ast.AssignStmt)
+	return ok && !assign.TokPos.IsValid()
+}
+
+// coverStatements returns the list of statements as it was before markers
+// were inserted, without modifying the AST.
+func coverStatements(list []ast.Stmt) []ast.Stmt {
+	var stmts []ast.Stmt
+	for i := 0; i < len(list); i++ {
+		stmt := list[i]
+		if isCoverMarker(stmt) {
+			continue
+		}
+		if label, ok := stmt.(*ast.LabeledStmt); ok && i+1 < len(list) {
+			if unmarked := coverUnmarkLabel(label, list[i+1]); unmarked != nil {
+				stmt = unmarked
+				i++
+			}
+		}
+		stmts = append(stmts, stmt)
+	}
+	return stmts
+}
+
+// coverClauseEnd returns the end of a case clause with the given body, like
+// its End method does before markers are inserted.
+func coverClauseEnd(colon token.Pos, body []ast.Stmt) token.Pos {
+	if body := coverStatements(body); len(body) != 0 {
+		return body[len(body)-1].End()
+	}
+	return colon + 1
+}
+
+// coverUnmarkLabel returns a copy of the labeled statement with the marker
+// after the label replaced by next, or nil if there is no such marker.
+func coverUnmarkLabel(label *ast.LabeledStmt, next ast.Stmt) *ast.LabeledStmt {
+	switch stmt := label.Stmt.(type) {
+	case *ast.LabeledStmt:
+		inner := coverUnmarkLabel(stmt, next)
+		if inner == nil {
+			return nil
+		}
+		next = inner
+	default:
+		if !isCoverMarker(stmt) {
+			return nil
+		}
+	}
+	unmarked := *label
+	unmarked.Stmt = next
+	return &unmarked
+}
+
+// coverStatementBoundary returns the position where the block that contains
+// the given statement ends, if this is the last statement of the block.
+// Statements with nested blocks end at the start of the first nested block.
+func coverStatementBoundary(stmt ast.Stmt) token.Pos {
+	switch s := stmt.(type) {
+	case *ast.BlockStmt:
+		return s.Lbrace
+	case *ast.IfStmt:
+		if pos := coverFindFuncLit(s.Init); pos.IsValid() {
+			return pos
+		}
+		if pos := coverFindFuncLit(s.Cond); pos.IsValid() {
+			return pos
+		}
+		return s.Body.Lbrace
+	case *ast.ForStmt:
+		if pos := coverFindFuncLit(s.Init); pos.IsValid() {
+			return pos
+		}
+		if pos := coverFindFuncLit(s.Cond); pos.IsValid() {
+			return pos
+		}
+		if pos := coverFindFuncLit(s.Post); pos.IsValid() {
+			return pos
+		}
+		return s.Body.Lbrace
+	case *ast.LabeledStmt:
+		return coverStatementBoundary(s.Stmt)
+	case *ast.RangeStmt:
+		if pos := coverFindFuncLit(s.X); pos.IsValid() {
+			return pos
+		}
+		return s.Body.Lbrace
+	case *ast.SwitchStmt:
+		if pos := coverFindFuncLit(s.Init); pos.IsValid() {
+			return pos
+		}
+		if pos := coverFindFuncLit(s.Tag); pos.IsValid() {
+			return pos
+		}
+		return s.Body.Lbrace
+	case *ast.SelectStmt:
+		return s.Body.Lbrace
+	case *ast.TypeSwitchStmt:
+		if pos := coverFindFuncLit(s.Init); pos.IsValid() {
+			return pos
+		}
+		return s.Body.Lbrace
+	}
+	if pos := coverFindFuncLit(stmt); pos.IsValid() {
+		return pos
+	}
+	return stmt.End()
+}
+
+// coverEndsBlock returns whether the statement is the last statement of a
+// block, because it changes the control flow or contains a nested block.
+func coverEndsBlock(stmt ast.Stmt) bool {
+	switch s := stmt.(type) {
+	case *ast.BlockStmt, *ast.BranchStmt, *ast.ForStmt, *ast.IfStmt, *ast.LabeledStmt, *ast.RangeStmt, *ast.SwitchStmt, *ast.SelectStmt, *ast.TypeSwitchStmt:
+		return true
+	case *ast.ExprStmt:
+		// Calls to panic change the control flow.
+		if call, ok := s.X.(*ast.CallExpr); ok {
+			if ident, ok := call.Fun.(*ast.Ident); ok && ident.Name == "panic" && len(call.Args) == 1 {
+				return true
+			}
+		}
+	}
+	return coverFindFuncLit(stmt).IsValid()
+}
+
+// coverIsControl returns whether the statement is a control statement that
+// can't be separated from its label, because break or continue may refer to
+// the label.
+func coverIsControl(stmt ast.Stmt) bool {
+	switch stmt.(type) {
+	case *ast.ForStmt, *ast.RangeStmt, *ast.SelectStmt, *ast.SwitchStmt, *ast.TypeSwitchStmt:
+		return true
+	}
+	return false
+}
+
+// coverFindFuncLit returns the start of the body of the first function
+// literal in the given node, or token.NoPos if there is none. Function literals
+// have blocks of their own.
+func coverFindFuncLit(node ast.Node) token.Pos {
+	pos := token.NoPos
+	if node == nil {
+		return pos
+	}
+	ast.Inspect(node, func(n ast.Node) bool {
+		if pos.IsValid() {
+			return false
+		}
+		if lit, ok := n.(*ast.FuncLit); ok {
+			pos = lit.Body.Lbrace
+			return false
+		}
+		return true
+	})
+	return pos
+}
@@ -96,6 +96,11 @@ func (b *builder) createLandingPad() {
 		b.SetCurrentDebugLocation(uint(pos.Line), uint(pos.Column), b.difunc, llvm.Metadata{})
 	}
 
+	if !b.traceFrame.IsNil() {
+		// Drop the trace frames of the functions that were unwound.
+		b.createRuntimeCall("traceRestore", []llvm.Value{b.traceFrame}, "")
+	}
+
 	b.createRunDefers()
 
 	// Continue at the 'recover' block, which returns to the parent in an
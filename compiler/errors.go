@@ -24,6 +24,14 @@ func (c *compilerContext) addError(pos token.Pos, msg string) {
 	c.diagnostics = append(c.diagnostics, c.makeError(pos, msg))
 }
 
+// addWarning adds a compiler diagnostic that doesn't stop the build. It is
+// reported as a soft error.
+func (c *compilerContext) addWarning(pos token.Pos, msg string) {
+	err := c.makeError(pos, msg)
+	err.Soft = true
+	c.diagnostics = append(c.diagnostics, err)
+}
+
 // getPosition returns the position information for the given value, as far as
 // it is available.
 func getPosition(val llvm.Value) token.Position {
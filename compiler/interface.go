@@ -7,6 +7,7 @@ package compiler
 
 import (
 	"encoding/binary"
+	"encoding/hex"
 	"fmt"
 	"go/token"
 	"go/types"
@@ -59,8 +60,13 @@ const (
 	structFieldFlagHasTag
 	structFieldFlagIsExported
 	structFieldFlagIsEmbedded
+	structFieldFlagHasTagTable
 )
 
+// Flag set in the numIn field of a signature type struct if the last parameter
+// is variadic. Must be kept up to date with src/reflect/type.go.
+const signatureFlagVariadic = 1 << 15
+
 type reflectChanDir int
 
 const (
@@ -117,6 +123,29 @@ func (c *compilerContext) pkgPathPtr(pkgpath string) llvm.Value {
 	return pkgPathPtr
 }
 
+// structFieldData returns the global that holds the given struct field data.
+// Many struct types have fields with the same name, offset and tag (for
+// example, the many structs that start with an ID or Name field), so the
+// global is named after its contents and has linkonce_odr linkage: identical
+// field data is then emitted only once, both within a package and when
+// packages are linked together. This doesn't rely on LLVM constant merging, so
+// it also works with optimizations disabled.
+func (c *compilerContext) structFieldData(data string) llvm.Value {
+	// The data may contain zero bytes, which can't be part of a global name.
+	globalName := "reflect/types.structfield:" + hex.EncodeToString([]byte(data))
+	global := c.mod.NamedGlobal(globalName)
+	if global.IsNil() {
+		initializer := c.ctx.ConstString(data, false)
+		global = llvm.AddGlobal(c.mod, initializer.Type(), globalName)
+		global.SetInitializer(initializer)
+		global.SetAlignment(1)
+		global.SetUnnamedAddr(true)
+		global.SetLinkage(llvm.LinkOnceODRLinkage)
+		global.SetGlobalConstant(true)
+	}
+	return global
+}
+
 // getTypeCode returns a reference to a type code.
 // A type code is a pointer to a constant global that describes the type.
 // This function returns a pointer to the 'kind' field (which might not be the
@@ -174,7 +203,7 @@ func (c *compilerContext) getTypeCode(typ types.Type) llvm.Value {
 				types.NewVar(token.NoPos, nil, "ptrTo", types.Typ[types.UnsafePointer]),
 			)
 		case *types.Named:
-			name := typ.Obj().Name()
+			name := getReflectTypeName(typ)
 			var pkgname string
 			if pkg := typ.Obj().Pkg(); pkg != nil {
 				pkgname = pkg.Name()
@@ -228,14 +257,21 @@ func (c *compilerContext) getTypeCode(typ types.Type) llvm.Value {
 			)
 		case *types.Interface:
 			typeFieldTypes = append(typeFieldTypes,
+				types.NewVar(token.NoPos, nil, "numMethods", types.Typ[types.Uint16]),
 				types.NewVar(token.NoPos, nil, "ptrTo", types.Typ[types.UnsafePointer]),
+				types.NewVar(token.NoPos, nil, "methods", types.NewArray(types.Typ[types.UnsafePointer], int64(typ.NumMethods()))),
+				types.NewVar(token.NoPos, nil, "string", types.NewArray(types.Typ[types.Int8], int64(len(c.getReflectTypeString(typ))+1))),
 			)
-			// TODO: methods
 		case *types.Signature:
 			typeFieldTypes = append(typeFieldTypes,
+				types.NewVar(token.NoPos, nil, "numIn", types.Typ[types.Uint16]),
 				types.NewVar(token.NoPos, nil, "ptrTo", types.Typ[types.UnsafePointer]),
+				types.NewVar(token.NoPos, nil, "numOut", types.Typ[types.Uint16]),
+				types.NewVar(token.NoPos, nil, "call", reflectCallThunkSignature),
+				types.NewVar(token.NoPos, nil, "makeFunc", types.NewSignature(nil, nil, nil, false)),
+				types.NewVar(token.NoPos, nil, "params", types.NewArray(types.Typ[types.UnsafePointer], int64(typ.Params().Len()+typ.Results().Len()))),
+				types.NewVar(token.NoPos, nil, "string", types.NewArray(types.Typ[types.Int8], int64(len(c.getReflectTypeString(typ))+1))),
 			)
-			// TODO: signature params and return values
 		}
 		if hasMethodSet {
 			// This method set is appended at the start of the struct. It is
@@ -268,7 +304,7 @@ func (c *compilerContext) getTypeCode(typ types.Type) llvm.Value {
 		case *types.Basic:
 			typeFields = []llvm.Value{c.getTypeCode(types.NewPointer(typ))}
 		case *types.Named:
-			name := typ.Obj().Name()
+			name := getReflectTypeName(typ)
 			var pkgpath string
 			var pkgname string
 			if pkg := typ.Obj().Pkg(); pkg != nil {
@@ -276,12 +312,16 @@ func (c *compilerContext) getTypeCode(typ types.Type) llvm.Value {
 				pkgname = pkg.Name()
 			}
 			pkgPathPtr := c.pkgPathPtr(pkgpath)
+			numMethods := numExportedMethods(ms)
+			if types.IsInterface(typ) {
+				numMethods = ms.Len()
+			}
 			typeFields = []llvm.Value{
-				llvm.ConstInt(c.ctx.Int16Type(), uint64(ms.Len()), false), // numMethods
-				c.getTypeCode(types.NewPointer(typ)),                      // ptrTo
-				c.getTypeCode(typ.Underlying()),                           // underlying
-				pkgPathPtr,                                                // pkgpath pointer
-				c.ctx.ConstString(pkgname+"."+name+"\x00", false),         // name
+				llvm.ConstInt(c.ctx.Int16Type(), uint64(numMethods), false), // numMethods
+				c.getTypeCode(types.NewPointer(typ)),                        // ptrTo
+				c.getTypeCode(typ.Underlying()),                             // underlying
+				pkgPathPtr,                                                  // pkgpath pointer
+				c.ctx.ConstString(pkgname+"."+name+"\x00", false),           // name
 			}
 			metabyte |= 1 << 5 // "named" flag
 		case *types.Chan:
@@ -308,7 +348,7 @@ func (c *compilerContext) getTypeCode(typ types.Type) llvm.Value {
 			}
 		case *types.Pointer:
 			typeFields = []llvm.Value{
-				llvm.ConstInt(c.ctx.Int16Type(), uint64(ms.Len()), false), // numMethods
+				llvm.ConstInt(c.ctx.Int16Type(), uint64(numExportedMethods(ms)), false), // numMethods
 				c.getTypeCode(typ.Elem()),
 			}
 		case *types.Array:
@@ -337,8 +377,8 @@ func (c *compilerContext) getTypeCode(typ types.Type) llvm.Value {
 			llvmStructType := c.getLLVMType(typ)
 			size := c.targetData.TypeStoreSize(llvmStructType)
 			typeFields = []llvm.Value{
-				llvm.ConstInt(c.ctx.Int16Type(), uint64(ms.Len()), false), // numMethods
-				c.getTypeCode(types.NewPointer(typ)),                      // ptrTo
+				llvm.ConstInt(c.ctx.Int16Type(), uint64(numExportedMethods(ms)), false), // numMethods
+				c.getTypeCode(types.NewPointer(typ)),                                    // ptrTo
 				pkgPathPtr,
 				llvm.ConstInt(c.ctx.Int32Type(), uint64(size), false),            // size
 				llvm.ConstInt(c.ctx.Int16Type(), uint64(typ.NumFields()), false), // numFields
@@ -366,20 +406,24 @@ func (c *compilerContext) getTypeCode(typ types.Type) llvm.Value {
 				var offsBytes [binary.MaxVarintLen32]byte
 				offLen := binary.PutUvarint(offsBytes[:], offset)
 
-				data := string(flags) + string(offsBytes[:offLen]) + field.Name() + "\x00"
+				// A tag in the conventional format is preceded by a table with
+				// its keys and unquoted values, followed by the size of the
+				// table, so that StructTag.Lookup can find the table from the
+				// tag and doesn't need to parse it.
+				var tag string
 				if typ.Tag(i) != "" {
 					if len(typ.Tag(i)) > 0xff {
 						c.addError(field.Pos(), fmt.Sprintf("struct tag is %d bytes which is too long, max is 255", len(typ.Tag(i))))
 					}
-					data += string([]byte{byte(len(typ.Tag(i)))}) + typ.Tag(i)
+					if table, ok := structTagTable(typ.Tag(i)); ok {
+						flags |= structFieldFlagHasTagTable
+						tag = table + string([]byte{byte(len(table))})
+					}
+					tag += string([]byte{byte(len(typ.Tag(i)))}) + typ.Tag(i)
 				}
-				dataInitializer := c.ctx.ConstString(data, false)
-				dataGlobal := llvm.AddGlobal(c.mod, dataInitializer.Type(), globalName+"."+field.Name())
-				dataGlobal.SetInitializer(dataInitializer)
-				dataGlobal.SetAlignment(1)
-				dataGlobal.SetUnnamedAddr(true)
-				dataGlobal.SetLinkage(llvm.InternalLinkage)
-				dataGlobal.SetGlobalConstant(true)
+
+				data := string(flags) + string(offsBytes[:offLen]) + field.Name() + "\x00" + tag
+				dataGlobal := c.structFieldData(data)
 				fieldType := c.getTypeCode(field.Type())
 				fields = append(fields, llvm.ConstNamedStruct(structFieldType, []llvm.Value{
 					fieldType,
@@ -391,11 +435,40 @@ func (c *compilerContext) getTypeCode(typ types.Type) llvm.Value {
 			}
 			typeFields = append(typeFields, llvm.ConstArray(structFieldType, fields))
 		case *types.Interface:
-			typeFields = []llvm.Value{c.getTypeCode(types.NewPointer(typ))}
-			// TODO: methods
+			// The method signatures are the same globals that are referenced
+			// from method tables, so reflect can compare them by pointer.
+			var methods []llvm.Value
+			for i := 0; i < typ.NumMethods(); i++ {
+				signatureGlobal := c.getMethodSignature(typ.Method(i))
+				methods = append(methods, llvm.ConstBitCast(signatureGlobal, c.i8ptrType))
+			}
+			typeFields = []llvm.Value{
+				llvm.ConstInt(c.ctx.Int16Type(), uint64(typ.NumMethods()), false), // numMethods
+				c.getTypeCode(types.NewPointer(typ)),                              // ptrTo
+				llvm.ConstArray(c.i8ptrType, methods),                             // methods
+				c.ctx.ConstString(c.getReflectTypeString(typ)+"\x00", false),      // string
+			}
 		case *types.Signature:
-			typeFields = []llvm.Value{c.getTypeCode(types.NewPointer(typ))}
-			// TODO: params, return values, etc
+			numIn := uint64(typ.Params().Len())
+			if typ.Variadic() {
+				numIn |= signatureFlagVariadic
+			}
+			var params []llvm.Value
+			for i := 0; i < typ.Params().Len(); i++ {
+				params = append(params, c.getTypeCode(typ.Params().At(i).Type()))
+			}
+			for i := 0; i < typ.Results().Len(); i++ {
+				params = append(params, c.getTypeCode(typ.Results().At(i).Type()))
+			}
+			typeFields = []llvm.Value{
+				llvm.ConstInt(c.ctx.Int16Type(), numIn, false),                       // numIn
+				c.getTypeCode(types.NewPointer(typ)),                                 // ptrTo
+				llvm.ConstInt(c.ctx.Int16Type(), uint64(typ.Results().Len()), false), // numOut
+				c.getReflectCallThunk(typ, typeCodeName, isLocal),                    // call
+				c.getReflectMakeFuncThunk(typ, typeCodeName, isLocal),                // makeFunc
+				llvm.ConstArray(c.i8ptrType, params),                                 // params
+				c.ctx.ConstString(c.getReflectTypeString(typ)+"\x00", false),         // string
+			}
 		}
 		// Prepend metadata byte.
 		typeFields = append([]llvm.Value{
@@ -446,6 +519,225 @@ func (c *compilerContext) getTypeCode(typ types.Type) llvm.Value {
 	})
 }
 
+// reflectCallThunkSignature is the Go signature of the call thunk stored in
+// each signature type struct. Must be kept up to date with funcType in
+// src/reflect/type.go.
+var reflectCallThunkSignature = types.NewSignature(nil, types.NewTuple(
+	types.NewVar(token.NoPos, nil, "fn", types.Typ[types.UnsafePointer]),
+	types.NewVar(token.NoPos, nil, "args", types.Typ[types.UnsafePointer]),
+	types.NewVar(token.NoPos, nil, "results", types.Typ[types.UnsafePointer]),
+), nil, false)
+
+// getReflectCallThunk returns a func value (without context) that can be used
+// by reflect.Value.Call to call a func value of the given signature. The thunk
+// looks like this in Go syntax:
+//
+//	func thunk(fn *func(x, y int) (int, error), args, results *[2]unsafe.Pointer) {
+//	    r0, r1 := (*fn)(*(*int)(args[0]), *(*int)(args[1]))
+//	    *(*int)(results[0]) = r0
+//	    *(*error)(results[1]) = r1
+//	}
+//
+// That way, the reflect package doesn't need to know anything about the calling
+// convention of the target.
+func (c *compilerContext) getReflectCallThunk(sig *types.Signature, typeCodeName string, isLocal bool) llvm.Value {
+	thunkName := "reflect/types.call:" + typeCodeName
+	thunkType := c.getRawFuncType(reflectCallThunkSignature)
+	var thunk llvm.Value
+	if !isLocal {
+		thunk = c.mod.NamedFunction(thunkName)
+	}
+	if thunk.IsNil() {
+		thunk = llvm.AddFunction(c.mod, thunkName, thunkType)
+		c.addStandardAttributes(thunk)
+		if isLocal {
+			thunk.SetLinkage(llvm.InternalLinkage)
+		} else {
+			thunk.SetLinkage(llvm.LinkOnceODRLinkage)
+		}
+		thunk.SetUnnamedAddr(true)
+
+		// Create a new builder just to create this thunk.
+		b := builder{
+			compilerContext: c,
+			Builder:         c.ctx.NewBuilder(),
+		}
+		defer b.Builder.Dispose()
+		b.SetInsertPointAtEnd(c.ctx.AddBasicBlock(thunk, "entry"))
+
+		// Load the func value that should be called.
+		funcValueType := c.getFuncType(sig)
+		fnPtr := b.CreateBitCast(thunk.Param(0), llvm.PointerType(funcValueType, 0), "")
+		fnType, funcPtr, context := b.decodeFuncValue(b.CreateLoad(funcValueType, fnPtr, ""), sig)
+
+		// Load all parameters, one pointer for each parameter.
+		argsPtr := b.CreateBitCast(thunk.Param(1), llvm.PointerType(c.i8ptrType, 0), "")
+		var params []llvm.Value
+		for i := 0; i < sig.Params().Len(); i++ {
+			paramType := c.getLLVMType(sig.Params().At(i).Type())
+			param := llvm.ConstNull(paramType)
+			if c.targetData.TypeAllocSize(paramType) != 0 {
+				gep := b.CreateInBoundsGEP(c.i8ptrType, argsPtr, []llvm.Value{
+					llvm.ConstInt(c.ctx.Int32Type(), uint64(i), false),
+				}, "")
+				ptr := b.CreateLoad(c.i8ptrType, gep, "")
+				ptr = b.CreateBitCast(ptr, llvm.PointerType(paramType, 0), "")
+				param = b.CreateLoad(paramType, ptr, "")
+			}
+			params = append(params, b.expandFormalParam(param)...)
+		}
+		params = append(params, context)
+
+		// Do the call, and store the results.
+		result := b.CreateCall(fnType, funcPtr, params, "")
+		resultsPtr := b.CreateBitCast(thunk.Param(2), llvm.PointerType(c.i8ptrType, 0), "")
+		for i := 0; i < sig.Results().Len(); i++ {
+			value := result
+			if sig.Results().Len() > 1 {
+				value = b.CreateExtractValue(result, i, "")
+			}
+			if c.targetData.TypeAllocSize(value.Type()) == 0 {
+				continue
+			}
+			gep := b.CreateInBoundsGEP(c.i8ptrType, resultsPtr, []llvm.Value{
+				llvm.ConstInt(c.ctx.Int32Type(), uint64(i), false),
+			}, "")
+			ptr := b.CreateLoad(c.i8ptrType, gep, "")
+			ptr = b.CreateBitCast(ptr, llvm.PointerType(value.Type(), 0), "")
+			b.CreateStore(value, ptr)
+		}
+		b.CreateRetVoid()
+	}
+	return c.ctx.ConstStruct([]llvm.Value{
+		llvm.ConstNull(c.i8ptrType),
+		llvm.ConstBitCast(thunk, c.rawVoidFuncType),
+	}, false)
+}
+
+// reflectMakeFuncSignature is the Go signature of the call field of
+// makeFuncImpl in src/reflect/makefunc.go, which is called by the makeFunc
+// thunk.
+var reflectMakeFuncSignature = types.NewSignature(nil, types.NewTuple(
+	types.NewVar(token.NoPos, nil, "args", types.Typ[types.UnsafePointer]),
+	types.NewVar(token.NoPos, nil, "results", types.Typ[types.UnsafePointer]),
+), nil, false)
+
+// getReflectMakeFuncThunk returns a func value (without context) whose function
+// pointer is used for the functions created by reflect.MakeFunc. The context of
+// these functions is a *reflect.makeFuncImpl. The thunk looks like this in Go
+// syntax:
+//
+//	func thunk(x, y int, context *makeFuncImpl) (int, error) {
+//	    var r0 int
+//	    var r1 error
+//	    context.call(&[2]unsafe.Pointer{&x, &y}, &[2]unsafe.Pointer{&r0, &r1})
+//	    return r0, r1
+//	}
+//
+// It is the inverse of the call thunk: it converts a call using the calling
+// convention of the target into a call with pointers to the parameters and
+// results, which the reflect package can handle.
+func (c *compilerContext) getReflectMakeFuncThunk(sig *types.Signature, typeCodeName string, isLocal bool) llvm.Value {
+	thunkName := "reflect/types.makeFunc:" + typeCodeName
+	thunkType := c.getRawFuncType(sig)
+	var thunk llvm.Value
+	if !isLocal {
+		thunk = c.mod.NamedFunction(thunkName)
+	}
+	if thunk.IsNil() {
+		thunk = llvm.AddFunction(c.mod, thunkName, thunkType)
+		c.addStandardAttributes(thunk)
+		if isLocal {
+			thunk.SetLinkage(llvm.InternalLinkage)
+		} else {
+			thunk.SetLinkage(llvm.LinkOnceODRLinkage)
+		}
+		thunk.SetUnnamedAddr(true)
+
+		// Create a new builder just to create this thunk.
+		b := builder{
+			compilerContext: c,
+			Builder:         c.ctx.NewBuilder(),
+		}
+		defer b.Builder.Dispose()
+		b.SetInsertPointAtEnd(c.ctx.AddBasicBlock(thunk, "entry"))
+
+		// Store all parameters in an alloca, and create an array with a
+		// pointer to each of them.
+		args := llvm.ConstNull(c.i8ptrType)
+		if sig.Params().Len() != 0 {
+			argsType := llvm.ArrayType(c.i8ptrType, sig.Params().Len())
+			argsAlloca := b.CreateAlloca(argsType, "args")
+			paramIndex := 0
+			for i := 0; i < sig.Params().Len(); i++ {
+				paramType := c.getLLVMType(sig.Params().At(i).Type())
+				var fields []llvm.Value
+				for range c.expandFormalParamType(paramType, "", nil) {
+					fields = append(fields, thunk.Param(paramIndex))
+					paramIndex++
+				}
+				alloca := b.CreateAlloca(paramType, "")
+				b.CreateStore(b.collapseFormalParam(paramType, fields), alloca)
+				gep := b.CreateInBoundsGEP(argsType, argsAlloca, []llvm.Value{
+					llvm.ConstInt(c.ctx.Int32Type(), 0, false),
+					llvm.ConstInt(c.ctx.Int32Type(), uint64(i), false),
+				}, "")
+				b.CreateStore(b.CreateBitCast(alloca, c.i8ptrType, ""), gep)
+			}
+			args = b.CreateBitCast(argsAlloca, c.i8ptrType, "")
+		}
+
+		// Create an alloca for each result, and an array with a pointer to
+		// each of them.
+		results := llvm.ConstNull(c.i8ptrType)
+		var resultAllocas []llvm.Value
+		if sig.Results().Len() != 0 {
+			resultsType := llvm.ArrayType(c.i8ptrType, sig.Results().Len())
+			resultsAlloca := b.CreateAlloca(resultsType, "results")
+			for i := 0; i < sig.Results().Len(); i++ {
+				resultType := c.getLLVMType(sig.Results().At(i).Type())
+				alloca := b.CreateAlloca(resultType, "")
+				b.CreateStore(llvm.ConstNull(resultType), alloca)
+				resultAllocas = append(resultAllocas, alloca)
+				gep := b.CreateInBoundsGEP(resultsType, resultsAlloca, []llvm.Value{
+					llvm.ConstInt(c.ctx.Int32Type(), 0, false),
+					llvm.ConstInt(c.ctx.Int32Type(), uint64(i), false),
+				}, "")
+				b.CreateStore(b.CreateBitCast(alloca, c.i8ptrType, ""), gep)
+			}
+			results = b.CreateBitCast(resultsAlloca, c.i8ptrType, "")
+		}
+
+		// The context is a pointer to a makeFuncImpl, which starts with the
+		// func value that should be called.
+		context := thunk.Param(thunk.ParamsCount() - 1)
+		funcValueType := c.getFuncType(reflectMakeFuncSignature)
+		fnPtr := b.CreateBitCast(context, llvm.PointerType(funcValueType, 0), "")
+		fnType, funcPtr, fnContext := b.decodeFuncValue(b.CreateLoad(funcValueType, fnPtr, ""), reflectMakeFuncSignature)
+		b.CreateCall(fnType, funcPtr, []llvm.Value{args, results, fnContext}, "")
+
+		// Load the results and return them.
+		switch sig.Results().Len() {
+		case 0:
+			b.CreateRetVoid()
+		case 1:
+			b.CreateRet(b.CreateLoad(thunkType.ReturnType(), resultAllocas[0], ""))
+		default:
+			returnType := thunkType.ReturnType()
+			value := llvm.Undef(returnType)
+			for i, alloca := range resultAllocas {
+				result := b.CreateLoad(returnType.StructElementTypes()[i], alloca, "")
+				value = b.CreateInsertValue(value, result, i, "")
+			}
+			b.CreateRet(value)
+		}
+	}
+	return c.ctx.ConstStruct([]llvm.Value{
+		llvm.ConstNull(c.i8ptrType),
+		llvm.ConstBitCast(thunk, c.rawVoidFuncType),
+	}, false)
+}
+
 // getTypeKind returns the type kind for the given type, as defined by
 // reflect.Kind.
 func getTypeKind(t types.Type) uint8 {
@@ -494,17 +786,70 @@ var basicTypeNames = [...]string{
 	types.UnsafePointer: "unsafe.Pointer",
 }
 
+// structTagTable returns the table of keys and values of a struct tag in the
+// conventional format (see reflect.StructTag). The table starts with the number
+// of keys, followed by each key and its unquoted value, each prefixed with its
+// length. It returns false if the tag is not in the conventional format, or if
+// the table doesn't fit in 255 bytes.
+func structTagTable(tag string) (string, bool) {
+	numKeys := 0
+	var table string
+	for {
+		// Skip leading space.
+		tag = strings.TrimLeft(tag, " ")
+		if tag == "" {
+			break
+		}
+
+		// Scan to colon, like reflect.StructTag.Lookup.
+		i := 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			return "", false
+		}
+		name := tag[:i]
+		tag = tag[i+1:]
+
+		// Scan quoted string to find value.
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			return "", false
+		}
+		value, err := strconv.Unquote(tag[:i+1])
+		if err != nil {
+			return "", false
+		}
+		tag = tag[i+1:]
+		numKeys++
+		table += string([]byte{byte(len(name))}) + name + string([]byte{byte(len(value))}) + value
+	}
+	table = string([]byte{byte(numKeys)}) + table
+	if numKeys == 0 || len(table) > 0xff {
+		return "", false
+	}
+	return table, true
+}
+
 // getTypeCodeName returns a name for this type that can be used in the
 // interface lowering pass to assign type codes as expected by the reflect
 // package. See getTypeCodeNum.
 func getTypeCodeName(t types.Type) (string, bool) {
 	switch t := t.(type) {
 	case *types.Named:
+		name, isLocal := getNamedTypeName(t)
 		// Note: check for `t.Obj().Pkg() != nil` for Go 1.18 only.
 		if t.Obj().Pkg() != nil && t.Obj().Parent() != t.Obj().Pkg().Scope() {
-			return "named:" + t.String() + "$local", true
+			return "named:" + name + "$local", true
 		}
-		return "named:" + t.String(), false
+		return "named:" + name, isLocal
 	case *types.Array:
 		s, isLocal := getTypeCodeName(t.Elem())
 		return "array:" + strconv.FormatInt(t.Len(), 10) + ":" + s, isLocal
@@ -579,7 +924,14 @@ func getTypeCodeName(t types.Type) (string, bool) {
 			if local {
 				isLocal = true
 			}
-			elems[i] = embedded + t.Field(i).Name() + ":" + s
+			name := t.Field(i).Name()
+			if !t.Field(i).Exported() && t.Field(i).Pkg() != nil {
+				// Structs with unexported fields declared in different
+				// packages are different types, and have a different
+				// PkgPath for these fields.
+				name = t.Field(i).Pkg().Path() + "." + name
+			}
+			elems[i] = embedded + name + ":" + s
 			if t.Tag(i) != "" {
 				elems[i] += "`" + t.Tag(i) + "`"
 			}
@@ -590,16 +942,192 @@ func getTypeCodeName(t types.Type) (string, bool) {
 	}
 }
 
+// getNamedTypeName returns the fully qualified name of a named type, as used
+// in type code names. For instantiated generic types, the type arguments are
+// written out using getTypeCodeName instead of types.TypeString. This makes
+// sure identical types get the same name regardless of how the type arguments
+// were spelled (for example any versus interface{}) or in which package the
+// type was instantiated, so that the type code globals get merged by the
+// linker.
+func getNamedTypeName(t *types.Named) (string, bool) {
+	name := t.Obj().Name()
+	if pkg := t.Obj().Pkg(); pkg != nil {
+		name = pkg.Path() + "." + name
+	}
+	isLocal := false
+	if args := t.TypeArgs(); args.Len() != 0 {
+		elems := make([]string, args.Len())
+		for i := range elems {
+			s, local := getTypeCodeName(args.At(i))
+			if local {
+				isLocal = true
+			}
+			elems[i] = s
+		}
+		name += "[" + strings.Join(elems, ",") + "]"
+	}
+	return name, isLocal
+}
+
+// getReflectTypeName returns the name of a named type as returned by
+// reflect.Type.Name(). For instantiated generic types, this includes the type
+// arguments qualified by their package path, like "Pair[int,main.Point]". The
+// type arguments are formatted like reflect does, so that the name doesn't
+// depend on how they were spelled (for example any versus interface{}).
+func getReflectTypeName(t *types.Named) string {
+	name := t.Obj().Name()
+	if args := t.TypeArgs(); args.Len() != 0 {
+		elems := make([]string, args.Len())
+		for i := range elems {
+			elems[i] = formatReflectType(args.At(i), (*types.Package).Path)
+		}
+		name += "[" + strings.Join(elems, ",") + "]"
+	}
+	return name
+}
+
+// getReflectTypeString returns the string returned by reflect.Type.String()
+// for function and interface types, which can't be reconstructed at runtime
+// from the type code. It returns the empty string if type strings are
+// stripped, in which case reflect falls back to "func" and "interface {}".
+func (c *compilerContext) getReflectTypeString(typ types.Type) string {
+	if c.NoTypeStrings {
+		return ""
+	}
+	return reflectTypeString(typ)
+}
+
+// reflectTypeString formats a type in the same way as reflect.Type.String()
+// in upstream Go: types are qualified by their package name and parameter
+// names are omitted.
+func reflectTypeString(typ types.Type) string {
+	return formatReflectType(typ, (*types.Package).Name)
+}
+
+// formatReflectType formats a type like reflectTypeString, but with named
+// types qualified by the given function.
+func formatReflectType(typ types.Type, qualify func(*types.Package) string) string {
+	switch typ := typ.(type) {
+	case *types.Named:
+		if pkg := typ.Obj().Pkg(); pkg != nil {
+			return qualify(pkg) + "." + getReflectTypeName(typ)
+		}
+		return getReflectTypeName(typ)
+	case *types.Basic:
+		return basicTypeNames[typ.Kind()]
+	case *types.Pointer:
+		return "*" + formatReflectType(typ.Elem(), qualify)
+	case *types.Slice:
+		return "[]" + formatReflectType(typ.Elem(), qualify)
+	case *types.Array:
+		return "[" + strconv.FormatInt(typ.Len(), 10) + "]" + formatReflectType(typ.Elem(), qualify)
+	case *types.Map:
+		return "map[" + formatReflectType(typ.Key(), qualify) + "]" + formatReflectType(typ.Elem(), qualify)
+	case *types.Chan:
+		elem := formatReflectType(typ.Elem(), qualify)
+		switch typ.Dir() {
+		case types.SendOnly:
+			return "chan<- " + elem
+		case types.RecvOnly:
+			return "<-chan " + elem
+		default:
+			if strings.HasPrefix(elem, "<-") {
+				return "chan (" + elem + ")"
+			}
+			return "chan " + elem
+		}
+	case *types.Struct:
+		if typ.NumFields() == 0 {
+			return "struct {}"
+		}
+		fields := make([]string, typ.NumFields())
+		for i := range fields {
+			field := typ.Field(i)
+			s := formatReflectType(field.Type(), qualify)
+			if !field.Embedded() {
+				s = field.Name() + " " + s
+			}
+			if tag := typ.Tag(i); tag != "" {
+				s += " " + strconv.Quote(tag)
+			}
+			fields[i] = s
+		}
+		return "struct { " + strings.Join(fields, "; ") + " }"
+	case *types.Signature:
+		return "func" + reflectSignatureString(typ, qualify)
+	case *types.Interface:
+		if typ.NumMethods() == 0 {
+			return "interface {}"
+		}
+		methods := make([]string, typ.NumMethods())
+		for i := range methods {
+			method := typ.Method(i)
+			name := method.Name()
+			if !method.Exported() && method.Pkg() != nil {
+				name = qualify(method.Pkg()) + "." + name
+			}
+			methods[i] = name + reflectSignatureString(method.Type().(*types.Signature), qualify)
+		}
+		return "interface { " + strings.Join(methods, "; ") + " }"
+	default:
+		return typ.String()
+	}
+}
+
+// reflectSignatureString formats the parameters and results of a function
+// signature, like "(int, ...string) (bool, error)".
+func reflectSignatureString(sig *types.Signature, qualify func(*types.Package) string) string {
+	params := make([]string, sig.Params().Len())
+	for i := range params {
+		t := sig.Params().At(i).Type()
+		if sig.Variadic() && i == len(params)-1 {
+			params[i] = "..." + formatReflectType(t.(*types.Slice).Elem(), qualify)
+		} else {
+			params[i] = formatReflectType(t, qualify)
+		}
+	}
+	s := "(" + strings.Join(params, ", ") + ")"
+	switch sig.Results().Len() {
+	case 0:
+	case 1:
+		s += " " + formatReflectType(sig.Results().At(0).Type(), qualify)
+	default:
+		results := make([]string, sig.Results().Len())
+		for i := range results {
+			results[i] = formatReflectType(sig.Results().At(i).Type(), qualify)
+		}
+		s += " (" + strings.Join(results, ", ") + ")"
+	}
+	return s
+}
+
+// getTypeMethodSetName returns the name of the global method set of the given
+// type. Like getNamedTypeName, it is stable across packages for instantiated
+// generic types.
+func getTypeMethodSetName(typ types.Type) string {
+	switch typ := typ.(type) {
+	case *types.Named:
+		name, _ := getNamedTypeName(typ)
+		return name + "$methodset"
+	case *types.Pointer:
+		if named, ok := typ.Elem().(*types.Named); ok {
+			name, _ := getNamedTypeName(named)
+			return "*" + name + "$methodset"
+		}
+	}
+	return typ.String() + "$methodset"
+}
+
 // getTypeMethodSet returns a reference (GEP) to a global method set. This
 // method set should be unreferenced after the interface lowering pass.
 func (c *compilerContext) getTypeMethodSet(typ types.Type) llvm.Value {
-	globalName := typ.String() + "$methodset"
+	globalName := getTypeMethodSetName(typ)
 	global := c.mod.NamedGlobal(globalName)
 	if global.IsNil() {
 		ms := c.program.MethodSets.MethodSet(typ)
 
 		// Create method set.
-		var signatures, wrappers []llvm.Value
+		var signatures, wrappers, reflectMethods []llvm.Value
 		for i := 0; i < ms.Len(); i++ {
 			method := ms.At(i)
 			signatureGlobal := c.getMethodSignature(method.Obj().(*types.Func))
@@ -612,6 +1140,34 @@ func (c *compilerContext) getTypeMethodSet(typ types.Type) llvm.Value {
 			}
 			wrapper := c.getInterfaceInvokeWrapper(fn, llvmFnType, llvmFn)
 			wrappers = append(wrappers, wrapper)
+			if method.Obj().Exported() {
+				// Only exported methods are visible through reflection. The
+				// invoke wrapper takes the receiver as stored in an interface,
+				// so reflect can call it like a func(unsafe.Pointer, params...)
+				// using the call thunk of that signature.
+				sig := method.Type().(*types.Signature)
+				invokeSig := methodFuncType(types.Typ[types.UnsafePointer], sig)
+				invokeSigName, isLocal := getTypeCodeName(invokeSig)
+				reflectMethods = append(reflectMethods, c.ctx.ConstStruct([]llvm.Value{
+					llvm.ConstBitCast(signatureGlobal, c.i8ptrType),
+					c.getTypeCode(methodFuncType(typ, sig)),
+					llvm.ConstBitCast(wrapper, c.rawVoidFuncType),
+					c.getReflectCallThunk(invokeSig, invokeSigName, isLocal),
+				}, false))
+			}
+		}
+
+		// Create the method table used by reflect, which replaces the method
+		// set in the type struct during interface lowering.
+		reflectMethodTable := llvm.ConstNull(c.i8ptrType)
+		if len(reflectMethods) != 0 {
+			value := llvm.ConstArray(reflectMethods[0].Type(), reflectMethods)
+			table := llvm.AddGlobal(c.mod, value.Type(), strings.TrimSuffix(globalName, "$methodset")+"$methods")
+			table.SetInitializer(value)
+			table.SetGlobalConstant(true)
+			table.SetUnnamedAddr(true)
+			table.SetLinkage(llvm.LinkOnceODRLinkage)
+			reflectMethodTable = llvm.ConstBitCast(table, c.i8ptrType)
 		}
 
 		// Construct global value.
@@ -619,6 +1175,7 @@ func (c *compilerContext) getTypeMethodSet(typ types.Type) llvm.Value {
 			llvm.ConstInt(c.uintptrType, uint64(ms.Len()), false),
 			llvm.ConstArray(c.i8ptrType, signatures),
 			c.ctx.ConstStruct(wrappers, false),
+			reflectMethodTable,
 		}, false)
 		global = llvm.AddGlobal(c.mod, globalValue.Type(), globalName)
 		global.SetInitializer(globalValue)
@@ -629,6 +1186,50 @@ func (c *compilerContext) getTypeMethodSet(typ types.Type) llvm.Value {
 	return global
 }
 
+// hasReflectPragma returns whether the given type declaration has a
+// //go:reflect pragma.
+func (c *compilerContext) hasReflectPragma(member *ssa.Type) bool {
+	doc := c.astComments[member.Object().Pkg().Path()+"."+member.Name()]
+	if doc == nil {
+		return false
+	}
+	for _, comment := range doc.List {
+		if comment.Text == "//go:reflect" {
+			return true
+		}
+	}
+	return false
+}
+
+// createReflectKeep implements the //go:reflect pragma: the type and the
+// pointer to it are kept in the program with all their reflect metadata, even
+// if they are only created through reflection (for example with reflect.New)
+// or if the methods of the type are only called through reflection.
+//
+// The type codes are added to a list with appending linkage, which is read and
+// removed by the interface lowering pass.
+func (c *compilerContext) createReflectKeep(member *ssa.Type) {
+	if named, ok := member.Type().(*types.Named); ok && named.TypeParams().Len() != 0 {
+		c.addError(member.Pos(), "//go:reflect is not supported on generic types")
+		return
+	}
+	var entries []llvm.Value
+	if global := c.mod.NamedGlobal("reflect/types.keep"); !global.IsNil() {
+		initializer := global.Initializer()
+		for i := 0; i < initializer.Type().ArrayLength(); i++ {
+			entries = append(entries, c.builder.CreateExtractValue(initializer, i, ""))
+		}
+		global.EraseFromParentAsGlobal()
+	}
+	for _, typ := range []types.Type{member.Type(), types.NewPointer(member.Type())} {
+		entries = append(entries, llvm.ConstBitCast(c.getTypeCode(typ), c.i8ptrType))
+	}
+	initializer := llvm.ConstArray(c.i8ptrType, entries)
+	global := llvm.AddGlobal(c.mod, initializer.Type(), "reflect/types.keep")
+	global.SetInitializer(initializer)
+	global.SetLinkage(llvm.AppendingLinkage)
+}
+
 // getMethodSignatureName returns a unique name (that can be used as the name of
 // a global) for the given method.
 func (c *compilerContext) getMethodSignatureName(method *types.Func) string {
@@ -637,29 +1238,55 @@ func (c *compilerContext) getMethodSignatureName(method *types.Func) string {
 	if token.IsExported(method.Name()) {
 		globalName = "reflect/methods." + signature
 	} else {
-		globalName = method.Type().(*types.Signature).Recv().Pkg().Path() + ".$methods." + signature
+		globalName = method.Pkg().Path() + ".$methods." + signature
 	}
 	return globalName
 }
 
-// getMethodSignature returns a global variable which is a reference to an
-// external *i8 indicating the indicating the signature of this method. It is
-// used during the interface lowering pass.
+// getMethodSignature returns a global variable that identifies the signature of
+// this method. It is used during the interface lowering pass, and by the reflect
+// package which reads the method type (without receiver) and the method name
+// from it. See methodSignature in src/reflect/type.go.
 func (c *compilerContext) getMethodSignature(method *types.Func) llvm.Value {
 	globalName := c.getMethodSignatureName(method)
 	signatureGlobal := c.mod.NamedGlobal(globalName)
 	if signatureGlobal.IsNil() {
-		// TODO: put something useful in these globals, such as the method
-		// signature. Useful to one day implement reflect.Value.Method(n).
-		signatureGlobal = llvm.AddGlobal(c.mod, c.ctx.Int8Type(), globalName)
-		signatureGlobal.SetInitializer(llvm.ConstInt(c.ctx.Int8Type(), 0, false))
+		sig := method.Type().(*types.Signature)
+		value := c.ctx.ConstStruct([]llvm.Value{
+			c.getTypeCode(types.NewSignature(nil, sig.Params(), sig.Results(), sig.Variadic())), // typ
+			c.ctx.ConstString(method.Name()+"\x00", false),                                      // name
+		}, false)
+		signatureGlobal = llvm.AddGlobal(c.mod, value.Type(), globalName)
+		signatureGlobal.SetInitializer(value)
 		signatureGlobal.SetLinkage(llvm.LinkOnceODRLinkage)
 		signatureGlobal.SetGlobalConstant(true)
-		signatureGlobal.SetAlignment(1)
+		signatureGlobal.SetAlignment(int(c.targetData.ABITypeAlignment(c.i8ptrType)))
 	}
 	return signatureGlobal
 }
 
+// methodFuncType returns the type of a method expression of the given method
+// of recv, that is, the method signature with the receiver as first parameter.
+func methodFuncType(recv types.Type, sig *types.Signature) *types.Signature {
+	params := []*types.Var{types.NewParam(token.NoPos, nil, "", recv)}
+	for i := 0; i < sig.Params().Len(); i++ {
+		params = append(params, sig.Params().At(i))
+	}
+	return types.NewSignature(nil, types.NewTuple(params...), sig.Results(), sig.Variadic())
+}
+
+// numExportedMethods returns the number of exported methods in the method set,
+// which is the number of methods visible through reflection.
+func numExportedMethods(ms *types.MethodSet) int {
+	n := 0
+	for i := 0; i < ms.Len(); i++ {
+		if ms.At(i).Obj().Exported() {
+			n++
+		}
+	}
+	return n
+}
+
 // createTypeAssert will emit the code for a typeassert, used in if statements
 // and in type switches (Go SSA does not have type switches, only if/else
 // chains). Note that even though the Go SSA does not contain type switches,
@@ -949,7 +1576,21 @@ func typestring(t types.Type) string {
 	case *types.Map:
 		return "map[" + typestring(t.Key()) + "]" + typestring(t.Elem())
 	case *types.Named:
-		return t.String()
+		// Instantiated generic types are written out with typestring for the
+		// type arguments, so that for example T[any] and T[interface{}] result
+		// in the same method signature.
+		s := t.Obj().Name()
+		if pkg := t.Obj().Pkg(); pkg != nil {
+			s = pkg.Path() + "." + s
+		}
+		if args := t.TypeArgs(); args.Len() != 0 {
+			elems := make([]string, args.Len())
+			for i := range elems {
+				elems[i] = typestring(args.At(i))
+			}
+			s += "[" + strings.Join(elems, ",") + "]"
+		}
+		return s
 	case *types.Pointer:
 		return "*" + typestring(t.Elem())
 	case *types.Signature:
@@ -1,6 +1,7 @@
 package compiler
 
 import (
+	"go/token"
 	"strconv"
 	"strings"
 
@@ -36,6 +37,9 @@ func (b *builder) createInterruptGlobal(instr *ssa.CallCommon) (llvm.Value, erro
 		// Fall back to a generic error.
 		return llvm.Value{}, b.makeError(instr.Pos(), "interrupt function must be constant")
 	}
+	if fn, ok := instr.Args[1].(*ssa.Function); ok {
+		b.checkInterruptHandler(fn)
+	}
 	_, funcRawPtr, funcContext := b.decodeFuncValue(funcValue, nil)
 	funcPtr := llvm.ConstPtrToInt(funcRawPtr, b.uintptrType)
 
@@ -92,3 +96,26 @@ func (b *builder) createInterruptGlobal(instr *ssa.CallCommon) (llvm.Value, erro
 
 	return interrupt, nil
 }
+
+// checkInterruptHandler reports an error for every channel operation in the
+// given interrupt handler that may block. An interrupt handler can't be paused
+// like a goroutine, so it may only use non-blocking channel operations: those
+// in a select statement with a default case.
+func (b *builder) checkInterruptHandler(fn *ssa.Function) {
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			switch instr := instr.(type) {
+			case *ssa.Send:
+				b.addError(instr.Pos(), "channel send in interrupt handler may block: use a select statement with a default case")
+			case *ssa.UnOp:
+				if instr.Op == token.ARROW {
+					b.addError(instr.Pos(), "channel receive in interrupt handler may block: use a select statement with a default case")
+				}
+			case *ssa.Select:
+				if instr.Blocking {
+					b.addError(instr.Pos(), "select statement in interrupt handler may block: add a default case")
+				}
+			}
+		}
+	}
+}
@@ -30,8 +30,21 @@ func (b *builder) defineIntrinsicFunction() {
 		b.createVolatileLoad()
 	case strings.HasPrefix(name, "runtime/volatile.Store"):
 		b.createVolatileStore()
+	case strings.HasPrefix(name, "math/fixed."):
+		b.createFixedPointOp()
 	case strings.HasPrefix(name, "sync/atomic.") && token.IsExported(b.fn.Name()):
 		b.createFunctionStart(true)
+		if b.Race {
+			// Atomic operations synchronize goroutines, which the race
+			// detector needs to know about.
+			ptr := b.CreateBitCast(b.getValue(b.fn.Params[0], getPos(b.fn)), b.i8ptrType, "")
+			if !strings.HasPrefix(b.fn.Name(), "Load") {
+				b.createRuntimeCall("racereleasemerge", []llvm.Value{ptr}, "")
+			}
+			if !strings.HasPrefix(b.fn.Name(), "Store") {
+				b.createRuntimeCall("raceacquire", []llvm.Value{ptr}, "")
+			}
+		}
 		returnValue := b.createAtomicOp(b.fn.Name())
 		if !returnValue.IsNil() {
 			b.CreateRet(returnValue)
@@ -155,6 +168,36 @@ func (b *builder) defineMathOp() {
 	b.CreateRet(result)
 }
 
+// createFixedPointOp implements the builtins of the math/fixed package as calls
+// to the LLVM saturating intrinsics. LLVM expands these to plain integer
+// operations on targets that don't have special instructions for them.
+func (b *builder) createFixedPointOp() {
+	b.createFunctionStart(true)
+	x := b.getValue(b.fn.Params[0], getPos(b.fn))
+	y := b.getValue(b.fn.Params[1], getPos(b.fn))
+	args := []llvm.Value{x, y}
+	var intrinsicName string
+	switch b.fn.Name() {
+	case "addSat":
+		intrinsicName = "llvm.sadd.sat.i32"
+	case "subSat":
+		intrinsicName = "llvm.ssub.sat.i32"
+	default:
+		panic("unreachable: unknown fixed-point operation") // sanity check
+	}
+	paramTypes := make([]llvm.Type, len(args))
+	for i, arg := range args {
+		paramTypes[i] = arg.Type()
+	}
+	llvmFnType := llvm.FunctionType(x.Type(), paramTypes, false)
+	llvmFn := b.mod.NamedFunction(intrinsicName)
+	if llvmFn.IsNil() {
+		llvmFn = llvm.AddFunction(b.mod, intrinsicName, llvmFnType)
+	}
+	result := b.createCall(llvmFnType, llvmFn, args, "")
+	b.CreateRet(result)
+}
+
 // Implement most math/bits functions.
 //
 // This implements all the functions that operate on bits. It does not yet
@@ -33,18 +33,20 @@ func (b *builder) createMakeMap(expr *ssa.MakeMap) (llvm.Value, error) {
 		// Trivially comparable keys.
 		llvmKeyType = b.getLLVMType(keyType)
 		alg = hashmapAlgorithmBinary
-	} else {
-		// All other keys. Implemented as map[interface{}]valueType for ease of
-		// implementation.
+	} else if _, ok := keyType.(*types.Interface); ok {
+		// Interface keys.
 		llvmKeyType = b.getLLVMRuntimeType("_interface")
 		alg = hashmapAlgorithmInterface
+	} else {
+		// All other keys, like floats and structs with string fields. They
+		// are hashed and compared with functions for this key type.
+		llvmKeyType = b.getLLVMType(keyType)
 	}
 	keySize := b.targetData.TypeAllocSize(llvmKeyType)
 	valueSize := b.targetData.TypeAllocSize(llvmValueType)
 	llvmKeySize := llvm.ConstInt(b.uintptrType, keySize, false)
 	llvmValueSize := llvm.ConstInt(b.uintptrType, valueSize, false)
 	sizeHint := llvm.ConstInt(b.uintptrType, 8, false)
-	algEnum := llvm.ConstInt(b.ctx.Int8Type(), alg, false)
 	if expr.Reserve != nil {
 		sizeHint = b.getValue(expr.Reserve, getPos(expr))
 		var err error
@@ -53,6 +55,12 @@ func (b *builder) createMakeMap(expr *ssa.MakeMap) (llvm.Value, error) {
 			return llvm.Value{}, err
 		}
 	}
+	if !hashmapHasAlgorithm(keyType) {
+		keyHash, keyEqual := b.getMapKeyFuncs(mapType.Key())
+		hashmap := b.createRuntimeCall("hashmapMakeFunc", []llvm.Value{llvmKeySize, llvmValueSize, sizeHint, keyHash, keyEqual}, "")
+		return hashmap, nil
+	}
+	algEnum := llvm.ConstInt(b.ctx.Int8Type(), alg, false)
 	hashmap := b.createRuntimeCall("hashmapMake", []llvm.Value{llvmKeySize, llvmValueSize, sizeHint, algEnum}, "")
 	return hashmap, nil
 }
@@ -60,6 +68,7 @@ func (b *builder) createMakeMap(expr *ssa.MakeMap) (llvm.Value, error) {
 // createMapLookup returns the value in a map. It calls a runtime function
 // depending on the map key type to load the map value and its comma-ok value.
 func (b *builder) createMapLookup(keyType, valueType types.Type, m, key llvm.Value, commaOk bool, pos token.Pos) (llvm.Value, error) {
+	b.createRaceAccess(nil, m, b.ctx.Int8Type(), false, pos)
 	llvmValueType := b.getLLVMType(valueType)
 
 	// Allocate the memory for the resulting type. Do not zero this memory: it
@@ -78,7 +87,6 @@ func (b *builder) createMapLookup(keyType, valueType types.Type, m, key llvm.Val
 
 	// Do the lookup. How it is done depends on the key type.
 	var commaOkValue llvm.Value
-	origKeyType := keyType
 	keyType = keyType.Underlying()
 	if t, ok := keyType.(*types.Basic); ok && t.Info()&types.IsString != 0 {
 		// key is a string
@@ -95,15 +103,19 @@ func (b *builder) createMapLookup(keyType, valueType types.Type, m, key llvm.Val
 		params := []llvm.Value{m, mapKeyPtr, mapValuePtr, mapValueSize}
 		commaOkValue = b.createRuntimeCall("hashmapBinaryGet", params, "")
 		b.emitLifetimeEnd(mapKeyPtr, mapKeySize)
-	} else {
-		// Not trivially comparable using memcmp. Make it an interface instead.
-		itfKey := key
-		if _, ok := keyType.(*types.Interface); !ok {
-			// Not already an interface, so convert it to an interface now.
-			itfKey = b.createMakeInterface(key, origKeyType, pos)
-		}
-		params := []llvm.Value{m, itfKey, mapValuePtr, mapValueSize}
+	} else if _, ok := keyType.(*types.Interface); ok {
+		// key is an interface
+		params := []llvm.Value{m, key, mapValuePtr, mapValueSize}
 		commaOkValue = b.createRuntimeCall("hashmapInterfaceGet", params, "")
+	} else {
+		// key is hashed and compared with the functions passed to
+		// hashmapMakeFunc, so it must be stored in memory like binary keys
+		mapKeyAlloca, mapKeyPtr, mapKeySize := b.createTemporaryAlloca(key.Type(), "hashmap.key")
+		b.CreateStore(key, mapKeyAlloca)
+		b.zeroUndefBytes(b.getLLVMType(keyType), mapKeyAlloca)
+		params := []llvm.Value{m, mapKeyPtr, mapValuePtr, mapValueSize}
+		commaOkValue = b.createRuntimeCall("hashmapGenericGet", params, "")
+		b.emitLifetimeEnd(mapKeyPtr, mapKeySize)
 	}
 
 	// Load the resulting value from the hashmap. The value is set to the zero
@@ -124,9 +136,9 @@ func (b *builder) createMapLookup(keyType, valueType types.Type, m, key llvm.Val
 // createMapUpdate updates a map key to a given value, by creating an
 // appropriate runtime call.
 func (b *builder) createMapUpdate(keyType types.Type, m, key, value llvm.Value, pos token.Pos) {
+	b.createRaceAccess(nil, m, b.ctx.Int8Type(), true, pos)
 	valueAlloca, valuePtr, valueSize := b.createTemporaryAlloca(value.Type(), "hashmap.value")
 	b.CreateStore(value, valueAlloca)
-	origKeyType := keyType
 	keyType = keyType.Underlying()
 	if t, ok := keyType.(*types.Basic); ok && t.Info()&types.IsString != 0 {
 		// key is a string
@@ -140,15 +152,19 @@ func (b *builder) createMapUpdate(keyType types.Type, m, key, value llvm.Value,
 		params := []llvm.Value{m, keyPtr, valuePtr}
 		b.createRuntimeCall("hashmapBinarySet", params, "")
 		b.emitLifetimeEnd(keyPtr, keySize)
-	} else {
-		// Key is not trivially comparable, so compare it as an interface instead.
-		itfKey := key
-		if _, ok := keyType.(*types.Interface); !ok {
-			// Not already an interface, so convert it to an interface first.
-			itfKey = b.createMakeInterface(key, origKeyType, pos)
-		}
-		params := []llvm.Value{m, itfKey, valuePtr}
+	} else if _, ok := keyType.(*types.Interface); ok {
+		// key is an interface
+		params := []llvm.Value{m, key, valuePtr}
 		b.createRuntimeCall("hashmapInterfaceSet", params, "")
+	} else {
+		// key is hashed and compared with the functions passed to
+		// hashmapMakeFunc
+		keyAlloca, keyPtr, keySize := b.createTemporaryAlloca(key.Type(), "hashmap.key")
+		b.CreateStore(key, keyAlloca)
+		b.zeroUndefBytes(b.getLLVMType(keyType), keyAlloca)
+		params := []llvm.Value{m, keyPtr, valuePtr}
+		b.createRuntimeCall("hashmapGenericSet", params, "")
+		b.emitLifetimeEnd(keyPtr, keySize)
 	}
 	b.emitLifetimeEnd(valuePtr, valueSize)
 }
@@ -156,7 +172,7 @@ func (b *builder) createMapUpdate(keyType types.Type, m, key, value llvm.Value,
 // createMapDelete deletes a key from a map by calling the appropriate runtime
 // function. It is the implementation of the Go delete() builtin.
 func (b *builder) createMapDelete(keyType types.Type, m, key llvm.Value, pos token.Pos) error {
-	origKeyType := keyType
+	b.createRaceAccess(nil, m, b.ctx.Int8Type(), true, pos)
 	keyType = keyType.Underlying()
 	if t, ok := keyType.(*types.Basic); ok && t.Info()&types.IsString != 0 {
 		// key is a string
@@ -171,17 +187,21 @@ func (b *builder) createMapDelete(keyType types.Type, m, key llvm.Value, pos tok
 		b.createRuntimeCall("hashmapBinaryDelete", params, "")
 		b.emitLifetimeEnd(keyPtr, keySize)
 		return nil
-	} else {
-		// Key is not trivially comparable, so compare it as an interface
-		// instead.
-		itfKey := key
-		if _, ok := keyType.(*types.Interface); !ok {
-			// Not already an interface, so convert it to an interface first.
-			itfKey = b.createMakeInterface(key, origKeyType, pos)
-		}
-		params := []llvm.Value{m, itfKey}
+	} else if _, ok := keyType.(*types.Interface); ok {
+		// key is an interface
+		params := []llvm.Value{m, key}
 		b.createRuntimeCall("hashmapInterfaceDelete", params, "")
 		return nil
+	} else {
+		// key is hashed and compared with the functions passed to
+		// hashmapMakeFunc
+		keyAlloca, keyPtr, keySize := b.createTemporaryAlloca(key.Type(), "hashmap.key")
+		b.CreateStore(key, keyAlloca)
+		b.zeroUndefBytes(b.getLLVMType(keyType), keyAlloca)
+		params := []llvm.Value{m, keyPtr}
+		b.createRuntimeCall("hashmapGenericDelete", params, "")
+		b.emitLifetimeEnd(keyPtr, keySize)
+		return nil
 	}
 }
 
@@ -196,42 +216,14 @@ func (b *builder) createMapIteratorNext(rangeVal ssa.Value, llvmRangeVal, it llv
 	llvmKeyType := b.getLLVMType(keyType)
 	llvmValueType := b.getLLVMType(valueType)
 
-	// There is a special case in which keys are stored as an interface value
-	// instead of the value they normally are. This happens for non-trivially
-	// comparable types such as float32 or some structs.
-	isKeyStoredAsInterface := false
-	if t, ok := keyType.Underlying().(*types.Basic); ok && t.Info()&types.IsString != 0 {
-		// key is a string
-	} else if hashmapIsBinaryKey(keyType) {
-		// key can be compared with runtime.memequal
-	} else {
-		// The key is stored as an interface value, and may or may not be an
-		// interface type (for example, float32 keys are stored as an interface
-		// value).
-		if _, ok := keyType.Underlying().(*types.Interface); !ok {
-			isKeyStoredAsInterface = true
-		}
-	}
-
-	// Determine the type of the key as stored in the map.
-	llvmStoredKeyType := llvmKeyType
-	if isKeyStoredAsInterface {
-		llvmStoredKeyType = b.getLLVMRuntimeType("_interface")
-	}
-
-	// Extract the key and value from the map.
-	mapKeyAlloca, mapKeyPtr, mapKeySize := b.createTemporaryAlloca(llvmStoredKeyType, "range.key")
+	// Extract the key and value from the map. Keys are always stored as the
+	// key type itself.
+	mapKeyAlloca, mapKeyPtr, mapKeySize := b.createTemporaryAlloca(llvmKeyType, "range.key")
 	mapValueAlloca, mapValuePtr, mapValueSize := b.createTemporaryAlloca(llvmValueType, "range.value")
 	ok := b.createRuntimeCall("hashmapNext", []llvm.Value{llvmRangeVal, it, mapKeyPtr, mapValuePtr}, "range.next")
-	mapKey := b.CreateLoad(llvmStoredKeyType, mapKeyAlloca, "")
+	mapKey := b.CreateLoad(llvmKeyType, mapKeyAlloca, "")
 	mapValue := b.CreateLoad(llvmValueType, mapValueAlloca, "")
 
-	if isKeyStoredAsInterface {
-		// The key is stored as an interface but it isn't of interface type.
-		// Extract the underlying value.
-		mapKey = b.extractValueFromInterface(mapKey, llvmKeyType)
-	}
-
 	// End the lifetimes of the allocas, because we're done with them.
 	b.emitLifetimeEnd(mapKeyPtr, mapKeySize)
 	b.emitLifetimeEnd(mapValuePtr, mapValueSize)
@@ -271,8 +263,279 @@ func hashmapIsBinaryKey(keyType types.Type) bool {
 	}
 }
 
+// hashmapHasAlgorithm returns whether keys of this type can be hashed with one
+// of the hashmap algorithms in the runtime (string, binary or interface). All
+// other keys are hashed and compared with the functions returned by
+// getMapKeyFuncs.
+func hashmapHasAlgorithm(keyType types.Type) bool {
+	switch keyType := keyType.Underlying().(type) {
+	case *types.Basic:
+		if keyType.Info()&types.IsString != 0 {
+			return true
+		}
+	case *types.Interface:
+		return true
+	}
+	return hashmapIsBinaryKey(keyType.Underlying())
+}
+
+// Signatures of the functions that hash and compare map keys, see the keyHash
+// and keyEqual fields of runtime.hashmap.
+var (
+	hashmapKeyHashSignature = types.NewSignature(nil, types.NewTuple(
+		types.NewVar(token.NoPos, nil, "key", types.Typ[types.UnsafePointer]),
+		types.NewVar(token.NoPos, nil, "size", types.Typ[types.Uintptr]),
+		types.NewVar(token.NoPos, nil, "seed", types.Typ[types.Uintptr]),
+	), types.NewTuple(types.NewVar(token.NoPos, nil, "", types.Typ[types.Uint32])), false)
+	hashmapKeyEqualSignature = types.NewSignature(nil, types.NewTuple(
+		types.NewVar(token.NoPos, nil, "x", types.Typ[types.UnsafePointer]),
+		types.NewVar(token.NoPos, nil, "y", types.Typ[types.UnsafePointer]),
+		types.NewVar(token.NoPos, nil, "n", types.Typ[types.Uintptr]),
+	), types.NewTuple(types.NewVar(token.NoPos, nil, "", types.Typ[types.Bool])), false)
+)
+
+// getMapKeyFuncs returns the func values (without context) that hash and
+// compare map keys of the given type, for maps created with
+// runtime.hashmapMakeFunc.
+func (c *compilerContext) getMapKeyFuncs(keyType types.Type) (hash, equal llvm.Value) {
+	funcValue := func(fn llvm.Value) llvm.Value {
+		return c.ctx.ConstStruct([]llvm.Value{
+			llvm.ConstNull(c.i8ptrType),
+			llvm.ConstBitCast(fn, c.rawVoidFuncType),
+		}, false)
+	}
+	return funcValue(c.getMapKeyHashFunc(keyType)), funcValue(c.getMapKeyEqualFunc(keyType))
+}
+
+// mapKeyRuntimeFuncs returns the names of the runtime functions that hash and
+// compare values of the given type as (part of) a map key. It returns empty
+// strings for structs and arrays that must be compared field by field.
+func mapKeyRuntimeFuncs(t types.Type) (hash, equal string) {
+	if hashmapIsBinaryKey(t.Underlying()) {
+		return "hash32", "memequal"
+	}
+	switch t := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case t.Info()&types.IsString != 0:
+			return "hashmapStringPtrHash", "hashmapStringEqual"
+		case t.Info()&types.IsFloat != 0:
+			return "hashmapFloatPtrHash", "hashmapFloatEqual"
+		case t.Info()&types.IsComplex != 0:
+			return "hashmapComplexPtrHash", "hashmapComplexEqual"
+		case t.Kind() == types.UnsafePointer:
+			return "hash32", "memequal"
+		}
+	case *types.Chan:
+		return "hash32", "memequal"
+	case *types.Interface:
+		return "hashmapInterfacePtrHash", "hashmapInterfaceEqual"
+	}
+	return "", ""
+}
+
+// getMapKeyFunc returns the function to hash or compare map keys of the given
+// type: either a runtime function or a function generated for this type.
+func (c *compilerContext) getMapKeyFunc(t types.Type, runtimeName, prefix string, sig *types.Signature) (fn llvm.Value, isNew bool) {
+	if runtimeName != "" {
+		_, fn = c.getFunction(c.program.ImportedPackage("runtime").Members[runtimeName].(*ssa.Function))
+		return fn, false
+	}
+	typeCodeName, isLocal := getTypeCodeName(t)
+	fnName := prefix + typeCodeName
+	if !isLocal {
+		fn = c.mod.NamedFunction(fnName)
+		if !fn.IsNil() {
+			return fn, false
+		}
+	}
+	fn = llvm.AddFunction(c.mod, fnName, c.getRawFuncType(sig))
+	c.addStandardAttributes(fn)
+	if isLocal {
+		fn.SetLinkage(llvm.InternalLinkage)
+	} else {
+		fn.SetLinkage(llvm.LinkOnceODRLinkage)
+	}
+	fn.SetUnnamedAddr(true)
+	return fn, true
+}
+
+// getMapKeyHashFunc returns the function that hashes map keys of the given
+// type, with the signature of hashmapKeyHashSignature. For structs and arrays
+// that can't be hashed as plain binary data, the function is generated for the
+// type. It looks like this in Go syntax:
+//
+//	func hash(key *struct{s string; f float64}, size, seed uintptr) uint32 {
+//	    var hash uint32
+//	    hash = hashmapCombineHash(hash, hashmapStringPtrHash(&key.s, 8, seed))
+//	    hash = hashmapCombineHash(hash, hashmapFloatPtrHash(&key.f, 8, seed))
+//	    return hash
+//	}
+func (c *compilerContext) getMapKeyHashFunc(t types.Type) llvm.Value {
+	runtimeName, _ := mapKeyRuntimeFuncs(t)
+	fn, isNew := c.getMapKeyFunc(t, runtimeName, "runtime.hashmapKeyHash:", hashmapKeyHashSignature)
+	if !isNew {
+		return fn
+	}
+
+	// Create a new builder just to create this function.
+	b := builder{
+		compilerContext: c,
+		Builder:         c.ctx.NewBuilder(),
+	}
+	defer b.Builder.Dispose()
+	entry := c.ctx.AddBasicBlock(fn, "entry")
+	b.SetInsertPointAtEnd(entry)
+
+	llvmType := c.getLLVMType(t)
+	key := b.CreateBitCast(fn.Param(0), llvm.PointerType(llvmType, 0), "")
+	seed := fn.Param(2)
+	zero := llvm.ConstInt(c.ctx.Int32Type(), 0, false)
+	hash := llvm.ConstInt(c.ctx.Int32Type(), 0, false)
+	switch t := t.Underlying().(type) {
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			if t.Field(i).Name() == "_" {
+				// Blank fields are ignored in comparisons, so they must also
+				// be ignored while hashing.
+				continue
+			}
+			fieldPtr := b.CreateInBoundsGEP(llvmType, key, []llvm.Value{zero, llvm.ConstInt(c.ctx.Int32Type(), uint64(i), false)}, "")
+			hash = b.createRuntimeCall("hashmapCombineHash", []llvm.Value{hash, b.createMapKeyHash(t.Field(i).Type(), fieldPtr, seed)}, "")
+		}
+		b.CreateRet(hash)
+	case *types.Array:
+		if t.Len() == 0 {
+			b.CreateRet(hash)
+			break
+		}
+		// Hash all elements in a loop.
+		loop := c.ctx.AddBasicBlock(fn, "loop")
+		exit := c.ctx.AddBasicBlock(fn, "exit")
+		b.CreateBr(loop)
+		b.SetInsertPointAtEnd(loop)
+		index := b.CreatePHI(c.uintptrType, "index")
+		prevHash := b.CreatePHI(c.ctx.Int32Type(), "hash")
+		elemPtr := b.CreateInBoundsGEP(llvmType, key, []llvm.Value{zero, index}, "")
+		hash = b.createRuntimeCall("hashmapCombineHash", []llvm.Value{prevHash, b.createMapKeyHash(t.Elem(), elemPtr, seed)}, "")
+		nextIndex := b.CreateAdd(index, llvm.ConstInt(c.uintptrType, 1, false), "")
+		done := b.CreateICmp(llvm.IntEQ, nextIndex, llvm.ConstInt(c.uintptrType, uint64(t.Len()), false), "")
+		b.CreateCondBr(done, exit, loop)
+		index.AddIncoming([]llvm.Value{llvm.ConstInt(c.uintptrType, 0, false), nextIndex}, []llvm.BasicBlock{entry, loop})
+		prevHash.AddIncoming([]llvm.Value{zero, hash}, []llvm.BasicBlock{entry, loop})
+		b.SetInsertPointAtEnd(exit)
+		b.CreateRet(hash)
+	default:
+		panic("unexpected map key type: " + t.String())
+	}
+	return fn
+}
+
+// getMapKeyEqualFunc returns the function that compares map keys of the given
+// type, with the signature of hashmapKeyEqualSignature. Like with
+// getMapKeyHashFunc, it is generated for structs and arrays that can't be
+// compared as plain binary data. It looks like this in Go syntax:
+//
+//	func equal(x, y *struct{s string; f float64}, n uintptr) bool {
+//	    if !hashmapStringEqual(&x.s, &y.s, 8) {
+//	        return false
+//	    }
+//	    if !hashmapFloatEqual(&x.f, &y.f, 8) {
+//	        return false
+//	    }
+//	    return true
+//	}
+func (c *compilerContext) getMapKeyEqualFunc(t types.Type) llvm.Value {
+	_, runtimeName := mapKeyRuntimeFuncs(t)
+	fn, isNew := c.getMapKeyFunc(t, runtimeName, "runtime.hashmapKeyEqual:", hashmapKeyEqualSignature)
+	if !isNew {
+		return fn
+	}
+
+	// Create a new builder just to create this function.
+	b := builder{
+		compilerContext: c,
+		Builder:         c.ctx.NewBuilder(),
+	}
+	defer b.Builder.Dispose()
+	entry := c.ctx.AddBasicBlock(fn, "entry")
+	notEqual := c.ctx.AddBasicBlock(fn, "notequal")
+	b.SetInsertPointAtEnd(notEqual)
+	b.CreateRet(llvm.ConstInt(c.ctx.Int1Type(), 0, false))
+	b.SetInsertPointAtEnd(entry)
+
+	llvmType := c.getLLVMType(t)
+	x := b.CreateBitCast(fn.Param(0), llvm.PointerType(llvmType, 0), "")
+	y := b.CreateBitCast(fn.Param(1), llvm.PointerType(llvmType, 0), "")
+	zero := llvm.ConstInt(c.ctx.Int32Type(), 0, false)
+	switch t := t.Underlying().(type) {
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			if t.Field(i).Name() == "_" {
+				// Blank fields are ignored in comparisons.
+				continue
+			}
+			indices := []llvm.Value{zero, llvm.ConstInt(c.ctx.Int32Type(), uint64(i), false)}
+			xField := b.CreateInBoundsGEP(llvmType, x, indices, "")
+			yField := b.CreateInBoundsGEP(llvmType, y, indices, "")
+			next := c.ctx.AddBasicBlock(fn, "next")
+			b.CreateCondBr(b.createMapKeyEqual(t.Field(i).Type(), xField, yField), next, notEqual)
+			b.SetInsertPointAtEnd(next)
+		}
+		b.CreateRet(llvm.ConstInt(c.ctx.Int1Type(), 1, false))
+	case *types.Array:
+		if t.Len() == 0 {
+			b.CreateRet(llvm.ConstInt(c.ctx.Int1Type(), 1, false))
+			break
+		}
+		// Compare all elements in a loop.
+		loop := c.ctx.AddBasicBlock(fn, "loop")
+		next := c.ctx.AddBasicBlock(fn, "next")
+		equal := c.ctx.AddBasicBlock(fn, "equal")
+		b.CreateBr(loop)
+		b.SetInsertPointAtEnd(loop)
+		index := b.CreatePHI(c.uintptrType, "index")
+		xElem := b.CreateInBoundsGEP(llvmType, x, []llvm.Value{zero, index}, "")
+		yElem := b.CreateInBoundsGEP(llvmType, y, []llvm.Value{zero, index}, "")
+		b.CreateCondBr(b.createMapKeyEqual(t.Elem(), xElem, yElem), next, notEqual)
+		b.SetInsertPointAtEnd(next)
+		nextIndex := b.CreateAdd(index, llvm.ConstInt(c.uintptrType, 1, false), "")
+		done := b.CreateICmp(llvm.IntEQ, nextIndex, llvm.ConstInt(c.uintptrType, uint64(t.Len()), false), "")
+		b.CreateCondBr(done, equal, loop)
+		index.AddIncoming([]llvm.Value{llvm.ConstInt(c.uintptrType, 0, false), nextIndex}, []llvm.BasicBlock{entry, next})
+		b.SetInsertPointAtEnd(equal)
+		b.CreateRet(llvm.ConstInt(c.ctx.Int1Type(), 1, false))
+	default:
+		panic("unexpected map key type: " + t.String())
+	}
+	return fn
+}
+
+// createMapKeyHash hashes the value of type t that ptr points to, as part of a
+// map key.
+func (b *builder) createMapKeyHash(t types.Type, ptr, seed llvm.Value) llvm.Value {
+	fn := b.getMapKeyHashFunc(t)
+	size := llvm.ConstInt(b.uintptrType, b.targetData.TypeAllocSize(b.getLLVMType(t)), false)
+	ptr = b.CreateBitCast(ptr, b.i8ptrType, "") // LLVM 14
+	return b.CreateCall(b.getRawFuncType(hashmapKeyHashSignature), fn, []llvm.Value{ptr, size, seed, llvm.Undef(b.i8ptrType)}, "")
+}
+
+// createMapKeyEqual compares the values of type t that x and y point to, as
+// part of a map key.
+func (b *builder) createMapKeyEqual(t types.Type, x, y llvm.Value) llvm.Value {
+	fn := b.getMapKeyEqualFunc(t)
+	size := llvm.ConstInt(b.uintptrType, b.targetData.TypeAllocSize(b.getLLVMType(t)), false)
+	x = b.CreateBitCast(x, b.i8ptrType, "") // LLVM 14
+	y = b.CreateBitCast(y, b.i8ptrType, "") // LLVM 14
+	return b.CreateCall(b.getRawFuncType(hashmapKeyEqualSignature), fn, []llvm.Value{x, y, size, llvm.Undef(b.i8ptrType)}, "")
+}
+
 func (b *builder) zeroUndefBytes(llvmType llvm.Type, ptr llvm.Value) error {
-	// We know that hashmapIsBinaryKey is true, so we only have to handle those types that can show up there.
+	// We know that the key is either a binary key (see hashmapIsBinaryKey) or
+	// a key that is hashed with getMapKeyFuncs. Only integers, pointers,
+	// arrays and structs can contain or be followed by padding bytes: other
+	// types like floats, strings (a struct of a pointer and an integer) and
+	// interfaces don't need to be handled specially.
 	// To zero all undefined bytes, we iterate over all the fields in the type.  For each element, compute the
 	// offset of that element.  If it's Basic type, there are no internal padding bytes.  For compound types, we recurse to ensure
 	// we handle nested types.  Next, we determine if there are any padding bytes before the next
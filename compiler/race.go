@@ -0,0 +1,74 @@
+package compiler
+
+// This file implements the compiler side of -race, the data race detector.
+// Every load and store that may access memory shared between goroutines is
+// preceded by a call to runtime.raceread or runtime.racewrite with the address
+// and size of the access and a constant runtime.traceSite with its position.
+// Map operations are checked as an access to the map itself. The detector is
+// implemented in the runtime, see src/runtime/race.go.
+
+import (
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/ssa"
+	"tinygo.org/x/go-llvm"
+)
+
+// needsRaceInstrumentation returns whether the memory accesses in the current
+// function should be checked for data races.
+func (b *builder) needsRaceInstrumentation() bool {
+	if !b.Race || b.info.norace || b.fn.Syntax() == nil || b.fn.Pkg == nil {
+		return false
+	}
+	switch b.fn.Pkg.Pkg.Path() {
+	case "runtime", "runtime/interrupt", "runtime/volatile", "internal/task", "sync", "sync/atomic":
+		// The race detector itself and the synchronization primitives that it
+		// knows about are implemented in these packages.
+		return false
+	}
+	return true
+}
+
+// createRaceAccess inserts a call to the race detector for a load (or store, if
+// write is set) of a value of the given type at the given address. The addr
+// value may be nil if the address doesn't come from a pointer in the SSA.
+func (b *builder) createRaceAccess(addr ssa.Value, llvmAddr llvm.Value, valueType llvm.Type, write bool, pos token.Pos) {
+	if !b.raceInstrument || isLocalAddr(addr) {
+		return
+	}
+	fnName := "raceread"
+	if write {
+		fnName = "racewrite"
+	}
+	site := llvm.ConstPointerNull(llvm.PointerType(b.getLLVMRuntimeType("traceSite"), 0))
+	if pos.IsValid() {
+		site = b.getTraceSite(b.program.Fset.Position(pos))
+	}
+	b.createRuntimeCall(fnName, []llvm.Value{
+		b.CreateBitCast(llvmAddr, b.i8ptrType, ""),
+		llvm.ConstInt(b.uintptrType, b.targetData.TypeStoreSize(valueType), false),
+		site,
+	}, "")
+}
+
+// isLocalAddr returns whether the given address points into a local variable
+// that doesn't escape, and can therefore not be accessed by other goroutines.
+func isLocalAddr(addr ssa.Value) bool {
+	for {
+		switch value := addr.(type) {
+		case *ssa.Alloc:
+			return !value.Heap
+		case *ssa.FieldAddr:
+			addr = value.X
+		case *ssa.IndexAddr:
+			if _, ok := value.X.Type().Underlying().(*types.Pointer); !ok {
+				// Index into a slice, which may point anywhere.
+				return false
+			}
+			addr = value.X
+		default:
+			return false
+		}
+	}
+}
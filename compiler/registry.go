@@ -0,0 +1,113 @@
+package compiler
+
+// This file implements the //go:registry pragma. A registry is a package level
+// slice variable that is declared like this:
+//
+//     //go:registry
+//     var Handlers []func(cmd string) bool
+//
+// Functions in any package can then add themselves to the registry:
+//
+//     //go:registry example.com/commands.Handlers
+//     func handleHelp(cmd string) bool { ... }
+//
+// The registry is filled in at link time (see transform.LowerRegistries), so
+// it can be used during package initialization and functions are only kept in
+// the binary if the registry itself is used somewhere.
+
+import (
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+	"tinygo.org/x/go-llvm"
+)
+
+// createRegistry checks the declaration of a registry and emits the marker
+// global that declares it, for transform.LowerRegistries.
+func (c *compilerContext) createRegistry(g *ssa.Global, info globalInfo) {
+	if len(info.registryArgs) != 0 {
+		c.addError(g.Pos(), "//go:registry on a variable declares a registry and expects no arguments")
+		return
+	}
+	if info.extern {
+		c.addError(g.Pos(), "//go:registry variable cannot be declared with //go:extern")
+		return
+	}
+	if _, ok := g.Type().(*types.Pointer).Elem().Underlying().(*types.Slice); !ok {
+		c.addError(g.Pos(), "//go:registry variable must be a slice, not "+g.Type().(*types.Pointer).Elem().String())
+		return
+	}
+	if initFn, ok := g.Pkg.Members["init"].(*ssa.Function); ok {
+		for _, block := range initFn.Blocks {
+			for _, instr := range block.Instrs {
+				if store, ok := instr.(*ssa.Store); ok && store.Addr == g {
+					c.addError(g.Pos(), "//go:registry variable cannot have an initializer")
+					return
+				}
+			}
+		}
+	}
+
+	marker := llvm.AddGlobal(c.mod, c.ctx.Int8Type(), info.linkName+"$registry")
+	marker.SetInitializer(llvm.ConstNull(c.ctx.Int8Type()))
+	marker.SetGlobalConstant(true)
+}
+
+// createRegistryEntry adds the function to the registry with the given name,
+// which is in the form pkgpath.Name.
+func (c *compilerContext) createRegistryEntry(fn *ssa.Function, name string) {
+	index := strings.LastIndexByte(name, '.')
+	if index < 0 {
+		c.addError(fn.Pos(), "//go:registry: invalid registry name "+name+", expected pkgpath.Name")
+		return
+	}
+	pkgPath, varName := name[:index], name[index+1:]
+	pkg := c.program.ImportedPackage(pkgPath)
+	if pkg == nil {
+		c.addError(fn.Pos(), "//go:registry: package "+pkgPath+" is not part of the program")
+		return
+	}
+	registry, ok := pkg.Members[varName].(*ssa.Global)
+	if !ok {
+		c.addError(fn.Pos(), "//go:registry: "+name+" is not a package level variable")
+		return
+	}
+	slice, ok := registry.Type().(*types.Pointer).Elem().Underlying().(*types.Slice)
+	if !ok {
+		c.addError(fn.Pos(), "//go:registry: "+name+" is not a slice")
+		return
+	}
+	if !types.Identical(slice.Elem().Underlying(), fn.Signature) {
+		c.addError(fn.Pos(), "//go:registry: cannot use "+fn.Name()+" (type "+fn.Signature.String()+") as "+slice.Elem().String()+" in registry "+name)
+		return
+	}
+	if c.getFunctionInfo(fn).exported {
+		c.addError(fn.Pos(), "//go:registry: exported functions cannot be added to a registry")
+		return
+	}
+
+	// Add the func value to the list of entries of this registry. The list
+	// has appending linkage, so that the lists of all packages are combined
+	// when the packages are linked together.
+	_, llvmFn := c.getFunction(fn)
+	funcValueType := c.getFuncType(fn.Signature)
+	entry := c.ctx.ConstStruct([]llvm.Value{
+		llvm.ConstNull(c.i8ptrType),
+		llvm.ConstBitCast(llvmFn, c.rawVoidFuncType),
+	}, false)
+	entriesName := c.getGlobalInfo(registry).linkName + "$registry.entries"
+	var entries []llvm.Value
+	if global := c.mod.NamedGlobal(entriesName); !global.IsNil() {
+		initializer := global.Initializer()
+		for i := 0; i < initializer.Type().ArrayLength(); i++ {
+			entries = append(entries, c.builder.CreateExtractValue(initializer, i, ""))
+		}
+		global.EraseFromParentAsGlobal()
+	}
+	entries = append(entries, entry)
+	initializer := llvm.ConstArray(funcValueType, entries)
+	global := llvm.AddGlobal(c.mod, initializer.Type(), entriesName)
+	global.SetInitializer(initializer)
+	global.SetLinkage(llvm.AppendingLinkage)
+}
@@ -30,6 +30,10 @@ type functionInfo struct {
 	exported   bool       // go:export, CGo
 	interrupt  bool       // go:interrupt
 	nobounds   bool       // go:nobounds
+	norace     bool       // go:norace
+	ramfunc    bool       // go:ramfunc
+	registry   string     // go:registry - registry to add this function to
+	wasmExport string     // go:wasmexport - the name of the WebAssembly export
 	variadic   bool       // go:variadic (CGo only)
 	inline     inlineType // go:inline
 }
@@ -170,6 +174,10 @@ func (c *compilerContext) getFunction(fn *ssa.Function) (llvm.Type, llvm.Value)
 		llvmFn.AddAttributeAtIndex(1, c.ctx.CreateEnumAttribute(llvm.AttributeKindID("nocapture"), 0))
 		llvmFn.AddAttributeAtIndex(2, c.ctx.CreateEnumAttribute(llvm.AttributeKindID("readonly"), 0))
 		llvmFn.AddAttributeAtIndex(2, c.ctx.CreateEnumAttribute(llvm.AttributeKindID("nocapture"), 0))
+	case "runtime.raceread", "runtime.racewrite":
+		// The race detector only uses the address of the access, so it
+		// doesn't prevent heap allocations from being moved to the stack.
+		llvmFn.AddAttributeAtIndex(1, c.ctx.CreateEnumAttribute(llvm.AttributeKindID("nocapture"), 0))
 	case "runtime.trackPointer":
 		// This function is necessary for tracking pointers on the stack in a
 		// portable way (see gc_stack_portable.go). Indicate to the optimizer
@@ -247,6 +255,13 @@ func (c *compilerContext) getFunctionInfo(f *ssa.Function) functionInfo {
 		// Pick the default linkName.
 		linkName: f.RelString(nil),
 	}
+	// Check for //go:linkname, which may be anywhere in the file that declares
+	// the function.
+	if len(f.TypeArgs()) == 0 {
+		if linkName, ok := c.loaderPkg.Linkname(f.Object()); ok {
+			info.linkName = linkName
+		}
+	}
 	// Check for //go: pragmas, which may change the link name (among others).
 	c.parsePragmas(&info, f)
 	c.functionInfos[f] = info
@@ -304,20 +319,43 @@ func (c *compilerContext) parsePragmas(info *functionInfo, f *ssa.Function) {
 				info.exported = true
 				info.module = parts[1]
 				info.importName = parts[2]
+			case "//go:wasmexport":
+				// Export a function from the WebAssembly module, with the
+				// same restrictions on the types as //go:wasmimport. Unlike
+				// //export, the function may be called before _start.
+				if len(parts) != 2 {
+					c.addError(f.Pos(), "//go:wasmexport expects one argument, the name of the export")
+					continue
+				}
+				c.checkWasmExport(f, comment.Text)
+				importName = parts[1]
+				info.exported = true
+				info.wasmExport = parts[1]
 			case "//go:inline":
 				info.inline = inlineHint
 			case "//go:noinline":
 				info.inline = inlineNone
-			case "//go:linkname":
-				if len(parts) != 3 || parts[1] != f.Name() {
-					continue
+			case "//go:norace":
+				// Don't check memory accesses in this function for data races
+				// when building with -race.
+				info.norace = true
+			case "//go:noescape":
+				// TinyGo does its own escape analysis, so this pragma has no
+				// effect. Like the gc toolchain, only allow it on declarations
+				// (functions that are implemented elsewhere).
+				if f.Blocks != nil {
+					c.addError(f.Pos(), "can only use //go:noescape with external func implementations")
 				}
-				// Only enable go:linkname when the package imports "unsafe".
-				// This is a slightly looser requirement than what gc uses: gc
-				// requires the file to import "unsafe", not the package as a
-				// whole.
-				if hasUnsafeImport(f.Pkg.Pkg) {
-					info.linkName = parts[2]
+			case "//go:nosplit", "//go:nocheckptr", "//go:uintptrescapes", "//go:systemstack",
+				"//go:nowritebarrier", "//go:nowritebarrierrec", "//go:yeswritebarrierrec":
+				// These pragmas are used in the standard library and in low
+				// level code written for the gc toolchain. They don't apply to
+				// TinyGo: goroutine stacks don't grow, pointers aren't checked
+				// with -d=checkptr and the GC doesn't use write barriers.
+				// The standard library uses them in many places, so only warn
+				// about them in other packages.
+				if !c.loaderPkg.Standard && f.Pkg != nil && f.Pkg.Pkg == c.pkg {
+					c.addWarning(f.Pos(), parts[0]+" is ignored by TinyGo")
 				}
 			case "//go:section":
 				// Only enable go:section when the package imports "unsafe".
@@ -327,6 +365,23 @@ func (c *compilerContext) parsePragmas(info *functionInfo, f *ssa.Function) {
 					info.section = parts[1]
 					info.inline = inlineNone
 				}
+			case "//go:ramfunc":
+				// Place this function in RAM, for example because it writes to
+				// the flash bank that the rest of the program runs from. Like
+				// go:section, this implies go:noinline.
+				if hasUnsafeImport(f.Pkg.Pkg) {
+					info.ramfunc = true
+					info.section = ".ramfuncs"
+					info.inline = inlineNone
+				}
+			case "//go:registry":
+				// Add this function to a registry declared elsewhere, see
+				// registry.go.
+				if len(parts) != 2 {
+					c.addError(f.Pos(), "//go:registry on a function expects one argument, the registry to add it to")
+					continue
+				}
+				info.registry = parts[1]
 			case "//go:nobounds":
 				// Skip bounds checking in this function. Useful for some
 				// runtime functions.
@@ -394,6 +449,52 @@ func (c *compilerContext) checkWasmImport(f *ssa.Function, pragma string) {
 	}
 }
 
+// Check whether this function cannot be used in //go:wasmexport. It will add an
+// error if this is the case.
+func (c *compilerContext) checkWasmExport(f *ssa.Function, pragma string) {
+	if c.archFamily() != "wasm32" {
+		c.addError(f.Pos(), "//go:wasmexport is only supported on WebAssembly")
+		return
+	}
+	if f.Blocks == nil {
+		c.addError(f.Pos(), "can only use //go:wasmexport on function definitions")
+		return
+	}
+	if f.Signature.Recv() != nil || f.TypeParams() != nil || f.TypeArgs() != nil {
+		c.addError(f.Pos(), pragma+": can only export plain functions")
+		return
+	}
+	if f.Signature.Results().Len() > 1 {
+		c.addError(f.Signature.Results().At(1).Pos(), fmt.Sprintf("%s: too many return values", pragma))
+	} else if f.Signature.Results().Len() == 1 {
+		result := f.Signature.Results().At(0)
+		if !isValidWasmExportType(result.Type()) {
+			c.addError(result.Pos(), fmt.Sprintf("%s: unsupported result type %s", pragma, result.Type().String()))
+		}
+	}
+	for _, param := range f.Params {
+		if !isValidWasmExportType(param.Type()) {
+			c.addError(param.Pos(), fmt.Sprintf("%s: unsupported parameter type %s", pragma, param.Type().String()))
+		}
+	}
+}
+
+// isValidWasmExportType returns whether the type can be used in the signature
+// of a //go:wasmexport function. Besides the types allowed in //go:wasmimport,
+// pointers are allowed in both parameters and results: they are passed as an
+// i32 offset into the linear memory of the module.
+func isValidWasmExportType(typ types.Type) bool {
+	switch typ := typ.Underlying().(type) {
+	case *types.Pointer:
+		return true
+	case *types.Basic:
+		if typ.Kind() == types.UnsafePointer {
+			return true
+		}
+	}
+	return isValidWasmType(typ, false)
+}
+
 // Check whether the type maps directly to a WebAssembly type, according to:
 // https://github.com/golang/go/issues/59149
 func isValidWasmType(typ types.Type, isReturn bool) bool {
@@ -486,14 +587,19 @@ func (c *compilerContext) addStandardAttributes(llvmFn llvm.Value) {
 // linkName is equal to .RelString(nil) on a global and extern is false, but for
 // some symbols this is different (due to //go:extern for example).
 type globalInfo struct {
-	linkName string // go:extern
-	extern   bool   // go:extern
+	linkName string // go:extern, go:linkname
+	extern   bool   // go:extern, go:linkname
 	align    int    // go:align
 	section  string // go:section
+	registry bool   // go:registry
+	// Arguments of the //go:registry pragma, which are not allowed on
+	// variables. Checked in createRegistry.
+	registryArgs []string
 }
 
-// loadASTComments loads comments on globals from the AST, for use later in the
-// program. In particular, they are required for //go:extern pragmas on globals.
+// loadASTComments loads comments on globals and types from the AST, for use
+// later in the program. In particular, they are required for //go:extern
+// pragmas on globals and //go:reflect pragmas on types.
 func (c *compilerContext) loadASTComments(pkg *loader.Package) {
 	for _, file := range pkg.Files {
 		for _, decl := range file.Decls {
@@ -513,6 +619,18 @@ func (c *compilerContext) loadASTComments(pkg *loader.Package) {
 							}
 						}
 					}
+				case token.TYPE:
+					for _, spec := range decl.Specs {
+						spec := spec.(*ast.TypeSpec)
+						doc := spec.Doc
+						if doc == nil && len(decl.Specs) == 1 {
+							doc = decl.Doc
+						}
+						if doc != nil {
+							id := pkg.Pkg.Path() + "." + spec.Name.Name
+							c.astComments[id] = doc
+						}
+					}
 				}
 			}
 		}
@@ -573,9 +691,49 @@ func (c *compilerContext) getGlobalInfo(g *ssa.Global) globalInfo {
 	if doc != nil {
 		info.parsePragmas(doc)
 	}
+	// A global with //go:linkname usually refers to a global defined in a
+	// different package. But if it is initialized here, or if the link name
+	// is in its own package, it is defined here under that link name.
+	if linkName, ok := c.loaderPkg.Linkname(g.Object()); ok {
+		info.linkName = linkName
+		info.extern = !strings.HasPrefix(linkName, g.Pkg.Pkg.Path()+".") && !hasInitializer(g)
+	}
 	return info
 }
 
+// hasInitializer returns whether the package initializer of the global stores
+// a value in it, or in one of its fields or elements.
+func hasInitializer(g *ssa.Global) bool {
+	init := g.Pkg.Func("init")
+	if init == nil {
+		return false
+	}
+	for _, block := range init.Blocks {
+		for _, instr := range block.Instrs {
+			store, ok := instr.(*ssa.Store)
+			if !ok {
+				continue
+			}
+			addr := store.Addr
+			for {
+				switch x := addr.(type) {
+				case *ssa.FieldAddr:
+					addr = x.X
+					continue
+				case *ssa.IndexAddr:
+					addr = x.X
+					continue
+				}
+				break
+			}
+			if addr == g {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // Parse //go: pragma comments from the source. In particular, it parses the
 // //go:extern pragma on globals.
 func (info *globalInfo) parsePragmas(doc *ast.CommentGroup) {
@@ -599,6 +757,9 @@ func (info *globalInfo) parsePragmas(doc *ast.CommentGroup) {
 			if len(parts) == 2 {
 				info.section = parts[1]
 			}
+		case "//go:registry":
+			info.registry = true
+			info.registryArgs = parts[1:]
 		}
 	}
 }
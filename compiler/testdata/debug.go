@@ -0,0 +1,20 @@
+package main
+
+func scopes(n int) int {
+	sum := 0
+	for i := 0; i < n; i++ {
+		inner := i * 2
+		sum += inner
+	}
+	return sum
+}
+
+func addressTaken() int {
+	x := 3
+	escape(&x)
+	return x
+}
+
+//go:noinline
+func escape(p *int) {
+}
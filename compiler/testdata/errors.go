@@ -11,6 +11,12 @@ func empty()
 func implementation() {
 }
 
+// ERROR: can only use //go:noescape with external func implementations
+//
+//go:noescape
+func noescapeImplementation(p *int) {
+}
+
 type Uint uint32
 
 //go:wasmimport modulename validparam
@@ -41,3 +47,65 @@ func invalidreturn() int
 //
 //go:wasmimport modulename invalidUnsafePointerReturn
 func invalidUnsafePointerReturn() unsafe.Pointer
+
+//go:wasmexport validexport
+func validexport(a int32, b uint64, c float64, d unsafe.Pointer, e *Uint) *int32 {
+	return nil
+}
+
+// ERROR: //go:wasmexport invalidexport: unsupported result type int
+// ERROR: //go:wasmexport invalidexport: unsupported parameter type string
+//
+//go:wasmexport invalidexport
+func invalidexport(s string) int {
+	return 0
+}
+
+// ERROR: //go:wasmexport expects one argument, the name of the export
+//
+//go:wasmexport
+func exportWithoutName() {
+}
+
+var events = make(chan int, 1)
+
+// ERROR: //go:interrupt not supported on this architecture
+// ERROR: channel send in interrupt handler may block: use a select statement with a default case
+// ERROR: select statement in interrupt handler may block: add a default case
+//
+//go:interrupt
+func interruptHandler() {
+	select {
+	case events <- 1:
+	default:
+	}
+	events <- 2
+	select {
+	case events <- 3:
+	case <-events:
+	}
+}
+
+// ERROR: //go:reflect is not supported on generic types
+//
+//go:reflect
+type Generic[T any] struct {
+	value T
+}
+
+// ERROR: //go:registry on a variable declares a registry and expects no arguments
+//
+//go:registry main.handlers
+var registryWithArgument []func()
+
+// ERROR: //go:registry on a function expects one argument, the registry to add it to
+//
+//go:registry
+func registryEntryWithoutName() {
+}
+
+// WARNING: //go:nosplit is ignored by TinyGo
+//
+//go:nosplit
+func nosplit() {
+}
@@ -11,10 +11,16 @@ target triple = "wasm32-unknown-wasi"
 @"reflect/types.type:pointer:named:error" = linkonce_odr constant { i8, i16, ptr } { i8 -43, i16 0, ptr @"reflect/types.type:named:error" }, align 4
 @"reflect/types.type:named:error" = linkonce_odr constant { i8, i16, ptr, ptr, ptr, [7 x i8] } { i8 116, i16 1, ptr @"reflect/types.type:pointer:named:error", ptr @"reflect/types.type:interface:{Error:func:{}{basic:string}}", ptr @"reflect/types.type.pkgpath.empty", [7 x i8] c".error\00" }, align 4
 @"reflect/types.type.pkgpath.empty" = linkonce_odr unnamed_addr constant [1 x i8] zeroinitializer, align 1
-@"reflect/types.type:interface:{Error:func:{}{basic:string}}" = linkonce_odr constant { i8, ptr } { i8 84, ptr @"reflect/types.type:pointer:interface:{Error:func:{}{basic:string}}" }, align 4
+@"reflect/types.type:interface:{Error:func:{}{basic:string}}" = linkonce_odr constant { i8, i16, ptr, [1 x ptr], [29 x i8] } { i8 84, i16 1, ptr @"reflect/types.type:pointer:interface:{Error:func:{}{basic:string}}", [1 x ptr] [ptr @"reflect/methods.Error() string"], [29 x i8] c"interface { Error() string }\00" }, align 4
+@"reflect/types.type:func:{}{basic:string}" = linkonce_odr constant { i8, i16, ptr, i16, { ptr, ptr }, { ptr, ptr }, [1 x ptr], [14 x i8] } { i8 24, i16 0, ptr @"reflect/types.type:pointer:func:{}{basic:string}", i16 1, { ptr, ptr } { ptr null, ptr @"reflect/types.call:func:{}{basic:string}" }, { ptr, ptr } { ptr null, ptr @"reflect/types.makeFunc:func:{}{basic:string}" }, [1 x ptr] [ptr @"reflect/types.type:basic:string"], [14 x i8] c"func() string\00" }, align 4
+@"reflect/types.type:basic:string" = linkonce_odr constant { i8, ptr } { i8 81, ptr @"reflect/types.type:pointer:basic:string" }, align 4
+@"reflect/types.type:pointer:basic:string" = linkonce_odr constant { i8, i16, ptr } { i8 -43, i16 0, ptr @"reflect/types.type:basic:string" }, align 4
+@"reflect/types.type:pointer:func:{}{basic:string}" = linkonce_odr constant { i8, i16, ptr } { i8 -43, i16 0, ptr @"reflect/types.type:func:{}{basic:string}" }, align 4
+@"reflect/methods.Error() string" = linkonce_odr constant { ptr, [6 x i8] } { ptr @"reflect/types.type:func:{}{basic:string}", [6 x i8] c"Error\00" }, align 4
 @"reflect/types.type:pointer:interface:{Error:func:{}{basic:string}}" = linkonce_odr constant { i8, i16, ptr } { i8 -43, i16 0, ptr @"reflect/types.type:interface:{Error:func:{}{basic:string}}" }, align 4
 @"reflect/types.type:pointer:interface:{String:func:{}{basic:string}}" = linkonce_odr constant { i8, i16, ptr } { i8 -43, i16 0, ptr @"reflect/types.type:interface:{String:func:{}{basic:string}}" }, align 4
-@"reflect/types.type:interface:{String:func:{}{basic:string}}" = linkonce_odr constant { i8, ptr } { i8 84, ptr @"reflect/types.type:pointer:interface:{String:func:{}{basic:string}}" }, align 4
+@"reflect/types.type:interface:{String:func:{}{basic:string}}" = linkonce_odr constant { i8, i16, ptr, [1 x ptr], [30 x i8] } { i8 84, i16 1, ptr @"reflect/types.type:pointer:interface:{String:func:{}{basic:string}}", [1 x ptr] [ptr @"reflect/methods.String() string"], [30 x i8] c"interface { String() string }\00" }, align 4
+@"reflect/methods.String() string" = linkonce_odr constant { ptr, [7 x i8] } { ptr @"reflect/types.type:func:{}{basic:string}", [7 x i8] c"String\00" }, align 4
 @"reflect/types.typeid:basic:int" = external constant i8
 
 ; Function Attrs: allockind("alloc,zeroed") allocsize(0)
@@ -55,6 +61,42 @@ entry:
   ret %runtime._interface { ptr @"reflect/types.type:pointer:named:error", ptr null }
 }
 
+; Function Attrs: nounwind
+define linkonce_odr void @"reflect/types.call:func:{}{basic:string}"(ptr %0, ptr %1, ptr %2, ptr %3) unnamed_addr #2 {
+entry:
+  %.unpack = load ptr, ptr %0, align 4
+  %.elt1 = getelementptr inbounds { ptr, ptr }, ptr %0, i32 0, i32 1
+  %.unpack2 = load ptr, ptr %.elt1, align 4
+  %4 = call %runtime._string %.unpack2(ptr %.unpack)
+  %5 = load ptr, ptr %2, align 4
+  %.elt = extractvalue %runtime._string %4, 0
+  store ptr %.elt, ptr %5, align 4
+  %.repack3 = getelementptr inbounds %runtime._string, ptr %5, i32 0, i32 1
+  %.elt4 = extractvalue %runtime._string %4, 1
+  store i32 %.elt4, ptr %.repack3, align 4
+  ret void
+}
+
+; Function Attrs: nounwind
+define linkonce_odr %runtime._string @"reflect/types.makeFunc:func:{}{basic:string}"(ptr %0) unnamed_addr #2 {
+entry:
+  %results = alloca [1 x ptr], align 4
+  %1 = alloca %runtime._string, align 4
+  store ptr null, ptr %1, align 4
+  %.repack1 = getelementptr inbounds %runtime._string, ptr %1, i32 0, i32 1
+  store i32 0, ptr %.repack1, align 4
+  store ptr %1, ptr %results, align 4
+  %.unpack = load ptr, ptr %0, align 4
+  %.elt2 = getelementptr inbounds { ptr, ptr }, ptr %0, i32 0, i32 1
+  %.unpack3 = load ptr, ptr %.elt2, align 4
+  call void %.unpack3(ptr null, ptr nonnull %results, ptr %.unpack)
+  %.unpack4 = load ptr, ptr %1, align 4
+  %2 = insertvalue %runtime._string undef, ptr %.unpack4, 0
+  %.unpack5 = load i32, ptr %.repack1, align 4
+  %3 = insertvalue %runtime._string %2, i32 %.unpack5, 1
+  ret %runtime._string %3
+}
+
 ; Function Attrs: nounwind
 define hidden %runtime._interface @main.anonymousInterfaceType(ptr %context) unnamed_addr #2 {
 entry:
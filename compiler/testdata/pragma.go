@@ -59,6 +59,12 @@ func functionInSection() {
 func exportedFunctionInSection() {
 }
 
+// This function should be placed in the .ramfuncs section.
+//
+//go:ramfunc
+func functionInRAM() {
+}
+
 //go:wasmimport modulename import1
 func declaredImport()
 
@@ -77,3 +83,12 @@ var undefinedGlobalNotInSection uint32
 //go:align 1024
 //go:section .global_section
 var multipleGlobalPragmas uint32
+
+// Import a function and a global from a different package using go:linkname
+// directives that are not part of their doc comments, which gc also allows.
+//go:linkname withLinkageName3 somepkg.someFunction3
+//go:linkname linknamedGlobal somepkg.someGlobal
+
+func withLinkageName3()
+
+var linknamedGlobal uint32
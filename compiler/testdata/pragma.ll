@@ -10,6 +10,7 @@ target triple = "wasm32-unknown-wasi"
 @main.globalInSection = hidden global i32 0, section ".special_global_section", align 4
 @undefinedGlobalNotInSection = external global i32, align 4
 @main.multipleGlobalPragmas = hidden global i32 0, section ".global_section", align 1024
+@somepkg.someGlobal = external global i32, align 4
 
 ; Function Attrs: allockind("alloc,zeroed") allocsize(0)
 declare noalias nonnull ptr @runtime.alloc(i32, ptr, ptr) #0
@@ -60,10 +61,18 @@ entry:
   ret void
 }
 
+; Function Attrs: noinline nounwind
+define hidden void @main.functionInRAM(ptr %context) unnamed_addr #5 section ".ramfuncs" {
+entry:
+  ret void
+}
+
 declare void @main.declaredImport() #7
 
 declare void @main.undefinedFunctionNotInSection(ptr) #1
 
+declare void @somepkg.someFunction3(ptr) #1
+
 attributes #0 = { allockind("alloc,zeroed") allocsize(0) "alloc-family"="runtime.alloc" "target-features"="+bulk-memory,+nontrapping-fptoint,+sign-ext" }
 attributes #1 = { "target-features"="+bulk-memory,+nontrapping-fptoint,+sign-ext" }
 attributes #2 = { nounwind "target-features"="+bulk-memory,+nontrapping-fptoint,+sign-ext" }
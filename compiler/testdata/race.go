@@ -0,0 +1,12 @@
+package main
+
+var counter int
+
+func increment() {
+	counter++
+}
+
+//go:norace
+func incrementNoRace() {
+	counter++
+}
@@ -0,0 +1,114 @@
+package compiler
+
+// This file implements -panic=trace, which prints a stack trace with function
+// names and line numbers on panic. It works as follows:
+//   * Every function allocates a runtime.traceFrame in the entry block and
+//     links it into a list of frames of the current goroutine by calling
+//     runtime.tracePush. The frame is unlinked again on return.
+//   * Before every call that may panic, a pointer to a constant
+//     runtime.traceSite (with the function name, file and line of the call) is
+//     stored in the frame.
+// The runtime walks this list on panic. Unlike a table indexed by program
+// counter, this doesn't require unwinding the stack, which isn't possible on
+// all targets (WebAssembly for example).
+
+import (
+	"go/token"
+	"strconv"
+
+	"tinygo.org/x/go-llvm"
+)
+
+// needsTraceFrame returns whether the current function should keep track of
+// the position of its calls for stack traces.
+func (b *builder) needsTraceFrame() bool {
+	if !b.PanicTrace || b.fn.Syntax() == nil || b.fn.Pkg == nil {
+		return false
+	}
+	switch b.fn.Pkg.Pkg.Path() {
+	case "runtime", "internal/task":
+		// The trace functions themselves are implemented in these packages.
+		return false
+	}
+	return true
+}
+
+// createTraceFrame allocates the trace frame of the current function and links
+// it into the list of frames of the current goroutine. It must be called from
+// within the entry block.
+func (b *builder) createTraceFrame() {
+	b.traceSites = make(map[string]llvm.Value)
+	b.traceFrame = b.CreateAlloca(b.getLLVMRuntimeType("traceFrame"), "traceframe")
+	b.createRuntimeCall("tracePush", []llvm.Value{b.traceFrame}, "")
+}
+
+// createTraceSite stores the position of the call that's about to be made in
+// the trace frame of the current function, if there is one.
+func (b *builder) createTraceSite() {
+	if b.traceFrame.IsNil() || !b.tracePos.IsValid() {
+		return
+	}
+	sitePtr := b.CreateInBoundsGEP(b.getLLVMRuntimeType("traceFrame"), b.traceFrame, []llvm.Value{
+		llvm.ConstInt(b.ctx.Int32Type(), 0, false),
+		llvm.ConstInt(b.ctx.Int32Type(), 1, false),
+	}, "traceframe.site")
+	b.CreateStore(b.getTraceSite(b.program.Fset.Position(b.tracePos)), sitePtr)
+}
+
+// getTraceSite returns the constant runtime.traceSite global for the given
+// position in the current function.
+func (b *builder) getTraceSite(pos token.Position) llvm.Value {
+	key := pos.Filename + ":" + strconv.Itoa(pos.Line)
+	if site, ok := b.traceSites[key]; ok {
+		return site
+	}
+	// The file is part of the function info instead of the site, because all
+	// calls in a function are usually in the same file. Package initializers
+	// are the exception: they get one function info per file.
+	name := b.fn.RelString(nil)
+	function, ok := b.traceFuncs[name+"\x00"+pos.Filename]
+	if !ok {
+		funcType := b.getLLVMRuntimeType("traceFunc")
+		function = llvm.AddGlobal(b.mod, funcType, name+"$tracefunc")
+		function.SetInitializer(llvm.ConstNamedStruct(funcType, []llvm.Value{
+			b.createConstString(name),
+			b.createConstString(pos.Filename),
+		}))
+		function.SetLinkage(llvm.InternalLinkage)
+		function.SetGlobalConstant(true)
+		function.SetUnnamedAddr(true)
+		b.traceFuncs[name+"\x00"+pos.Filename] = function
+	}
+	siteType := b.getLLVMRuntimeType("traceSite")
+	site := llvm.AddGlobal(b.mod, siteType, name+"$tracesite")
+	site.SetInitializer(llvm.ConstNamedStruct(siteType, []llvm.Value{
+		function,
+		llvm.ConstInt(b.ctx.Int32Type(), uint64(pos.Line), false),
+	}))
+	site.SetLinkage(llvm.InternalLinkage)
+	site.SetGlobalConstant(true)
+	site.SetUnnamedAddr(true)
+	b.traceSites[key] = site
+	return site
+}
+
+// createConstString returns a constant Go string for use in metadata such as
+// trace function info and coverage blocks. Equal strings (like file names) are
+// shared within the package.
+func (c *compilerContext) createConstString(s string) llvm.Value {
+	global, ok := c.constStrings[s]
+	if !ok {
+		globalType := llvm.ArrayType(c.ctx.Int8Type(), len(s))
+		global = llvm.AddGlobal(c.mod, globalType, c.pkg.Path()+"$string")
+		global.SetInitializer(c.ctx.ConstString(s, false))
+		global.SetLinkage(llvm.InternalLinkage)
+		global.SetGlobalConstant(true)
+		global.SetUnnamedAddr(true)
+		global.SetAlignment(1)
+		c.constStrings[s] = global
+	}
+	zero := llvm.ConstInt(c.ctx.Int32Type(), 0, false)
+	strPtr := llvm.ConstInBoundsGEP(global.GlobalValueType(), global, []llvm.Value{zero, zero})
+	strLen := llvm.ConstInt(c.uintptrType, uint64(len(s)), false)
+	return llvm.ConstNamedStruct(c.getLLVMRuntimeType("_string"), []llvm.Value{strPtr, strLen})
+}
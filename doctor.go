@@ -0,0 +1,231 @@
+package main
+
+// This file implements the doctor command, which checks whether everything
+// that is needed to build and flash a program for a given target is installed
+// and usable.
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/shlex"
+	"github.com/tinygo-org/tinygo/builder"
+	"github.com/tinygo-org/tinygo/compileopts"
+)
+
+// Status of a single doctor check.
+const (
+	doctorOK = iota
+	doctorWarning
+	doctorError
+)
+
+// doctorCheck is the result of a single check done by the doctor command.
+type doctorCheck struct {
+	name   string
+	status int
+	result string // what was found, or what is wrong
+	fix    string // how to fix the problem, if any
+}
+
+// Install hints for commonly used flash tools.
+var doctorInstallHints = map[string]string{
+	"avrdude":         "install avrdude using your package manager",
+	"bossac":          "install BOSSA from https://github.com/shumatech/BOSSA/releases (version 1.9 or later)",
+	"esptool.py":      "install esptool with: pip install esptool",
+	"nrfjprog":        "install the nRF Command Line Tools from https://www.nordicsemi.com",
+	"nrfutil":         "install nrfutil with: pip install adafruit-nrfutil",
+	"openocd":         "install OpenOCD using your package manager (version 0.11 or later)",
+	"picotool":        "install picotool from https://github.com/raspberrypi/picotool",
+	"qemu-system-arm": "install QEMU using your package manager",
+}
+
+// Doctor checks the toolchain and the connected board for the target in the
+// options, prints the results together with suggestions to fix any problem,
+// and returns an error if the target cannot be built or flashed.
+func Doctor(port string, options *compileopts.Options) error {
+	config, err := builder.NewConfig(options)
+	if err != nil {
+		return err
+	}
+
+	var checks []doctorCheck
+	checks = append(checks, checkDoctorLinker(config))
+	checks = append(checks, doctorCheck{
+		name:   "objcopy",
+		status: doctorOK,
+		result: "built into tinygo",
+	})
+	checks = append(checks, checkDoctorFlasher(config)...)
+	if len(config.Target.GDB) != 0 {
+		checks = append(checks, checkDoctorGDB(config))
+	}
+	checks = append(checks, checkDoctorBoard(port, config, options)...)
+
+	failed := 0
+	for _, check := range checks {
+		label := "ok"
+		switch check.status {
+		case doctorWarning:
+			label = "warning"
+		case doctorError:
+			label = "error"
+			failed++
+		}
+		fmt.Printf("%-9s %-12s %s\n", "["+label+"]", check.name+":", check.result)
+		if check.fix != "" && check.status != doctorOK {
+			fmt.Printf("%-9s %-12s fix: %s\n", "", "", check.fix)
+		}
+	}
+	if failed != 0 {
+		return fmt.Errorf("found %d problem(s) for target %s", failed, options.Target)
+	}
+	return nil
+}
+
+// checkDoctorLinker checks that the linker of the target can be found.
+func checkDoctorLinker(config *compileopts.Config) doctorCheck {
+	check := doctorCheck{name: "linker"}
+	path, builtin, err := builder.LookupTool(config.Target.Linker)
+	switch {
+	case err != nil:
+		check.status = doctorError
+		check.result = err.Error()
+		check.fix = "install " + config.Target.Linker + " and make sure it is in your $PATH"
+		if config.Target.Linker == "ld.lld" || config.Target.Linker == "wasm-ld" {
+			check.fix = "install LLD (the LLVM linker) using your package manager, or use an official TinyGo release which includes it"
+		}
+	case builtin:
+		check.result = config.Target.Linker + " (built into tinygo)"
+	default:
+		check.result = path
+	}
+	return check
+}
+
+// checkDoctorFlasher checks the tools used for flashing (or running) the
+// program on the target.
+func checkDoctorFlasher(config *compileopts.Config) []doctorCheck {
+	flashMethod, _ := config.Programmer()
+	switch flashMethod {
+	case "command", "":
+		if config.Target.FlashCommand == "" {
+			if config.Target.Emulator == "" {
+				return nil
+			}
+			parts, err := shlex.Split(config.Target.Emulator)
+			if err != nil || len(parts) == 0 {
+				return []doctorCheck{{name: "emulator", status: doctorError, result: "invalid emulator command: " + config.Target.Emulator}}
+			}
+			return []doctorCheck{checkDoctorCommand("emulator", parts[0])}
+		}
+		parts, err := shlex.Split(config.Target.FlashCommand)
+		if err != nil || len(parts) == 0 {
+			return []doctorCheck{{name: "flash tool", status: doctorError, result: "invalid flash command: " + config.Target.FlashCommand}}
+		}
+		return []doctorCheck{checkDoctorCommand("flash tool", parts[0])}
+	case "openocd":
+		return []doctorCheck{checkDoctorCommand("flash tool", "openocd")}
+	case "bmp":
+		check := doctorCheck{name: "flash tool"}
+		gdbPort, _, err := getBMPPorts()
+		if err != nil {
+			check.status = doctorError
+			check.result = err.Error()
+			check.fix = "connect the Black Magic Probe"
+		} else {
+			check.result = "Black Magic Probe at " + gdbPort
+		}
+		return []doctorCheck{check}
+	case "msd":
+		return []doctorCheck{{
+			name:   "flash tool",
+			status: doctorOK,
+			result: "not needed (copying to mass storage device)",
+		}}
+	default:
+		return []doctorCheck{{name: "flash tool", status: doctorError, result: "unknown flash method: " + flashMethod}}
+	}
+}
+
+// checkDoctorCommand checks whether the given command can be found in $PATH.
+func checkDoctorCommand(name, command string) doctorCheck {
+	check := doctorCheck{name: name}
+	path, err := exec.LookPath(command)
+	if err != nil {
+		check.status = doctorError
+		check.result = command + " not found in $PATH"
+		check.fix = doctorInstallHints[filepath.Base(command)]
+		if check.fix == "" {
+			check.fix = "install " + command + " and make sure it is in your $PATH"
+		}
+		return check
+	}
+	check.result = path
+	return check
+}
+
+// checkDoctorGDB checks for a debugger, which is only needed for tinygo gdb.
+func checkDoctorGDB(config *compileopts.Config) doctorCheck {
+	check := doctorCheck{name: "debugger"}
+	path, err := config.Target.LookupGDB()
+	if err != nil {
+		check.status = doctorWarning
+		check.result = err.Error()
+		check.fix = "install one of these to use tinygo gdb: " + strings.Join(config.Target.GDB, ", ")
+		return check
+	}
+	check.result = path
+	return check
+}
+
+// checkDoctorBoard checks whether the board is connected and accessible, and
+// whether its bootloader is running.
+func checkDoctorBoard(port string, config *compileopts.Config, options *compileopts.Options) []doctorCheck {
+	var checks []doctorCheck
+	flashMethod, _ := config.Programmer()
+	usesPort := len(config.Target.SerialPort) != 0 || config.Target.PortReset == "true" || strings.Contains(config.Target.FlashCommand, "{port}")
+	if usesPort {
+		check := doctorCheck{name: "serial port"}
+		found, err := getDefaultPort(port, config.Target.SerialPort)
+		switch {
+		case err != nil:
+			check.status = doctorWarning
+			check.result = "board not found: " + err.Error()
+			check.fix = "connect the board using a USB cable that supports data (not just charging)"
+		default:
+			check.result = found
+			if err := checkDeviceAccess(found); err != nil {
+				check.status = doctorError
+				check.result = found + " is not accessible: " + err.Error()
+				check.fix = deviceAccessFix(found)
+			}
+		}
+		checks = append(checks, check)
+	}
+
+	if flashMethod == "msd" && len(config.Target.FlashVolume) != 0 {
+		check := doctorCheck{name: "bootloader"}
+		var mounted string
+		for _, path := range uf2InfoPaths(config.Target.FlashVolume, options) {
+			if matches, _ := filepath.Glob(path); len(matches) != 0 {
+				mounted = filepath.Dir(matches[0])
+				break
+			}
+		}
+		switch {
+		case mounted != "":
+			check.result = "bootloader volume mounted at " + mounted
+		case config.Target.PortReset == "true":
+			check.result = "not running, the board will be reset into the bootloader when flashing"
+		default:
+			check.status = doctorWarning
+			check.result = "no volume named " + strings.Join(config.Target.FlashVolume, " or ") + " is mounted"
+			check.fix = "enter the bootloader, usually by double-tapping the reset button"
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
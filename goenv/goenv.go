@@ -292,8 +292,9 @@ func getGoroot() string {
 		}
 	case "darwin":
 		candidates = []string{
-			"/usr/local/go",             // manually installed
-			"/usr/local/opt/go/libexec", // from Homebrew
+			"/usr/local/go",                // manually installed
+			"/usr/local/opt/go/libexec",    // from Homebrew (Intel)
+			"/opt/homebrew/opt/go/libexec", // from Homebrew (Apple Silicon)
 		}
 	}
 
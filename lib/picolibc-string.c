@@ -0,0 +1,147 @@
+// This file is included in the picolibc build.
+// It replaces the memcpy, memmove and memset implementations of picolibc, which
+// only handle a single byte at a time when optimizing for size (as all
+// libraries are). These versions handle a word at a time when the buffers can
+// be aligned, with a few words per loop iteration. They are used for every
+// copy in Go code that is not small enough to be inlined by the compiler.
+
+#include <stddef.h>
+#include <stdint.h>
+#include <string.h>
+
+// Prevent the compiler from turning the loops below into calls to the very
+// functions that they implement.
+#if defined(__clang__)
+#define NO_BUILTIN __attribute__((no_builtin))
+#else
+#define NO_BUILTIN __attribute__((optimize("no-tree-loop-distribute-patterns")))
+#endif
+
+typedef uintptr_t word_t;
+
+#define WORD_SIZE sizeof(word_t)
+#define MISALIGNMENT(p) ((uintptr_t)(p) & (WORD_SIZE - 1))
+
+// Number of words handled per loop iteration. ARMv7-M and ARMv8-M mainline
+// cores can load and store 8 registers with a single ldm/stm instruction and
+// have enough registers to do so. Other cores (Cortex-M0, RISC-V, Xtensa) use
+// 4 words, which is enough to hide the loop overhead without running out of
+// registers.
+#if defined(__ARM_ARCH_7M__) || defined(__ARM_ARCH_7EM__) || defined(__ARM_ARCH_8M_MAIN__)
+#define UNROLL 8
+#define REPEAT(x) x(0) x(1) x(2) x(3) x(4) x(5) x(6) x(7)
+#else
+#define UNROLL 4
+#define REPEAT(x) x(0) x(1) x(2) x(3)
+#endif
+
+// Copy forwards, which is also correct for overlapping buffers when dst is
+// below src.
+static inline void NO_BUILTIN copy_forward(unsigned char *d, const unsigned char *s, size_t n) {
+	if (MISALIGNMENT(d) == MISALIGNMENT(s)) {
+		while (n != 0 && MISALIGNMENT(d) != 0) {
+			*d++ = *s++;
+			n--;
+		}
+		word_t *dw = (word_t *)d;
+		const word_t *sw = (const word_t *)s;
+		while (n >= UNROLL * WORD_SIZE) {
+#define LOAD(i) word_t w##i = sw[i];
+#define STORE(i) dw[i] = w##i;
+			REPEAT(LOAD)
+			REPEAT(STORE)
+#undef LOAD
+#undef STORE
+			dw += UNROLL;
+			sw += UNROLL;
+			n -= UNROLL * WORD_SIZE;
+		}
+		while (n >= WORD_SIZE) {
+			*dw++ = *sw++;
+			n -= WORD_SIZE;
+		}
+		d = (unsigned char *)dw;
+		s = (const unsigned char *)sw;
+	}
+	while (n != 0) {
+		*d++ = *s++;
+		n--;
+	}
+}
+
+// Copy backwards, for overlapping buffers where dst is above src.
+static inline void NO_BUILTIN copy_backward(unsigned char *d, const unsigned char *s, size_t n) {
+	d += n;
+	s += n;
+	if (MISALIGNMENT(d) == MISALIGNMENT(s)) {
+		while (n != 0 && MISALIGNMENT(d) != 0) {
+			*--d = *--s;
+			n--;
+		}
+		word_t *dw = (word_t *)d;
+		const word_t *sw = (const word_t *)s;
+		while (n >= UNROLL * WORD_SIZE) {
+			dw -= UNROLL;
+			sw -= UNROLL;
+#define LOAD(i) word_t w##i = sw[UNROLL - 1 - i];
+#define STORE(i) dw[UNROLL - 1 - i] = w##i;
+			REPEAT(LOAD)
+			REPEAT(STORE)
+#undef LOAD
+#undef STORE
+			n -= UNROLL * WORD_SIZE;
+		}
+		while (n >= WORD_SIZE) {
+			*--dw = *--sw;
+			n -= WORD_SIZE;
+		}
+		d = (unsigned char *)dw;
+		s = (const unsigned char *)sw;
+	}
+	while (n != 0) {
+		*--d = *--s;
+		n--;
+	}
+}
+
+void *NO_BUILTIN memcpy(void *restrict dst, const void *restrict src, size_t n) {
+	copy_forward(dst, src, n);
+	return dst;
+}
+
+void *NO_BUILTIN memmove(void *dst, const void *src, size_t n) {
+	if ((uintptr_t)dst - (uintptr_t)src >= n) {
+		// dst is below src or the buffers don't overlap.
+		copy_forward(dst, src, n);
+	} else {
+		copy_backward(dst, src, n);
+	}
+	return dst;
+}
+
+void *NO_BUILTIN memset(void *dst, int c, size_t n) {
+	unsigned char *d = dst;
+	while (n != 0 && MISALIGNMENT(d) != 0) {
+		*d++ = (unsigned char)c;
+		n--;
+	}
+	word_t *dw = (word_t *)d;
+	word_t w = ((word_t)-1 / 0xff) * (unsigned char)c;
+	while (n >= UNROLL * WORD_SIZE) {
+#define STORE(i) dw[i] = w;
+		REPEAT(STORE)
+#undef STORE
+		dw += UNROLL;
+		n -= UNROLL * WORD_SIZE;
+	}
+	while (n >= WORD_SIZE) {
+		*dw++ = w;
+		n -= WORD_SIZE;
+	}
+	d = (unsigned char *)dw;
+	while (n != 0) {
+		*d++ = (unsigned char)c;
+		n--;
+	}
+	return dst;
+}
@@ -227,6 +227,7 @@ func pathsToOverride(goMinor int, needsSyscallPackage bool) map[string]bool {
 	paths := map[string]bool{
 		"":                      true,
 		"crypto/":               true,
+		"crypto/hardware/":      false,
 		"crypto/rand/":          false,
 		"device/":               false,
 		"examples/":             false,
@@ -236,12 +237,18 @@ func pathsToOverride(goMinor int, needsSyscallPackage bool) map[string]bool {
 		"internal/reflectlite/": false,
 		"internal/task/":        false,
 		"machine/":              false,
+		"math/":                 true,
+		"math/dsp/":             false,
+		"math/fixed/":           false,
 		"net/":                  true,
 		"os/":                   true,
 		"reflect/":              false,
 		"runtime/":              false,
+		"strconv/":              true,
+		"strconv/f32/":          false,
 		"sync/":                 true,
 		"testing/":              true,
+		"tinygo/":               false,
 	}
 
 	if goMinor >= 19 {
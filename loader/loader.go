@@ -18,6 +18,7 @@ import (
 	"path"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"unicode"
@@ -50,6 +51,7 @@ type PackageJSON struct {
 	Name       string
 	ForTest    string
 	Root       string
+	Standard   bool
 	Module     struct {
 		Path      string
 		Main      bool
@@ -62,6 +64,7 @@ type PackageJSON struct {
 	GoFiles  []string
 	CgoFiles []string
 	CFiles   []string
+	SFiles   []string
 
 	// Embedded files
 	EmbedFiles []string
@@ -87,9 +90,19 @@ type Package struct {
 	FileHashes   map[string][]byte
 	CFlags       []string // CFlags used during CGo preprocessing (only set if CGo is used)
 	CGoHeaders   []string // text above 'import "C"' lines
+	CGoExport    string   // contents of _cgo_export.h (only set if a function is exported with //export)
 	EmbedGlobals map[string][]*EmbedFile
+	Linknames    map[string]string // function or global name -> link name (//go:linkname)
 	Pkg          *types.Package
 	info         types.Info
+	generated    []generatedFile
+}
+
+// generatedFile is a Go file that isn't on disk, but is generated by the
+// compiler for a package.
+type generatedFile struct {
+	name string
+	src  []byte
 }
 
 type EmbedFile struct {
@@ -250,6 +263,43 @@ func Load(config *compileopts.Config, inputPkg string, clangHeaders string, type
 	return p, nil
 }
 
+// importStack returns the chain of imports from the main package to the given
+// package, starting with the main package.
+func (p *Program) importStack(importPath string) []string {
+	main := p.sorted[len(p.sorted)-1].ImportPath
+	parents := map[string]string{main: ""}
+	queue := []string{main}
+	for len(queue) != 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == importPath {
+			var stack []string
+			for path := current; path != ""; path = parents[path] {
+				stack = append([]string{path}, stack...)
+			}
+			return stack
+		}
+		pkg := p.Packages[current]
+		if pkg == nil {
+			continue
+		}
+		for _, imported := range pkg.Imports {
+			if mapped, ok := pkg.ImportMap[imported]; ok {
+				imported = mapped
+			}
+			if i := strings.Index(imported, " ["); i >= 0 {
+				// Test variant, see the comment in Load.
+				imported = imported[:i]
+			}
+			if _, ok := parents[imported]; !ok {
+				parents[imported] = current
+				queue = append(queue, imported)
+			}
+		}
+	}
+	return []string{importPath}
+}
+
 // getOriginalPath looks whether this path is in the generated GOROOT and if so,
 // replaces the path with the original path (in GOROOT or TINYGOROOT). Otherwise
 // the input path is returned.
@@ -339,6 +389,13 @@ func (p *Package) parseFile(path string, mode parser.Mode) (*ast.File, error) {
 	return parser.ParseFile(p.program.fset, originalPath, data, mode)
 }
 
+// AddGeneratedFile adds a Go file with the given name and source to the
+// package, as if it were a file in the package directory. It must be called
+// before Parse.
+func (p *Package) AddGeneratedFile(name string, src []byte) {
+	p.generated = append(p.generated, generatedFile{name, src})
+}
+
 // Parse parses and typechecks this package.
 //
 // Idempotent.
@@ -400,6 +457,7 @@ func (p *Package) Check() error {
 	p.Pkg = typesPkg
 
 	p.extractEmbedLines(checker.Error)
+	p.extractLinknames(checker.Error)
 	if len(typeErrors) != 0 {
 		return Errors{p, typeErrors}
 	}
@@ -430,6 +488,17 @@ func (p *Package) parseFiles() ([]*ast.File, error) {
 	for _, file := range p.CgoFiles {
 		parseFile(file)
 	}
+	for _, file := range p.generated {
+		path := filepath.Join(p.OriginalDir(), file.name)
+		sum := sha512.Sum512_224(file.src)
+		p.FileHashes[path] = sum[:]
+		f, err := parser.ParseFile(p.program.fset, path, file.src, parser.ParseComments)
+		if err != nil {
+			fileErrs = append(fileErrs, err)
+			continue
+		}
+		files = append(files, f)
+	}
 
 	// Do CGo processing.
 	// This is done when there are any CgoFiles at all. In that case, len(files)
@@ -440,6 +509,7 @@ func (p *Package) parseFiles() ([]*ast.File, error) {
 		var initialCFlags []string
 		initialCFlags = append(initialCFlags, p.program.config.CFlags()...)
 		initialCFlags = append(initialCFlags, "-I"+p.Dir)
+		p.CGoExport = cgo.ExportHeader(files)
 		generated, headerCode, cflags, ldflags, accessedFiles, errs := cgo.Process(files, p.program.workingDir, p.ImportPath, p.program.fset, initialCFlags, p.program.clangHeaders)
 		p.CFlags = append(initialCFlags, cflags...)
 		p.CGoHeaders = headerCode
@@ -596,6 +666,165 @@ func (p *Package) extractEmbedLines(addError func(error)) {
 	}
 }
 
+// extractLinknames finds all //go:linkname lines in the package. Like gc, they
+// may appear anywhere in a file, not just in the doc comment of the function
+// or global they refer to.
+func (p *Package) extractLinknames(addError func(error)) {
+	// Only allow //go:linkname when the package imports "unsafe". This is a
+	// slightly looser requirement than what gc uses: gc requires the file to
+	// import "unsafe", not the package as a whole.
+	hasUnsafe := false
+	for _, imp := range p.Pkg.Imports() {
+		if imp == types.Unsafe {
+			hasUnsafe = true
+		}
+	}
+
+	for _, file := range p.Files {
+		for _, group := range file.Comments {
+			for _, comment := range group.List {
+				if !strings.HasPrefix(comment.Text, "//go:linkname ") {
+					continue
+				}
+				parts := strings.Fields(comment.Text)
+				if len(parts) == 2 {
+					// The one argument form only marks a symbol as
+					// accessible with //go:linkname from other packages,
+					// which is always the case in TinyGo.
+					continue
+				}
+				if len(parts) != 3 {
+					addError(types.Error{
+						Fset: p.program.fset,
+						Pos:  comment.Pos(),
+						Msg:  "usage: //go:linkname localname [linkname]",
+					})
+					continue
+				}
+				if !hasUnsafe {
+					addError(types.Error{
+						Fset: p.program.fset,
+						Pos:  comment.Pos(),
+						Msg:  "//go:linkname only allowed in Go files that import \"unsafe\"",
+					})
+					continue
+				}
+				switch p.Pkg.Scope().Lookup(parts[1]).(type) {
+				case *types.Func, *types.Var:
+				default:
+					addError(types.Error{
+						Fset: p.program.fset,
+						Pos:  comment.Pos(),
+						Msg:  "//go:linkname must refer to declared function or variable",
+					})
+					continue
+				}
+				if p.Linknames == nil {
+					p.Linknames = make(map[string]string)
+				}
+				p.Linknames[parts[1]] = parts[2]
+			}
+		}
+	}
+}
+
+// Linkname returns the link name set with //go:linkname for the given
+// package-level function or global. The object may be part of any package in
+// the program.
+func (p *Package) Linkname(obj types.Object) (string, bool) {
+	if obj == nil || obj.Pkg() == nil || obj.Pkg().Scope().Lookup(obj.Name()) != obj {
+		// Not a package-level object, for example a method or a local
+		// variable.
+		return "", false
+	}
+	pkg := p
+	if obj.Pkg() != p.Pkg {
+		pkg = p.program.Packages[obj.Pkg().Path()]
+		if pkg == nil {
+			return "", false
+		}
+	}
+	name, ok := pkg.Linknames[obj.Name()]
+	return name, ok
+}
+
+// RecordUse records that the identifier refers to the given object, like the
+// type checker does for the identifiers it has seen. This is needed for
+// identifiers that are added to the AST after type checking.
+func (p *Package) RecordUse(ident *ast.Ident, obj types.Object) {
+	p.info.Uses[ident] = obj
+}
+
+// CheckLinknames returns an error if a //go:linkname directive refers to a
+// function or global in a package of the program that doesn't define it. It
+// must be called after all packages have been linked together, with a function
+// that returns whether the given symbol is used but not defined. Undefined
+// symbols outside Go packages, like C functions, are left to the linker.
+func (p *Program) CheckLinknames(isUndefined func(linkName string) bool) error {
+	for _, pkg := range p.sorted {
+		var errs []error
+		for name, linkName := range pkg.Linknames {
+			slash := strings.LastIndexByte(linkName, '/')
+			dot := strings.IndexByte(linkName[slash+1:], '.')
+			if dot < 0 {
+				continue
+			}
+			if _, ok := p.Packages[linkName[:slash+1+dot]]; !ok {
+				continue
+			}
+			if !isUndefined(linkName) {
+				continue
+			}
+			errs = append(errs, types.Error{
+				Fset: p.fset,
+				Pos:  pkg.Pkg.Scope().Lookup(name).Pos(),
+				Msg:  "//go:linkname refers to undefined " + linkName,
+			})
+		}
+		if len(errs) != 0 {
+			sort.Slice(errs, func(i, j int) bool {
+				return errs[i].(types.Error).Pos < errs[j].(types.Error).Pos
+			})
+			return Errors{pkg, errs}
+		}
+	}
+	return nil
+}
+
+// CheckAssembly returns an error for functions outside the standard library
+// that are implemented in Go assembly and are still used by the program, as
+// reported by isUndefined. Go assembly can't be compiled by TinyGo, but most
+// packages only need it on some code paths or provide a pure Go fallback (see
+// the purego build tag), so this is only known after optimizing the program.
+// The error includes the import chain that leads to the package, so that it's
+// clear which dependency is the culprit.
+func (p *Program) CheckAssembly(isUndefined func(name string) bool) error {
+	for _, pkg := range p.sorted {
+		if len(pkg.SFiles) == 0 || pkg.Standard {
+			continue
+		}
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				decl, ok := decl.(*ast.FuncDecl)
+				if !ok || decl.Body != nil || decl.Recv != nil {
+					continue
+				}
+				if !isUndefined(pkg.Pkg.Path() + "." + decl.Name.Name) {
+					continue
+				}
+				return Error{
+					ImportStack: p.importStack(pkg.ImportPath),
+					Err: scanner.Error{
+						Pos: p.fset.Position(decl.Pos()),
+						Msg: fmt.Sprintf("function %s is implemented in Go assembly (%s) which is not supported by TinyGo", decl.Name.Name, strings.Join(pkg.SFiles, ", ")),
+					},
+				}
+			}
+		}
+	}
+	return nil
+}
+
 // matchPattern returns true if (and only if) the given pattern would match the
 // filename. The pattern could also match a parent directory of name, in which
 // case hidden files do not match.
@@ -15,6 +15,7 @@ import (
 	"os"
 	"os/exec"
 	"os/signal"
+	"path"
 	"path/filepath"
 	"regexp"
 	"runtime"
@@ -52,6 +53,10 @@ func (e *commandError) Error() string {
 	return e.Msg + " " + e.File + ": " + e.Err.Error()
 }
 
+func (e *commandError) Unwrap() error {
+	return e.Err
+}
+
 // moveFile renames the file from src to dst. If renaming doesn't work (for
 // example, the rename crosses a filesystem boundary), the file is copied and
 // the old file is removed.
@@ -141,7 +146,7 @@ func printCommand(cmd string, args ...string) {
 
 // Build compiles and links the given package and writes it to outpath.
 func Build(pkgName, outpath string, options *compileopts.Options) error {
-	config, err := builder.NewConfig(options)
+	config, err := newConfig(options)
 	if err != nil {
 		return err
 	}
@@ -155,6 +160,14 @@ func Build(pkgName, outpath string, options *compileopts.Options) error {
 		return nil
 	}
 
+	// The output path may be a directory, in which case the binary is written
+	// to a file with the default name in this directory.
+	outdir := ""
+	if outpath != "" && isOutputDir(outpath) {
+		outdir = outpath
+		outpath = ""
+	}
+
 	// Create a temporary directory for intermediary files.
 	tmpdir, err := os.MkdirTemp("", "tinygo")
 	if err != nil {
@@ -185,6 +198,7 @@ func Build(pkgName, outpath string, options *compileopts.Options) error {
 				// Pick a default output path based on the main directory.
 				outpath = filepath.Base(result.MainDir) + config.DefaultBinaryExtension()
 			}
+			outpath = filepath.Join(outdir, outpath)
 		}
 
 		if err := os.Rename(result.Binary, outpath); err != nil {
@@ -214,11 +228,94 @@ func Build(pkgName, outpath string, options *compileopts.Options) error {
 	return nil
 }
 
+// BuildPackages builds all main packages matching the given package patterns,
+// like `go build ./cmd/...`. Each binary is written to outdir and is named
+// after the last element of its import path. If outdir is empty, the binaries
+// are only built to check that they compile and are then discarded, like `go
+// build` does when building multiple packages. Packages that are shared between
+// binaries are only compiled once, through the build cache.
+//
+// All packages are built even if some of them fail to build, in which case
+// their errors are printed and an error is returned at the end.
+func BuildPackages(pkgPatterns []string, outdir string, options *compileopts.Options) error {
+	pkgNames, err := getListOfMainPackages(pkgPatterns, options)
+	if err != nil {
+		return fmt.Errorf("cannot resolve packages: %w", err)
+	}
+	if len(pkgNames) == 0 {
+		return fmt.Errorf("no main packages to build in %s", strings.Join(pkgPatterns, " "))
+	}
+	config, err := newConfig(options)
+	if err != nil {
+		return err
+	}
+
+	if outdir == "" {
+		outdir, err = os.MkdirTemp("", "tinygo-build")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(outdir)
+	} else if err := os.MkdirAll(outdir, 0777); err != nil {
+		return err
+	}
+
+	// Check that no two binaries have the same name before building anything.
+	outpaths := make([]string, len(pkgNames))
+	pkgForOutpath := make(map[string]string)
+	for i, pkgName := range pkgNames {
+		outpath := filepath.Join(outdir, path.Base(pkgName)+config.DefaultBinaryExtension())
+		if other, ok := pkgForOutpath[outpath]; ok {
+			return fmt.Errorf("cannot write both %s and %s to %s", other, pkgName, outpath)
+		}
+		pkgForOutpath[outpath] = pkgName
+		outpaths[i] = outpath
+	}
+
+	failed := 0
+	for i, pkgName := range pkgNames {
+		err := Build(pkgName, outpaths[i], options)
+		if err != nil {
+			failed++
+			printCompilerError(func(args ...interface{}) {
+				fmt.Fprintln(os.Stderr, args...)
+			}, err)
+		}
+	}
+	if failed != 0 {
+		return fmt.Errorf("failed to build %d of %d packages", failed, len(pkgNames))
+	}
+	return nil
+}
+
+// isOutputDir returns whether the -o flag refers to a directory, like in `go
+// build`: either an existing directory or a path that ends in a slash.
+func isOutputDir(outpath string) bool {
+	if strings.HasSuffix(outpath, "/") || strings.HasSuffix(outpath, string(filepath.Separator)) {
+		return true
+	}
+	st, err := os.Stat(outpath)
+	return err == nil && st.IsDir()
+}
+
+// parseOffset parses the value of the -code-offset and -ram-offset flags,
+// which is either a number (like 0x40000) or a size (like 256KB).
+func parseOffset(s string) (uint64, error) {
+	if n, err := strconv.ParseUint(s, 0, 64); err == nil {
+		return n, nil
+	}
+	size, err := bytesize.Parse(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid offset %q: expected a number like 0x40000 or a size like 256KB", s)
+	}
+	return uint64(size), nil
+}
+
 // Test runs the tests in the given package. Returns whether the test passed and
 // possibly an error if the test failed to run.
 func Test(pkgName string, stdout, stderr io.Writer, options *compileopts.Options, outpath string) (bool, error) {
 	options.TestConfig.CompileTestBinary = true
-	config, err := builder.NewConfig(options)
+	config, err := newConfig(options)
 	if err != nil {
 		return false, err
 	}
@@ -255,6 +352,25 @@ func Test(pkgName string, stdout, stderr io.Writer, options *compileopts.Options
 		flags = append(flags, "-test.shuffle="+testConfig.Shuffle)
 	}
 
+	// Let the test binary write its coverage profile to a temporary file, so
+	// that the coverage can be reported and the profile can be merged with
+	// the profiles of other packages. This needs a filesystem that's shared
+	// with the host, so on other systems only the test binary itself prints
+	// the coverage.
+	var coverFile string
+	if testConfig.CoverMode != "" && !testConfig.CompileOnly && hasHostFilesystem(config) {
+		f, err := os.CreateTemp("", "tinygo-cover-*.out")
+		if err != nil {
+			return false, err
+		}
+		f.Close()
+		defer os.Remove(f.Name())
+		coverFile = f.Name()
+		flags = append(flags, "-test.coverprofile="+coverFile)
+	} else if testConfig.CoverProfile != "" && !testConfig.CompileOnly {
+		fmt.Fprintf(stderr, "warning: cannot write coverage profile on target %s\n", config.Triple())
+	}
+
 	logToStdout := testConfig.Verbose || testConfig.BenchRegexp != ""
 
 	var buf bytes.Buffer
@@ -311,6 +427,9 @@ func Test(pkgName string, stdout, stderr io.Writer, options *compileopts.Options
 			for _, d := range dirs[1:] {
 				args = append(args, "--dir="+d)
 			}
+			if coverFile != "" {
+				args = append(args, "--dir="+filepath.Dir(coverFile))
+			}
 
 			// The below re-organizes the arguments so that the current
 			// directory is added last.
@@ -349,13 +468,91 @@ func Test(pkgName string, stdout, stderr io.Writer, options *compileopts.Options
 		// Pretend the test passed - it at least didn't fail.
 		return true, nil
 	} else if passed && !testConfig.CompileOnly {
-		fmt.Fprintf(w, "ok  \t%s\t%.3fs\n", importPath, duration.Seconds())
+		coverage := ""
+		if coverFile != "" {
+			var coverErr error
+			coverage, coverErr = readCoverProfile(coverFile, testConfig.CoverProfile)
+			if coverErr != nil {
+				return false, coverErr
+			}
+		}
+		fmt.Fprintf(w, "ok  \t%s\t%.3fs%s\n", importPath, duration.Seconds(), coverage)
 	} else {
 		fmt.Fprintf(w, "FAIL\t%s\t%.3fs\n", importPath, duration.Seconds())
 	}
 	return passed, err
 }
 
+// hasHostFilesystem returns whether programs for this target can access the
+// filesystem of the host: on operating systems and WASI, but not in the
+// browser or on baremetal systems.
+func hasHostFilesystem(config *compileopts.Config) bool {
+	if config.GOOS() == "js" {
+		return false
+	}
+	for _, tag := range config.BuildTags() {
+		if tag == "baremetal" {
+			return false
+		}
+	}
+	return true
+}
+
+// coverProfileLock serializes writes to the -coverprofile file by tests that
+// run in parallel.
+var coverProfileLock sync.Mutex
+
+// readCoverProfile reads the coverage profile written by a test binary and
+// returns the coverage summary to print after the test result. If profile is
+// not empty, the blocks are also appended to that file.
+func readCoverProfile(path, profile string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	header, blocks, _ := strings.Cut(string(data), "\n")
+	if !strings.HasPrefix(header, "mode: ") {
+		return "", fmt.Errorf("invalid coverage profile written by test: %s", path)
+	}
+
+	// Compute the percentage of statements covered, like the test binary
+	// does. Each line has the form "file:start,end numStmt count".
+	var total, covered int
+	for _, line := range strings.Split(strings.TrimSpace(blocks), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		numStmt, _ := strconv.Atoi(fields[1])
+		total += numStmt
+		if fields[2] != "0" {
+			covered += numStmt
+		}
+	}
+
+	if profile != "" {
+		coverProfileLock.Lock()
+		defer coverProfileLock.Unlock()
+		f, err := os.OpenFile(profile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		if st, err := f.Stat(); err == nil && st.Size() == 0 {
+			// Only the first package writes the mode line.
+			blocks = string(data)
+		}
+		if _, err := f.WriteString(blocks); err != nil {
+			return "", err
+		}
+	}
+
+	if total == 0 {
+		return "\tcoverage: [no statements]", nil
+	}
+	return fmt.Sprintf("\tcoverage: %.1f%% of statements", 100*float64(covered)/float64(total)), nil
+}
+
 func dirsToModuleRoot(maindir, modroot string) []string {
 	var dirs = []string{"."}
 	last := ".."
@@ -371,7 +568,7 @@ func dirsToModuleRoot(maindir, modroot string) []string {
 
 // Flash builds and flashes the built binary to the given serial port.
 func Flash(pkgName, port string, options *compileopts.Options) error {
-	config, err := builder.NewConfig(options)
+	config, err := newConfig(options)
 	if err != nil {
 		return err
 	}
@@ -427,7 +624,7 @@ func Flash(pkgName, port string, options *compileopts.Options) error {
 	}
 
 	// do we need port reset to put MCU into bootloader mode?
-	if config.Target.PortReset == "true" && flashMethod != "openocd" {
+	if needsPortReset(config, flashMethod, fileExt, options) {
 		port, err := getDefaultPort(port, config.Target.SerialPort)
 		if err == nil {
 			err = touchSerialPortAt1200bps(port)
@@ -457,6 +654,22 @@ func Flash(pkgName, port string, options *compileopts.Options) error {
 			}
 		}
 
+		if options.ESPFlashEncryption {
+			// Let esptool.py encrypt the image while writing it, using the
+			// flash encryption key stored in the chip.
+			index := -1
+			for i, arg := range flashCmdList {
+				if arg == "write_flash" {
+					index = i
+					break
+				}
+			}
+			if index < 0 {
+				return errors.New("-esp-flash-encryption is only supported with esptool.py flash commands")
+			}
+			flashCmdList = append(flashCmdList[:index+1], append([]string{"--encrypt"}, flashCmdList[index+1:]...)...)
+		}
+
 		// Fill in fields in the command template.
 		fileToken := "{" + fileExt[1:] + "}"
 		for i, arg := range flashCmdList {
@@ -545,7 +758,7 @@ func Flash(pkgName, port string, options *compileopts.Options) error {
 // Note: this command is expected to execute just before exiting, as it
 // modifies global state.
 func Debug(debugger, pkgName string, ocdOutput bool, options *compileopts.Options) error {
-	config, err := builder.NewConfig(options)
+	config, err := newConfig(options)
 	if err != nil {
 		return err
 	}
@@ -767,14 +980,17 @@ func Debug(debugger, pkgName string, ocdOutput bool, options *compileopts.Option
 // Run compiles and runs the given program. Depending on the target provided in
 // the options, it will run the program directly on the host or will run it in
 // an emulator. For example, -target=wasm will cause the binary to be run inside
-// of a WebAssembly VM.
-func Run(pkgName string, options *compileopts.Options, cmdArgs []string) error {
-	config, err := builder.NewConfig(options)
+// of a WebAssembly VM. The program reads from the standard input of this
+// process, so it can be used in a shell pipeline. If timeout is not zero, the
+// program is terminated when it runs for longer than that.
+func Run(pkgName string, options *compileopts.Options, timeout time.Duration, cmdArgs []string) error {
+	config, err := newConfig(options)
 	if err != nil {
 		return err
 	}
 
-	_, err = buildAndRun(pkgName, config, os.Stdout, cmdArgs, nil, 0, func(cmd *exec.Cmd, result builder.BuildResult) error {
+	_, err = buildAndRun(pkgName, config, os.Stdout, cmdArgs, nil, timeout, func(cmd *exec.Cmd, result builder.BuildResult) error {
+		cmd.Stdin = os.Stdin
 		return cmd.Run()
 	})
 	return err
@@ -797,6 +1013,10 @@ func buildAndRun(pkgName string, config *compileopts.Config, stdout io.Writer, c
 			needsEnvInVars = true
 		}
 	}
+	// Add the arguments and environment variables passed with -arg and -env,
+	// without modifying the slices of the caller.
+	cmdArgs = append(cmdArgs[:len(cmdArgs):len(cmdArgs)], config.Options.RunArgs...)
+	environmentVars = append(environmentVars[:len(environmentVars):len(environmentVars)], config.Options.RunEnv...)
 	var args, env []string
 	var extraCmdEnv []string
 	if needsEnvInVars {
@@ -821,6 +1041,13 @@ func buildAndRun(pkgName string, config *compileopts.Config, stdout io.Writer, c
 		// Wasmtime needs some special flags to pass environment variables
 		// and allow reading from the current directory.
 		args = append(args, "--dir=.")
+		for _, dir := range config.Options.RunDirs {
+			if host, guest, ok := strings.Cut(dir, "::"); ok {
+				args = append(args, "--mapdir="+guest+"::"+host)
+			} else {
+				args = append(args, "--dir="+dir)
+			}
+		}
 		for _, v := range environmentVars {
 			args = append(args, "--env", v)
 		}
@@ -885,8 +1112,11 @@ func buildAndRun(pkgName string, config *compileopts.Config, stdout io.Writer, c
 	} else {
 		cmd = exec.Command(name, args...)
 	}
-	cmd.Env = append(cmd.Env, env...)
-	cmd.Env = append(cmd.Env, extraCmdEnv...)
+	if len(env) != 0 || len(extraCmdEnv) != 0 {
+		// Extend the environment of this process instead of replacing it.
+		cmd.Env = append(os.Environ(), env...)
+		cmd.Env = append(cmd.Env, extraCmdEnv...)
+	}
 
 	// Configure stdout/stderr. The stdout may go to a buffer, not a real
 	// stdout.
@@ -912,7 +1142,7 @@ func buildAndRun(pkgName string, config *compileopts.Config, stdout io.Writer, c
 	err = run(cmd, result)
 	if err != nil {
 		if ctx != nil && ctx.Err() == context.DeadlineExceeded {
-			stdout.Write([]byte(fmt.Sprintf("--- timeout of %s exceeded, terminating...\n", timeout)))
+			fmt.Fprintf(os.Stderr, "--- timeout of %s exceeded, terminating...\n", timeout)
 			err = ctx.Err()
 		}
 		return result, &commandError{"failed to run compiled binary", result.Binary, err}
@@ -945,7 +1175,36 @@ func touchSerialPortAt1200bps(port string) (err error) {
 	return fmt.Errorf("opening port: %s", err)
 }
 
+// needsPortReset returns whether the MCU must be put into bootloader mode with
+// a 1200bps touch before flashing, according to the target. This is skipped
+// when the bootloader is already running, which is known when the mass storage
+// device of a UF2 bootloader is already mounted.
+func needsPortReset(config *compileopts.Config, flashMethod, fileExt string, options *compileopts.Options) bool {
+	if config.Target.PortReset != "true" || flashMethod == "openocd" {
+		return false
+	}
+	if flashMethod == "msd" && fileExt == ".uf2" {
+		for _, path := range uf2InfoPaths(config.Target.FlashVolume, options) {
+			if matches, _ := filepath.Glob(path); len(matches) != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 func flashUF2UsingMSD(volumes []string, tmppath string, options *compileopts.Options) error {
+	d, err := locateDevice(volumes, uf2InfoPaths(volumes, options), options.Timeout)
+	if err != nil {
+		return err
+	}
+
+	return moveFile(tmppath, filepath.Dir(d)+"/flash.uf2")
+}
+
+// uf2InfoPaths returns the glob patterns of the INFO_UF2.TXT file of the
+// given UF2 bootloader volumes.
+func uf2InfoPaths(volumes []string, options *compileopts.Options) []string {
 	// find standard UF2 info path
 	infoPaths := make([]string, 0, len(volumes))
 	for _, volume := range volumes {
@@ -966,13 +1225,7 @@ func flashUF2UsingMSD(volumes []string, tmppath string, options *compileopts.Opt
 			}
 		}
 	}
-
-	d, err := locateDevice(volumes, infoPaths, options.Timeout)
-	if err != nil {
-		return err
-	}
-
-	return moveFile(tmppath, filepath.Dir(d)+"/flash.uf2")
+	return infoPaths
 }
 
 func flashHexUsingMSD(volumes []string, tmppath string, options *compileopts.Options) error {
@@ -1032,9 +1285,19 @@ func locateDevice(volumes, paths []string, timeout time.Duration) (string, error
 }
 
 func windowsFindUSBDrive(volume string, options *compileopts.Options) (string, error) {
-	cmd := executeCommand(options, "wmic",
-		"PATH", "Win32_LogicalDisk", "WHERE", "VolumeName = '"+volume+"'",
-		"get", "DeviceID,VolumeName,FileSystem,DriveType")
+	var cmd *exec.Cmd
+	if _, err := exec.LookPath("wmic"); err == nil {
+		cmd = executeCommand(options, "wmic",
+			"PATH", "Win32_LogicalDisk", "WHERE", "VolumeName = '"+volume+"'",
+			"get", "DeviceID,DriveType,FileSystem")
+	} else {
+		// wmic is deprecated and not installed by default on newer Windows
+		// versions (including Windows on ARM64), so use PowerShell instead.
+		// The output has the same columns as the wmic command above.
+		cmd = executeCommand(options, "powershell", "-NoProfile", "-Command",
+			"Get-CimInstance Win32_LogicalDisk -Filter \"VolumeName = '"+volume+"'\" | "+
+				"ForEach-Object { \"$($_.DeviceID) $($_.DriveType) $($_.FileSystem)\" }")
+	}
 
 	var out bytes.Buffer
 	cmd.Stdout = &out
@@ -1204,20 +1467,23 @@ func usage(command string) {
 		fmt.Fprintln(os.Stderr, "version:", version)
 		fmt.Fprintf(os.Stderr, "usage: %s <command> [arguments]\n", os.Args[0])
 		fmt.Fprintln(os.Stderr, "\ncommands:")
-		fmt.Fprintln(os.Stderr, "  build:   compile packages and dependencies")
-		fmt.Fprintln(os.Stderr, "  run:     compile and run immediately")
-		fmt.Fprintln(os.Stderr, "  test:    test packages")
-		fmt.Fprintln(os.Stderr, "  flash:   compile and flash to the device")
-		fmt.Fprintln(os.Stderr, "  gdb:     run/flash and immediately enter GDB")
-		fmt.Fprintln(os.Stderr, "  lldb:    run/flash and immediately enter LLDB")
-		fmt.Fprintln(os.Stderr, "  monitor: open communication port")
-		fmt.Fprintln(os.Stderr, "  env:     list environment variables used during build")
-		fmt.Fprintln(os.Stderr, "  list:    run go list using the TinyGo root")
-		fmt.Fprintln(os.Stderr, "  clean:   empty cache directory ("+goenv.Get("GOCACHE")+")")
-		fmt.Fprintln(os.Stderr, "  targets: list targets")
-		fmt.Fprintln(os.Stderr, "  info:    show info for specified target")
-		fmt.Fprintln(os.Stderr, "  version: show version")
-		fmt.Fprintln(os.Stderr, "  help:    print this help text")
+		fmt.Fprintln(os.Stderr, "  build:      compile packages and dependencies")
+		fmt.Fprintln(os.Stderr, "  run:        compile and run immediately")
+		fmt.Fprintln(os.Stderr, "  test:       test packages")
+		fmt.Fprintln(os.Stderr, "  flash:      compile and flash to the device")
+		fmt.Fprintln(os.Stderr, "  gdb:        run/flash and immediately enter GDB")
+		fmt.Fprintln(os.Stderr, "  lldb:       run/flash and immediately enter LLDB")
+		fmt.Fprintln(os.Stderr, "  monitor:    open communication port")
+		fmt.Fprintln(os.Stderr, "  env:        list environment variables used during build")
+		fmt.Fprintln(os.Stderr, "  list:       run go list using the TinyGo root")
+		fmt.Fprintln(os.Stderr, "  clean:      empty cache directory ("+goenv.Get("GOCACHE")+")")
+		fmt.Fprintln(os.Stderr, "  targets:    list targets")
+		fmt.Fprintln(os.Stderr, "  target:     print a target specification with inherited properties (target explain <name>)")
+		fmt.Fprintln(os.Stderr, "  info:       show info for specified target")
+		fmt.Fprintln(os.Stderr, "  dump-types: list type codes (reflect metadata) in a binary")
+		fmt.Fprintln(os.Stderr, "  doctor:     check the tools needed to build and flash the target")
+		fmt.Fprintln(os.Stderr, "  version:    show version")
+		fmt.Fprintln(os.Stderr, "  help:       print this help text")
 
 		if flag.Parsed() {
 			fmt.Fprintln(os.Stderr, "\nflags:")
@@ -1311,6 +1577,25 @@ func handleCompilerError(err error) {
 	}
 }
 
+// newConfig is like builder.NewConfig, but also prints the warnings that were
+// found in the target specification.
+func newConfig(options *compileopts.Options) (*compileopts.Config, error) {
+	config, err := builder.NewConfig(options)
+	if err != nil {
+		return nil, err
+	}
+	printTargetWarnings(config.Target)
+	return config, nil
+}
+
+// printTargetWarnings prints the warnings found while loading a target
+// specification, like unknown properties in a target file.
+func printTargetWarnings(spec *compileopts.TargetSpec) {
+	for _, warning := range spec.Warnings {
+		fmt.Fprintln(os.Stderr, "warning:", warning)
+	}
+}
+
 // This is a special type for the -X flag to parse the pkgpath.Var=stringVal
 // format. It has to be a special type to allow multiple variables to be defined
 // this way.
@@ -1361,11 +1646,21 @@ func parseGoLinkFlag(flagsString string) (map[string]map[string]string, error) {
 // include wildards using `go list`.
 // For example [./...] => ["pkg1", "pkg1/pkg12", "pkg2"]
 func getListOfPackages(pkgs []string, options *compileopts.Options) ([]string, error) {
-	config, err := builder.NewConfig(options)
+	return listPackages(pkgs, nil, options)
+}
+
+// getListOfMainPackages is like getListOfPackages, but only returns the main
+// packages, which are the packages that can be built into a binary.
+func getListOfMainPackages(pkgs []string, options *compileopts.Options) ([]string, error) {
+	return listPackages(pkgs, []string{"-f", `{{if eq .Name "main"}}{{.ImportPath}}{{end}}`}, options)
+}
+
+func listPackages(pkgs, extraArgs []string, options *compileopts.Options) ([]string, error) {
+	config, err := newConfig(options)
 	if err != nil {
 		return nil, err
 	}
-	cmd, err := loader.List(config, nil, pkgs)
+	cmd, err := loader.List(config, extraArgs, pkgs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to run `go list`: %w", err)
 	}
@@ -1380,7 +1675,9 @@ func getListOfPackages(pkgs []string, options *compileopts.Options) ([]string, e
 	var pkgNames []string
 	sc := bufio.NewScanner(outputBuf)
 	for sc.Scan() {
-		pkgNames = append(pkgNames, sc.Text())
+		if sc.Text() != "" {
+			pkgNames = append(pkgNames, sc.Text())
+		}
 	}
 
 	return pkgNames, nil
@@ -1396,7 +1693,8 @@ func main() {
 
 	opt := flag.String("opt", "z", "optimization level: 0, 1, 2, s, z")
 	gc := flag.String("gc", "", "garbage collector to use (none, leaking, conservative)")
-	panicStrategy := flag.String("panic", "print", "panic strategy (print, trap)")
+	panicStrategy := flag.String("panic", "print", "panic strategy (print, trace, trap)")
+	race := flag.Bool("race", false, "enable data race detection (linux and darwin only)")
 	scheduler := flag.String("scheduler", "", "which scheduler to use (none, tasks, asyncify)")
 	serial := flag.String("serial", "", "which serial output to use (none, uart, usb)")
 	work := flag.Bool("work", false, "print the name of the temporary build directory and do not delete this directory on exit")
@@ -1404,27 +1702,55 @@ func main() {
 	var tags buildutil.TagsFlag
 	flag.Var(&tags, "tags", "a space-separated list of extra build tags")
 	target := flag.String("target", "", "chip/board name or JSON target specification file")
+	strictTarget := flag.Bool("strict-target", false, "treat unknown properties in target specification files as errors")
 	var stackSize uint64
 	flag.Func("stack-size", "goroutine stack size (if unknown at compile time)", func(s string) error {
 		size, err := bytesize.Parse(s)
 		stackSize = uint64(size)
 		return err
 	})
+	var codeOffset, ramOffset uint64
+	flag.Func("code-offset", "move the start of the code (FLASH_TEXT) memory region by this many bytes, for example to build for the second slot of a dual-bank layout", func(s string) (err error) {
+		codeOffset, err = parseOffset(s)
+		return
+	})
+	flag.Func("ram-offset", "move the start of the RAM memory region by this many bytes", func(s string) (err error) {
+		ramOffset, err = parseOffset(s)
+		return
+	})
+	extFlashString := flag.String("extflash", "", "comma separated list of packages whose constant data is placed in memory-mapped external flash")
 	printSize := flag.String("size", "", "print sizes (none, short, full)")
 	printStacks := flag.Bool("print-stacks", false, "print stack sizes of goroutines")
+	var warnStackFrame uint64
+	flag.Func("warn-stack-frame", "warn about functions whose local variables use more than this many bytes of stack", func(s string) error {
+		size, err := bytesize.Parse(s)
+		warnStackFrame = uint64(size)
+		return err
+	})
+	warnHeapAllocInLoop := flag.Bool("Wheap-alloc-in-loop", false, "warn about heap allocations inside loops")
+	heapAllocInLoopErrorsString := flag.String("Werror-heap-alloc-in-loop", "", "comma separated list of packages in which heap allocations inside loops are errors")
+	mapFile := flag.String("map", "", "write a linker map with symbol placement and symbol references to the given file")
 	printAllocsString := flag.String("print-allocs", "", "regular expression of functions for which heap allocations should be printed")
+	printFloat64String := flag.String("print-float64", "", "regular expression of functions for which float64 operations should be printed")
 	printCommands := flag.Bool("x", false, "Print commands")
 	parallelism := flag.Int("p", runtime.GOMAXPROCS(0), "the number of build jobs that can run in parallel")
 	nodebug := flag.Bool("no-debug", false, "strip debug information")
+	noTypeStrings := flag.Bool("no-type-strings", false, "strip reflect.Type.String() output of function and interface types to reduce binary size")
+	reflectIncludeString := flag.String("reflect-include", "", "regular expression of types (like main.Config) for which reflect metadata is always kept")
 	ocdCommandsString := flag.String("ocd-commands", "", "OpenOCD commands, overriding target spec (can specify multiple separated by commas)")
 	ocdOutput := flag.Bool("ocd-output", false, "print OCD daemon output during debug")
 	port := flag.String("port", "", "flash port (can specify multiple candidates separated by commas)")
-	timeout := flag.Duration("timeout", 20*time.Second, "the length of time to retry locating the MSD volume to be used for flashing")
+	timeout := flag.Duration("timeout", 20*time.Second, "the length of time to retry locating the MSD volume to be used for flashing, or the serial port to monitor")
 	programmer := flag.String("programmer", "", "which hardware programmer to use")
 	ldflags := flag.String("ldflags", "", "Go link tool compatible ldflags")
 	llvmFeatures := flag.String("llvm-features", "", "comma separated LLVM features to enable")
 	cpuprofile := flag.String("cpuprofile", "", "cpuprofile output")
 	monitor := flag.Bool("monitor", false, "enable serial monitor")
+	imageVersion := flag.Uint64("image-version", 0, "version number written into the firmware image header, for targets that define one")
+	mcubootKey := flag.String("mcuboot-key", "", "sign the firmware image for MCUboot with the given PEM private key")
+	mcubootVersion := flag.String("mcuboot-version", "", "MCUboot image version (major.minor.revision+build)")
+	espSecureBootKey := flag.String("esp-secure-boot-key", "", "sign ESP32 images for secure boot v2 with the given RSA-3072 PEM private key")
+	espFlashEncryption := flag.Bool("esp-flash-encryption", false, "encrypt ESP32 images while flashing (flash encryption must be enabled on the chip)")
 	baudrate := flag.Int("baudrate", 115200, "baudrate of serial monitor")
 
 	// Internal flags, that are only intended for TinyGo development.
@@ -1439,7 +1765,7 @@ func main() {
 	skipDwarf := flag.Bool("internal-nodwarf", false, "internal flag, use -no-debug instead")
 
 	var flagJSON, flagDeps, flagTest bool
-	if command == "help" || command == "list" || command == "info" || command == "build" {
+	if command == "help" || command == "list" || command == "info" || command == "build" || command == "dump-types" {
 		flag.BoolVar(&flagJSON, "json", false, "print data in JSON format")
 	}
 	if command == "help" || command == "list" {
@@ -1451,7 +1777,35 @@ func main() {
 		flag.StringVar(&outpath, "o", "", "output filename")
 	}
 
+	var runTimeout time.Duration
+	if command == "help" || command == "run" {
+		flag.DurationVar(&runTimeout, "run-timeout", 0, "terminate the program when it runs for longer than this (0 means no limit)")
+	}
+
+	var runEnv, runArgs, runDirs []string
+	if command == "help" || command == "run" || command == "test" {
+		flag.Func("env", "set the environment variable `NAME=VALUE` for the program, can be repeated", func(s string) error {
+			if !strings.Contains(s, "=") {
+				return fmt.Errorf("invalid environment variable %q: expected NAME=VALUE", s)
+			}
+			runEnv = append(runEnv, s)
+			return nil
+		})
+		flag.Func("arg", "pass an extra command line argument to the program, can be repeated", func(s string) error {
+			runArgs = append(runArgs, s)
+			return nil
+		})
+		flag.Func("dir", "make the host directory `DIR[::GUESTDIR]` available to WASI programs, can be repeated", func(s string) error {
+			if s == "" || strings.HasPrefix(s, "::") || strings.HasSuffix(s, "::") {
+				return fmt.Errorf("invalid directory %q: expected DIR or DIR::GUESTDIR", s)
+			}
+			runDirs = append(runDirs, s)
+			return nil
+		})
+	}
+
 	var testConfig compileopts.TestConfig
+	var flagCover bool
 	if command == "help" || command == "test" {
 		flag.BoolVar(&testConfig.CompileOnly, "c", false, "compile the test binary but do not run it")
 		flag.BoolVar(&testConfig.Verbose, "v", false, "verbose: print additional output")
@@ -1463,6 +1817,9 @@ func main() {
 		flag.StringVar(&testConfig.BenchTime, "benchtime", "", "run each benchmark for duration `d`")
 		flag.BoolVar(&testConfig.BenchMem, "benchmem", false, "show memory stats for benchmarks")
 		flag.StringVar(&testConfig.Shuffle, "shuffle", "", "shuffle the order the tests and benchmarks run")
+		flag.BoolVar(&flagCover, "cover", false, "enable coverage analysis of the tested package")
+		flag.StringVar(&testConfig.CoverMode, "covermode", "", "coverage mode: set, count (implies -cover)")
+		flag.StringVar(&testConfig.CoverProfile, "coverprofile", "", "write a coverage profile to `file` (implies -cover)")
 	}
 
 	// Early command processing, before commands are interpreted by the Go flag
@@ -1493,43 +1850,95 @@ func main() {
 		}
 	}
 
+	var reflectInclude *regexp.Regexp
+	if *reflectIncludeString != "" {
+		reflectInclude, err = regexp.Compile(*reflectIncludeString)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	var printFloat64 *regexp.Regexp
+	if *printFloat64String != "" {
+		printFloat64, err = regexp.Compile(*printFloat64String)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	if (flagCover || testConfig.CoverProfile != "") && testConfig.CoverMode == "" {
+		testConfig.CoverMode = "set"
+	}
+
 	var ocdCommands []string
 	if *ocdCommandsString != "" {
 		ocdCommands = strings.Split(*ocdCommandsString, ",")
 	}
 
+	var extFlashPackages []string
+	if *extFlashString != "" {
+		extFlashPackages = strings.Split(*extFlashString, ",")
+	}
+
+	var heapAllocInLoopErrors []string
+	if *heapAllocInLoopErrorsString != "" {
+		heapAllocInLoopErrors = strings.Split(*heapAllocInLoopErrorsString, ",")
+	}
+
 	options := &compileopts.Options{
-		GOOS:            goenv.Get("GOOS"),
-		GOARCH:          goenv.Get("GOARCH"),
-		GOARM:           goenv.Get("GOARM"),
-		Target:          *target,
-		StackSize:       stackSize,
-		Opt:             *opt,
-		GC:              *gc,
-		PanicStrategy:   *panicStrategy,
-		Scheduler:       *scheduler,
-		Serial:          *serial,
-		Work:            *work,
-		InterpTimeout:   *interpTimeout,
-		PrintIR:         *printIR,
-		DumpSSA:         *dumpSSA,
-		VerifyIR:        *verifyIR,
-		SkipDWARF:       *skipDwarf,
-		Semaphore:       make(chan struct{}, *parallelism),
-		Debug:           !*nodebug,
-		PrintSizes:      *printSize,
-		PrintStacks:     *printStacks,
-		PrintAllocs:     printAllocs,
-		Tags:            []string(tags),
-		TestConfig:      testConfig,
-		GlobalValues:    globalVarValues,
-		Programmer:      *programmer,
-		OpenOCDCommands: ocdCommands,
-		LLVMFeatures:    *llvmFeatures,
-		PrintJSON:       flagJSON,
-		Monitor:         *monitor,
-		BaudRate:        *baudrate,
-		Timeout:         *timeout,
+		GOOS:               goenv.Get("GOOS"),
+		GOARCH:             goenv.Get("GOARCH"),
+		GOARM:              goenv.Get("GOARM"),
+		Target:             *target,
+		StrictTarget:       *strictTarget,
+		StackSize:          stackSize,
+		Opt:                *opt,
+		GC:                 *gc,
+		PanicStrategy:      *panicStrategy,
+		Race:               *race,
+		Scheduler:          *scheduler,
+		Serial:             *serial,
+		Work:               *work,
+		InterpTimeout:      *interpTimeout,
+		PrintIR:            *printIR,
+		DumpSSA:            *dumpSSA,
+		VerifyIR:           *verifyIR,
+		SkipDWARF:          *skipDwarf,
+		NoTypeStrings:      *noTypeStrings,
+		Semaphore:          make(chan struct{}, *parallelism),
+		Debug:              !*nodebug,
+		PrintSizes:         *printSize,
+		MapFile:            *mapFile,
+		PrintStacks:        *printStacks,
+		PrintAllocs:        printAllocs,
+		PrintFloat64:       printFloat64,
+		ReflectInclude:     reflectInclude,
+		Tags:               []string(tags),
+		TestConfig:         testConfig,
+		GlobalValues:       globalVarValues,
+		Programmer:         *programmer,
+		OpenOCDCommands:    ocdCommands,
+		LLVMFeatures:       *llvmFeatures,
+		PrintJSON:          flagJSON,
+		Monitor:            *monitor,
+		BaudRate:           *baudrate,
+		Timeout:            *timeout,
+		ImageVersion:       *imageVersion,
+		MCUbootKey:         *mcubootKey,
+		MCUbootVersion:     *mcubootVersion,
+		ESPSecureBootKey:   *espSecureBootKey,
+		ESPFlashEncryption: *espFlashEncryption,
+		CodeOffset:         codeOffset,
+		RAMOffset:          ramOffset,
+		WarnStackFrame:     warnStackFrame,
+		WarnAllocInLoop:    *warnHeapAllocInLoop,
+		AllocInLoopErrors:  heapAllocInLoopErrors,
+		ExtFlashPackages:   extFlashPackages,
+		RunEnv:             runEnv,
+		RunArgs:            runArgs,
+		RunDirs:            runDirs,
 	}
 	if *printCommands {
 		options.PrintCommands = printCommand
@@ -1558,19 +1967,28 @@ func main() {
 
 	switch command {
 	case "build":
-		pkgName := "."
-		if flag.NArg() == 1 {
-			pkgName = filepath.ToSlash(flag.Arg(0))
-		} else if flag.NArg() > 1 {
-			fmt.Fprintln(os.Stderr, "build only accepts a single positional argument: package name, but multiple were specified")
-			usage(command)
-			os.Exit(1)
+		pkgNames := []string{"."}
+		if flag.NArg() != 0 {
+			pkgNames = nil
+			for _, arg := range flag.Args() {
+				pkgNames = append(pkgNames, filepath.ToSlash(arg))
+			}
 		}
 		if options.Target == "" && filepath.Ext(outpath) == ".wasm" {
 			options.Target = "wasm"
 		}
 
-		err := Build(pkgName, outpath, options)
+		if len(pkgNames) > 1 || strings.Contains(pkgNames[0], "...") {
+			// Build multiple packages, like `go build ./...`.
+			if outpath != "" && !isOutputDir(outpath) {
+				fmt.Fprintln(os.Stderr, "cannot write multiple packages to non-directory", outpath)
+				os.Exit(1)
+			}
+			err := BuildPackages(pkgNames, outpath, options)
+			handleCompilerError(err)
+			break
+		}
+		err := Build(pkgNames[0], outpath, options)
 		handleCompilerError(err)
 	case "build-library":
 		// Note: this command is only meant to be used while making a release!
@@ -1606,6 +2024,7 @@ func main() {
 		if err != nil {
 			handleCompilerError(err)
 		}
+		printTargetWarnings(spec)
 		config := &compileopts.Config{
 			Options: options,
 			Target:  spec,
@@ -1637,7 +2056,13 @@ func main() {
 			os.Exit(1)
 		}
 		pkgName := filepath.ToSlash(flag.Arg(0))
-		err := Run(pkgName, options, flag.Args()[1:])
+		err := Run(pkgName, options, runTimeout, flag.Args()[1:])
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// Exit like the program did, without printing an error, so that
+			// scripts can check the exit code as if they ran it directly.
+			os.Exit(exitCode(exitErr))
+		}
 		handleCompilerError(err)
 	case "test":
 		var pkgNames []string
@@ -1659,6 +2084,15 @@ func main() {
 			os.Exit(1)
 		}
 
+		if testConfig.CoverProfile != "" {
+			// The profiles of all tested packages are appended to this file.
+			err := os.WriteFile(testConfig.CoverProfile, nil, 0666)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "cannot create coverage profile:", err)
+				os.Exit(1)
+			}
+		}
+
 		fail := make(chan struct{}, 1)
 		var wg sync.WaitGroup
 		bufs := make([]testOutputBuf, len(explicitPkgNames))
@@ -1722,6 +2156,35 @@ func main() {
 	case "monitor":
 		err := Monitor("", *port, options)
 		handleCompilerError(err)
+	case "doctor":
+		err := Doctor(*port, options)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+	case "dump-types":
+		if flag.NArg() != 1 {
+			fmt.Fprintln(os.Stderr, "dump-types expects exactly one binary file")
+			usage(command)
+			os.Exit(1)
+		}
+		typecodes, err := builder.LoadTypeCodes(flag.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+		if flagJSON {
+			json, _ := json.MarshalIndent(typecodes, "", "  ")
+			fmt.Println(string(json))
+			break
+		}
+		var total uint64
+		fmt.Printf("   size  type\n")
+		for _, t := range typecodes {
+			fmt.Printf("%7d  %s\n", t.Size, t.Name)
+			total += t.Size
+		}
+		fmt.Printf("%7d  total (%d type codes)\n", total, len(typecodes))
 	case "targets":
 		dir := filepath.Join(goenv.Get("TINYGOROOT"), "targets")
 		entries, err := ioutil.ReadDir(dir)
@@ -1731,8 +2194,8 @@ func main() {
 			return
 		}
 		for _, entry := range entries {
-			if !entry.Mode().IsRegular() || !strings.HasSuffix(entry.Name(), ".json") {
-				// Only inspect JSON files.
+			if !entry.Mode().IsRegular() || !strings.HasSuffix(entry.Name(), ".json") || strings.HasSuffix(entry.Name(), ".schema.json") {
+				// Only inspect JSON files (but not the JSON schema).
 				continue
 			}
 			path := filepath.Join(dir, entry.Name())
@@ -1751,6 +2214,20 @@ func main() {
 			name = name[:len(name)-5]
 			fmt.Println(name)
 		}
+	case "target":
+		if flag.NArg() != 2 || flag.Arg(0) != "explain" {
+			fmt.Fprintln(os.Stderr, "usage: tinygo target explain <name>")
+			os.Exit(1)
+		}
+		options.Target = flag.Arg(1)
+		spec, err := compileopts.LoadTarget(options)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+		printTargetWarnings(spec)
+		data, _ := json.MarshalIndent(spec, "", "\t")
+		fmt.Println(string(data))
 	case "info":
 		if flag.NArg() == 1 {
 			options.Target = flag.Arg(0)
@@ -1759,7 +2236,7 @@ func main() {
 			usage(command)
 			os.Exit(1)
 		}
-		config, err := builder.NewConfig(options)
+		config, err := newConfig(options)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, err)
 			usage(command)
@@ -1806,7 +2283,7 @@ func main() {
 			fmt.Printf("cached GOROOT:     %s\n", cachedGOROOT)
 		}
 	case "list":
-		config, err := builder.NewConfig(options)
+		config, err := newConfig(options)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, err)
 			usage(command)
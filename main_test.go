@@ -6,12 +6,14 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"reflect"
 	"regexp"
 	"runtime"
@@ -49,6 +51,7 @@ func TestBuild(t *testing.T) {
 		"cgo/",
 		"channel.go",
 		"embed/",
+		"fixed.go",
 		"float.go",
 		"gc.go",
 		"generics.go",
@@ -61,6 +64,7 @@ func TestBuild(t *testing.T) {
 		"math.go",
 		"print.go",
 		"reflect.go",
+		"registry.go",
 		"slice.go",
 		"sort.go",
 		"stdlib.go",
@@ -115,6 +119,50 @@ func TestBuild(t *testing.T) {
 			}
 			runTestWithConfig("ldflags.go", t, opts, nil, nil)
 		})
+
+		if runtime.GOOS == "linux" || runtime.GOOS == "darwin" {
+			// The race detector must not report synchronized accesses.
+			t.Run("race", func(t *testing.T) {
+				t.Parallel()
+				opts := optionsFromTarget("", sema)
+				opts.Race = true
+				runTestWithConfig("race.go", t, opts, nil, nil)
+			})
+
+			// A data race must be reported, and the program must exit with
+			// status 66 like with the gc toolchain.
+			t.Run("race-detected", func(t *testing.T) {
+				t.Parallel()
+				opts := optionsFromTarget("", sema)
+				opts.Race = true
+				config, err := builder.NewConfig(&opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var output bytes.Buffer
+				exitCode := 0
+				_, err = buildAndRun("./testdata/racy.go", config, &output, nil, nil, time.Minute, func(cmd *exec.Cmd, result builder.BuildResult) error {
+					err := cmd.Run()
+					if err, ok := err.(*exec.ExitError); ok {
+						exitCode = err.ExitCode()
+						return nil
+					}
+					return err
+				})
+				if err != nil {
+					printCompilerError(t.Log, err)
+					t.FailNow()
+				}
+				if exitCode != 66 {
+					t.Errorf("expected exit code 66, got %d", exitCode)
+				}
+				for _, s := range []string{"WARNING: DATA RACE\n", "racy.go:12\n", "racy.go:15\n", "x: 1\n", "Found 1 data race\n"} {
+					if !strings.Contains(output.String(), s) {
+						t.Errorf("missing %q in output:\n%s", s, output.String())
+					}
+				}
+			})
+		}
 	})
 
 	if testing.Short() {
@@ -505,6 +553,42 @@ func TestTest(t *testing.T) {
 					t.Error("test passed")
 				}
 			})
+
+			t.Run("Cover", func(t *testing.T) {
+				t.Parallel()
+
+				// Test a package with code coverage, and compare the profile
+				// with the one written by go test. It includes blocks that
+				// don't have any code, like empty if bodies and cases.
+
+				var wg sync.WaitGroup
+				defer wg.Wait()
+
+				out := ioLogger(t, &wg)
+				defer out.Close()
+
+				opts := targ.opts
+				opts.TestConfig.CoverMode = "count"
+				opts.TestConfig.CoverProfile = filepath.Join(t.TempDir(), "profile.txt")
+				passed, err := Test("github.com/tinygo-org/tinygo/tests/testing/cover", out, out, &opts, "")
+				if err != nil {
+					t.Errorf("test error: %v", err)
+				}
+				if !passed {
+					t.Error("test failed")
+				}
+				profile, err := os.ReadFile(opts.TestConfig.CoverProfile)
+				if err != nil {
+					t.Fatal("could not read coverage profile:", err)
+				}
+				expected, err := os.ReadFile("tests/testing/cover/profile.txt")
+				if err != nil {
+					t.Fatal("could not read expected profile:", err)
+				}
+				if !bytes.Equal(profile, expected) {
+					t.Errorf("unexpected coverage profile:\n%s\nexpected:\n%s", profile, expected)
+				}
+			})
 		})
 	}
 }
@@ -525,6 +609,17 @@ func ioLogger(t *testing.T, wg *sync.WaitGroup) io.WriteCloser {
 	return w
 }
 
+// Test that tinygo run -run-timeout terminates a program that runs for too
+// long.
+func TestRunTimeout(t *testing.T) {
+	t.Parallel()
+	opts := optionsFromTarget("", sema)
+	err := Run("./testdata/sleep.go", &opts, time.Second, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a timeout, got: %v", err)
+	}
+}
+
 func TestGetListOfPackages(t *testing.T) {
 	opts := optionsFromTarget("", sema)
 	tests := []struct {
@@ -565,6 +660,18 @@ func TestGetListOfPackages(t *testing.T) {
 	}
 }
 
+func TestGetListOfMainPackages(t *testing.T) {
+	opts := optionsFromTarget("", sema)
+	pkgs, err := getListOfMainPackages([]string{"./tests/tinygotest", "./tests/testing/recurse/..."}, &opts)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	expectedPkgs := []string{"github.com/tinygo-org/tinygo/tests/tinygotest"}
+	if !reflect.DeepEqual(expectedPkgs, pkgs) {
+		t.Errorf("expected %v, got %v", expectedPkgs, pkgs)
+	}
+}
+
 // This TestMain is necessary because TinyGo may also be invoked to run certain
 // LLVM tools in a separate process. Not capturing these invocations would lead
 // to recursive tests.
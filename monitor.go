@@ -13,6 +13,7 @@ import (
 	"os/signal"
 	"regexp"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/mattn/go-tty"
@@ -21,45 +22,34 @@ import (
 	"go.bug.st/serial"
 )
 
-// Monitor connects to the given port and reads/writes the serial port.
+// Monitor connects to the given port and reads/writes the serial port. When the
+// connection is lost, for example because the board was reset and its USB CDC
+// port disappeared, it waits for the port to come back and reconnects.
 func Monitor(executable, port string, options *compileopts.Options) error {
 	config, err := builder.NewConfig(options)
 	if err != nil {
 		return err
 	}
 
-	wait := 300
-	for i := 0; i <= wait; i++ {
-		port, err = getDefaultPort(port, config.Target.SerialPort)
-		if err != nil {
-			if i < wait {
-				time.Sleep(10 * time.Millisecond)
-				continue
-			}
-			return err
-		}
-		break
-	}
-
 	br := options.BaudRate
 	if br <= 0 {
 		br = 115200
 	}
 
-	wait = 300
-	var p serial.Port
-	for i := 0; i <= wait; i++ {
-		p, err = serial.Open(port, &serial.Mode{BaudRate: br})
-		if err != nil {
-			if i < wait {
-				time.Sleep(10 * time.Millisecond)
-				continue
-			}
-			return err
-		}
-		break
+	timeout := options.Timeout
+	if timeout <= 0 {
+		timeout = 3 * time.Second
+	}
+
+	conn := &monitorConn{
+		port:          port,
+		usbInterfaces: config.Target.SerialPort,
+		mode:          &serial.Mode{BaudRate: br},
 	}
-	defer p.Close()
+	if err := conn.open(timeout); err != nil {
+		return err
+	}
+	defer conn.close()
 
 	tty, err := tty.Open()
 	if err != nil {
@@ -77,7 +67,7 @@ func Monitor(executable, port string, options *compileopts.Options) error {
 		os.Exit(0)
 	}()
 
-	fmt.Printf("Connected to %s. Press Ctrl-C to exit.\n", port)
+	fmt.Printf("Connected to %s. Press Ctrl-C to exit.\n", conn.name)
 
 	errCh := make(chan error, 1)
 
@@ -85,10 +75,18 @@ func Monitor(executable, port string, options *compileopts.Options) error {
 		buf := make([]byte, 100*1024)
 		var line []byte
 		for {
-			n, err := p.Read(buf)
+			n, err := conn.read(buf)
 			if err != nil {
-				errCh <- fmt.Errorf("read error: %w", err)
-				return
+				// The port may disappear while the board resets (for
+				// example right after flashing), so try to reconnect
+				// before giving up.
+				fmt.Printf("\n[tinygo: lost connection to %s, waiting for it to come back]\n", conn.name)
+				if err := conn.open(timeout); err != nil {
+					errCh <- fmt.Errorf("read error: %w", err)
+					return
+				}
+				fmt.Printf("[tinygo: reconnected to %s]\n", conn.name)
+				continue
 			}
 			start := 0
 			for i, c := range buf[:n] {
@@ -121,13 +119,89 @@ func Monitor(executable, port string, options *compileopts.Options) error {
 			if r == 0 {
 				continue
 			}
-			p.Write([]byte(string(r)))
+			conn.write([]byte(string(r)))
 		}
 	}()
 
 	return <-errCh
 }
 
+// monitorConn is a serial connection that can be reopened when the port
+// disappears and comes back, which happens with USB CDC ports when the board
+// is reset.
+type monitorConn struct {
+	port          string   // -port flag (may be empty)
+	usbInterfaces []string // USB VID/PID pairs of the target
+	mode          *serial.Mode
+
+	lock sync.Mutex
+	p    serial.Port
+	name string // name of the currently opened port
+}
+
+// open (re)opens the serial port, retrying until the timeout expires. This
+// also covers the case where the port was found but vanishes right after,
+// because the board was still re-enumerating.
+func (c *monitorConn) open(timeout time.Duration) error {
+	c.close()
+
+	var err error
+	for start := time.Now(); ; {
+		var name string
+		var p serial.Port
+		name, err = getDefaultPort(c.port, c.usbInterfaces)
+		if err == nil {
+			p, err = serial.Open(name, c.mode)
+		}
+		if err == nil {
+			c.lock.Lock()
+			c.p = p
+			c.name = name
+			c.lock.Unlock()
+			return nil
+		}
+		if time.Since(start) >= timeout {
+			return err
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (c *monitorConn) read(buf []byte) (int, error) {
+	c.lock.Lock()
+	p := c.p
+	c.lock.Unlock()
+	if p == nil {
+		return 0, errors.New("port closed")
+	}
+	n, err := p.Read(buf)
+	if err == nil && n == 0 {
+		// Some platforms return zero bytes instead of an error when the
+		// device was removed.
+		if _, statusErr := p.GetModemStatusBits(); statusErr != nil {
+			err = statusErr
+		}
+	}
+	return n, err
+}
+
+func (c *monitorConn) write(buf []byte) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.p != nil {
+		c.p.Write(buf)
+	}
+}
+
+func (c *monitorConn) close() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.p != nil {
+		c.p.Close()
+		c.p = nil
+	}
+}
+
 var addressMatch = regexp.MustCompile(`^panic: runtime error at 0x([0-9a-f]+): `)
 
 // Extract the address from the "panic: runtime error at" message.
@@ -0,0 +1,313 @@
+// Package atecc608 is a driver for the Microchip ATECC608 secure element (and
+// the older ATECC508), connected over I2C. It implements the
+// hardware.SecureElement interface.
+//
+// The chip must have been configured and its configuration and data zones
+// locked (for example with the Microchip provisioning tools) before it can be
+// used: key slots must be configured to hold P-256 private keys, and the random
+// number generator only returns a fixed test pattern while the configuration
+// zone is unlocked.
+package atecc608
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hardware"
+	"errors"
+	"math/big"
+	"time"
+)
+
+var (
+	ErrWake       = errors.New("atecc608: chip didn't wake up")
+	ErrTimeout    = errors.New("atecc608: timeout waiting for the chip")
+	ErrCRC        = errors.New("atecc608: CRC mismatch in response")
+	ErrResponse   = errors.New("atecc608: unexpected response length")
+	ErrParse      = errors.New("atecc608: command parse error")
+	ErrExecution  = errors.New("atecc608: command execution error")
+	ErrCommandCRC = errors.New("atecc608: chip received command with bad CRC")
+)
+
+// I2C is the I2C bus the chip is connected to. It is implemented by
+// machine.I2C.
+type I2C interface {
+	Tx(addr uint16, w, r []byte) error
+}
+
+// DefaultAddress is the 7-bit I2C address of the chip as shipped by Microchip.
+const DefaultAddress = 0x60
+
+// Word addresses: the first byte of every write to the chip.
+const (
+	wordSleep   = 0x01
+	wordIdle    = 0x02
+	wordCommand = 0x03
+)
+
+// Command opcodes.
+const (
+	opNonce  = 0x16
+	opRandom = 0x1b
+	opGenKey = 0x40
+	opSign   = 0x41
+)
+
+// Status codes returned in a 4 byte response.
+const (
+	statusSuccess   = 0x00
+	statusParse     = 0x03
+	statusExecution = 0x0f
+	statusAfterWake = 0x11
+	statusCRC       = 0xff
+)
+
+// numSlots is the number of data slots. Only slots 0-15 can hold a private
+// key.
+const numSlots = 16
+
+// Device is an ATECC608 connected over I2C.
+type Device struct {
+	bus     I2C
+	address uint16
+}
+
+// Config is the configuration of the Device.
+type Config struct {
+	// Address is the 7-bit I2C address. It defaults to DefaultAddress.
+	Address uint16
+}
+
+// New returns a new ATECC608 driver for the chip on the given bus. The bus
+// must be configured to run at 100kHz or lower, as waking up the chip relies
+// on the SDA line being held low long enough while sending the address 0.
+func New(bus I2C) *Device {
+	return &Device{
+		bus:     bus,
+		address: DefaultAddress,
+	}
+}
+
+// Configure sets the configuration and checks that the chip is present by
+// waking it up.
+func (d *Device) Configure(config Config) error {
+	if config.Address != 0 {
+		d.address = config.Address
+	}
+	if err := d.wake(); err != nil {
+		return err
+	}
+	return d.idle()
+}
+
+// GenerateKey generates a new private key in the given slot and returns its
+// public key.
+func (d *Device) GenerateKey(slot int) (*ecdsa.PublicKey, error) {
+	if slot < 0 || slot >= numSlots {
+		return nil, hardware.ErrInvalidSlot
+	}
+	var resp [64]byte
+	if err := d.execute(opGenKey, 0x04, uint16(slot), nil, resp[:], 215*time.Millisecond); err != nil {
+		return nil, err
+	}
+	return publicKey(resp[:])
+}
+
+// PublicKey returns the public key of the private key in the given slot.
+func (d *Device) PublicKey(slot int) (*ecdsa.PublicKey, error) {
+	if slot < 0 || slot >= numSlots {
+		return nil, hardware.ErrInvalidSlot
+	}
+	var resp [64]byte
+	if err := d.execute(opGenKey, 0x00, uint16(slot), nil, resp[:], 215*time.Millisecond); err != nil {
+		return nil, err
+	}
+	return publicKey(resp[:])
+}
+
+// SignDigest signs a SHA-256 digest with the private key in the given slot.
+func (d *Device) SignDigest(slot int, digest []byte) (r, s *big.Int, err error) {
+	if slot < 0 || slot >= numSlots {
+		return nil, nil, hardware.ErrInvalidSlot
+	}
+	if len(digest) != 32 {
+		return nil, nil, hardware.ErrDigestSize
+	}
+	// The digest is first loaded into the TempKey register with a Nonce
+	// command in pass-through mode, then signed as an external message. Both
+	// commands must be sent in the same wake period, as TempKey is cleared
+	// when the chip goes to sleep.
+	if err := d.wake(); err != nil {
+		return nil, nil, err
+	}
+	defer d.idle()
+	if err := d.command(opNonce, 0x03, 0, digest, nil, 7*time.Millisecond); err != nil {
+		return nil, nil, err
+	}
+	var resp [64]byte
+	if err := d.command(opSign, 0x80, uint16(slot), nil, resp[:], 115*time.Millisecond); err != nil {
+		return nil, nil, err
+	}
+	r = new(big.Int).SetBytes(resp[:32])
+	s = new(big.Int).SetBytes(resp[32:])
+	return r, s, nil
+}
+
+// Read fills p with random bytes from the random number generator of the
+// chip.
+func (d *Device) Read(p []byte) (n int, err error) {
+	for n < len(p) {
+		var resp [32]byte
+		if err := d.execute(opRandom, 0x00, 0, nil, resp[:], 23*time.Millisecond); err != nil {
+			return n, err
+		}
+		n += copy(p[n:], resp[:])
+	}
+	return n, nil
+}
+
+// publicKey converts the X and Y coordinates returned by the chip to a public
+// key.
+func publicKey(xy []byte) (*ecdsa.PublicKey, error) {
+	x := new(big.Int).SetBytes(xy[:32])
+	y := new(big.Int).SetBytes(xy[32:])
+	curve := elliptic.P256()
+	if !curve.IsOnCurve(x, y) {
+		return nil, ErrResponse
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// execute wakes up the chip, runs a single command and puts the chip back in
+// idle mode.
+func (d *Device) execute(opcode, param1 byte, param2 uint16, data, resp []byte, maxTime time.Duration) error {
+	if err := d.wake(); err != nil {
+		return err
+	}
+	defer d.idle()
+	return d.command(opcode, param1, param2, data, resp, maxTime)
+}
+
+// command sends a command packet and reads the response into resp. Commands
+// that only return a status must be called with an empty resp.
+func (d *Device) command(opcode, param1 byte, param2 uint16, data, resp []byte, maxTime time.Duration) error {
+	// The packet consists of the word address, the count (which includes
+	// itself and the CRC), the opcode, both parameters, the data and the CRC.
+	packet := make([]byte, 0, 8+len(data))
+	packet = append(packet, wordCommand, byte(7+len(data)), opcode, param1, byte(param2), byte(param2>>8))
+	packet = append(packet, data...)
+	crc := crc16(packet[1:])
+	packet = append(packet, byte(crc), byte(crc>>8))
+	if err := d.bus.Tx(d.address, packet, nil); err != nil {
+		return err
+	}
+
+	// The chip doesn't acknowledge its address while it's executing the
+	// command, so poll until it responds.
+	var count [1]byte
+	for start := time.Now(); ; {
+		time.Sleep(time.Millisecond)
+		if err := d.bus.Tx(d.address, nil, count[:]); err == nil {
+			break
+		}
+		if time.Since(start) > maxTime {
+			return ErrTimeout
+		}
+	}
+
+	// Read the rest of the response. A response of 4 bytes contains only a
+	// status byte, which is also used to report errors.
+	n := int(count[0])
+	if n != 4 && n != 3+len(resp) {
+		return ErrResponse
+	}
+	buf := make([]byte, n)
+	buf[0] = count[0]
+	if err := d.bus.Tx(d.address, nil, buf[1:]); err != nil {
+		return err
+	}
+	crc = crc16(buf[:n-2])
+	if buf[n-2] != byte(crc) || buf[n-1] != byte(crc>>8) {
+		return ErrCRC
+	}
+	if n == 4 && len(resp) != 1 {
+		if err := statusError(buf[1]); err != nil {
+			return err
+		}
+		if len(resp) != 0 {
+			return ErrResponse
+		}
+		return nil
+	}
+	copy(resp, buf[1:n-2])
+	return nil
+}
+
+// statusError converts a status byte to an error.
+func statusError(status byte) error {
+	switch status {
+	case statusSuccess:
+		return nil
+	case statusParse:
+		return ErrParse
+	case statusExecution:
+		return ErrExecution
+	case statusCRC:
+		return ErrCommandCRC
+	default:
+		return errors.New("atecc608: command failed with status 0x" + hex(status))
+	}
+}
+
+// wake wakes the chip up from sleep or idle mode. Sending the address 0 keeps
+// the SDA line low long enough to wake it up; the chip won't acknowledge it so
+// the error is ignored.
+func (d *Device) wake() error {
+	d.bus.Tx(0, []byte{0}, nil)
+	time.Sleep(1500 * time.Microsecond)
+
+	var resp [4]byte
+	if err := d.bus.Tx(d.address, nil, resp[:]); err != nil {
+		return ErrWake
+	}
+	if resp != [4]byte{0x04, statusAfterWake, 0x33, 0x43} {
+		return ErrWake
+	}
+	return nil
+}
+
+// idle puts the chip in idle mode, which keeps the watchdog timer from
+// putting it to sleep while still saving power.
+func (d *Device) idle() error {
+	return d.bus.Tx(d.address, []byte{wordIdle}, nil)
+}
+
+// Sleep puts the chip in its low power sleep mode. It is woken up
+// automatically by the next command.
+func (d *Device) Sleep() error {
+	if err := d.wake(); err != nil {
+		return err
+	}
+	return d.bus.Tx(d.address, []byte{wordSleep}, nil)
+}
+
+// crc16 calculates the CRC used in command and response packets: CRC-16 with
+// polynomial 0x8005, processing the least significant bit of each byte first.
+func crc16(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		for bit := byte(1); bit != 0; bit <<= 1 {
+			dataBit := b&bit != 0
+			crcBit := crc&0x8000 != 0
+			crc <<= 1
+			if dataBit != crcBit {
+				crc ^= 0x8005
+			}
+		}
+	}
+	return crc
+}
+
+func hex(b byte) string {
+	const digits = "0123456789abcdef"
+	return string([]byte{digits[b>>4], digits[b&0xf]})
+}
@@ -0,0 +1,157 @@
+package atecc608
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// fakeBus records the packets written to the chip and returns the bytes of the
+// queued responses. Like the real chip, it doesn't acknowledge the address 0
+// used to wake it up, and reads fail while no response is queued.
+type fakeBus struct {
+	written [][]byte
+	resp    []byte
+}
+
+var errNoAck = errors.New("no acknowledge")
+
+// wakeResponse is the status packet returned after the chip wakes up.
+var wakeResponse = []byte{0x04, statusAfterWake, 0x33, 0x43}
+
+func (b *fakeBus) Tx(addr uint16, w, r []byte) error {
+	if addr != DefaultAddress {
+		return errNoAck
+	}
+	if len(w) != 0 {
+		b.written = append(b.written, append([]byte(nil), w...))
+	}
+	if len(r) != 0 {
+		if len(b.resp) < len(r) {
+			return errNoAck
+		}
+		copy(r, b.resp)
+		b.resp = b.resp[len(r):]
+	}
+	return nil
+}
+
+// response returns a response packet with the given data.
+func response(data ...byte) []byte {
+	packet := append([]byte{byte(3 + len(data))}, data...)
+	crc := crc16(packet)
+	return append(packet, byte(crc), byte(crc>>8))
+}
+
+func TestCRC16(t *testing.T) {
+	// Status packet sent after wake-up.
+	if crc := crc16(wakeResponse[:2]); crc != 0x4333 {
+		t.Errorf("crc16 of wake response = %#04x, want 0x4333", crc)
+	}
+	// Random command in its default mode.
+	if crc := crc16([]byte{0x07, opRandom, 0x00, 0x00, 0x00}); crc != 0xcd24 {
+		t.Errorf("crc16 of Random command = %#04x, want 0xcd24", crc)
+	}
+}
+
+func TestRead(t *testing.T) {
+	bus := &fakeBus{}
+	d := New(bus)
+
+	// Every Random command returns 32 bytes, so reading 40 bytes takes two
+	// commands, each in its own wake period.
+	random := make([]byte, 64)
+	for i := range random {
+		random[i] = byte(i)
+	}
+	bus.resp = append(bus.resp, wakeResponse...)
+	bus.resp = append(bus.resp, response(random[:32]...)...)
+	bus.resp = append(bus.resp, wakeResponse...)
+	bus.resp = append(bus.resp, response(random[32:]...)...)
+	buf := make([]byte, 40)
+	n, err := d.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(buf) || !bytes.Equal(buf, random[:40]) {
+		t.Errorf("Read returned %d bytes: % x", n, buf)
+	}
+
+	command := []byte{wordCommand, 0x07, opRandom, 0x00, 0x00, 0x00, 0x24, 0xcd}
+	want := [][]byte{command, {wordIdle}, command, {wordIdle}}
+	if len(bus.written) != len(want) {
+		t.Fatalf("wrote %d packets, want %d: % x", len(bus.written), len(want), bus.written)
+	}
+	for i := range want {
+		if !bytes.Equal(bus.written[i], want[i]) {
+			t.Errorf("packet %d: wrote % x, want % x", i, bus.written[i], want[i])
+		}
+	}
+}
+
+func TestSignDigest(t *testing.T) {
+	bus := &fakeBus{}
+	d := New(bus)
+
+	// The Nonce and Sign commands are sent in the same wake period.
+	digest := bytes.Repeat([]byte{0xaa}, 32)
+	sig := make([]byte, 64)
+	sig[31] = 1
+	sig[63] = 2
+	bus.resp = append(bus.resp, wakeResponse...)
+	bus.resp = append(bus.resp, response(statusSuccess)...)
+	bus.resp = append(bus.resp, response(sig...)...)
+	r, s, err := d.SignDigest(3, digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Int64() != 1 || s.Int64() != 2 {
+		t.Errorf("SignDigest returned r=%v s=%v", r, s)
+	}
+	if len(bus.written) != 3 {
+		t.Fatalf("wrote %d packets, want 3: % x", len(bus.written), bus.written)
+	}
+	if nonce := bus.written[0]; nonce[2] != opNonce || !bytes.Equal(nonce[6:38], digest) {
+		t.Errorf("unexpected Nonce command: % x", nonce)
+	}
+	if sign := bus.written[1]; sign[2] != opSign || sign[3] != 0x80 || sign[4] != 3 {
+		t.Errorf("unexpected Sign command: % x", sign)
+	}
+	if !bytes.Equal(bus.written[2], []byte{wordIdle}) {
+		t.Errorf("chip wasn't put in idle mode: % x", bus.written[2])
+	}
+}
+
+func TestCommandErrors(t *testing.T) {
+	bus := &fakeBus{}
+	d := New(bus)
+
+	// Errors are reported in a status packet.
+	bus.resp = response(statusExecution)
+	if err := d.command(opRandom, 0, 0, nil, make([]byte, 32), 0); err != ErrExecution {
+		t.Errorf("expected ErrExecution, got %v", err)
+	}
+	bus.resp = response(0x07)
+	if err := d.command(opRandom, 0, 0, nil, make([]byte, 32), 0); err == nil || err.Error() != "atecc608: command failed with status 0x07" {
+		t.Errorf("expected error for unknown status, got %v", err)
+	}
+
+	// A corrupted response.
+	bus.resp = response(make([]byte, 32)...)
+	bus.resp[len(bus.resp)-1] ^= 0xff
+	if err := d.command(opRandom, 0, 0, nil, make([]byte, 32), 0); err != ErrCRC {
+		t.Errorf("expected ErrCRC, got %v", err)
+	}
+
+	// A response of the wrong length.
+	bus.resp = response(make([]byte, 16)...)
+	if err := d.command(opRandom, 0, 0, nil, make([]byte, 32), 0); err != ErrResponse {
+		t.Errorf("expected ErrResponse, got %v", err)
+	}
+
+	// The chip didn't wake up.
+	bus.resp = nil
+	if _, err := d.Read(make([]byte, 1)); err != ErrWake {
+		t.Errorf("expected ErrWake, got %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+// Package hardware provides access to private keys that are stored in a secure
+// element: a separate chip that generates and stores keys and signs with them,
+// without ever revealing the private key. This way device identity keys never
+// live in (readable) flash memory.
+//
+// Drivers for specific chips are in subpackages, such as
+// crypto/hardware/atecc608 and crypto/hardware/se050. A key in a secure element
+// is used through a Key, which implements crypto.Signer so that it can be used
+// with packages like crypto/x509 and crypto/tls:
+//
+//	se := atecc608.New(machine.I2C0)
+//	err := se.Configure(atecc608.Config{})
+//	key, err := hardware.NewKey(se, 0)
+//	sig, err := key.Sign(nil, digest, crypto.SHA256)
+//
+// Only ECDSA with the NIST P-256 curve is supported, as that's what these chips
+// have in common.
+//
+// A Key can be used as the client certificate key for mutual TLS, as required
+// by IoT cloud platforms like AWS IoT and Azure IoT Hub. The certificate itself
+// is not secret, so it can be stored in flash:
+//
+//	config := &tls.Config{
+//		Certificates: []tls.Certificate{{
+//			Certificate: [][]byte{certDER},
+//			PrivateKey:  key,
+//		}},
+//	}
+//	conn, err := tls.Dial("tcp", "example.iot.us-east-1.amazonaws.com:8883", config)
+package hardware
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"encoding/asn1"
+	"errors"
+	"io"
+	"math/big"
+)
+
+var (
+	ErrInvalidSlot = errors.New("hardware: invalid key slot")
+	ErrDigestSize  = errors.New("hardware: digest must be 32 bytes (SHA-256)")
+	ErrHashSize    = errors.New("hardware: digest doesn't match hash function")
+)
+
+// SecureElement is a chip that stores P-256 private keys in numbered slots and
+// signs with them. Which slots are available and whether they can hold a
+// private key depends on the chip and how it's configured.
+type SecureElement interface {
+	// GenerateKey generates a new private key in the given slot, replacing
+	// the key that was stored there, and returns its public key.
+	GenerateKey(slot int) (*ecdsa.PublicKey, error)
+
+	// PublicKey returns the public key of the private key in the given slot.
+	PublicKey(slot int) (*ecdsa.PublicKey, error)
+
+	// SignDigest signs a SHA-256 digest with the private key in the given
+	// slot.
+	SignDigest(slot int, digest []byte) (r, s *big.Int, err error)
+
+	// Read fills p with random bytes from the random number generator of the
+	// chip. This makes a SecureElement usable as an io.Reader for functions
+	// that need a source of randomness.
+	Read(p []byte) (n int, err error)
+}
+
+// Key is a private key stored in a slot of a secure element. It implements
+// crypto.Signer.
+type Key struct {
+	element SecureElement
+	slot    int
+	public  *ecdsa.PublicKey
+}
+
+// NewKey returns the key that is stored in the given slot of the secure
+// element.
+func NewKey(element SecureElement, slot int) (*Key, error) {
+	public, err := element.PublicKey(slot)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{element: element, slot: slot, public: public}, nil
+}
+
+// GenerateKey generates a new key in the given slot of the secure element,
+// replacing the key that was stored there.
+func GenerateKey(element SecureElement, slot int) (*Key, error) {
+	public, err := element.GenerateKey(slot)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{element: element, slot: slot, public: public}, nil
+}
+
+// Slot returns the slot of the secure element where the key is stored.
+func (k *Key) Slot() int {
+	return k.slot
+}
+
+// Public returns the public key, as an *ecdsa.PublicKey.
+func (k *Key) Public() crypto.PublicKey {
+	return k.public
+}
+
+// Sign signs a digest with the key. The signature is ASN.1 encoded, like the
+// signatures returned by ecdsa.PrivateKey.Sign. The rand argument is ignored:
+// the secure element uses its own random number generator.
+//
+// The digest may be of any hash function, not just SHA-256, so that the key can
+// be used with every ECDSA signature scheme that a TLS 1.2 server may ask for.
+// ECDSA only uses the leftmost 256 bits of a digest for a P-256 key, so longer
+// digests are truncated and shorter ones padded before they're passed to the
+// secure element.
+func (k *Key) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if opts != nil && opts.HashFunc() != 0 && opts.HashFunc().Size() != len(digest) {
+		return nil, ErrHashSize
+	}
+	var buf [32]byte
+	if len(digest) >= len(buf) {
+		copy(buf[:], digest)
+	} else {
+		copy(buf[len(buf)-len(digest):], digest)
+	}
+	r, s, err := k.element.SignDigest(k.slot, buf[:])
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(struct {
+		R, S *big.Int
+	}{r, s})
+}
@@ -0,0 +1,360 @@
+// Package se050 is a driver for the NXP EdgeLock SE050 secure element,
+// connected over I2C. It implements the hardware.SecureElement interface using
+// the IoT applet that is preinstalled on the chip.
+//
+// Keys are stored in secure objects, which are identified by a 32-bit object
+// ID. The slot numbers used by this driver are those object IDs, so they must be
+// in the range of user objects: 0x00000001 to 0x7bffffff.
+package se050
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hardware"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+)
+
+var (
+	ErrTimeout  = errors.New("se050: timeout waiting for the chip")
+	ErrCRC      = errors.New("se050: CRC mismatch in response")
+	ErrProtocol = errors.New("se050: unexpected block from chip")
+	ErrResponse = errors.New("se050: invalid response")
+)
+
+// I2C is the I2C bus the chip is connected to. It is implemented by
+// machine.I2C.
+type I2C interface {
+	Tx(addr uint16, w, r []byte) error
+}
+
+// DefaultAddress is the 7-bit I2C address of the chip.
+const DefaultAddress = 0x48
+
+// appletAID is the application identifier of the IoT applet.
+var appletAID = []byte{0xa0, 0x00, 0x00, 0x03, 0x96, 0x54, 0x53, 0x00, 0x00, 0x00, 0x01, 0x03, 0x00, 0x00, 0x00, 0x00}
+
+// Instructions and parameters of the IoT applet APDUs, see NXP AN12413.
+const (
+	claApplet = 0x80
+
+	insWrite  = 0x01
+	insRead   = 0x02
+	insCrypto = 0x03
+	insMgmt   = 0x04
+
+	p1Default   = 0x00
+	p1EC        = 0x01
+	p1KeyPair   = 0x60
+	p1Curve     = 0x0b
+	p1Signature = 0x0c
+
+	p2Default      = 0x00
+	p2Create       = 0x04
+	p2Sign         = 0x09
+	p2List         = 0x25
+	p2Exist        = 0x27
+	p2DeleteObject = 0x28
+	p2Param        = 0x40
+	p2Random       = 0x49
+
+	tag1 = 0x41
+	tag2 = 0x42
+	tag3 = 0x43
+
+	curveP256 = 0x03 // NIST P-256
+
+	curveParamA     = 0x01
+	curveParamB     = 0x02
+	curveParamG     = 0x04
+	curveParamN     = 0x08
+	curveParamPrime = 0x10
+
+	sigECDSASHA256 = 0x21
+
+	resultSuccess = 0x01
+)
+
+// Device is an SE050 connected over I2C.
+type Device struct {
+	bus     I2C
+	address uint16
+	seq     byte // sequence number of the next I-block sent by the host
+}
+
+// Config is the configuration of the Device.
+type Config struct {
+	// Address is the 7-bit I2C address. It defaults to DefaultAddress.
+	Address uint16
+}
+
+// New returns a new SE050 driver for the chip on the given bus.
+func New(bus I2C) *Device {
+	return &Device{
+		bus:     bus,
+		address: DefaultAddress,
+	}
+}
+
+// Configure sets the configuration, starts a session with the IoT applet and
+// makes sure the P-256 curve is available.
+func (d *Device) Configure(config Config) error {
+	if config.Address != 0 {
+		d.address = config.Address
+	}
+	if err := d.resync(); err != nil {
+		return err
+	}
+	selectApplet := append([]byte{0x00, 0xa4, 0x04, 0x00, byte(len(appletAID))}, appletAID...)
+	selectApplet = append(selectApplet, 0x00)
+	if _, err := d.command(selectApplet); err != nil {
+		return err
+	}
+	return d.createCurve()
+}
+
+// createCurve creates the P-256 curve in the chip if it doesn't exist yet.
+// Keys can only be created for curves that exist.
+func (d *Device) createCurve() error {
+	resp, err := d.apdu(insRead, p1Curve, p2List, nil)
+	if err != nil {
+		return err
+	}
+	list, err := findTLV(resp, tag1)
+	if err != nil {
+		return err
+	}
+	if len(list) >= curveP256 && list[curveP256-1] == resultSuccess {
+		return nil
+	}
+
+	if _, err := d.apdu(insWrite, p1Curve, p2Create, tlv(nil, tag1, []byte{curveP256})); err != nil {
+		return err
+	}
+	params := elliptic.P256().Params()
+	a := new(big.Int).Sub(params.P, big.NewInt(3))
+	g := elliptic.Marshal(params, params.Gx, params.Gy)
+	for _, param := range []struct {
+		typ   byte
+		value []byte
+	}{
+		{curveParamA, a.FillBytes(make([]byte, 32))},
+		{curveParamB, params.B.FillBytes(make([]byte, 32))},
+		{curveParamG, g},
+		{curveParamN, params.N.FillBytes(make([]byte, 32))},
+		{curveParamPrime, params.P.FillBytes(make([]byte, 32))},
+	} {
+		data := tlv(nil, tag1, []byte{curveP256})
+		data = tlv(data, tag2, []byte{param.typ})
+		data = tlv(data, tag3, param.value)
+		if _, err := d.apdu(insWrite, p1Curve, p2Param, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GenerateKey generates a new P-256 key pair in the object with the given ID,
+// deleting the object first if it exists, and returns the public key.
+func (d *Device) GenerateKey(slot int) (*ecdsa.PublicKey, error) {
+	id, err := objectID(slot)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := d.apdu(insMgmt, p1Default, p2Exist, id)
+	if err != nil {
+		return nil, err
+	}
+	exists, err := findTLV(resp, tag1)
+	if err != nil {
+		return nil, err
+	}
+	if len(exists) == 1 && exists[0] == resultSuccess {
+		if _, err := d.apdu(insMgmt, p1Default, p2DeleteObject, id); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := d.apdu(insWrite, p1EC|p1KeyPair, p2Default, tlv(id, tag2, []byte{curveP256})); err != nil {
+		return nil, err
+	}
+	return d.PublicKey(slot)
+}
+
+// PublicKey returns the public key of the key pair in the object with the
+// given ID.
+func (d *Device) PublicKey(slot int) (*ecdsa.PublicKey, error) {
+	id, err := objectID(slot)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := d.apdu(insRead, p1Default, p2Default, id)
+	if err != nil {
+		return nil, err
+	}
+	point, err := findTLV(resp, tag1)
+	if err != nil {
+		return nil, err
+	}
+	curve := elliptic.P256()
+	x, y := elliptic.Unmarshal(curve, point)
+	if x == nil {
+		return nil, ErrResponse
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// SignDigest signs a SHA-256 digest with the key pair in the object with the
+// given ID.
+func (d *Device) SignDigest(slot int, digest []byte) (r, s *big.Int, err error) {
+	id, err := objectID(slot)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(digest) != 32 {
+		return nil, nil, hardware.ErrDigestSize
+	}
+	data := tlv(id, tag2, []byte{sigECDSASHA256})
+	data = tlv(data, tag3, digest)
+	resp, err := d.apdu(insCrypto, p1Signature, p2Sign, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	der, err := findTLV(resp, tag1)
+	if err != nil {
+		return nil, nil, err
+	}
+	var sig struct {
+		R, S *big.Int
+	}
+	if _, err := asn1.Unmarshal(der, &sig); err != nil {
+		return nil, nil, ErrResponse
+	}
+	return sig.R, sig.S, nil
+}
+
+// Read fills p with random bytes from the random number generator of the
+// chip.
+func (d *Device) Read(p []byte) (n int, err error) {
+	for n < len(p) {
+		size := len(p) - n
+		if size > 128 {
+			size = 128
+		}
+		resp, err := d.apdu(insMgmt, p1Default, p2Random, tlv(nil, tag1, []byte{byte(size >> 8), byte(size)}))
+		if err != nil {
+			return n, err
+		}
+		random, err := findTLV(resp, tag1)
+		if err != nil {
+			return n, err
+		}
+		if len(random) != size {
+			return n, ErrResponse
+		}
+		n += copy(p[n:], random)
+	}
+	return n, nil
+}
+
+// objectID returns the TLV with the object ID for the given slot.
+func objectID(slot int) ([]byte, error) {
+	if slot <= 0 || slot > 0x7bffffff {
+		return nil, hardware.ErrInvalidSlot
+	}
+	return tlv(nil, tag1, []byte{byte(slot >> 24), byte(slot >> 16), byte(slot >> 8), byte(slot)}), nil
+}
+
+// apdu sends a command APDU to the IoT applet and returns the response data.
+func (d *Device) apdu(ins, p1, p2 byte, data []byte) ([]byte, error) {
+	if len(data) > 255 {
+		// Extended length APDUs are not needed for any of the commands used
+		// here.
+		return nil, ErrResponse
+	}
+	cmd := make([]byte, 0, 6+len(data))
+	cmd = append(cmd, claApplet, ins, p1, p2)
+	if len(data) != 0 {
+		cmd = append(cmd, byte(len(data)))
+		cmd = append(cmd, data...)
+	}
+	cmd = append(cmd, 0x00) // Le: as much response data as available
+	return d.command(cmd)
+}
+
+// command sends a command APDU and returns the response data, or an error if
+// the status word isn't 0x9000.
+func (d *Device) command(cmd []byte) ([]byte, error) {
+	resp, err := d.transceive(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) < 2 {
+		return nil, ErrResponse
+	}
+	sw := resp[len(resp)-2:]
+	if sw[0] != 0x90 || sw[1] != 0x00 {
+		return nil, &StatusError{SW: uint16(sw[0])<<8 | uint16(sw[1])}
+	}
+	return resp[:len(resp)-2], nil
+}
+
+// StatusError is returned when the chip returns a status word other than
+// 0x9000 (success).
+type StatusError struct {
+	SW uint16
+}
+
+func (e *StatusError) Error() string {
+	const digits = "0123456789abcdef"
+	s := "se050: command failed with status 0x"
+	for shift := 12; shift >= 0; shift -= 4 {
+		s += string(digits[e.SW>>shift&0xf])
+	}
+	return s
+}
+
+// tlv appends a tag-length-value item to buf.
+func tlv(buf []byte, tag byte, value []byte) []byte {
+	buf = append(buf, tag)
+	switch {
+	case len(value) < 0x80:
+		buf = append(buf, byte(len(value)))
+	case len(value) < 0x100:
+		buf = append(buf, 0x81, byte(len(value)))
+	default:
+		buf = append(buf, 0x82, byte(len(value)>>8), byte(len(value)))
+	}
+	return append(buf, value...)
+}
+
+// findTLV returns the value of the first item with the given tag.
+func findTLV(buf []byte, tag byte) ([]byte, error) {
+	for len(buf) >= 2 {
+		itemTag := buf[0]
+		length := int(buf[1])
+		buf = buf[2:]
+		switch length {
+		case 0x81:
+			if len(buf) < 1 {
+				return nil, ErrResponse
+			}
+			length = int(buf[0])
+			buf = buf[1:]
+		case 0x82:
+			if len(buf) < 2 {
+				return nil, ErrResponse
+			}
+			length = int(buf[0])<<8 | int(buf[1])
+			buf = buf[2:]
+		}
+		if length > len(buf) {
+			return nil, ErrResponse
+		}
+		if itemTag == tag {
+			return buf[:length], nil
+		}
+		buf = buf[length:]
+	}
+	return nil, ErrResponse
+}
@@ -0,0 +1,169 @@
+package se050
+
+// This file implements the T=1 block transmission protocol over I2C, which is
+// used to exchange APDUs with the chip, see NXP UM11225. Only the parts needed
+// for a well-behaved connection are implemented: errors are reported instead of
+// being recovered from by retransmission.
+
+import (
+	"time"
+)
+
+const (
+	nadHost = 0x5a // node address byte of blocks sent by the host
+	nadChip = 0xa5 // node address byte of blocks sent by the chip
+
+	pcbMore       = 0x20 // more data bit of an I-block
+	pcbRBlock     = 0x80
+	pcbSBlock     = 0xc0
+	pcbResync     = 0xc0 // S(RESYNCH request)
+	pcbWTXRequest = 0xc3 // S(WTX request), sent by the chip
+	pcbResponse   = 0x20 // set in S-blocks that respond to a request
+
+	// maxInfo is the maximum size of the information field of a block, the
+	// default IFSC of the chip.
+	maxInfo = 254
+
+	// readTimeout is the time the chip has to respond to a block. The chip
+	// asks for more time with a WTX request when an operation takes longer.
+	readTimeout = time.Second
+)
+
+// resync resets the protocol state, both of the chip and of the driver.
+func (d *Device) resync() error {
+	d.seq = 0
+	pcb, _, err := d.exchange(pcbResync, nil)
+	if err != nil {
+		return err
+	}
+	if pcb != pcbResync|pcbResponse {
+		return ErrProtocol
+	}
+	return nil
+}
+
+// transceive sends an APDU in one or more I-blocks and returns the response
+// APDU.
+func (d *Device) transceive(apdu []byte) ([]byte, error) {
+	var pcb byte
+	var info []byte
+	for {
+		n := len(apdu)
+		if n > maxInfo {
+			n = maxInfo
+		}
+		more := n < len(apdu)
+		blockPCB := d.seq << 6
+		if more {
+			blockPCB |= pcbMore
+		}
+		var err error
+		pcb, info, err = d.exchange(blockPCB, apdu[:n])
+		if err != nil {
+			return nil, err
+		}
+		d.seq ^= 1
+		apdu = apdu[n:]
+		if !more {
+			break
+		}
+		// The chip acknowledges each chained block with an R-block.
+		if pcb&pcbSBlock != pcbRBlock {
+			return nil, ErrProtocol
+		}
+	}
+
+	// The response may be chained too. Each block is acknowledged with an
+	// R-block containing the sequence number of the next expected block.
+	var resp []byte
+	for {
+		if pcb&pcbRBlock != 0 {
+			return nil, ErrProtocol
+		}
+		resp = append(resp, info...)
+		if pcb&pcbMore == 0 {
+			return resp, nil
+		}
+		var err error
+		pcb, info, err = d.exchange(pcbRBlock|(pcb>>6&1^1)<<4, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// exchange sends a block and returns the block the chip sends in response,
+// after answering any requests for a waiting time extension.
+func (d *Device) exchange(pcb byte, info []byte) (byte, []byte, error) {
+	if err := d.writeBlock(pcb, info); err != nil {
+		return 0, nil, err
+	}
+	for {
+		pcb, info, err := d.readBlock()
+		if err != nil {
+			return 0, nil, err
+		}
+		if pcb != pcbWTXRequest {
+			return pcb, info, nil
+		}
+		if err := d.writeBlock(pcbWTXRequest|pcbResponse, info); err != nil {
+			return 0, nil, err
+		}
+	}
+}
+
+// writeBlock sends a single block.
+func (d *Device) writeBlock(pcb byte, info []byte) error {
+	block := make([]byte, 0, 3+len(info)+2)
+	block = append(block, nadHost, pcb, byte(len(info)))
+	block = append(block, info...)
+	crc := crc16(block)
+	block = append(block, byte(crc), byte(crc>>8))
+	return d.bus.Tx(d.address, block, nil)
+}
+
+// readBlock reads a single block. The chip doesn't acknowledge its address
+// while it's busy, so this polls until the chip responds.
+func (d *Device) readBlock() (byte, []byte, error) {
+	var header [3]byte
+	for start := time.Now(); ; {
+		time.Sleep(time.Millisecond)
+		if err := d.bus.Tx(d.address, nil, header[:]); err == nil {
+			break
+		}
+		if time.Since(start) > readTimeout {
+			return 0, nil, ErrTimeout
+		}
+	}
+	if header[0] != nadChip {
+		return 0, nil, ErrProtocol
+	}
+	block := make([]byte, 3+int(header[2])+2)
+	copy(block, header[:])
+	if err := d.bus.Tx(d.address, nil, block[3:]); err != nil {
+		return 0, nil, err
+	}
+	n := len(block) - 2
+	crc := crc16(block[:n])
+	if block[n] != byte(crc) || block[n+1] != byte(crc>>8) {
+		return 0, nil, ErrCRC
+	}
+	return header[1], block[3:n], nil
+}
+
+// crc16 calculates the CRC of a block: CRC-16/X-25, as used by ISO 7816-3. It
+// is sent least significant byte first.
+func crc16(data []byte) uint16 {
+	crc := uint16(0xffff)
+	for _, b := range data {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0x8408
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc ^ 0xffff
+}
@@ -0,0 +1,166 @@
+package se050
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// fakeBus records the blocks written to the chip and returns the bytes of the
+// queued response blocks. Reads fail while no response is queued, like a chip
+// that doesn't acknowledge its address while it's busy.
+type fakeBus struct {
+	written [][]byte
+	resp    []byte
+}
+
+var errNoAck = errors.New("no acknowledge")
+
+func (b *fakeBus) Tx(addr uint16, w, r []byte) error {
+	if addr != DefaultAddress {
+		return errNoAck
+	}
+	if len(w) != 0 {
+		b.written = append(b.written, append([]byte(nil), w...))
+	}
+	if len(r) != 0 {
+		if len(b.resp) < len(r) {
+			return errNoAck
+		}
+		copy(r, b.resp)
+		b.resp = b.resp[len(r):]
+	}
+	return nil
+}
+
+// block returns a T=1 block with the given node address, PCB and information
+// field.
+func block(nad, pcb byte, info []byte) []byte {
+	b := append([]byte{nad, pcb, byte(len(info))}, info...)
+	crc := crc16(b)
+	return append(b, byte(crc), byte(crc>>8))
+}
+
+func TestCRC16(t *testing.T) {
+	// Check value of CRC-16/X-25.
+	if crc := crc16([]byte("123456789")); crc != 0x906e {
+		t.Errorf("crc16(123456789) = %#04x, want 0x906e", crc)
+	}
+	// S(RESYNCH request), as sent by the NXP middleware.
+	if b := block(nadHost, pcbResync, nil); !bytes.Equal(b, []byte{0x5a, 0xc0, 0x00, 0xff, 0xfc}) {
+		t.Errorf("S(RESYNCH) block = % x", b)
+	}
+}
+
+func TestTransceive(t *testing.T) {
+	bus := &fakeBus{}
+	d := New(bus)
+
+	// Two APDUs in single I-blocks: the sequence number alternates.
+	for i, pcb := range []byte{0x00, 0x40} {
+		bus.written = nil
+		bus.resp = block(nadChip, pcb, []byte{0x90, 0x00})
+		resp, err := d.transceive([]byte{0x00, 0xa4, 0x04, 0x00})
+		if err != nil {
+			t.Fatalf("transceive %d: %v", i, err)
+		}
+		if !bytes.Equal(resp, []byte{0x90, 0x00}) {
+			t.Errorf("transceive %d: response % x", i, resp)
+		}
+		want := block(nadHost, pcb, []byte{0x00, 0xa4, 0x04, 0x00})
+		if len(bus.written) != 1 || !bytes.Equal(bus.written[0], want) {
+			t.Errorf("transceive %d: wrote % x, want % x", i, bus.written, want)
+		}
+	}
+}
+
+func TestTransceiveChained(t *testing.T) {
+	bus := &fakeBus{}
+	d := New(bus)
+
+	// A command that doesn't fit in one block is sent in two blocks, the
+	// first of which is acknowledged with an R-block. The response is
+	// chained too and each block is acknowledged by the host.
+	apdu := make([]byte, maxInfo+10)
+	for i := range apdu {
+		apdu[i] = byte(i)
+	}
+	bus.resp = append(bus.resp, block(nadChip, pcbRBlock|0x10, nil)...)
+	bus.resp = append(bus.resp, block(nadChip, 0x00|pcbMore, []byte{1, 2, 3})...)
+	bus.resp = append(bus.resp, block(nadChip, 0x40, []byte{4, 0x90, 0x00})...)
+	resp, err := d.transceive(apdu)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(resp, []byte{1, 2, 3, 4, 0x90, 0x00}) {
+		t.Errorf("response % x", resp)
+	}
+	want := [][]byte{
+		block(nadHost, 0x00|pcbMore, apdu[:maxInfo]),
+		block(nadHost, 0x40, apdu[maxInfo:]),
+		block(nadHost, pcbRBlock|0x10, nil),
+	}
+	if len(bus.written) != len(want) {
+		t.Fatalf("wrote %d blocks, want %d", len(bus.written), len(want))
+	}
+	for i := range want {
+		if !bytes.Equal(bus.written[i], want[i]) {
+			t.Errorf("block %d: wrote % x, want % x", i, bus.written[i], want[i])
+		}
+	}
+}
+
+func TestTransceiveWTX(t *testing.T) {
+	bus := &fakeBus{}
+	d := New(bus)
+
+	// A request for a waiting time extension is answered with the same
+	// information field, after which the chip sends the response.
+	bus.resp = append(bus.resp, block(nadChip, pcbWTXRequest, []byte{0x01})...)
+	bus.resp = append(bus.resp, block(nadChip, 0x00, []byte{0x90, 0x00})...)
+	if _, err := d.transceive([]byte{0x80, 0x04, 0x00, 0x49}); err != nil {
+		t.Fatal(err)
+	}
+	want := block(nadHost, pcbWTXRequest|pcbResponse, []byte{0x01})
+	if len(bus.written) != 2 || !bytes.Equal(bus.written[1], want) {
+		t.Errorf("wrote % x, want WTX response % x", bus.written, want)
+	}
+}
+
+func TestReadBlockErrors(t *testing.T) {
+	bus := &fakeBus{}
+	d := New(bus)
+
+	bus.resp = block(nadChip, 0x00, []byte{0x90, 0x00})
+	bus.resp[len(bus.resp)-1] ^= 0xff
+	if _, _, err := d.readBlock(); err != ErrCRC {
+		t.Errorf("expected ErrCRC for corrupted block, got %v", err)
+	}
+
+	bus.resp = block(nadHost, 0x00, []byte{0x90, 0x00})
+	if _, _, err := d.readBlock(); err != ErrProtocol {
+		t.Errorf("expected ErrProtocol for wrong node address, got %v", err)
+	}
+}
+
+func TestCommandStatus(t *testing.T) {
+	bus := &fakeBus{}
+	d := New(bus)
+
+	bus.resp = block(nadChip, 0x00, []byte{0x41, 0x01, 0x01, 0x90, 0x00})
+	data, err := d.apdu(insMgmt, p1Default, p2Exist, []byte{0x41, 0x04, 0x00, 0x00, 0x00, 0x01})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, err := findTLV(data, tag1); err != nil || !bytes.Equal(value, []byte{resultSuccess}) {
+		t.Errorf("findTLV(% x) = % x, %v", data, value, err)
+	}
+
+	bus.resp = block(nadChip, 0x40, []byte{0x6a, 0x82})
+	_, err = d.apdu(insRead, p1Default, p2Default, nil)
+	if serr, ok := err.(*StatusError); !ok || serr.SW != 0x6a82 {
+		t.Errorf("expected StatusError 0x6a82, got %v", err)
+	} else if s := serr.Error(); s != "se050: command failed with status 0x6a82" {
+		t.Errorf("unexpected error string %q", s)
+	}
+}
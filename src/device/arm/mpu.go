@@ -0,0 +1,86 @@
+// Hand created file. DO NOT DELETE.
+// Cortex-M Memory Protection Unit-related definitions.
+
+//go:build cortexm
+
+package arm
+
+import (
+	"runtime/volatile"
+	"unsafe"
+)
+
+const MPU_BASE = SCS_BASE + 0x0D90
+
+// Memory Protection Unit (MPU)
+//
+// The MPU is optional on all Cortex-M cores. ARMv6-M and ARMv7-M cores (like
+// the Cortex-M0+, Cortex-M3, Cortex-M4 and Cortex-M7) implement PMSAv7, where
+// a region has a power-of-two size and is described by RBAR and RASR. ARMv8-M
+// cores (like the Cortex-M33) implement PMSAv8, where a region is described by
+// a base and limit address in RBAR and RLAR and the memory attributes are
+// stored in MAIR0/MAIR1.
+type MPU_Type struct {
+	TYPE  volatile.Register32 // 0xD90: MPU Type Register
+	CTRL  volatile.Register32 // 0xD94: MPU Control Register
+	RNR   volatile.Register32 // 0xD98: MPU Region Number Register
+	RBAR  volatile.Register32 // 0xD9C: MPU Region Base Address Register
+	RASR  volatile.Register32 // 0xDA0: MPU Region Attribute and Size Register (RLAR on ARMv8-M)
+	_     [7]uint32           // alias registers and reserved
+	MAIR0 volatile.Register32 // 0xDC0: MPU Memory Attribute Indirection Register 0 (ARMv8-M only)
+	MAIR1 volatile.Register32 // 0xDC4: MPU Memory Attribute Indirection Register 1 (ARMv8-M only)
+}
+
+var MPU = (*MPU_Type)(unsafe.Pointer(uintptr(MPU_BASE)))
+
+// MPU register fields.
+const (
+	// TYPE: MPU Type Register
+	MPU_TYPE_DREGION_Pos = 0x8    // Position of DREGION field.
+	MPU_TYPE_DREGION_Msk = 0xff00 // Bit mask of DREGION field.
+
+	// CTRL: MPU Control Register
+	MPU_CTRL_ENABLE     = 0x1 // Bit ENABLE.
+	MPU_CTRL_HFNMIENA   = 0x2 // Bit HFNMIENA.
+	MPU_CTRL_PRIVDEFENA = 0x4 // Bit PRIVDEFENA.
+
+	// RASR: MPU Region Attribute and Size Register (PMSAv7)
+	MPU_RASR_ENABLE   = 0x1        // Bit ENABLE.
+	MPU_RASR_SIZE_Pos = 0x1        // Position of SIZE field.
+	MPU_RASR_SIZE_Msk = 0x3e       // Bit mask of SIZE field.
+	MPU_RASR_SRD_Pos  = 0x8        // Position of SRD field.
+	MPU_RASR_SRD_Msk  = 0xff00     // Bit mask of SRD field.
+	MPU_RASR_B        = 0x10000    // Bit B.
+	MPU_RASR_C        = 0x20000    // Bit C.
+	MPU_RASR_S        = 0x40000    // Bit S.
+	MPU_RASR_TEX_Pos  = 0x13       // Position of TEX field.
+	MPU_RASR_TEX_Msk  = 0x380000   // Bit mask of TEX field.
+	MPU_RASR_AP_Pos   = 0x18       // Position of AP field.
+	MPU_RASR_AP_Msk   = 0x7000000  // Bit mask of AP field.
+	MPU_RASR_XN       = 0x10000000 // Bit XN.
+
+	// RASR.AP values (PMSAv7)
+	MPU_RASR_AP_NoAccess = 0x0 // No access.
+	MPU_RASR_AP_PrivRW   = 0x1 // Read/write for privileged code only.
+	MPU_RASR_AP_RW       = 0x3 // Full access.
+	MPU_RASR_AP_PrivRO   = 0x5 // Read-only for privileged code only.
+	MPU_RASR_AP_RO       = 0x6 // Read-only.
+
+	// RBAR: MPU Region Base Address Register (PMSAv8)
+	MPU_RBAR_XN     = 0x1  // Bit XN.
+	MPU_RBAR_AP_Pos = 0x1  // Position of AP field.
+	MPU_RBAR_AP_Msk = 0x6  // Bit mask of AP field.
+	MPU_RBAR_SH_Pos = 0x3  // Position of SH field.
+	MPU_RBAR_SH_Msk = 0x18 // Bit mask of SH field.
+
+	// RBAR.AP values (PMSAv8)
+	MPU_RBAR_AP_PrivRW = 0x0 // Read/write for privileged code only.
+	MPU_RBAR_AP_RW     = 0x1 // Full access.
+	MPU_RBAR_AP_PrivRO = 0x2 // Read-only for privileged code only.
+	MPU_RBAR_AP_RO     = 0x3 // Read-only.
+
+	// RLAR: MPU Region Limit Address Register (PMSAv8)
+	MPU_RLAR_EN           = 0x1 // Bit EN.
+	MPU_RLAR_AttrIndx_Pos = 0x1 // Position of AttrIndx field.
+	MPU_RLAR_AttrIndx_Msk = 0xe // Bit mask of AttrIndx field.
+)
@@ -1,15 +1,17 @@
 package main
 
-// This example demonstrates how to use go:section to place code into RAM for
+// This example demonstrates how to use go:ramfunc to place code into RAM for
 // execution.  The code is present in flash in the `.data` region and copied
 // into the correct place in RAM early in startup sequence (at the same time
 // as non-zero global variables are initialized).
 //
 // This example should work on any ARM Cortex MCU.
 //
-// For Go code use the pragma "//go:section", for cgo use the "section" and
+// For Go code use the pragma "//go:ramfunc", for cgo use the "section" and
 // "noinline" attributes.  The `.ramfuncs` section is explicitly placed into
-// the `.data` region by the linker script.
+// the `.data` region by the linker script. Code in RAM is needed for example
+// when writing to the flash bank the rest of the program runs from. Note that
+// such code must not call functions that are still in flash.
 //
 // Running the example should print out the program counter from the functions
 // below.  The program counters should be in different memory regions.
@@ -39,7 +41,7 @@ import (
 	"device"
 	"fmt"
 	"time"
-	_ "unsafe" // unsafe is required for "//go:section"
+	_ "unsafe" // unsafe is required for "//go:ramfunc"
 )
 
 /*
@@ -78,7 +80,7 @@ func main() {
 	fmt.Printf("cgo in flash: 0x%X\n", C.main_c_in_flash())
 }
 
-//go:section .ramfuncs
+//go:ramfunc
 func in_ram() uintptr {
 	return device.AsmFull("MOV {}, PC", nil)
 }
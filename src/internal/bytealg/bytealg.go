@@ -13,13 +13,15 @@ const (
 // If the lengths are different and there are no differing bytes, compares based on length.
 func Compare(a, b []byte) int {
 	// Compare for differing bytes.
-	for i := 0; i < len(a) && i < len(b); i++ {
-		switch {
-		case a[i] < b[i]:
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	if i := mismatch(sliceData(a), sliceData(b), n); i < n {
+		if a[i] < b[i] {
 			return -1
-		case a[i] > b[i]:
-			return 1
 		}
+		return 1
 	}
 
 	// Compare lengths.
@@ -72,13 +74,7 @@ func Equal(a, b []byte) bool {
 		return false
 	}
 
-	for i, v := range a {
-		if v != b[i] {
-			return false
-		}
-	}
-
-	return true
+	return mismatch(sliceData(a), sliceData(b), len(a)) == len(a)
 }
 
 // Index finds the base index of the first instance of the byte sequence b in a.
@@ -95,23 +91,13 @@ func Index(a, b []byte) int {
 // Index finds the index of the first instance of the specified byte in the slice.
 // If the byte is not found, this returns -1.
 func IndexByte(b []byte, c byte) int {
-	for i, v := range b {
-		if v == c {
-			return i
-		}
-	}
-	return -1
+	return indexByte(sliceData(b), len(b), c)
 }
 
 // Index finds the index of the first instance of the specified byte in the string.
 // If the byte is not found, this returns -1.
 func IndexByteString(s string, c byte) int {
-	for i := 0; i < len(s); i++ {
-		if s[i] == c {
-			return i
-		}
-	}
-	return -1
+	return indexByte(stringData(s), len(s), c)
 }
 
 // Index finds the base index of the first instance of a substring in a string.
@@ -0,0 +1,84 @@
+package bytealg
+
+// This file implements the inner loops of IndexByte, Equal and Compare by
+// processing a machine word at a time instead of a single byte. Only aligned
+// words are loaded, so this is also safe on cores that don't support unaligned
+// loads (such as the Cortex-M0).
+
+import "unsafe"
+
+const (
+	wordSize = int(unsafe.Sizeof(uintptr(0)))
+
+	// Word with the lowest and the highest bit set in every byte.
+	lsbs = ^uintptr(0) / 0xff
+	msbs = lsbs << 7
+)
+
+// stringData returns a pointer to the bytes of the string.
+func stringData(s string) unsafe.Pointer {
+	return *(*unsafe.Pointer)(unsafe.Pointer(&s))
+}
+
+// sliceData returns a pointer to the bytes of the slice.
+func sliceData(b []byte) unsafe.Pointer {
+	return *(*unsafe.Pointer)(unsafe.Pointer(&b))
+}
+
+// isAligned returns whether a word can be loaded from p.
+func isAligned(p unsafe.Pointer) bool {
+	return uintptr(p)%uintptr(wordSize) == 0
+}
+
+// indexByte returns the index of the first instance of c in the n bytes
+// starting at p, or -1 if there is none.
+func indexByte(p unsafe.Pointer, n int, c byte) int {
+	i := 0
+	for ; i < n && !isAligned(unsafe.Add(p, i)); i++ {
+		if *(*byte)(unsafe.Add(p, i)) == c {
+			return i
+		}
+	}
+
+	// Skip all words that don't contain c: after XOR-ing with the pattern, a
+	// word containing c has a zero byte.
+	pattern := lsbs * uintptr(c)
+	for ; i+wordSize <= n; i += wordSize {
+		x := *(*uintptr)(unsafe.Add(p, i)) ^ pattern
+		if (x-lsbs)&^x&msbs != 0 {
+			break
+		}
+	}
+
+	for ; i < n; i++ {
+		if *(*byte)(unsafe.Add(p, i)) == c {
+			return i
+		}
+	}
+	return -1
+}
+
+// mismatch returns the index of the first byte that differs between the n
+// bytes starting at a and b, or n if they are equal.
+func mismatch(a, b unsafe.Pointer, n int) int {
+	i := 0
+	if uintptr(a)%uintptr(wordSize) == uintptr(b)%uintptr(wordSize) {
+		for ; i < n && !isAligned(unsafe.Add(a, i)); i++ {
+			if *(*byte)(unsafe.Add(a, i)) != *(*byte)(unsafe.Add(b, i)) {
+				return i
+			}
+		}
+		for ; i+wordSize <= n; i += wordSize {
+			if *(*uintptr)(unsafe.Add(a, i)) != *(*uintptr)(unsafe.Add(b, i)) {
+				break
+			}
+		}
+	}
+
+	for ; i < n; i++ {
+		if *(*byte)(unsafe.Add(a, i)) != *(*byte)(unsafe.Add(b, i)) {
+			return i
+		}
+	}
+	return n
+}
@@ -0,0 +1,22 @@
+//go:build runtime_latency
+
+package task
+
+// latencyData records when a task was made runnable, for the scheduler latency
+// statistics of the runtime.
+type latencyData struct {
+	runnableAt  uint64
+	inInterrupt bool
+}
+
+// SetRunnable records the time at which the task was made runnable, and
+// whether this happened in an interrupt handler.
+func (t *Task) SetRunnable(now uint64, inInterrupt bool) {
+	t.latency.runnableAt = now
+	t.latency.inInterrupt = inInterrupt
+}
+
+// Runnable returns the values recorded by SetRunnable.
+func (t *Task) Runnable() (since uint64, inInterrupt bool) {
+	return t.latency.runnableAt, t.latency.inInterrupt
+}
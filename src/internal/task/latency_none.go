@@ -0,0 +1,12 @@
+//go:build !runtime_latency
+
+package task
+
+type latencyData struct{}
+
+func (t *Task) SetRunnable(now uint64, inInterrupt bool) {
+}
+
+func (t *Task) Runnable() (since uint64, inInterrupt bool) {
+	return 0, false
+}
@@ -21,9 +21,20 @@ type Task struct {
 	// state is the underlying running state of the task.
 	state state
 
+	// latency holds data for the scheduler latency statistics.
+	latency latencyData
+
 	// DeferFrame stores a pointer to the (stack allocated) defer frame of the
 	// goroutine that is used for the recover builtin.
 	DeferFrame unsafe.Pointer
+
+	// TraceFrame stores a pointer to the innermost (stack allocated) trace
+	// frame of the goroutine, when compiling with -panic=trace.
+	TraceFrame unsafe.Pointer
+
+	// RaceContext stores the state of the data race detector for this
+	// goroutine, when compiling with -race.
+	RaceContext unsafe.Pointer
 }
 
 // getGoroutineStackSize is a compiler intrinsic that returns the stack size for
@@ -107,11 +107,15 @@ func runqueuePushBack(*Task)
 //go:linkname runtime_alloc runtime.alloc
 func runtime_alloc(size uintptr, layout unsafe.Pointer) unsafe.Pointer
 
+//go:linkname raceGoStart runtime.racegostart
+func raceGoStart(*Task)
+
 // start creates and starts a new goroutine with the given function and arguments.
 // The new goroutine is scheduled to run later.
 func start(fn uintptr, args unsafe.Pointer, stackSize uintptr) {
 	t := &Task{}
 	t.state.initialize(fn, args, stackSize)
+	raceGoStart(t)
 	runqueuePushBack(t)
 }
 
@@ -2,6 +2,8 @@
 
 package machine
 
+// CPUFrequency returns the frequency of the CPU clock. It is fixed on the
+// nRF51, so there is no SetCPUFrequency.
 func CPUFrequency() uint32 {
 	return 16000000
 }
@@ -37,6 +37,13 @@ var (
 	errFlashCannotErasePastEOF  = errors.New("cannot erase beyond end of flash data")
 )
 
+// FlashBlockWear is the number of erase and write cycles of a single erase
+// block of Flash, see FlashWear.
+type FlashBlockWear struct {
+	Erases uint32 // number of times the block was erased
+	Writes uint32 // number of WriteAt calls that wrote to the block
+}
+
 // BlockDevice is the raw device that is meant to store flash data.
 type BlockDevice interface {
 	// ReadAt reads the given number of bytes from the block device.
@@ -0,0 +1,20 @@
+//go:build !flashwear && (nrf || nrf51 || nrf52 || nrf528xx || stm32f4 || stm32l4 || stm32wlx || atsamd21 || atsamd51 || atsame5x || rp2040)
+
+package machine
+
+// FlashWear returns nil: flash wear accounting is only enabled when building
+// with -tags=flashwear.
+func FlashWear() []FlashBlockWear {
+	return nil
+}
+
+// ResetFlashWear does nothing: flash wear accounting is only enabled when
+// building with -tags=flashwear.
+func ResetFlashWear() {
+}
+
+func recordFlashErase(start, count int64) {
+}
+
+func recordFlashWrite(off, size int64) {
+}
@@ -0,0 +1,56 @@
+//go:build flashwear && (nrf || nrf51 || nrf52 || nrf528xx || stm32f4 || stm32l4 || stm32wlx || atsamd21 || atsamd51 || atsame5x || rp2040)
+
+package machine
+
+// This file implements flash wear accounting, which is enabled with
+// -tags=flashwear. It counts the erase and write cycles of each erase block of
+// Flash, to check during development whether a filesystem or key/value store
+// spreads its writes evenly over the available blocks.
+
+// flashWear contains the counters of each erase block of Flash. It is allocated
+// when it is first needed.
+var flashWear []FlashBlockWear
+
+func initFlashWear() {
+	if flashWear == nil {
+		flashWear = make([]FlashBlockWear, Flash.Size()/Flash.EraseBlockSize())
+	}
+}
+
+// FlashWear returns a copy of the erase and write counters of each erase block
+// of Flash, indexed by block number. The counters start at zero at startup
+// and when ResetFlashWear is called.
+func FlashWear() []FlashBlockWear {
+	initFlashWear()
+	wear := make([]FlashBlockWear, len(flashWear))
+	copy(wear, flashWear)
+	return wear
+}
+
+// ResetFlashWear sets all flash wear counters to zero.
+func ResetFlashWear() {
+	for i := range flashWear {
+		flashWear[i] = FlashBlockWear{}
+	}
+}
+
+// recordFlashErase counts an erase cycle of the given blocks.
+func recordFlashErase(start, count int64) {
+	initFlashWear()
+	for block := start; block < start+count && block < int64(len(flashWear)); block++ {
+		flashWear[block].Erases++
+	}
+}
+
+// recordFlashWrite counts a write cycle of each block that contains at least
+// one of the written bytes.
+func recordFlashWrite(off, size int64) {
+	if size <= 0 {
+		return
+	}
+	initFlashWear()
+	blockSize := Flash.EraseBlockSize()
+	for block := off / blockSize; block <= (off+size-1)/blockSize && block < int64(len(flashWear)); block++ {
+		flashWear[block].Writes++
+	}
+}
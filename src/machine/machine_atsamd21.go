@@ -1847,12 +1847,14 @@ func (f flashBlockDevice) WriteAt(p []byte, off int64) (n int, err error) {
 		waitWhileFlashBusy()
 
 		if err := checkFlashError(); err != nil {
+			recordFlashWrite(off, int64(j))
 			return j, err
 		}
 
 		address += uintptr(f.WriteBlockSize())
 	}
 
+	recordFlashWrite(off, int64(len(padded)))
 	return len(padded), nil
 }
 
@@ -1899,12 +1901,14 @@ func (f flashBlockDevice) EraseBlocks(start, len int64) error {
 		waitWhileFlashBusy()
 
 		if err := checkFlashError(); err != nil {
+			recordFlashErase(start, i-start)
 			return err
 		}
 
 		address += uintptr(f.EraseBlockSize())
 	}
 
+	recordFlashErase(start, len)
 	return nil
 }
 
@@ -18,8 +18,10 @@ import (
 
 const deviceName = sam.Device
 
+// CPUFrequency returns the current CPU frequency, which is 120MHz unless
+// changed with SetCPUFrequency.
 func CPUFrequency() uint32 {
-	return 120000000
+	return cpuFrequency
 }
 
 const (
@@ -1522,8 +1524,9 @@ func (spi SPI) Configure(config SPIConfig) error {
 	freqGCLK1 := SERCOM_FREQ_REF / 2 / baudRateGCLK1
 
 	// Same for GCLK0 (120MHz).
-	baudRateGCLK0 := (SERCOM_FREQ_REF_GCLK0/2 + config.Frequency - 1) / config.Frequency
-	freqGCLK0 := SERCOM_FREQ_REF_GCLK0 / 2 / baudRateGCLK0
+	freqRefGCLK0 := CPUFrequency()
+	baudRateGCLK0 := (freqRefGCLK0/2 + config.Frequency - 1) / config.Frequency
+	freqGCLK0 := freqRefGCLK0 / 2 / baudRateGCLK0
 
 	// Pick the clock source that is the closest to the maximum baud rate.
 	// Note: there may be reasons to prefer the lower frequency clock (like
@@ -1534,10 +1537,12 @@ func (spi SPI) Configure(config SPIConfig) error {
 		// division, and the baudRate value fits in the BAUD register.
 		setSERCOMClockGenerator(spi.SERCOM, sam.GCLK_PCHCTRL_GEN_GCLK0)
 		spi.Bus.BAUD.Set(uint8(baudRateGCLK0 - 1))
+		spiGCLK0[spi.SERCOM] = spiGCLK0Config{spi.Bus, config.Frequency}
 	} else {
 		// Use the 48MHz clock in other cases.
 		setSERCOMClockGenerator(spi.SERCOM, sam.GCLK_PCHCTRL_GEN_GCLK1)
 		spi.Bus.BAUD.Set(uint8(baudRateGCLK1 - 1))
+		spiGCLK0[spi.SERCOM] = spiGCLK0Config{}
 	}
 
 	// Enable SPI port.
@@ -2174,12 +2179,14 @@ func (f flashBlockDevice) WriteAt(p []byte, off int64) (n int, err error) {
 		waitWhileFlashBusy()
 
 		if err := checkFlashError(); err != nil {
+			recordFlashWrite(off, int64(j))
 			return j, err
 		}
 
 		address += uintptr(f.WriteBlockSize())
 	}
 
+	recordFlashWrite(off, int64(len(padded)))
 	return len(padded), nil
 }
 
@@ -2228,12 +2235,14 @@ func (f flashBlockDevice) EraseBlocks(start, len int64) error {
 		waitWhileFlashBusy()
 
 		if err := checkFlashError(); err != nil {
+			recordFlashErase(start, i-start)
 			return err
 		}
 
 		address += uintptr(f.EraseBlockSize())
 	}
 
+	recordFlashErase(start, len)
 	return nil
 }
 
@@ -0,0 +1,99 @@
+//go:build (sam && atsamd51) || (sam && atsame5x)
+
+package machine
+
+import (
+	"device/sam"
+	"errors"
+	"runtime/interrupt"
+)
+
+// The CPU runs from GCLK0, which is fed by DPLL0. DPLL0 multiplies the 2MHz
+// GCLK7 (set up by the runtime) to 120MHz. The UART and I2C peripherals run
+// from the 48MHz GCLK1 and the time base runs from the 32kHz oscillator, so
+// those are not affected by a change in CPU frequency. Only SPI buses that
+// were configured to use GCLK0 need to be updated.
+
+var cpuFrequency uint32 = 120000000
+
+var errInvalidCPUFrequency = errors.New("machine: CPU frequency must be 48MHz or a multiple of 2MHz between 96MHz and 200MHz")
+
+const dpllRefFreq = 2000000 // GCLK7
+
+// spiGCLK0Config is the configuration of an SPI bus that runs from GCLK0, and
+// therefore must have its baud rate recalculated when the CPU frequency
+// changes.
+type spiGCLK0Config struct {
+	bus       *sam.SERCOM_SPIM_Type
+	frequency uint32
+}
+
+var spiGCLK0 [8]spiGCLK0Config
+
+// SetCPUFrequency changes the CPU frequency, for example to reduce power
+// consumption. Supported frequencies are 48MHz (running directly from the
+// DFLL) and multiples of 2MHz between 96MHz and 200MHz. Frequencies above
+// 120MHz are outside of the specification of the chip.
+//
+// The flash wait states are adjusted automatically by the hardware. SPI buses
+// that run from the CPU clock are reconfigured so that they keep running at
+// (at most) their configured frequency. PWM peripherals run from the CPU clock
+// as well and must be configured again.
+func SetCPUFrequency(freq uint32) error {
+	useDPLL := freq != 48000000
+	if useDPLL && (freq%dpllRefFreq != 0 || freq < 96000000 || freq > 200000000) {
+		return errInvalidCPUFrequency
+	}
+
+	mask := interrupt.Disable()
+	defer interrupt.Restore(mask)
+
+	// Run from the DFLL while DPLL0 is being reconfigured.
+	setGCLK0Source(sam.GCLK_GENCTRL_SRC_DFLL)
+	sam.OSCCTRL.DPLL[0].DPLLCTRLA.ClearBits(sam.OSCCTRL_DPLL_DPLLCTRLA_ENABLE)
+	for sam.OSCCTRL.DPLL[0].DPLLSYNCBUSY.HasBits(sam.OSCCTRL_DPLL_DPLLSYNCBUSY_ENABLE) {
+	}
+
+	if useDPLL {
+		// multiplier = LDR + 1
+		sam.OSCCTRL.DPLL[0].DPLLRATIO.Set((0x0 << sam.OSCCTRL_DPLL_DPLLRATIO_LDRFRAC_Pos) |
+			((freq/dpllRefFreq - 1) << sam.OSCCTRL_DPLL_DPLLRATIO_LDR_Pos))
+		for sam.OSCCTRL.DPLL[0].DPLLSYNCBUSY.HasBits(sam.OSCCTRL_DPLL_DPLLSYNCBUSY_DPLLRATIO) {
+		}
+		sam.OSCCTRL.DPLL[0].DPLLCTRLA.Set(sam.OSCCTRL_DPLL_DPLLCTRLA_ENABLE)
+		for !sam.OSCCTRL.DPLL[0].DPLLSTATUS.HasBits(sam.OSCCTRL_DPLL_DPLLSTATUS_CLKRDY) ||
+			!sam.OSCCTRL.DPLL[0].DPLLSTATUS.HasBits(sam.OSCCTRL_DPLL_DPLLSTATUS_LOCK) {
+		}
+		setGCLK0Source(sam.GCLK_GENCTRL_SRC_DPLL0)
+	}
+
+	cpuFrequency = freq
+
+	for _, config := range spiGCLK0 {
+		if config.bus == nil {
+			continue
+		}
+		baudRate := (freq/2 + config.frequency - 1) / config.frequency
+		if baudRate > 256 {
+			baudRate = 256
+		}
+		// The BAUD register can only be written while the SPI is disabled.
+		config.bus.CTRLA.ClearBits(sam.SERCOM_SPIM_CTRLA_ENABLE)
+		for config.bus.SYNCBUSY.HasBits(sam.SERCOM_SPIM_SYNCBUSY_ENABLE) {
+		}
+		config.bus.BAUD.Set(uint8(baudRate - 1))
+		config.bus.CTRLA.SetBits(sam.SERCOM_SPIM_CTRLA_ENABLE)
+		for config.bus.SYNCBUSY.HasBits(sam.SERCOM_SPIM_SYNCBUSY_ENABLE) {
+		}
+	}
+	return nil
+}
+
+// setGCLK0Source switches the main clock generator to a different source.
+func setGCLK0Source(src uint32) {
+	sam.GCLK.GENCTRL[0].Set((src << sam.GCLK_GENCTRL_SRC_Pos) |
+		sam.GCLK_GENCTRL_IDC |
+		sam.GCLK_GENCTRL_GENEN)
+	for sam.GCLK.SYNCBUSY.HasBits(sam.GCLK_SYNCBUSY_GENCTRL_GCLK0) {
+	}
+}
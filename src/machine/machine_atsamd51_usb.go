@@ -9,6 +9,12 @@ import (
 	"unsafe"
 )
 
+// The USB peripheral has 8 endpoints, which all support every transfer type.
+const (
+	usbEndpointCount = 8
+	usbEndpointTypes = 1<<usb.ENDPOINT_TYPE_ISOCHRONOUS | 1<<usb.ENDPOINT_TYPE_BULK | 1<<usb.ENDPOINT_TYPE_INTERRUPT
+)
+
 const (
 	// these are SAMD51 specific.
 	usb_DEVICE_PCKSIZE_BYTE_COUNT_Pos  = 0
@@ -57,6 +63,11 @@ func (dev *USBDevice) Configure(config UARTConfig) {
 	// enable interrupt for start of frame
 	sam.USB_DEVICE.INTENSET.SetBits(sam.USB_DEVICE_INTENSET_SOF)
 
+	// enable interrupts for suspend, wakeup and end of resume
+	sam.USB_DEVICE.INTENSET.SetBits(sam.USB_DEVICE_INTENSET_SUSPEND |
+		sam.USB_DEVICE_INTENSET_WAKEUP |
+		sam.USB_DEVICE_INTENSET_EORSM)
+
 	// enable USB
 	sam.USB_DEVICE.CTRLA.SetBits(sam.USB_DEVICE_CTRLA_ENABLE)
 
@@ -123,11 +134,22 @@ func handleUSBIRQ(intr interrupt.Interrupt) {
 		initEndpoint(0, usb.ENDPOINT_TYPE_CONTROL)
 
 		usbConfiguration = 0
+		handleUSBBusReset()
 
 		// ack the End-Of-Reset interrupt
 		sam.USB_DEVICE.INTFLAG.Set(sam.USB_DEVICE_INTFLAG_EORST)
 	}
 
+	// Bus suspended by the host
+	if (flags & sam.USB_DEVICE_INTFLAG_SUSPEND) > 0 {
+		handleUSBSuspend()
+	}
+
+	// Bus activity after a suspend, or end of a resume signalled by the host
+	if (flags & (sam.USB_DEVICE_INTFLAG_WAKEUP | sam.USB_DEVICE_INTFLAG_EORSM)) > 0 {
+		handleUSBResume()
+	}
+
 	// Start of frame
 	if (flags & sam.USB_DEVICE_INTFLAG_SOF) > 0 {
 		// if you want to blink LED showing traffic, this would be the place...
@@ -194,6 +216,12 @@ func handleUSBIRQ(intr interrupt.Interrupt) {
 	}
 }
 
+// sendUSBRemoteWakeup drives resume signalling on the bus. The hardware stops
+// it by itself after the required time.
+func sendUSBRemoteWakeup() {
+	sam.USB_DEVICE.CTRLB.SetBits(sam.USB_DEVICE_CTRLB_UPRSM)
+}
+
 func initEndpoint(ep, config uint32) {
 	switch config {
 	case usb.ENDPOINT_TYPE_INTERRUPT | usb.EndpointIn:
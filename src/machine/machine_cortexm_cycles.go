@@ -0,0 +1,111 @@
+//go:build cortexm
+
+package machine
+
+import (
+	"device/arm"
+	"runtime/interrupt"
+	"runtime/volatile"
+	"unsafe"
+)
+
+// Registers of the DWT cycle counter, which is present on the Cortex-M3 and
+// newer cores but not on the Cortex-M0 and Cortex-M0+.
+var (
+	dwtCTRL   = (*volatile.Register32)(unsafe.Pointer(uintptr(0xe0001000)))
+	dwtCYCCNT = (*volatile.Register32)(unsafe.Pointer(uintptr(0xe0001004)))
+	dwtLAR    = (*volatile.Register32)(unsafe.Pointer(uintptr(0xe0001fb0)))
+	demCR     = (*volatile.Register32)(unsafe.Pointer(uintptr(0xe000edfc)))
+)
+
+const (
+	demCR_TRCENA     = 1 << 24
+	dwtCTRL_CYCCNTEN = 1 << 0
+	dwtCTRL_NOCYCCNT = 1 << 25
+)
+
+var (
+	// cycleCounter is 1 if the DWT cycle counter is enabled, -1 if there is
+	// none, and 0 if this hasn't been checked yet.
+	cycleCounter int8
+
+	// inTimingCritical is set while running a TimingCritical function.
+	inTimingCritical bool
+
+	// delayEnd is the cycle count at which the last delay in a
+	// TimingCritical function ended, or at which the function started.
+	delayEnd uint32
+)
+
+//export tinygo_delayLoop
+func delayLoop(iterations uint32)
+
+// hasCycleCounter returns whether the chip has a cycle counter, and enables it
+// the first time it is called.
+func hasCycleCounter() bool {
+	if cycleCounter == 0 {
+		cycleCounter = -1
+		// The cycle counter is part of ARMv7-M and ARMv8-M Mainline, and the
+		// NOCYCCNT bit tells whether it was left out of the chip anyway.
+		if arm.SCB.CPUID.Get()&arm.SCB_CPUID_ARCHITECTURE_Msk == 0xf<<arm.SCB_CPUID_ARCHITECTURE_Pos {
+			demCR.SetBits(demCR_TRCENA)
+			dwtLAR.Set(0xc5acce55) // unlock the DWT, needed on the Cortex-M7
+			if !dwtCTRL.HasBits(dwtCTRL_NOCYCCNT) {
+				dwtCTRL.SetBits(dwtCTRL_CYCCNTEN)
+				cycleCounter = 1
+			}
+		}
+	}
+	return cycleCounter > 0
+}
+
+// DelayCycles waits for at least the given number of CPU cycles, without
+// sleeping or letting other goroutines run. It is meant for bit-banged
+// protocols with timing requirements that are too short for time.Sleep, such
+// as WS2812 LEDs or DHT22 sensors.
+//
+// Cores with a cycle counter (Cortex-M3 and newer) wait until the counter has
+// advanced far enough. Inside a TimingCritical function the delay is counted
+// from the end of the previous delay instead of from the call, so that the
+// time spent in between (for example in code that runs slower from flash
+// because of wait states) doesn't add up. Other cores run a busy-wait loop from
+// RAM, which can't account for this.
+func DelayCycles(cycles uint32) {
+	if hasCycleCounter() {
+		start := dwtCYCCNT.Get()
+		if inTimingCritical {
+			start = delayEnd
+		}
+		end := start + cycles
+		for int32(dwtCYCCNT.Get()-end) < 0 {
+		}
+		delayEnd = end
+		return
+	}
+
+	// Cycles per iteration of tinygo_delayLoop.
+	cyclesPerIteration := uint32(3)
+	if arm.SCB.CPUID.Get()&arm.SCB_CPUID_PARTNO_Msk == 0xc20<<arm.SCB_CPUID_PARTNO_Pos {
+		cyclesPerIteration = 4 // Cortex-M0
+	}
+	if iterations := cycles / cyclesPerIteration; iterations != 0 {
+		delayLoop(iterations)
+	}
+}
+
+// TimingCritical calls fn with interrupts disabled, so that it can't be
+// interrupted while it bit-bangs a protocol with DelayCycles. It also makes
+// DelayCycles count delays from the end of the previous delay, see
+// DelayCycles. Interrupts are delayed until fn returns, so fn should not run
+// for longer than necessary.
+func TimingCritical(fn func()) {
+	mask := interrupt.Disable()
+	wasInTimingCritical := inTimingCritical
+	if !wasInTimingCritical && hasCycleCounter() {
+		delayEnd = dwtCYCCNT.Get()
+		inTimingCritical = true
+	}
+	fn()
+	inTimingCritical = wasInTimingCritical
+	interrupt.Restore(mask)
+}
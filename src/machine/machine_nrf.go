@@ -350,6 +350,7 @@ func (f flashBlockDevice) WriteAt(p []byte, off int64) (n int, err error) {
 		waitWhileFlashBusy()
 	}
 
+	recordFlashWrite(off, int64(len(padded)))
 	return len(padded), nil
 }
 
@@ -391,6 +392,7 @@ func (f flashBlockDevice) EraseBlocks(start, len int64) error {
 		address += uintptr(f.EraseBlockSize())
 	}
 
+	recordFlashErase(start, len)
 	return nil
 }
 
@@ -11,6 +11,13 @@ import (
 	"unsafe"
 )
 
+// The USB peripheral has 8 bulk/interrupt endpoints. The isochronous endpoint
+// (number 8) is not supported.
+const (
+	usbEndpointCount = 8
+	usbEndpointTypes = 1<<usb.ENDPOINT_TYPE_BULK | 1<<usb.ENDPOINT_TYPE_INTERRUPT
+)
+
 var (
 	sendOnEP0DATADONE struct {
 		ptr    *byte
@@ -20,6 +27,10 @@ var (
 	epinen      uint32
 	epouten     uint32
 	easyDMABusy volatile.Register8
+
+	// usbWakeupPending is set when a remote wakeup was requested while the
+	// peripheral was still in low power mode.
+	usbWakeupPending bool
 )
 
 // enterCriticalSection is used to protect access to easyDMA - only one thing
@@ -58,8 +69,8 @@ func (dev *USBDevice) Configure(config UARTConfig) {
 	intr.SetPriority(0x40) // interrupt priority 2 (lower number means more important)
 	intr.Enable()
 
-	// enable interrupt for end of reset and start of frame
-	nrf.USBD.INTEN.Set(nrf.USBD_INTENSET_USBEVENT)
+	// enable interrupt for USB events (ready, suspend, resume) and bus reset
+	nrf.USBD.INTEN.Set(nrf.USBD_INTENSET_USBEVENT | nrf.USBD_INTENSET_USBRESET)
 
 	// errata 187
 	// https://infocenter.nordicsemi.com/topic/errata_nRF52840_EngB/ERR/nRF52840/EngineeringB/latest/anomaly_840_187.html
@@ -94,10 +105,11 @@ func handleUSBIRQ(interrupt.Interrupt) {
 		// if you want to blink LED showing traffic, this would be the place...
 	}
 
-	// USBD ready event
+	// USBD ready, suspend and resume events
 	if nrf.USBD.EVENTS_USBEVENT.Get() == 1 {
 		nrf.USBD.EVENTS_USBEVENT.Set(0)
-		if (nrf.USBD.EVENTCAUSE.Get() & nrf.USBD_EVENTCAUSE_READY) > 0 {
+		cause := nrf.USBD.EVENTCAUSE.Get()
+		if (cause & nrf.USBD_EVENTCAUSE_READY) > 0 {
 
 			// Configure control endpoint
 			initEndpoint(0, usb.ENDPOINT_TYPE_CONTROL)
@@ -105,7 +117,32 @@ func handleUSBIRQ(interrupt.Interrupt) {
 
 			usbConfiguration = 0
 		}
-		nrf.USBD.EVENTCAUSE.Set(0)
+		if (cause & nrf.USBD_EVENTCAUSE_SUSPEND) > 0 {
+			// Reduce the power consumption of the peripheral while the bus
+			// is suspended.
+			nrf.USBD.LOWPOWER.Set(nrf.USBD_LOWPOWER_LOWPOWER_LowPower)
+			handleUSBSuspend()
+		}
+		if (cause & nrf.USBD_EVENTCAUSE_RESUME) > 0 {
+			nrf.USBD.LOWPOWER.Set(nrf.USBD_LOWPOWER_LOWPOWER_ForceNormal)
+			handleUSBResume()
+		}
+		if (cause&nrf.USBD_EVENTCAUSE_USBWUALLOWED) > 0 && usbWakeupPending {
+			// The peripheral left low power mode, so it is now possible to
+			// drive resume signalling on the bus.
+			usbWakeupPending = false
+			nrf.USBD.DPDMVALUE.Set(nrf.USBD_DPDMVALUE_STATE_Resume)
+			nrf.USBD.TASKS_DPDMDRIVE.Set(1)
+		}
+		nrf.USBD.EVENTCAUSE.Set(cause)
+	}
+
+	// USB bus reset
+	if nrf.USBD.EVENTS_USBRESET.Get() == 1 {
+		nrf.USBD.EVENTS_USBRESET.Set(0)
+		nrf.USBD.LOWPOWER.Set(nrf.USBD_LOWPOWER_LOWPOWER_ForceNormal)
+		usbWakeupPending = false
+		handleUSBBusReset()
 	}
 
 	if nrf.USBD.EVENTS_EP0DATADONE.Get() == 1 {
@@ -213,6 +250,14 @@ func parseUSBSetupRegisters() usb.Setup {
 	}
 }
 
+// sendUSBRemoteWakeup takes the peripheral out of low power mode. The resume
+// signalling itself is started from the interrupt handler once the peripheral
+// reports that it is allowed to do so.
+func sendUSBRemoteWakeup() {
+	usbWakeupPending = true
+	nrf.USBD.LOWPOWER.Set(nrf.USBD_LOWPOWER_LOWPOWER_ForceNormal)
+}
+
 func initEndpoint(ep, config uint32) {
 	switch config {
 	case usb.ENDPOINT_TYPE_INTERRUPT | usb.EndpointIn:
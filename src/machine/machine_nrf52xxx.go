@@ -8,6 +8,8 @@ import (
 	"unsafe"
 )
 
+// CPUFrequency returns the frequency of the CPU clock. It is fixed on the
+// nRF52, so there is no SetCPUFrequency.
 func CPUFrequency() uint32 {
 	return 64000000
 }
@@ -5,14 +5,202 @@ package machine
 import (
 	"device/arm"
 	"device/rp"
+	"errors"
+	"runtime/interrupt"
 	"runtime/volatile"
+	"time"
 	"unsafe"
 )
 
+// CPUFrequency returns the current system clock frequency, which is 125MHz
+// unless changed with SetCPUFrequency.
 func CPUFrequency() uint32 {
+	if freq := configuredFreq[clkSys]; freq != 0 {
+		return freq
+	}
 	return 125 * MHz
 }
 
+var errInvalidCPUFrequency = errors.New("machine: CPU frequency cannot be generated by the PLL")
+
+// Frequency above which the core voltage is raised, as the RP2040 is only
+// specified up to 133MHz at the default voltage.
+const overclockFreq = 133 * MHz
+
+type vregType struct {
+	vreg      volatile.Register32
+	bod       volatile.Register32
+	chipReset volatile.Register32
+}
+
+var vreg = (*vregType)(unsafe.Pointer(rp.VREG_AND_CHIP_RESET))
+
+const (
+	vregVSELPos  = 4
+	vregVSELMsk  = 0xf << vregVSELPos
+	vregVSEL1_10 = 0xb // 1.10V, the default
+	vregVSEL1_15 = 0xc // 1.15V
+	vregVSEL1_20 = 0xd // 1.20V
+)
+
+// SetCPUFrequency changes the system clock frequency, for example to
+// overclock the chip or to reduce power consumption. The frequency must be
+// one that can be generated by the system PLL from the 12MHz crystal, such as
+// 48MHz, 125MHz, 133MHz or 200MHz. Frequencies up to around 250MHz usually
+// work, but are outside of the RP2040 specifications.
+//
+// The core voltage is raised for frequencies above 133MHz, and the flash clock
+// divider is increased when the flash clock would be too fast. The peripheral
+// clock follows the system clock: the baud rates of enabled UART, SPI and I2C
+// peripherals are recalculated so they keep running at the same speed, but PWM
+// peripherals must be configured again. The time base is derived from the
+// crystal and is not affected.
+func SetCPUFrequency(freq uint32) error {
+	vco, postDiv1, postDiv2, ok := pllSysConfig(freq)
+	if !ok {
+		return errInvalidCPUFrequency
+	}
+
+	if freq > overclockFreq {
+		vsel := uint32(vregVSEL1_15)
+		if freq > 200*MHz {
+			vsel = vregVSEL1_20
+		}
+		if (vreg.vreg.Get()&vregVSELMsk)>>vregVSELPos < vsel {
+			vreg.vreg.ReplaceBits(vsel<<vregVSELPos, vregVSELMsk, 0)
+			// Wait for the voltage to settle.
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	mask := interrupt.Disable()
+	defer interrupt.Restore(mask)
+
+	// Read the baud rates of the peripherals while the old frequency is still
+	// known, to restore them after the frequency change.
+	bauds := readPeripheralBaudRates()
+
+	// Increase the flash clock divider before increasing the frequency, and
+	// decrease it after decreasing the frequency.
+	flashDiv := flashClockDivider(freq)
+	if flashDiv > xipSSI.baudr.Get() {
+		setFlashClockDivider(flashDiv)
+	}
+
+	// Switch away from the PLL while it is reconfigured.
+	clksys := clocks.clock(clkSys)
+	clksys.configure(0, // clk_ref
+		0,
+		12*MHz,
+		12*MHz)
+
+	pllSys.init(1, vco, postDiv1, postDiv2)
+
+	clksys.configure(rp.CLOCKS_CLK_SYS_CTRL_SRC_CLKSRC_CLK_SYS_AUX,
+		rp.CLOCKS_CLK_SYS_CTRL_AUXSRC_CLKSRC_PLL_SYS,
+		freq,
+		freq)
+
+	clkperi := clocks.clock(clkPeri)
+	clkperi.configure(0,
+		rp.CLOCKS_CLK_PERI_CTRL_AUXSRC_CLK_SYS,
+		freq,
+		freq)
+
+	if flashDiv < xipSSI.baudr.Get() {
+		setFlashClockDivider(flashDiv)
+	}
+
+	bauds.restore()
+	return nil
+}
+
+// peripheralBaudRates stores the baud rates of the UART, SPI and I2C
+// peripherals, which all run from clk_peri or clk_sys. A zero baud rate means
+// the peripheral is not enabled.
+type peripheralBaudRates struct {
+	uart [2]uint32
+	spi  [2]uint32
+	i2c  [2]uint32
+}
+
+var (
+	uartBuses = [2]*rp.UART0_Type{rp.UART0, rp.UART1}
+	spiBuses  = [2]*rp.SPI0_Type{rp.SPI0, rp.SPI1}
+	i2cBuses  = [2]*rp.I2C0_Type{rp.I2C0, rp.I2C1}
+)
+
+// readPeripheralBaudRates reads back the baud rates of all enabled UART, SPI
+// and I2C peripherals from their divider registers.
+func readPeripheralBaudRates() (bauds peripheralBaudRates) {
+	freq := uint64(CPUFrequency())
+	for i, bus := range uartBuses {
+		if !bus.UARTCR.HasBits(rp.UART0_UARTCR_UARTEN) {
+			continue
+		}
+		// baud = freq / (16 * (ibrd + fbrd/64))
+		div := 64*uint64(bus.UARTIBRD.Get()) + uint64(bus.UARTFBRD.Get())
+		bauds.uart[i] = uint32((4*freq + div/2) / div)
+	}
+	for i, bus := range spiBuses {
+		if !bus.SSPCR1.HasBits(rp.SPI0_SSPCR1_SSE) {
+			continue
+		}
+		bauds.spi[i] = SPI{Bus: bus}.GetBaudRate()
+	}
+	for i, bus := range i2cBuses {
+		if bus.IC_ENABLE.Get()&rp.I2C0_IC_ENABLE_ENABLE_Msk == 0 {
+			continue
+		}
+		period := uint64(bus.IC_FS_SCL_HCNT.Get()) + uint64(bus.IC_FS_SCL_LCNT.Get())
+		bauds.i2c[i] = uint32((freq + period/2) / period)
+	}
+	return
+}
+
+// restore sets the baud rates of the peripherals again, using the current
+// CPU frequency.
+func (bauds *peripheralBaudRates) restore() {
+	for i, bus := range uartBuses {
+		if bauds.uart[i] != 0 {
+			uart := UART{Bus: bus}
+			uart.SetBaudRate(bauds.uart[i])
+		}
+	}
+	for i, bus := range spiBuses {
+		if bauds.spi[i] != 0 {
+			SPI{Bus: bus}.SetBaudRate(bauds.spi[i])
+		}
+	}
+	for i, bus := range i2cBuses {
+		if bauds.i2c[i] != 0 {
+			i2c := I2C{Bus: bus}
+			i2c.SetBaudRate(bauds.i2c[i])
+		}
+	}
+}
+
+// pllSysConfig returns the VCO frequency and post dividers of the system PLL
+// to generate the given frequency from the 12MHz reference, preferring a high
+// VCO frequency for lower jitter (like the Pico SDK).
+func pllSysConfig(freq uint32) (vco, postDiv1, postDiv2 uint32, ok bool) {
+	const refFreq = xoscFreq * MHz
+	for fbdiv := uint32(320); fbdiv >= 16; fbdiv-- {
+		vco = fbdiv * refFreq
+		if vco < 750*MHz || vco > 1600*MHz {
+			continue
+		}
+		for postDiv1 = 7; postDiv1 >= 1; postDiv1-- {
+			for postDiv2 = postDiv1; postDiv2 >= 1; postDiv2-- {
+				if vco%(postDiv1*postDiv2) == 0 && vco/(postDiv1*postDiv2) == freq {
+					return vco, postDiv1, postDiv2, true
+				}
+			}
+		}
+	}
+	return 0, 0, 0, false
+}
+
 // Returns the period of a clock cycle for the raspberry pi pico in nanoseconds.
 // Used in PWM API.
 func cpuPeriod() uint32 {
@@ -187,6 +187,7 @@ func (f flashBlockDevice) WriteAt(p []byte, off int64) (n int, err error) {
 		(*C.uint8_t)(unsafe.Pointer(&padded[0])),
 		C.ulong(len(padded)))
 
+	recordFlashWrite(off, int64(len(padded)))
 	return len(padded), nil
 }
 
@@ -231,6 +232,7 @@ func (f flashBlockDevice) EraseBlocks(start, length int64) error {
 
 	C.flash_erase_blocks(C.uint32_t(address), C.ulong(length*f.EraseBlockSize()))
 
+	recordFlashErase(start, length)
 	return nil
 }
 
@@ -5,6 +5,7 @@ package machine
 import (
 	"device/rp"
 	"runtime/interrupt"
+	"runtime/volatile"
 )
 
 // UART on the RP2040.
@@ -12,6 +13,9 @@ type UART struct {
 	Buffer    *RingBuffer
 	Bus       *rp.UART0_Type
 	Interrupt interrupt.Interrupt
+
+	halfDuplex    bool
+	breakReceived volatile.Register8
 }
 
 // Configure the UART.
@@ -60,7 +64,7 @@ func (uart *UART) Configure(config UARTConfig) error {
 
 // SetBaudRate sets the baudrate to be used for the UART.
 func (uart *UART) SetBaudRate(br uint32) {
-	div := 8 * 125 * MHz / br
+	div := 8 * CPUFrequency() / br
 
 	ibrd := div >> 7
 	var fbrd uint32
@@ -87,15 +91,69 @@ func (uart *UART) SetBaudRate(br uint32) {
 
 // WriteByte writes a byte of data to the UART.
 func (uart *UART) WriteByte(c byte) error {
+	if uart.halfDuplex {
+		uart.Bus.UARTCR.ClearBits(rp.UART0_UARTCR_RXE)
+	}
+
 	// wait until buffer is not full
 	for uart.Bus.UARTFR.HasBits(rp.UART0_UARTFR_TXFF) {
 	}
 
 	// write data
 	uart.Bus.UARTDR.Set(uint32(c))
+
+	if uart.halfDuplex {
+		// Only start listening again once the byte has been sent.
+		for uart.Bus.UARTFR.HasBits(rp.UART0_UARTFR_BUSY) {
+		}
+		uart.Bus.UARTCR.SetBits(rp.UART0_UARTCR_RXE)
+	}
 	return nil
 }
 
+// SetHalfDuplex enables or disables half-duplex mode, for buses with a single
+// data line such as LIN and SDI-12. The RP2040 can't send and receive on the
+// same pin, so both TX and RX must be connected to the data line, usually
+// through a transceiver. In half-duplex mode the receiver is disabled while
+// sending, so that the UART doesn't receive the bytes it sends itself.
+func (uart *UART) SetHalfDuplex(enable bool) {
+	uart.halfDuplex = enable
+}
+
+// SetBreak starts or stops sending a break: while a break is sent, the TX line
+// is held low. LIN and SDI-12 start a message with a break of a minimum
+// length, which must be timed by the caller:
+//
+//	uart.SetBreak(true)
+//	time.Sleep(12 * time.Millisecond)
+//	uart.SetBreak(false)
+func (uart *UART) SetBreak(enable bool) {
+	// Wait until all data has been sent, as the break would corrupt it.
+	for uart.Bus.UARTFR.HasBits(rp.UART0_UARTFR_BUSY) {
+	}
+	if enable {
+		if uart.halfDuplex {
+			uart.Bus.UARTCR.ClearBits(rp.UART0_UARTCR_RXE)
+		}
+		uart.Bus.UARTLCR_H.SetBits(rp.UART0_UARTLCR_H_BRK)
+	} else {
+		uart.Bus.UARTLCR_H.ClearBits(rp.UART0_UARTLCR_H_BRK)
+		if uart.halfDuplex {
+			uart.Bus.UARTCR.SetBits(rp.UART0_UARTCR_RXE)
+		}
+	}
+}
+
+// BreakReceived returns whether a break was received since the last call. The
+// break itself is not stored in the RX buffer.
+func (uart *UART) BreakReceived() bool {
+	mask := interrupt.Disable()
+	received := uart.breakReceived.Get() != 0
+	uart.breakReceived.Set(0)
+	interrupt.Restore(mask)
+	return received
+}
+
 // SetFormat for number of data bits, stop bits, and parity for the UART.
 func (uart *UART) SetFormat(databits, stopbits uint8, parity UARTParity) error {
 	var pen, pev uint8
@@ -133,5 +191,11 @@ func initUART(uart *UART) {
 func (uart *UART) handleInterrupt(interrupt.Interrupt) {
 	for uart.Bus.UARTFR.HasBits(rp.UART0_UARTFR_RXFE) {
 	}
-	uart.Receive(byte((uart.Bus.UARTDR.Get() & 0xFF)))
+	data := uart.Bus.UARTDR.Get()
+	if data&rp.UART0_UARTDR_BE != 0 {
+		// A break is received as a zero byte with the break error flag set.
+		uart.breakReceived.Set(1)
+		return
+	}
+	uart.Receive(byte((data & 0xFF)))
 }
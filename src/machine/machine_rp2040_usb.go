@@ -10,6 +10,12 @@ import (
 	"unsafe"
 )
 
+// The USB controller has 16 endpoints, which all support every transfer type.
+const (
+	usbEndpointCount = 16
+	usbEndpointTypes = 1<<usb.ENDPOINT_TYPE_ISOCHRONOUS | 1<<usb.ENDPOINT_TYPE_BULK | 1<<usb.ENDPOINT_TYPE_INTERRUPT
+)
+
 var (
 	sendOnEP0DATADONE struct {
 		offset int
@@ -47,10 +53,12 @@ func (dev *USBDevice) Configure(config UARTConfig) {
 	rp.USBCTRL_REGS.SIE_CTRL.Set(rp.USBCTRL_REGS_SIE_CTRL_EP0_INT_1BUF)
 
 	// Enable interrupts for when a buffer is done, when the bus is reset,
-	// and when a setup packet is received
+	// when a setup packet is received and when the bus is suspended or resumed
 	rp.USBCTRL_REGS.INTE.Set(rp.USBCTRL_REGS_INTE_BUFF_STATUS |
 		rp.USBCTRL_REGS_INTE_BUS_RESET |
-		rp.USBCTRL_REGS_INTE_SETUP_REQ)
+		rp.USBCTRL_REGS_INTE_SETUP_REQ |
+		rp.USBCTRL_REGS_INTE_DEV_SUSPEND |
+		rp.USBCTRL_REGS_INTE_DEV_RESUME_FROM_HOST)
 
 	// Present full speed device by enabling pull up on DP
 	rp.USBCTRL_REGS.SIE_CTRL.SetBits(rp.USBCTRL_REGS_SIE_CTRL_PULLUP_EN)
@@ -131,7 +139,27 @@ func handleUSBIRQ(intr interrupt.Interrupt) {
 
 		rp.USBCTRL_REGS.ADDR_ENDP.Set(0)
 		initEndpoint(0, usb.ENDPOINT_TYPE_CONTROL)
+		handleUSBBusReset()
+	}
+
+	// Bus is suspended by the host
+	if (status & rp.USBCTRL_REGS_INTS_DEV_SUSPEND) > 0 {
+		rp.USBCTRL_REGS.SIE_STATUS.Set(rp.USBCTRL_REGS_SIE_STATUS_SUSPENDED)
+		handleUSBSuspend()
 	}
+
+	// Bus is resumed by the host
+	if (status & rp.USBCTRL_REGS_INTS_DEV_RESUME_FROM_HOST) > 0 {
+		rp.USBCTRL_REGS.SIE_STATUS.Set(rp.USBCTRL_REGS_SIE_STATUS_RESUME)
+		handleUSBResume()
+	}
+}
+
+// sendUSBRemoteWakeup drives resume signalling on the bus. The hardware stops
+// it by itself after the required time.
+func sendUSBRemoteWakeup() {
+	rp.USBCTRL_REGS.SIE_CTRL.SetBits(rp.USBCTRL_REGS_SIE_CTRL_RESUME)
+	handleUSBResume()
 }
 
 func initEndpoint(ep, config uint32) {
@@ -0,0 +1,109 @@
+//go:build rp2040
+
+package machine
+
+import (
+	"device/rp"
+	"runtime/volatile"
+	"unsafe"
+)
+
+// The XIP (execute in place) cache is a 16kB cache in front of the external
+// QSPI flash, from which all code and read-only data is read.
+
+type xipCtrlType struct {
+	ctrl       volatile.Register32
+	flush      volatile.Register32
+	stat       volatile.Register32
+	ctrHit     volatile.Register32
+	ctrAcc     volatile.Register32
+	streamAddr volatile.Register32
+	streamCtr  volatile.Register32
+	streamFifo volatile.Register32
+}
+
+var xipCtrl = (*xipCtrlType)(unsafe.Pointer(rp.XIP_CTRL))
+
+const (
+	xipCtrlEN         = 1 << 0
+	xipStatFlushReady = 1 << 0
+)
+
+// FlushXIPCache invalidates the XIP cache, for example after the flash has
+// been written to without using machine.Flash (which flushes the cache
+// itself). It blocks until the flush has completed.
+func FlushXIPCache() {
+	xipCtrl.flush.Set(1)
+	// Reading FLUSH blocks until the flush has completed, but check the
+	// status as well in case the read is optimized differently.
+	xipCtrl.flush.Get()
+	for !xipCtrl.stat.HasBits(xipStatFlushReady) {
+	}
+}
+
+// EnableXIPCache enables or disables the XIP cache. The cache is enabled at
+// reset. Disabling it makes all code run (much) slower, but gives more
+// predictable timing and allows the cache memory to be used as 16kB of extra
+// RAM at 0x15000000.
+func EnableXIPCache(enable bool) {
+	if enable {
+		xipCtrl.ctrl.SetBits(xipCtrlEN)
+	} else {
+		xipCtrl.ctrl.ClearBits(xipCtrlEN)
+	}
+}
+
+// XIPCacheStats returns the number of cache hits and the total number of
+// cacheable accesses since the last call to ResetXIPCacheStats (or reset).
+func XIPCacheStats() (hits, accesses uint32) {
+	return xipCtrl.ctrHit.Get(), xipCtrl.ctrAcc.Get()
+}
+
+// ResetXIPCacheStats resets the counters returned by XIPCacheStats.
+func ResetXIPCacheStats() {
+	// Both counters are cleared by writing any value.
+	xipCtrl.ctrHit.Set(0)
+	xipCtrl.ctrAcc.Set(0)
+}
+
+// The SSI peripheral that reads from flash. It is configured by the second
+// stage bootloader, only the clock divider is changed afterwards.
+type xipSSIType struct {
+	ctrlr0 volatile.Register32
+	ctrlr1 volatile.Register32
+	ssienr volatile.Register32
+	mwcr   volatile.Register32
+	ser    volatile.Register32
+	baudr  volatile.Register32
+}
+
+const xipSSIBase = 0x18000000
+
+var xipSSI = (*xipSSIType)(unsafe.Pointer(uintptr(xipSSIBase)))
+
+// Maximum clock frequency of the flash chips on common RP2040 boards.
+const maxFlashFreq = 133 * MHz
+
+// flashClockDivider returns the smallest clock divider (which must be even)
+// that keeps the flash clock within spec at the given system clock frequency.
+func flashClockDivider(sysFreq uint32) uint32 {
+	div := (sysFreq + maxFlashFreq - 1) / maxFlashFreq
+	div += div & 1
+	if div < 2 {
+		div = 2
+	}
+	return div
+}
+
+// setFlashClockDivider changes the flash clock divider. The SSI must be
+// disabled to do so, which means this code must run from RAM with interrupts
+// disabled, and it must not read any global (which may be stored in flash).
+//
+//go:ramfunc
+func setFlashClockDivider(div uint32) {
+	ssienr := (*uint32)(unsafe.Pointer(uintptr(xipSSIBase + 0x08)))
+	baudr := (*uint32)(unsafe.Pointer(uintptr(xipSSIBase + 0x14)))
+	volatile.StoreUint32(ssienr, 0)
+	volatile.StoreUint32(baudr, div)
+	volatile.StoreUint32(ssienr, 1)
+}
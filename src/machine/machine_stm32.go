@@ -7,6 +7,10 @@ import "device/stm32"
 const deviceName = stm32.Device
 
 // Peripheral abstraction layer for the stm32.
+//
+// The clocks are configured once at startup by the initCLK function of each
+// family, so there is no SetCPUFrequency: changing the clock at runtime would
+// need a PLL and flash wait state configuration for every family.
 
 const (
 	portA Pin = iota * 16
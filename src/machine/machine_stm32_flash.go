@@ -41,7 +41,9 @@ func (f flashBlockDevice) WriteAt(p []byte, off int64) (n int, err error) {
 	unlockFlash()
 	defer lockFlash()
 
-	return writeFlashData(FlashDataStart()+uintptr(off), f.pad(p))
+	n, err = writeFlashData(FlashDataStart()+uintptr(off), f.pad(p))
+	recordFlashWrite(off, int64(n))
+	return n, err
 }
 
 // Size returns the number of bytes in this block device.
@@ -83,10 +85,12 @@ func (f flashBlockDevice) EraseBlocks(start, len int64) error {
 
 	for i := blk; i < blk+len; i++ {
 		if err := eraseBlock(uint32(i)); err != nil {
+			recordFlashErase(start, i-blk)
 			return err
 		}
 	}
 
+	recordFlashErase(start, len)
 	return nil
 }
 
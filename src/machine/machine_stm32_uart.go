@@ -20,10 +20,14 @@ type UART struct {
 	RxAltFuncSelector uint8
 
 	// Registers specific to the chip
-	rxReg       *volatile.Register32
-	txReg       *volatile.Register32
-	statusReg   *volatile.Register32
-	txEmptyFlag uint32
+	rxReg          *volatile.Register32
+	txReg          *volatile.Register32
+	statusReg      *volatile.Register32
+	txEmptyFlag    uint32
+	txCompleteFlag uint32
+
+	config     UARTConfig
+	halfDuplex bool
 }
 
 // Configure the UART.
@@ -48,6 +52,7 @@ func (uart *UART) Configure(config UARTConfig) {
 	enableAltFuncClock(unsafe.Pointer(uart.Bus))
 
 	uart.configurePins(config)
+	uart.config = config
 
 	// Set baud rate
 	uart.SetBaudRate(config.BaudRate)
@@ -75,9 +80,66 @@ func (uart *UART) SetBaudRate(br uint32) {
 
 // WriteByte writes a byte of data to the UART.
 func (uart *UART) WriteByte(c byte) error {
+	if uart.halfDuplex {
+		uart.Bus.CR1.ClearBits(stm32.USART_CR1_RE)
+	}
+
 	uart.txReg.Set(uint32(c))
 
 	for !uart.statusReg.HasBits(uart.txEmptyFlag) {
 	}
+
+	if uart.halfDuplex {
+		// Only start listening again once the byte has been sent.
+		for !uart.statusReg.HasBits(uart.txCompleteFlag) {
+		}
+		uart.Bus.CR1.SetBits(stm32.USART_CR1_RE)
+	}
 	return nil
 }
+
+// SetHalfDuplex enables or disables half-duplex mode, for buses with a single
+// data line such as LIN and SDI-12. In half-duplex mode, only the TX pin is
+// used: it is released while not sending so that it can be used to receive
+// data. The receiver is disabled while sending, so that the UART doesn't
+// receive the bytes it sends itself.
+func (uart *UART) SetHalfDuplex(enable bool) {
+	// The half-duplex selection can only be changed while the USART is
+	// disabled.
+	uart.Bus.CR1.ClearBits(stm32.USART_CR1_UE)
+	if enable {
+		uart.Bus.CR3.SetBits(stm32.USART_CR3_HDSEL)
+	} else {
+		uart.Bus.CR3.ClearBits(stm32.USART_CR3_HDSEL)
+	}
+	uart.Bus.CR1.SetBits(stm32.USART_CR1_UE)
+	uart.halfDuplex = enable
+}
+
+// SetBreak starts or stops sending a break: while a break is sent, the TX line
+// is held low. LIN and SDI-12 start a message with a break of a minimum
+// length, which must be timed by the caller:
+//
+//	uart.SetBreak(true)
+//	time.Sleep(12 * time.Millisecond)
+//	uart.SetBreak(false)
+//
+// The break request of the USART itself sends a break of a fixed length which
+// is too short for SDI-12, so the TX pin is driven low as a GPIO pin instead.
+func (uart *UART) SetBreak(enable bool) {
+	// Wait until all data has been sent, as the break would corrupt it.
+	for !uart.statusReg.HasBits(uart.txCompleteFlag) {
+	}
+	if enable {
+		if uart.halfDuplex {
+			uart.Bus.CR1.ClearBits(stm32.USART_CR1_RE)
+		}
+		uart.config.TX.Configure(PinConfig{Mode: PinOutput})
+		uart.config.TX.Low()
+	} else {
+		uart.configurePins(uart.config)
+		if uart.halfDuplex {
+			uart.Bus.CR1.SetBits(stm32.USART_CR1_RE)
+		}
+	}
+}
@@ -661,6 +661,7 @@ func (uart *UART) setRegisters() {
 	uart.txReg = &uart.Bus.DR
 	uart.statusReg = &uart.Bus.SR
 	uart.txEmptyFlag = stm32.USART_SR_TXE
+	uart.txCompleteFlag = stm32.USART_SR_TC
 }
 
 // -- SPI ----------------------------------------------------------------------
@@ -222,6 +222,7 @@ func (uart *UART) setRegisters() {
 	uart.txReg = &uart.Bus.TDR
 	uart.statusReg = &uart.Bus.ISR
 	uart.txEmptyFlag = stm32.USART_ISR_TXE
+	uart.txCompleteFlag = stm32.USART_ISR_TC
 }
 
 //---------- SPI related types and code
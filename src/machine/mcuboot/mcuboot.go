@@ -0,0 +1,160 @@
+// Package mcuboot reads and updates the image trailer that the MCUboot
+// bootloader keeps at the end of each image slot. It can be used to confirm a
+// newly installed image, so that MCUboot doesn't revert it on the next reset,
+// and to mark a new image in the secondary slot as pending.
+//
+// Images for MCUboot can be created with the -mcuboot-key flag:
+//
+//	tinygo build -target=<board> -mcuboot-key=key.pem -o app.signed.bin
+//
+// A typical over-the-air update looks like this:
+//
+//  1. Write the new image to the secondary slot and call RequestUpgrade on it.
+//  2. Reset. MCUboot swaps the two slots and starts the new image.
+//  3. The new image checks that it works and calls Confirm on the primary slot.
+//
+// If the new image is never confirmed, MCUboot reverts to the old image on the
+// next reset. So to revert an unconfirmed image, reset without calling Confirm.
+//
+// This package only supports the default MCUboot configuration with a maximum
+// write alignment of 8 bytes (BOOT_MAX_ALIGN).
+package mcuboot
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+var (
+	ErrInvalidMagic = errors.New("mcuboot: invalid magic in image trailer")
+	ErrNotErased    = errors.New("mcuboot: image trailer is not erased")
+)
+
+// Device is the flash device that holds the image slots, for example
+// machine.Flash.
+type Device interface {
+	io.ReaderAt
+	io.WriterAt
+}
+
+// Slot is an MCUboot image slot on a flash device. Only the trailer at the end
+// of the slot is read or written, so Offset may be negative if the device
+// doesn't start at the start of the slot (for example, machine.Flash starts at
+// the end of the running program).
+type Slot struct {
+	Device Device
+	Offset int64 // offset of the slot on the device
+	Size   int64 // size of the slot in bytes
+}
+
+const (
+	maxAlign  = 8
+	magicSize = 16
+
+	flagSet   = 0x01
+	flagUnset = 0xff
+)
+
+// Magic value at the end of a slot, see boot_img_magic in MCUboot.
+var magic = [magicSize]byte{
+	0x77, 0xc2, 0x95, 0xf3,
+	0x60, 0xd2, 0xef, 0x7f,
+	0x35, 0x52, 0x50, 0x0f,
+	0x2c, 0xb6, 0x79, 0x80,
+}
+
+func (s Slot) magicOffset() int64 {
+	return s.Offset + s.Size - magicSize
+}
+
+func (s Slot) imageOKOffset() int64 {
+	return s.magicOffset() - maxAlign
+}
+
+// readMagic returns whether the slot trailer has a valid magic, or an error if
+// it is neither valid nor erased.
+func (s Slot) readMagic() (bool, error) {
+	var buf [magicSize]byte
+	if _, err := s.Device.ReadAt(buf[:], s.magicOffset()); err != nil {
+		return false, err
+	}
+	if buf == magic {
+		return true, nil
+	}
+	if bytes.Count(buf[:], []byte{0xff}) == magicSize {
+		return false, nil
+	}
+	return false, ErrInvalidMagic
+}
+
+func (s Slot) readImageOK() (byte, error) {
+	var buf [1]byte
+	_, err := s.Device.ReadAt(buf[:], s.imageOKOffset())
+	return buf[0], err
+}
+
+// IsConfirmed returns whether the image in this slot is confirmed, that is,
+// whether MCUboot will keep running it after the next reset. An image that
+// was installed without a swap (for example by flashing it directly) is always
+// confirmed.
+func (s Slot) IsConfirmed() (bool, error) {
+	ok, err := s.readMagic()
+	if err != nil || !ok {
+		return !ok, err
+	}
+	imageOK, err := s.readImageOK()
+	return imageOK == flagSet, err
+}
+
+// Confirm marks the image in this slot (normally the primary slot) as good,
+// so that MCUboot doesn't revert it on the next reset. It does nothing if the
+// image is already confirmed.
+func (s Slot) Confirm() error {
+	confirmed, err := s.IsConfirmed()
+	if err != nil || confirmed {
+		return err
+	}
+	imageOK, err := s.readImageOK()
+	if err != nil {
+		return err
+	}
+	if imageOK != flagUnset {
+		return ErrNotErased
+	}
+	_, err = s.Device.WriteAt([]byte{flagSet}, s.imageOKOffset())
+	return err
+}
+
+// RequestUpgrade marks the image in this slot (normally the secondary slot) as
+// pending, so that MCUboot swaps it into the primary slot on the next reset. If
+// permanent is false, the new image must confirm itself or it is reverted on
+// the reset after that. If permanent is true, the new image is confirmed
+// immediately.
+//
+// The trailer of the slot must be erased, which is the case after erasing the
+// whole slot and writing the new image to it.
+func (s Slot) RequestUpgrade(permanent bool) error {
+	ok, err := s.readMagic()
+	if err != nil {
+		return err
+	}
+	if ok {
+		// Already pending.
+		return nil
+	}
+	if permanent {
+		imageOK, err := s.readImageOK()
+		if err != nil {
+			return err
+		}
+		if imageOK != flagUnset {
+			return ErrNotErased
+		}
+		if _, err := s.Device.WriteAt([]byte{flagSet}, s.imageOKOffset()); err != nil {
+			return err
+		}
+	}
+	_, err = s.Device.WriteAt(magic[:], s.magicOffset())
+	return err
+}
@@ -0,0 +1,287 @@
+// Package onewire implements the 1-Wire bus used by sensors such as the
+// DS18B20 temperature sensor. The bus can be driven by a pin with a pull-up
+// resistor (NewPin), or by a UART with TX and RX connected to the data line
+// (NewUART):
+//
+//	bus := onewire.NewPin(machine.D2)
+//	roms, err := bus.Search(false)
+//	err = bus.Select(roms[0])
+//	err = bus.WriteByte(0x44) // DS18B20: convert temperature
+//
+// Only standard speed is supported, not overdrive.
+package onewire
+
+import (
+	"errors"
+)
+
+var (
+	ErrNoPresence = errors.New("onewire: no device present")
+	ErrBusLow     = errors.New("onewire: bus is held low")
+	ErrTimeout    = errors.New("onewire: timeout")
+	ErrSearch     = errors.New("onewire: devices changed during search")
+	ErrCRC        = errors.New("onewire: CRC mismatch")
+)
+
+// ROM commands.
+const (
+	cmdSearchROM   = 0xf0
+	cmdSearchAlarm = 0xec
+	cmdReadROM     = 0x33
+	cmdMatchROM    = 0x55
+	cmdSkipROM     = 0xcc
+)
+
+// ROM is the 64-bit ROM code that identifies a device on the bus: the family
+// code, a 48-bit serial number and a CRC, in the order in which they are sent
+// on the bus.
+type ROM [8]byte
+
+// Family returns the family code of the device, such as 0x28 for a DS18B20.
+func (rom ROM) Family() byte {
+	return rom[0]
+}
+
+// Valid returns whether the CRC of the ROM code is correct.
+func (rom ROM) Valid() bool {
+	return CRC8(rom[:7]) == rom[7]
+}
+
+// driver sends and receives bits on the bus.
+type driver interface {
+	// reset sends a reset pulse and returns whether a device responded with
+	// a presence pulse.
+	reset() (bool, error)
+
+	// touchBit writes a bit and returns the bit that was read back in the same
+	// time slot. Writing a 1 bit is also how a bit is read from a device.
+	touchBit(bit bool) (bool, error)
+}
+
+// Bus is a 1-Wire bus.
+type Bus struct {
+	driver driver
+}
+
+// Reset resets all devices on the bus, and returns ErrNoPresence if there is
+// none.
+func (bus *Bus) Reset() error {
+	present, err := bus.driver.reset()
+	if err != nil {
+		return err
+	}
+	if !present {
+		return ErrNoPresence
+	}
+	return nil
+}
+
+// WriteByte writes a byte, least significant bit first.
+func (bus *Bus) WriteByte(b byte) error {
+	for i := 0; i < 8; i++ {
+		if _, err := bus.driver.touchBit(b&(1<<i) != 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadByte reads a byte, least significant bit first.
+func (bus *Bus) ReadByte() (byte, error) {
+	var b byte
+	for i := 0; i < 8; i++ {
+		bit, err := bus.driver.touchBit(true)
+		if err != nil {
+			return 0, err
+		}
+		if bit {
+			b |= 1 << i
+		}
+	}
+	return b, nil
+}
+
+// Write writes all bytes in p.
+func (bus *Bus) Write(p []byte) (n int, err error) {
+	for n < len(p) {
+		if err := bus.WriteByte(p[n]); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Read fills p with bytes read from the bus.
+func (bus *Bus) Read(p []byte) (n int, err error) {
+	for n < len(p) {
+		p[n], err = bus.ReadByte()
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Select resets the bus and selects the device with the given ROM code. The
+// following commands are only received by that device.
+func (bus *Bus) Select(rom ROM) error {
+	if err := bus.Reset(); err != nil {
+		return err
+	}
+	if err := bus.WriteByte(cmdMatchROM); err != nil {
+		return err
+	}
+	_, err := bus.Write(rom[:])
+	return err
+}
+
+// Skip resets the bus and selects all devices on it. The following commands
+// are received by all devices, so reading only works if there is just one.
+func (bus *Bus) Skip() error {
+	if err := bus.Reset(); err != nil {
+		return err
+	}
+	return bus.WriteByte(cmdSkipROM)
+}
+
+// ReadROM returns the ROM code of the device on the bus. It only works if
+// there is a single device on the bus, use Search otherwise.
+func (bus *Bus) ReadROM() (ROM, error) {
+	var rom ROM
+	if err := bus.Reset(); err != nil {
+		return rom, err
+	}
+	if err := bus.WriteByte(cmdReadROM); err != nil {
+		return rom, err
+	}
+	if _, err := bus.Read(rom[:]); err != nil {
+		return rom, err
+	}
+	if !rom.Valid() {
+		return rom, ErrCRC
+	}
+	return rom, nil
+}
+
+// Search returns the ROM codes of all devices on the bus, or only of those
+// with an active alarm condition if alarm is true. It returns an empty list
+// and no error if there are no devices.
+func (bus *Bus) Search(alarm bool) ([]ROM, error) {
+	command := byte(cmdSearchROM)
+	if alarm {
+		command = cmdSearchAlarm
+	}
+
+	// This is the search algorithm of Maxim application note 187. Each pass
+	// finds one device, by walking down a binary tree of the ROM code bits:
+	// at each bit where devices disagree (a discrepancy), the pass takes the
+	// branch it didn't take in the previous pass.
+	var roms []ROM
+	var rom ROM
+	lastDiscrepancy := -1
+	for {
+		present, err := bus.driver.reset()
+		if err != nil {
+			return roms, err
+		}
+		if !present {
+			return roms, nil
+		}
+		if err := bus.WriteByte(command); err != nil {
+			return roms, err
+		}
+
+		discrepancy := -1
+		for i := 0; i < 64; i++ {
+			// All devices send the bit and then its complement.
+			bit, err := bus.driver.touchBit(true)
+			if err != nil {
+				return roms, err
+			}
+			complement, err := bus.driver.touchBit(true)
+			if err != nil {
+				return roms, err
+			}
+
+			var direction bool
+			switch {
+			case bit && complement:
+				// No device is taking part in the search anymore.
+				if i == 0 && len(roms) == 0 {
+					return roms, nil
+				}
+				return roms, ErrSearch
+			case bit != complement:
+				// All remaining devices have the same bit.
+				direction = bit
+			case i < lastDiscrepancy:
+				// Take the same branch as in the previous pass.
+				direction = rom[i/8]&(1<<(i%8)) != 0
+			default:
+				// Take the 0 branch the first time, and the 1 branch at the
+				// point where the previous pass took the 0 branch.
+				direction = i == lastDiscrepancy
+			}
+			if bit == complement && !direction {
+				discrepancy = i
+			}
+
+			if direction {
+				rom[i/8] |= 1 << (i % 8)
+			} else {
+				rom[i/8] &^= 1 << (i % 8)
+			}
+			// Devices with a different bit stop taking part in the search.
+			if _, err := bus.driver.touchBit(direction); err != nil {
+				return roms, err
+			}
+		}
+
+		if !rom.Valid() {
+			return roms, ErrCRC
+		}
+		roms = append(roms, rom)
+		if discrepancy < 0 {
+			return roms, nil
+		}
+		lastDiscrepancy = discrepancy
+	}
+}
+
+// CRC8 returns the 8-bit CRC used for ROM codes and the scratchpad of most
+// devices: polynomial x^8 + x^5 + x^4 + 1, least significant bit first. The CRC
+// of data followed by its CRC is zero.
+func CRC8(data []byte) byte {
+	var crc byte
+	for _, b := range data {
+		for i := 0; i < 8; i++ {
+			mix := (crc ^ b) & 1
+			crc >>= 1
+			if mix != 0 {
+				crc ^= 0x8c
+			}
+			b >>= 1
+		}
+	}
+	return crc
+}
+
+// CRC16 returns the 16-bit CRC used by some devices to protect data
+// transfers: polynomial x^16 + x^15 + x^2 + 1, least significant bit first.
+// Devices usually send the inverted CRC.
+func CRC16(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xa001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
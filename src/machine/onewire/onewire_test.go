@@ -0,0 +1,223 @@
+package onewire
+
+import (
+	"sort"
+	"testing"
+)
+
+// fakeDriver simulates devices on a 1-Wire bus, which is a wired AND: a bit
+// reads as 1 only if no device pulls the line low. The devices understand the
+// Read ROM and Search ROM commands.
+type fakeDriver struct {
+	devices []ROM
+	alarms  []bool // which devices have an alarm condition
+
+	// vanish removes all devices after the first search pass, but keeps the
+	// presence pulse, like a device that is disconnected during the search.
+	vanish bool
+
+	active  []ROM // devices still taking part in the current command
+	command byte
+	bit     int // number of bits since the reset
+}
+
+func (d *fakeDriver) reset() (bool, error) {
+	if d.vanish && d.command == cmdSearchROM && d.bit == 8+64*3 {
+		d.devices = nil
+	}
+	d.active = append([]ROM(nil), d.devices...)
+	d.command = 0
+	d.bit = 0
+	return len(d.devices) != 0 || d.vanish, nil
+}
+
+func (d *fakeDriver) touchBit(bit bool) (bool, error) {
+	i := d.bit
+	d.bit++
+	if i < 8 {
+		// Command byte, least significant bit first.
+		if bit {
+			d.command |= 1 << i
+		}
+		if i == 7 && d.command == cmdSearchAlarm {
+			d.active = d.active[:0]
+			for j, rom := range d.devices {
+				if d.alarms[j] {
+					d.active = append(d.active, rom)
+				}
+			}
+		}
+		return bit, nil
+	}
+	i -= 8
+	switch d.command {
+	case cmdReadROM:
+		return bit && d.wiredAND(i, false), nil
+	case cmdSearchROM, cmdSearchAlarm:
+		// Each ROM bit is sent by the devices, then its complement, after
+		// which the host writes the bit that selects the devices that stay
+		// in the search.
+		romBit := i / 3
+		switch i % 3 {
+		case 0:
+			return bit && d.wiredAND(romBit, false), nil
+		case 1:
+			return bit && d.wiredAND(romBit, true), nil
+		default:
+			active := d.active[:0]
+			for _, rom := range d.active {
+				if romBitValue(rom, romBit) == bit {
+					active = append(active, rom)
+				}
+			}
+			d.active = active
+			return bit, nil
+		}
+	}
+	return bit, nil
+}
+
+// wiredAND returns the bus level when all active devices send the given bit of
+// their ROM code, or its complement.
+func (d *fakeDriver) wiredAND(i int, complement bool) bool {
+	for _, rom := range d.active {
+		if romBitValue(rom, i) == complement {
+			return false
+		}
+	}
+	return true
+}
+
+func romBitValue(rom ROM, i int) bool {
+	return rom[i/8]&(1<<(i%8)) != 0
+}
+
+// makeROM returns a ROM code with the given family code and serial number, and
+// a valid CRC.
+func makeROM(family byte, serial uint64) ROM {
+	rom := ROM{family}
+	for i := 1; i < 7; i++ {
+		rom[i] = byte(serial >> (8 * (i - 1)))
+	}
+	rom[7] = CRC8(rom[:7])
+	return rom
+}
+
+func sortROMs(roms []ROM) {
+	sort.Slice(roms, func(i, j int) bool {
+		return string(roms[i][:]) < string(roms[j][:])
+	})
+}
+
+func TestCRC8(t *testing.T) {
+	// Example ROM code from Maxim application note 27.
+	rom := ROM{0x02, 0x1c, 0xb8, 0x01, 0x00, 0x00, 0x00, 0xa2}
+	if crc := CRC8(rom[:7]); crc != 0xa2 {
+		t.Errorf("CRC8 = %#02x, want 0xa2", crc)
+	}
+	if !rom.Valid() {
+		t.Error("ROM code is not valid")
+	}
+	if crc := CRC8(rom[:]); crc != 0 {
+		t.Errorf("CRC8 including the CRC = %#02x, want 0", crc)
+	}
+	rom[3] ^= 0x10
+	if rom.Valid() {
+		t.Error("corrupted ROM code is valid")
+	}
+}
+
+func TestCRC16(t *testing.T) {
+	// Check value of CRC-16/ARC.
+	if crc := CRC16([]byte("123456789")); crc != 0xbb3d {
+		t.Errorf("CRC16 = %#04x, want 0xbb3d", crc)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	devices := []ROM{
+		makeROM(0x28, 0x0000_0000_0001),
+		makeROM(0x28, 0x0000_0000_0002),
+		makeROM(0x28, 0x8000_0000_0001),
+		makeROM(0x10, 0x1234_5678_9abc),
+		makeROM(0x3b, 0x0000_0000_0001),
+	}
+	for n := 0; n <= len(devices); n++ {
+		bus := &Bus{driver: &fakeDriver{devices: devices[:n]}}
+		roms, err := bus.Search(false)
+		if err != nil {
+			t.Fatalf("%d devices: %v", n, err)
+		}
+		want := append([]ROM(nil), devices[:n]...)
+		sortROMs(roms)
+		sortROMs(want)
+		if len(roms) != len(want) {
+			t.Fatalf("%d devices: found %d: %x", n, len(roms), roms)
+		}
+		for i := range want {
+			if roms[i] != want[i] {
+				t.Errorf("%d devices: found %x, want %x", n, roms, want)
+				break
+			}
+		}
+	}
+}
+
+func TestSearchAlarm(t *testing.T) {
+	devices := []ROM{
+		makeROM(0x28, 1),
+		makeROM(0x28, 2),
+		makeROM(0x28, 3),
+	}
+	driver := &fakeDriver{devices: devices, alarms: []bool{false, true, false}}
+	roms, err := (&Bus{driver: driver}).Search(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roms) != 1 || roms[0] != devices[1] {
+		t.Errorf("found %x, want only %x", roms, devices[1])
+	}
+
+	// No device has an alarm condition.
+	driver.alarms = []bool{false, false, false}
+	roms, err = (&Bus{driver: driver}).Search(true)
+	if err != nil || len(roms) != 0 {
+		t.Errorf("found %x, %v, want no devices", roms, err)
+	}
+}
+
+func TestSearchErrors(t *testing.T) {
+	// The devices disappear after the first one has been found.
+	driver := &fakeDriver{
+		devices: []ROM{makeROM(0x28, 1), makeROM(0x28, 2)},
+		vanish:  true,
+	}
+	roms, err := (&Bus{driver: driver}).Search(false)
+	if err != ErrSearch || len(roms) != 1 {
+		t.Errorf("expected ErrSearch after one device, got %x, %v", roms, err)
+	}
+
+	// A device with an invalid ROM code.
+	rom := makeROM(0x28, 1)
+	rom[7] ^= 0xff
+	roms, err = (&Bus{driver: &fakeDriver{devices: []ROM{rom}}}).Search(false)
+	if err != ErrCRC || len(roms) != 0 {
+		t.Errorf("expected ErrCRC, got %x, %v", roms, err)
+	}
+}
+
+func TestReadROM(t *testing.T) {
+	rom := makeROM(0x28, 0x1234)
+	bus := &Bus{driver: &fakeDriver{devices: []ROM{rom}}}
+	got, err := bus.ReadROM()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != rom {
+		t.Errorf("ReadROM = %x, want %x", got, rom)
+	}
+
+	if _, err := (&Bus{driver: &fakeDriver{}}).ReadROM(); err != ErrNoPresence {
+		t.Errorf("expected ErrNoPresence without devices, got %v", err)
+	}
+}
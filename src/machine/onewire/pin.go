@@ -0,0 +1,80 @@
+//go:build cortexm
+
+package onewire
+
+import (
+	"machine"
+)
+
+// pinDriver bit-bangs the 1-Wire protocol on a pin. The pin is only ever
+// driven low: it is switched to an input to release the bus, which is pulled
+// high by the pull-up resistor.
+type pinDriver struct {
+	pin            machine.Pin
+	cyclesPerMicro uint32
+}
+
+// NewPin returns a bus that is driven by bit-banging the given pin, which must
+// have an external pull-up resistor (usually 4.7kΩ) to the supply voltage.
+// Interrupts are disabled during each time slot, for up to 70µs at a time.
+//
+// Bit-banging relies on machine.DelayCycles, so it is only available on
+// Cortex-M chips. Use NewUART on other chips.
+func NewPin(pin machine.Pin) *Bus {
+	pin.Configure(machine.PinConfig{Mode: machine.PinInput})
+	return &Bus{driver: &pinDriver{
+		pin:            pin,
+		cyclesPerMicro: machine.CPUFrequency() / 1000000,
+	}}
+}
+
+func (d *pinDriver) low() {
+	d.pin.Low()
+	d.pin.Configure(machine.PinConfig{Mode: machine.PinOutput})
+}
+
+func (d *pinDriver) release() {
+	d.pin.Configure(machine.PinConfig{Mode: machine.PinInput})
+}
+
+func (d *pinDriver) delay(us uint32) {
+	machine.DelayCycles(us * d.cyclesPerMicro)
+}
+
+func (d *pinDriver) reset() (present bool, err error) {
+	// The reset pulse may be longer than 480µs, so interrupts don't need to
+	// be disabled for it.
+	d.low()
+	d.delay(480)
+	machine.TimingCritical(func() {
+		d.release()
+		d.delay(70)
+		present = !d.pin.Get()
+	})
+	d.delay(410)
+	if !d.pin.Get() {
+		// The bus is still held low: it's shorted or missing its pull-up.
+		return false, ErrBusLow
+	}
+	return present, nil
+}
+
+func (d *pinDriver) touchBit(bit bool) (value bool, err error) {
+	machine.TimingCritical(func() {
+		d.low()
+		if bit {
+			// Write a 1 or read a bit: release the bus quickly, and sample
+			// it before the 15µs in which a device may hold it low.
+			d.delay(6)
+			d.release()
+			d.delay(9)
+			value = d.pin.Get()
+			d.delay(55)
+		} else {
+			d.delay(60)
+			d.release()
+			d.delay(10)
+		}
+	})
+	return value, nil
+}
@@ -0,0 +1,78 @@
+package onewire
+
+import (
+	"time"
+)
+
+// UART is a UART whose TX and RX pins are both connected to the 1-Wire data
+// line, TX through an open drain driver or a diode. It is implemented by
+// machine.UART on chips that support changing the baud rate.
+type UART interface {
+	SetBaudRate(br uint32)
+	WriteByte(c byte) error
+	ReadByte() (byte, error)
+	Buffered() int
+}
+
+// uartDriver generates the 1-Wire time slots with a UART: the start bit of a
+// UART frame pulls the line low, and what the UART receives shows whether a
+// device held it low for longer.
+type uartDriver struct {
+	uart UART
+}
+
+// NewUART returns a bus that is driven by a UART. This doesn't need precise
+// timing from the CPU, so it works on any chip and interrupts stay enabled.
+func NewUART(uart UART) *Bus {
+	return &Bus{driver: &uartDriver{uart: uart}}
+}
+
+// uartTimeout is how long to wait for the UART to receive a frame it sent.
+const uartTimeout = 10 * time.Millisecond
+
+// transfer sends a byte and returns the byte that was received at the same
+// time.
+func (d *uartDriver) transfer(b byte) (byte, error) {
+	for d.uart.Buffered() > 0 {
+		d.uart.ReadByte() // discard stale data
+	}
+	if err := d.uart.WriteByte(b); err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	for d.uart.Buffered() == 0 {
+		if time.Since(start) > uartTimeout {
+			return 0, ErrTimeout
+		}
+	}
+	return d.uart.ReadByte()
+}
+
+func (d *uartDriver) reset() (bool, error) {
+	// At 9600 baud, the start bit and four 0 bits hold the line low for
+	// 520µs, which is the reset pulse. Devices that answer with a presence
+	// pulse pull some of the following 1 bits low.
+	d.uart.SetBaudRate(9600)
+	b, err := d.transfer(0xf0)
+	d.uart.SetBaudRate(115200)
+	if err != nil {
+		return false, err
+	}
+	return b != 0xf0, nil
+}
+
+func (d *uartDriver) touchBit(bit bool) (bool, error) {
+	// At 115200 baud, the start bit alone (8.7µs) is the short low pulse of
+	// a 1 bit or a read, and a 0x00 byte holds the line low for a 0 bit. A
+	// device sending a 0 bit holds the line low long enough to change the
+	// received byte.
+	out := byte(0x00)
+	if bit {
+		out = 0xff
+	}
+	b, err := d.transfer(out)
+	if err != nil {
+		return false, err
+	}
+	return b == 0xff, nil
+}
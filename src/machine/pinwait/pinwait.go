@@ -0,0 +1,75 @@
+// Package pinwait lets drivers wait for an input pin to reach a given level,
+// such as the BUSY pin of an ePaper display or the data ready pin of a sensor,
+// without keeping the CPU busy in a polling loop:
+//
+//	// Wait for the display to finish its refresh.
+//	err := pinwait.Wait(busyPin, false, 5*time.Second)
+//
+// On chips that support pin change interrupts, the waiting goroutine is woken
+// up by the interrupt. Other goroutines keep running in the meantime, and when
+// there is nothing else to do the CPU sleeps until the next interrupt. On other
+// chips the pin is checked periodically, sleeping in between.
+package pinwait
+
+import (
+	"errors"
+	"machine"
+	"runtime"
+	"time"
+)
+
+// ErrTimeout is returned by Wait when the pin didn't reach the requested level
+// in time.
+var ErrTimeout = errors.New("pinwait: timeout")
+
+// checkInterval is the longest time between two checks of the pin level. The
+// scheduler can't interrupt a sleeping goroutine, so a goroutine waiting with a
+// timeout sleeps in steps of at most this duration to notice the interrupt
+// soon enough. It is also the polling interval on chips without pin change
+// interrupts.
+const checkInterval = 10 * time.Millisecond
+
+// Wait waits until the pin reads the given level (true for high, false for
+// low) and returns nil, or returns ErrTimeout once the timeout has passed. A
+// timeout of zero or less means waiting forever. The pin must already be
+// configured as an input.
+//
+// Wait uses the pin change interrupt of the pin, so it replaces any callback
+// that was set with SetInterrupt and removes the interrupt before returning.
+func Wait(pin machine.Pin, level bool, timeout time.Duration) error {
+	if pin.Get() == level {
+		return nil
+	}
+
+	// The condition variable is notified from the interrupt when the pin
+	// changes to the requested level.
+	cond := new(runtime.Cond)
+	hasInterrupt := setInterrupt(pin, level, func() {
+		cond.Notify()
+	})
+	if hasInterrupt {
+		defer clearInterrupt(pin, level)
+	}
+
+	start := time.Now()
+	for pin.Get() != level {
+		if timeout <= 0 && hasInterrupt {
+			cond.Wait()
+			continue
+		}
+		sleep := checkInterval
+		if timeout > 0 {
+			remaining := timeout - time.Since(start)
+			if remaining <= 0 {
+				return ErrTimeout
+			}
+			if remaining < sleep {
+				sleep = remaining
+			}
+		}
+		if !cond.Poll() {
+			time.Sleep(sleep)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+//go:build nrf || sam || rp2040 || stm32 || esp32c3 || k210 || mimxrt1062
+
+package pinwait
+
+import "machine"
+
+// setInterrupt calls notify from the pin change interrupt when the pin changes
+// to the given level. It returns false if no interrupt could be set up for the
+// pin, for example because all interrupt channels are in use.
+func setInterrupt(pin machine.Pin, level bool, notify func()) bool {
+	err := pin.SetInterrupt(pinChange(level), func(machine.Pin) {
+		notify()
+	})
+	return err == nil
+}
+
+// clearInterrupt removes the interrupt set by setInterrupt with the same level.
+// Some chips (such as the rp2040) only disable the pin change that is passed to
+// SetInterrupt, so it must match the one the interrupt was set with.
+func clearInterrupt(pin machine.Pin, level bool) {
+	pin.SetInterrupt(pinChange(level), nil)
+}
+
+// pinChange returns the pin change to the given level.
+func pinChange(level bool) machine.PinChange {
+	if level {
+		return machine.PinRising
+	}
+	return machine.PinFalling
+}
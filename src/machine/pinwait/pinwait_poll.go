@@ -0,0 +1,13 @@
+//go:build !(nrf || sam || rp2040 || stm32 || esp32c3 || k210 || mimxrt1062)
+
+package pinwait
+
+import "machine"
+
+// setInterrupt returns false: pin change interrupts are not supported on this
+// chip, so the pin is polled instead.
+func setInterrupt(pin machine.Pin, level bool, notify func()) bool {
+	return false
+}
+
+func clearInterrupt(pin machine.Pin, level bool) {}
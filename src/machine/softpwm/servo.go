@@ -0,0 +1,49 @@
+package softpwm
+
+import (
+	"errors"
+	"machine"
+)
+
+var ErrInvalidAngle = errors.New("softpwm: servo angle must be between 0 and 180 degrees")
+
+// servoPeriod is the period of the control signal of hobby servos: 20ms (50Hz)
+// in nanoseconds.
+const servoPeriod = 20e6
+
+// Servo is a hobby servo, which is controlled by the width of a pulse sent
+// every 20ms.
+type Servo struct {
+	pwm     Device
+	channel uint8
+}
+
+// NewServo returns a servo connected to the given pin. Like Open, it uses the
+// first of the given hardware PWM peripherals that supports the pin and falls
+// back to software PWM otherwise. The PWM is configured with a period of 20ms,
+// so it can't be shared with channels that need a different period.
+func NewServo(pin machine.Pin, hardware ...Device) (Servo, error) {
+	pwm, channel, err := Open(pin, machine.PWMConfig{Period: servoPeriod}, hardware...)
+	if err != nil {
+		return Servo{}, err
+	}
+	return Servo{pwm: pwm, channel: channel}, nil
+}
+
+// SetMicroseconds sets the width of the pulse. Most servos accept pulses from
+// 1000µs to 2000µs, with 1500µs being the center position, but some have a
+// wider range.
+func (s Servo) SetMicroseconds(us uint16) {
+	value := uint64(s.pwm.Top()) * uint64(us) / (servoPeriod / 1000)
+	s.pwm.Set(s.channel, uint32(value))
+}
+
+// SetAngle sets the position of the servo in degrees, from 0 to 180, assuming
+// these correspond to pulses of 1000µs and 2000µs.
+func (s Servo) SetAngle(angle int) error {
+	if angle < 0 || angle > 180 {
+		return ErrInvalidAngle
+	}
+	s.SetMicroseconds(uint16(1000 + angle*1000/180))
+	return nil
+}
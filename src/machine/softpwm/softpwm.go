@@ -0,0 +1,265 @@
+// Package softpwm generates PWM signals in software, for pins that aren't
+// connected to a hardware PWM peripheral or when all of them are in use. It has
+// the same API as the hardware PWM peripherals in the machine package, so it
+// can be used to dim LEDs or to drive servos (see Servo) on any output pin:
+//
+//	pwm := &softpwm.PWM{}
+//	err := pwm.Configure(machine.PWMConfig{})
+//	ch, err := pwm.Channel(machine.D4)
+//	pwm.Set(ch, pwm.Top()/4) // 25% duty cycle
+//
+// Open picks a hardware PWM peripheral if one supports the pin, and only falls
+// back to software PWM if none does.
+//
+// All channels of a PWM share the same period, and are switched on together at
+// the start of each period. The pins are switched by a timer, which is chip
+// specific. This determines how accurate the signal is:
+//
+//   - rp2040: alarm 1 of the TIMER peripheral, at the highest interrupt
+//     priority. Edges are placed with 1µs resolution and are usually no more
+//     than a few µs late, unless interrupts are disabled for longer elsewhere.
+//     Only one PWM can be used at a time.
+//   - Other chips: a goroutine that sleeps until the next edge. Edges are late
+//     by the sleep resolution of the chip (for example 30.5µs on the nRF
+//     series) and by however long other goroutines run without yielding. This
+//     is good enough for LEDs and most servos, but not for signals that need
+//     precise timing.
+//
+// In both cases the duty cycle is updated at the start of a period, so that no
+// period is cut short or stretched when Set is called.
+package softpwm
+
+import (
+	"errors"
+	"machine"
+	"runtime/interrupt"
+)
+
+var (
+	ErrTooManyChannels = errors.New("softpwm: too many channels")
+	ErrTimerInUse      = errors.New("softpwm: timer already used by another PWM")
+)
+
+// maxChannels is the number of pins a single PWM can drive.
+const maxChannels = 8
+
+// defaultPeriod is the period in microseconds used when PWMConfig.Period is
+// left zero. It is longer than the default period of hardware PWM peripherals
+// (100Hz instead of around 1kHz) to limit the time spent switching pins, but
+// still short enough to dim LEDs without flicker.
+const defaultPeriod = 10000
+
+// minPeriod is the shortest period that can be configured, in microseconds.
+const minPeriod = 100
+
+// Device is the API of a PWM peripheral in the machine package, which is also
+// implemented by PWM.
+type Device interface {
+	Configure(config machine.PWMConfig) error
+	Channel(pin machine.Pin) (uint8, error)
+	Set(channel uint8, value uint32)
+	Top() uint32
+}
+
+// PWM is a software PWM that drives up to 8 pins with the same period. The
+// zero value is ready to be configured.
+type PWM struct {
+	pins        [maxChannels]machine.Pin
+	values      [maxChannels]uint32
+	inverting   uint8 // bit mask of inverted channels
+	numChannels uint8
+	period      uint32 // in microseconds
+	started     bool   // whether the timer that drives the PWM is running
+
+	// pending is the schedule for the next period. It is copied to active at
+	// the start of each period by step.
+	pending schedule
+	active  schedule
+	index   uint8 // number of edges of the active schedule that have passed
+}
+
+// schedule lists when the pins switch during a period.
+type schedule struct {
+	period   uint32 // in microseconds
+	start    uint8  // bit mask of channels that are active at the start
+	numEdges uint8
+	edges    [maxChannels]edge
+}
+
+// edge is a point in the period where one or more channels become inactive.
+type edge struct {
+	time uint32 // in microseconds since the start of the period
+	mask uint8  // bit mask of channels
+}
+
+// Configure sets the period of the PWM and starts the timer that switches the
+// pins. It may be called again later to change the period.
+func (pwm *PWM) Configure(config machine.PWMConfig) error {
+	if err := pwm.SetPeriod(config.Period); err != nil {
+		return err
+	}
+	return pwm.start()
+}
+
+// SetPeriod changes the period of the PWM, in nanoseconds, or sets the default
+// period if it is zero. The duty cycle of the channels is kept the same, by
+// scaling their values to the new Top value.
+func (pwm *PWM) SetPeriod(period uint64) error {
+	period /= 1000 // nanoseconds to microseconds
+	if period == 0 {
+		period = defaultPeriod
+	}
+	if period < minPeriod {
+		period = minPeriod
+	}
+	if period > 1<<31 {
+		return machine.ErrPWMPeriodTooLong
+	}
+	if pwm.period != 0 {
+		for i := uint8(0); i < pwm.numChannels; i++ {
+			pwm.values[i] = uint32(uint64(pwm.values[i]) * period / uint64(pwm.period))
+		}
+	}
+	pwm.period = uint32(period)
+	pwm.update()
+	return nil
+}
+
+// Top returns the value that corresponds to a duty cycle of 100%. It is the
+// period in microseconds.
+func (pwm *PWM) Top() uint32 {
+	return pwm.period
+}
+
+// Channel configures the pin as an output and returns the channel to use with
+// Set. The pin is low (inactive) until Set is called.
+func (pwm *PWM) Channel(pin machine.Pin) (uint8, error) {
+	for i := uint8(0); i < pwm.numChannels; i++ {
+		if pwm.pins[i] == pin {
+			return i, nil
+		}
+	}
+	if pwm.numChannels == maxChannels {
+		return 0, ErrTooManyChannels
+	}
+	pin.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	pin.Low()
+	channel := pwm.numChannels
+	pwm.pins[channel] = pin
+	pwm.numChannels++
+	return channel, nil
+}
+
+// SetInverting sets whether the channel is inverted: if it is, the pin is low
+// while the channel is active and high otherwise.
+func (pwm *PWM) SetInverting(channel uint8, inverting bool) {
+	if inverting {
+		pwm.inverting |= 1 << channel
+	} else {
+		pwm.inverting &^= 1 << channel
+	}
+	pwm.update()
+}
+
+// Set sets the duty cycle of the channel, from 0 (always inactive) to Top()
+// (always active). The new value takes effect at the start of the next period.
+func (pwm *PWM) Set(channel uint8, value uint32) {
+	if value > pwm.period {
+		value = pwm.period
+	}
+	pwm.values[channel] = value
+	pwm.update()
+}
+
+// update recalculates the pending schedule from the channel values.
+func (pwm *PWM) update() {
+	var s schedule
+	s.period = pwm.period
+	for i := uint8(0); i < pwm.numChannels; i++ {
+		value := pwm.values[i]
+		if value == 0 {
+			continue
+		}
+		s.start |= 1 << i
+		if value >= pwm.period {
+			continue
+		}
+		// Insert the edge, keeping the edges sorted by time.
+		j := uint8(0)
+		for j < s.numEdges && s.edges[j].time < value {
+			j++
+		}
+		if j < s.numEdges && s.edges[j].time == value {
+			s.edges[j].mask |= 1 << i
+			continue
+		}
+		copy(s.edges[j+1:], s.edges[j:s.numEdges])
+		s.edges[j] = edge{time: value, mask: 1 << i}
+		s.numEdges++
+	}
+
+	// The timer may run in an interrupt, so it must not see a partially
+	// updated schedule.
+	mask := interrupt.Disable()
+	pwm.pending = s
+	interrupt.Restore(mask)
+}
+
+// step switches the pins for the current point in the period, and returns the
+// time until the next point in microseconds. It is called by the timer.
+func (pwm *PWM) step() uint32 {
+	var now uint32
+	if pwm.index == 0 {
+		// Start of a new period.
+		pwm.active = pwm.pending
+		for i := uint8(0); i < pwm.numChannels; i++ {
+			pwm.setPin(i, pwm.active.start&(1<<i) != 0)
+		}
+	} else {
+		e := pwm.active.edges[pwm.index-1]
+		for i := uint8(0); i < pwm.numChannels; i++ {
+			if e.mask&(1<<i) != 0 {
+				pwm.setPin(i, false)
+			}
+		}
+		now = e.time
+	}
+	if pwm.index < pwm.active.numEdges {
+		pwm.index++
+		return pwm.active.edges[pwm.index-1].time - now
+	}
+	pwm.index = 0
+	return pwm.active.period - now
+}
+
+// setPin sets the pin of the channel to its active or inactive level.
+func (pwm *PWM) setPin(channel uint8, active bool) {
+	pwm.pins[channel].Set(active != (pwm.inverting&(1<<channel) != 0))
+}
+
+// defaultPWM is the software PWM used by Open.
+var defaultPWM PWM
+
+// Open returns a PWM device and channel to drive the given pin. It uses the
+// first of the given hardware PWM peripherals that supports the pin, and falls
+// back to software PWM if there is none. The returned device is configured with
+// the given configuration, which also applies to other pins using the same
+// device.
+func Open(pin machine.Pin, config machine.PWMConfig, hardware ...Device) (Device, uint8, error) {
+	for _, pwm := range hardware {
+		if err := pwm.Configure(config); err != nil {
+			continue
+		}
+		if channel, err := pwm.Channel(pin); err == nil {
+			return pwm, channel, nil
+		}
+	}
+	if err := defaultPWM.Configure(config); err != nil {
+		return nil, 0, err
+	}
+	channel, err := defaultPWM.Channel(pin)
+	if err != nil {
+		return nil, 0, err
+	}
+	return &defaultPWM, channel, nil
+}
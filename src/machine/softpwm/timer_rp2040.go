@@ -0,0 +1,56 @@
+//go:build rp2040
+
+package softpwm
+
+import (
+	"device/rp"
+	"runtime/interrupt"
+)
+
+// The PWM is driven by alarm 1 of the TIMER peripheral, which counts in
+// microseconds. Alarm 0 is used by the runtime for sleeping.
+const timerAlarm = 1
+
+// minDelay is the shortest time in the future an alarm can be set, in
+// microseconds. An alarm that is set in the past only fires when the lower 32
+// bits of the timer wrap around, after more than an hour.
+const minDelay = 2
+
+var (
+	timerPWM  *PWM   // the PWM driven by the alarm
+	nextAlarm uint32 // time the alarm is set to
+)
+
+// start starts the alarm interrupt that drives the PWM.
+func (pwm *PWM) start() error {
+	if pwm.started {
+		return nil
+	}
+	if timerPWM != nil {
+		return ErrTimerInUse
+	}
+	timerPWM = pwm
+	pwm.started = true
+
+	intr := interrupt.New(rp.IRQ_TIMER_IRQ_1, handleAlarm)
+	rp.TIMER.INTR.Set(1 << timerAlarm)
+	rp.TIMER.INTE.SetBits(1 << timerAlarm)
+	intr.SetPriority(0x00) // highest priority, for the least jitter
+	intr.Enable()
+
+	nextAlarm = rp.TIMER.TIMERAWL.Get() + minDelay
+	rp.TIMER.ALARM1.Set(nextAlarm)
+	return nil
+}
+
+func handleAlarm(interrupt.Interrupt) {
+	rp.TIMER.INTR.Set(1 << timerAlarm)
+	nextAlarm += timerPWM.step()
+
+	// Skip ahead if the handler is running late, so that the alarm isn't set
+	// in the past. This delays the edge, but that's better than stopping.
+	if now := rp.TIMER.TIMERAWL.Get(); int32(nextAlarm-now) < minDelay {
+		nextAlarm = now + minDelay
+	}
+	rp.TIMER.ALARM1.Set(nextAlarm)
+}
@@ -0,0 +1,32 @@
+//go:build !rp2040
+
+package softpwm
+
+import (
+	"time"
+)
+
+// start starts a goroutine that drives the PWM, sleeping between edges.
+func (pwm *PWM) start() error {
+	if pwm.started {
+		return nil
+	}
+	pwm.started = true
+	go pwm.run()
+	return nil
+}
+
+func (pwm *PWM) run() {
+	// Sleep until an absolute time, so that delays don't add up over time.
+	next := time.Now()
+	for {
+		next = next.Add(time.Duration(pwm.step()) * time.Microsecond)
+		now := time.Now()
+		if now.Sub(next) > time.Duration(pwm.active.period)*time.Microsecond {
+			// Other goroutines kept the CPU busy for more than a period. Skip
+			// ahead instead of trying to catch up.
+			next = now
+		}
+		time.Sleep(next.Sub(now))
+	}
+}
@@ -0,0 +1,156 @@
+//go:build atsamd51 || atsame5x
+
+package spiflash
+
+import (
+	"device/sam"
+	"machine"
+	"runtime/volatile"
+	"unsafe"
+)
+
+// qspiMemory is the start of the address space of the QSPI peripheral. Data
+// of all transfers is read from and written to this area.
+const qspiMemory = 0x04000000
+
+type qspiTransport struct{}
+
+// NewQSPI returns a transport that uses the QSPI peripheral, running at the
+// given clock frequency (or the nearest lower frequency that is possible). The
+// flash chip must be connected to the QSPI pins: PA08-PA11 for the data lines,
+// PB10 for the clock and PB11 for chip select.
+func NewQSPI(frequency uint32) Transport {
+	// Enable the peripheral clocks.
+	sam.MCLK.APBCMASK.SetBits(sam.MCLK_APBCMASK_QSPI_)
+	sam.MCLK.AHBMASK.SetBits(sam.MCLK_AHBMASK_QSPI_)
+	sam.MCLK.AHBMASK.ClearBits(sam.MCLK_AHBMASK_QSPI_2X_)
+
+	sam.QSPI.CTRLA.SetBits(sam.QSPI_CTRLA_SWRST)
+
+	for _, pin := range []machine.Pin{machine.PA08, machine.PA09, machine.PA10, machine.PA11, machine.PB10, machine.PB11} {
+		pin.Configure(machine.PinConfig{Mode: machine.PinCom})
+	}
+
+	sam.QSPI.CTRLB.Set(sam.QSPI_CTRLB_MODE_MEMORY<<sam.QSPI_CTRLB_MODE_Pos |
+		sam.QSPI_CTRLB_DATALEN_8BITS<<sam.QSPI_CTRLB_DATALEN_Pos |
+		sam.QSPI_CTRLB_CSMODE_LASTXFER<<sam.QSPI_CTRLB_CSMODE_Pos)
+
+	// The QSPI clock is the CPU clock divided by BAUD+1.
+	baud := (machine.CPUFrequency() + frequency - 1) / frequency
+	if baud > 0 {
+		baud--
+	}
+	if baud > 255 {
+		baud = 255
+	}
+	sam.QSPI.BAUD.Set(baud << sam.QSPI_BAUD_BAUD_Pos)
+
+	sam.QSPI.CTRLA.SetBits(sam.QSPI_CTRLA_ENABLE)
+	return qspiTransport{}
+}
+
+// Read sends the command and then reads len(data) bytes.
+func (q qspiTransport) Read(cmd Command, data []byte) error {
+	disableCache()
+	defer enableCache()
+
+	iframe := q.instructionFrame(cmd, len(data) != 0)
+	if cmd.AddressLen != 0 {
+		iframe |= sam.QSPI_INSTRFRAME_TFRTYPE_READMEMORY << sam.QSPI_INSTRFRAME_TFRTYPE_Pos
+	} else {
+		iframe |= sam.QSPI_INSTRFRAME_TFRTYPE_READ << sam.QSPI_INSTRFRAME_TFRTYPE_Pos
+	}
+	q.runInstruction(cmd, iframe)
+
+	// In memory mode, the address sent to the chip is the offset in the QSPI
+	// address space.
+	base := uintptr(qspiMemory)
+	if cmd.AddressLen != 0 {
+		base += uintptr(cmd.Address)
+	}
+	for i := range data {
+		data[i] = volatile.LoadUint8((*uint8)(unsafe.Pointer(base + uintptr(i))))
+	}
+	q.endTransfer()
+	return nil
+}
+
+// Write sends the command followed by data.
+func (q qspiTransport) Write(cmd Command, data []byte) error {
+	disableCache()
+	defer enableCache()
+
+	iframe := q.instructionFrame(cmd, len(data) != 0)
+	base := uintptr(qspiMemory)
+	if cmd.AddressLen != 0 && len(data) != 0 {
+		iframe |= sam.QSPI_INSTRFRAME_TFRTYPE_WRITEMEMORY << sam.QSPI_INSTRFRAME_TFRTYPE_Pos
+		base += uintptr(cmd.Address)
+	} else {
+		// Commands without data (like erase commands) send the address from
+		// the INSTRADDR register.
+		iframe |= sam.QSPI_INSTRFRAME_TFRTYPE_WRITE << sam.QSPI_INSTRFRAME_TFRTYPE_Pos
+		sam.QSPI.INSTRADDR.Set(cmd.Address)
+	}
+	q.runInstruction(cmd, iframe)
+
+	for i, b := range data {
+		volatile.StoreUint8((*uint8)(unsafe.Pointer(base+uintptr(i))), b)
+	}
+	q.endTransfer()
+	return nil
+}
+
+// SupportsQuad returns true: the QSPI peripheral has four data lines.
+func (q qspiTransport) SupportsQuad() bool {
+	return true
+}
+
+// instructionFrame returns the INSTRFRAME value for the command, without the
+// transfer type.
+func (q qspiTransport) instructionFrame(cmd Command, hasData bool) uint32 {
+	iframe := uint32(sam.QSPI_INSTRFRAME_INSTREN)
+	if cmd.Quad {
+		iframe |= sam.QSPI_INSTRFRAME_WIDTH_QUAD_OUTPUT << sam.QSPI_INSTRFRAME_WIDTH_Pos
+	} else {
+		iframe |= sam.QSPI_INSTRFRAME_WIDTH_SINGLE_BIT_SPI << sam.QSPI_INSTRFRAME_WIDTH_Pos
+	}
+	switch cmd.AddressLen {
+	case 3:
+		iframe |= sam.QSPI_INSTRFRAME_ADDREN | sam.QSPI_INSTRFRAME_ADDRLEN_24BITS<<sam.QSPI_INSTRFRAME_ADDRLEN_Pos
+	case 4:
+		iframe |= sam.QSPI_INSTRFRAME_ADDREN | sam.QSPI_INSTRFRAME_ADDRLEN_32BITS<<sam.QSPI_INSTRFRAME_ADDRLEN_Pos
+	}
+	if hasData {
+		iframe |= sam.QSPI_INSTRFRAME_DATAEN
+	}
+	iframe |= uint32(cmd.DummyCycles) << sam.QSPI_INSTRFRAME_DUMMYLEN_Pos
+	return iframe
+}
+
+func (q qspiTransport) runInstruction(cmd Command, iframe uint32) {
+	sam.QSPI.INSTRCTRL.Set(uint32(cmd.Opcode))
+	sam.QSPI.INSTRFRAME.Set(iframe)
+	// Read back INSTRFRAME to synchronize the system bus with the QSPI
+	// peripheral, as described in the datasheet.
+	sam.QSPI.INSTRFRAME.Get()
+}
+
+func (q qspiTransport) endTransfer() {
+	sam.QSPI.CTRLA.Set(sam.QSPI_CTRLA_ENABLE | sam.QSPI_CTRLA_LASTXFER)
+	for !sam.QSPI.INTFLAG.HasBits(sam.QSPI_INTFLAG_INSTREND) {
+	}
+	sam.QSPI.INTFLAG.Set(sam.QSPI_INTFLAG_INSTREND)
+}
+
+// disableCache disables and invalidates the cache controller, so that reads
+// from the QSPI address space return the data of the current transfer.
+func disableCache() {
+	sam.CMCC.CTRL.ClearBits(sam.CMCC_CTRL_CEN)
+	for sam.CMCC.SR.HasBits(sam.CMCC_SR_CSTS) {
+	}
+	sam.CMCC.MAINT0.SetBits(sam.CMCC_MAINT0_INVALL)
+}
+
+func enableCache() {
+	sam.CMCC.CTRL.SetBits(sam.CMCC_CTRL_CEN)
+}
@@ -0,0 +1,132 @@
+package spiflash
+
+// This file reads the Serial Flash Discoverable Parameters (SFDP) table, see
+// JEDEC standard JESD216. Only the basic flash parameter table is used.
+
+import "encoding/binary"
+
+const (
+	sfdpSignature   = 0x50444653 // "SFDP" in little endian
+	sfdpHeaderSize  = 8
+	sfdpBasicParams = 0xff00 // parameter ID of the basic flash parameter table
+)
+
+// readSFDP reads the basic flash parameters from the SFDP table using the
+// given read function, which reads from the SFDP address space.
+func readSFDP(read func(addr uint32, buf []byte) error) (Params, error) {
+	var header [sfdpHeaderSize]byte
+	if err := read(0, header[:]); err != nil {
+		return Params{}, err
+	}
+	if binary.LittleEndian.Uint32(header[0:4]) != sfdpSignature {
+		return Params{}, ErrNoSFDP
+	}
+	numHeaders := int(header[6]) + 1
+
+	// Find the basic flash parameter table. The first parameter header must
+	// point to it, but a later header may point to a newer version of it.
+	var table []byte
+	for i := 0; i < numHeaders; i++ {
+		var paramHeader [sfdpHeaderSize]byte
+		if err := read(uint32(sfdpHeaderSize*(i+1)), paramHeader[:]); err != nil {
+			return Params{}, err
+		}
+		id := uint16(paramHeader[7])<<8 | uint16(paramHeader[0])
+		if id != sfdpBasicParams {
+			continue
+		}
+		length := int(paramHeader[3]) * 4
+		if length > 4*16 {
+			// Later DWORDs are not used.
+			length = 4 * 16
+		}
+		if table != nil && length < len(table) {
+			continue
+		}
+		pointer := uint32(paramHeader[4]) | uint32(paramHeader[5])<<8 | uint32(paramHeader[6])<<16
+		table = make([]byte, length)
+		if err := read(pointer, table); err != nil {
+			return Params{}, err
+		}
+	}
+	return parseBasicParams(table)
+}
+
+// parseBasicParams parses the basic flash parameter table. The table must
+// contain at least the 9 DWORDs of the first version of JESD216.
+func parseBasicParams(table []byte) (Params, error) {
+	if len(table) < 9*4 {
+		return Params{}, ErrInvalidSFDP
+	}
+	dword := func(n int) uint32 {
+		// DWORDs are numbered from 1 in the standard.
+		return binary.LittleEndian.Uint32(table[(n-1)*4:])
+	}
+
+	params := Params{
+		PageSize: 256,
+	}
+
+	// DWORD 1: address bytes and supported fast read modes.
+	dw1 := dword(1)
+	switch (dw1 >> 17) & 0b11 {
+	case 0b01, 0b10:
+		params.Address4Byte = true
+	}
+
+	// DWORD 2: flash memory density, in bits.
+	density := dword(2)
+	if density&(1<<31) == 0 {
+		params.Size = (int64(density) + 1) / 8
+	} else {
+		n := density &^ (1 << 31)
+		if n < 3 || n > 62 {
+			return Params{}, ErrInvalidSFDP
+		}
+		params.Size = 1 << (n - 3)
+	}
+
+	// DWORD 3: 1-1-4 fast read.
+	if dw1&(1<<22) != 0 {
+		dw3 := dword(3)
+		params.QuadReadOpcode = byte(dw3 >> 24)
+		params.QuadReadDummyCycles = int((dw3>>16)&0x1f) + int((dw3>>21)&0x7)
+	}
+
+	// DWORD 8 and 9: erase types. Use the smallest one.
+	for _, dw := range []uint32{dword(8), dword(8) >> 16, dword(9), dword(9) >> 16} {
+		sizeExp := dw & 0xff
+		if sizeExp == 0 {
+			// Erase type not supported.
+			continue
+		}
+		size := int64(1) << sizeExp
+		if params.EraseBlockSize == 0 || size < params.EraseBlockSize {
+			params.EraseBlockSize = size
+			params.EraseOpcode = byte(dw >> 8)
+		}
+	}
+	if params.EraseBlockSize == 0 {
+		// Fall back to the 4kB erase command in DWORD 1.
+		if dw1&0b11 != 0b01 {
+			return Params{}, ErrInvalidSFDP
+		}
+		params.EraseBlockSize = 4096
+		params.EraseOpcode = byte(dw1 >> 8)
+	}
+
+	// The following DWORDs were added in JESD216A.
+	if len(table) >= 16*4 {
+		// DWORD 11: page size.
+		params.PageSize = 1 << ((dword(11) >> 4) & 0xf)
+
+		// DWORD 15: quad enable requirements.
+		params.QuadEnable = uint8((dword(15) >> 20) & 0b111)
+	} else if params.QuadReadOpcode != 0 {
+		// Older chips have the quad enable bit in status register 2 (like
+		// most chips), but it's not described in the table.
+		params.QuadEnable = 4
+	}
+
+	return params, nil
+}
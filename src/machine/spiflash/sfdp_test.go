@@ -0,0 +1,167 @@
+package spiflash
+
+import (
+	"errors"
+	"testing"
+)
+
+// SFDP table of a Winbond W25Q128JV (16MB), which has a JESD216B basic flash
+// parameter table of 16 DWORDs at address 0x80.
+var sfdpW25Q128 = []byte{
+	// SFDP header: signature, revision 1.6, one parameter header.
+	'S', 'F', 'D', 'P', 0x06, 0x01, 0x00, 0xff,
+	// Parameter header: basic flash parameters, revision 1.6, 16 DWORDs at
+	// address 0x80.
+	0x00, 0x06, 0x01, 0x10, 0x80, 0x00, 0x00, 0xff,
+}
+
+var basicParamsW25Q128 = []byte{
+	0xe5, 0x20, 0xf9, 0xff, // DWORD 1
+	0xff, 0xff, 0xff, 0x07, // DWORD 2: 128Mbit
+	0x44, 0xeb, 0x08, 0x6b, // DWORD 3: 1-1-4 read with opcode 0x6b and 8 dummy cycles
+	0x08, 0x3b, 0x42, 0xbb,
+	0xfe, 0xff, 0xff, 0xff,
+	0xff, 0xff, 0x00, 0x00,
+	0xff, 0xff, 0x40, 0xeb,
+	0x0c, 0x20, 0x0f, 0x52, // DWORD 8: 4kB erase (0x20), 32kB erase (0x52)
+	0x10, 0xd8, 0x00, 0x00, // DWORD 9: 64kB erase (0xd8)
+	0x36, 0x02, 0xa6, 0x00,
+	0x82, 0xea, 0x14, 0xc9, // DWORD 11: 256 byte pages
+	0xe9, 0x63, 0x76, 0x33,
+	0x7a, 0x75, 0x7a, 0x75,
+	0xf7, 0xa2, 0xd5, 0x5c,
+	0x19, 0xf7, 0x4d, 0xff, // DWORD 15: quad enable method 4
+	0xe9, 0x70, 0xf9, 0xa5,
+}
+
+// sfdpImage returns the SFDP address space with the given headers and the
+// basic flash parameter table at 0x80.
+func sfdpImage(headers, table []byte) []byte {
+	image := make([]byte, 0x80+len(table))
+	copy(image, headers)
+	copy(image[0x80:], table)
+	return image
+}
+
+// sfdpReader returns a read function for readSFDP that reads from the given
+// SFDP address space.
+func sfdpReader(image []byte) func(addr uint32, buf []byte) error {
+	return func(addr uint32, buf []byte) error {
+		if int(addr)+len(buf) > len(image) {
+			return errors.New("read beyond end of SFDP table")
+		}
+		copy(buf, image[addr:])
+		return nil
+	}
+}
+
+func TestReadSFDP(t *testing.T) {
+	params, err := readSFDP(sfdpReader(sfdpImage(sfdpW25Q128, basicParamsW25Q128)))
+	if err != nil {
+		t.Fatal("could not read SFDP table:", err)
+	}
+	expected := Params{
+		Size:                16 * 1024 * 1024,
+		PageSize:            256,
+		EraseBlockSize:      4096,
+		EraseOpcode:         0x20,
+		QuadReadOpcode:      0x6b,
+		QuadReadDummyCycles: 8,
+		QuadEnable:          4,
+	}
+	if params != expected {
+		t.Errorf("unexpected parameters:\n got: %+v\nwant: %+v", params, expected)
+	}
+}
+
+func TestReadSFDPErrors(t *testing.T) {
+	// No SFDP signature, as returned by chips that don't support the command.
+	image := sfdpImage(sfdpW25Q128, basicParamsW25Q128)
+	for i := 0; i < 4; i++ {
+		image[i] = 0xff
+	}
+	if _, err := readSFDP(sfdpReader(image)); err != ErrNoSFDP {
+		t.Errorf("expected ErrNoSFDP without signature, got %v", err)
+	}
+
+	// No basic flash parameter table.
+	headers := append([]byte(nil), sfdpW25Q128...)
+	headers[8] = 0x84 // sector map parameter table
+	if _, err := readSFDP(sfdpReader(sfdpImage(headers, basicParamsW25Q128))); err != ErrInvalidSFDP {
+		t.Errorf("expected ErrInvalidSFDP without basic parameter table, got %v", err)
+	}
+
+	// Read errors are passed on.
+	short := sfdpImage(sfdpW25Q128, basicParamsW25Q128)[:0x90]
+	if _, err := readSFDP(sfdpReader(short)); err == nil || err == ErrInvalidSFDP {
+		t.Errorf("expected read error, got %v", err)
+	}
+}
+
+func TestReadSFDPNewestTable(t *testing.T) {
+	// Two parameter headers for the basic flash parameter table: the first is
+	// the 9 DWORD version 1.0 table, the second the 16 DWORD version 1.6
+	// table at 0x80. The longer (newer) table must be used.
+	headers := []byte{
+		'S', 'F', 'D', 'P', 0x06, 0x01, 0x01, 0xff,
+		0x00, 0x00, 0x01, 0x09, 0xc0, 0x00, 0x00, 0xff,
+		0x00, 0x06, 0x01, 0x10, 0x80, 0x00, 0x00, 0xff,
+	}
+	image := sfdpImage(headers, basicParamsW25Q128)
+	image = append(image, basicParamsW25Q128[:9*4]...)
+	params, err := readSFDP(sfdpReader(image))
+	if err != nil {
+		t.Fatal("could not read SFDP table:", err)
+	}
+	if params.QuadEnable != 4 || params.PageSize != 256 {
+		t.Errorf("old table was used: %+v", params)
+	}
+}
+
+func TestParseBasicParams(t *testing.T) {
+	// A JESD216 (version 1.0) table only has 9 DWORDs. It doesn't describe the
+	// page size and quad enable method, so defaults are used.
+	params, err := parseBasicParams(basicParamsW25Q128[:9*4])
+	if err != nil {
+		t.Fatal("could not parse table:", err)
+	}
+	if params.PageSize != 256 || params.QuadEnable != 4 || params.Size != 16*1024*1024 {
+		t.Errorf("unexpected parameters for JESD216 table: %+v", params)
+	}
+
+	// Densities of 4Gbit and above are stored as a power of two.
+	table := append([]byte(nil), basicParamsW25Q128...)
+	table[4], table[5], table[6], table[7] = 34, 0x00, 0x00, 0x80 // 2^34 bits
+	params, err = parseBasicParams(table)
+	if err != nil {
+		t.Fatal("could not parse table:", err)
+	}
+	if params.Size != 2*1024*1024*1024 {
+		t.Errorf("expected size of 2GB, got %d", params.Size)
+	}
+
+	// Without erase types in DWORD 8 and 9, the 4kB erase command from
+	// DWORD 1 is used.
+	table = append([]byte(nil), basicParamsW25Q128...)
+	for i := 7 * 4; i < 9*4; i++ {
+		table[i] = 0
+	}
+	params, err = parseBasicParams(table)
+	if err != nil {
+		t.Fatal("could not parse table:", err)
+	}
+	if params.EraseBlockSize != 4096 || params.EraseOpcode != 0x20 {
+		t.Errorf("unexpected erase command: %d bytes with opcode %#x", params.EraseBlockSize, params.EraseOpcode)
+	}
+
+	// Without any erase command, the table is not usable.
+	table[0] &^= 0b11
+	if _, err := parseBasicParams(table); err != ErrInvalidSFDP {
+		t.Errorf("expected ErrInvalidSFDP without erase command, got %v", err)
+	}
+
+	// The table must have at least 9 DWORDs.
+	if _, err := parseBasicParams(basicParamsW25Q128[:8*4]); err != ErrInvalidSFDP {
+		t.Errorf("expected ErrInvalidSFDP for short table, got %v", err)
+	}
+}
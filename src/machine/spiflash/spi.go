@@ -0,0 +1,79 @@
+package spiflash
+
+import "machine"
+
+// SPI is the SPI bus used by the SPI transport. It is implemented by
+// machine.SPI.
+type SPI interface {
+	Tx(w, r []byte) error
+}
+
+type spiTransport struct {
+	bus SPI
+	cs  machine.Pin
+}
+
+// NewSPI returns a transport for a flash chip connected to a regular SPI bus,
+// with the given chip select pin. The SPI bus must already be configured (in
+// mode 0). The chip select pin is configured as an output.
+func NewSPI(bus SPI, cs machine.Pin) Transport {
+	cs.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	cs.High()
+	return &spiTransport{
+		bus: bus,
+		cs:  cs,
+	}
+}
+
+// Read sends the command and then reads len(data) bytes.
+func (t *spiTransport) Read(cmd Command, data []byte) error {
+	if cmd.Quad {
+		return ErrQuadNotSupported
+	}
+	t.cs.Low()
+	defer t.cs.High()
+	if err := t.sendCommand(cmd); err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return t.bus.Tx(nil, data)
+}
+
+// Write sends the command followed by data.
+func (t *spiTransport) Write(cmd Command, data []byte) error {
+	if cmd.Quad {
+		return ErrQuadNotSupported
+	}
+	t.cs.Low()
+	defer t.cs.High()
+	if err := t.sendCommand(cmd); err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return t.bus.Tx(data, nil)
+}
+
+// SupportsQuad returns false: a regular SPI bus has only one data line in each
+// direction.
+func (t *spiTransport) SupportsQuad() bool {
+	return false
+}
+
+// sendCommand sends the opcode, address and dummy cycles of the command.
+func (t *spiTransport) sendCommand(cmd Command) error {
+	var buf [1 + 4 + 4]byte
+	buf[0] = cmd.Opcode
+	n := 1
+	for i := cmd.AddressLen - 1; i >= 0; i-- {
+		buf[n] = byte(cmd.Address >> (8 * i))
+		n++
+	}
+	// There is one dummy byte per 8 dummy cycles. Commands with a number of
+	// dummy cycles that is not a multiple of 8 can't be used over SPI.
+	n += cmd.DummyCycles / 8
+	return t.bus.Tx(buf[:n], nil)
+}
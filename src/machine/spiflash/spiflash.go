@@ -0,0 +1,397 @@
+// Package spiflash is a driver for external NOR flash chips connected over SPI
+// or QSPI, such as the W25Q and GD25Q series that are found on many boards.
+//
+// The size and capabilities of the chip are discovered with the JEDEC SFDP
+// (Serial Flash Discoverable Parameters) table, so most chips work without any
+// chip specific configuration:
+//
+//	flash := spiflash.New(spiflash.NewSPI(machine.SPI1, machine.FLASH_CS))
+//	err := flash.Configure(spiflash.Config{})
+//
+// A Device implements the same BlockDevice interface as machine.Flash, so it
+// can be used by anything that stores data in machine.Flash, for example as the
+// Device of an MCUboot image slot (see package machine/mcuboot).
+package spiflash
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrNoSFDP            = errors.New("spiflash: chip doesn't have an SFDP table, set Config.Params")
+	ErrInvalidSFDP       = errors.New("spiflash: invalid SFDP table")
+	ErrQuadNotSupported  = errors.New("spiflash: transport doesn't support quad mode")
+	ErrTimeout           = errors.New("spiflash: timeout waiting for the chip")
+	errWritePastEOF      = errors.New("spiflash: cannot write beyond end of flash")
+	errReadPastEOF       = errors.New("spiflash: cannot read beyond end of flash")
+	errErasePastEOF      = errors.New("spiflash: cannot erase beyond end of flash")
+	errUnknownQuadEnable = errors.New("spiflash: unsupported quad enable method")
+)
+
+// Commands supported by (nearly) all NOR flash chips.
+const (
+	cmdWriteStatus      = 0x01 // write status register 1 (and optionally 2)
+	cmdPageProgram      = 0x02
+	cmdReadStatus       = 0x05 // read status register 1
+	cmdWriteEnable      = 0x06
+	cmdFastRead         = 0x0b
+	cmdWriteStatus2     = 0x31 // write status register 2 (only some chips)
+	cmdReadStatus2      = 0x35 // read status register 2
+	cmdWriteStatus2Alt  = 0x3e // write status register 2 (quad enable method 3)
+	cmdReadStatus2Alt   = 0x3f // read status register 2 (quad enable method 3)
+	cmdReadSFDP         = 0x5a
+	cmdEnableReset      = 0x66
+	cmdReset            = 0x99
+	cmdReadJEDECID      = 0x9f
+	cmdReleasePowerDown = 0xab
+	cmdEnter4ByteMode   = 0xb7
+
+	statusBusy = 0x01 // write in progress bit in status register 1
+)
+
+// Command is a single transaction with a flash chip. The opcode is followed by
+// AddressLen bytes of the address (if any), DummyCycles clock cycles and then
+// the data.
+type Command struct {
+	Opcode      byte
+	Address     uint32
+	AddressLen  int  // address length in bytes: 0 (no address), 3 or 4
+	DummyCycles int  // number of clock cycles between the address and the data
+	Quad        bool // transfer the data over four data lines (1-1-4 mode)
+}
+
+// Transport sends commands to a flash chip. It is implemented by NewSPI for any
+// SPI bus, and by QSPI controllers on chips that have them.
+type Transport interface {
+	// Read sends the command and then reads len(data) bytes.
+	Read(cmd Command, data []byte) error
+
+	// Write sends the command followed by data.
+	Write(cmd Command, data []byte) error
+
+	// SupportsQuad returns whether the transport can transfer data over
+	// four data lines.
+	SupportsQuad() bool
+}
+
+// JEDECID is the identification of a flash chip as returned by the JEDEC read
+// identification command.
+type JEDECID struct {
+	Manufacturer byte
+	MemoryType   byte
+	Capacity     byte
+}
+
+// Params describes the geometry and capabilities of a flash chip. They are
+// read from the SFDP table of the chip, or can be set in Config for chips
+// without such a table.
+type Params struct {
+	Size           int64 // size of the chip in bytes
+	PageSize       int64 // maximum size of a single program operation, usually 256
+	EraseBlockSize int64 // size of the smallest erasable block, usually 4096
+	EraseOpcode    byte  // command to erase a single block of EraseBlockSize
+
+	// Address4Byte is set if the chip supports 4-byte addresses, which are
+	// needed for chips bigger than 16MB.
+	Address4Byte bool
+
+	// QuadReadOpcode and QuadReadDummyCycles describe the 1-1-4 fast read
+	// command, if the chip supports it (QuadReadOpcode is 0 otherwise).
+	QuadReadOpcode      byte
+	QuadReadDummyCycles int
+
+	// QuadEnable is the way the quad enable bit is set, see the quad enable
+	// requirements in JESD216 (bits 22:20 of the 15th DWORD of the basic flash
+	// parameter table).
+	QuadEnable uint8
+}
+
+// Config is the configuration of a flash chip.
+type Config struct {
+	// Params describes the chip. If it is nil, the parameters are read from
+	// the SFDP table of the chip.
+	Params *Params
+
+	// Quad enables quad mode reads if both the chip and the transport support
+	// them.
+	Quad bool
+}
+
+// Device is a NOR flash chip.
+type Device struct {
+	transport  Transport
+	id         JEDECID
+	params     Params
+	addressLen int
+	quad       bool
+}
+
+// New returns a new flash device that uses the given transport. It must be
+// configured with Configure before use.
+func New(transport Transport) *Device {
+	return &Device{
+		transport: transport,
+	}
+}
+
+// Configure resets the chip and reads its identification and (unless they are
+// given in the config) its parameters. It switches to 4-byte addressing for
+// chips bigger than 16MB and enables quad mode if requested.
+func (d *Device) Configure(config Config) error {
+	// The chip may be in deep power down mode (after a soft reset of the
+	// microcontroller for example) or in a different addressing mode.
+	if err := d.command(cmdReleasePowerDown); err != nil {
+		return err
+	}
+	if err := d.command(cmdEnableReset); err != nil {
+		return err
+	}
+	if err := d.command(cmdReset); err != nil {
+		return err
+	}
+	// Most chips need 30µs to reset.
+	time.Sleep(50 * time.Microsecond)
+
+	var id [3]byte
+	if err := d.transport.Read(Command{Opcode: cmdReadJEDECID}, id[:]); err != nil {
+		return err
+	}
+	d.id = JEDECID{id[0], id[1], id[2]}
+
+	if config.Params != nil {
+		d.params = *config.Params
+	} else {
+		params, err := readSFDP(func(addr uint32, buf []byte) error {
+			return d.transport.Read(Command{
+				Opcode:      cmdReadSFDP,
+				Address:     addr,
+				AddressLen:  3,
+				DummyCycles: 8,
+			}, buf)
+		})
+		if err != nil {
+			return err
+		}
+		d.params = params
+	}
+
+	d.addressLen = 3
+	if d.params.Size > 1<<24 {
+		if !d.params.Address4Byte {
+			return ErrInvalidSFDP
+		}
+		if err := d.command(cmdEnter4ByteMode); err != nil {
+			return err
+		}
+		d.addressLen = 4
+	}
+
+	d.quad = false
+	if config.Quad && d.params.QuadReadOpcode != 0 {
+		if !d.transport.SupportsQuad() {
+			return ErrQuadNotSupported
+		}
+		if err := d.enableQuad(); err != nil {
+			return err
+		}
+		d.quad = true
+	}
+	return nil
+}
+
+// ID returns the JEDEC identification of the chip.
+func (d *Device) ID() JEDECID {
+	return d.id
+}
+
+// Params returns the parameters of the chip.
+func (d *Device) Params() Params {
+	return d.params
+}
+
+// ReadAt reads the given number of bytes from the flash chip.
+func (d *Device) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < 0 || off+int64(len(p)) > d.params.Size {
+		return 0, errReadPastEOF
+	}
+	cmd := Command{
+		Opcode:      cmdFastRead,
+		Address:     uint32(off),
+		AddressLen:  d.addressLen,
+		DummyCycles: 8,
+	}
+	if d.quad {
+		cmd.Opcode = d.params.QuadReadOpcode
+		cmd.DummyCycles = d.params.QuadReadDummyCycles
+		cmd.Quad = true
+	}
+	if err := d.transport.Read(cmd, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteAt writes the given number of bytes to the flash chip. The data is
+// split in page program operations. This method assumes that the destination
+// is already erased.
+func (d *Device) WriteAt(p []byte, off int64) (n int, err error) {
+	if off < 0 || off+int64(len(p)) > d.params.Size {
+		return 0, errWritePastEOF
+	}
+	for n < len(p) {
+		// A page program operation wraps around at the end of the page, so
+		// don't write past it.
+		chunk := int(d.params.PageSize - (off+int64(n))%d.params.PageSize)
+		if chunk > len(p)-n {
+			chunk = len(p) - n
+		}
+		if err := d.writeEnable(); err != nil {
+			return n, err
+		}
+		err := d.transport.Write(Command{
+			Opcode:     cmdPageProgram,
+			Address:    uint32(off) + uint32(n),
+			AddressLen: d.addressLen,
+		}, p[n:n+chunk])
+		if err != nil {
+			return n, err
+		}
+		if err := d.waitUntilReady(); err != nil {
+			return n, err
+		}
+		n += chunk
+	}
+	return n, nil
+}
+
+// Size returns the number of bytes in this block device.
+func (d *Device) Size() int64 {
+	return d.params.Size
+}
+
+// WriteBlockSize returns the block size in which data can be written to
+// memory. It can be used by a client to optimize writes, non-aligned writes
+// should always work correctly.
+func (d *Device) WriteBlockSize() int64 {
+	return d.params.PageSize
+}
+
+// EraseBlockSize returns the smallest erasable area on this particular chip
+// in bytes. This is used for the block size in EraseBlocks.
+func (d *Device) EraseBlockSize() int64 {
+	return d.params.EraseBlockSize
+}
+
+// EraseBlocks erases the given number of blocks. The start and len parameters
+// are in block numbers, use EraseBlockSize to map addresses to blocks.
+func (d *Device) EraseBlocks(start, len int64) error {
+	if start < 0 || (start+len)*d.params.EraseBlockSize > d.params.Size {
+		return errErasePastEOF
+	}
+	for block := start; block < start+len; block++ {
+		if err := d.writeEnable(); err != nil {
+			return err
+		}
+		err := d.transport.Write(Command{
+			Opcode:     d.params.EraseOpcode,
+			Address:    uint32(block * d.params.EraseBlockSize),
+			AddressLen: d.addressLen,
+		}, nil)
+		if err != nil {
+			return err
+		}
+		if err := d.waitUntilReady(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// command sends a command without address and data.
+func (d *Device) command(opcode byte) error {
+	return d.transport.Write(Command{Opcode: opcode}, nil)
+}
+
+func (d *Device) writeEnable() error {
+	return d.command(cmdWriteEnable)
+}
+
+func (d *Device) readStatus(opcode byte) (byte, error) {
+	var status [1]byte
+	err := d.transport.Read(Command{Opcode: opcode}, status[:])
+	return status[0], err
+}
+
+// waitUntilReady waits until the current program or erase operation has
+// finished. Erasing a block usually takes less than 400ms.
+func (d *Device) waitUntilReady() error {
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		status, err := d.readStatus(cmdReadStatus)
+		if err != nil {
+			return err
+		}
+		if status&statusBusy == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return ErrTimeout
+		}
+	}
+}
+
+// enableQuad sets the quad enable bit of the chip, using the method from the
+// quad enable requirements of the SFDP table.
+func (d *Device) enableQuad() error {
+	var (
+		readOpcode  byte = cmdReadStatus2
+		writeOpcode byte
+		bit         byte
+		bothRegs    bool // write status register 1 and 2 together
+	)
+	switch d.params.QuadEnable {
+	case 0:
+		// No quad enable bit.
+		return nil
+	case 1, 4, 5:
+		// Bit 1 of status register 2, written together with status register
+		// 1.
+		writeOpcode, bit, bothRegs = cmdWriteStatus, 1<<1, true
+	case 2:
+		// Bit 6 of status register 1.
+		readOpcode, writeOpcode, bit = cmdReadStatus, cmdWriteStatus, 1<<6
+	case 3:
+		// Bit 7 of status register 2, with separate commands.
+		readOpcode, writeOpcode, bit = cmdReadStatus2Alt, cmdWriteStatus2Alt, 1<<7
+	case 6:
+		// Bit 1 of status register 2, with a separate write command.
+		writeOpcode, bit = cmdWriteStatus2, 1<<1
+	default:
+		return errUnknownQuadEnable
+	}
+
+	status, err := d.readStatus(readOpcode)
+	if err != nil {
+		return err
+	}
+	if status&bit != 0 {
+		// Already enabled.
+		return nil
+	}
+	data := []byte{status | bit}
+	if bothRegs {
+		status1, err := d.readStatus(cmdReadStatus)
+		if err != nil {
+			return err
+		}
+		data = []byte{status1, status | bit}
+	}
+	if err := d.writeEnable(); err != nil {
+		return err
+	}
+	if err := d.transport.Write(Command{Opcode: writeOpcode}, data); err != nil {
+		return err
+	}
+	return d.waitUntilReady()
+}
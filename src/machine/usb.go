@@ -5,6 +5,7 @@ package machine
 import (
 	"machine/usb"
 	"machine/usb/descriptor"
+	"runtime/interrupt"
 
 	"errors"
 )
@@ -83,8 +84,10 @@ func strToUTF16LEDescriptor(in string, out []byte) {
 const cdcLineInfoSize = 7
 
 var (
-	ErrUSBReadTimeout = errors.New("USB read timeout")
-	ErrUSBBytesRead   = errors.New("USB invalid number of bytes read")
+	ErrUSBReadTimeout          = errors.New("USB read timeout")
+	ErrUSBBytesRead            = errors.New("USB invalid number of bytes read")
+	ErrUSBNotSuspended         = errors.New("USB bus is not suspended")
+	ErrUSBRemoteWakeupDisabled = errors.New("USB remote wakeup not enabled by host")
 )
 
 var (
@@ -95,6 +98,24 @@ var (
 
 	usbConfiguration uint8
 	usbSetInterface  uint8
+
+	usbState          usb.DeviceState
+	usbStateHandler   func(USBState)
+	usbSuspendHandler func()
+	usbResumeHandler  func()
+)
+
+// USBState is the state of the USB device as seen from the bus, which follows
+// the device states of the USB specification.
+type USBState = usb.State
+
+const (
+	USBStateDetached   = usb.StateDetached
+	USBStateAttached   = usb.StateAttached
+	USBStateDefault    = usb.StateDefault
+	USBStateAddressed  = usb.StateAddressed
+	USBStateConfigured = usb.StateConfigured
+	USBStateSuspended  = usb.StateSuspended
 )
 
 //go:align 4
@@ -131,28 +152,18 @@ var (
 )
 
 // sendDescriptor creates and sends the various USB descriptor types that
-// can be requested by the host.
-func sendDescriptor(setup usb.Setup) {
+// can be requested by the host. It returns false if the request must be
+// stalled.
+func sendDescriptor(setup usb.Setup) bool {
 	switch setup.WValueH {
 	case descriptor.TypeConfiguration:
 		sendUSBPacket(0, usbDescriptor.Configuration, setup.WLength)
-		return
+		return true
 	case descriptor.TypeDevice:
-		// composite descriptor
-		switch {
-		case (usbDescriptorConfig & usb.DescriptorConfigHID) > 0:
-			usbDescriptor = descriptor.CDCHID
-		case (usbDescriptorConfig & usb.DescriptorConfigMIDI) > 0:
-			usbDescriptor = descriptor.CDCMIDI
-		case (usbDescriptorConfig & usb.DescriptorConfigJoystick) > 0:
-			usbDescriptor = descriptor.CDCJoystick
-		default:
-			usbDescriptor = descriptor.CDC
-		}
-
 		usbDescriptor.Configure(usbVendorID(), usbProductID())
+		configureUSBInterfaceNames()
 		sendUSBPacket(0, usbDescriptor.Device, setup.WLength)
-		return
+		return true
 
 	case descriptor.TypeString:
 		switch setup.WValueL {
@@ -176,21 +187,33 @@ func sendDescriptor(setup usb.Setup) {
 		case usb.ISERIAL:
 			// TODO: allow returning a product serial number
 			SendZlp()
+
+		default:
+			if setup.WValueL >= usb.IINTERFACE && setup.WValueL < usb.IINTERFACE+usb.NumberOfInterfaces {
+				if b := usbInterfaceStrings[setup.WValueL-usb.IINTERFACE]; b != nil {
+					sendUSBPacket(0, b, setup.WLength)
+					return true
+				}
+			}
+			SendZlp()
 		}
-		return
+		return true
 	case descriptor.TypeHIDReport:
 		if h, ok := usbDescriptor.HID[setup.WIndex]; ok {
 			sendUSBPacket(0, h, setup.WLength)
-			return
+			return true
 		}
-	case descriptor.TypeDeviceQualifier:
-		// skip
+	case descriptor.TypeDeviceQualifier, descriptor.TypeOtherSpeedConfiguration:
+		// None of the supported USB peripherals can operate at high speed,
+		// and a full speed only device must answer these with a request
+		// error.
+		return false
 	default:
 	}
 
 	// do not know how to handle this message, so return zero
 	SendZlp()
-	return
+	return true
 }
 
 func handleStandardSetup(setup usb.Setup) bool {
@@ -227,11 +250,18 @@ func handleStandardSetup(setup usb.Setup) bool {
 		return true
 
 	case usb.SET_ADDRESS:
-		return handleUSBSetAddress(setup)
+		if !handleUSBSetAddress(setup) {
+			return false
+		}
+		if setup.WValueL != 0 {
+			setUSBState(USBStateAddressed)
+		} else {
+			setUSBState(USBStateDefault)
+		}
+		return true
 
 	case usb.GET_DESCRIPTOR:
-		sendDescriptor(setup)
-		return true
+		return sendDescriptor(setup)
 
 	case usb.SET_DESCRIPTOR:
 		return false
@@ -249,6 +279,11 @@ func handleStandardSetup(setup usb.Setup) bool {
 
 			usbConfiguration = setup.WValueL
 			USBDev.InitEndpointComplete = true
+			if usbConfiguration != 0 {
+				setUSBState(USBStateConfigured)
+			} else {
+				setUSBState(USBStateAddressed)
+			}
 
 			SendZlp()
 			return true
@@ -272,49 +307,342 @@ func handleStandardSetup(setup usb.Setup) bool {
 	}
 }
 
+// SetSuspendHandlers sets the callbacks that are called when the host
+// suspends the bus and when the bus resumes again, either because the host
+// resumed it or after a remote wakeup. A suspended device must draw very little
+// current, so these callbacks are a good place to gate the clocks of other
+// peripherals. They are called from an interrupt and must not allocate. Either
+// callback may be nil.
+func (dev *USBDevice) SetSuspendHandlers(suspend, resume func()) {
+	usbSuspendHandler = suspend
+	usbResumeHandler = resume
+}
+
+// Suspended returns whether the host has currently suspended the bus.
+func (dev *USBDevice) Suspended() bool {
+	return usbState.State() == USBStateSuspended
+}
+
+// State returns the current state of the device.
+func (dev *USBDevice) State() USBState {
+	return usbState.State()
+}
+
+// SetStateHandler sets a callback that is called on every state change, for
+// example to start goroutines that use the USB connection once the device is
+// configured, or to switch to a different power profile when the device is
+// detached. It is called from an interrupt and must not allocate.
+func (dev *USBDevice) SetStateHandler(fn func(USBState)) {
+	usbStateHandler = fn
+}
+
+// ConfigureVBUS enables VBUS detection on the given pin, which must be
+// connected to VBUS (usually through a voltage divider). Without it, the
+// device can only find out that it is attached when the host resets the bus,
+// and never finds out that it was detached.
+func (dev *USBDevice) ConfigureVBUS(pin Pin) error {
+	pin.Configure(PinConfig{Mode: PinInput})
+	handleUSBVBUS(pin)
+	return pin.SetInterrupt(PinRising|PinFalling, handleUSBVBUS)
+}
+
+// handleUSBVBUS is called when the level of the VBUS pin changes.
+func handleUSBVBUS(pin Pin) {
+	if usbState.VBUS(pin.Get()) {
+		notifyUSBState()
+	}
+}
+
+// setUSBState changes the state of the device and calls the state handler.
+func setUSBState(state USBState) {
+	if usbState.Set(state) {
+		notifyUSBState()
+	}
+}
+
+// notifyUSBState calls the state handler after a state change.
+func notifyUSBState() {
+	if usbStateHandler != nil {
+		usbStateHandler(usbState.State())
+	}
+}
+
+// RemoteWakeup signals the host to resume the suspended bus, for example after
+// a key press on a HID keyboard. The host must have enabled remote wakeup,
+// which it usually only does for HID devices.
+func (dev *USBDevice) RemoteWakeup() error {
+	if usbState.State() != USBStateSuspended {
+		return ErrUSBNotSuspended
+	}
+	if !isRemoteWakeUpEnabled {
+		return ErrUSBRemoteWakeupDisabled
+	}
+	sendUSBRemoteWakeup()
+	return nil
+}
+
+// handleUSBSuspend is called from the USB interrupt when the bus is suspended.
+func handleUSBSuspend() {
+	if !usbState.Suspend() {
+		return
+	}
+	notifyUSBState()
+	if usbSuspendHandler != nil {
+		usbSuspendHandler()
+	}
+}
+
+// handleUSBResume is called from the USB interrupt when the bus resumes after
+// being suspended. A bus reset also ends a suspend.
+func handleUSBResume() {
+	if !usbState.Resume() {
+		return
+	}
+	notifyUSBState()
+	if usbResumeHandler != nil {
+		usbResumeHandler()
+	}
+}
+
+// handleUSBBusReset resets the state of the device after a bus reset.
+func handleUSBBusReset() {
+	isRemoteWakeUpEnabled = false
+	handleUSBResume()
+	setUSBState(USBStateDefault)
+}
+
+// usbInterfaceStrings contains the string descriptors for interface names set
+// with SetUSBInterfaceName. They are created in advance because the descriptors
+// are sent from an interrupt, where heap allocations are not allowed.
+var usbInterfaceStrings [usb.NumberOfInterfaces][]byte
+
+var errUSBInvalidInterface = errors.New("USB interface number out of range")
+
+// SetUSBInterfaceName sets the name of the given interface (for example
+// usb.HID_INTERFACE), which is shown by the host in device managers. This
+// function must be executed from the init().
+func SetUSBInterfaceName(iface uint8, name string) error {
+	if iface >= usb.NumberOfInterfaces {
+		return errUSBInvalidInterface
+	}
+	usbInterfaceStrings[iface] = descriptor.String(name)
+	return nil
+}
+
+// configureUSBInterfaceNames points the interface descriptors of the current
+// configuration at the interface name strings, if a name was set.
+func configureUSBInterfaceNames() {
+	for i, b := range usbInterfaceStrings {
+		if b == nil {
+			continue
+		}
+		iface, err := descriptor.FindInterfaceType(usbDescriptor.Configuration, uint8(i))
+		if err != nil {
+			continue
+		}
+		iface.Interface(usb.IINTERFACE + uint8(i))
+	}
+}
+
+// SetUSBHIDReportDescriptor replaces the default HID report descriptor (a
+// keyboard, mouse and consumer control combo) with a custom one, for example
+// one built with descriptor.HIDReport. This function must be executed from the
+// init().
+func SetUSBHIDReportDescriptor(desc []byte) error {
+	usbFunctionHID = descriptor.FunctionHID
+	usbFunctionHID.HID = map[uint16][]byte{usb.HID_INTERFACE: desc}
+	buildUSBDescriptor()
+	return nil
+}
+
+var (
+	// Functions of the composite device, other than CDC which is always the
+	// first function. They are copies, so that they can be changed without
+	// changing the defaults in the descriptor package.
+	usbFunctionHID      = descriptor.FunctionHID
+	usbFunctionJoystick = descriptor.FunctionJoystick
+	usbFunctions        descriptor.CustomFunctions
+)
+
+var (
+	errUSBTooManyInterfaces    = errors.New("USB: too many interfaces")
+	errUSBNoFreeEndpoint       = errors.New("USB: no free endpoint of this type")
+	errUSBEndpointNotAllocated = errors.New("USB: function uses an endpoint that was not allocated")
+)
+
+// usbEndpointTypeMask selects the transfer type from an endpoint configuration
+// or from the attributes of an endpoint descriptor.
+const usbEndpointTypeMask = 0x3
+
+// usbEndpointAllocated is the set of endpoints handed out by
+// AllocateUSBEndpoint, as a bit mask.
+var usbEndpointAllocated uint32
+
+// AllocateUSBEndpoint reserves a free endpoint for the given configuration,
+// which is a transfer type combined with a direction (for example
+// usb.ENDPOINT_TYPE_BULK|usb.EndpointIn), and sets its handlers. The returned
+// endpoint number must be used in the endpoint descriptors of a function that
+// is then enabled with EnableUSBFunction.
+//
+// Endpoints are handed out from the highest number down, skipping endpoints
+// that are in use or that the hardware does not support for this transfer
+// type. Built-in classes (CDC, HID, MIDI) use fixed endpoints, so they must be
+// enabled before allocating endpoints. This function must be executed from
+// the init().
+func AllocateUSBEndpoint(config uint32, txHandler func(), rxHandler func([]byte)) (uint8, error) {
+	if usbEndpointTypes&(1<<(config&usbEndpointTypeMask)) == 0 {
+		return 0, errUSBNoFreeEndpoint
+	}
+	count := len(endPoints)
+	if usbEndpointCount < count {
+		count = usbEndpointCount
+	}
+	for ep := count - 1; ep > 0; ep-- {
+		if endPoints[ep] != usb.ENDPOINT_TYPE_DISABLE {
+			continue
+		}
+		endPoints[ep] = config
+		usbEndpointAllocated |= 1 << ep
+		usbTxHandler[ep] = txHandler
+		usbRxHandler[ep] = rxHandler
+		return uint8(ep), nil
+	}
+	return 0, errUSBNoFreeEndpoint
+}
+
+// claimUSBEndpoint configures a fixed endpoint of a built-in class.
+func claimUSBEndpoint(ep int, config uint32) {
+	if usbEndpointAllocated&(1<<ep) != 0 {
+		panic("machine: USB endpoint of built-in class already allocated")
+	}
+	endPoints[ep] = config
+}
+
+// checkUSBEndpoints verifies that all endpoints used by the function were
+// allocated with AllocateUSBEndpoint for the same type and direction.
+func checkUSBEndpoints(f *descriptor.Function) error {
+	for _, d := range f.Descriptors {
+		if len(d) < 4 || d[1] != descriptor.TypeEndpoint {
+			continue
+		}
+		ep := d[2] & 0x0f
+		config := uint32(d[2]&usb.EndpointIn) | uint32(d[3]&usbEndpointTypeMask)
+		if usbEndpointAllocated&(1<<ep) == 0 || endPoints[ep] != config {
+			return errUSBEndpointNotAllocated
+		}
+	}
+	return nil
+}
+
+// EnableUSBFunction adds a function, such as a mass storage device, to the
+// composite USB device after the built-in functions. Its interfaces are
+// renumbered to follow those of the functions before it, and the number of its
+// first interface is returned. setupHandler is called for class requests to
+// that interface. The endpoints of the function must have been allocated with
+// AllocateUSBEndpoint. This function must be executed from the init().
+//
+// The built-in classes (CDC, HID, MIDI) must be enabled before: enabling them
+// afterwards would renumber the interfaces of this function, so it panics.
+func EnableUSBFunction(f descriptor.Function, setupHandler func(usb.Setup) bool) (uint8, error) {
+	if err := checkUSBEndpoints(&f); err != nil {
+		return 0, err
+	}
+	_, count := f.Interfaces()
+	if int(usbInterfaceCount())+int(count) > usb.NumberOfInterfaces {
+		return 0, errUSBTooManyInterfaces
+	}
+	_, _, builtin := usbBuiltinFunctions()
+	first, err := usbFunctions.Add(f, builtin...)
+	if err != nil {
+		return 0, err
+	}
+	usbSetupHandler[first] = setupHandler
+	buildUSBDescriptor()
+	return first, nil
+}
+
+// usbInterfaceCount returns the number of interfaces of the current
+// configuration.
+func usbInterfaceCount() uint8 {
+	return usbDescriptor.Configuration[4]
+}
+
+// usbBuiltinFunctions returns the device and configuration descriptors and
+// the functions of the enabled built-in classes.
+func usbBuiltinFunctions() (device, configuration []byte, functions []descriptor.Function) {
+	device = descriptor.DeviceCDC.Bytes()
+	configuration = descriptor.ConfigurationCDC.Bytes()
+	functions = []descriptor.Function{descriptor.FunctionCDC}
+	switch {
+	case (usbDescriptorConfig & usb.DescriptorConfigHID) > 0:
+		functions = append(functions, usbFunctionHID)
+	case (usbDescriptorConfig & usb.DescriptorConfigJoystick) > 0:
+		device = descriptor.DeviceJoystick.Bytes()
+		configuration = descriptor.ConfigurationCDCJoystick.Bytes()
+		functions = append(functions, usbFunctionJoystick)
+	}
+	if (usbDescriptorConfig & usb.DescriptorConfigMIDI) > 0 {
+		functions = append(functions, descriptor.FunctionMIDI)
+	}
+	return
+}
+
+// buildUSBDescriptor assembles the device and configuration descriptors from
+// the enabled functions. It is called each time a function is enabled, and
+// never from an interrupt because it allocates memory.
+func buildUSBDescriptor() {
+	device, configuration, builtin := usbBuiltinFunctions()
+	d, err := usbFunctions.Composite(device, configuration, builtin...)
+	if err != nil {
+		panic("machine: USB built-in class enabled after EnableUSBFunction")
+	}
+
+	// The descriptors may be requested from the USB interrupt at any time.
+	state := interrupt.Disable()
+	usbDescriptor = d
+	interrupt.Restore(state)
+}
+
 func EnableCDC(txHandler func(), rxHandler func([]byte), setupHandler func(usb.Setup) bool) {
 	usbDescriptorConfig |= usb.DescriptorConfigCDC
-	endPoints[usb.CDC_ENDPOINT_ACM] = (usb.ENDPOINT_TYPE_INTERRUPT | usb.EndpointIn)
-	endPoints[usb.CDC_ENDPOINT_OUT] = (usb.ENDPOINT_TYPE_BULK | usb.EndpointOut)
-	endPoints[usb.CDC_ENDPOINT_IN] = (usb.ENDPOINT_TYPE_BULK | usb.EndpointIn)
+	claimUSBEndpoint(usb.CDC_ENDPOINT_ACM, usb.ENDPOINT_TYPE_INTERRUPT|usb.EndpointIn)
+	claimUSBEndpoint(usb.CDC_ENDPOINT_OUT, usb.ENDPOINT_TYPE_BULK|usb.EndpointOut)
+	claimUSBEndpoint(usb.CDC_ENDPOINT_IN, usb.ENDPOINT_TYPE_BULK|usb.EndpointIn)
 	usbRxHandler[usb.CDC_ENDPOINT_OUT] = rxHandler
 	usbTxHandler[usb.CDC_ENDPOINT_IN] = txHandler
 	usbSetupHandler[usb.CDC_ACM_INTERFACE] = setupHandler // 0x02 (Communications and CDC Control)
 	usbSetupHandler[usb.CDC_DATA_INTERFACE] = nil         // 0x0A (CDC-Data)
+	buildUSBDescriptor()
 }
 
 // EnableHID enables HID. This function must be executed from the init().
 func EnableHID(txHandler func(), rxHandler func([]byte), setupHandler func(usb.Setup) bool) {
 	usbDescriptorConfig |= usb.DescriptorConfigHID
-	endPoints[usb.HID_ENDPOINT_IN] = (usb.ENDPOINT_TYPE_INTERRUPT | usb.EndpointIn)
+	claimUSBEndpoint(usb.HID_ENDPOINT_IN, usb.ENDPOINT_TYPE_INTERRUPT|usb.EndpointIn)
 	usbTxHandler[usb.HID_ENDPOINT_IN] = txHandler
 	usbSetupHandler[usb.HID_INTERFACE] = setupHandler // 0x03 (HID - Human Interface Device)
+	buildUSBDescriptor()
 }
 
 // EnableMIDI enables MIDI. This function must be executed from the init().
 func EnableMIDI(txHandler func(), rxHandler func([]byte), setupHandler func(usb.Setup) bool) {
 	usbDescriptorConfig |= usb.DescriptorConfigMIDI
-	endPoints[usb.MIDI_ENDPOINT_OUT] = (usb.ENDPOINT_TYPE_BULK | usb.EndpointOut)
-	endPoints[usb.MIDI_ENDPOINT_IN] = (usb.ENDPOINT_TYPE_BULK | usb.EndpointIn)
+	claimUSBEndpoint(usb.MIDI_ENDPOINT_OUT, usb.ENDPOINT_TYPE_BULK|usb.EndpointOut)
+	claimUSBEndpoint(usb.MIDI_ENDPOINT_IN, usb.ENDPOINT_TYPE_BULK|usb.EndpointIn)
 	usbRxHandler[usb.MIDI_ENDPOINT_OUT] = rxHandler
 	usbTxHandler[usb.MIDI_ENDPOINT_IN] = txHandler
+	buildUSBDescriptor()
 }
 
 // EnableJoystick enables HID. This function must be executed from the init().
 func EnableJoystick(txHandler func(), rxHandler func([]byte), setupHandler func(usb.Setup) bool, hidDesc []byte) {
-	class, err := descriptor.FindClassHIDType(descriptor.CDCJoystick.Configuration, descriptor.ClassHIDJoystick.Bytes())
-	if err != nil {
-		// TODO: some way to notify about error
-		return
-	}
-
-	class.ClassLength(uint16(len(hidDesc)))
-	descriptor.CDCJoystick.HID[2] = hidDesc
+	usbFunctionJoystick.HID = map[uint16][]byte{usb.HID_INTERFACE: hidDesc}
 
 	usbDescriptorConfig |= usb.DescriptorConfigJoystick
-	endPoints[usb.HID_ENDPOINT_OUT] = (usb.ENDPOINT_TYPE_INTERRUPT | usb.EndpointOut)
+	claimUSBEndpoint(usb.HID_ENDPOINT_OUT, usb.ENDPOINT_TYPE_INTERRUPT|usb.EndpointOut)
 	usbRxHandler[usb.HID_ENDPOINT_OUT] = rxHandler
-	endPoints[usb.HID_ENDPOINT_IN] = (usb.ENDPOINT_TYPE_INTERRUPT | usb.EndpointIn)
+	claimUSBEndpoint(usb.HID_ENDPOINT_IN, usb.ENDPOINT_TYPE_INTERRUPT|usb.EndpointIn)
 	usbTxHandler[usb.HID_ENDPOINT_IN] = txHandler
 	usbSetupHandler[usb.HID_INTERFACE] = setupHandler // 0x03 (HID - Human Interface Device)
+	buildUSBDescriptor()
 }
@@ -0,0 +1,216 @@
+package descriptor
+
+import "errors"
+
+// Function is a function of a composite device: a group of interfaces (one for
+// HID, two for CDC and MIDI) together with their class specific and endpoint
+// descriptors.
+type Function struct {
+	// Descriptors of the function, in the order in which they appear in the
+	// configuration descriptor. The interface numbers in these descriptors
+	// are shifted by Composite so that the function follows the functions
+	// before it.
+	Descriptors [][]byte
+
+	// HID report descriptors, by interface number (before shifting).
+	HID map[uint16][]byte
+}
+
+var FunctionCDC = Function{
+	Descriptors: [][]byte{
+		InterfaceAssociationCDC.Bytes(),
+		InterfaceCDCControl.Bytes(),
+		ClassSpecificCDCHeader.Bytes(),
+		ClassSpecificCDCCallManagement.Bytes(),
+		ClassSpecificCDCACM.Bytes(),
+		ClassSpecificCDCUnion.Bytes(),
+		EndpointEP1IN.Bytes(),
+		InterfaceCDCData.Bytes(),
+		EndpointEP2OUT.Bytes(),
+		EndpointEP3IN.Bytes(),
+	},
+}
+
+var FunctionHID = Function{
+	Descriptors: [][]byte{
+		InterfaceHID.Bytes(),
+		ClassHID.Bytes(),
+		EndpointEP4IN.Bytes(),
+	},
+	HID: CDCHID.HID,
+}
+
+var FunctionJoystick = Function{
+	Descriptors: [][]byte{
+		InterfaceHIDJoystick.Bytes(),
+		ClassHIDJoystick.Bytes(),
+		EndpointEP4IN.Bytes(),
+		EndpointEP5OUT.Bytes(),
+	},
+	HID: map[uint16][]byte{},
+}
+
+var FunctionMIDI = Function{
+	Descriptors: [][]byte{
+		InterfaceAssociationMIDI.Bytes(),
+		InterfaceAudio.Bytes(),
+		ClassSpecificAudioInterface.Bytes(),
+		InterfaceMIDIStreaming.Bytes(),
+		ClassSpecificMIDIHeader.Bytes(),
+		ClassSpecificMIDIInJack1.Bytes(),
+		ClassSpecificMIDIInJack2.Bytes(),
+		ClassSpecificMIDIOutJack1.Bytes(),
+		ClassSpecificMIDIOutJack2.Bytes(),
+		EndpointEP7OUT.Bytes(),
+		ClassSpecificMIDIOutEndpoint.Bytes(),
+		EndpointEP6IN.Bytes(),
+		ClassSpecificMIDIInEndpoint.Bytes(),
+	},
+}
+
+// Interfaces returns the interface numbers used by the function, as the first
+// interface number and the number of interfaces.
+func (f *Function) Interfaces() (first, count uint8) {
+	first = 0xff
+	for _, d := range f.Descriptors {
+		if len(d) >= interfaceTypeLen && d[1] == TypeInterface && d[3] == 0 {
+			if d[2] < first {
+				first = d[2]
+			}
+			count++
+		}
+	}
+	if count == 0 {
+		first = 0
+	}
+	return
+}
+
+// Composite builds the descriptor of a composite device from the device
+// descriptor, the configuration descriptor header (of which only the
+// attributes and maximum power are used) and the given functions. The
+// interfaces of every function are renumbered to follow those of the function
+// before it, and the report length of HID class descriptors is set from the
+// HID report descriptors of the function. The input descriptors are not
+// modified.
+func Composite(device, configuration []byte, functions ...Function) Descriptor {
+	parts := [][]byte{append([]byte(nil), configuration...)}
+	hid := make(map[uint16][]byte)
+
+	var next uint8
+	for i := range functions {
+		f := &functions[i]
+		first, count := f.Interfaces()
+		delta := next - first
+
+		// Interface class and subclass of the interface that the following
+		// class specific descriptors belong to.
+		var class, subclass uint8
+		var iface uint16
+		for _, d := range f.Descriptors {
+			d = append([]byte(nil), d...)
+			switch d[1] {
+			case TypeInterface:
+				class, subclass = d[5], d[6]
+				iface = uint16(d[2])
+				d[2] += delta
+			case TypeInterfaceAssociation:
+				d[2] += delta
+			case TypeClassSpecific:
+				shiftClassSpecificInterfaces(d, class, subclass, delta)
+			case TypeClassHID:
+				if report, ok := f.HID[iface]; ok && len(d) >= ClassHIDTypeLen {
+					ClassHIDType{data: d}.ClassLength(uint16(len(report)))
+				}
+			}
+			parts = append(parts, d)
+		}
+		for iface, report := range f.HID {
+			// Interface numbers are 8 bits, and delta relies on wrapping
+			// around to move interfaces to a lower number.
+			hid[uint16(uint8(iface)+delta)] = report
+		}
+		next += count
+	}
+
+	conf := ConfigurationType{data: parts[0]}
+	conf.NumInterfaces(next)
+	c := Append(parts)
+	ConfigurationType{data: c}.TotalLength(uint16(len(c)))
+
+	return Descriptor{
+		Device:        device,
+		Configuration: c,
+		HID:           hid,
+	}
+}
+
+// ErrFunctionOrder is returned when the built-in functions of a composite
+// device change after a custom function was added.
+var ErrFunctionOrder = errors.New("USB: built-in function enabled after a custom function")
+
+// CustomFunctions is the list of functions that follow the built-in functions
+// (CDC, HID, MIDI) of a composite device. The interface numbers of a custom
+// function are handed out when it is added, so the built-in functions before
+// it must not change afterwards: that would renumber its interfaces.
+type CustomFunctions struct {
+	functions []Function
+	builtin   uint8 // interfaces of the built-in functions
+}
+
+// Add appends f after the given built-in functions and the custom functions
+// that were added before, and returns the number of its first interface. It
+// returns ErrFunctionOrder if the built-in functions don't use the same number
+// of interfaces as when the first custom function was added.
+func (c *CustomFunctions) Add(f Function, builtin ...Function) (uint8, error) {
+	n := interfaceCount(builtin)
+	if len(c.functions) == 0 {
+		c.builtin = n
+	} else if n != c.builtin {
+		return 0, ErrFunctionOrder
+	}
+	first := n + interfaceCount(c.functions)
+	c.functions = append(c.functions, f)
+	return first, nil
+}
+
+// Composite builds the descriptor of a composite device from the built-in
+// functions followed by the custom functions, like the Composite function. It
+// returns ErrFunctionOrder if the built-in functions don't use the same number
+// of interfaces as when the first custom function was added.
+func (c *CustomFunctions) Composite(device, configuration []byte, builtin ...Function) (Descriptor, error) {
+	if len(c.functions) != 0 && interfaceCount(builtin) != c.builtin {
+		return Descriptor{}, ErrFunctionOrder
+	}
+	functions := append(builtin[:len(builtin):len(builtin)], c.functions...)
+	return Composite(device, configuration, functions...), nil
+}
+
+// interfaceCount returns the number of interfaces of the given functions.
+func interfaceCount(functions []Function) (n uint8) {
+	for i := range functions {
+		_, count := functions[i].Interfaces()
+		n += count
+	}
+	return n
+}
+
+// shiftClassSpecificInterfaces adjusts the interface numbers that are part of
+// some class specific interface descriptors.
+func shiftClassSpecificInterfaces(d []byte, class, subclass, delta uint8) {
+	switch {
+	case class == 0x02 && d[2] == cdcFunctionalCallManagement && len(d) >= 5:
+		// data interface
+		d[4] += delta
+	case class == 0x02 && d[2] == cdcFunctionalUnion:
+		// control interface, followed by the subordinate interfaces
+		for i := 3; i < len(d); i++ {
+			d[i] += delta
+		}
+	case class == 0x01 && subclass == 0x01 && d[2] == 0x01 && len(d) >= 8:
+		// audio control header: the streaming interfaces of the collection
+		for i := 8; i < len(d) && i < 8+int(d[7]); i++ {
+			d[i] += delta
+		}
+	}
+}
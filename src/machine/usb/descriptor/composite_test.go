@@ -0,0 +1,50 @@
+package descriptor_test
+
+import (
+	"machine/usb/descriptor"
+	"testing"
+)
+
+func TestCustomFunctionsOrder(t *testing.T) {
+	device := descriptor.DeviceCDC.Bytes()
+	configuration := descriptor.ConfigurationCDC.Bytes()
+	cdc := descriptor.FunctionCDC
+	hid := descriptor.FunctionHID
+
+	// The MIDI function (two interfaces) is used as a custom function here.
+	var c descriptor.CustomFunctions
+	first, err := c.Add(descriptor.FunctionMIDI, cdc)
+	if err != nil || first != 2 {
+		t.Fatalf("Add: got %d, %v, want 2, nil", first, err)
+	}
+	first, err = c.Add(descriptor.FunctionMIDI, cdc)
+	if err != nil || first != 4 {
+		t.Fatalf("second Add: got %d, %v, want 4, nil", first, err)
+	}
+	d, err := c.Composite(device, configuration, cdc)
+	if err != nil {
+		t.Fatal("Composite:", err)
+	}
+	if n := d.Configuration[4]; n != 6 {
+		t.Errorf("Composite: got %d interfaces, want 6", n)
+	}
+
+	// Enabling a built-in function after the custom functions would renumber
+	// their interfaces.
+	if _, err := c.Composite(device, configuration, cdc, hid); err != descriptor.ErrFunctionOrder {
+		t.Errorf("Composite with a new built-in function: got %v, want ErrFunctionOrder", err)
+	}
+	if _, err := c.Add(descriptor.FunctionMIDI, cdc, hid); err != descriptor.ErrFunctionOrder {
+		t.Errorf("Add with a new built-in function: got %v, want ErrFunctionOrder", err)
+	}
+
+	// Built-in functions that are enabled first are fine.
+	var c2 descriptor.CustomFunctions
+	first, err = c2.Add(descriptor.FunctionMIDI, cdc, hid)
+	if err != nil || first != 3 {
+		t.Fatalf("Add after HID: got %d, %v, want 3, nil", first, err)
+	}
+	if _, err := c2.Composite(device, configuration, cdc, hid); err != nil {
+		t.Error("Composite after HID:", err)
+	}
+}
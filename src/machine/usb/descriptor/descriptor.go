@@ -5,17 +5,18 @@ import (
 )
 
 const (
-	TypeDevice                = 0x1
-	TypeConfiguration         = 0x2
-	TypeString                = 0x3
-	TypeInterface             = 0x4
-	TypeEndpoint              = 0x5
-	TypeDeviceQualifier       = 0x6
-	TypeInterfaceAssociation  = 0xb
-	TypeClassHID              = 0x21
-	TypeHIDReport             = 0x22
-	TypeClassSpecific         = 0x24
-	TypeClassSpecificEndpoint = 0x25
+	TypeDevice                  = 0x1
+	TypeConfiguration           = 0x2
+	TypeString                  = 0x3
+	TypeInterface               = 0x4
+	TypeEndpoint                = 0x5
+	TypeDeviceQualifier         = 0x6
+	TypeOtherSpeedConfiguration = 0x7
+	TypeInterfaceAssociation    = 0xb
+	TypeClassHID                = 0x21
+	TypeHIDReport               = 0x22
+	TypeClassSpecific           = 0x24
+	TypeClassSpecificEndpoint   = 0x25
 )
 
 // DeviceDescBank is the USB device endpoint .
@@ -13,6 +13,8 @@ const (
 	hidUnit            = 0x65
 	hidCollection      = 0xa1
 	hidInput           = 0x81
+	hidOutput          = 0x91
+	hidFeature         = 0xb1
 	hidReportSize      = 0x75
 	hidReportCount     = 0x95
 	hidReportID        = 0x85
@@ -99,6 +101,7 @@ var (
 var (
 	HIDCollectionPhysical    = []byte{hidCollection, 0x00}
 	HIDCollectionApplication = []byte{hidCollection, 0x01}
+	HIDCollectionLogical     = []byte{hidCollection, 0x02}
 	HIDCollectionEnd         = []byte{0xc0}
 )
 
@@ -114,8 +117,52 @@ var (
 
 	// Input (Data, Variable, Relative), 2 position bytes (X & Y)
 	HIDInputDataVarRel = []byte{hidInput, 0x06}
+
+	// Output (Data, Variable, Absolute), for example keyboard LEDs
+	HIDOutputDataVarAbs = []byte{hidOutput, 0x02}
+
+	// Output (Const, Variable, Absolute), padding
+	HIDOutputConstVarAbs = []byte{hidOutput, 0x03}
+
+	// Feature (Data, Variable, Absolute)
+	HIDFeatureDataVarAbs = []byte{hidFeature, 0x02}
 )
 
+// HIDUsagePage returns a Usage Page item for the given page, for usage pages
+// that don't have a predefined HIDUsagePage* variable such as vendor defined
+// pages (0xFF00-0xFFFF).
+func HIDUsagePage(page int) []byte {
+	if page > 255 {
+		return []byte{hidUsagePage + 1, uint8(page), uint8(page >> 8)}
+	}
+
+	return []byte{hidUsagePage, byte(page)}
+}
+
+// HIDUsage returns a Usage item for the given usage ID within the current
+// usage page.
+func HIDUsage(usage int) []byte {
+	if usage > 255 {
+		return []byte{hidUsage + 1, uint8(usage), uint8(usage >> 8)}
+	}
+
+	return []byte{hidUsage, byte(usage)}
+}
+
+// HIDReport concatenates the given items into a single HID report
+// descriptor, for use with a custom HID device:
+//
+//	report := descriptor.HIDReport(
+//		descriptor.HIDUsagePage(0xFF00),
+//		descriptor.HIDUsage(0x01),
+//		descriptor.HIDCollectionApplication,
+//		...
+//		descriptor.HIDCollectionEnd,
+//	)
+func HIDReport(items ...[]byte) []byte {
+	return Append(items)
+}
+
 func HIDReportSize(size int) []byte {
 	return []byte{hidReportSize, byte(size)}
 }
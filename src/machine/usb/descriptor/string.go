@@ -0,0 +1,60 @@
+package descriptor
+
+import (
+	"errors"
+)
+
+// String returns a string descriptor for the given string, encoded as UTF-16LE
+// as required by the USB specification. Strings that don't fit in a single
+// descriptor (126 UTF-16 code units) are truncated. If that would split a
+// surrogate pair, the remaining unpaired surrogate is replaced with U+FFFD like
+// the unicode/utf16 package does.
+//
+// This does the UTF-16 encoding itself instead of using the unicode/utf16
+// package, to avoid pulling in that package for such a small amount of code.
+func String(s string) []byte {
+	out := make([]byte, 2, 2+len(s)*2)
+	for _, r := range s {
+		if r >= 0x10000 {
+			// Encode as a surrogate pair.
+			r -= 0x10000
+			out = appendUTF16(out, uint16(0xd800+((r>>10)&0x3ff)))
+			out = appendUTF16(out, uint16(0xdc00+(r&0x3ff)))
+		} else {
+			out = appendUTF16(out, uint16(r))
+		}
+	}
+	if len(out) > 254 {
+		out = out[:254]
+		if c := uint16(out[252]) | uint16(out[253])<<8; c >= 0xd800 && c < 0xdc00 {
+			out = appendUTF16(out[:252], 0xfffd)
+		}
+	}
+	out[0] = byte(len(out))
+	out[1] = TypeString
+	return out
+}
+
+func appendUTF16(out []byte, c uint16) []byte {
+	return append(out, byte(c), byte(c>>8))
+}
+
+var errNoInterfaceFound = errors.New("no interface found")
+
+// FindInterfaceType finds the interface descriptor with the given interface
+// number (and alternate setting 0) in a configuration descriptor.
+func FindInterfaceType(des []byte, number uint8) (InterfaceType, error) {
+	for i := 0; i+1 < len(des); {
+		length := int(des[i])
+		if length == 0 || i+length > len(des) {
+			// Malformed descriptor.
+			break
+		}
+		if des[i+1] == TypeInterface && length == interfaceTypeLen && des[i+2] == number && des[i+3] == 0 {
+			return InterfaceType{data: des[i : i+length]}, nil
+		}
+		i += length
+	}
+
+	return InterfaceType{}, errNoInterfaceFound
+}
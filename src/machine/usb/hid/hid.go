@@ -50,6 +50,18 @@ func handler() {
 	}
 }
 
+// SetReportDescriptor replaces the default HID report descriptor with a custom
+// one. It must be called from init(), before any HID device is created.
+func SetReportDescriptor(desc []byte) error {
+	return machine.SetUSBHIDReportDescriptor(desc)
+}
+
+// SetInterfaceName sets the name of the HID interface, as shown by the host.
+// It must be called from init().
+func SetInterfaceName(name string) error {
+	return machine.SetUSBInterfaceName(usb.HID_INTERFACE, name)
+}
+
 var DefaultSetupHandler = setupHandler
 
 func setupHandler(setup usb.Setup) bool {
@@ -0,0 +1,101 @@
+package usb
+
+// State is the state of a USB device as seen from the bus, which follows the
+// device states of the USB specification.
+type State uint8
+
+const (
+	// Not connected to a host. This state is only left when the host resets
+	// the bus, or when VBUS is detected.
+	StateDetached State = iota
+
+	// Connected to a host (VBUS detected), but not yet reset by the host.
+	StateAttached
+
+	// Reset by the host, but no address has been assigned yet.
+	StateDefault
+
+	// An address has been assigned by the host.
+	StateAddressed
+
+	// The host selected a configuration, so the device can be used.
+	StateConfigured
+
+	// The host suspended the bus.
+	StateSuspended
+)
+
+// String returns a human readable name of the state.
+func (s State) String() string {
+	switch s {
+	case StateDetached:
+		return "detached"
+	case StateAttached:
+		return "attached"
+	case StateDefault:
+		return "default"
+	case StateAddressed:
+		return "addressed"
+	case StateConfigured:
+		return "configured"
+	case StateSuspended:
+		return "suspended"
+	default:
+		return "unknown"
+	}
+}
+
+// DeviceState tracks the state of a device through the events on the bus. The
+// zero value is a detached device. All methods report whether the state
+// changed, so that the caller can notify the application.
+type DeviceState struct {
+	state  State
+	resume State // state to return to after a suspend
+}
+
+// State returns the current state.
+func (d *DeviceState) State() State {
+	return d.state
+}
+
+// Set changes the state, for example after a bus reset or when the host
+// assigned an address or selected a configuration.
+func (d *DeviceState) Set(state State) bool {
+	if state == d.state {
+		return false
+	}
+	d.state = state
+	return true
+}
+
+// VBUS updates the state after the level of VBUS changed: a detached device
+// becomes attached when VBUS is present, and every device becomes detached
+// when it is not.
+func (d *DeviceState) VBUS(present bool) bool {
+	if !present {
+		return d.Set(StateDetached)
+	}
+	if d.state == StateDetached {
+		return d.Set(StateAttached)
+	}
+	return false
+}
+
+// Suspend moves the device to the suspended state when the host suspends the
+// bus. A detached device ignores it: without a host the bus is always idle,
+// which looks like a suspend.
+func (d *DeviceState) Suspend() bool {
+	if d.state == StateSuspended || d.state == StateDetached {
+		return false
+	}
+	d.resume = d.state
+	return d.Set(StateSuspended)
+}
+
+// Resume returns a suspended device to the state it had before the suspend.
+func (d *DeviceState) Resume() bool {
+	if d.state != StateSuspended {
+		return false
+	}
+	return d.Set(d.resume)
+}
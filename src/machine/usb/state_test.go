@@ -0,0 +1,49 @@
+package usb_test
+
+import (
+	"machine/usb"
+	"testing"
+)
+
+func TestDeviceState(t *testing.T) {
+	var d usb.DeviceState
+	step := func(event string, changed bool, want usb.State) {
+		t.Helper()
+		if got := d.State(); got != want {
+			t.Errorf("%s: state is %s, want %s", event, got, want)
+		}
+		if !changed {
+			t.Errorf("%s: state did not change", event)
+		}
+	}
+	ignored := func(event string, changed bool, want usb.State) {
+		t.Helper()
+		if changed || d.State() != want {
+			t.Errorf("%s: state changed to %s, want it to stay %s", event, d.State(), want)
+		}
+	}
+
+	step("VBUS high", d.VBUS(true), usb.StateAttached)
+	ignored("VBUS high again", d.VBUS(true), usb.StateAttached)
+	step("bus reset", d.Set(usb.StateDefault), usb.StateDefault)
+	step("set address", d.Set(usb.StateAddressed), usb.StateAddressed)
+	step("set configuration", d.Set(usb.StateConfigured), usb.StateConfigured)
+	step("suspend", d.Suspend(), usb.StateSuspended)
+	ignored("suspend again", d.Suspend(), usb.StateSuspended)
+	step("resume", d.Resume(), usb.StateConfigured)
+	ignored("resume again", d.Resume(), usb.StateConfigured)
+
+	// A detached bus is idle, which the hardware reports as a suspend. That
+	// must not hide the next attach.
+	step("VBUS low", d.VBUS(false), usb.StateDetached)
+	ignored("suspend while detached", d.Suspend(), usb.StateDetached)
+	ignored("resume while detached", d.Resume(), usb.StateDetached)
+	step("VBUS high after detach", d.VBUS(true), usb.StateAttached)
+
+	// A device that is detached while suspended doesn't resume to its old
+	// state.
+	step("suspend while attached", d.Suspend(), usb.StateSuspended)
+	step("VBUS low while suspended", d.VBUS(false), usb.StateDetached)
+	ignored("resume after detach", d.Resume(), usb.StateDetached)
+	step("VBUS high after suspended detach", d.VBUS(true), usb.StateAttached)
+}
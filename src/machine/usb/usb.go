@@ -16,6 +16,7 @@ const (
 	IMANUFACTURER = 1
 	IPRODUCT      = 2
 	ISERIAL       = 3
+	IINTERFACE    = 4 // first of NumberOfInterfaces interface name strings
 
 	ENDPOINT_TYPE_DISABLE     = 0xFF
 	ENDPOINT_TYPE_CONTROL     = 0x00
@@ -60,7 +61,7 @@ const (
 	CONFIG_REMOTE_WAKEUP = 0x20
 
 	// Interface
-	NumberOfInterfaces = 3
+	NumberOfInterfaces = 8
 	CDC_ACM_INTERFACE  = 0 // CDC ACM
 	CDC_DATA_INTERFACE = 1 // CDC Data
 	CDC_FIRST_ENDPOINT = 1
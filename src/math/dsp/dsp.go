@@ -0,0 +1,69 @@
+// Package dsp implements vector operations commonly used for signal processing
+// and sensor fusion, such as dot products, FIR filters and the FFT, in the
+// spirit of the CMSIS-DSP library.
+//
+// The functions are written in plain Go, in a form that LLVM can turn into the
+// specialized instructions of the target where these exist: multiply-accumulate
+// instructions such as SMLAL on Cortex-M3 and up, and fused multiply-add (VFMA)
+// on cores with a single precision FPU like the Cortex-M4F and Cortex-M7.
+// Fixed-point variants use the Q31 type of the math/fixed package, for chips
+// without an FPU.
+package dsp
+
+import "math/fixed"
+
+// DotFloat32 returns the dot product of a and b. The slices must have the same
+// length.
+func DotFloat32(a, b []float32) float32 {
+	if len(a) != len(b) {
+		panic("dsp: slices of different length")
+	}
+	// Use four independent accumulators, which allows FPUs to pipeline the
+	// multiply-add operations.
+	var sum0, sum1, sum2, sum3 float32
+	i := 0
+	for ; i+4 <= len(a); i += 4 {
+		sum0 += a[i] * b[i]
+		sum1 += a[i+1] * b[i+1]
+		sum2 += a[i+2] * b[i+2]
+		sum3 += a[i+3] * b[i+3]
+	}
+	for ; i < len(a); i++ {
+		sum0 += a[i] * b[i]
+	}
+	return (sum0 + sum1) + (sum2 + sum3)
+}
+
+// DotQ31 returns the dot product of a and b, saturated to the range of Q31. The
+// slices must have the same length.
+func DotQ31(a, b []fixed.Q31) fixed.Q31 {
+	if len(a) != len(b) {
+		panic("dsp: slices of different length")
+	}
+	// Accumulate the products with 48 fractional bits, which leaves 16 guard
+	// bits so that the sum can't overflow for less than 32768 elements. Only
+	// shift and saturate at the end, like CMSIS-DSP does.
+	var sum int64
+	for i := range a {
+		sum += int64(a[i]) * int64(b[i]) >> 14
+	}
+	return saturateQ31(sum >> 17)
+}
+
+// ScaleFloat32 multiplies every value in v by scale.
+func ScaleFloat32(v []float32, scale float32) {
+	for i := range v {
+		v[i] *= scale
+	}
+}
+
+// saturateQ31 clamps x to the range of Q31.
+func saturateQ31(x int64) fixed.Q31 {
+	switch {
+	case x > int64(fixed.MaxQ31):
+		return fixed.MaxQ31
+	case x < int64(fixed.MinQ31):
+		return fixed.MinQ31
+	}
+	return fixed.Q31(x)
+}
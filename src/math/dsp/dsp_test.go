@@ -0,0 +1,137 @@
+package dsp_test
+
+import (
+	"math"
+	"math/dsp"
+	"math/fixed"
+	"testing"
+)
+
+func TestDotFloat32(t *testing.T) {
+	// Use a length that isn't a multiple of four, to test the tail loop.
+	a := []float32{1, 2, 3, 4, 5, 6, 7}
+	b := []float32{7, 6, 5, 4, 3, 2, 1}
+	if got := dsp.DotFloat32(a, b); got != 84 {
+		t.Errorf("DotFloat32: got %v, want 84", got)
+	}
+	if got := dsp.DotFloat32(nil, nil); got != 0 {
+		t.Errorf("DotFloat32 of empty slices: got %v, want 0", got)
+	}
+}
+
+func TestDotQ31(t *testing.T) {
+	half := fixed.Float32ToQ31(0.5)
+	quarter := fixed.Float32ToQ31(0.25)
+	a := []fixed.Q31{half, half, quarter}
+	b := []fixed.Q31{half, quarter, fixed.MinQ31}
+	// 0.25 + 0.125 - 0.25
+	if got, want := dsp.DotQ31(a, b), fixed.Float32ToQ31(0.125); got != want {
+		t.Errorf("DotQ31: got %d, want %d", got, want)
+	}
+
+	// The sum saturates instead of wrapping around.
+	a = []fixed.Q31{fixed.MaxQ31, fixed.MaxQ31, fixed.MaxQ31}
+	if got := dsp.DotQ31(a, a); got != fixed.MaxQ31 {
+		t.Errorf("DotQ31 overflow: got %d, want %d", got, fixed.MaxQ31)
+	}
+	b = []fixed.Q31{fixed.MinQ31, fixed.MinQ31, fixed.MinQ31}
+	if got := dsp.DotQ31(a, b); got != fixed.MinQ31 {
+		t.Errorf("DotQ31 underflow: got %d, want %d", got, fixed.MinQ31)
+	}
+}
+
+func TestFFT(t *testing.T) {
+	const n = 16
+	f := dsp.NewFFT(n)
+	if f.Len() != n {
+		t.Fatalf("Len: got %d, want %d", f.Len(), n)
+	}
+
+	// A cosine with 3 periods in the input has all its energy in bins 3 and
+	// n-3.
+	re := make([]float32, n)
+	im := make([]float32, n)
+	for i := range re {
+		re[i] = float32(math.Cos(2 * math.Pi * 3 * float64(i) / n))
+	}
+	orig := append([]float32(nil), re...)
+	f.Transform(re, im)
+	for i := range re {
+		want := float32(0)
+		if i == 3 || i == n-3 {
+			want = n / 2
+		}
+		if !closeTo(re[i], want) || !closeTo(im[i], 0) {
+			t.Errorf("bin %d: got %v%+vi, want %v", i, re[i], im[i], want)
+		}
+	}
+
+	// The inverse transform restores the input.
+	f.Inverse(re, im)
+	for i := range re {
+		if !closeTo(re[i], orig[i]) || !closeTo(im[i], 0) {
+			t.Errorf("inverse %d: got %v%+vi, want %v", i, re[i], im[i], orig[i])
+		}
+	}
+}
+
+func TestFFTLength(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewFFT(12) did not panic")
+		}
+	}()
+	dsp.NewFFT(12)
+}
+
+func TestFIRFloat32(t *testing.T) {
+	coeffs := []float32{1, 2, 3}
+	src := []float32{1, 0, 0, 0, 1, 1, 1, 1}
+	// Computed by hand: the impulse response, then the step response.
+	want := []float32{1, 2, 3, 0, 1, 3, 6, 6}
+
+	// Filtering the whole signal at once.
+	f := dsp.NewFIRFloat32(coeffs)
+	dst := make([]float32, len(src))
+	f.Filter(dst, src)
+	for i := range want {
+		if dst[i] != want[i] {
+			t.Errorf("sample %d: got %v, want %v", i, dst[i], want[i])
+		}
+	}
+
+	// Filtering in blocks of different sizes gives the same result.
+	f.Reset()
+	dst = make([]float32, len(src))
+	f.Filter(dst[:3], src[:3])
+	f.Filter(dst[3:4], src[3:4])
+	f.Filter(dst[4:], src[4:])
+	for i := range want {
+		if dst[i] != want[i] {
+			t.Errorf("sample %d (blocks): got %v, want %v", i, dst[i], want[i])
+		}
+	}
+}
+
+func TestFIRQ31(t *testing.T) {
+	// A moving average of two samples.
+	half := fixed.Float32ToQ31(0.5)
+	f := dsp.NewFIRQ31([]fixed.Q31{half, half})
+	quarter := fixed.Float32ToQ31(0.25)
+	src := []fixed.Q31{half, half, 0, 0}
+	want := []fixed.Q31{quarter, half, quarter, 0}
+	dst := make([]fixed.Q31, len(src))
+	f.Filter(dst[:1], src[:1])
+	f.Filter(dst[1:], src[1:])
+	for i := range want {
+		if dst[i] != want[i] {
+			t.Errorf("sample %d: got %d, want %d", i, dst[i], want[i])
+		}
+	}
+}
+
+// closeTo returns whether a and b are equal, apart from float32 rounding
+// errors.
+func closeTo(a, b float32) bool {
+	return math.Abs(float64(a-b)) < 1e-4
+}
@@ -0,0 +1,94 @@
+package dsp
+
+import "math"
+
+// FFT computes the fast Fourier transform of complex float32 signals with a
+// fixed length, using precomputed twiddle factors.
+type FFT struct {
+	n   int
+	cos []float32
+	sin []float32
+}
+
+// NewFFT prepares a FFT of length n, which must be a power of two.
+func NewFFT(n int) *FFT {
+	if n <= 0 || n&(n-1) != 0 {
+		panic("dsp: FFT length is not a power of two")
+	}
+	// The twiddle factors are calculated once using float64 math, so that the
+	// transform itself only needs float32 operations.
+	f := &FFT{
+		n:   n,
+		cos: make([]float32, n/2),
+		sin: make([]float32, n/2),
+	}
+	for i := range f.cos {
+		sin, cos := math.Sincos(-2 * math.Pi * float64(i) / float64(n))
+		f.cos[i] = float32(cos)
+		f.sin[i] = float32(sin)
+	}
+	return f
+}
+
+// Len returns the length of the transform.
+func (f *FFT) Len() int {
+	return f.n
+}
+
+// Transform computes the forward transform in place. The real and imaginary
+// parts of the signal are stored in re and im, which must both have the length
+// of the transform.
+func (f *FFT) Transform(re, im []float32) {
+	f.transform(re, im, 1)
+}
+
+// Inverse computes the inverse transform in place, including the scaling by
+// 1/n so that Inverse undoes Transform.
+func (f *FFT) Inverse(re, im []float32) {
+	f.transform(re, im, -1)
+	scale := 1 / float32(f.n)
+	ScaleFloat32(re, scale)
+	ScaleFloat32(im, scale)
+}
+
+// transform implements an iterative radix-2 decimation-in-time FFT. The sign
+// of the twiddle factors is flipped for the inverse transform.
+func (f *FFT) transform(re, im []float32, sign float32) {
+	n := f.n
+	if len(re) != n || len(im) != n {
+		panic("dsp: FFT input has the wrong length")
+	}
+
+	// Reorder the input in bit-reversed order.
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j |= bit
+		if i < j {
+			re[i], re[j] = re[j], re[i]
+			im[i], im[j] = im[j], im[i]
+		}
+	}
+
+	// Combine the transforms of increasing size.
+	for size := 2; size <= n; size <<= 1 {
+		half := size / 2
+		step := n / size
+		for start := 0; start < n; start += size {
+			for k := 0; k < half; k++ {
+				wr := f.cos[k*step]
+				wi := sign * f.sin[k*step]
+				a := start + k
+				b := a + half
+				tr := re[b]*wr - im[b]*wi
+				ti := re[b]*wi + im[b]*wr
+				re[b] = re[a] - tr
+				im[b] = im[a] - ti
+				re[a] += tr
+				im[a] += ti
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package dsp
+
+import "math/fixed"
+
+// FIRFloat32 is a finite impulse response filter. It keeps the last input
+// samples between calls to Filter, so that a signal can be filtered in blocks.
+type FIRFloat32 struct {
+	coeffs []float32
+	buf    []float32 // last len(coeffs)-1 input samples, oldest first
+}
+
+// NewFIRFloat32 returns a filter with the given coefficients (taps), in the
+// usual order: coeffs[0] is applied to the newest sample.
+func NewFIRFloat32(coeffs []float32) *FIRFloat32 {
+	if len(coeffs) == 0 {
+		panic("dsp: FIR filter without coefficients")
+	}
+	// Store the coefficients in reverse order, so that filtering is a dot
+	// product of the coefficients and the samples in memory order.
+	reversed := make([]float32, len(coeffs))
+	for i, c := range coeffs {
+		reversed[len(coeffs)-1-i] = c
+	}
+	return &FIRFloat32{
+		coeffs: reversed,
+		buf:    make([]float32, len(coeffs)-1),
+	}
+}
+
+// Filter filters the samples in src and stores the result in dst, which must
+// be at least as long as src.
+func (f *FIRFloat32) Filter(dst, src []float32) {
+	// The buffer only needs to be reallocated when the block size increases.
+	n := len(f.coeffs) - 1
+	f.buf = append(f.buf[:n], src...)
+	for i := range src {
+		dst[i] = DotFloat32(f.coeffs, f.buf[i:i+n+1])
+	}
+	copy(f.buf, f.buf[len(src):])
+	f.buf = f.buf[:n]
+}
+
+// Reset clears the previous input samples of the filter.
+func (f *FIRFloat32) Reset() {
+	for i := range f.buf {
+		f.buf[i] = 0
+	}
+}
+
+// FIRQ31 is a finite impulse response filter using Q31 fixed-point values. It
+// keeps the last input samples between calls to Filter, so that a signal can be
+// filtered in blocks.
+type FIRQ31 struct {
+	coeffs []fixed.Q31
+	buf    []fixed.Q31 // last len(coeffs)-1 input samples, oldest first
+}
+
+// NewFIRQ31 returns a filter with the given coefficients (taps), in the usual
+// order: coeffs[0] is applied to the newest sample.
+func NewFIRQ31(coeffs []fixed.Q31) *FIRQ31 {
+	if len(coeffs) == 0 {
+		panic("dsp: FIR filter without coefficients")
+	}
+	reversed := make([]fixed.Q31, len(coeffs))
+	for i, c := range coeffs {
+		reversed[len(coeffs)-1-i] = c
+	}
+	return &FIRQ31{
+		coeffs: reversed,
+		buf:    make([]fixed.Q31, len(coeffs)-1),
+	}
+}
+
+// Filter filters the samples in src and stores the result in dst, which must
+// be at least as long as src.
+func (f *FIRQ31) Filter(dst, src []fixed.Q31) {
+	// The buffer only needs to be reallocated when the block size increases.
+	n := len(f.coeffs) - 1
+	f.buf = append(f.buf[:n], src...)
+	for i := range src {
+		dst[i] = DotQ31(f.coeffs, f.buf[i:i+n+1])
+	}
+	copy(f.buf, f.buf[len(src):])
+	f.buf = f.buf[:n]
+}
+
+// Reset clears the previous input samples of the filter.
+func (f *FIRQ31) Reset() {
+	for i := range f.buf {
+		f.buf[i] = 0
+	}
+}
@@ -0,0 +1,143 @@
+// Package fixed implements saturating fixed-point arithmetic, for signal
+// processing and control loops on chips without a floating point unit.
+//
+// Two formats are provided: Q16 (Q16.16) with 16 integer and 16 fractional
+// bits, and Q31 (Q1.31) which holds a value in the range [-1, 1) with 31
+// fractional bits. All operations saturate instead of wrapping around on
+// overflow.
+//
+// Addition and subtraction are implemented as compiler builtins using the LLVM
+// saturating intrinsics, and multiplication as a 64-bit multiply and shift. On
+// cores with the DSP extension (such as the Cortex-M4 and Cortex-M7) these are
+// lowered to instructions like QADD, SMULL and SSAT.
+package fixed
+
+// Q16 is a signed fixed-point number with 16 integer bits and 16 fractional
+// bits.
+type Q16 int32
+
+// Q31 is a signed fixed-point number in the range [-1, 1) with 31 fractional
+// bits.
+type Q31 int32
+
+const (
+	// One is the Q16 value 1.0.
+	One Q16 = 1 << 16
+
+	// MaxQ16 and MinQ16 are the largest and smallest Q16 values.
+	MaxQ16 Q16 = 1<<31 - 1
+	MinQ16 Q16 = -1 << 31
+
+	// MaxQ31 and MinQ31 are the largest (just below 1.0) and smallest (-1.0)
+	// Q31 values.
+	MaxQ31 Q31 = 1<<31 - 1
+	MinQ31 Q31 = -1 << 31
+)
+
+// Compiler builtins, see the package documentation.
+func addSat(x, y int32) int32
+func subSat(x, y int32) int32
+
+// saturate clamps x to the range of an int32.
+func saturate(x int64) int32 {
+	switch {
+	case x > 1<<31-1:
+		return 1<<31 - 1
+	case x < -1<<31:
+		return -1 << 31
+	}
+	return int32(x)
+}
+
+// IntToQ16 returns the Q16 value of n, saturated if it doesn't fit.
+func IntToQ16(n int) Q16 {
+	switch v := int64(n); {
+	case v >= 1<<15:
+		return MaxQ16
+	case v < -1<<15:
+		return MinQ16
+	default:
+		return Q16(v << 16)
+	}
+}
+
+// Float32ToQ16 returns the Q16 value of f, rounded towards zero and saturated
+// if it doesn't fit.
+func Float32ToQ16(f float32) Q16 {
+	return Q16(saturate(int64(f * (1 << 16))))
+}
+
+// Float32ToQ31 returns the Q31 value of f, rounded towards zero and saturated
+// if it is outside of the range [-1, 1).
+func Float32ToQ31(f float32) Q31 {
+	switch {
+	case f >= 1:
+		return MaxQ31
+	case f <= -1:
+		return MinQ31
+	}
+	return Q31(f * (1 << 31))
+}
+
+// Add returns x+y.
+func (x Q16) Add(y Q16) Q16 {
+	return Q16(addSat(int32(x), int32(y)))
+}
+
+// Sub returns x-y.
+func (x Q16) Sub(y Q16) Q16 {
+	return Q16(subSat(int32(x), int32(y)))
+}
+
+// Mul returns x*y, rounded towards negative infinity.
+func (x Q16) Mul(y Q16) Q16 {
+	// The arithmetic shift rounds towards negative infinity.
+	return Q16(saturate(int64(x) * int64(y) >> 16))
+}
+
+// Div returns x/y, rounded towards zero. It panics if y is zero.
+func (x Q16) Div(y Q16) Q16 {
+	return Q16(saturate(int64(x) << 16 / int64(y)))
+}
+
+// Int returns the integer part of x, rounded towards negative infinity.
+func (x Q16) Int() int {
+	return int(x >> 16)
+}
+
+// Float32 returns x as a float32.
+func (x Q16) Float32() float32 {
+	return float32(x) / (1 << 16)
+}
+
+// Q31 returns x as a Q31 value, saturated if it is outside of the range
+// [-1, 1).
+func (x Q16) Q31() Q31 {
+	return Q31(saturate(int64(x) << 15))
+}
+
+// Add returns x+y.
+func (x Q31) Add(y Q31) Q31 {
+	return Q31(addSat(int32(x), int32(y)))
+}
+
+// Sub returns x-y.
+func (x Q31) Sub(y Q31) Q31 {
+	return Q31(subSat(int32(x), int32(y)))
+}
+
+// Mul returns x*y, rounded towards negative infinity. The only product that
+// saturates is -1 * -1.
+func (x Q31) Mul(y Q31) Q31 {
+	return Q31(saturate(int64(x) * int64(y) >> 31))
+}
+
+// Float32 returns x as a float32.
+func (x Q31) Float32() float32 {
+	return float32(x) / (1 << 31)
+}
+
+// Q16 returns x as a Q16 value.
+func (x Q31) Q16() Q16 {
+	return Q16(x >> 15)
+}
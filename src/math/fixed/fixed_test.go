@@ -0,0 +1,71 @@
+package fixed_test
+
+import (
+	"math/fixed"
+	"testing"
+)
+
+func TestQ16Mul(t *testing.T) {
+	const half = fixed.One / 2
+	tests := []struct {
+		x, y, want fixed.Q16
+	}{
+		{fixed.IntToQ16(3), fixed.IntToQ16(-2), fixed.IntToQ16(-6)},
+		{fixed.IntToQ16(-3), half, -3 * half},
+		// Products that don't fit are rounded towards negative infinity, not
+		// towards zero.
+		{1, half, 0},
+		{-1, half, -1},
+		{1, -half, -1},
+		{-3, half, -2},
+		{-1, -1, 0},
+		// Saturation.
+		{fixed.IntToQ16(200), fixed.IntToQ16(200), fixed.MaxQ16},
+		{fixed.IntToQ16(200), fixed.IntToQ16(-200), fixed.MinQ16},
+		{fixed.MinQ16, fixed.MinQ16, fixed.MaxQ16},
+	}
+	for _, tc := range tests {
+		if got := tc.x.Mul(tc.y); got != tc.want {
+			t.Errorf("Q16(%d).Mul(%d): got %d, want %d", tc.x, tc.y, got, tc.want)
+		}
+	}
+}
+
+func TestQ31Mul(t *testing.T) {
+	const half = fixed.Q31(1 << 30)
+	tests := []struct {
+		x, y, want fixed.Q31
+	}{
+		{half, half, half / 2},
+		{-half, half, -half / 2},
+		{fixed.MinQ31, half, -half},
+		// Rounding towards negative infinity.
+		{1, half, 0},
+		{-1, half, -1},
+		{fixed.MinQ31, 1, -1},
+		{fixed.MaxQ31, -1, -1},
+		// -1 * -1 is the only product that saturates.
+		{fixed.MinQ31, fixed.MinQ31, fixed.MaxQ31},
+		{fixed.MaxQ31, fixed.MaxQ31, fixed.MaxQ31 - 1},
+	}
+	for _, tc := range tests {
+		if got := tc.x.Mul(tc.y); got != tc.want {
+			t.Errorf("Q31(%d).Mul(%d): got %d, want %d", tc.x, tc.y, got, tc.want)
+		}
+	}
+}
+
+func TestAddSub(t *testing.T) {
+	if got := fixed.MaxQ16.Add(1); got != fixed.MaxQ16 {
+		t.Errorf("MaxQ16.Add(1): got %d, want MaxQ16", got)
+	}
+	if got := fixed.MinQ16.Sub(1); got != fixed.MinQ16 {
+		t.Errorf("MinQ16.Sub(1): got %d, want MinQ16", got)
+	}
+	if got := fixed.MinQ31.Add(-1); got != fixed.MinQ31 {
+		t.Errorf("MinQ31.Add(-1): got %d, want MinQ31", got)
+	}
+	if got := fixed.IntToQ16(2).Sub(fixed.IntToQ16(5)); got != fixed.IntToQ16(-3) {
+		t.Errorf("2-5: got %d, want %d", got, fixed.IntToQ16(-3))
+	}
+}
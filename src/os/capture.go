@@ -0,0 +1,50 @@
+package os
+
+// captureFileHandle is a write-only file handle that keeps everything that is
+// written to it in memory.
+type captureFileHandle struct {
+	data []byte
+}
+
+func (h *captureFileHandle) Read(b []byte) (n int, err error) {
+	return 0, ErrUnsupported
+}
+
+func (h *captureFileHandle) ReadAt(b []byte, offset int64) (n int, err error) {
+	return 0, ErrUnsupported
+}
+
+func (h *captureFileHandle) Seek(offset int64, whence int) (newoffset int64, err error) {
+	return 0, ErrUnsupported
+}
+
+func (h *captureFileHandle) Sync() (err error) {
+	return nil
+}
+
+func (h *captureFileHandle) Write(b []byte) (n int, err error) {
+	h.data = append(h.data, b...)
+	return len(b), nil
+}
+
+func (h *captureFileHandle) WriteAt(b []byte, offset int64) (n int, err error) {
+	return 0, ErrUnsupported
+}
+
+func (h *captureFileHandle) Close() (err error) {
+	return nil
+}
+
+// captureStdout replaces Stdout with a file that keeps its output in memory.
+// The returned function restores Stdout and returns the captured output. It is
+// called by the testing package to check the output of examples, which works
+// the same on every system, with or without pipes.
+func captureStdout() (finish func() string) {
+	stdout := Stdout
+	handle := &captureFileHandle{}
+	Stdout = &File{&file{handle: handle, name: stdout.name}}
+	return func() string {
+		Stdout = stdout
+		return string(handle.data)
+	}
+}
@@ -38,6 +38,23 @@ type Filesystem interface {
 	Remove(name string) error
 }
 
+// handleFilesystem is implemented by filesystems inside the os package whose
+// files can't be represented as a file descriptor, such as the in-memory
+// filesystem used for TempDir on systems without a filesystem. They also
+// provide the operations that aren't part of the Filesystem interface yet.
+type handleFilesystem interface {
+	Filesystem
+
+	// openFileHandle opens the named file, like OpenFile.
+	openFileHandle(name string, flag int, perm FileMode) (FileHandle, error)
+
+	// stat returns a FileInfo describing the named file.
+	stat(name string) (FileInfo, error)
+
+	// removeAll removes the named file or directory, including its children.
+	removeAll(name string) error
+}
+
 // FileHandle is an interface that should be implemented by filesystems
 // implementing the Filesystem interface.
 //
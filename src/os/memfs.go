@@ -0,0 +1,252 @@
+//go:build baremetal || (wasm && !wasi)
+
+package os
+
+import (
+	"io"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// memFilesystem is a simple filesystem that keeps all files in memory. It is
+// used to provide a temporary directory on systems without a filesystem, so
+// that tests using t.TempDir can run there too.
+type memFilesystem struct {
+	// nodes maps a clean path (starting with a slash) to the file or directory
+	// at that path.
+	nodes map[string]*memNode
+}
+
+type memNode struct {
+	name    string
+	dir     bool
+	data    []byte
+	mode    FileMode
+	modTime time.Time
+}
+
+var tempFilesystem *memFilesystem
+
+// mountTempFilesystem mounts an in-memory filesystem at TempDir, if that
+// hasn't been done already. It is called by the testing package.
+func mountTempFilesystem() {
+	if tempFilesystem != nil {
+		return
+	}
+	tempFilesystem = &memFilesystem{
+		nodes: map[string]*memNode{
+			"/": {name: "/", dir: true, mode: ModeDir | 0777, modTime: time.Now()},
+		},
+	}
+	Mount(TempDir()+"/", tempFilesystem)
+}
+
+// cleanMemPath removes the trailing slash from a path, if there is one.
+func cleanMemPath(name string) string {
+	if len(name) > 1 && name[len(name)-1] == '/' {
+		name = name[:len(name)-1]
+	}
+	return name
+}
+
+// parent returns the parent directory of the given path, or an error if it
+// doesn't exist.
+func (m *memFilesystem) parent(name string) (*memNode, error) {
+	dir := name[:strings.LastIndexByte(name, '/')+1]
+	node := m.nodes[cleanMemPath(dir)]
+	if node == nil {
+		return nil, ErrNotExist
+	}
+	if !node.dir {
+		return nil, syscall.ENOTDIR
+	}
+	return node, nil
+}
+
+func (m *memFilesystem) OpenFile(name string, flag int, perm FileMode) (uintptr, error) {
+	// Files on this filesystem can't be represented as a file descriptor.
+	return 0, ErrUnsupported
+}
+
+func (m *memFilesystem) openFileHandle(name string, flag int, perm FileMode) (FileHandle, error) {
+	name = cleanMemPath(name)
+	node := m.nodes[name]
+	writable := flag&(O_WRONLY|O_RDWR) != 0
+	switch {
+	case node != nil && flag&O_CREATE != 0 && flag&O_EXCL != 0:
+		return nil, ErrExist
+	case node != nil && node.dir && writable:
+		return nil, syscall.EISDIR
+	case node == nil && flag&O_CREATE == 0:
+		return nil, ErrNotExist
+	case node == nil:
+		if _, err := m.parent(name); err != nil {
+			return nil, err
+		}
+		node = &memNode{
+			name:    name[strings.LastIndexByte(name, '/')+1:],
+			mode:    perm & ModePerm,
+			modTime: time.Now(),
+		}
+		m.nodes[name] = node
+	case flag&O_TRUNC != 0 && writable:
+		node.data = nil
+		node.modTime = time.Now()
+	}
+	return &memFile{
+		node:     node,
+		readable: flag&O_WRONLY == 0,
+		writable: writable,
+		append:   flag&O_APPEND != 0,
+	}, nil
+}
+
+func (m *memFilesystem) Mkdir(name string, perm FileMode) error {
+	name = cleanMemPath(name)
+	if m.nodes[name] != nil {
+		return ErrExist
+	}
+	if _, err := m.parent(name); err != nil {
+		return err
+	}
+	m.nodes[name] = &memNode{
+		name:    name[strings.LastIndexByte(name, '/')+1:],
+		dir:     true,
+		mode:    ModeDir | perm&ModePerm,
+		modTime: time.Now(),
+	}
+	return nil
+}
+
+func (m *memFilesystem) Remove(name string) error {
+	name = cleanMemPath(name)
+	node := m.nodes[name]
+	if node == nil {
+		return ErrNotExist
+	}
+	if name == "/" {
+		return ErrInvalid
+	}
+	if node.dir {
+		for path := range m.nodes {
+			if strings.HasPrefix(path, name+"/") {
+				return syscall.ENOTEMPTY
+			}
+		}
+	}
+	delete(m.nodes, name)
+	return nil
+}
+
+func (m *memFilesystem) stat(name string) (FileInfo, error) {
+	node := m.nodes[cleanMemPath(name)]
+	if node == nil {
+		return nil, ErrNotExist
+	}
+	return memFileInfo{node}, nil
+}
+
+func (m *memFilesystem) removeAll(name string) error {
+	name = cleanMemPath(name)
+	for path := range m.nodes {
+		if path != "/" && (path == name || strings.HasPrefix(path, name+"/")) {
+			delete(m.nodes, path)
+		}
+	}
+	return nil
+}
+
+// memFile is an open file in a memFilesystem. It implements FileHandle.
+type memFile struct {
+	node     *memNode
+	offset   int64
+	readable bool
+	writable bool
+	append   bool
+}
+
+func (f *memFile) Read(b []byte) (n int, err error) {
+	n, err = f.ReadAt(b, f.offset)
+	f.offset += int64(n)
+	return
+}
+
+func (f *memFile) ReadAt(b []byte, offset int64) (n int, err error) {
+	if !f.readable {
+		return 0, ErrUnsupported
+	}
+	if f.node.dir {
+		return 0, syscall.EISDIR
+	}
+	if offset >= int64(len(f.node.data)) {
+		return 0, io.EOF
+	}
+	n = copy(b, f.node.data[offset:])
+	if n < len(b) {
+		err = io.EOF
+	}
+	return
+}
+
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += int64(len(f.node.data))
+	}
+	if offset < 0 {
+		return 0, ErrInvalid
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *memFile) Sync() error {
+	return nil
+}
+
+func (f *memFile) Write(b []byte) (n int, err error) {
+	if f.append {
+		f.offset = int64(len(f.node.data))
+	}
+	n, err = f.WriteAt(b, f.offset)
+	f.offset += int64(n)
+	return
+}
+
+func (f *memFile) WriteAt(b []byte, offset int64) (n int, err error) {
+	if !f.writable {
+		return 0, ErrUnsupported
+	}
+	if end := offset + int64(len(b)); end > int64(len(f.node.data)) {
+		if end > int64(cap(f.node.data)) {
+			data := make([]byte, end, end*2)
+			copy(data, f.node.data)
+			f.node.data = data
+		}
+		f.node.data = f.node.data[:end]
+	}
+	n = copy(f.node.data[offset:], b)
+	f.node.modTime = time.Now()
+	return n, nil
+}
+
+func (f *memFile) Close() error {
+	return nil
+}
+
+// memFileInfo describes a file or directory in a memFilesystem.
+type memFileInfo struct {
+	node *memNode
+}
+
+func (fi memFileInfo) Name() string       { return fi.node.name }
+func (fi memFileInfo) Size() int64        { return int64(len(fi.node.data)) }
+func (fi memFileInfo) Mode() FileMode     { return fi.node.mode }
+func (fi memFileInfo) ModTime() time.Time { return fi.node.modTime }
+func (fi memFileInfo) IsDir() bool        { return fi.node.dir }
+func (fi memFileInfo) Sys() interface{}   { return nil }
+
+var _ FileInfo = memFileInfo{}
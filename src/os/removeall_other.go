@@ -1,4 +1,4 @@
-//go:build baremetal || js || wasi
+//go:build baremetal || js
 
 // Copyright 2009 The Go Authors. All rights reserved.
 // Use of this source code is governed by a BSD-style
@@ -11,5 +11,13 @@ import (
 )
 
 func removeAll(path string) error {
+	if fs, suffix := findMount(path); fs != nil {
+		if fs, ok := fs.(handleFilesystem); ok {
+			if err := fs.removeAll(suffix); err != nil {
+				return &PathError{Op: "RemoveAll", Path: path, Err: err}
+			}
+			return nil
+		}
+	}
 	return &PathError{Op: "RemoveAll", Path: path, Err: syscall.ENOSYS}
 }
@@ -6,17 +6,35 @@
 
 package os
 
-// Stat is a stub, not yet implemented
+// Stat returns the FileInfo structure describing file. It is only implemented
+// for files in an in-memory filesystem.
 func (f *File) Stat() (FileInfo, error) {
+	if handle, ok := f.handle.(*memFile); ok {
+		return memFileInfo{handle.node}, nil
+	}
 	return nil, ErrNotImplemented
 }
 
 // statNolog stats a file with no test logging.
 func statNolog(name string) (FileInfo, error) {
+	if fs, suffix := findMount(name); fs != nil {
+		if fs, ok := fs.(handleFilesystem); ok {
+			info, err := fs.stat(suffix)
+			if err != nil {
+				return nil, &PathError{Op: "stat", Path: name, Err: err}
+			}
+			return info, nil
+		}
+	}
 	return nil, &PathError{Op: "stat", Path: name, Err: ErrNotImplemented}
 }
 
 // lstatNolog lstats a file with no test logging.
 func lstatNolog(name string) (FileInfo, error) {
-	return nil, &PathError{Op: "lstat", Path: name, Err: ErrNotImplemented}
+	// There are no symbolic links on these systems.
+	info, err := statNolog(name)
+	if err != nil {
+		err.(*PathError).Op = "lstat"
+	}
+	return info, err
 }
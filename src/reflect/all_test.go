@@ -1559,8 +1559,6 @@ func TestIsZero(t *testing.T) {
 			t.Errorf("%d: IsZero(Zero(TypeOf((%s)(%+v)))) is false", i, x.Kind(), tt.x)
 		}
 
-		/* // TODO(tinygo): missing SetZero support
-
 		p := New(x.Type()).Elem()
 		p.Set(x)
 		p.SetZero()
@@ -1568,7 +1566,6 @@ func TestIsZero(t *testing.T) {
 			t.Errorf("%d: IsZero((%s)(%+v)) is true after SetZero", i, p.Kind(), tt.x)
 
 		}
-		*/
 
 	}
 
@@ -2166,7 +2163,7 @@ func fmtSelect(info []caseInfo) string {
 	return buf.String()
 }
 
-// TODO(tinygo): missing func/method/call support
+*/
 
 type two [2]uintptr
 
@@ -2258,6 +2255,7 @@ func TestCallWithStruct(t *testing.T) {
 	}
 }
 
+/* // TODO(tinygo): missing func/method/call support
 func TestCallReturnsEmpty(t *testing.T) {
 	// Issue 21717: past-the-end pointer write in Call with
 	// nonzero-sized frame and zero-sized return value.
@@ -2281,6 +2279,7 @@ func TestCallReturnsEmpty(t *testing.T) {
 	}
 	runtime.KeepAlive(v)
 }
+*/
 
 func TestMakeFunc(t *testing.T) {
 	f := dummy
@@ -2441,8 +2440,6 @@ func TestMakeFuncInvalidReturnAssignments(t *testing.T) {
 	})
 }
 
-*/
-
 type Point struct {
 	x, y int
 }
@@ -3135,8 +3132,6 @@ func TestFieldByIndex(t *testing.T) {
 	}
 }
 
-/*
-
 func TestFieldByName(t *testing.T) {
 	for _, test := range fieldTests {
 		s := TypeOf(test.s)
@@ -3177,8 +3172,6 @@ func TestFieldByName(t *testing.T) {
 	}
 }
 
-*/
-
 func TestImportPath(t *testing.T) {
 	tests := []struct {
 		t    Type
@@ -3306,6 +3299,8 @@ func TestMethodPkgPath(t *testing.T) {
 	}
 }
 
+*/
+
 func TestVariadicType(t *testing.T) {
 	// Test example from Type documentation.
 	var f func(x int, y ...float64)
@@ -3329,6 +3324,8 @@ func TestVariadicType(t *testing.T) {
 	t.Error(s)
 }
 
+/*
+
 type inner struct {
 	x int
 }
@@ -4929,14 +4926,14 @@ func TestOverflow(t *testing.T) {
 	}
 }
 
-/*
-
 func checkSameType(t *testing.T, x Type, y any) {
 	if x != TypeOf(y) || TypeOf(Zero(x).Interface()) != TypeOf(y) {
 		t.Errorf("did not find preexisting type for %s (vs %s)", TypeOf(x), TypeOf(y))
 	}
 }
 
+/*
+
 func TestArrayOf(t *testing.T) {
 	// check construction and use of type not in binary
 	tests := []struct {
@@ -5176,6 +5173,8 @@ func TestArrayOfDirectIface(t *testing.T) {
 	}
 }
 
+*/
+
 // Ensure passing in negative lengths panics.
 // See https://golang.org/issue/43603
 func TestArrayOfPanicOnNegativeLength(t *testing.T) {
@@ -5208,6 +5207,7 @@ func TestSliceOf(t *testing.T) {
 	checkSameType(t, SliceOf(TypeOf(T1(1))), []T1{})
 }
 
+/*
 func TestSliceOverflow(t *testing.T) {
 	// check that MakeSlice panics when size of slice overflows uint
 	const S = 1e6
@@ -5255,6 +5255,8 @@ func TestSliceOfGC(t *testing.T) {
 	}
 }
 
+*/
+
 func TestStructOfFieldName(t *testing.T) {
 	// invalid field name "1nvalid"
 	shouldPanic("has invalid name", func() {
@@ -5282,8 +5284,11 @@ func TestStructOfFieldName(t *testing.T) {
 	// verify creation of a struct with valid struct fields
 	validFields := []StructField{
 		{
-			Name: "φ",
-			Type: TypeOf(""),
+			// TinyGo: φ is not exported (see go/token.IsExported), so it
+			// needs a PkgPath.
+			Name:    "φ",
+			PkgPath: "p",
+			Type:    TypeOf(""),
 		},
 		{
 			Name: "ValidName",
@@ -5433,6 +5438,7 @@ func TestStructOf(t *testing.T) {
 		struct{ F structFieldType }{})
 }
 
+/*
 func TestStructOfExportRules(t *testing.T) {
 	type S1 struct{}
 	type s2 struct{}
@@ -6616,6 +6622,8 @@ func TestReflectMethodTraceback(t *testing.T) {
 	}
 }
 
+*/
+
 func TestSmallZero(t *testing.T) {
 	type T [10]byte
 	typ := TypeOf(T{})
@@ -6679,6 +6687,8 @@ func TestFieldByIndexNil(t *testing.T) {
 	t.Fatalf("did not panic")
 }
 
+/*
+
 // Given
 //	type Outer struct {
 //		*Inner
@@ -7715,8 +7725,6 @@ func TestMapIterNilMap(t *testing.T) {
 	}
 }
 
-/*
-
 func TestMapIterReset(t *testing.T) {
 	iter := new(MapIter)
 
@@ -7837,8 +7845,6 @@ func TestMapIterSafety(t *testing.T) {
 	}()
 }
 
-*/
-
 func TestMapIterNext(t *testing.T) {
 	// The first call to Next should reflect any
 	// insertions to the map since the iterator was created.
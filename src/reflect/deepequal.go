@@ -43,8 +43,20 @@ func deepValueEqual(v1, v2 Value, visited map[visit]struct{}) bool {
 	}
 
 	if hard(v1, v2) {
-		addr1 := v1.pointer()
-		addr2 := v2.pointer()
+		// For a Ptr or Map value, we need to check whether the value is
+		// indirect, which we do by calling the pointer method. Slice and
+		// Interface values are always stored as a pointer to the slice header
+		// or interface, which identifies them.
+		ptrval := func(v Value) unsafe.Pointer {
+			switch v.Kind() {
+			case Ptr, Map:
+				return v.pointer()
+			default:
+				return v.value
+			}
+		}
+		addr1 := ptrval(v1)
+		addr2 := ptrval(v2)
 		if uintptr(addr1) > uintptr(addr2) {
 			// Canonicalize order to reduce number of entries in visited.
 			// Assumes non-moving garbage collector.
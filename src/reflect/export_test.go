@@ -4,3 +4,9 @@ type OtherPkgFields struct {
 	OtherExported   int
 	otherUnexported int
 }
+
+// HasTagTable returns whether StructTag.Lookup uses the table emitted by the
+// compiler for this tag, instead of parsing the tag.
+func HasTagTable(tag StructTag) bool {
+	return tag.table() != nil
+}
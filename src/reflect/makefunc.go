@@ -1,5 +1,91 @@
 package reflect
 
+import "unsafe"
+
+// makeFuncImpl is the context of a function created by MakeFunc. The function
+// pointer of such a function is the makeFunc thunk that the compiler generates
+// for every function type, which looks like this in Go syntax:
+//
+//	func thunk(x, y int, context *makeFuncImpl) (int, error) {
+//	    var r0 int
+//	    var r1 error
+//	    context.call(&[2]unsafe.Pointer{&x, &y}, &[2]unsafe.Pointer{&r0, &r1})
+//	    return r0, r1
+//	}
+//
+// The call field must therefore be the first field of this struct.
+type makeFuncImpl struct {
+	call func(args, results unsafe.Pointer)
+	typ  *rawType
+	fn   func([]Value) []Value
+}
+
+// MakeFunc returns a new function of the given Type that wraps the function fn.
+// When called, that new function does the following:
+//
+//   - converts its arguments to a slice of Values.
+//   - runs results := fn(args).
+//   - returns the results as a slice of Values, one per formal result.
+//
+// The function fn may return values that are assignable to the result types
+// instead of the exact types. If typ describes a variadic function, the final
+// Value is itself a slice representing the variadic arguments, as in the body
+// of a variadic function.
+//
+// MakeFunc doesn't support function types created by FuncOf.
 func MakeFunc(typ Type, fn func(args []Value) (results []Value)) Value {
-	panic("unimplemented: reflect.MakeFunc()")
+	if typ == nil || typ.Kind() != Func {
+		panic("reflect: call of MakeFunc with non-Func type")
+	}
+	t := typ.(*rawType)
+	ftype := t.funcType("MakeFunc")
+	if ftype.makeFunc == nil {
+		panic("reflect: MakeFunc of function with a type created by FuncOf")
+	}
+
+	impl := &makeFuncImpl{typ: t, fn: fn}
+	impl.call = impl.invoke
+	thunk := (*funcHeader)(unsafe.Pointer(&ftype.makeFunc))
+	return Value{
+		typecode: t,
+		value:    unsafe.Pointer(&funcHeader{Context: unsafe.Pointer(impl), Code: thunk.Code}),
+		flags:    valueFlagExported,
+	}
+}
+
+// invoke is called by the makeFunc thunk with pointers to the arguments and
+// results of the call. It calls fn with the arguments and stores its results.
+func (impl *makeFuncImpl) invoke(args, results unsafe.Pointer) {
+	t := impl.typ
+	ftype := t.funcType("MakeFunc")
+	numIn := t.NumIn()
+	numOut := t.NumOut()
+
+	// Copy the arguments, as fn may keep them after the call returns.
+	in := make([]Value, numIn)
+	for i := range in {
+		targ := ftype.param(i)
+		arg := New(targ)
+		memcpy(arg.value, *(*unsafe.Pointer)(unsafe.Add(args, uintptr(i)*unsafe.Sizeof(args))), targ.Size())
+		in[i] = arg.Elem().loadIndirect(valueFlagExported)
+	}
+
+	out := impl.fn(in)
+	if len(out) != numOut {
+		panic("reflect: wrong return count from function created by MakeFunc")
+	}
+	for i, v := range out {
+		tout := ftype.param(numIn + i)
+		if !v.IsValid() {
+			panic("reflect: function created by MakeFunc using closure returned zero Value")
+		}
+		if v.isRO() {
+			panic("reflect: function created by MakeFunc returned value obtained from unexported field")
+		}
+		if !v.typecode.AssignableTo(tout) {
+			panic("reflect: function created by MakeFunc using closure returned wrong type: have " + v.typecode.String() + " for " + tout.String())
+		}
+		result := *(*unsafe.Pointer)(unsafe.Add(results, uintptr(i)*unsafe.Sizeof(results)))
+		NewAt(tout, result).Elem().Set(v)
+	}
 }
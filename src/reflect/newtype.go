@@ -0,0 +1,377 @@
+package reflect
+
+// This file implements the construction of new types at runtime: ArrayOf,
+// ChanOf, SliceOf, StructOf and FuncOf. The type structs are allocated on the
+// heap with the same layout as the type structs emitted by the compiler (see
+// the comment at the top of type.go), together with their pointer type.
+//
+// A type is only constructed if it doesn't already exist: the types known at
+// compile time are looked up in compilerTypes first, so that for example
+// ArrayOf(4, TypeOf(0)) is the same Type as TypeOf([4]int{}). Constructed
+// types are kept in a list, so that constructing the same type twice also
+// returns the same Type.
+
+import (
+	"internal/itoa"
+	"unicode"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// compilerTypes lists all array, channel, function, slice and struct types in
+// the program, terminated by nil. It is defined by the interface lowering pass
+// (see transform/interface-lowering.go), and is only included in the program
+// if the functions in this file are used.
+//
+//go:extern
+var compilerTypes [0]*rawType
+
+// constructedTypes contains all types constructed at runtime. They are never
+// freed.
+var constructedTypes []*rawType
+
+// findType returns an existing type of the given kind for which match returns
+// true, or nil if there is none. It looks for types known at compile time and
+// types constructed before.
+func findType(kind Kind, match func(t *rawType) bool) *rawType {
+	for i := uintptr(0); ; i++ {
+		t := *(**rawType)(unsafe.Add(unsafe.Pointer(&compilerTypes), i*unsafe.Sizeof((*rawType)(nil))))
+		if t == nil {
+			break
+		}
+		if t.Kind() == kind && match(t) {
+			return t
+		}
+	}
+	for _, t := range constructedTypes {
+		if t.Kind() == kind && match(t) {
+			return t
+		}
+	}
+	return nil
+}
+
+// newType allocates a zeroed type struct of the given size with the given meta
+// byte. It also creates the pointer type and stores it in the ptrTo field,
+// which is at the same offset in all type structs that have one.
+func newType(size uintptr, meta uint8) *rawType {
+	t := (*rawType)(alloc(size, nil))
+	t.meta = meta
+	ptr := &ptrType{
+		rawType: rawType{meta: uint8(Pointer) | flagComparable | flagIsBinary},
+		elem:    t,
+	}
+	(*elemType)(unsafe.Pointer(t)).ptrTo = &ptr.rawType
+	constructedTypes = append(constructedTypes, t)
+	return t
+}
+
+// typeFlags returns the flagComparable and flagIsBinary flags of t.
+func (t *rawType) typeFlags() uint8 {
+	if t.ptrtag() != 0 {
+		// Pointer to a pointer type, see pointerTo.
+		return flagComparable | flagIsBinary
+	}
+	return t.meta & (flagComparable | flagIsBinary)
+}
+
+// SliceOf returns the slice type with element type t.
+// For example, if t represents int, SliceOf(t) represents []int.
+func SliceOf(t Type) Type {
+	if t == nil {
+		panic("reflect: nil type passed to SliceOf")
+	}
+	elem := t.(*rawType)
+	if st := findType(Slice, func(st *rawType) bool {
+		return st.elem() == elem
+	}); st != nil {
+		return st
+	}
+	st := (*elemType)(unsafe.Pointer(newType(unsafe.Sizeof(elemType{}), uint8(Slice))))
+	st.elem = elem
+	return &st.rawType
+}
+
+// ChanOf returns the channel type with the given direction and element type.
+// For example, if t represents int, ChanOf(RecvDir, t) represents <-chan int.
+func ChanOf(dir ChanDir, t Type) Type {
+	if t == nil {
+		panic("reflect: nil type passed to ChanOf")
+	}
+	switch dir {
+	case RecvDir, SendDir, BothDir:
+	default:
+		panic("reflect.ChanOf: invalid dir")
+	}
+	elem := t.(*rawType)
+	if elem.Size() >= 1<<16 {
+		panic("reflect.ChanOf: element size too large")
+	}
+	if ct := findType(Chan, func(ct *rawType) bool {
+		return ct.elem() == elem && ct.ChanDir() == dir
+	}); ct != nil {
+		return ct
+	}
+	ct := (*elemType)(unsafe.Pointer(newType(unsafe.Sizeof(elemType{}), uint8(Chan)|flagComparable|flagIsBinary)))
+	// The numMethod field of channel types holds the direction, see ChanDir.
+	ct.numMethod = uint16(dir)
+	ct.elem = elem
+	return &ct.rawType
+}
+
+// ArrayOf returns the array type with the given length and element type.
+// For example, if t represents int, ArrayOf(5, t) represents [5]int.
+func ArrayOf(length int, t Type) Type {
+	if t == nil {
+		panic("reflect: nil type passed to ArrayOf")
+	}
+	if length < 0 {
+		panic("reflect: negative length passed to ArrayOf")
+	}
+	elem := t.(*rawType)
+	if elem.Size() != 0 && uintptr(length) > ^uintptr(0)/elem.Size() {
+		panic("reflect.ArrayOf: array size would exceed virtual address space")
+	}
+	if at := findType(Array, func(at *rawType) bool {
+		return at.elem() == elem && at.Len() == length
+	}); at != nil {
+		return at
+	}
+	at := (*arrayType)(unsafe.Pointer(newType(unsafe.Sizeof(arrayType{}), uint8(Array)|elem.typeFlags())))
+	at.elem = elem
+	at.arrayLen = uintptr(length)
+	return &at.rawType
+}
+
+// StructOf returns the struct type containing fields. The Offset and Index
+// fields are ignored and computed as they would be by the compiler.
+//
+// Unexported fields must have their PkgPath set, and all of them must be from
+// the same package. Embedded fields are only supported if the embedded type
+// doesn't have methods.
+func StructOf(fields []StructField) Type {
+	if len(fields) > 0xffff {
+		panic("reflect.StructOf: too many fields")
+	}
+
+	// Check the fields and compute the layout.
+	var size uintptr
+	var pkgPath string
+	alignment := uintptr(1)
+	flags := uint8(flagComparable | flagIsBinary)
+	offsets := make([]uintptr, len(fields))
+	for i, field := range fields {
+		if field.Name == "" {
+			panic("reflect.StructOf: field " + itoa.Itoa(i) + " has no name")
+		}
+		if !isValidFieldName(field.Name) {
+			panic("reflect.StructOf: field " + itoa.Itoa(i) + " has invalid name")
+		}
+		if field.PkgPath == "" {
+			// Like go/token.IsExported.
+			if c, _ := utf8.DecodeRuneInString(field.Name); !unicode.IsUpper(c) {
+				panic("reflect.StructOf: field \"" + field.Name + "\" is unexported but missing PkgPath")
+			}
+		} else {
+			if field.Anonymous {
+				panic("reflect.StructOf: field \"" + field.Name + "\" is anonymous but has PkgPath set")
+			}
+			if pkgPath != "" && pkgPath != field.PkgPath {
+				// There is only one package path per struct type.
+				panic("reflect.StructOf: unexported fields from different packages")
+			}
+			pkgPath = field.PkgPath
+		}
+		if field.Type == nil {
+			panic("reflect.StructOf: field " + itoa.Itoa(i) + " has no type")
+		}
+		if field.Anonymous && field.Type.NumMethod() != 0 {
+			panic("reflect.StructOf: embedded field with methods not implemented")
+		}
+		if len(field.Tag) > 0xff {
+			panic("reflect.StructOf: tag of field " + field.Name + " is too long")
+		}
+		for _, prev := range fields[:i] {
+			if prev.Name == field.Name {
+				panic("reflect.StructOf: duplicate field " + field.Name)
+			}
+		}
+		fieldType := field.Type.(*rawType)
+		fieldAlign := uintptr(fieldType.Align())
+		size = align(size, fieldAlign)
+		offsets[i] = size
+		size += fieldType.Size()
+		if fieldAlign > alignment {
+			alignment = fieldAlign
+		}
+		flags &= fieldType.typeFlags()
+	}
+	size = align(size, alignment)
+	if uint64(size) > 0xffffffff {
+		panic("reflect.StructOf: struct size would exceed virtual address space")
+	}
+
+	if st := findType(Struct, func(st *rawType) bool {
+		if st.NumField() != len(fields) {
+			return false
+		}
+		for i, field := range fields {
+			f := st.rawField(i)
+			if f.Name != field.Name || f.PkgPath != field.PkgPath || f.Type != field.Type.(*rawType) || f.Tag != field.Tag || f.Anonymous != field.Anonymous {
+				return false
+			}
+		}
+		return true
+	}); st != nil {
+		return st
+	}
+
+	// The fields array has room for at least one field.
+	numSlots := len(fields)
+	if numSlots == 0 {
+		numSlots = 1
+	}
+	typeSize := unsafe.Offsetof(structType{}.fields) + uintptr(numSlots)*unsafe.Sizeof(structField{})
+	st := (*structType)(unsafe.Pointer(newType(typeSize, uint8(Struct)|flags)))
+	st.pkgpath = &append([]byte(pkgPath), 0)[0]
+	st.size = uint32(size)
+	st.numField = uint16(len(fields))
+	for i, field := range fields {
+		// Encode the field data like getTypeCode in compiler/interface.go.
+		var fieldFlags byte
+		if field.PkgPath == "" {
+			fieldFlags |= structFieldFlagIsExported
+		}
+		if field.Anonymous {
+			fieldFlags |= structFieldFlagAnonymous | structFieldFlagIsEmbedded
+		}
+		if field.Tag != "" {
+			fieldFlags |= structFieldFlagHasTag
+		}
+		data := []byte{fieldFlags}
+		for offset := offsets[i]; ; offset >>= 7 {
+			if offset < 0x80 {
+				data = append(data, byte(offset))
+				break
+			}
+			data = append(data, byte(offset)|0x80)
+		}
+		data = append(data, field.Name...)
+		data = append(data, 0)
+		if field.Tag != "" {
+			data = append(data, byte(len(field.Tag)))
+			data = append(data, string(field.Tag)...)
+		}
+
+		sf := (*structField)(unsafe.Add(unsafe.Pointer(&st.fields[0]), uintptr(i)*unsafe.Sizeof(structField{})))
+		sf.fieldType = field.Type.(*rawType)
+		sf.data = unsafe.Pointer(&data[0])
+	}
+	return &st.rawType
+}
+
+// isValidFieldName returns whether name is a valid Go identifier.
+func isValidFieldName(name string) bool {
+	for i, c := range name {
+		if !(unicode.IsLetter(c) || c == '_' || (i > 0 && unicode.IsDigit(c))) {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// FuncOf returns the function type with the given argument and result types.
+// For example if k represents int and e represents string, FuncOf([]Type{k},
+// []Type{e}, false) represents func(int) string.
+//
+// The variadic argument controls whether the function is variadic. FuncOf
+// panics if variadic is true and in[len(in)-1] does not represent a slice.
+//
+// Functions of a constructed type can't be called with Value.Call, as there
+// is no way to generate the code to call them at runtime.
+func FuncOf(in, out []Type, variadic bool) Type {
+	if variadic && (len(in) == 0 || in[len(in)-1].Kind() != Slice) {
+		panic("reflect.FuncOf: last arg of variadic func must be slice")
+	}
+	if len(in) >= funcFlagVariadic || len(out) > 0xffff {
+		panic("reflect.FuncOf: too many arguments")
+	}
+	for _, t := range in {
+		if t == nil {
+			panic("reflect: nil type passed to FuncOf")
+		}
+	}
+	for _, t := range out {
+		if t == nil {
+			panic("reflect: nil type passed to FuncOf")
+		}
+	}
+
+	if ft := findType(Func, func(ft *rawType) bool {
+		if ft.NumIn() != len(in) || ft.NumOut() != len(out) || ft.IsVariadic() != variadic {
+			return false
+		}
+		for i, t := range in {
+			if ft.In(i) != t {
+				return false
+			}
+		}
+		for i, t := range out {
+			if ft.Out(i) != t {
+				return false
+			}
+		}
+		return true
+	}); ft != nil {
+		return ft
+	}
+
+	str := funcTypeString(in, out, variadic)
+	params := append(append([]Type(nil), in...), out...)
+	paramSize := unsafe.Sizeof((*rawType)(nil))
+	typeSize := unsafe.Offsetof(funcType{}.params) + uintptr(len(params))*paramSize + uintptr(len(str)) + 1
+	ft := (*funcType)(unsafe.Pointer(newType(typeSize, uint8(Func))))
+	ft.numIn = uint16(len(in))
+	if variadic {
+		ft.numIn |= funcFlagVariadic
+	}
+	ft.numOut = uint16(len(out))
+	for i, t := range params {
+		*(**rawType)(unsafe.Add(unsafe.Pointer(&ft.params[0]), uintptr(i)*paramSize)) = t.(*rawType)
+	}
+	// The string follows the parameters, see typeString.
+	copy(unsafe.Slice((*byte)(unsafe.Add(unsafe.Pointer(&ft.params[0]), uintptr(len(params))*paramSize)), len(str)), str)
+	return &ft.rawType
+}
+
+// funcTypeString returns the result of String() for a function type, like
+// "func(int, ...string) (bool, error)".
+func funcTypeString(in, out []Type, variadic bool) string {
+	s := "func("
+	for i, t := range in {
+		if i > 0 {
+			s += ", "
+		}
+		if variadic && i == len(in)-1 {
+			s += "..." + t.Elem().String()
+		} else {
+			s += t.String()
+		}
+	}
+	s += ")"
+	switch len(out) {
+	case 0:
+	case 1:
+		s += " " + out[0].String()
+	default:
+		s += " ("
+		for i, t := range out {
+			if i > 0 {
+				s += ", "
+			}
+			s += t.String()
+		}
+		s += ")"
+	}
+	return s
+}
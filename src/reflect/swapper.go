@@ -10,10 +10,13 @@ import "unsafe"
 // 		Use of this source code is governed by a BSD-style
 // 		license that can be found in the LICENSE file.
 
+// Swapper returns a function that swaps the elements in the provided slice.
+//
+// Swapper panics if the provided interface is not a slice.
 func Swapper(slice interface{}) func(i, j int) {
 	v := ValueOf(slice)
 	if v.Kind() != Slice {
-		panic(&ValueError{Method: "Swapper"})
+		panic(&ValueError{Method: "Swapper", Kind: v.Kind()})
 	}
 
 	// Just return Nop func if nothing to swap.
@@ -21,12 +24,47 @@ func Swapper(slice interface{}) func(i, j int) {
 		return func(i, j int) {}
 	}
 
-	typ := v.typecode.Elem()
+	typ := v.typecode.elem()
 	size := typ.Size()
+	align := uintptr(typ.Align())
+	header := *(*sliceHeader)(v.value)
 
-	header := (*sliceHeader)(v.value)
-	tmp := unsafe.Pointer(&make([]byte, size)[0])
+	// Fast paths for elements that can be swapped with a single load and store
+	// each. This requires the element to be aligned like the integer of the
+	// same size, so that the loads and stores are aligned too.
+	switch {
+	case size == 0:
+		return func(i, j int) {
+			if uint(i) >= uint(header.len) || uint(j) >= uint(header.len) {
+				panic("reflect: slice index out of range")
+			}
+		}
+	case typ.Kind() == String:
+		ss := unsafe.Slice((*string)(header.data), header.len)
+		return func(i, j int) { ss[i], ss[j] = ss[j], ss[i] }
+	case size == unsafe.Sizeof(uintptr(0)) && align == unsafe.Alignof(uintptr(0)):
+		// Use unsafe.Pointer so that a pointer element is never only stored in
+		// a non-pointer variable.
+		ps := unsafe.Slice((*unsafe.Pointer)(header.data), header.len)
+		return func(i, j int) { ps[i], ps[j] = ps[j], ps[i] }
+	case size == 8 && align == unsafe.Alignof(uint64(0)):
+		is := unsafe.Slice((*uint64)(header.data), header.len)
+		return func(i, j int) { is[i], is[j] = is[j], is[i] }
+	case size == 4 && align == unsafe.Alignof(uint32(0)):
+		is := unsafe.Slice((*uint32)(header.data), header.len)
+		return func(i, j int) { is[i], is[j] = is[j], is[i] }
+	case size == 2 && align == unsafe.Alignof(uint16(0)):
+		is := unsafe.Slice((*uint16)(header.data), header.len)
+		return func(i, j int) { is[i], is[j] = is[j], is[i] }
+	case size == 1:
+		is := unsafe.Slice((*uint8)(header.data), header.len)
+		return func(i, j int) { is[i], is[j] = is[j], is[i] }
+	}
 
+	// Other elements are swapped through a temporary buffer. It is allocated
+	// like a value of the element type, so that pointers in it are seen by the
+	// garbage collector.
+	tmp := alloc(size, nil)
 	return func(i, j int) {
 		if uint(i) >= uint(header.len) || uint(j) >= uint(header.len) {
 			panic("reflect: slice index out of range")
@@ -29,12 +29,12 @@
 //     ptrTo        *typeStruct
 //     elem         *typeStruct // element type of the array
 //     arrayLen     uintptr     // length of the array (this is part of the type)
-// - map types (this is still missing the key and element types)
+// - map types (see mapType)
 //     meta         uint8
 //     nmethods     uint16 (0)
 //     ptrTo        *typeStruct
-//     elem         *typeStruct
-//     key          *typeStruct
+//     elem         *typeStruct // element type of the map
+//     key          *typeStruct // key type of the map
 // - struct types (see structType):
 //     meta         uint8
 //     nmethods     uint16
@@ -43,12 +43,20 @@
 //     pkgpath      *byte       // package path; null terminated
 //     numField     uint16
 //     fields       [...]structField // the remaining fields are all of type structField
-// - interface types (this is missing the interface methods):
+// - interface types (see interfaceType):
 //     meta         uint8
+//     nmethods     uint16      // number of methods
 //     ptrTo        *typeStruct
-// - signature types (this is missing input and output parameters):
+//     methods      [...]*methodSignature // all methods, sorted like in go/types
+//     str          [1]byte     // result of String(); null terminated
+// - signature types (see funcType):
 //     meta         uint8
+//     numIn        uint16      // number of parameters, top bit set if variadic
 //     ptrTo        *typeStruct
+//     numOut       uint16      // number of results
+//     call         func        // compiler-generated thunk used by Value.Call
+//     params       [...]*typeStruct // numIn parameters followed by numOut results
+//     str          [1]byte     // result of String(); null terminated
 // - named types
 //     meta         uint8
 //     nmethods     uint16      // number of methods
@@ -59,6 +67,15 @@
 //
 // The type struct is essentially a union of all the above types. Which it is,
 // can be determined by looking at the meta byte.
+//
+// Named, pointer and struct types with a non-zero nmethods field (which only
+// counts exported methods) are preceded by a pointer to their method table (see
+// methodTable). The method table is sorted by name, and each entry points to a
+// method signature (see methodSignature) that contains the method name and the
+// method type without receiver. Each entry also contains the interface invoke
+// wrapper of the method and the call thunk to call this wrapper, if the program
+// calls methods through Value.Method (otherwise they are removed during
+// interface lowering).
 
 package reflect
 
@@ -74,6 +91,7 @@ const (
 	structFieldFlagHasTag
 	structFieldFlagIsExported
 	structFieldFlagIsEmbedded
+	structFieldFlagHasTagTable
 )
 
 type Kind uint8
@@ -360,7 +378,7 @@ type Type interface {
 	// and FieldByNameFunc returns no match.
 	// This behavior mirrors Go's handling of name lookup in
 	// structs containing embedded fields.
-	//FieldByNameFunc(match func(string) bool) (StructField, bool)
+	FieldByNameFunc(match func(string) bool) (StructField, bool)
 
 	// In returns the type of a function type's i'th input parameter.
 	// It panics if the type's Kind is not Func.
@@ -401,6 +419,10 @@ const (
 	flagIsBinary   = 128 // flag that is set if this type uses the hashmap binary algorithm
 )
 
+// Flag set in the numIn field of a function type if the last parameter is
+// variadic. Must be kept up to date with compiler/interface.go.
+const funcFlagVariadic = 1 << 15
+
 // The base type struct. All type structs start with this.
 type rawType struct {
 	meta uint8 // metadata byte, contains kind and flags (see contants above)
@@ -446,6 +468,35 @@ type namedType struct {
 	name      [1]byte
 }
 
+// Type for interface types. The methods array isn't necessarily 1 element
+// long, instead it contains numMethod method signatures (including unexported
+// methods). It is followed by the result of String(), which is the empty
+// string when built with -no-type-strings.
+type interfaceType struct {
+	rawType
+	numMethod uint16
+	ptrTo     *rawType
+	methods   [1]*methodSignature
+}
+
+// Type for function types. The params array isn't necessarily 1 element long,
+// instead it contains all input parameters followed by all output parameters.
+// It is followed by the result of String(), like in interfaceType.
+// The call field is a compiler-generated thunk that calls the function value fn
+// points to, with args and results each pointing to an array of pointers to the
+// parameter and result values. The makeFunc field holds (without context) the
+// function pointer of the functions created by MakeFunc, see makeFuncImpl. It
+// is nil if MakeFunc isn't used in the program.
+type funcType struct {
+	rawType
+	numIn    uint16
+	ptrTo    *rawType
+	numOut   uint16
+	call     func(fn, args, results unsafe.Pointer)
+	makeFunc func()
+	params   [1]*rawType
+}
+
 // Type for struct types. The numField value is intentionally put before ptrTo
 // for better struct packing on 32-bit and 64-bit architectures. On these
 // architectures, the ptrTo field still has the same offset as in all the other
@@ -468,6 +519,28 @@ type structField struct {
 	data      unsafe.Pointer // various bits of information, packed in a byte array
 }
 
+// Entry in the method table of a type, see the comment at the top of this file.
+// Must be kept up to date with getTypeMethodSet in compiler/interface.go.
+type methodTableEntry struct {
+	signature *methodSignature
+	typ       *rawType       // method type with the receiver as first parameter
+	fn        unsafe.Pointer // invoke wrapper, or nil if Value.Method isn't used
+	call      func(fn, args, results unsafe.Pointer)
+}
+
+// Method signature global, see getMethodSignature in compiler/interface.go.
+type methodSignature struct {
+	typ  *rawType // method type without receiver
+	name [1]byte  // method name; null terminated
+}
+
+// methods returns the method table of this type. It must only be called on
+// non-interface types that have a non-zero number of methods.
+func (t *rawType) methods() []methodTableEntry {
+	table := *(**methodTableEntry)(unsafe.Add(unsafe.Pointer(t), -int(unsafe.Sizeof(uintptr(0)))))
+	return unsafe.Slice(table, t.NumMethod())
+}
+
 // Equivalent to (go/types.Type).Underlying(): if this is a named type return
 // the underlying type, else just return the type itself.
 func (t *rawType) underlying() *rawType {
@@ -580,8 +653,15 @@ func (t *rawType) String() string {
 		s += " }"
 		return s
 	case Interface:
-		// TODO(dgryski): Needs actual method set info
+		if s := t.typeString(); s != "" {
+			return s
+		}
 		return "interface {}"
+	case Func:
+		if s := t.typeString(); s != "" {
+			return s
+		}
+		return "func"
 	default:
 		return t.Kind().String()
 	}
@@ -589,6 +669,38 @@ func (t *rawType) String() string {
 	return t.Kind().String()
 }
 
+// typeString returns the string stored in the type struct of an (unnamed)
+// interface or function type.
+func (t *rawType) typeString() string {
+	if t.Kind() == Func {
+		ftype := (*funcType)(unsafe.Pointer(t))
+		numParams := uintptr(ftype.numIn&^funcFlagVariadic) + uintptr(ftype.numOut)
+		return readStringZ(unsafe.Add(unsafe.Pointer(&ftype.params[0]), numParams*unsafe.Sizeof(ftype.params[0])))
+	}
+	itype := (*interfaceType)(unsafe.Pointer(t))
+	return readStringZ(unsafe.Add(unsafe.Pointer(&itype.methods[0]), uintptr(itype.numMethod)*unsafe.Sizeof(itype.methods[0])))
+}
+
+// interfaceMethods returns the method signatures of an interface type.
+func (t *rawType) interfaceMethods() []*methodSignature {
+	itype := (*interfaceType)(unsafe.Pointer(t.underlying()))
+	return unsafe.Slice(&itype.methods[0], itype.numMethod)
+}
+
+// funcType returns the underlying function type struct. It panics with the
+// given method name if t is not a function type.
+func (t *rawType) funcType(method string) *funcType {
+	if t.Kind() != Func {
+		panic(&TypeError{method})
+	}
+	return (*funcType)(unsafe.Pointer(t.underlying()))
+}
+
+// param returns the i'th entry of the params array of a function type.
+func (t *funcType) param(i int) *rawType {
+	return *(**rawType)(unsafe.Add(unsafe.Pointer(&t.params[0]), uintptr(i)*unsafe.Sizeof(t.params[0])))
+}
+
 func (t *rawType) Kind() Kind {
 	if t == nil {
 		return Invalid
@@ -651,6 +763,15 @@ func rawStructFieldFromPointer(descriptor *structType, fieldType *rawType, data
 	var tag string
 	if flagsByte&structFieldFlagHasTag != 0 {
 		data = unsafe.Add(data, 1) // C: data+1
+		if flagsByte&structFieldFlagHasTagTable != 0 {
+			// Skip the tag table and its size, see StructTag.table.
+			numKeys := uintptr(*(*byte)(data))
+			data = unsafe.Add(data, 1)
+			for i := uintptr(0); i < numKeys*2; i++ {
+				data = unsafe.Add(data, 1+uintptr(*(*byte)(data)))
+			}
+			data = unsafe.Add(data, 1)
+		}
 		tagLen := uintptr(*(*byte)(data))
 		data = unsafe.Add(data, 1) // C: data+1
 		tag = *(*string)(unsafe.Pointer(&stringHeader{
@@ -715,6 +836,16 @@ func (t *rawType) rawField(n int) rawStructField {
 //
 // For internal use only.
 func (t *rawType) rawFieldByName(n string) (rawStructField, []int, bool) {
+	return t.rawFieldByNameFunc(func(name string) bool {
+		return name == n
+	})
+}
+
+// rawFieldByNameFunc returns nearly the same value as FieldByNameFunc but
+// without converting the Type member to an interface.
+//
+// For internal use only.
+func (t *rawType) rawFieldByNameFunc(match func(string) bool) (rawStructField, []int, bool) {
 	if t.Kind() != Struct {
 		panic(&TypeError{"Field"})
 	}
@@ -722,10 +853,16 @@ func (t *rawType) rawFieldByName(n string) (rawStructField, []int, bool) {
 	type fieldWalker struct {
 		t     *rawType
 		index []int
+		count int // number of times this struct was embedded at this depth
 	}
 
 	queue := make([]fieldWalker, 0, 4)
-	queue = append(queue, fieldWalker{t, nil})
+	queue = append(queue, fieldWalker{t, nil, 1})
+
+	// Struct types that were already searched at a lower depth. Embedded
+	// fields of those types can be ignored, as they are hidden by the fields
+	// found at the lower depth (and they may be recursive).
+	visited := make(map[*rawType]bool)
 
 	for len(queue) > 0 {
 		type result struct {
@@ -738,6 +875,11 @@ func (t *rawType) rawFieldByName(n string) (rawStructField, []int, bool) {
 
 		// For all the structs at this level..
 		for _, ll := range queue {
+			if visited[ll.t] {
+				continue
+			}
+			visited[ll.t] = true
+
 			// Iterate over all the fields looking for the matching name
 			// Also calculate field offset.
 
@@ -757,24 +899,43 @@ func (t *rawType) rawFieldByName(n string) (rawStructField, []int, bool) {
 
 				name := readStringZ(data)
 				data = unsafe.Add(data, len(name))
-				if name == n {
-					found = append(found, result{
-						rawStructFieldFromPointer(descriptor, field.fieldType, data, flagsByte, name, offset),
-						append(ll.index, int(i)),
-					})
-				}
 
-				structOrPtrToStruct := field.fieldType.Kind() == Struct || (field.fieldType.Kind() == Pointer && field.fieldType.elem().Kind() == Struct)
-				if flagsByte&structFieldFlagIsEmbedded == structFieldFlagIsEmbedded && structOrPtrToStruct {
+				// Copy the index, so that it isn't shared between fields.
+				index := append(ll.index[:len(ll.index):len(ll.index)], int(i))
+
+				if match(name) {
+					r := result{
+						rawStructFieldFromPointer(descriptor, field.fieldType, data, flagsByte, name, offset),
+						index,
+					}
+					found = append(found, r)
+					if ll.count > 1 {
+						// The struct was embedded multiple times at this
+						// depth, so the field is ambiguous.
+						found = append(found, r)
+					}
+				} else if flagsByte&structFieldFlagIsEmbedded == structFieldFlagIsEmbedded {
 					embedded := field.fieldType
 					if embedded.Kind() == Pointer {
 						embedded = embedded.elem()
 					}
-
-					nextlevel = append(nextlevel, fieldWalker{
-						t:     embedded,
-						index: append(ll.index, int(i)),
-					})
+					if embedded.Kind() == Struct {
+						next := -1
+						for j := range nextlevel {
+							if nextlevel[j].t == embedded {
+								next = j
+							}
+						}
+						if next >= 0 {
+							nextlevel[next].count = 2 // exact count doesn't matter
+						} else {
+							nextlevel = append(nextlevel, fieldWalker{
+								t:     embedded,
+								index: index,
+								count: ll.count,
+							})
+						}
+					}
 				}
 
 				// update offset/field pointer if there *is* a next field
@@ -940,27 +1101,89 @@ func (t *rawType) FieldAlign() int {
 // AssignableTo returns whether a value of type t can be assigned to a variable
 // of type u.
 func (t *rawType) AssignableTo(u Type) bool {
-	if t == u.(*rawType) {
-		return true
+	if u == nil {
+		panic("reflect: nil type passed to Type.AssignableTo")
 	}
-
-	if u.Kind() == Interface && u.NumMethod() == 0 {
+	uu := u.(*rawType)
+	if t == uu {
 		return true
 	}
+	if uu.Kind() == Interface {
+		return t.implements(uu)
+	}
+	return t.directlyAssignableTo(uu)
+}
 
-	if u.Kind() == Interface {
-		panic("reflect: unimplemented: AssignableTo with interface")
+// directlyAssignableTo returns whether a value of type t can be assigned to a
+// variable of the non-interface type u, following the assignability rules of
+// the Go specification. Unnamed types are deduplicated by the compiler, so
+// identical underlying types have the same type descriptor.
+func (t *rawType) directlyAssignableTo(u *rawType) bool {
+	if (t.isNamed() && u.isNamed()) || t.Kind() != u.Kind() {
+		return false
 	}
-	return false
+	if t.Kind() == Chan && t.ChanDir() == BothDir && t.elem() == u.elem() {
+		// A bidirectional channel can be assigned to a directional channel
+		// with the same element type.
+		return true
+	}
+	return t.underlying() == u.underlying()
 }
 
 func (t *rawType) Implements(u Type) bool {
+	if u == nil {
+		panic("reflect: nil type passed to Type.Implements")
+	}
 	if u.Kind() != Interface {
 		panic("reflect: non-interface type passed to Type.Implements")
 	}
-	return t.AssignableTo(u)
+	return t.implements(u.(*rawType))
+}
+
+// implements returns whether t has all methods of the interface type u. Method
+// signatures are unique globals (see getMethodSignature in
+// compiler/interface.go), so they can be compared by pointer.
+func (t *rawType) implements(u *rawType) bool {
+	for _, signature := range u.interfaceMethods() {
+		if !t.hasMethod(signature) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasMethod returns whether the method set of t contains a method with the
+// given signature.
+func (t *rawType) hasMethod(signature *methodSignature) bool {
+	if t.Kind() == Interface {
+		for _, method := range t.interfaceMethods() {
+			if method == signature {
+				return true
+			}
+		}
+		return false
+	}
+	if t.NumMethod() != 0 {
+		for _, entry := range t.methods() {
+			if entry.signature == signature {
+				return true
+			}
+		}
+	}
+	// Method tables only contain exported methods.
+	return hasUnexportedMethod(t, signature)
 }
 
+// hasUnexportedMethod returns whether the method set of t contains the given
+// unexported method. The signatures of unexported methods include the package
+// path (see getMethodSignatureName in compiler/interface.go), so methods with
+// the same name in different packages don't match. It returns false for
+// exported methods.
+//
+// This function is defined by the interface lowering pass, which knows the
+// method sets of all types in the program.
+func hasUnexportedMethod(t *rawType, signature *methodSignature) bool
+
 // Comparable returns whether values of this type can be compared to each other.
 func (t *rawType) Comparable() bool {
 	return (t.meta & flagComparable) == flagComparable
@@ -973,32 +1196,50 @@ func (t *rawType) isBinary() bool {
 
 func (t *rawType) ChanDir() ChanDir {
 	if t.Kind() != Chan {
-		panic(TypeError{"ChanDir"})
+		panic(&TypeError{"ChanDir"})
 	}
 
-	dir := int((*elemType)(unsafe.Pointer(t)).numMethod)
+	dir := int((*elemType)(unsafe.Pointer(t.underlying())).numMethod)
 
 	// nummethod is overloaded for channel to store channel direction
 	return ChanDir(dir)
 }
 
+// ConvertibleTo reports whether a value of type t is convertible to type u.
+// Even if ConvertibleTo returns true, the conversion may still panic, for
+// example when converting a slice to an array that is longer than the slice.
 func (t *rawType) ConvertibleTo(u Type) bool {
-	panic("unimplemented: (reflect.Type).ConvertibleTo()")
+	if u == nil {
+		panic("reflect: nil type passed to Type.ConvertibleTo")
+	}
+	return convertOp(u.(*rawType), t) != nil
 }
 
+// IsVariadic returns whether the last input parameter of a function type is a
+// "..." parameter. It panics if the type is not a function type.
 func (t *rawType) IsVariadic() bool {
-	panic("unimplemented: (reflect.Type).IsVariadic()")
+	return t.funcType("IsVariadic").numIn&funcFlagVariadic != 0
 }
 
+// NumIn returns the number of input parameters of a function type. It panics
+// if the type is not a function type.
 func (t *rawType) NumIn() int {
-	panic("unimplemented: (reflect.Type).NumIn()")
+	return int(t.funcType("NumIn").numIn &^ funcFlagVariadic)
 }
 
+// NumOut returns the number of output parameters of a function type. It
+// panics if the type is not a function type.
 func (t *rawType) NumOut() int {
-	panic("unimplemented: (reflect.Type).NumOut()")
+	return int(t.funcType("NumOut").numOut)
 }
 
+// NumMethod returns the number of methods in the method set of t. For
+// non-interface types, only exported methods are counted.
 func (t *rawType) NumMethod() int {
+	if t.ptrtag() != 0 {
+		// Pointers to pointers have no methods.
+		return 0
+	}
 
 	if t.isNamed() {
 		return int((*namedType)(unsafe.Pointer(t)).numMethod)
@@ -1009,6 +1250,8 @@ func (t *rawType) NumMethod() int {
 		return int((*ptrType)(unsafe.Pointer(t)).numMethod)
 	case Struct:
 		return int((*structType)(unsafe.Pointer(t)).numMethod)
+	case Interface:
+		return int((*interfaceType)(unsafe.Pointer(t)).numMethod)
 	}
 
 	// Other types have no methods attached.  Note we don't panic here.
@@ -1057,20 +1300,71 @@ func (t *rawType) Key() Type {
 	return t.key()
 }
 
-func (t rawType) In(i int) Type {
-	panic("unimplemented: (reflect.Type).In()")
+// In returns the type of the i'th input parameter of a function type. It
+// panics if the type is not a function type or if i is out of range.
+func (t *rawType) In(i int) Type {
+	ftype := t.funcType("In")
+	if uint(i) >= uint(ftype.numIn&^funcFlagVariadic) {
+		panic("reflect: Function index out of range")
+	}
+	return ftype.param(i)
 }
 
-func (t rawType) Out(i int) Type {
-	panic("unimplemented: (reflect.Type).Out()")
+// Out returns the type of the i'th output parameter of a function type. It
+// panics if the type is not a function type or if i is out of range.
+func (t *rawType) Out(i int) Type {
+	ftype := t.funcType("Out")
+	if uint(i) >= uint(ftype.numOut) {
+		panic("reflect: Function index out of range")
+	}
+	return ftype.param(int(ftype.numIn&^funcFlagVariadic) + i)
 }
 
-func (t rawType) Method(i int) Method {
-	panic("unimplemented: (reflect.Type).Method()")
+// Method returns the i'th exported method in the method set of t, sorted by
+// name. It panics if i is out of range.
+//
+// The Func field of the returned Method is only set if the program calls
+// methods through Value.Method or Value.MethodByName, otherwise it is the zero
+// Value.
+func (t *rawType) Method(i int) Method {
+	if uint(i) >= uint(t.NumMethod()) {
+		panic("reflect: Method index out of range")
+	}
+	if t.Kind() == Interface {
+		// Methods of interface types don't have a Func.
+		signature := t.interfaceMethods()[i]
+		return Method{
+			Name:  readStringZ(unsafe.Pointer(&signature.name[0])),
+			Type:  signature.typ,
+			Index: i,
+		}
+	}
+	entry := &t.methods()[i]
+	m := Method{
+		Name:  readStringZ(unsafe.Pointer(&entry.signature.name[0])),
+		Type:  entry.typ,
+		Index: i,
+	}
+	if entry.fn != nil {
+		m.Func = Value{
+			typecode: entry.typ,
+			value:    unsafe.Pointer(&methodValue{entry: entry}),
+			flags:    valueFlagExported | valueFlagMethod,
+		}
+	}
+	return m
 }
 
-func (t rawType) MethodByName(name string) (Method, bool) {
-	panic("unimplemented: (reflect.Type).MethodByName()")
+// MethodByName returns the exported method with the given name in the method
+// set of t, and whether it was found.
+func (t *rawType) MethodByName(name string) (Method, bool) {
+	for i := 0; i < t.NumMethod(); i++ {
+		m := t.Method(i)
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return Method{}, false
 }
 
 func (t *rawType) PkgPath() string {
@@ -1103,6 +1397,29 @@ func (t *rawType) FieldByName(name string) (StructField, bool) {
 	}, true
 }
 
+// FieldByNameFunc returns the struct field with a name that satisfies the
+// match function, using the same rules for embedded fields as FieldByName.
+func (t *rawType) FieldByNameFunc(match func(string) bool) (StructField, bool) {
+	if t.Kind() != Struct {
+		panic(&TypeError{"FieldByNameFunc"})
+	}
+
+	field, index, ok := t.rawFieldByNameFunc(match)
+	if !ok {
+		return StructField{}, false
+	}
+
+	return StructField{
+		Name:      field.Name,
+		PkgPath:   field.PkgPath,
+		Type:      field.Type, // note: converts rawType to Type
+		Tag:       field.Tag,
+		Anonymous: field.Anonymous,
+		Offset:    field.Offset,
+		Index:     index,
+	}, true
+}
+
 func (t *rawType) FieldByIndex(index []int) StructField {
 	ftype := t
 	var field rawStructField
@@ -1168,8 +1485,20 @@ type rawStructField struct {
 // A StructTag is the tag string in a struct field.
 type StructTag string
 
-// TODO: it would be feasible to do the key/value splitting at compile time,
-// avoiding the code size cost of doing it at runtime
+// structTagData is the layout of structTags.
+type structTagData struct {
+	size uintptr
+	data [0]byte
+}
+
+// structTags contains the data of all struct fields with a tag table (see
+// structFieldFlagHasTagTable), followed by a bitmap with a bit set for the first
+// byte of each tag in the data. It is defined by the interface lowering pass
+// (see transform/interface-lowering.go), which moves the struct field data
+// there, and is only included in the program if StructTag.Lookup is used.
+//
+//go:extern
+var structTags structTagData
 
 // Get returns the value associated with key in the tag string.
 func (tag StructTag) Get(key string) string {
@@ -1179,6 +1508,10 @@ func (tag StructTag) Get(key string) string {
 
 // Lookup returns the value associated with key in the tag string.
 func (tag StructTag) Lookup(key string) (value string, ok bool) {
+	if table := tag.table(); table != nil {
+		return lookupTagTable(table, key)
+	}
+
 	for tag != "" {
 		// Skip leading space.
 		i := 0
@@ -1229,6 +1562,53 @@ func (tag StructTag) Lookup(key string) (value string, ok bool) {
 	return "", false
 }
 
+// table returns the table with the keys and values of the tag that the
+// compiler emitted right before a struct tag in the conventional format, or nil
+// if there is no such table, for example because the tag string was created at
+// runtime.
+func (tag StructTag) table() unsafe.Pointer {
+	data := (*stringHeader)(unsafe.Pointer(&tag)).data
+	offset := uintptr(data) - uintptr(unsafe.Pointer(&structTags.data))
+	if tag == "" || offset >= structTags.size {
+		return nil
+	}
+	// Check that the tag starts at the start of a tag in the table, and that
+	// it is the whole tag and not a part of it.
+	starts := unsafe.Add(unsafe.Pointer(&structTags.data), structTags.size)
+	if *(*byte)(unsafe.Add(starts, offset/8))&(1<<(offset%8)) == 0 || uintptr(*(*byte)(unsafe.Add(data, -1))) != uintptr(len(tag)) {
+		return nil
+	}
+	// The tag is preceded by its length, the size of the table and the table.
+	tableSize := uintptr(*(*byte)(unsafe.Add(data, -2)))
+	return unsafe.Add(data, -2-int(tableSize))
+}
+
+// lookupTagTable returns the value associated with key in a tag table emitted
+// by the compiler. The table consists of the number of keys followed by each
+// key and its value, each prefixed with its length.
+func lookupTagTable(table unsafe.Pointer, key string) (value string, ok bool) {
+	numKeys := int(*(*byte)(table))
+	table = unsafe.Add(table, 1)
+	for i := 0; i < numKeys; i++ {
+		nameLen := uintptr(*(*byte)(table))
+		name := *(*string)(unsafe.Pointer(&stringHeader{
+			data: unsafe.Add(table, 1),
+			len:  nameLen,
+		}))
+		table = unsafe.Add(table, 1+nameLen)
+		valueLen := uintptr(*(*byte)(table))
+		value := *(*string)(unsafe.Pointer(&stringHeader{
+			data: unsafe.Add(table, 1),
+			len:  valueLen,
+		}))
+		table = unsafe.Add(table, 1+valueLen)
+		if name == key {
+			return value, true
+		}
+	}
+	return "", false
+}
+
 // TypeError is the error that is used in a panic when invoking a method on a
 // type that is not applicable to that type.
 type TypeError struct {
@@ -1243,18 +1623,6 @@ func align(offset uintptr, alignment uintptr) uintptr {
 	return (offset + alignment - 1) &^ (alignment - 1)
 }
 
-func SliceOf(t Type) Type {
-	panic("unimplemented: reflect.SliceOf()")
-}
-
-func ArrayOf(n int, t Type) Type {
-	panic("unimplemented: reflect.ArrayOf()")
-}
-
-func StructOf([]StructField) Type {
-	panic("unimplemented: reflect.StructOf()")
-}
-
 func MapOf(key, value Type) Type {
 	panic("unimplemented: reflect.MapOf()")
 }
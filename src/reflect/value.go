@@ -1,6 +1,7 @@
 package reflect
 
 import (
+	"internal/itoa"
 	"math"
 	"unsafe"
 )
@@ -16,6 +17,7 @@ const (
 	valueFlagExported
 	valueFlagEmbedRO
 	valueFlagStickyRO
+	valueFlagMethod // value points to a methodValue
 
 	valueFlagRO = valueFlagEmbedRO | valueFlagStickyRO
 )
@@ -57,6 +59,22 @@ func (v Value) checkRO() {
 	}
 }
 
+// checkExported panics if v was obtained through an unexported struct field, in
+// which case its data can't be used by the given method.
+func (v Value) checkExported(method string) {
+	if !v.isExported() || v.isRO() {
+		panic("reflect: " + method + " using value obtained using unexported field")
+	}
+}
+
+// checkNotMethod panics if v was returned by Value.Method, as such a value can
+// only be called and not be converted to a func value.
+func (v Value) checkNotMethod(op string) {
+	if v.flags&valueFlagMethod != 0 {
+		panic("unimplemented: " + op + " on method value")
+	}
+}
+
 func Indirect(v Value) Value {
 	if v.Kind() != Ptr {
 		return v
@@ -80,8 +98,11 @@ func ValueOf(i interface{}) Value {
 }
 
 func (v Value) Interface() interface{} {
-	if !v.isExported() {
-		panic("(reflect.Value).Interface: unexported")
+	if !v.CanInterface() {
+		// Values obtained through unexported fields can still be read with
+		// the other methods (like Int and String), but can't be used to get
+		// around the restrictions on unexported fields.
+		panic("reflect.Value.Interface: cannot return value obtained from unexported field or method")
 	}
 	return valueInterfaceUnsafe(v)
 }
@@ -89,6 +110,7 @@ func (v Value) Interface() interface{} {
 // valueInterfaceUnsafe is used by the runtime to hash map keys. It should not
 // be subject to the isExported check.
 func valueInterfaceUnsafe(v Value) interface{} {
+	v.checkNotMethod("(reflect.Value).Interface()")
 	if v.typecode.Kind() == Interface {
 		// The value itself is an interface. This can happen when getting the
 		// value of a struct field of interface type, like this:
@@ -141,6 +163,11 @@ func (v Value) IsZero() bool {
 		return v.Len() == 0
 	case Struct:
 		for i := 0; i < v.NumField(); i++ {
+			if v.typecode.rawField(i).Name == "_" {
+				// Blank fields can't be set, so they are not part of the
+				// value.
+				continue
+			}
 			if !v.Field(i).IsZero() {
 				return false
 			}
@@ -175,6 +202,9 @@ func (v Value) IsNil() bool {
 		if v.value == nil {
 			return true
 		}
+		if v.flags&valueFlagMethod != 0 {
+			return false
+		}
 		fn := (*funcHeader)(v.value)
 		return fn.Code == nil
 	case Slice:
@@ -207,6 +237,9 @@ func (v Value) UnsafePointer() unsafe.Pointer {
 		slice := (*sliceHeader)(v.value)
 		return slice.data
 	case Func:
+		if v.flags&valueFlagMethod != 0 {
+			return (*methodValue)(v.value).entry.fn
+		}
 		fn := (*funcHeader)(v.value)
 		if fn.Context != nil {
 			return fn.Context
@@ -286,8 +319,16 @@ func (v Value) Addr() Value {
 	}
 }
 
+// UnsafeAddr returns a pointer to the data of v, which must be addressable.
+// It is meant for advanced clients that also import the "unsafe" package.
 func (v Value) UnsafeAddr() uintptr {
-	return uintptr(v.Addr().UnsafePointer())
+	if !v.IsValid() {
+		panic(&ValueError{Method: "UnsafeAddr", Kind: Invalid})
+	}
+	if !v.CanAddr() {
+		panic("reflect.Value.UnsafeAddr of unaddressable value")
+	}
+	return uintptr(v.value)
 }
 
 func (v Value) CanSet() bool {
@@ -548,10 +589,12 @@ func (v Value) Slice(i, j int) Value {
 		hdr.cap = hdr.cap - i
 		hdr.data = unsafe.Add(hdr.data, i*elemSize)
 
+		// The result is a new slice header, which is not addressable (but
+		// its elements are).
 		return Value{
 			typecode: v.typecode,
 			value:    unsafe.Pointer(&hdr),
-			flags:    v.flags,
+			flags:    v.flags&valueFlagExported | v.flags.ro(),
 		}
 
 	case Array:
@@ -574,7 +617,7 @@ func (v Value) Slice(i, j int) Value {
 		return Value{
 			typecode: v.typecode,
 			value:    unsafe.Pointer(&hdr),
-			flags:    v.flags,
+			flags:    v.flags&valueFlagExported | v.flags.ro(),
 		}
 	}
 
@@ -600,7 +643,7 @@ func (v Value) Slice3(i, j, k int) Value {
 		return Value{
 			typecode: v.typecode,
 			value:    unsafe.Pointer(&hdr),
-			flags:    v.flags,
+			flags:    v.flags&valueFlagExported | v.flags.ro(),
 		}
 
 	case Array:
@@ -668,8 +711,11 @@ func (v Value) Elem() Value {
 		if ptr == nil {
 			return Value{}
 		}
-		// Don't copy RO flags
-		flags := (v.flags & (valueFlagIndirect | valueFlagExported)) | valueFlagIndirect
+		// The pointed-to value is addressable. Keep the RO flags as they are
+		// (and not as v.flags.ro()), so that the exported fields of an
+		// embedded struct are still settable through an unexported embedded
+		// pointer.
+		flags := v.flags&(valueFlagExported|valueFlagRO) | valueFlagIndirect
 		return Value{
 			typecode: v.typecode.elem(),
 			value:    ptr,
@@ -680,7 +726,7 @@ func (v Value) Elem() Value {
 		return Value{
 			typecode: (*rawType)(typecode),
 			value:    value,
-			flags:    v.flags &^ valueFlagIndirect,
+			flags:    v.flags&valueFlagExported | v.flags.ro(),
 		}
 	default:
 		panic(&ValueError{Method: "Elem", Kind: v.Kind()})
@@ -795,18 +841,24 @@ func (v Value) Index(i int) Value {
 		return Value{
 			typecode: uint8Type,
 			value:    unsafe.Pointer(uintptr(*(*uint8)(unsafe.Add(s.data, i)))),
-			flags:    v.flags & valueFlagExported,
+			flags:    v.flags&valueFlagExported | v.flags.ro(),
 		}
 	case Array:
-		// Extract an element from the array.
+		// Extract an element from the array. This only loads the element
+		// itself (or returns a pointer to it), the array is never copied.
+		if uint(i) >= uint(v.typecode.Len()) {
+			panic("reflect: array index out of range")
+		}
 		elemType := v.typecode.elem()
 		elemSize := elemType.Size()
 		size := v.typecode.Size()
+		// Elements of addressable arrays are addressable.
+		flags := v.flags&(valueFlagExported|valueFlagIndirect) | v.flags.ro()
 		if size == 0 {
 			// The element size is 0 and/or the length of the array is 0.
 			return Value{
 				typecode: v.typecode.elem(),
-				flags:    v.flags,
+				flags:    flags,
 			}
 		}
 		if elemSize > unsafe.Sizeof(uintptr(0)) {
@@ -817,7 +869,7 @@ func (v Value) Index(i int) Value {
 			addr := unsafe.Add(v.value, elemSize*uintptr(i)) // pointer to new value
 			return Value{
 				typecode: v.typecode.elem(),
-				flags:    v.flags,
+				flags:    flags,
 				value:    addr,
 			}
 		}
@@ -834,7 +886,7 @@ func (v Value) Index(i int) Value {
 			}
 			return Value{
 				typecode: v.typecode.elem(),
-				flags:    v.flags,
+				flags:    flags,
 				value:    value,
 			}
 		}
@@ -845,7 +897,7 @@ func (v Value) Index(i int) Value {
 		value := maskAndShift(uintptr(v.value), offset, elemSize)
 		return Value{
 			typecode: v.typecode.elem(),
-			flags:    v.flags,
+			flags:    flags,
 			value:    unsafe.Pointer(value),
 		}
 	default:
@@ -867,6 +919,18 @@ func loadValue(ptr unsafe.Pointer, size uintptr) uintptr {
 	return loadedValue
 }
 
+// loadIndirect returns a copy of v, whose value must be a pointer to the value
+// like for addressable values, that is not addressable and has the given flags.
+// It is used for values that are copied out of a map or channel, which can't be
+// modified in place.
+func (v Value) loadIndirect(flags valueFlags) Value {
+	if size := v.typecode.Size(); size <= unsafe.Sizeof(uintptr(0)) {
+		v.value = unsafe.Pointer(loadValue(v.value, size))
+	}
+	v.flags = flags
+	return v
+}
+
 // maskAndShift cuts out a part of a uintptr. Note that the offset may not be 0.
 func maskAndShift(value, offset, size uintptr) uintptr {
 	mask := ^uintptr(0) >> ((unsafe.Sizeof(uintptr(0)) - size) * 8)
@@ -897,6 +961,9 @@ func overflowFloat32(x float64) bool {
 	return math.MaxFloat32 < x && x <= math.MaxFloat64
 }
 
+// MapKeys returns a slice containing all the keys present in the map, in
+// unspecified order. It panics if v's Kind is not Map. It returns an empty
+// slice if v represents a nil map.
 func (v Value) MapKeys() []Value {
 	if v.Kind() != Map {
 		panic(&ValueError{Method: "MapKeys", Kind: v.Kind()})
@@ -913,17 +980,10 @@ func (v Value) MapKeys() []Value {
 	k := New(v.typecode.Key())
 	e := New(v.typecode.Elem())
 
-	keyType := v.typecode.Key().(*rawType)
-	isKeyStoredAsInterface := keyType.Kind() != String && !keyType.isBinary()
+	flags := v.flags&valueFlagExported | v.flags.ro()
 
 	for hashmapNext(v.pointer(), it, k.value, e.value) {
-		if isKeyStoredAsInterface {
-			intf := *(*interface{})(k.value)
-			v := ValueOf(intf)
-			keys = append(keys, v)
-		} else {
-			keys = append(keys, k.Elem())
-		}
+		keys = append(keys, k.Elem().loadIndirect(flags))
 		k = New(v.typecode.Key())
 	}
 
@@ -939,6 +999,13 @@ func hashmapBinaryGet(m unsafe.Pointer, key, value unsafe.Pointer, valueSize uin
 //go:linkname hashmapInterfaceGet runtime.hashmapInterfaceGetUnsafePointer
 func hashmapInterfaceGet(m unsafe.Pointer, key interface{}, value unsafe.Pointer, valueSize uintptr) bool
 
+//go:linkname hashmapGenericGet runtime.hashmapGenericGetUnsafePointer
+func hashmapGenericGet(m unsafe.Pointer, key, value unsafe.Pointer, valueSize uintptr) bool
+
+// MapIndex returns the value associated with key in the map v. It panics if
+// v's Kind is not Map or if key is not assignable to the map's key type. It
+// returns the zero Value if key is not found in the map or if v represents a
+// nil map.
 func (v Value) MapIndex(key Value) Value {
 	if v.Kind() != Map {
 		panic(&ValueError{Method: "MapIndex", Kind: v.Kind()})
@@ -954,29 +1021,61 @@ func (v Value) MapIndex(key Value) Value {
 
 	elemType := v.typecode.Elem()
 	elem := New(elemType)
+	flags := v.flags&valueFlagExported | (v.flags | key.flags).ro()
 
+	var ok bool
 	if vkey.Kind() == String {
-		if ok := hashmapStringGet(v.pointer(), *(*string)(key.value), elem.value, elemType.Size()); !ok {
-			return Value{}
-		}
-		return elem.Elem()
+		ok = hashmapStringGet(v.pointer(), *(*string)(key.value), elem.value, elemType.Size())
 	} else if vkey.isBinary() {
-		var keyptr unsafe.Pointer
-		if key.isIndirect() || key.typecode.Size() > unsafe.Sizeof(uintptr(0)) {
-			keyptr = key.value
-		} else {
-			keyptr = unsafe.Pointer(&key.value)
+		ok = hashmapBinaryGet(v.pointer(), mapBinaryKey(vkey, key), elem.value, elemType.Size())
+	} else if vkey.Kind() == Interface {
+		ok = hashmapInterfaceGet(v.pointer(), valueInterfaceUnsafe(key), elem.value, elemType.Size())
+	} else {
+		ok = hashmapGenericGet(v.pointer(), mapBinaryKey(vkey, key), elem.value, elemType.Size())
+	}
+	if !ok {
+		return Value{}
+	}
+	return elem.Elem().loadIndirect(flags)
+}
+
+// mapBinaryKey returns a pointer to a copy of the key, for maps with binary
+// keys and for maps that hash keys with a function for the key type. The
+// padding bytes in the copy are zero like the keys stored by the compiler, so
+// that the same key always hashes to the same value.
+func mapBinaryKey(vkey *rawType, key Value) unsafe.Pointer {
+	var keyptr unsafe.Pointer
+	if key.isIndirect() || key.typecode.Size() > unsafe.Sizeof(uintptr(0)) {
+		keyptr = key.value
+	} else {
+		keyptr = unsafe.Pointer(&key.value)
+	}
+	buf := alloc(vkey.Size(), nil)
+	copyWithoutPadding(buf, keyptr, vkey)
+	return buf
+}
+
+// copyWithoutPadding copies a value of type t from src to dst, skipping the
+// padding bytes between struct fields.
+func copyWithoutPadding(dst, src unsafe.Pointer, t *rawType) {
+	switch t.Kind() {
+	case Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.rawField(i)
+			copyWithoutPadding(unsafe.Add(dst, field.Offset), unsafe.Add(src, field.Offset), field.Type)
 		}
-		//TODO(dgryski): zero out padding bytes in key, if any
-		if ok := hashmapBinaryGet(v.pointer(), keyptr, elem.value, elemType.Size()); !ok {
-			return Value{}
+	case Array:
+		elem := t.elem()
+		if elem.Kind() != Struct && elem.Kind() != Array {
+			memcpy(dst, src, t.Size())
+			return
 		}
-		return elem.Elem()
-	} else {
-		if ok := hashmapInterfaceGet(v.pointer(), key.Interface(), elem.value, elemType.Size()); !ok {
-			return Value{}
+		for i := 0; i < t.Len(); i++ {
+			offset := uintptr(i) * elem.Size()
+			copyWithoutPadding(unsafe.Add(dst, offset), unsafe.Add(src, offset), elem)
 		}
-		return elem.Elem()
+	default:
+		memcpy(dst, src, t.Size())
 	}
 }
 
@@ -986,70 +1085,115 @@ func hashmapNewIterator() unsafe.Pointer
 //go:linkname hashmapNext runtime.hashmapNextUnsafePointer
 func hashmapNext(m unsafe.Pointer, it unsafe.Pointer, key, value unsafe.Pointer) bool
 
+// hiter mirrors runtime.hashmapIterator, so that a MapIter can store the
+// iterator inline and be reset without allocating.
+type hiter struct {
+	buckets      unsafe.Pointer
+	numBuckets   uintptr
+	bucketNumber uintptr
+	bucket       unsafe.Pointer
+	bucketIndex  uint8
+}
+
+// MapRange returns a range iterator for a map. It panics if v's Kind is not
+// Map.
+//
+// Like with a range statement, the map may be modified during the iteration:
+// entries that are deleted before they are reached are not returned, and
+// entries that are added may or may not be returned. This also holds when the
+// map grows during the iteration.
 func (v Value) MapRange() *MapIter {
 	if v.Kind() != Map {
 		panic(&ValueError{Method: "MapRange", Kind: v.Kind()})
 	}
 
-	keyType := v.typecode.key()
-	isKeyStoredAsInterface := keyType.Kind() != String && !keyType.isBinary()
-
 	return &MapIter{
-		m:            v,
-		it:           hashmapNewIterator(),
-		keyInterface: isKeyStoredAsInterface,
+		m: v,
 	}
 }
 
+// A MapIter is an iterator for ranging over a map. See Value.MapRange.
 type MapIter struct {
 	m   Value
-	it  unsafe.Pointer
+	it  hiter
 	key Value
 	val Value
 
-	valid        bool
-	keyInterface bool
+	valid bool
+	done  bool
 }
 
+// Key returns the key of it's current map entry.
 func (it *MapIter) Key() Value {
 	if !it.valid {
 		panic("reflect.MapIter.Key called on invalid iterator")
 	}
 
-	if it.keyInterface {
-		intf := *(*interface{})(it.key.value)
-		v := ValueOf(intf)
-		return v
-	}
-
-	return it.key.Elem()
+	return it.key.Elem().loadIndirect(it.m.flags&valueFlagExported | it.m.flags.ro())
 }
 
+// Value returns the value of it's current map entry.
 func (it *MapIter) Value() Value {
 	if !it.valid {
 		panic("reflect.MapIter.Value called on invalid iterator")
 	}
 
-	return it.val.Elem()
+	return it.val.Elem().loadIndirect(it.m.flags&valueFlagExported | it.m.flags.ro())
 }
 
+// Next advances the map iterator and reports whether there is another entry.
+// It returns false when it is exhausted; subsequent calls to Next panic.
 func (it *MapIter) Next() bool {
+	if !it.m.IsValid() {
+		panic("reflect.MapIter.Next called on an iterator that does not have an associated map Value")
+	}
+	if it.done {
+		panic("reflect.MapIter.Next called on exhausted iterator")
+	}
+
 	it.key = New(it.m.typecode.Key())
 	it.val = New(it.m.typecode.Elem())
 
-	it.valid = hashmapNext(it.m.pointer(), it.it, it.key.value, it.val.value)
+	it.valid = hashmapNext(it.m.pointer(), unsafe.Pointer(&it.it), it.key.value, it.val.value)
+	it.done = !it.valid
 	return it.valid
 }
 
+// Reset modifies it to iterate over v. It panics if v's Kind is not Map and v
+// is not the zero Value. Reset(Value{}) causes it to not refer to any map,
+// which may allow the previously iterated-over map to be garbage collected.
+func (it *MapIter) Reset(v Value) {
+	if v.IsValid() && v.Kind() != Map {
+		panic(&ValueError{Method: "MapIter.Reset", Kind: v.Kind()})
+	}
+	*it = MapIter{m: v}
+}
+
+// SetIterKey assigns to v the key of iter's current map entry. It is
+// equivalent to v.Set(iter.Key()).
+func (v Value) SetIterKey(iter *MapIter) {
+	v.Set(iter.Key())
+}
+
+// SetIterValue assigns to v the value of iter's current map entry. It is
+// equivalent to v.Set(iter.Value()).
+func (v Value) SetIterValue(iter *MapIter) {
+	v.Set(iter.Value())
+}
+
 func (v Value) Set(x Value) {
 	v.checkAddressable()
 	v.checkRO()
+	x.checkNotMethod("(reflect.Value).Set()")
+	if x.isRO() {
+		panic("reflect: reflect.Value.Set using value obtained using unexported field")
+	}
 	if !x.typecode.AssignableTo(v.typecode) {
 		panic("reflect: cannot set")
 	}
 
 	if v.typecode.Kind() == Interface && x.typecode.Kind() != Interface {
-		intf := composeInterface(unsafe.Pointer(x.typecode), x.value)
+		intf := valueInterfaceUnsafe(x)
 		x = Value{
 			typecode: v.typecode,
 			value:    unsafe.Pointer(&intf),
@@ -1065,6 +1209,14 @@ func (v Value) Set(x Value) {
 	memcpy(v.value, xptr, size)
 }
 
+// SetZero sets v to be the zero value of v's type.
+// It panics if CanSet returns false.
+func (v Value) SetZero() {
+	v.checkAddressable()
+	v.checkRO()
+	memzero(v.value, v.typecode.Size())
+}
+
 func (v Value) SetBool(x bool) {
 	v.checkAddressable()
 	v.checkRO()
@@ -1076,6 +1228,19 @@ func (v Value) SetBool(x bool) {
 	}
 }
 
+// SetPointer sets the unsafe.Pointer value v to x. It panics if v's Kind is
+// not UnsafePointer.
+func (v Value) SetPointer(x unsafe.Pointer) {
+	v.checkAddressable()
+	v.checkRO()
+	switch v.Kind() {
+	case UnsafePointer:
+		*(*unsafe.Pointer)(v.value) = x
+	default:
+		panic(&ValueError{Method: "SetPointer", Kind: v.Kind()})
+	}
+}
+
 func (v Value) SetInt(x int64) {
 	v.checkAddressable()
 	v.checkRO()
@@ -1173,6 +1338,7 @@ func (v Value) SetLen(n int) {
 		panic(&ValueError{Method: "reflect.Value.SetLen", Kind: v.Kind()})
 	}
 	v.checkAddressable()
+	v.checkRO()
 	hdr := (*sliceHeader)(v.value)
 	if int(uintptr(n)) != n || uintptr(n) > hdr.cap {
 		panic("reflect.Value.SetLen: slice length out of range")
@@ -1211,122 +1377,196 @@ func (v Value) OverflowUint(x uint64) bool {
 	panic(&ValueError{Method: "reflect.Value.OverflowUint", Kind: v.Kind()})
 }
 
+// CanConvert reports whether the value v can be converted to type t. If
+// v.CanConvert(t) returns true then v.Convert(t) will not panic.
 func (v Value) CanConvert(t Type) bool {
-	panic("unimplemented: (reflect.Value).CanConvert()")
+	vt := v.Type()
+	if !vt.ConvertibleTo(t) {
+		return false
+	}
+	// Converting from slice to array or to pointer-to-array can panic
+	// depending on the value.
+	switch {
+	case vt.Kind() == Slice && t.Kind() == Array:
+		if t.Len() > v.Len() {
+			return false
+		}
+	case vt.Kind() == Slice && t.Kind() == Ptr && t.Elem().Kind() == Array:
+		n := t.Elem().Len()
+		if n > v.Len() {
+			return false
+		}
+	}
+	return true
 }
 
+// Convert returns the value v converted to type t. If the usual Go conversion
+// rules do not allow conversion of the value v to type t, or if converting v to
+// type t panics, Convert panics.
 func (v Value) Convert(t Type) Value {
-	if v, ok := convertOp(v, t); ok {
-		return v
+	if v.typecode == nil {
+		panic(&ValueError{Method: "Convert", Kind: Invalid})
 	}
-
-	panic("reflect.Value.Convert: value of type " + v.typecode.String() + " cannot be converted to type " + t.String())
-}
-
-func convertOp(src Value, typ Type) (Value, bool) {
-
-	// Easy check first.  Do we even need to do anything?
-	if src.typecode.underlying() == typ.(*rawType).underlying() {
-		return Value{
-			typecode: typ.(*rawType),
-			value:    src.value,
-			flags:    src.flags,
-		}, true
+	v.checkNotMethod("(reflect.Value).Convert()")
+	op := convertOp(t.(*rawType), v.typecode)
+	if op == nil {
+		panic("reflect.Value.Convert: value of type " + v.typecode.String() + " cannot be converted to type " + t.String())
 	}
+	return op(v, t.(*rawType))
+}
 
+// convertOp returns the function to convert a value of type src to a value of
+// type dst. If the conversion is illegal, convertOp returns nil.
+func convertOp(dst, src *rawType) func(Value, *rawType) Value {
 	switch src.Kind() {
 	case Int, Int8, Int16, Int32, Int64:
-		switch rtype := typ.(*rawType); rtype.Kind() {
+		switch dst.Kind() {
 		case Int, Int8, Int16, Int32, Int64, Uint, Uint8, Uint16, Uint32, Uint64, Uintptr:
-			return cvtInt(src, rtype), true
+			return cvtInt
 		case Float32, Float64:
-			return cvtIntFloat(src, rtype), true
+			return cvtIntFloat
 		case String:
-			return cvtIntString(src, rtype), true
+			return cvtIntString
 		}
 
 	case Uint, Uint8, Uint16, Uint32, Uint64, Uintptr:
-		switch rtype := typ.(*rawType); rtype.Kind() {
+		switch dst.Kind() {
 		case Int, Int8, Int16, Int32, Int64, Uint, Uint8, Uint16, Uint32, Uint64, Uintptr:
-			return cvtUint(src, rtype), true
+			return cvtUint
 		case Float32, Float64:
-			return cvtUintFloat(src, rtype), true
+			return cvtUintFloat
 		case String:
-			return cvtUintString(src, rtype), true
+			return cvtUintString
 		}
 
 	case Float32, Float64:
-		switch rtype := typ.(*rawType); rtype.Kind() {
+		switch dst.Kind() {
 		case Int, Int8, Int16, Int32, Int64:
-			return cvtFloatInt(src, rtype), true
+			return cvtFloatInt
 		case Uint, Uint8, Uint16, Uint32, Uint64, Uintptr:
-			return cvtFloatUint(src, rtype), true
+			return cvtFloatUint
 		case Float32, Float64:
-			return cvtFloat(src, rtype), true
+			return cvtFloat
 		}
 
-		/*
-			case Complex64, Complex128:
-				switch src.Kind() {
-				case Complex64, Complex128:
-					return cvtComplex
-				}
-		*/
-
-	case Slice:
-		if typ.Kind() == String && !src.typecode.elem().isNamed() {
-			rtype := typ.(*rawType)
+	case Complex64, Complex128:
+		switch dst.Kind() {
+		case Complex64, Complex128:
+			return cvtComplex
+		}
 
-			switch src.Type().Elem().Kind() {
+	case String:
+		if dst.Kind() == Slice && !dst.elem().isNamed() {
+			switch dst.elem().Kind() {
 			case Uint8:
-				return cvtBytesString(src, rtype), true
+				return cvtStringBytes
 			case Int32:
-				return cvtRunesString(src, rtype), true
+				return cvtStringRunes
 			}
 		}
 
-	case String:
-		rtype := typ.(*rawType)
-		if typ.Kind() == Slice && !rtype.elem().isNamed() {
-			switch typ.Elem().Kind() {
+	case Slice:
+		if dst.Kind() == String && !src.elem().isNamed() {
+			switch src.elem().Kind() {
 			case Uint8:
-				return cvtStringBytes(src, rtype), true
+				return cvtBytesString
 			case Int32:
-				return cvtStringRunes(src, rtype), true
+				return cvtRunesString
 			}
 		}
+		// "x is a slice, T is a pointer-to-array type,
+		// and the slice and array types have identical element types."
+		if dst.Kind() == Ptr && dst.elem().Kind() == Array && src.elem() == dst.elem().elem() {
+			return cvtSliceArrayPtr
+		}
+		// "x is a slice, T is an array type,
+		// and the slice and array types have identical element types."
+		if dst.Kind() == Array && src.elem() == dst.elem() {
+			return cvtSliceArray
+		}
+
+	case Chan:
+		// "x is a bidirectional channel value, T is a channel type, x's type
+		// V and T have identical element types, and at least one of V or T
+		// is not a defined type."
+		if dst.Kind() == Chan && src.underlying().ChanDir() == BothDir && src.elem() == dst.elem() && (!src.isNamed() || !dst.isNamed()) {
+			return cvtDirect
+		}
+	}
+
+	// dst and src have the same underlying type. Type codes are unique, so
+	// comparing them is enough. This ignores the special case of struct types
+	// that only differ in their field tags.
+	if dst.underlying() == src.underlying() {
+		return cvtDirect
+	}
+
+	// dst and src are non-defined pointer types with the same underlying base
+	// type.
+	if dst.Kind() == Ptr && !dst.isNamed() && src.Kind() == Ptr && !src.isNamed() && dst.elem().underlying() == src.elem().underlying() {
+		return cvtDirect
+	}
+
+	if dst.Kind() == Interface && src.Implements(dst) {
+		if src.Kind() == Interface {
+			return cvtI2I
+		}
+		return cvtT2I
 	}
 
-	// TODO(dgryski): Unimplemented:
-	// Chan
-	// Non-defined pointers types with same underlying base type
-	// Interface <-> Type conversions
+	return nil
+}
 
-	return Value{}, false
+// convertFlags returns the flags for the result of a conversion of a value with
+// the given flags. The result is never addressable.
+func convertFlags(flags valueFlags) valueFlags {
+	return flags&valueFlagExported | flags.ro()
+}
+
+// cvtDirect converts a value to a type with the same memory layout.
+func cvtDirect(v Value, t *rawType) Value {
+	if v.isIndirect() {
+		// Copy the value, so that the result isn't addressable.
+		size := t.Size()
+		if size <= unsafe.Sizeof(uintptr(0)) {
+			var value unsafe.Pointer
+			memcpy(unsafe.Pointer(&value), v.value, size)
+			v.value = value
+		} else {
+			ptr := alloc(size, nil)
+			memcpy(ptr, v.value, size)
+			v.value = ptr
+		}
+	}
+	return Value{
+		typecode: t,
+		value:    v.value,
+		flags:    convertFlags(v.flags),
+	}
 }
 
 func cvtInt(v Value, t *rawType) Value {
-	return makeInt(v.flags, uint64(v.Int()), t)
+	return makeInt(convertFlags(v.flags), uint64(v.Int()), t)
 }
 
 func cvtUint(v Value, t *rawType) Value {
-	return makeInt(v.flags, v.Uint(), t)
+	return makeInt(convertFlags(v.flags), v.Uint(), t)
 }
 
 func cvtIntFloat(v Value, t *rawType) Value {
-	return makeFloat(v.flags, float64(v.Int()), t)
+	return makeFloat(convertFlags(v.flags), float64(v.Int()), t)
 }
 
 func cvtUintFloat(v Value, t *rawType) Value {
-	return makeFloat(v.flags, float64(v.Uint()), t)
+	return makeFloat(convertFlags(v.flags), float64(v.Uint()), t)
 }
 
 func cvtFloatInt(v Value, t *rawType) Value {
-	return makeInt(v.flags, uint64(int64(v.Float())), t)
+	return makeInt(convertFlags(v.flags), uint64(int64(v.Float())), t)
 }
 
 func cvtFloatUint(v Value, t *rawType) Value {
-	return makeInt(v.flags, uint64(v.Float()), t)
+	return makeInt(convertFlags(v.flags), uint64(v.Float()), t)
 }
 
 func cvtFloat(v Value, t *rawType) Value {
@@ -1334,9 +1574,13 @@ func cvtFloat(v Value, t *rawType) Value {
 		// Don't do any conversion if both types have underlying type float32.
 		// This avoids converting to float64 and back, which will
 		// convert a signaling NaN to a quiet NaN. See issue 36400.
-		return makeFloat32(v.flags, v.Float32(), t)
+		return makeFloat32(convertFlags(v.flags), v.Float32(), t)
 	}
-	return makeFloat(v.flags, v.Float(), t)
+	return makeFloat(convertFlags(v.flags), v.Float(), t)
+}
+
+func cvtComplex(v Value, t *rawType) Value {
+	return makeComplex(convertFlags(v.flags), v.Complex(), t)
 }
 
 //go:linkname stringToBytes runtime.stringToBytes
@@ -1347,7 +1591,7 @@ func cvtStringBytes(v Value, t *rawType) Value {
 	return Value{
 		typecode: t,
 		value:    unsafe.Pointer(&b),
-		flags:    v.flags,
+		flags:    convertFlags(v.flags),
 	}
 }
 
@@ -1359,7 +1603,7 @@ func cvtBytesString(v Value, t *rawType) Value {
 	return Value{
 		typecode: t,
 		value:    unsafe.Pointer(&s),
-		flags:    v.flags,
+		flags:    convertFlags(v.flags),
 	}
 }
 
@@ -1422,20 +1666,110 @@ func makeFloat32(flags valueFlags, f float32, t *rawType) Value {
 	return v
 }
 
-func cvtIntString(src Value, t *rawType) Value {
-	panic("cvtUintString: unimplemented")
+func makeComplex(flags valueFlags, c complex128, t *rawType) Value {
+	size := t.Size()
+
+	v := Value{
+		typecode: t,
+		flags:    flags,
+	}
+
+	ptr := unsafe.Pointer(&v.value)
+	if size > unsafe.Sizeof(uintptr(0)) {
+		ptr = alloc(size, nil)
+		v.value = ptr
+	}
+
+	switch size {
+	case 8:
+		*(*complex64)(ptr) = complex64(c)
+	case 16:
+		*(*complex128)(ptr) = c
+	}
+	return v
+}
+
+func makeString(flags valueFlags, s string, t *rawType) Value {
+	return Value{
+		typecode: t,
+		value:    unsafe.Pointer(&s),
+		flags:    flags,
+	}
+}
+
+func cvtIntString(v Value, t *rawType) Value {
+	s := "\uFFFD"
+	if x := v.Int(); int64(rune(x)) == x {
+		s = string(rune(x))
+	}
+	return makeString(convertFlags(v.flags), s, t)
 }
 
-func cvtUintString(src Value, t *rawType) Value {
-	panic("cvtUintString: unimplemented")
+func cvtUintString(v Value, t *rawType) Value {
+	s := "\uFFFD"
+	if x := v.Uint(); uint64(rune(x)) == x {
+		s = string(rune(x))
+	}
+	return makeString(convertFlags(v.flags), s, t)
 }
 
-func cvtStringRunes(src Value, t *rawType) Value {
-	panic("cvsStringRunes: unimplemented")
+func cvtStringRunes(v Value, t *rawType) Value {
+	r := []rune(*(*string)(v.value))
+	return Value{
+		typecode: t,
+		value:    unsafe.Pointer(&r),
+		flags:    convertFlags(v.flags),
+	}
 }
 
-func cvtRunesString(src Value, t *rawType) Value {
-	panic("cvsRunesString: unimplemented")
+func cvtRunesString(v Value, t *rawType) Value {
+	return makeString(convertFlags(v.flags), string(*(*[]rune)(v.value)), t)
+}
+
+func cvtSliceArrayPtr(v Value, t *rawType) Value {
+	n := t.elem().Len()
+	if n > v.Len() {
+		panic("reflect: cannot convert slice with length " + itoa.Itoa(v.Len()) + " to pointer to array with length " + itoa.Itoa(n))
+	}
+	return Value{
+		typecode: t,
+		value:    (*sliceHeader)(v.value).data,
+		flags:    convertFlags(v.flags),
+	}
+}
+
+func cvtSliceArray(v Value, t *rawType) Value {
+	n := t.Len()
+	if n > v.Len() {
+		panic("reflect: cannot convert slice with length " + itoa.Itoa(v.Len()) + " to array with length " + itoa.Itoa(n))
+	}
+	// Copy the elements, like cvtDirect does.
+	return cvtDirect(Value{
+		typecode: t,
+		value:    (*sliceHeader)(v.value).data,
+		flags:    v.flags | valueFlagIndirect,
+	}, t)
+}
+
+// cvtT2I converts a value to an interface type that it implements.
+func cvtT2I(v Value, t *rawType) Value {
+	intf := valueInterfaceUnsafe(v)
+	return Value{
+		typecode: t,
+		value:    unsafe.Pointer(&intf),
+		flags:    convertFlags(v.flags),
+	}
+}
+
+// cvtI2I converts an interface value to another interface type that its type
+// implements.
+func cvtI2I(v Value, t *rawType) Value {
+	intf := *(*interface{})(v.value)
+	return Value{
+		typecode: t,
+		value:    unsafe.Pointer(&intf),
+		flags:    convertFlags(v.flags),
+	}
 }
 
 //go:linkname slicePanic runtime.slicePanic
@@ -1485,13 +1819,18 @@ func init() {
 	zerobuffer = s.data
 }
 
+// Zero returns a Value representing the zero value for the specified type.
+// The returned value is neither addressable nor settable.
 func Zero(typ Type) Value {
+	if typ == nil {
+		panic("reflect: Zero(nil)")
+	}
 	size := typ.Size()
 	if size <= unsafe.Sizeof(uintptr(0)) {
 		return Value{
 			typecode: typ.(*rawType),
 			value:    nil,
-			flags:    valueFlagExported | valueFlagRO,
+			flags:    valueFlagExported,
 		}
 	}
 
@@ -1499,20 +1838,23 @@ func Zero(typ Type) Value {
 		return Value{
 			typecode: typ.(*rawType),
 			value:    unsafe.Pointer(zerobuffer),
-			flags:    valueFlagExported | valueFlagRO,
+			flags:    valueFlagExported,
 		}
 	}
 
 	return Value{
 		typecode: typ.(*rawType),
 		value:    alloc(size, nil),
-		flags:    valueFlagExported | valueFlagRO,
+		flags:    valueFlagExported,
 	}
 }
 
 // New is the reflect equivalent of the new(T) keyword, returning a pointer to a
 // new value of the given type.
 func New(typ Type) Value {
+	if typ == nil {
+		panic("reflect: New(nil)")
+	}
 	return Value{
 		typecode: pointerTo(typ.(*rawType)),
 		value:    alloc(typ.Size(), nil),
@@ -1566,6 +1908,9 @@ func (e *ValueError) Error() string {
 //go:linkname memcpy runtime.memcpy
 func memcpy(dst, src unsafe.Pointer, size uintptr)
 
+//go:linkname memzero runtime.memzero
+func memzero(ptr unsafe.Pointer, size uintptr)
+
 //go:linkname alloc runtime.alloc
 func alloc(size uintptr, layout unsafe.Pointer) unsafe.Pointer
 
@@ -1653,7 +1998,7 @@ func (v *Value) extendSlice(n int) {
 
 	if old.len+uintptr(n) > old.cap {
 		// we need to grow the slice
-		nbuf, nlen, ncap = sliceGrow(old.data, old.len, old.cap, old.cap+uintptr(n), v.typecode.elem().Size())
+		nbuf, nlen, ncap = sliceGrow(old.data, old.len, old.cap, old.len+uintptr(n), v.typecode.elem().Size())
 	} else {
 		// we can reuse the slice we have
 		nbuf = old.data
@@ -1677,6 +2022,7 @@ func Append(v Value, x ...Value) Value {
 	if v.Kind() != Slice {
 		panic(&ValueError{Method: "Append", Kind: v.Kind()})
 	}
+	v.checkExported("reflect.Append")
 	oldLen := v.Len()
 	v.extendSlice(len(x))
 	for i, xx := range x {
@@ -1688,15 +2034,14 @@ func Append(v Value, x ...Value) Value {
 // AppendSlice appends a slice t to a slice s and returns the resulting slice.
 // The slices s and t must have the same element type.
 func AppendSlice(s, t Value) Value {
-	if s.typecode.Kind() != Slice || t.typecode.Kind() != Slice || s.typecode != t.typecode {
+	if s.typecode.Kind() != Slice || t.typecode.Kind() != Slice || s.typecode.elem() != t.typecode.elem() {
 		// Not a very helpful error message, but shortened to just one error to
 		// keep code size down.
 		panic("reflect.AppendSlice: invalid types")
 	}
-	if !s.isExported() || !t.isExported() {
-		// One of the sides was not exported, so can't access the data.
-		panic("reflect.AppendSlice: unexported")
-	}
+	// One of the sides may not be exported, so can't access the data.
+	s.checkExported("reflect.AppendSlice")
+	t.checkExported("reflect.AppendSlice")
 	sSlice := (*sliceHeader)(s.value)
 	tSlice := (*sliceHeader)(t.value)
 	elemSize := s.typecode.elem().Size()
@@ -1731,11 +2076,23 @@ func hashmapBinaryDelete(m unsafe.Pointer, key unsafe.Pointer)
 //go:linkname hashmapInterfaceDelete runtime.hashmapInterfaceDeleteUnsafePointer
 func hashmapInterfaceDelete(m unsafe.Pointer, key interface{})
 
+//go:linkname hashmapGenericSet runtime.hashmapGenericSetUnsafePointer
+func hashmapGenericSet(m unsafe.Pointer, key, value unsafe.Pointer)
+
+//go:linkname hashmapGenericDelete runtime.hashmapGenericDeleteUnsafePointer
+func hashmapGenericDelete(m unsafe.Pointer, key unsafe.Pointer)
+
+// SetMapIndex sets the element associated with key in the map v to elem. It
+// panics if v's Kind is not Map, or if v is a nil map and elem is not the
+// zero Value. If elem is the zero Value, SetMapIndex deletes the key from the
+// map. As in Go, key's value must be assignable to the map's key type, and
+// elem's value must be assignable to the map's elem type.
 func (v Value) SetMapIndex(key, elem Value) {
 	v.checkRO()
 	if v.Kind() != Map {
 		panic(&ValueError{Method: "SetMapIndex", Kind: v.Kind()})
 	}
+	key.checkExported("reflect.Value.SetMapIndex")
 
 	vkey := v.typecode.key()
 
@@ -1747,64 +2104,48 @@ func (v Value) SetMapIndex(key, elem Value) {
 	// if elem is the zero Value, it means delete
 	del := elem == Value{}
 
-	if !del && !elem.typecode.AssignableTo(v.typecode.elem()) {
+	if del {
+		if vkey.Kind() == String {
+			hashmapStringDelete(v.pointer(), *(*string)(key.value))
+		} else if vkey.isBinary() {
+			hashmapBinaryDelete(v.pointer(), mapBinaryKey(vkey, key))
+		} else if vkey.Kind() == Interface {
+			hashmapInterfaceDelete(v.pointer(), valueInterfaceUnsafe(key))
+		} else {
+			hashmapGenericDelete(v.pointer(), mapBinaryKey(vkey, key))
+		}
+		return
+	}
+
+	elem.checkExported("reflect.Value.SetMapIndex")
+	if !elem.typecode.AssignableTo(v.typecode.elem()) {
 		panic("reflect.Value.SetMapIndex: incompatible types for value")
 	}
 
 	// make elem an interface if it needs to be converted
 	if v.typecode.elem().Kind() == Interface && elem.typecode.Kind() != Interface {
-		intf := composeInterface(unsafe.Pointer(elem.typecode), elem.value)
+		intf := valueInterfaceUnsafe(elem)
 		elem = Value{
 			typecode: v.typecode.elem(),
 			value:    unsafe.Pointer(&intf),
 		}
 	}
 
-	if key.Kind() == String {
-		if del {
-			hashmapStringDelete(v.pointer(), *(*string)(key.value))
-		} else {
-			var elemptr unsafe.Pointer
-			if elem.isIndirect() || elem.typecode.Size() > unsafe.Sizeof(uintptr(0)) {
-				elemptr = elem.value
-			} else {
-				elemptr = unsafe.Pointer(&elem.value)
-			}
-			hashmapStringSet(v.pointer(), *(*string)(key.value), elemptr)
-		}
-
-	} else if key.typecode.isBinary() {
-		var keyptr unsafe.Pointer
-		if key.isIndirect() || key.typecode.Size() > unsafe.Sizeof(uintptr(0)) {
-			keyptr = key.value
-		} else {
-			keyptr = unsafe.Pointer(&key.value)
-		}
-
-		if del {
-			hashmapBinaryDelete(v.pointer(), keyptr)
-		} else {
-			var elemptr unsafe.Pointer
-			if elem.isIndirect() || elem.typecode.Size() > unsafe.Sizeof(uintptr(0)) {
-				elemptr = elem.value
-			} else {
-				elemptr = unsafe.Pointer(&elem.value)
-			}
-			hashmapBinarySet(v.pointer(), keyptr, elemptr)
-		}
+	var elemptr unsafe.Pointer
+	if elem.isIndirect() || elem.typecode.Size() > unsafe.Sizeof(uintptr(0)) {
+		elemptr = elem.value
 	} else {
-		if del {
-			hashmapInterfaceDelete(v.pointer(), key.Interface())
-		} else {
-			var elemptr unsafe.Pointer
-			if elem.isIndirect() || elem.typecode.Size() > unsafe.Sizeof(uintptr(0)) {
-				elemptr = elem.value
-			} else {
-				elemptr = unsafe.Pointer(&elem.value)
-			}
+		elemptr = unsafe.Pointer(&elem.value)
+	}
 
-			hashmapInterfaceSet(v.pointer(), key.Interface(), elemptr)
-		}
+	if vkey.Kind() == String {
+		hashmapStringSet(v.pointer(), *(*string)(key.value), elemptr)
+	} else if vkey.isBinary() {
+		hashmapBinarySet(v.pointer(), mapBinaryKey(vkey, key), elemptr)
+	} else if vkey.Kind() == Interface {
+		hashmapInterfaceSet(v.pointer(), valueInterfaceUnsafe(key), elemptr)
+	} else {
+		hashmapGenericSet(v.pointer(), mapBinaryKey(vkey, key), elemptr)
 	}
 }
 
@@ -1830,9 +2171,38 @@ func (v Value) FieldByIndex(index []int) Value {
 	return v
 }
 
-// FieldByIndexErr returns the nested field corresponding to index.
+// FieldByIndexErr returns the nested field corresponding to index. It returns
+// an error if evaluation requires stepping through a nil pointer, but panics
+// if it must step through a field that is not a struct.
 func (v Value) FieldByIndexErr(index []int) (Value, error) {
-	return Value{}, &ValueError{Method: "FieldByIndexErr"}
+	if len(index) == 1 {
+		return v.Field(index[0]), nil
+	}
+	if v.Kind() != Struct {
+		panic(&ValueError{"FieldByIndexErr", v.Kind()})
+	}
+	for i, x := range index {
+		if i > 0 {
+			if v.Kind() == Pointer && v.typecode.elem().Kind() == Struct {
+				if v.IsNil() {
+					return Value{}, &nilEmbeddedError{v.typecode.elem().Name()}
+				}
+				v = v.Elem()
+			}
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
+
+// nilEmbeddedError is returned by FieldByIndexErr. The errors package can't be
+// used here, as it depends on the reflect package.
+type nilEmbeddedError struct {
+	name string
+}
+
+func (e *nilEmbeddedError) Error() string {
+	return "reflect: indirection through nil pointer to embedded struct field " + e.name
 }
 
 func (v Value) FieldByName(name string) Value {
@@ -1846,9 +2216,29 @@ func (v Value) FieldByName(name string) Value {
 	return Value{}
 }
 
+// FieldByNameFunc returns the struct field with a name that satisfies the
+// match function. It panics if v's Kind is not struct. It returns the zero
+// Value if no field was found.
+func (v Value) FieldByNameFunc(match func(string) bool) Value {
+	if v.Kind() != Struct {
+		panic(&ValueError{"FieldByNameFunc", v.Kind()})
+	}
+
+	if field, ok := v.typecode.FieldByNameFunc(match); ok {
+		return v.FieldByIndex(field.Index)
+	}
+	return Value{}
+}
+
 //go:linkname hashmapMake runtime.hashmapMakeUnsafePointer
 func hashmapMake(keySize, valueSize uintptr, sizeHint uintptr, alg uint8) unsafe.Pointer
 
+//go:linkname hashmapMakeFunc runtime.hashmapMakeFuncUnsafePointer
+func hashmapMakeFunc(keySize, valueSize uintptr, sizeHint uintptr, keyHash func(key unsafe.Pointer, size, seed uintptr) uint32, keyEqual func(x, y unsafe.Pointer, n uintptr) bool) unsafe.Pointer
+
+//go:linkname hashmapInterfaceHash runtime.hashmapInterfaceHash
+func hashmapInterfaceHash(itf interface{}, seed uintptr) uint32
+
 // MakeMapWithSize creates a new map with the specified type and initial space
 // for approximately n elements.
 func MakeMapWithSize(typ Type, n int) Value {
@@ -1871,18 +2261,27 @@ func MakeMapWithSize(typ Type, n int) Value {
 	key := typ.Key().(*rawType)
 	val := typ.Elem().(*rawType)
 
-	var alg uint8
+	var m unsafe.Pointer
 
 	if key.Kind() == String {
-		alg = hashmapAlgorithmString
+		m = hashmapMake(key.Size(), val.Size(), uintptr(n), hashmapAlgorithmString)
 	} else if key.isBinary() {
-		alg = hashmapAlgorithmBinary
+		m = hashmapMake(key.Size(), val.Size(), uintptr(n), hashmapAlgorithmBinary)
+	} else if key.Kind() == Interface {
+		m = hashmapMake(key.Size(), val.Size(), uintptr(n), hashmapAlgorithmInterface)
 	} else {
-		alg = hashmapAlgorithmInterface
+		// The compiler generates the hash and equal functions for other key
+		// types, which isn't possible here. Instead, hash and compare the keys
+		// as interfaces. This is slower but gives the same result.
+		keyHash := func(ptr unsafe.Pointer, size, seed uintptr) uint32 {
+			return hashmapInterfaceHash(valueInterfaceUnsafe(NewAt(key, ptr).Elem()), seed)
+		}
+		keyEqual := func(x, y unsafe.Pointer, n uintptr) bool {
+			return valueInterfaceUnsafe(NewAt(key, x).Elem()) == valueInterfaceUnsafe(NewAt(key, y).Elem())
+		}
+		m = hashmapMakeFunc(key.Size(), val.Size(), uintptr(n), keyHash, keyEqual)
 	}
 
-	m := hashmapMake(key.Size(), val.Size(), uintptr(n), alg)
-
 	return Value{
 		typecode: typ.(*rawType),
 		value:    m,
@@ -1905,16 +2304,156 @@ type SelectCase struct {
 	Send Value     // value to send (for send)
 }
 
+// chanSelectState has the same layout as runtime.chanSelectState: a channel
+// and, for send operations, a pointer to the value to send.
+type chanSelectState struct {
+	ch    unsafe.Pointer
+	value unsafe.Pointer
+}
+
+//go:linkname chanselect runtime.chanSelectUnsafePointer
+func chanselect(recvbuf unsafe.Pointer, states []chanSelectState, blocking bool) (uintptr, bool)
+
+// Select executes a select operation described by the list of cases. Like the
+// Go select statement, it blocks until at least one of the cases can proceed,
+// makes a choice and then executes that case. It returns the index of the
+// chosen case and, if that case was a receive operation, the value received
+// and a boolean indicating whether the value corresponds to a send on the
+// channel (as opposed to a zero value received because the channel is closed).
+//
+// Cases with a zero Value as channel are ignored, like cases with a nil
+// channel in a select statement.
 func Select(cases []SelectCase) (chosen int, recv Value, recvOK bool) {
-	panic("unimplemented: reflect.Select")
+	// Every case gets a state, so that the index returned by the runtime is
+	// the index in cases. A default case is never ready, like a nil channel.
+	states := make([]chanSelectState, len(cases))
+	defaultCase := -1
+	var recvSize uintptr
+	for i, c := range cases {
+		switch c.Dir {
+		case SelectDefault:
+			if defaultCase >= 0 {
+				panic("reflect.Select: multiple default cases")
+			}
+			if c.Chan.IsValid() {
+				panic("reflect.Select: default case has Chan value")
+			}
+			if c.Send.IsValid() {
+				panic("reflect.Select: default case has Send value")
+			}
+			defaultCase = i
+		case SelectSend:
+			if !c.Chan.IsValid() {
+				break
+			}
+			c.Chan.checkExported("reflect.Select")
+			if c.Chan.Kind() != Chan || c.Chan.typecode.ChanDir()&SendDir == 0 {
+				panic("reflect.Select: SendDir case using recv-only channel")
+			}
+			if !c.Send.IsValid() {
+				panic("reflect.Select: SendDir case missing Send value")
+			}
+			states[i] = chanSelectState{
+				ch:    c.Chan.pointer(),
+				value: c.Chan.chanValuePointer(c.Send),
+			}
+		case SelectRecv:
+			if c.Send.IsValid() {
+				panic("reflect.Select: RecvDir case has Send value")
+			}
+			if !c.Chan.IsValid() {
+				break
+			}
+			c.Chan.checkExported("reflect.Select")
+			if c.Chan.Kind() != Chan || c.Chan.typecode.ChanDir()&RecvDir == 0 {
+				panic("reflect.Select: RecvDir case using send-only channel")
+			}
+			states[i] = chanSelectState{ch: c.Chan.pointer()}
+			if size := c.Chan.typecode.elem().Size(); size > recvSize {
+				recvSize = size
+			}
+		default:
+			panic("reflect.Select: invalid Dir")
+		}
+	}
+
+	// All receive cases share the same buffer, as only one of them can
+	// proceed.
+	var recvbuf unsafe.Pointer
+	if recvSize != 0 {
+		recvbuf = alloc(recvSize, nil)
+	}
+	index, ok := chanselect(recvbuf, states, defaultCase < 0)
+	if index == ^uintptr(0) {
+		// No case could proceed immediately.
+		return defaultCase, Value{}, false
+	}
+	chosen = int(index)
+	if cases[chosen].Dir == SelectRecv {
+		recv = Value{
+			typecode: cases[chosen].Chan.typecode.elem(),
+			value:    recvbuf,
+		}.loadIndirect(valueFlagExported)
+		recvOK = ok
+	}
+	return chosen, recv, recvOK
 }
 
+//go:linkname chansend runtime.chanSendUnsafePointer
+func chansend(p unsafe.Pointer, value unsafe.Pointer)
+
+//go:linkname chantrysend runtime.chanTrySendUnsafePointer
+func chantrysend(p unsafe.Pointer, value unsafe.Pointer) bool
+
+//go:linkname chanclose runtime.chanCloseUnsafePointer
+func chanclose(p unsafe.Pointer)
+
+// Send sends x on the channel v. It blocks until the value can be sent.
 func (v Value) Send(x Value) {
-	panic("unimplemented: reflect.Value.Send()")
+	v.checkChanDir("Send", SendDir)
+	chansend(v.pointer(), v.chanValuePointer(x))
+}
+
+// TrySend attempts to send x on the channel v without blocking. It reports
+// whether the value was sent.
+func (v Value) TrySend(x Value) bool {
+	v.checkChanDir("TrySend", SendDir)
+	return chantrysend(v.pointer(), v.chanValuePointer(x))
 }
 
+// Close closes the channel v. It panics if v is not a channel or if it is a
+// receive-only channel.
 func (v Value) Close() {
-	panic("unimplemented: reflect.Value.Close()")
+	if v.Kind() != Chan {
+		panic(&ValueError{Method: "Close", Kind: v.Kind()})
+	}
+	v.checkExported("reflect.Value.Close")
+	if v.typecode.ChanDir() == RecvDir {
+		panic("reflect: close of receive-only channel")
+	}
+	chanclose(v.pointer())
+}
+
+//go:linkname chanmake runtime.chanMakeUnsafePointer
+func chanmake(elementSize uintptr, bufSize uintptr) unsafe.Pointer
+
+// MakeChan creates a new channel with the specified type and buffer size.
+func MakeChan(typ Type, buffer int) Value {
+	if typ.Kind() != Chan {
+		panic("reflect.MakeChan of non-chan type")
+	}
+	if buffer < 0 {
+		panic("reflect.MakeChan: negative buffer size")
+	}
+	if typ.ChanDir() != BothDir {
+		panic("reflect.MakeChan: unidirectional channel type")
+	}
+
+	return Value{
+		typecode: typ.(*rawType),
+		value:    chanmake(typ.Elem().Size(), uintptr(buffer)),
+		flags:    valueFlagExported,
+	}
 }
 
 // MakeMap creates a new map with the specified type.
@@ -1922,22 +2461,295 @@ func MakeMap(typ Type) Value {
 	return MakeMapWithSize(typ, 8)
 }
 
+// Call calls the function v with the input arguments in. For example, if
+// len(in) == 3, v.Call(in) represents the Go call v(in[0], in[1], in[2]).
+// Call panics if v's Kind is not Func. It returns the output results as Values.
+// If v is a variadic function, Call creates the variadic slice parameter
+// itself, copying in the corresponding values.
 func (v Value) Call(in []Value) []Value {
-	panic("unimplemented: (reflect.Value).Call()")
+	return v.call("Call", in, false)
+}
+
+// CallSlice calls the variadic function v with the input arguments in,
+// assigning the slice in[len(in)-1] to v's final variadic argument. For
+// example, if len(in) == 3, v.CallSlice(in) represents the Go call v(in[0],
+// in[1], in[2]...). CallSlice panics if v's Kind is not Func or if v is not
+// variadic. It returns the output results as Values.
+func (v Value) CallSlice(in []Value) []Value {
+	return v.call("CallSlice", in, true)
+}
+
+func (v Value) call(op string, in []Value, isSlice bool) []Value {
+	if v.Kind() != Func {
+		panic(&ValueError{Method: op, Kind: v.Kind()})
+	}
+	if !v.isExported() {
+		panic("reflect: " + op + " using value obtained using unexported field")
+	}
+	if v.IsNil() {
+		panic("reflect: call of nil function")
+	}
+	t := v.typecode
+	ftype := t.funcType(op)
+	numIn := t.NumIn()
+	isVariadic := t.IsVariadic()
+
+	// Check the number of arguments.
+	if isSlice {
+		if !isVariadic {
+			panic("reflect: CallSlice of non-variadic function")
+		}
+		if len(in) != numIn {
+			panic("reflect: CallSlice with wrong number of input arguments")
+		}
+	} else {
+		if isVariadic {
+			numIn--
+		}
+		if len(in) < numIn {
+			panic("reflect: Call with too few input arguments")
+		}
+		if !isVariadic && len(in) > numIn {
+			panic("reflect: Call with too many input arguments")
+		}
+	}
+	for _, x := range in {
+		if x.Kind() == Invalid {
+			panic("reflect: " + op + " using zero Value argument")
+		}
+	}
+
+	// Pack the variadic arguments in a slice.
+	if isVariadic && !isSlice {
+		sliceType := t.In(numIn)
+		elem := sliceType.Elem()
+		extra := len(in) - numIn
+		slice := MakeSlice(sliceType, extra, extra)
+		for i := 0; i < extra; i++ {
+			x := in[numIn+i]
+			if !x.typecode.AssignableTo(elem) {
+				panic("reflect: cannot use " + x.typecode.String() + " as type " + elem.String() + " in " + op)
+			}
+			slice.Index(i).Set(x)
+		}
+		in = append(in[:numIn:numIn], slice)
+	}
+
+	// Collect pointers to all the arguments. Values that are stored directly
+	// in the value pointer are copied into a word in words.
+	numOut := t.NumOut()
+	args := make([]unsafe.Pointer, len(in))
+	results := make([]unsafe.Pointer, numOut)
+	words := make([]unsafe.Pointer, len(in)+numOut)
+	for i, x := range in {
+		targ := ftype.param(i)
+		if !x.typecode.AssignableTo(targ) {
+			panic("reflect: " + op + " using " + x.typecode.String() + " as type " + targ.String())
+		}
+		x.checkNotMethod("(reflect.Value)." + op + "()")
+		if targ.Kind() == Interface && x.typecode.Kind() != Interface {
+			intf := valueInterfaceUnsafe(x)
+			args[i] = unsafe.Pointer(&intf)
+		} else if x.isIndirect() || x.typecode.Size() > unsafe.Sizeof(uintptr(0)) {
+			args[i] = x.value
+		} else {
+			words[i] = x.value
+			args[i] = unsafe.Pointer(&words[i])
+		}
+	}
+
+	// Allocate space for all the results.
+	for i := 0; i < numOut; i++ {
+		tout := t.Out(i)
+		if tout.Size() > unsafe.Sizeof(uintptr(0)) {
+			results[i] = alloc(tout.Size(), nil)
+		} else {
+			results[i] = unsafe.Pointer(&words[len(in)+i])
+		}
+	}
+
+	// Methods are called through their interface invoke wrapper, which takes
+	// the receiver as it is stored in an interface as the first parameter.
+	fn, call := v.value, ftype.call
+	if v.flags&valueFlagMethod != 0 {
+		m := (*methodValue)(v.value)
+		recv := m.recv
+		if m.bound {
+			args = append([]unsafe.Pointer{unsafe.Pointer(&recv)}, args...)
+		} else {
+			// Method expression: the receiver is the first argument.
+			_, recv = decomposeInterface(valueInterfaceUnsafe(in[0]))
+			args[0] = unsafe.Pointer(&recv)
+		}
+		fn = unsafe.Pointer(&funcHeader{Code: m.entry.fn})
+		call = m.entry.call
+	}
+
+	if call == nil {
+		// Function types created by FuncOf don't have a call thunk.
+		panic("reflect: " + op + " of function with a type created by FuncOf")
+	}
+
+	// Do the call through the compiler-generated thunk.
+	var argsPtr, resultsPtr unsafe.Pointer
+	if len(args) != 0 {
+		argsPtr = unsafe.Pointer(&args[0])
+	}
+	if len(results) != 0 {
+		resultsPtr = unsafe.Pointer(&results[0])
+	}
+	call(fn, argsPtr, resultsPtr)
+
+	// Wrap the results in Values.
+	out := make([]Value, numOut)
+	for i := range out {
+		tout := t.Out(i).(*rawType)
+		value := results[i]
+		if tout.Size() <= unsafe.Sizeof(uintptr(0)) {
+			value = words[len(in)+i]
+		}
+		out[i] = Value{
+			typecode: tout,
+			value:    value,
+			flags:    valueFlagExported,
+		}
+	}
+	return out
+}
+
+// methodValue is the value of a Value returned by Value.Method (a method value
+// with a bound receiver) or of the Func field of Type.Method (a method
+// expression, which takes the receiver as first argument).
+type methodValue struct {
+	entry *methodTableEntry
+	recv  unsafe.Pointer // receiver as stored in an interface, if bound
+	bound bool
 }
 
+// Method returns a function value corresponding to v's i'th method. The
+// arguments to a Call on the returned function should not include a receiver;
+// the returned function will always use v as the receiver. Method panics if i
+// is out of range or if v is a nil interface value.
+//
+// The returned Value can only be called: it cannot be converted to a func
+// value with Interface or be assigned with Set. Calling methods on values of
+// interface kind is not yet supported.
 func (v Value) Method(i int) Value {
-	panic("unimplemented: (reflect.Value).Method()")
+	if v.typecode == nil {
+		panic(&ValueError{Method: "Method", Kind: Invalid})
+	}
+	if v.typecode.Kind() == Interface {
+		panic("unimplemented: (reflect.Value).Method() on interface value")
+	}
+	if v.flags&valueFlagMethod != 0 || uint(i) >= uint(v.typecode.NumMethod()) {
+		panic("reflect: Method index out of range")
+	}
+	entry := &v.typecode.methods()[i]
+	if entry.fn == nil {
+		// The compiler removes method references from the method tables if
+		// Value.Method isn't used, so this should not happen.
+		panic("reflect: method " + readStringZ(unsafe.Pointer(&entry.signature.name[0])) + " was not retained")
+	}
+	_, recv := decomposeInterface(valueInterfaceUnsafe(v))
+	return Value{
+		typecode: entry.signature.typ,
+		value:    unsafe.Pointer(&methodValue{entry: entry, recv: recv, bound: true}),
+		flags:    v.flags&valueFlagExported | valueFlagMethod,
+	}
 }
 
+// MethodByName returns a function value corresponding to the method of v with
+// the given name. The arguments to a Call on the returned function should not
+// include a receiver; the returned function will always use v as the receiver.
+// It returns the zero Value if no method was found.
 func (v Value) MethodByName(name string) Value {
-	panic("unimplemented: (reflect.Value).MethodByName()")
+	if v.typecode == nil {
+		panic(&ValueError{Method: "MethodByName", Kind: Invalid})
+	}
+	if v.typecode.Kind() == Interface {
+		panic("unimplemented: (reflect.Value).MethodByName() on interface value")
+	}
+	if v.flags&valueFlagMethod != 0 {
+		panic("reflect: MethodByName of method value")
+	}
+	m, ok := v.typecode.MethodByName(name)
+	if !ok {
+		return Value{}
+	}
+	return v.Method(m.Index)
 }
 
+//go:linkname chanrecv runtime.chanRecvUnsafePointer
+func chanrecv(p unsafe.Pointer, value unsafe.Pointer) bool
+
+//go:linkname chantryrecv runtime.chanTryRecvUnsafePointer
+func chantryrecv(p unsafe.Pointer, value unsafe.Pointer) (received, ok bool)
+
+// Recv receives and returns a value from the channel v. It blocks until a value
+// is ready. The boolean value ok is false if the value is the zero value
+// because the channel is closed.
 func (v Value) Recv() (x Value, ok bool) {
-	panic("unimplemented: (reflect.Value).Recv()")
+	elem := v.checkChanDir("Recv", RecvDir)
+	ok = chanrecv(v.pointer(), elem.value)
+	return elem.Elem().loadIndirect(valueFlagExported), ok
+}
+
+// TryRecv attempts to receive a value from the channel v without blocking. If
+// no value is ready, x is the zero Value. The boolean value ok is false if the
+// value is the zero value because the channel is closed.
+func (v Value) TryRecv() (x Value, ok bool) {
+	elem := v.checkChanDir("TryRecv", RecvDir)
+	received, ok := chantryrecv(v.pointer(), elem.value)
+	if !received {
+		return Value{}, false
+	}
+	return elem.Elem().loadIndirect(valueFlagExported), ok
 }
 
+// checkChanDir panics if v is not a channel that allows the given direction,
+// and returns a new pointer to an element of the channel.
+func (v Value) checkChanDir(method string, dir ChanDir) Value {
+	if v.Kind() != Chan {
+		panic(&ValueError{Method: method, Kind: v.Kind()})
+	}
+	v.checkExported("reflect.Value." + method)
+	if v.typecode.ChanDir()&dir == 0 {
+		if dir == SendDir {
+			panic("reflect: send on recv-only channel")
+		}
+		panic("reflect: recv on send-only channel")
+	}
+	return New(v.typecode.Elem())
+}
+
+// chanValuePointer returns a pointer to x, converted to the element type of the
+// channel v, for sending it over the channel.
+func (v Value) chanValuePointer(x Value) unsafe.Pointer {
+	x.checkExported("reflect.Value.Send")
+	elemType := v.typecode.elem()
+	if !x.typecode.AssignableTo(elemType) {
+		panic("reflect: cannot send: incompatible types")
+	}
+	if elemType.Kind() == Interface && x.typecode.Kind() != Interface {
+		intf := valueInterfaceUnsafe(x)
+		return unsafe.Pointer(&intf)
+	}
+	if elemType.Size() <= unsafe.Sizeof(uintptr(0)) && !x.isIndirect() {
+		value := x.value
+		return unsafe.Pointer(&value)
+	}
+	return x.value
+}
+
+// NewAt returns a Value representing a pointer to a value of the specified
+// type, using p as that pointer.
 func NewAt(typ Type, p unsafe.Pointer) Value {
-	panic("unimplemented: reflect.New()")
+	if typ == nil {
+		panic("reflect: NewAt(nil)")
+	}
+	return Value{
+		typecode: pointerTo(typ.(*rawType)),
+		value:    p,
+		flags:    valueFlagExported,
+	}
 }
@@ -4,7 +4,9 @@ import (
 	"encoding/base64"
 	. "reflect"
 	"sort"
+	"strings"
 	"testing"
+	"unsafe"
 )
 
 func TestTinyIndirectPointers(t *testing.T) {
@@ -251,6 +253,52 @@ func TestMapInterfaceKeys(t *testing.T) {
 	}
 }
 
+func TestMapAnyKeys(t *testing.T) {
+	m := map[any]int{"one": 1, 2: 2}
+	refm := ValueOf(m)
+
+	refm.SetMapIndex(ValueOf("three"), ValueOf(3))
+	if m["three"] != 3 {
+		t.Errorf("SetMapIndex with string key on map[any]int failed")
+	}
+
+	keys := refm.MapKeys()
+	if len(keys) != 3 {
+		t.Fatalf("MapKeys()=%v, want 3 keys", len(keys))
+	}
+	for _, k := range keys {
+		if k.Kind() != Interface {
+			t.Errorf("MapKeys: key kind %v, want %v", k.Kind(), Interface)
+		}
+		if got, want := refm.MapIndex(k).Int(), int64(m[k.Interface()]); got != want {
+			t.Errorf("MapIndex(%v)=%v, want %v", k, got, want)
+		}
+	}
+
+	var sum int64
+	it := refm.MapRange()
+	for it.Next() {
+		if it.Key().Kind() != Interface {
+			t.Errorf("MapIter.Key: key kind %v, want %v", it.Key().Kind(), Interface)
+		}
+		sum += it.Value().Int()
+	}
+	if sum != 6 {
+		t.Errorf("MapRange: sum of values %d, want 6", sum)
+	}
+
+	k := New(refm.Type().Key()).Elem()
+	e := New(refm.Type().Elem()).Elem()
+	it.Reset(refm)
+	for it.Next() {
+		k.SetIterKey(it)
+		e.SetIterValue(it)
+		if int64(m[k.Interface()]) != e.Int() {
+			t.Errorf("SetIterKey/SetIterValue: m[%v]=%v, got %v", k, m[k.Interface()], e)
+		}
+	}
+}
+
 func TestMapInterfaceElem(t *testing.T) {
 	m := make(map[string]interface{})
 	refm := ValueOf(m)
@@ -336,6 +384,31 @@ func TestTinySlice(t *testing.T) {
 	}
 }
 
+func TestTinyAppend(t *testing.T) {
+	type ints []int
+	type point struct{ X, Y int }
+
+	v := AppendSlice(ValueOf(ints{1, 2}), ValueOf([]int{3, 4, 5}))
+	if got, ok := v.Interface().(ints); !ok || !DeepEqual(got, ints{1, 2, 3, 4, 5}) {
+		t.Errorf("AppendSlice: got %#v, want ints{1, 2, 3, 4, 5}", v.Interface())
+	}
+
+	var points []point
+	pv := ValueOf(points)
+	for i := 0; i < 5; i++ {
+		pv = Append(pv, ValueOf(point{i, -i}), ValueOf(point{-i, i}))
+	}
+	points = pv.Interface().([]point)
+	if len(points) != 10 {
+		t.Fatalf("Append: got %d points, want 10", len(points))
+	}
+	for i := 0; i < 5; i++ {
+		if points[2*i] != (point{i, -i}) || points[2*i+1] != (point{-i, i}) {
+			t.Errorf("Append: wrong points at index %d: %v", 2*i, points[2*i:2*i+2])
+		}
+	}
+}
+
 func TestTinyBytes(t *testing.T) {
 	s := []byte("abcde")
 	refs := ValueOf(s)
@@ -421,6 +494,35 @@ func TestTinyNamedTypes(t *testing.T) {
 	v.Set(n)
 }
 
+type pair[A, B any] struct {
+	First  A
+	Second B
+}
+
+type pairElem struct{ X int }
+
+func TestTinyGenericNamedTypes(t *testing.T) {
+	typ := TypeOf(pair[int, pairElem]{})
+	if got, want := typ.Name(), "pair[int,reflect_test.pairElem]"; got != want {
+		t.Errorf("TypeOf.Name()=%v, want %v", got, want)
+	}
+	if got, want := typ.String(), "reflect_test.pair[int,reflect_test.pairElem]"; got != want {
+		t.Errorf("TypeOf.String()=%v, want %v", got, want)
+	}
+	if got, want := typ.PkgPath(), "reflect_test"; got != want {
+		t.Errorf("TypeOf.PkgPath()=%v, want %v", got, want)
+	}
+
+	// Different instantiations are different types with a different name.
+	other := TypeOf(pair[string, string]{})
+	if got, want := other.Name(), "pair[string,string]"; got != want {
+		t.Errorf("TypeOf.Name()=%v, want %v", got, want)
+	}
+	if typ == other {
+		t.Errorf("pair[int,pairElem] and pair[string,string] have the same type")
+	}
+}
+
 func TestTinyStruct(t *testing.T) {
 	type barStruct struct {
 		QuxString string
@@ -458,6 +560,128 @@ func TestTinyStruct(t *testing.T) {
 	}
 }
 
+type embeddedInner struct {
+	Deep int
+}
+
+type embeddedA struct {
+	A1, A2 int
+	embeddedInner
+}
+
+type embeddedB struct {
+	B int
+	*embeddedInner
+}
+
+type embeddedOuter struct {
+	X int
+	embeddedA
+	*embeddedB
+	*embeddedOuter // recursive
+}
+
+func TestTinyStructTag(t *testing.T) {
+	type S struct {
+		A int `json:"a,omitempty" xml:"x" json:"dup"`
+		B int `escaped:"\"q\"\x41"`
+		C int `not a conventional tag`
+		D int `json:"a,omitempty" xml:"x" json:"dup"` // same tag as A
+	}
+	typ := TypeOf(S{})
+	for i, tc := range []struct {
+		keys  []string
+		table bool
+	}{
+		{[]string{"json", "xml", "yaml", "a"}, true},
+		{[]string{"escaped", ""}, true},
+		{[]string{"not", "a"}, false},
+		{[]string{"json", "xml"}, true},
+	} {
+		tag := typ.Field(i).Tag
+		if HasTagTable(tag) != tc.table {
+			t.Errorf("field %d: HasTagTable() = %v, want %v", i, HasTagTable(tag), tc.table)
+		}
+		// A copy of the tag, or a part of it, is not a struct field tag and
+		// must be parsed. It should give the same result as the table.
+		copied := StructTag(string([]byte(tag)))
+		if HasTagTable(copied) || HasTagTable(tag[:len(tag)-1]) {
+			t.Errorf("field %d: found a tag table for a copy of the tag", i)
+		}
+		for _, key := range tc.keys {
+			value, ok := tag.Lookup(key)
+			wantValue, wantOk := copied.Lookup(key)
+			if value != wantValue || ok != wantOk {
+				t.Errorf("field %d: Lookup(%q) = %q, %v, want %q, %v", i, key, value, ok, wantValue, wantOk)
+			}
+		}
+	}
+	if v := typ.Field(0).Tag.Get("json"); v != "a,omitempty" {
+		t.Errorf("Get(json) = %q, want %q", v, "a,omitempty")
+	}
+	if v := typ.Field(1).Tag.Get("escaped"); v != `"q"A` {
+		t.Errorf("Get(escaped) = %q, want %q", v, `"q"A`)
+	}
+	if v, ok := typ.Field(3).Tag.Lookup("yaml"); v != "" || ok {
+		t.Errorf("Lookup(yaml) = %q, %v, want no value", v, ok)
+	}
+}
+
+func TestTinyEmbeddedFields(t *testing.T) {
+	typ := TypeOf(embeddedOuter{})
+	for _, tc := range []struct {
+		name  string
+		index []int
+	}{
+		{"X", []int{0}},
+		{"A2", []int{1, 1}},
+		{"B", []int{2, 0}},
+		{"embeddedOuter", []int{3}},
+		{"Deep", nil}, // ambiguous: embeddedA.Deep and embeddedB.Deep
+		{"Missing", nil},
+	} {
+		f, ok := typ.FieldByName(tc.name)
+		if ok != (tc.index != nil) || !equal(f.Index, tc.index) {
+			t.Errorf("FieldByName(%s) = %v, %v, want %v", tc.name, f.Index, ok, tc.index)
+		}
+		if f2 := typ.FieldByIndex(f.Index); ok && f2.Name != tc.name {
+			t.Errorf("FieldByIndex(%v) = %s, want %s", f.Index, f2.Name, tc.name)
+		}
+	}
+
+	f, ok := typ.FieldByNameFunc(func(name string) bool { return name == "A1" || name == "B" })
+	if ok {
+		t.Errorf("FieldByNameFunc with two matches at the same depth found %s", f.Name)
+	}
+	f, ok = typ.FieldByNameFunc(func(name string) bool { return strings.HasPrefix(name, "A2") })
+	if !ok || f.Name != "A2" || f.Type.Kind() != Int {
+		t.Errorf("FieldByNameFunc(A2) = %s, %v", f.Name, ok)
+	}
+
+	v := ValueOf(&embeddedOuter{
+		embeddedA: embeddedA{A2: 5},
+		embeddedB: &embeddedB{B: 7},
+	}).Elem()
+	if got := v.FieldByName("A2").Int(); got != 5 {
+		t.Errorf("FieldByName(A2) = %d, want 5", got)
+	}
+	if got := v.FieldByIndex([]int{2, 0}).Int(); got != 7 {
+		t.Errorf("FieldByIndex([2 0]) = %d, want 7", got)
+	}
+	if _, err := v.FieldByIndexErr([]int{2, 1, 0}); err == nil || !strings.Contains(err.Error(), "embeddedInner") {
+		t.Errorf("FieldByIndexErr through nil pointer returned %v", err)
+	}
+
+	func() {
+		defer func() {
+			if _, ok := recover().(*TypeError); !ok {
+				t.Errorf("FieldByNameFunc on a non-struct type did not panic with a *TypeError")
+			}
+		}()
+		TypeOf(0).FieldByNameFunc(func(string) bool { return true })
+	}()
+}
+
 func TestTinyZero(t *testing.T) {
 	s := "hello, world"
 	var sptr *string = &s
@@ -480,6 +704,49 @@ func TestTinyZero(t *testing.T) {
 	}
 }
 
+func TestTinyNew(t *testing.T) {
+	type point struct {
+		X, Y int
+		Name string
+		Tags []string
+	}
+
+	v := New(TypeOf(point{}))
+	if !v.Elem().CanSet() {
+		t.Fatalf("New(point).Elem() is not settable")
+	}
+	v.Elem().Field(0).SetInt(3)
+	v.Elem().Field(2).SetString("origin")
+	v.Elem().Field(3).Set(ValueOf([]string{"a", "b"}))
+
+	p := v.Interface().(*point)
+	if p.X != 3 || p.Y != 0 || p.Name != "origin" || len(p.Tags) != 2 {
+		t.Errorf("New(point) = %+v", *p)
+	}
+
+	v.Elem().Set(ValueOf(point{X: 1, Y: 2}))
+	if p.X != 1 || p.Y != 2 || p.Name != "" || p.Tags != nil {
+		t.Errorf("Set(point) = %+v", *p)
+	}
+
+	var n int32
+	v = NewAt(TypeOf(n), unsafe.Pointer(&n))
+	v.Elem().SetInt(-5)
+	if n != -5 || v.Interface().(*int32) != &n {
+		t.Errorf("NewAt(int32) did not point to n")
+	}
+	if v.Pointer() != uintptr(unsafe.Pointer(&n)) || v.Elem().UnsafeAddr() != uintptr(unsafe.Pointer(&n)) {
+		t.Errorf("NewAt(int32): Pointer() = %#x, Elem().UnsafeAddr() = %#x, want %p", v.Pointer(), v.Elem().UnsafeAddr(), &n)
+	}
+	shouldPanic("reflect: NewAt(nil)", func() { NewAt(nil, unsafe.Pointer(&n)) })
+
+	var p2 unsafe.Pointer
+	ValueOf(&p2).Elem().SetPointer(unsafe.Pointer(&n))
+	if p2 != unsafe.Pointer(&n) || ValueOf(p2).UnsafePointer() != unsafe.Pointer(&n) {
+		t.Errorf("SetPointer: got %p, want %p", p2, &n)
+	}
+}
+
 func addrDecode(body interface{}) {
 	vbody := ValueOf(body)
 	ptr := vbody.Elem()
@@ -505,6 +772,70 @@ func TestTinyAddr(t *testing.T) {
 	}
 }
 
+func TestTinySettability(t *testing.T) {
+	type inner struct{ X, y int }
+	type outer struct {
+		Inner  inner
+		ptr    *inner
+		Arr    [2]inner
+		arr    [2]inner
+		Slice  []int
+		M      map[string]int
+		hidden int
+	}
+	o := outer{ptr: &inner{}, Slice: []int{1, 2}, M: map[string]int{"a": 1}}
+	v := ValueOf(&o).Elem()
+
+	for _, tc := range []struct {
+		name    string
+		v       Value
+		canAddr bool
+		canSet  bool
+	}{
+		{"Inner.X", v.Field(0).Field(0), true, true},
+		{"Inner.y", v.Field(0).Field(1), true, false},
+		{"ptr.X", v.Field(1).Elem().Field(0), true, false},
+		{"Arr[1].X", v.Field(2).Index(1).Field(0), true, true},
+		{"arr[1].X", v.Field(3).Index(1).Field(0), true, false},
+		{"Slice[1]", v.Field(4).Index(1), true, true},
+		{"Slice[:1]", v.Field(4).Slice(0, 1), false, false},
+		{"M[a]", v.Field(5).MapIndex(ValueOf("a")), false, false},
+		{"hidden", v.Field(6), true, false},
+		{"Addr.Elem", v.Field(6).Addr().Elem(), true, false},
+		{"unaddressable", ValueOf(o).Field(0).Field(0), false, false},
+		{"Zero", Zero(TypeOf(0)), false, false},
+	} {
+		if got := tc.v.CanAddr(); got != tc.canAddr {
+			t.Errorf("%s: CanAddr() = %v, want %v", tc.name, got, tc.canAddr)
+		}
+		if got := tc.v.CanSet(); got != tc.canSet {
+			t.Errorf("%s: CanSet() = %v, want %v", tc.name, got, tc.canSet)
+		}
+	}
+
+	v.Field(2).Index(1).Field(0).SetInt(5)
+	if o.Arr[1].X != 5 {
+		t.Errorf("Arr[1].X = %d, want 5", o.Arr[1].X)
+	}
+	v.Field(0).Field(0).Addr().Elem().SetInt(3)
+	if o.Inner.X != 3 {
+		t.Errorf("Inner.X = %d, want 3", o.Inner.X)
+	}
+
+	if v.Field(1).Elem().Field(0).CanInterface() {
+		t.Errorf("ptr.X: CanInterface() = true, want false")
+	}
+	if !Zero(TypeOf(0)).CanInterface() {
+		t.Errorf("Zero: CanInterface() = false, want true")
+	}
+	if got := v.Field(5).MapIndex(ValueOf("a")).Interface(); got != 1 {
+		t.Errorf("M[a] = %v, want 1", got)
+	}
+	shouldPanic("using value obtained using unexported field", func() {
+		v.Field(0).Field(0).Set(v.Field(6))
+	})
+}
+
 func TestTinyNilType(t *testing.T) {
 	var a any = nil
 	typ := TypeOf(a)
@@ -531,23 +862,31 @@ type methodStruct struct {
 	i int
 }
 
-func (m methodStruct) valueMethod1() int {
+func (m methodStruct) ValueMethod1() int {
 	return m.i
 }
 
-func (m methodStruct) valueMethod2() int {
+func (m methodStruct) ValueMethod2(x string, y ...int) (string, int) {
+	return x, m.i
+}
+
+func (m methodStruct) valueMethod3() int {
 	return m.i
 }
 
-func (m *methodStruct) pointerMethod1() int {
+func (m *methodStruct) PointerMethod1() int {
 	return m.i
 }
 
-func (m *methodStruct) pointerMethod2() int {
+func (m *methodStruct) PointerMethod2() int {
 	return m.i
 }
 
-func (m *methodStruct) pointerMethod3() int {
+func (m *methodStruct) PointerMethod3() int {
+	return m.i
+}
+
+func (m *methodStruct) pointerMethod4() int {
 	return m.i
 }
 
@@ -563,6 +902,222 @@ func TestTinyNumMethods(t *testing.T) {
 	}
 }
 
+func TestTinyMethod(t *testing.T) {
+	reft := TypeOf(methodStruct{})
+	m := reft.Method(1)
+	if m.Name != "ValueMethod2" || m.PkgPath != "" || m.Index != 1 {
+		t.Errorf("Method(1) = %q (index %d), want ValueMethod2 (index 1)", m.Name, m.Index)
+	}
+	if m.Type.NumIn() != 3 || m.Type.In(0) != reft || m.Type.In(1).Kind() != String || !m.Type.IsVariadic() {
+		t.Errorf("Method(1).Type = %v, want func(methodStruct, string, ...int) (string, int)", m.Type)
+	}
+	if m.Type.NumOut() != 2 || m.Type.Out(0).Kind() != String || m.Type.Out(1).Kind() != Int {
+		t.Errorf("Method(1).Type = %v, want func(methodStruct, string, ...int) (string, int)", m.Type)
+	}
+
+	refptrt := TypeOf(&methodStruct{})
+	var names []string
+	for i := 0; i < refptrt.NumMethod(); i++ {
+		names = append(names, refptrt.Method(i).Name)
+	}
+	if want := []string{"PointerMethod1", "PointerMethod2", "PointerMethod3", "ValueMethod1", "ValueMethod2"}; !equal(names, want) {
+		t.Errorf("methods of *methodStruct = %v, want %v", names, want)
+	}
+
+	m, ok := refptrt.MethodByName("ValueMethod1")
+	if !ok || m.Index != 3 || m.Type.In(0) != refptrt {
+		t.Errorf("MethodByName(ValueMethod1) = %v, %v", m, ok)
+	}
+	if _, ok := refptrt.MethodByName("pointerMethod4"); ok {
+		t.Errorf("MethodByName found unexported method")
+	}
+	if n := TypeOf(0).NumMethod(); n != 0 {
+		t.Errorf("NumMethod() of int = %d, want 0", n)
+	}
+}
+
+func TestTinyMethodCall(t *testing.T) {
+	s := methodStruct{i: 5}
+	refv := ValueOf(s)
+	out := refv.MethodByName("ValueMethod2").Call([]Value{ValueOf("foo"), ValueOf(1), ValueOf(2)})
+	if len(out) != 2 || out[0].String() != "foo" || out[1].Int() != 5 {
+		t.Errorf("ValueMethod2 returned %v", out)
+	}
+	if m := refv.MethodByName("ValueMethod2"); m.Type().NumIn() != 2 || m.Kind() != Func {
+		t.Errorf("ValueMethod2 has type %v, want func(string, ...int) (string, int)", m.Type())
+	}
+	if m := refv.MethodByName("valueMethod3"); m.IsValid() {
+		t.Errorf("MethodByName returned unexported method")
+	}
+
+	// Pointer receivers see changes to the value.
+	refp := ValueOf(&s)
+	m := refp.MethodByName("PointerMethod1")
+	s.i = 7
+	if got := m.Call(nil)[0].Int(); got != 7 {
+		t.Errorf("PointerMethod1() = %d, want 7", got)
+	}
+	if got := refp.Method(3).Call(nil)[0].Int(); got != 7 {
+		t.Errorf("Method(3) (ValueMethod1) = %d, want 7", got)
+	}
+
+	// Method expressions take the receiver as first argument.
+	f := TypeOf(&s).Method(0).Func
+	if got := f.Call([]Value{refp})[0].Int(); got != 7 {
+		t.Errorf("(*methodStruct).PointerMethod1 = %d, want 7", got)
+	}
+
+	mustPanic := func(name string, f func()) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s did not panic", name)
+			}
+		}()
+		f()
+	}
+	mustPanic("Method out of range", func() { refv.Method(2) })
+	mustPanic("Call with too few arguments", func() { refv.Method(1).Call(nil) })
+	mustPanic("Interface of method value", func() { refv.Method(0).Interface() })
+}
+
+type tinyReader interface {
+	Read(p []byte) (n int, err error)
+}
+
+type tinyReadCloser interface {
+	tinyReader
+	Close() error
+}
+
+func TestTinyInterfaceMethods(t *testing.T) {
+	reft := TypeOf((*tinyReadCloser)(nil)).Elem()
+	if n := reft.NumMethod(); n != 2 {
+		t.Errorf("NumMethod() of tinyReadCloser = %d, want 2", n)
+	}
+	var names []string
+	for i := 0; i < reft.NumMethod(); i++ {
+		m := reft.Method(i)
+		names = append(names, m.Name)
+		if m.Func.IsValid() {
+			t.Errorf("Method(%d).Func of interface type is valid", i)
+		}
+	}
+	if want := []string{"Close", "Read"}; !equal(names, want) {
+		t.Errorf("methods of tinyReadCloser = %v, want %v", names, want)
+	}
+	m, ok := reft.MethodByName("Read")
+	if !ok || m.Index != 1 || m.Type.NumIn() != 1 || m.Type.In(0) != TypeOf([]byte(nil)) || m.Type.NumOut() != 2 {
+		t.Errorf("MethodByName(Read) = %v, %v", m, ok)
+	}
+
+	anon := TypeOf((*interface{ Close() error })(nil)).Elem()
+	if anon.NumMethod() != 1 || anon.Method(0).Name != "Close" {
+		t.Errorf("methods of %v are wrong", anon)
+	}
+	if anon.String() != "interface { Close() error }" {
+		t.Errorf("String() of interface type = %q", anon.String())
+	}
+
+	reader := TypeOf((*tinyReader)(nil)).Elem()
+	for _, tt := range []struct {
+		t, u Type
+		want bool
+	}{
+		{reft, reader, true},
+		{reader, reft, false},
+		{reft, anon, true},
+		{TypeOf(strings.NewReader("")), reader, true},
+		{TypeOf(strings.NewReader("")), reft, false},
+		{TypeOf(0), reader, false},
+	} {
+		if got := tt.t.Implements(tt.u); got != tt.want {
+			t.Errorf("%v.Implements(%v) = %v, want %v", tt.t, tt.u, got, tt.want)
+		}
+		if got := tt.t.AssignableTo(tt.u); got != tt.want {
+			t.Errorf("%v.AssignableTo(%v) = %v, want %v", tt.t, tt.u, got, tt.want)
+		}
+	}
+}
+
+type tinyUnexported interface {
+	tinyMethod() int
+}
+
+type tinyValueImpl struct{}
+
+func (tinyValueImpl) tinyMethod() int { return 1 }
+
+type tinyPointerImpl struct{}
+
+func (*tinyPointerImpl) tinyMethod() int { return 2 }
+
+type tinyEmbedImpl struct {
+	tinyValueImpl
+}
+
+func TestTinyImplementsUnexported(t *testing.T) {
+	itf := TypeOf((*tinyUnexported)(nil)).Elem()
+	for _, tt := range []struct {
+		t    Type
+		want bool
+	}{
+		{TypeOf(tinyValueImpl{}), true},
+		{TypeOf(&tinyValueImpl{}), true},
+		{TypeOf(tinyPointerImpl{}), false},
+		{TypeOf(&tinyPointerImpl{}), true},
+		{TypeOf(tinyEmbedImpl{}), true},
+		{TypeOf(strings.NewReader("")), false},
+		{TypeOf(0), false},
+		{itf, true},
+		{TypeOf((*tinyReader)(nil)).Elem(), false},
+	} {
+		if got := tt.t.Implements(itf); got != tt.want {
+			t.Errorf("%v.Implements(%v) = %v, want %v", tt.t, itf, got, tt.want)
+		}
+	}
+}
+
+func TestTinyAssignableTo(t *testing.T) {
+	type namedInts []int
+	type otherInts []int
+	type namedChan chan int
+
+	for _, tt := range []struct {
+		t, u Type
+		want bool
+	}{
+		{TypeOf([]int(nil)), TypeOf(namedInts(nil)), true},
+		{TypeOf(namedInts(nil)), TypeOf([]int(nil)), true},
+		{TypeOf(namedInts(nil)), TypeOf(otherInts(nil)), false},
+		{TypeOf(0), TypeOf(int64(0)), false},
+		{TypeOf(make(chan int)), TypeOf(make(<-chan int)), true},
+		{TypeOf(make(<-chan int)), TypeOf(make(chan int)), false},
+		{TypeOf(make(chan int)), TypeOf(make(chan<- string)), false},
+		{TypeOf(namedChan(nil)), TypeOf(make(chan<- int)), true},
+		{TypeOf(strings.NewReader("")), TypeOf((*tinyReader)(nil)).Elem(), true},
+		{TypeOf(0), TypeOf((*any)(nil)).Elem(), true},
+	} {
+		if got := tt.t.AssignableTo(tt.u); got != tt.want {
+			t.Errorf("%v.AssignableTo(%v) = %v, want %v", tt.t, tt.u, got, tt.want)
+		}
+	}
+
+	// Filter a list of types by an interface, like a type registry would.
+	reader := TypeOf((*tinyReader)(nil)).Elem()
+	var readers []string
+	for _, typ := range []Type{TypeOf(0), TypeOf(strings.NewReader("")), TypeOf(""), TypeOf(&strings.Builder{})} {
+		if typ.Implements(reader) {
+			readers = append(readers, typ.String())
+		}
+	}
+	if want := []string{"*strings.Reader"}; !equal(readers, want) {
+		t.Errorf("types implementing tinyReader = %v, want %v", readers, want)
+	}
+
+	shouldPanic("nil type passed to Type.AssignableTo", func() { TypeOf(0).AssignableTo(nil) })
+	shouldPanic("nil type passed to Type.Implements", func() { TypeOf(0).Implements(nil) })
+}
+
 func TestAssignableTo(t *testing.T) {
 	var a any
 	refa := ValueOf(&a).Elem()
@@ -595,6 +1150,284 @@ func TestConvert(t *testing.T) {
 	}
 }
 
+func TestTinyConvert(t *testing.T) {
+	type namedInt int
+	type namedBytes []byte
+	type namedPtr *int
+
+	for _, tc := range []struct {
+		v    any
+		t    Type
+		want any
+	}{
+		{namedInt(5), TypeOf(0.0), 5.0},
+		{-2.7, TypeOf(int8(0)), int8(-2)},
+		{complex64(1 + 2i), TypeOf(complex128(0)), 1 + 2i},
+		{65, TypeOf(""), "A"},
+		{uint64(1 << 40), TypeOf(""), "�"},
+		{"héllo", TypeOf([]rune(nil)), []rune("héllo")},
+		{[]rune("héllo"), TypeOf(""), "héllo"},
+		{namedBytes("abc"), TypeOf(""), "abc"},
+		{[]byte("abc"), TypeOf(namedBytes(nil)), namedBytes("abc")},
+		{[]int{1, 2, 3}, TypeOf([2]int{}), [2]int{1, 2}},
+		{3, TypeOf((*any)(nil)).Elem(), 3},
+	} {
+		v := ValueOf(tc.v)
+		if !v.Type().ConvertibleTo(tc.t) || !v.CanConvert(tc.t) {
+			t.Errorf("%T should be convertible to %v", tc.v, tc.t)
+			continue
+		}
+		c := v.Convert(tc.t)
+		if c.Type() != tc.t {
+			t.Errorf("Convert(%T -> %v) has type %v", tc.v, tc.t, c.Type())
+		}
+		if got := c.Interface(); !DeepEqual(got, tc.want) {
+			t.Errorf("Convert(%T -> %v) = %v, want %v", tc.v, tc.t, got, tc.want)
+		}
+	}
+
+	// Slice to array pointer shares the memory.
+	s := []int{1, 2, 3}
+	p := ValueOf(s).Convert(TypeOf((*[3]int)(nil))).Interface().(*[3]int)
+	p[0] = 5
+	if s[0] != 5 {
+		t.Errorf("converted array pointer does not point to the slice")
+	}
+	if ValueOf(s).CanConvert(TypeOf((*[4]int)(nil))) {
+		t.Errorf("CanConvert to a longer array pointer returned true")
+	}
+
+	x := 3
+	if c := ValueOf(&x).Convert(TypeOf(namedPtr(nil))); c.Interface().(namedPtr) != &x {
+		t.Errorf("Convert(*int -> namedPtr) failed")
+	}
+
+	// Converting an addressable value results in a copy.
+	n := namedInt(7)
+	c := ValueOf(&n).Elem().Convert(TypeOf(0))
+	n = 8
+	if c.Int() != 7 || c.CanSet() {
+		t.Errorf("Convert of addressable value: got %d (settable: %v), want 7", c.Int(), c.CanSet())
+	}
+
+	for _, tc := range []struct {
+		v any
+		t Type
+	}{
+		{"abc", TypeOf(0)},
+		{[]int{}, TypeOf("")},
+		{[]int{}, TypeOf([1]int8{})},
+		{struct{}{}, TypeOf(0)},
+	} {
+		if ValueOf(tc.v).Type().ConvertibleTo(tc.t) {
+			t.Errorf("%T should not be convertible to %v", tc.v, tc.t)
+		}
+	}
+}
+
+func TestTinyMakeChan(t *testing.T) {
+	v := MakeChan(TypeOf(make(chan int)), 2)
+	if v.Cap() != 2 || v.Len() != 0 {
+		t.Errorf("MakeChan: cap=%d len=%d", v.Cap(), v.Len())
+	}
+
+	v.Send(ValueOf(5))
+	if !v.TrySend(ValueOf(7)) {
+		t.Errorf("TrySend on channel with space failed")
+	}
+	if v.TrySend(ValueOf(9)) {
+		t.Errorf("TrySend on full channel succeeded")
+	}
+
+	c := v.Interface().(chan int)
+	if got := <-c; got != 5 {
+		t.Errorf("receive after Send: got %d, want 5", got)
+	}
+	if x, ok := v.Recv(); !ok || x.Int() != 7 {
+		t.Errorf("Recv: got %v, %v, want 7, true", x, ok)
+	}
+	if x, ok := v.TryRecv(); x.IsValid() || ok {
+		t.Errorf("TryRecv on empty channel: got %v, %v", x, ok)
+	}
+
+	v.Close()
+	if x, ok := v.Recv(); ok || x.Int() != 0 {
+		t.Errorf("Recv on closed channel: got %v, %v, want 0, false", x, ok)
+	}
+
+	s := MakeChan(TypeOf(make(chan string)), 1)
+	s.Send(ValueOf("hello"))
+	if x, ok := s.Recv(); !ok || x.String() != "hello" {
+		t.Errorf("Recv: got %v, %v, want hello, true", x, ok)
+	}
+}
+
+func TestTinySelect(t *testing.T) {
+	ints := make(chan int, 1)
+	strs := make(chan string, 1)
+	cases := []SelectCase{
+		{Dir: SelectRecv, Chan: ValueOf(ints)},
+		{Dir: SelectSend, Chan: ValueOf(strs), Send: ValueOf("hello")},
+		{Dir: SelectRecv}, // ignored
+	}
+
+	// Only the send case can proceed.
+	chosen, recv, recvOK := Select(cases)
+	if chosen != 1 || recv.IsValid() || recvOK {
+		t.Errorf("Select: got %d, %v, %v, want 1, <invalid>, false", chosen, recv, recvOK)
+	}
+	if s := <-strs; s != "hello" {
+		t.Errorf("Select sent %q, want hello", s)
+	}
+
+	// Only the receive case can proceed.
+	ints <- 5
+	strs <- "full"
+	chosen, recv, recvOK = Select(cases)
+	if chosen != 0 || !recvOK || recv.Int() != 5 || recv.CanAddr() {
+		t.Errorf("Select: got %d, %v, %v, want 0, 5, true", chosen, recv, recvOK)
+	}
+
+	// Nothing can proceed, so the default case is chosen.
+	cases = append(cases, SelectCase{Dir: SelectDefault})
+	chosen, recv, recvOK = Select(cases)
+	if chosen != 3 || recv.IsValid() || recvOK {
+		t.Errorf("Select: got %d, %v, %v, want 3, <invalid>, false", chosen, recv, recvOK)
+	}
+
+	// Blocking select, woken up by another goroutine.
+	go func() {
+		ints <- 7
+	}()
+	chosen, recv, recvOK = Select(cases[:3])
+	if chosen != 0 || !recvOK || recv.Int() != 7 {
+		t.Errorf("Select: got %d, %v, %v, want 0, 7, true", chosen, recv, recvOK)
+	}
+
+	close(ints)
+	chosen, recv, recvOK = Select(cases[:1])
+	if chosen != 0 || recvOK || recv.Int() != 0 {
+		t.Errorf("Select on closed channel: got %d, %v, %v, want 0, 0, false", chosen, recv, recvOK)
+	}
+}
+
+func TestTinyMapKeyElem(t *testing.T) {
+	type point struct{ X, Y int }
+	typ := TypeOf(map[point]map[string][]byte{})
+	if typ.Key() != TypeOf(point{}) {
+		t.Errorf("Key() = %v, want %v", typ.Key(), TypeOf(point{}))
+	}
+	elem := typ.Elem()
+	if elem != TypeOf(map[string][]byte{}) {
+		t.Errorf("Elem() = %v, want %v", elem, TypeOf(map[string][]byte{}))
+	}
+	if elem.Key().Kind() != String || elem.Elem() != TypeOf([]byte{}) {
+		t.Errorf("nested map: Key() = %v, Elem() = %v", elem.Key(), elem.Elem())
+	}
+	if got, want := typ.String(), "map[reflect_test.point]map[string][]uint8"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestTinyTypeString(t *testing.T) {
+	var stringer interface{ String() string }
+	tests := []struct {
+		typ  Type
+		want string
+	}{
+		{TypeOf(func(int, ...string) (bool, error) { return false, nil }), "func(int, ...string) (bool, error)"},
+		{TypeOf(func() {}), "func()"},
+		{TypeOf(&stringer).Elem(), "interface { String() string }"},
+		{TypeOf([]func(byte) *int{}), "[]func(uint8) *int"},
+		{TypeOf(map[string]interface{}{}), "map[string]interface {}"},
+	}
+	for _, tt := range tests {
+		if got := tt.typ.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+type handlerFunc func(name string, args ...int) (int, error)
+
+func TestTinyFuncType(t *testing.T) {
+	typ := TypeOf(handlerFunc(nil))
+	if typ.NumIn() != 2 || typ.In(0).Kind() != String || typ.In(1) != TypeOf([]int{}) {
+		t.Errorf("NumIn() = %d, want 2 (string, []int)", typ.NumIn())
+	}
+	if !typ.IsVariadic() {
+		t.Errorf("IsVariadic() = false, want true")
+	}
+	if typ.NumOut() != 2 || typ.Out(0).Kind() != Int || typ.Out(1) != TypeOf((*error)(nil)).Elem() {
+		t.Errorf("NumOut() = %d, want 2 (int, error)", typ.NumOut())
+	}
+	if got, want := typ.String(), "reflect_test.handlerFunc"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	typ = TypeOf(func(func(bool)) {})
+	if typ.NumIn() != 1 || typ.NumOut() != 0 || typ.IsVariadic() {
+		t.Errorf("func(func(bool)): NumIn() = %d, NumOut() = %d, IsVariadic() = %v", typ.NumIn(), typ.NumOut(), typ.IsVariadic())
+	}
+	if in := typ.In(0); in.NumIn() != 1 || in.In(0).Kind() != Bool {
+		t.Errorf("In(0) = %v, want func(bool)", in)
+	}
+	if got, want := typ.String(), "func(func(bool))"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	mustPanic := func(name string, f func()) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s did not panic", name)
+			}
+		}()
+		f()
+	}
+	mustPanic("In out of range", func() { typ.In(1) })
+	mustPanic("Out out of range", func() { typ.Out(0) })
+	mustPanic("NumIn on int", func() { TypeOf(0).NumIn() })
+}
+
+func TestTinyCall(t *testing.T) {
+	sum := 0
+	var f handlerFunc = func(name string, args ...int) (int, error) {
+		for _, arg := range args {
+			sum += arg
+		}
+		return len(name) + len(args), nil
+	}
+	out := ValueOf(f).Call([]Value{ValueOf("abc"), ValueOf(1), ValueOf(2)})
+	if len(out) != 2 || out[0].Int() != 5 || !out[1].IsNil() || sum != 3 {
+		t.Errorf("Call returned %v, sum %d; want [5, nil], sum 3", out, sum)
+	}
+	out = ValueOf(f).CallSlice([]Value{ValueOf(""), ValueOf([]int{4, 5, 6})})
+	if len(out) != 2 || out[0].Int() != 3 || sum != 18 {
+		t.Errorf("CallSlice returned %v, sum %d; want [3, nil], sum 18", out, sum)
+	}
+
+	describe := func(v any, s string) string {
+		return s + ":" + v.(string)
+	}
+	out = ValueOf(describe).Call([]Value{ValueOf("value"), ValueOf("key")})
+	if len(out) != 1 || out[0].String() != "key:value" {
+		t.Errorf("Call returned %v, want [key:value]", out)
+	}
+
+	mustPanic := func(name string, f func()) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s did not panic", name)
+			}
+		}()
+		f()
+	}
+	mustPanic("Call on int", func() { ValueOf(0).Call(nil) })
+	mustPanic("Call with too few arguments", func() { ValueOf(describe).Call(nil) })
+	mustPanic("Call with wrong argument type", func() { ValueOf(f).Call([]Value{ValueOf(1)}) })
+	mustPanic("CallSlice of non-variadic function", func() { ValueOf(describe).CallSlice(nil) })
+}
+
 func equal[T comparable](a, b []T) bool {
 	if len(a) != len(b) {
 		return false
@@ -607,3 +1440,402 @@ func equal[T comparable](a, b []T) bool {
 	}
 	return true
 }
+
+func TestTinyArrayOf(t *testing.T) {
+	at := ArrayOf(3, TypeOf(int16(0)))
+	if at.Kind() != Array || at.Len() != 3 || at.Elem() != TypeOf(int16(0)) {
+		t.Errorf("ArrayOf(3, int16) = %v", at)
+	}
+	if at.String() != "[3]int16" || at.Size() != 6 || at.Align() != 2 || !at.Comparable() {
+		t.Errorf("ArrayOf(3, int16): String() = %q, Size() = %d, Align() = %d, Comparable() = %v", at.String(), at.Size(), at.Align(), at.Comparable())
+	}
+	if ArrayOf(3, TypeOf(int16(0))) != at {
+		t.Errorf("ArrayOf(3, int16) returned a different type the second time")
+	}
+	if ArrayOf(4, TypeOf(int16(0))) == at {
+		t.Errorf("ArrayOf(4, int16) returned the same type as ArrayOf(3, int16)")
+	}
+	if ArrayOf(2, TypeOf([]int(nil))).Comparable() {
+		t.Errorf("array of slices is comparable")
+	}
+
+	v := New(at).Elem()
+	v.Index(1).SetInt(5)
+	v.Index(2).SetInt(-3)
+	if v.Index(0).Int() != 0 || v.Index(1).Int() != 5 || v.Index(2).Int() != -3 {
+		t.Errorf("unexpected array contents: %v", v.Interface())
+	}
+	if PointerTo(at).Elem() != at || v.Addr().Type() != PointerTo(at) {
+		t.Errorf("pointer type of %v is wrong", at)
+	}
+}
+
+func TestTinySliceOf(t *testing.T) {
+	st := SliceOf(TypeOf(""))
+	if st.Kind() != Slice || st.Elem() != TypeOf("") || st.String() != "[]string" {
+		t.Errorf("SliceOf(string) = %v", st)
+	}
+	if SliceOf(TypeOf("")) != st {
+		t.Errorf("SliceOf(string) returned a different type the second time")
+	}
+	v := Append(MakeSlice(st, 0, 1), ValueOf("a"), ValueOf("b"))
+	if v.Len() != 2 || v.Index(1).String() != "b" {
+		t.Errorf("unexpected slice contents: %v", v.Interface())
+	}
+}
+
+func TestTinyChanOf(t *testing.T) {
+	ct := ChanOf(BothDir, TypeOf(""))
+	if ct.Kind() != Chan || ct.Elem() != TypeOf("") || ct.ChanDir() != BothDir || ct.String() != "chan string" {
+		t.Errorf("ChanOf(BothDir, string) = %v", ct)
+	}
+	if ChanOf(BothDir, TypeOf("")) != ct {
+		t.Errorf("ChanOf(BothDir, string) returned a different type the second time")
+	}
+	rt := ChanOf(RecvDir, TypeOf(""))
+	if rt == ct || rt.ChanDir() != RecvDir || rt.String() != "<-chan string" {
+		t.Errorf("ChanOf(RecvDir, string) = %v", rt)
+	}
+	if st := ChanOf(SendDir, ct); st.ChanDir() != SendDir || st.String() != "chan<- chan string" {
+		t.Errorf("ChanOf(SendDir, chan string) = %v", st)
+	}
+	if TypeOf((<-chan int)(nil)).ChanDir() != RecvDir || TypeOf((chan<- int)(nil)).ChanDir() != SendDir {
+		t.Errorf("wrong ChanDir for compiler generated channel types")
+	}
+
+	v := MakeChan(ct, 1)
+	v.Send(ValueOf("hello"))
+	if x, ok := v.Recv(); !ok || x.String() != "hello" {
+		t.Errorf("Recv: got %v, %v, want hello, true", x, ok)
+	}
+}
+
+func TestTinyStructOf(t *testing.T) {
+	st := StructOf([]StructField{
+		{Name: "A", Type: TypeOf(uint8(0))},
+		{Name: "B", Type: TypeOf(int32(0)), Tag: `json:"b"`},
+		{Name: "c", PkgPath: "example.com/pkg", Type: TypeOf(uint8(0))},
+	})
+	if st.Kind() != Struct || st.NumField() != 3 || st.Size() != 12 || st.Align() != 4 {
+		t.Errorf("StructOf: Kind() = %v, NumField() = %d, Size() = %d, Align() = %d", st.Kind(), st.NumField(), st.Size(), st.Align())
+	}
+	if want := "struct { A uint8; B int32 \"json:\\\"b\\\"\"; c uint8 }"; st.String() != want {
+		t.Errorf("StructOf: String() = %q, want %q", st.String(), want)
+	}
+	for i, want := range []struct {
+		name    string
+		pkgPath string
+		offset  uintptr
+		tag     StructTag
+	}{
+		{"A", "", 0, ""},
+		{"B", "", 4, `json:"b"`},
+		{"c", "example.com/pkg", 8, ""},
+	} {
+		f := st.Field(i)
+		if f.Name != want.name || f.PkgPath != want.pkgPath || f.Offset != want.offset || f.Tag != want.tag {
+			t.Errorf("field %d = %+v", i, f)
+		}
+	}
+	if f, ok := st.FieldByName("B"); !ok || f.Tag.Get("json") != "b" {
+		t.Errorf("FieldByName(B) = %+v, %v", f, ok)
+	}
+	if StructOf([]StructField{
+		{Name: "A", Type: TypeOf(uint8(0))},
+		{Name: "B", Type: TypeOf(int32(0)), Tag: `json:"b"`},
+		{Name: "c", PkgPath: "example.com/pkg", Type: TypeOf(uint8(0))},
+	}) != st {
+		t.Errorf("StructOf returned a different type the second time")
+	}
+
+	v := New(st).Elem()
+	v.Field(1).SetInt(42)
+	if v.Field(1).Int() != 42 || !v.Field(0).CanSet() || v.Field(2).CanSet() {
+		t.Errorf("unexpected struct value: %v", v.Interface())
+	}
+	if !DeepEqual(v.Interface(), v.Interface()) || v.Interface() != v.Interface() {
+		t.Errorf("struct values are not equal")
+	}
+}
+
+func TestTinyUnexportedFieldPkgPath(t *testing.T) {
+	typ := TypeOf(struct {
+		X int
+		x int
+	}{})
+	if f := typ.Field(0); f.PkgPath != "" || !f.IsExported() {
+		t.Errorf("exported field: PkgPath=%q, IsExported()=%v", f.PkgPath, f.IsExported())
+	}
+	if f := typ.Field(1); f.PkgPath != "reflect_test" || f.IsExported() {
+		t.Errorf("unexported field: PkgPath=%q, IsExported()=%v", f.PkgPath, f.IsExported())
+	}
+
+	// The same struct with the unexported field declared in another package
+	// is a different type.
+	fields := []StructField{
+		{Name: "X", Type: TypeOf(0)},
+		{Name: "x", PkgPath: "reflect_test", Type: TypeOf(0)},
+	}
+	if st := StructOf(fields); st != typ {
+		t.Errorf("StructOf with the same package returned %v, want the existing type", st)
+	}
+	fields[1].PkgPath = "example.com/pkg"
+	if st := StructOf(fields); st == typ || typ.AssignableTo(st) {
+		t.Errorf("struct with a field of another package is the same type as %v", typ)
+	}
+}
+
+func TestTinyFuncOf(t *testing.T) {
+	ft := FuncOf([]Type{TypeOf(0), TypeOf([]string(nil))}, []Type{TypeOf(false), TypeOf((*error)(nil)).Elem()}, true)
+	if ft.Kind() != Func || ft.NumIn() != 2 || ft.NumOut() != 2 || !ft.IsVariadic() || ft.In(1) != TypeOf([]string(nil)) || ft.Out(0) != TypeOf(false) {
+		t.Errorf("FuncOf: unexpected type %v", ft)
+	}
+	if want := "func(int, ...string) (bool, error)"; ft.String() != want {
+		t.Errorf("FuncOf: String() = %q, want %q", ft.String(), want)
+	}
+	if FuncOf([]Type{TypeOf(0), TypeOf([]string(nil))}, []Type{TypeOf(false), TypeOf((*error)(nil)).Elem()}, true) != ft {
+		t.Errorf("FuncOf returned a different type the second time")
+	}
+	if FuncOf(nil, nil, false).String() != "func()" {
+		t.Errorf("FuncOf(nil, nil, false) = %v", FuncOf(nil, nil, false))
+	}
+}
+
+func TestTinySwapper(t *testing.T) {
+	type pair struct{ a, b int32 }
+	type triple [3]byte
+	x, y := 1, 2
+
+	pairs := []pair{{1, 2}, {3, 4}, {5, 6}}
+	Swapper(pairs)(0, 2)
+	if pairs[0] != (pair{5, 6}) || pairs[2] != (pair{1, 2}) {
+		t.Errorf("Swapper on []pair: got %v", pairs)
+	}
+
+	triples := []triple{{1, 2, 3}, {4, 5, 6}}
+	Swapper(triples)(0, 1)
+	if triples[0] != (triple{4, 5, 6}) || triples[1] != (triple{1, 2, 3}) {
+		t.Errorf("Swapper on []triple: got %v", triples)
+	}
+
+	ptrs := []*int{&x, &y}
+	Swapper(ptrs)(1, 0)
+	if ptrs[0] != &y || ptrs[1] != &x {
+		t.Errorf("Swapper on []*int: got %v", ptrs)
+	}
+
+	empty := make([]struct{}, 3)
+	Swapper(empty)(0, 2)
+}
+
+func TestTinyDeepEqualUnexported(t *testing.T) {
+	type inner struct {
+		name string
+		list []int
+	}
+	type outer struct {
+		a, b  []int
+		items []any
+		in    *inner
+	}
+	s := []int{1, 2, 3}
+	x := outer{a: s[:1], b: s[:1], items: []any{1, "x"}, in: &inner{"a", s}}
+	y := outer{a: s[:1], b: s[:1], items: []any{1, "x"}, in: &inner{"a", s}}
+	if !DeepEqual(x, y) {
+		t.Errorf("DeepEqual(%v, %v) = false, want true", x, y)
+	}
+
+	// Slices and interfaces that share their data or their type must still
+	// be compared by their contents.
+	y.b = s[:2]
+	if DeepEqual(x, y) {
+		t.Errorf("DeepEqual with different slice lengths = true, want false")
+	}
+	y.b = s[:1]
+	y.items = []any{1, "y"}
+	if DeepEqual(x, y) {
+		t.Errorf("DeepEqual with different interface values = true, want false")
+	}
+	if DeepEqual([]any{1, 2}, []any{1, 3}) {
+		t.Errorf("DeepEqual([]any{1, 2}, []any{1, 3}) = true, want false")
+	}
+
+	// Unexported fields can be read, but not converted back to an interface.
+	v := ValueOf(x).FieldByName("in").Elem().FieldByName("name")
+	if v.String() != "a" || v.Index(0).Uint() != 'a' {
+		t.Errorf("unexpected value of unexported field: %q", v.String())
+	}
+	if v.CanInterface() || v.Index(0).CanInterface() {
+		t.Errorf("CanInterface of unexported field = true, want false")
+	}
+	defer func() {
+		if r, _ := recover().(string); !strings.Contains(r, "unexported field") {
+			t.Errorf("unexpected panic: %v", r)
+		}
+	}()
+	v.Interface()
+}
+
+func TestTinyArrayIndex(t *testing.T) {
+	var big [1000]int32
+	for i := range big {
+		big[i] = int32(i * 3)
+	}
+
+	// An array stored in an interface is indexed in place.
+	v := ValueOf(big)
+	if v.Len() != 1000 || v.Cap() != 1000 {
+		t.Errorf("Len, Cap = %d, %d, want 1000, 1000", v.Len(), v.Cap())
+	}
+	for _, i := range []int{0, 1, 500, 999} {
+		if got := v.Index(i).Int(); got != int64(i*3) {
+			t.Errorf("Index(%d) = %d, want %d", i, got, i*3)
+		}
+	}
+	if v.Index(0).CanSet() {
+		t.Errorf("element of array in interface is settable")
+	}
+
+	// Through a pointer, the elements are addressable and can be set.
+	p := ValueOf(&big).Elem()
+	p.Index(999).SetInt(-1)
+	if big[999] != -1 {
+		t.Errorf("Index(999).SetInt(-1) did not modify the array: %d", big[999])
+	}
+	if p.Index(500).UnsafeAddr() != uintptr(unsafe.Pointer(&big[500])) {
+		t.Errorf("Index(500) does not point into the array")
+	}
+
+	defer func() {
+		if r, _ := recover().(string); r != "reflect: array index out of range" {
+			t.Errorf("unexpected panic: %v", r)
+		}
+	}()
+	v.Index(1000)
+}
+
+func TestTinyMapPaddedKeys(t *testing.T) {
+	type padded struct {
+		A int8
+		B int32
+	}
+	m := map[padded]string{{1, 2}: "one-two"}
+	refm := ValueOf(m)
+
+	// A key with garbage in its padding must still be found.
+	key := New(TypeOf(padded{})).Elem()
+	*(*[8]byte)(unsafe.Pointer(key.UnsafeAddr())) = [8]byte{1, 0xff, 0xff, 0xff, 2, 0, 0, 0}
+	if got := refm.MapIndex(key); !got.IsValid() || got.String() != "one-two" {
+		t.Errorf("MapIndex with padded key: got %v", got)
+	}
+	refm.SetMapIndex(key, ValueOf("updated"))
+	if len(m) != 1 || m[padded{1, 2}] != "updated" {
+		t.Errorf("SetMapIndex with padded key: got %v", m)
+	}
+	refm.SetMapIndex(key, Value{})
+	if len(m) != 0 {
+		t.Errorf("SetMapIndex delete with padded key: got %v", m)
+	}
+}
+
+func TestTinyMapAssignableKeys(t *testing.T) {
+	// Keys of an unnamed type that are assignable to the named key type of
+	// the map must be boxed with the key type of the map.
+	type pair struct {
+		S string
+		N int
+	}
+	m := map[pair]int{{"a", 1}: 1}
+	refm := ValueOf(m)
+	key := ValueOf(struct {
+		S string
+		N int
+	}{"a", 1})
+	if got := refm.MapIndex(key); !got.IsValid() || got.Int() != 1 {
+		t.Errorf("MapIndex with assignable key: got %v", got)
+	}
+	refm.SetMapIndex(key, ValueOf(2))
+	if len(m) != 1 || m[pair{"a", 1}] != 2 {
+		t.Errorf("SetMapIndex with assignable key: got %v", m)
+	}
+}
+
+func TestTinyMapRangeModify(t *testing.T) {
+	m := make(map[int]int)
+	for i := 0; i < 8; i++ {
+		m[i] = i
+	}
+	refm := ValueOf(m)
+
+	// Deleted entries that were not yet reached are not returned, even when
+	// the map grows during iteration.
+	seen := make(map[int]bool)
+	it := refm.MapRange()
+	for it.Next() {
+		k := int(it.Key().Int())
+		if seen[k] {
+			t.Errorf("key %d returned twice", k)
+		}
+		seen[k] = true
+		if k < 8 {
+			for j := 0; j < 8; j++ {
+				if !seen[j] {
+					refm.SetMapIndex(ValueOf(j), Value{})
+				}
+			}
+			for j := 100; j < 200; j++ {
+				refm.SetMapIndex(ValueOf(j), ValueOf(j))
+			}
+		}
+		if k >= 8 && k < 100 {
+			t.Errorf("unexpected key %d", k)
+		}
+	}
+	for j := 0; j < 8; j++ {
+		if seen[j] != mapHas(m, j) {
+			t.Errorf("key %d: seen=%v but in map=%v", j, seen[j], mapHas(m, j))
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Next on exhausted iterator did not panic")
+		}
+	}()
+	it.Next()
+}
+
+func mapHas(m map[int]int, k int) bool {
+	_, ok := m[k]
+	return ok
+}
+
+func TestTinyChanConvert(t *testing.T) {
+	// Small values that are stored indirectly (like struct fields) must be
+	// converted correctly when sent over a channel of interface type.
+	s := struct {
+		N int16
+		c chan int
+	}{N: 42, c: make(chan int, 1)}
+	field := ValueOf(&s).Elem().Field(0)
+	c := make(chan any, 1)
+	ValueOf(c).Send(field)
+	if x := <-c; x != int16(42) {
+		t.Errorf("Send: got %v, want 42", x)
+	}
+
+	// Zero-sized values can be sent in a select.
+	done := make(chan struct{}, 1)
+	chosen, _, _ := Select([]SelectCase{{Dir: SelectSend, Chan: ValueOf(done), Send: ValueOf(struct{}{})}})
+	if chosen != 0 || len(done) != 1 {
+		t.Errorf("Select: got %d, len %d, want 0, 1", chosen, len(done))
+	}
+
+	// Channels obtained through unexported fields can't be used.
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Send on unexported channel did not panic")
+		}
+	}()
+	ValueOf(&s).Elem().Field(1).Send(ValueOf(1))
+}
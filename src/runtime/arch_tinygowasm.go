@@ -63,6 +63,25 @@ var (
 	stackTop = uintptr(unsafe.Pointer(&globalsStartSymbol))
 )
 
+// wasmInitialized is set once the runtime is initialized, either by _start or
+// by the first call to a function exported with //go:wasmexport.
+var wasmInitialized bool
+
+// wasmExportInit is called by the compiler at the start of every function
+// exported with //go:wasmexport. This allows a module to be used as a library
+// (a WASI reactor) that the host calls without calling _start first: the
+// runtime and all packages are initialized on the first call, without running
+// main.
+func wasmExportInit() {
+	if wasmInitialized {
+		return
+	}
+	wasmInitialized = true
+	heapStart = uintptr(unsafe.Pointer(&heapStartSymbol))
+	heapEnd = uintptr(wasm_memory_size(wasmMemoryIndex) * wasmPageSize)
+	initialize()
+}
+
 func align(ptr uintptr) uintptr {
 	// Align to 16, which is the alignment of max_align_t:
 	// https://godbolt.org/z/dYqTsWrGq
@@ -1,3 +1,11 @@
+// Package cgo contains runtime support for code generated by the cgo tool.
+//
+// Unlike with the gc toolchain, most of the work is done by the compiler in
+// TinyGo, so this package only contains the Handle type.
+//
+// Exported Go functions can be called from C, but only synchronously: the call
+// must happen on the thread that is running the goroutine that called into C,
+// for example from a callback that C code invokes before returning. Calls from
+// other threads or from interrupt handlers are not supported, because the
+// scheduler can't switch to them.
 package cgo
-
-// dummy
@@ -0,0 +1,58 @@
+package cgo
+
+// Handle provides a way to pass values that contain Go pointers (pointers to
+// memory allocated by Go) between Go and C without breaking the cgo pointer
+// passing rules. A Handle is an integer value that can represent any Go value.
+// It is usually passed to C as the user data of a callback, which is then
+// given back to an exported Go function that calls Value on it.
+//
+// A Handle is only valid until its Delete method is called. Handles are not
+// garbage collected, so Delete must be called once the handle isn't needed
+// anymore to avoid leaking memory.
+//
+// The zero value of a Handle is not valid and can be used as a sentinel in C.
+type Handle uintptr
+
+// The handle table is not protected by a lock: this package is imported by
+// every package that uses cgo (including the device packages), so it can't
+// depend on sync without creating an import cycle. Goroutines are not
+// preempted, so the table is only ever accessed by one goroutine at a time.
+var (
+	handleValues = make(map[Handle]any)
+	handleIndex  Handle
+)
+
+// NewHandle returns a handle for the given value.
+//
+// The handle is valid until the program calls Delete on it. It must not be
+// used from an interrupt, as it allocates memory.
+func NewHandle(v any) Handle {
+	handleIndex++
+	h := handleIndex
+	handleValues[h] = v
+	return h
+}
+
+// Value returns the associated Go value for a valid handle.
+//
+// The method panics if the handle is invalid.
+func (h Handle) Value() any {
+	v, ok := handleValues[h]
+	if !ok {
+		panic("runtime/cgo: misuse of an invalid Handle")
+	}
+	return v
+}
+
+// Delete invalidates a handle. This method should only be called once the
+// program no longer needs to pass the handle to C and the C code no longer
+// has a copy of the handle value.
+//
+// The method panics if the handle is invalid.
+func (h Handle) Delete() {
+	_, ok := handleValues[h]
+	delete(handleValues, h)
+	if !ok {
+		panic("runtime/cgo: misuse of an invalid Handle")
+	}
+}
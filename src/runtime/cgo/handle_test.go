@@ -0,0 +1,57 @@
+package cgo
+
+import "testing"
+
+func TestHandle(t *testing.T) {
+	v := 42
+	for _, value := range []any{v, &v, "string", nil} {
+		h := NewHandle(value)
+		if h == 0 {
+			t.Errorf("NewHandle(%v) returned the zero handle", value)
+		}
+		if got := h.Value(); got != value {
+			t.Errorf("Value() = %v, want %v", got, value)
+		}
+		h.Delete()
+	}
+	if len(handleValues) != 0 {
+		t.Errorf("%d handles were not deleted", len(handleValues))
+	}
+}
+
+func TestHandleUnique(t *testing.T) {
+	// Every call returns a new handle, even for the same value.
+	h1 := NewHandle(1)
+	h2 := NewHandle(1)
+	if h1 == h2 {
+		t.Errorf("got the same handle %d twice", h1)
+	}
+	h1.Delete()
+	if got := h2.Value(); got != 1 {
+		t.Errorf("Value() = %v after deleting another handle, want 1", got)
+	}
+	h2.Delete()
+}
+
+func TestInvalidHandle(t *testing.T) {
+	deleted := NewHandle(42)
+	deleted.Delete()
+	for _, test := range []struct {
+		name string
+		fn   func()
+	}{
+		{"Value of zero handle", func() { Handle(0).Value() }},
+		{"Delete of zero handle", func() { Handle(0).Delete() }},
+		{"Value of deleted handle", func() { deleted.Value() }},
+		{"Delete of deleted handle", func() { deleted.Delete() }},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r != "runtime/cgo: misuse of an invalid Handle" {
+					t.Errorf("unexpected panic: %v", r)
+				}
+			}()
+			test.fn()
+		})
+	}
+}
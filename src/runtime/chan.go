@@ -22,6 +22,15 @@ package runtime
 // the 'comma-ok' value to true.
 // A receive operation on a closed channel is completed by zeroing the data
 // element of the receiving task and setting the 'comma-ok' value to false.
+//
+// Non-blocking channel operations (a send or receive in a select statement
+// with a default case) are safe to use from interrupts: they only run with
+// interrupts disabled and never pause the current task. This is the supported
+// way to pass values from an interrupt handler to a goroutine, usually with a
+// buffered channel. Blocking operations can't be used from interrupts as there
+// is no task to pause. The compiler rejects them directly in interrupt
+// handlers, and they panic at runtime when reached from an interrupt in any
+// other way (for example in a function called by the handler).
 
 import (
 	"internal/task"
@@ -171,6 +180,43 @@ func chanCapUnsafePointer(p unsafe.Pointer) int {
 	return chanCap(c)
 }
 
+// wrappers for use in reflect
+func chanMakeUnsafePointer(elementSize uintptr, bufSize uintptr) unsafe.Pointer {
+	return unsafe.Pointer(chanMake(elementSize, bufSize))
+}
+
+func chanSendUnsafePointer(p unsafe.Pointer, value unsafe.Pointer) {
+	var blockedlist channelBlockedList
+	chanSend((*channel)(p), value, &blockedlist)
+}
+
+func chanRecvUnsafePointer(p unsafe.Pointer, value unsafe.Pointer) bool {
+	var blockedlist channelBlockedList
+	return chanRecv((*channel)(p), value, &blockedlist)
+}
+
+func chanTrySendUnsafePointer(p unsafe.Pointer, value unsafe.Pointer) bool {
+	return (*channel)(p).trySend(value)
+}
+
+func chanTryRecvUnsafePointer(p unsafe.Pointer, value unsafe.Pointer) (received, ok bool) {
+	return (*channel)(p).tryRecv(value)
+}
+
+func chanCloseUnsafePointer(p unsafe.Pointer) {
+	chanClose((*channel)(p))
+}
+
+func chanSelectUnsafePointer(recvbuf unsafe.Pointer, states []chanSelectState, blocking bool) (uintptr, bool) {
+	if !blocking {
+		return tryChanSelect(recvbuf, states)
+	}
+	// The compiler allocates the blocked list for each case on the stack, but
+	// the number of cases isn't known at compile time here.
+	ops := make([]channelBlockedList, len(states))
+	return chanSelect(recvbuf, states, ops)
+}
+
 // resumeRX resumes the next receiver and returns the destination pointer.
 // If the ok value is true, then the caller is expected to store a value into this pointer.
 func (ch *channel) resumeRX(ok bool) unsafe.Pointer {
@@ -196,7 +242,7 @@ func (ch *channel) resumeRX(ok bool) unsafe.Pointer {
 	}
 
 	// push task onto runqueue
-	runqueue.Push(b.t)
+	runqueuePushBack(b.t)
 
 	return dst
 }
@@ -223,7 +269,7 @@ func (ch *channel) resumeTX() unsafe.Pointer {
 	}
 
 	// push task onto runqueue
-	runqueue.Push(b.t)
+	runqueuePushBack(b.t)
 
 	return src
 }
@@ -462,15 +508,22 @@ type chanSelectState struct {
 // This operation will block unless a value is immediately available.
 // May panic if the channel is closed.
 func chanSend(ch *channel, value unsafe.Pointer, blockedlist *channelBlockedList) {
+	racereleasemerge(unsafe.Pointer(ch))
 	i := interrupt.Disable()
 
 	if ch.trySend(value) {
 		// value immediately sent
 		chanDebug(ch)
 		interrupt.Restore(i)
+		raceacquire(unsafe.Pointer(ch))
 		return
 	}
 
+	if interrupt.In() {
+		interrupt.Restore(i)
+		runtimePanic("blocking channel send in interrupt")
+	}
+
 	if ch == nil {
 		// A nil channel blocks forever. Do not schedule this goroutine again.
 		interrupt.Restore(i)
@@ -490,6 +543,7 @@ func chanSend(ch *channel, value unsafe.Pointer, blockedlist *channelBlockedList
 	interrupt.Restore(i)
 	task.Pause()
 	sender.Ptr = nil
+	raceacquire(unsafe.Pointer(ch))
 }
 
 // chanRecv receives a single value over a channel.
@@ -497,15 +551,22 @@ func chanSend(ch *channel, value unsafe.Pointer, blockedlist *channelBlockedList
 // The received value is copied into the value pointer.
 // Returns the comma-ok value.
 func chanRecv(ch *channel, value unsafe.Pointer, blockedlist *channelBlockedList) bool {
+	racereleasemerge(unsafe.Pointer(ch))
 	i := interrupt.Disable()
 
 	if rx, ok := ch.tryRecv(value); rx {
 		// value immediately available
 		chanDebug(ch)
 		interrupt.Restore(i)
+		raceacquire(unsafe.Pointer(ch))
 		return ok
 	}
 
+	if interrupt.In() {
+		interrupt.Restore(i)
+		runtimePanic("blocking channel receive in interrupt")
+	}
+
 	if ch == nil {
 		// A nil channel blocks forever. Do not schedule this goroutine again.
 		interrupt.Restore(i)
@@ -526,6 +587,7 @@ func chanRecv(ch *channel, value unsafe.Pointer, blockedlist *channelBlockedList
 	task.Pause()
 	ok := receiver.Data == 1
 	receiver.Ptr, receiver.Data = nil, 0
+	raceacquire(unsafe.Pointer(ch))
 	return ok
 }
 
@@ -536,6 +598,7 @@ func chanClose(ch *channel) {
 		// Not allowed by the language spec.
 		runtimePanic("close of nil channel")
 	}
+	racereleasemerge(unsafe.Pointer(ch))
 	i := interrupt.Disable()
 	switch ch.state {
 	case chanStateClosed:
@@ -567,8 +630,9 @@ func chanClose(ch *channel) {
 // perhaps the most complicated statement in the Go spec. It returns the
 // selected index and the 'comma-ok' value.
 //
-// TODO: do this in a round-robin fashion (as specified in the Go spec) instead
-// of picking the first one that can proceed.
+// If one or more cases can proceed immediately, one of them is chosen at
+// random as specified in the Go spec. Otherwise, the first case that becomes
+// ready is chosen.
 func chanSelect(recvbuf unsafe.Pointer, states []chanSelectState, ops []channelBlockedList) (uintptr, bool) {
 	istate := interrupt.Disable()
 
@@ -578,6 +642,11 @@ func chanSelect(recvbuf unsafe.Pointer, states []chanSelectState, ops []channelB
 		return selected, ok
 	}
 
+	if interrupt.In() {
+		interrupt.Restore(istate)
+		runtimePanic("blocking select in interrupt")
+	}
+
 	// construct blocked operations
 	for i, v := range states {
 		if v.ch == nil {
@@ -632,32 +701,87 @@ func chanSelect(recvbuf unsafe.Pointer, states []chanSelectState, ops []channelB
 	task.Pause()
 
 	// figure out which one fired and return the ok value
-	return (uintptr(t.Ptr) - uintptr(unsafe.Pointer(&states[0]))) / unsafe.Sizeof(chanSelectState{}), t.Data != 0
+	selected := (uintptr(t.Ptr) - uintptr(unsafe.Pointer(&states[0]))) / unsafe.Sizeof(chanSelectState{})
+	raceacquire(unsafe.Pointer(states[selected].ch))
+	return selected, t.Data != 0
 }
 
 // tryChanSelect is like chanSelect, but it does a non-blocking select operation.
+// When multiple cases can proceed, it chooses one with a uniform pseudo-random
+// selection, so that no case is starved.
 func tryChanSelect(recvbuf unsafe.Pointer, states []chanSelectState) (uintptr, bool) {
+	for _, state := range states {
+		racereleasemerge(unsafe.Pointer(state.ch))
+	}
 	istate := interrupt.Disable()
 
-	// See whether we can receive from one of the channels.
+	// Count the cases that can proceed. Interrupts are disabled, so this
+	// doesn't change until the operation is done.
+	ready := uint32(0)
+	for _, state := range states {
+		if state.ready() {
+			ready++
+		}
+	}
+	if ready == 0 {
+		interrupt.Restore(istate)
+		return ^uintptr(0), false
+	}
+
+	// Do the operation of a randomly chosen case among them.
+	n := fastrand() % ready
 	for i, state := range states {
+		if !state.ready() {
+			continue
+		}
+		if n != 0 {
+			n--
+			continue
+		}
+		ok := true
 		if state.value == nil {
 			// A receive operation.
-			if rx, ok := state.ch.tryRecv(recvbuf); rx {
-				chanDebug(state.ch)
-				interrupt.Restore(istate)
-				return uintptr(i), ok
-			}
+			_, ok = state.ch.tryRecv(recvbuf)
 		} else {
 			// A send operation: state.value is not nil.
-			if state.ch.trySend(state.value) {
-				chanDebug(state.ch)
-				interrupt.Restore(istate)
-				return uintptr(i), true
-			}
+			state.ch.trySend(state.value)
 		}
+		chanDebug(state.ch)
+		interrupt.Restore(istate)
+		raceacquire(unsafe.Pointer(state.ch))
+		return uintptr(i), ok
 	}
 
+	// unreachable
 	interrupt.Restore(istate)
 	return ^uintptr(0), false
 }
+
+// ready returns whether the operation of this select case can proceed without
+// blocking. A send to a closed channel is ready, as it panics when chosen.
+// This must be called with interrupts disabled.
+func (s chanSelectState) ready() bool {
+	ch := s.ch
+	if ch == nil {
+		// Operations on a nil channel block forever.
+		return false
+	}
+	if s.value == nil {
+		// A receive operation.
+		switch ch.state {
+		case chanStateBuf, chanStateSend:
+			return ch.bufUsed != 0 || ch.blocked != nil
+		case chanStateClosed:
+			return true
+		}
+		return false
+	}
+	// A send operation.
+	switch ch.state {
+	case chanStateEmpty, chanStateBuf:
+		return ch.bufUsed < ch.bufSize
+	case chanStateRecv, chanStateClosed:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package runtime
+
+// This file keeps track of the code coverage counters that the compiler adds
+// to the package under test with tinygo test -cover. See compiler/coverage.go
+// for how the blocks are found.
+
+import _ "unsafe"
+
+// coverBlock is a range of statements in the source code that is always
+// executed together. The layout must match the one used in the compiler.
+type coverBlock struct {
+	file      string
+	startLine uint32
+	startCol  uint32
+	endLine   uint32
+	endCol    uint32
+	numStmt   uint32
+	count     uint32
+}
+
+// The coverage blocks of every instrumented package. This is filled in by the
+// compiler.
+//
+//go:registry
+var coverBlocks [][]coverBlock
+
+// The coverage mode: "set", "count", or the empty string if coverage is not
+// enabled. Set by the compiler when coverage is enabled.
+var coverMode string
+
+// coverMarker is referred to by the statements that the compiler inserts at
+// the start of every coverage block, to find where the counters must be
+// incremented. It is never called.
+func coverMarker() {}
+
+// testing_readCoverage calls fn for every coverage block in the program and
+// returns the coverage mode.
+//
+//go:linkname testing_readCoverage testing.readCoverage
+func testing_readCoverage(fn func(file string, startLine, startCol, endLine, endCol, numStmt, count uint32)) string {
+	for _, blocks := range coverBlocks {
+		for i := range blocks {
+			b := &blocks[i]
+			fn(b.file, b.startLine, b.startCol, b.endLine, b.endCol, b.numStmt, b.count)
+		}
+	}
+	return coverMode
+}
@@ -1,6 +1,8 @@
 // Package debug is a dummy package that is not yet implemented.
 package debug
 
+import "time"
+
 // SetMaxStack sets the maximum amount of memory that can be used by a single
 // goroutine stack.
 //
@@ -58,3 +60,36 @@ type Module struct {
 func SetGCPercent(n int) int {
 	return n
 }
+
+// LatencyStats holds the worst case scheduler latencies since the program
+// started or since the last call to ResetLatencyStats.
+//
+// These statistics are only recorded when the program is built with
+// -tags=runtime_latency. Otherwise, all values are zero.
+type LatencyStats struct {
+	// MaxDispatch is the longest time a goroutine was runnable (or should
+	// have woken up from a sleep) before the scheduler ran it.
+	MaxDispatch time.Duration
+
+	// MaxInterrupt is the longest time between an interrupt handler making a
+	// goroutine runnable (for example by sending on a channel) and the
+	// scheduler running that goroutine.
+	MaxInterrupt time.Duration
+}
+
+// ReadLatencyStats reads the scheduler latency statistics into stats.
+func ReadLatencyStats(stats *LatencyStats) {
+	dispatch, interrupt := readLatencyStats()
+	stats.MaxDispatch = time.Duration(dispatch)
+	stats.MaxInterrupt = time.Duration(interrupt)
+}
+
+// ResetLatencyStats resets the scheduler latency statistics, for example after
+// the program has finished initializing.
+func ResetLatencyStats() {
+	resetLatencyStats()
+}
+
+// Implemented in the runtime.
+func readLatencyStats() (dispatch, interrupt int64)
+func resetLatencyStats()
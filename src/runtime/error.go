@@ -6,3 +6,18 @@ type Error interface {
 
 	RuntimeError()
 }
+
+// errorString is a run time error with a fixed message.
+type errorString string
+
+func (e errorString) RuntimeError() {}
+
+func (e errorString) Error() string {
+	return "runtime error: " + string(e)
+}
+
+// Errors that math/bits panics with, which it refers to using //go:linkname.
+var (
+	overflowError = error(errorString("integer overflow"))
+	divideError   = error(errorString("integer divide by zero"))
+)
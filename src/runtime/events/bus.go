@@ -0,0 +1,99 @@
+package events
+
+import (
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// Bus delivers every published event to all of its subscribers. Each
+// subscriber has its own queue, so a slow subscriber only drops its own events
+// and doesn't hold up the others. Like a Queue, a Bus has a single producer:
+// events must be published from only one interrupt handler (or goroutine).
+// Subscribing and unsubscribing may happen at any time from any goroutine.
+type Bus[T any] struct {
+	lock sync.Mutex     // serializes Subscribe and Unsubscribe
+	subs unsafe.Pointer // *Subscription[T], the first subscriber
+}
+
+// Subscription is a subscriber of a Bus. The events published on the bus must
+// be received by a single goroutine.
+type Subscription[T any] struct {
+	queue *Queue[T]
+	bus   *Bus[T]
+	next  unsafe.Pointer // *Subscription[T]
+}
+
+// Subscribe adds a subscriber that can hold size events that have been
+// published but not yet received. Events that were published before Subscribe
+// returned are not delivered to it.
+func (b *Bus[T]) Subscribe(size int) *Subscription[T] {
+	s := &Subscription[T]{
+		queue: New[T](size),
+		bus:   b,
+	}
+	b.lock.Lock()
+	s.next = atomic.LoadPointer(&b.subs)
+	// The subscription is complete before it becomes visible to Publish.
+	atomic.StorePointer(&b.subs, unsafe.Pointer(s))
+	b.lock.Unlock()
+	return s
+}
+
+// Unsubscribe removes the subscriber from the bus. A Publish call that is
+// running at the same time (in an interrupt) may still deliver one last event
+// to it.
+func (s *Subscription[T]) Unsubscribe() {
+	b := s.bus
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	link := &b.subs
+	for p := atomic.LoadPointer(link); p != nil; p = atomic.LoadPointer(link) {
+		if p == unsafe.Pointer(s) {
+			// Leave s.next as it is, so that a Publish call that is
+			// currently at s still reaches the subscribers after it.
+			atomic.StorePointer(link, atomic.LoadPointer(&s.next))
+			return
+		}
+		link = &(*Subscription[T])(p).next
+	}
+}
+
+// Publish adds the event to the queue of every subscriber and wakes up the
+// goroutines waiting for it. It never blocks or allocates, so it can be called
+// from an interrupt. It returns false if the queue of at least one subscriber
+// was full, in which case that subscriber misses the event.
+func (b *Bus[T]) Publish(event T) bool {
+	ok := true
+	for p := atomic.LoadPointer(&b.subs); p != nil; {
+		s := (*Subscription[T])(p)
+		if !s.queue.Publish(event) {
+			ok = false
+		}
+		p = atomic.LoadPointer(&s.next)
+	}
+	return ok
+}
+
+// Receive returns the oldest event for this subscriber, waiting for one to be
+// published if there is none. See Queue.Receive.
+func (s *Subscription[T]) Receive() T {
+	return s.queue.Receive()
+}
+
+// TryReceive returns the oldest event for this subscriber, if there is one,
+// without blocking.
+func (s *Subscription[T]) TryReceive() (event T, ok bool) {
+	return s.queue.TryReceive()
+}
+
+// Len returns the number of events that are waiting to be received.
+func (s *Subscription[T]) Len() int {
+	return s.queue.Len()
+}
+
+// Dropped returns the number of events this subscriber missed because its
+// queue was full.
+func (s *Subscription[T]) Dropped() uint32 {
+	return s.queue.Dropped()
+}
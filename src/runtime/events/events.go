@@ -0,0 +1,133 @@
+// Package events provides a queue and a publish/subscribe bus to pass events
+// from an interrupt handler to goroutines.
+//
+// An interrupt handler can also send on a buffered channel, as long as it uses
+// a non-blocking send (a select statement with a default case): blocking
+// channel operations can't be used from interrupts, see the runtime channel
+// implementation. A Queue is an alternative for when that is too costly or
+// dropped events need to be counted. It is a fixed-size ring buffer that an
+// interrupt can publish to without disabling interrupts, allocating or
+// blocking, while a goroutine waits for the events:
+//
+//	var buttons = events.New[machine.Pin](8)
+//
+//	func main() {
+//		button.SetInterrupt(machine.PinFalling, func(p machine.Pin) {
+//			buttons.Publish(p)
+//		})
+//		for {
+//			pin := buttons.Receive()
+//			println("pressed:", pin)
+//		}
+//	}
+//
+// A Queue has a single producer and a single consumer: events must be
+// published from only one interrupt handler (or goroutine) and received by
+// only one goroutine. When several goroutines need the same events, publish
+// them on a Bus instead, which gives every subscriber its own queue:
+//
+//	var buttons events.Bus[machine.Pin]
+//
+//	func logButtons() {
+//		sub := buttons.Subscribe(8)
+//		defer sub.Unsubscribe()
+//		for {
+//			println("pressed:", sub.Receive())
+//		}
+//	}
+package events
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+// Queue is a single-producer, single-consumer queue of events of type T.
+type Queue[T any] struct {
+	buf     []T
+	mask    uint32
+	head    uint32 // number of published events, only written by the producer
+	tail    uint32 // number of received events, only written by the consumer
+	dropped uint32
+	cond    runtime.Cond
+}
+
+// New returns a new queue that can hold size events that have been published
+// but not yet received. The size is rounded up to a power of two.
+func New[T any](size int) *Queue[T] {
+	if size <= 0 {
+		panic("events: queue size must be positive")
+	}
+	n := 1
+	for n < size {
+		n <<= 1
+	}
+	return &Queue[T]{
+		buf:  make([]T, n),
+		mask: uint32(n - 1),
+	}
+}
+
+// Publish adds an event to the queue and wakes up the goroutine waiting in
+// Receive, if any. It never blocks or allocates, so it can be called from an
+// interrupt. If the queue is full the event is dropped and Publish returns
+// false.
+func (q *Queue[T]) Publish(event T) bool {
+	head := atomic.LoadUint32(&q.head)
+	if head-atomic.LoadUint32(&q.tail) > q.mask {
+		atomic.AddUint32(&q.dropped, 1)
+		return false
+	}
+	q.buf[head&q.mask] = event
+	// Only make the event visible to the consumer once it has been written.
+	atomic.StoreUint32(&q.head, head+1)
+	q.cond.Notify()
+	return true
+}
+
+// TryReceive returns the oldest event in the queue, if there is one, without
+// blocking.
+func (q *Queue[T]) TryReceive() (event T, ok bool) {
+	tail := atomic.LoadUint32(&q.tail)
+	if atomic.LoadUint32(&q.head) == tail {
+		return event, false
+	}
+	event = q.buf[tail&q.mask]
+	// Clear the slot so that the queue doesn't keep pointers alive.
+	var zero T
+	q.buf[tail&q.mask] = zero
+	atomic.StoreUint32(&q.tail, tail+1)
+	return event, true
+}
+
+// Receive returns the oldest event in the queue, waiting for one to be
+// published if the queue is empty. Other goroutines keep running while it
+// waits, and when there is nothing else to do the CPU sleeps until the next
+// interrupt.
+func (q *Queue[T]) Receive() T {
+	for {
+		if event, ok := q.TryReceive(); ok {
+			return event
+		}
+		// A notification from an event that was already received above
+		// makes Wait return immediately, so this loops at most once more
+		// than needed.
+		q.cond.Wait()
+	}
+}
+
+// Len returns the number of events in the queue.
+func (q *Queue[T]) Len() int {
+	return int(atomic.LoadUint32(&q.head) - atomic.LoadUint32(&q.tail))
+}
+
+// Cap returns the number of events the queue can hold.
+func (q *Queue[T]) Cap() int {
+	return len(q.buf)
+}
+
+// Dropped returns the number of events that were dropped by Publish because
+// the queue was full.
+func (q *Queue[T]) Dropped() uint32 {
+	return atomic.LoadUint32(&q.dropped)
+}
@@ -0,0 +1,102 @@
+package events_test
+
+import (
+	"runtime"
+	"runtime/events"
+	"testing"
+)
+
+func TestQueue(t *testing.T) {
+	q := events.New[int](3)
+	if q.Cap() != 4 {
+		t.Errorf("Cap: got %d, want 4", q.Cap())
+	}
+	for i := 0; i < 5; i++ {
+		if ok := q.Publish(i); ok != (i < 4) {
+			t.Errorf("Publish(%d): got %v, want %v", i, ok, i < 4)
+		}
+	}
+	if q.Len() != 4 || q.Dropped() != 1 {
+		t.Errorf("got Len %d and Dropped %d, want 4 and 1", q.Len(), q.Dropped())
+	}
+	for i := 0; i < 4; i++ {
+		if event, ok := q.TryReceive(); !ok || event != i {
+			t.Errorf("TryReceive: got %d, %v, want %d, true", event, ok, i)
+		}
+	}
+	if event, ok := q.TryReceive(); ok {
+		t.Errorf("TryReceive on an empty queue: got %d", event)
+	}
+
+	// The queue wraps around.
+	for i := 0; i < 10; i++ {
+		q.Publish(i)
+		if event := q.Receive(); event != i {
+			t.Errorf("Receive: got %d, want %d", event, i)
+		}
+	}
+}
+
+func TestQueueReceiveWaits(t *testing.T) {
+	q := events.New[int](4)
+	go func() {
+		for i := 0; i < 10; i++ {
+			for !q.Publish(i) {
+				// Let the consumer make room.
+				runtime.Gosched()
+			}
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		if event := q.Receive(); event != i {
+			t.Errorf("Receive: got %d, want %d", event, i)
+		}
+	}
+}
+
+func TestBus(t *testing.T) {
+	var bus events.Bus[int]
+	if !bus.Publish(0) {
+		t.Error("Publish without subscribers failed")
+	}
+
+	a := bus.Subscribe(4)
+	b := bus.Subscribe(2)
+	for i := 1; i <= 3; i++ {
+		if ok := bus.Publish(i); ok != (i <= 2) {
+			t.Errorf("Publish(%d): got %v, want %v", i, ok, i <= 2)
+		}
+	}
+	for i := 1; i <= 3; i++ {
+		if event := a.Receive(); event != i {
+			t.Errorf("subscriber a: got %d, want %d", event, i)
+		}
+	}
+	for i := 1; i <= 2; i++ {
+		if event := b.Receive(); event != i {
+			t.Errorf("subscriber b: got %d, want %d", event, i)
+		}
+	}
+	if a.Dropped() != 0 || b.Dropped() != 1 {
+		t.Errorf("got Dropped %d and %d, want 0 and 1", a.Dropped(), b.Dropped())
+	}
+
+	// An unsubscribed subscriber doesn't receive events, the others still do.
+	c := bus.Subscribe(2)
+	b.Unsubscribe()
+	bus.Publish(4)
+	if event, ok := b.TryReceive(); ok {
+		t.Errorf("unsubscribed subscriber received %d", event)
+	}
+	for _, s := range []*events.Subscription[int]{a, c} {
+		if event, ok := s.TryReceive(); !ok || event != 4 {
+			t.Errorf("TryReceive: got %d, %v, want 4, true", event, ok)
+		}
+	}
+	a.Unsubscribe()
+	c.Unsubscribe()
+	bus.Publish(5)
+	if a.Len() != 0 || c.Len() != 0 {
+		t.Error("events were delivered after all subscribers left")
+	}
+}
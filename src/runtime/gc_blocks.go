@@ -283,6 +283,9 @@ func alloc(size uintptr, layout unsafe.Pointer) unsafe.Pointer {
 
 	gcTotalAlloc += uint64(size)
 	gcMallocs++
+	if MemProfileRate > 0 {
+		memProfileAlloc(size)
+	}
 
 	neededBlocks := (size + (bytesPerBlock - 1)) / bytesPerBlock
 
@@ -374,6 +377,7 @@ func alloc(size uintptr, layout unsafe.Pointer) unsafe.Pointer {
 				size -= add
 			}
 			memzero(pointer, size)
+			racemalloc(pointer, size)
 			return pointer
 		}
 	}
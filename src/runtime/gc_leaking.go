@@ -31,6 +31,10 @@ func alloc(size uintptr, layout unsafe.Pointer) unsafe.Pointer {
 	// much. And by using platform-native data types (e.g. *uint8 for 8-bit
 	// systems).
 	size = align(size)
+	if MemProfileRate > 0 {
+		// This may allocate memory itself, so do it before claiming memory.
+		memProfileAlloc(size)
+	}
 	addr := heapptr
 	gcTotalAlloc += uint64(size)
 	gcMallocs++
@@ -41,6 +41,8 @@ type hashmapBucket struct {
 	// allocated but as they're of variable size they can't be shown here.
 }
 
+// The layout of hashmapIterator is mirrored by reflect.hiter, so it must be
+// kept in sync.
 type hashmapIterator struct {
 	buckets      unsafe.Pointer // pointer to array of hashapBuckets
 	numBuckets   uintptr        // length of buckets array
@@ -65,6 +67,21 @@ func hashmapTopHash(hash uint32) uint8 {
 
 // Create a new hashmap with the given keySize and valueSize.
 func hashmapMake(keySize, valueSize uintptr, sizeHint uintptr, alg uint8) *hashmap {
+	keyHash := hashmapKeyHashAlg(hashmapAlgorithm(alg))
+	keyEqual := hashmapKeyEqualAlg(hashmapAlgorithm(alg))
+	return hashmapMakeFunc(keySize, valueSize, sizeHint, keyHash, keyEqual)
+}
+
+func hashmapMakeUnsafePointer(keySize, valueSize uintptr, sizeHint uintptr, alg uint8) unsafe.Pointer {
+	return (unsafe.Pointer)(hashmapMake(keySize, valueSize, sizeHint, alg))
+}
+
+// Create a new hashmap that hashes and compares keys with the given functions.
+// The compiler generates these functions for key types that can't be hashed as
+// a string or as plain binary data, like floats and structs with string
+// fields. Maps created this way are accessed with the hashmapGeneric*
+// functions.
+func hashmapMakeFunc(keySize, valueSize uintptr, sizeHint uintptr, keyHash func(key unsafe.Pointer, size, seed uintptr) uint32, keyEqual func(x, y unsafe.Pointer, n uintptr) bool) *hashmap {
 	bucketBits := uint8(0)
 	for hashmapHasSpaceToGrow(bucketBits) && hashmapOverLoadFactor(sizeHint, bucketBits) {
 		bucketBits++
@@ -73,9 +90,6 @@ func hashmapMake(keySize, valueSize uintptr, sizeHint uintptr, alg uint8) *hashm
 	bucketBufSize := unsafe.Sizeof(hashmapBucket{}) + keySize*8 + valueSize*8
 	buckets := alloc(bucketBufSize*(1<<bucketBits), nil)
 
-	keyHash := hashmapKeyHashAlg(hashmapAlgorithm(alg))
-	keyEqual := hashmapKeyEqualAlg(hashmapAlgorithm(alg))
-
 	return &hashmap{
 		buckets:    buckets,
 		seed:       uintptr(fastrand()),
@@ -87,8 +101,8 @@ func hashmapMake(keySize, valueSize uintptr, sizeHint uintptr, alg uint8) *hashm
 	}
 }
 
-func hashmapMakeUnsafePointer(keySize, valueSize uintptr, sizeHint uintptr, alg uint8) unsafe.Pointer {
-	return (unsafe.Pointer)(hashmapMake(keySize, valueSize, sizeHint, alg))
+func hashmapMakeFuncUnsafePointer(keySize, valueSize uintptr, sizeHint uintptr, keyHash func(key unsafe.Pointer, size, seed uintptr) uint32, keyEqual func(x, y unsafe.Pointer, n uintptr) bool) unsafe.Pointer {
+	return (unsafe.Pointer)(hashmapMakeFunc(keySize, valueSize, sizeHint, keyHash, keyEqual))
 }
 
 func hashmapKeyEqualAlg(alg hashmapAlgorithm) func(x, y unsafe.Pointer, n uintptr) bool {
@@ -522,7 +536,76 @@ func hashmapStringDeleteUnsafePointer(m unsafe.Pointer, key string) {
 	hashmapStringDelete((*hashmap)(m), key)
 }
 
-// Hashmap with interface keys (for everything else).
+// Hashmap with keys that are hashed and compared with the functions passed to
+// hashmapMakeFunc. Like with binary keys, padding in the key must be zero.
+
+func hashmapGenericSet(m *hashmap, key, value unsafe.Pointer) {
+	if m == nil {
+		nilMapPanic()
+	}
+	hash := m.keyHash(key, m.keySize, m.seed)
+	hashmapSet(m, key, value, hash)
+}
+
+func hashmapGenericSetUnsafePointer(m unsafe.Pointer, key, value unsafe.Pointer) {
+	hashmapGenericSet((*hashmap)(m), key, value)
+}
+
+func hashmapGenericGet(m *hashmap, key, value unsafe.Pointer, valueSize uintptr) bool {
+	if m == nil {
+		memzero(value, uintptr(valueSize))
+		return false
+	}
+	hash := m.keyHash(key, m.keySize, m.seed)
+	return hashmapGet(m, key, value, valueSize, hash)
+}
+
+func hashmapGenericGetUnsafePointer(m unsafe.Pointer, key, value unsafe.Pointer, valueSize uintptr) bool {
+	return hashmapGenericGet((*hashmap)(m), key, value, valueSize)
+}
+
+func hashmapGenericDelete(m *hashmap, key unsafe.Pointer) {
+	if m == nil {
+		return
+	}
+	hash := m.keyHash(key, m.keySize, m.seed)
+	hashmapDelete(m, key, hash)
+}
+
+func hashmapGenericDeleteUnsafePointer(m unsafe.Pointer, key unsafe.Pointer) {
+	hashmapGenericDelete((*hashmap)(m), key)
+}
+
+// Hash and equality functions for floating point and complex numbers in
+// hashmapMakeFunc maps, either as the key itself or as part of a struct or
+// array key. The size is used to distinguish between float32 and float64 (or
+// complex64 and complex128).
+
+func hashmapFloatPtrHash(ptr unsafe.Pointer, size, seed uintptr) uint32 {
+	if size == 4 {
+		return hashmapFloat32Hash(ptr, seed)
+	}
+	return hashmapFloat64Hash(ptr, seed)
+}
+
+func hashmapFloatEqual(x, y unsafe.Pointer, n uintptr) bool {
+	if n == 4 {
+		return *(*float32)(x) == *(*float32)(y)
+	}
+	return *(*float64)(x) == *(*float64)(y)
+}
+
+func hashmapComplexPtrHash(ptr unsafe.Pointer, size, seed uintptr) uint32 {
+	half := size / 2
+	return hashmapCombineHash(hashmapFloatPtrHash(ptr, half, seed), hashmapFloatPtrHash(unsafe.Add(ptr, half), half, seed))
+}
+
+func hashmapComplexEqual(x, y unsafe.Pointer, n uintptr) bool {
+	half := n / 2
+	return hashmapFloatEqual(x, y, half) && hashmapFloatEqual(unsafe.Add(x, half), unsafe.Add(y, half), half)
+}
+
+// Hashmap with interface keys.
 
 // This is a method that is intentionally unexported in the reflect package. It
 // is identical to the Interface() method call, except it doesn't check whether
@@ -533,6 +616,13 @@ func hashmapStringDeleteUnsafePointer(m unsafe.Pointer, key string) {
 func valueInterfaceUnsafe(v reflect.Value) interface{}
 
 func hashmapFloat32Hash(ptr unsafe.Pointer, seed uintptr) uint32 {
+	if v := *(*float32)(ptr); v != v {
+		// NaN != NaN, so a NaN key can never be found again. Return a random
+		// hash so that lots of NaN keys don't all end up in the same bucket.
+		// This matches the behavior of the gc toolchain, see:
+		// https://research.swtch.com/randhash
+		return fastrand()
+	}
 	f := *(*uint32)(ptr)
 	if f == 0x80000000 {
 		// convert -0 to 0 for hashing
@@ -542,6 +632,10 @@ func hashmapFloat32Hash(ptr unsafe.Pointer, seed uintptr) uint32 {
 }
 
 func hashmapFloat64Hash(ptr unsafe.Pointer, seed uintptr) uint32 {
+	if v := *(*float64)(ptr); v != v {
+		// NaN, see hashmapFloat32Hash.
+		return fastrand()
+	}
 	f := *(*uint64)(ptr)
 	if f == 0x8000000000000000 {
 		// convert -0 to 0 for hashing
@@ -550,6 +644,14 @@ func hashmapFloat64Hash(ptr unsafe.Pointer, seed uintptr) uint32 {
 	return hash32(unsafe.Pointer(&f), 8, seed)
 }
 
+// hashmapCombineHash mixes the hash of a single array element or struct field
+// into the hash of the whole value. Unlike a plain XOR this depends on the
+// order of the elements, so that for example [2]int{1, 2} and [2]int{2, 1} or
+// [2]int{1, 1} and [2]int{2, 2} don't all result in the same hash.
+func hashmapCombineHash(hash, elem uint32) uint32 {
+	return (hash<<5 | hash>>27) ^ elem
+}
+
 func hashmapInterfaceHash(itf interface{}, seed uintptr) uint32 {
 	x := reflect.ValueOf(itf)
 	if x.RawType() == nil {
@@ -569,11 +671,6 @@ func hashmapInterfaceHash(itf interface{}, seed uintptr) uint32 {
 	case reflect.Bool, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
 		return hash32(ptr, x.RawType().Size(), seed)
 	case reflect.Float32:
-		// It should be possible to just has the contents. However, NaN != NaN
-		// so if you're using lots of NaNs as map keys (you shouldn't) then hash
-		// time may become exponential. To fix that, it would be better to
-		// return a random number instead:
-		// https://research.swtch.com/randhash
 		return hashmapFloat32Hash(ptr, seed)
 	case reflect.Float64:
 		return hashmapFloat64Hash(ptr, seed)
@@ -593,17 +690,23 @@ func hashmapInterfaceHash(itf interface{}, seed uintptr) uint32 {
 	case reflect.Array:
 		var hash uint32
 		for i := 0; i < x.Len(); i++ {
-			hash ^= hashmapInterfaceHash(valueInterfaceUnsafe(x.Index(i)), seed)
+			hash = hashmapCombineHash(hash, hashmapInterfaceHash(valueInterfaceUnsafe(x.Index(i)), seed))
 		}
 		return hash
 	case reflect.Struct:
 		var hash uint32
 		for i := 0; i < x.NumField(); i++ {
-			hash ^= hashmapInterfaceHash(valueInterfaceUnsafe(x.Field(i)), seed)
+			if x.Type().Field(i).Name == "_" {
+				// Blank fields are ignored in comparisons, so they must also be
+				// ignored while hashing.
+				continue
+			}
+			hash = hashmapCombineHash(hash, hashmapInterfaceHash(valueInterfaceUnsafe(x.Field(i)), seed))
 		}
 		return hash
 	default:
-		runtimePanic("comparing un-comparable type")
+		// Slices, maps and funcs are not comparable, so can't be hashed.
+		runtimePanic("hash of unhashable type")
 		return 0 // unreachable
 	}
 }
@@ -69,6 +69,11 @@ func reflectValueEqual(x, y reflect.Value) bool {
 		return true
 	case reflect.Struct:
 		for i := 0; i < x.NumField(); i++ {
+			if x.Type().Field(i).Name == "_" {
+				// Blank fields are not compared, see:
+				// https://go.dev/ref/spec#Comparison_operators
+				continue
+			}
 			if !reflectValueEqual(x.Field(i), y.Field(i)) {
 				return false
 			}
@@ -77,7 +82,9 @@ func reflectValueEqual(x, y reflect.Value) bool {
 	case reflect.Interface:
 		return reflectValueEqual(x.Elem(), y.Elem())
 	default:
-		runtimePanic("comparing un-comparable type")
+		// Slices, maps and funcs can only be compared against nil, which is
+		// handled by the compiler. Comparing them inside an interface panics.
+		runtimePanic("comparing uncomparable type")
 		return false // unreachable
 	}
 }
@@ -0,0 +1,45 @@
+//go:build runtime_latency
+
+package runtime
+
+// This file implements the scheduler latency statistics that can be read with
+// debug.ReadLatencyStats. They are only recorded when building with
+// -tags=runtime_latency, as they add some overhead to every scheduler
+// operation.
+
+import "internal/task"
+
+const latencyStats = true
+
+var (
+	// Longest time between a goroutine being made runnable and it running.
+	maxDispatchLatency timeUnit
+
+	// Like maxDispatchLatency, but for goroutines that were made runnable by
+	// an interrupt handler (for example by sending on a channel).
+	maxInterruptLatency timeUnit
+)
+
+// recordDispatchLatency is called by the scheduler just before it resumes the
+// given task.
+func recordDispatchLatency(t *task.Task) {
+	since, inInterrupt := t.Runnable()
+	latency := ticks() - timeUnit(since)
+	if latency > maxDispatchLatency {
+		maxDispatchLatency = latency
+	}
+	if inInterrupt && latency > maxInterruptLatency {
+		maxInterruptLatency = latency
+	}
+}
+
+//go:linkname debug_readLatencyStats runtime/debug.readLatencyStats
+func debug_readLatencyStats() (dispatch, interrupt int64) {
+	return ticksToNanoseconds(maxDispatchLatency), ticksToNanoseconds(maxInterruptLatency)
+}
+
+//go:linkname debug_resetLatencyStats runtime/debug.resetLatencyStats
+func debug_resetLatencyStats() {
+	maxDispatchLatency = 0
+	maxInterruptLatency = 0
+}
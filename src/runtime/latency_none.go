@@ -0,0 +1,19 @@
+//go:build !runtime_latency
+
+package runtime
+
+import "internal/task"
+
+const latencyStats = false
+
+func recordDispatchLatency(t *task.Task) {
+}
+
+//go:linkname debug_readLatencyStats runtime/debug.readLatencyStats
+func debug_readLatencyStats() (dispatch, interrupt int64) {
+	return 0, 0
+}
+
+//go:linkname debug_resetLatencyStats runtime/debug.resetLatencyStats
+func debug_resetLatencyStats() {
+}
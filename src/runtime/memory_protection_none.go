@@ -0,0 +1,7 @@
+//go:build (cortexm || tinygo.riscv32) && !memory_protection
+
+package runtime
+
+// initMemoryProtection is a no-op: memory protection is only configured when
+// enabled in the target with "memory-protection": true.
+func initMemoryProtection() {}
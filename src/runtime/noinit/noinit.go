@@ -0,0 +1,92 @@
+// Package noinit provides checksummed storage for values that must survive a
+// soft reset, like a reboot reason or a short crash log.
+//
+// Globals placed in the .noinit section are not zeroed by the startup code, so
+// they keep their value across a reset (a watchdog reset, a reset from the
+// reset pin, or a call to machine.CPUReset). After a power cycle however they
+// contain random data. A Var stores a checksum next to the value to tell these
+// cases apart:
+//
+//	//go:section .noinit
+//	var bootReason noinit.Var[uint32]
+//
+//	func main() {
+//		if reason, ok := bootReason.Load(); ok {
+//			println("rebooted because of", reason)
+//		}
+//		bootReason.Store(reasonWatchdog)
+//		// ...
+//	}
+//
+// Values must not contain pointers into the heap, as the heap doesn't survive a
+// reset and the garbage collector doesn't scan the .noinit section. The
+// .noinit section is available on ARM and RISC-V baremetal targets.
+package noinit
+
+import "unsafe"
+
+// magic is stored in each Var, so that a Var that only contains zeroes (which
+// would otherwise have a valid checksum) is not seen as valid.
+const magic = 0x6e6f_696e // "noin"
+
+// Var is a value of type T that is protected by a checksum. It should be
+// declared as a global in the .noinit section. The zero value is a Var that
+// doesn't hold a valid value.
+type Var[T any] struct {
+	magic    uint32
+	checksum uint32
+	value    T
+}
+
+// Load returns the stored value, and whether it is valid. The value is not
+// valid after a power cycle, when it was never stored, or when it was
+// corrupted. In that case the zero value of T is returned.
+func (v *Var[T]) Load() (value T, ok bool) {
+	if v.magic != magic || v.checksum != v.sum() {
+		return value, false
+	}
+	return v.value, true
+}
+
+// Store stores the value and updates the checksum, so that it can be loaded
+// after a reset.
+func (v *Var[T]) Store(value T) {
+	v.value = value
+	v.checksum = v.sum()
+	v.magic = magic
+}
+
+// Update loads the current value (or the zero value if it isn't valid),
+// passes it to f, and stores the value returned by f. It can for example be
+// used to count resets.
+func (v *Var[T]) Update(f func(value T) T) {
+	value, _ := v.Load()
+	v.Store(f(value))
+}
+
+// Clear invalidates the stored value, so that Load returns false until a new
+// value is stored.
+func (v *Var[T]) Clear() {
+	v.magic = 0
+	var zero T
+	v.value = zero
+	v.checksum = 0
+}
+
+// sum returns the CRC-32 (IEEE) checksum of the stored value. It is computed
+// bit by bit to avoid a lookup table, as the values are usually small.
+func (v *Var[T]) sum() uint32 {
+	buf := unsafe.Slice((*byte)(unsafe.Pointer(&v.value)), unsafe.Sizeof(v.value))
+	crc := ^uint32(0)
+	for _, b := range buf {
+		crc ^= uint32(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = (crc >> 1) ^ 0xedb88320
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return ^crc
+}
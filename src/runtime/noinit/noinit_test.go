@@ -0,0 +1,60 @@
+package noinit
+
+import (
+	"hash/crc32"
+	"testing"
+	"unsafe"
+)
+
+type bootLog struct {
+	Reason uint8
+	Count  uint16
+	PC     uint32
+}
+
+func TestVar(t *testing.T) {
+	// The zero value (and random data after a power cycle) is not valid.
+	var v Var[bootLog]
+	if value, ok := v.Load(); ok || value != (bootLog{}) {
+		t.Errorf("Load of zero Var: got %v, %v", value, ok)
+	}
+
+	want := bootLog{Reason: 3, Count: 1, PC: 0x1234}
+	v.Store(want)
+	if value, ok := v.Load(); !ok || value != want {
+		t.Errorf("Load after Store: got %v, %v, want %v, true", value, ok, want)
+	}
+
+	// A corrupted value is detected.
+	v.value.PC ^= 0x100
+	if value, ok := v.Load(); ok || value != (bootLog{}) {
+		t.Errorf("Load of corrupted Var: got %v, %v", value, ok)
+	}
+
+	v.Store(want)
+	v.Clear()
+	if _, ok := v.Load(); ok {
+		t.Errorf("Load after Clear: got valid value")
+	}
+}
+
+func TestVarUpdate(t *testing.T) {
+	var count Var[uint32]
+	for i := 0; i < 3; i++ {
+		count.Update(func(n uint32) uint32 {
+			return n + 1
+		})
+	}
+	if n, ok := count.Load(); !ok || n != 3 {
+		t.Errorf("Update: got %d, %v, want 3, true", n, ok)
+	}
+}
+
+func TestVarChecksum(t *testing.T) {
+	var v Var[[5]byte]
+	v.Store([5]byte{'h', 'e', 'l', 'l', 'o'})
+	buf := unsafe.Slice((*byte)(unsafe.Pointer(&v.value)), len(v.value))
+	if got, want := v.checksum, crc32.ChecksumIEEE(buf); got != want {
+		t.Errorf("checksum: got %#x, want %#x", got, want)
+	}
+}
@@ -42,6 +42,7 @@ func _panic(message interface{}) {
 		if frame != nil {
 			frame.PanicValue = message
 			frame.Panicking = true
+			capturePanicTrace()
 			tinygo_longjmp(frame)
 			// unreachable
 		}
@@ -49,6 +50,7 @@ func _panic(message interface{}) {
 	printstring("panic: ")
 	printitf(message)
 	printnl()
+	printPanicTrace()
 	abort()
 }
 
@@ -68,6 +70,7 @@ func runtimePanicAt(addr unsafe.Pointer, msg string) {
 		printstring("panic: runtime error: ")
 	}
 	println(msg)
+	printPanicTrace()
 	abort()
 }
 
@@ -123,6 +126,7 @@ func _recover(useParentFrame bool) interface{} {
 		// Only the first call to recover returns the panic value. It also stops
 		// the panicking sequence, hence setting panicking to false.
 		frame.Panicking = false
+		clearPanicTrace()
 		return frame.PanicValue
 	}
 	// Not panicking, so return a nil interface.
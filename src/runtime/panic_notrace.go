@@ -0,0 +1,21 @@
+//go:build !panic_trace
+
+package runtime
+
+// Stack traces are only available with -panic=trace, see panic_trace.go.
+
+func capturePanicTrace() {}
+
+func clearPanicTrace() {}
+
+func printPanicTrace() {}
+
+// Without stack traces, profiles contain no stacks.
+
+func profileStack(stack *[profileMaxDepth]uintptr) int {
+	return 0
+}
+
+func profileLocation(pc uintptr) (function, file string, line int) {
+	return "", "", 0
+}
@@ -0,0 +1,156 @@
+//go:build panic_trace
+
+package runtime
+
+// This file implements stack traces for -panic=trace. Every function compiled
+// in this mode allocates a traceFrame on the stack and links it into a list of
+// frames of the current goroutine. Before each call that may panic, the
+// compiler stores a pointer to a constant traceSite in the frame, so that the
+// list can be printed as a stack trace with function names and line numbers.
+// This works on every architecture, including WebAssembly where it's not
+// possible to walk the stack.
+
+import (
+	"internal/task"
+	"unsafe"
+)
+
+// traceFrame is a single frame in the list of frames of a goroutine.
+// The compiler knows about the layout of this struct, so it should not be
+// changed without also updating compiler/trace.go.
+type traceFrame struct {
+	previous *traceFrame
+	site     *traceSite
+}
+
+// systemTraceFrame is the list of trace frames for code that doesn't run in a
+// goroutine, for example code that runs on the system stack.
+var systemTraceFrame unsafe.Pointer
+
+// The stack trace of a panic is copied here just before it unwinds the stack,
+// so that it can still be printed when the panic isn't recovered.
+const maxPanicTrace = 32
+
+var (
+	panicTrace          [maxPanicTrace]*traceSite
+	panicTraceLen       int
+	panicTraceTruncated bool
+	panicTraceCaptured  bool
+)
+
+// traceHead returns a pointer to the innermost trace frame of the current
+// goroutine.
+//
+//go:inline
+func traceHead() *unsafe.Pointer {
+	if t := task.Current(); t != nil {
+		return &t.TraceFrame
+	}
+	return &systemTraceFrame
+}
+
+// Called at the start of every function compiled with -panic=trace.
+//
+//go:inline
+func tracePush(frame *traceFrame) {
+	head := traceHead()
+	frame.previous = (*traceFrame)(*head)
+	frame.site = nil
+	*head = unsafe.Pointer(frame)
+	profileTick()
+}
+
+// Called right before the return instruction of every function compiled with
+// -panic=trace.
+//
+//go:inline
+func tracePop(frame *traceFrame) {
+	*traceHead() = unsafe.Pointer(frame.previous)
+}
+
+// Called at the start of the landing pad of a function, when a panic unwound
+// the stack to this function. It drops the frames of the functions that were
+// unwound.
+//
+//go:inline
+func traceRestore(frame *traceFrame) {
+	*traceHead() = unsafe.Pointer(frame)
+}
+
+// capturePanicTrace copies the stack trace of the current goroutine, unless a
+// panic is already in progress: in that case the stack trace of the original
+// panic is kept.
+func capturePanicTrace() {
+	if panicTraceCaptured {
+		return
+	}
+	panicTraceCaptured = true
+	panicTraceLen = 0
+	panicTraceTruncated = false
+	for frame := (*traceFrame)(*traceHead()); frame != nil; frame = frame.previous {
+		if frame.site == nil {
+			continue
+		}
+		if panicTraceLen == maxPanicTrace {
+			panicTraceTruncated = true
+			break
+		}
+		panicTrace[panicTraceLen] = frame.site
+		panicTraceLen++
+	}
+}
+
+// clearPanicTrace forgets the captured stack trace, after the panic has been
+// recovered.
+func clearPanicTrace() {
+	panicTraceCaptured = false
+}
+
+// printPanicTrace prints the stack trace of the panic in progress, in a format
+// similar to the one of the gc toolchain.
+func printPanicTrace() {
+	printnl()
+	if panicTraceCaptured {
+		for i := 0; i < panicTraceLen; i++ {
+			printTraceSite(panicTrace[i])
+		}
+		if panicTraceTruncated {
+			printstring("...additional frames elided...\n")
+		}
+		return
+	}
+	for frame := (*traceFrame)(*traceHead()); frame != nil; frame = frame.previous {
+		if frame.site != nil {
+			printTraceSite(frame.site)
+		}
+	}
+}
+
+func printTraceSite(site *traceSite) {
+	printstring(site.function.name)
+	printstring("(...)\n\t")
+	printstring(site.function.file)
+	printstring(":")
+	printuint32(site.line)
+	printnl()
+}
+
+// profileStack stores the call sites of the current goroutine in stack, for the
+// profiler. The address of a call site is used as its program counter.
+func profileStack(stack *[profileMaxDepth]uintptr) int {
+	n := 0
+	for frame := (*traceFrame)(*traceHead()); frame != nil && n < len(stack); frame = frame.previous {
+		if frame.site != nil {
+			stack[n] = uintptr(unsafe.Pointer(frame.site))
+			n++
+		}
+	}
+	return n
+}
+
+// profileLocation returns the position of a call site returned by
+// profileStack.
+func profileLocation(pc uintptr) (function, file string, line int) {
+	site := (*traceSite)(unsafe.Pointer(pc))
+	return site.function.name, site.function.file, int(site.line)
+}
@@ -0,0 +1,148 @@
+package pprof
+
+import (
+	"time"
+)
+
+// Program counters used for samples without a (known) location.
+const (
+	unknownPC = 0
+	lostPC    = 1
+)
+
+// profileBuilder builds a profile in the protocol buffer format.
+type profileBuilder struct {
+	b        protobuf
+	start    time.Time
+	duration time.Duration
+
+	strings   map[string]int64
+	stringTab []string
+	locations map[uintptr]uint64
+	functions map[string]uint64
+	funcs     protobuf // encoded Function messages
+	locs      protobuf // encoded Location messages
+}
+
+func newProfileBuilder(start time.Time) *profileBuilder {
+	p := &profileBuilder{
+		start:     start,
+		strings:   make(map[string]int64),
+		locations: make(map[uintptr]uint64),
+		functions: make(map[string]uint64),
+	}
+	p.stringIndex("") // the first string must be the empty string
+	return p
+}
+
+// Field numbers of the Profile message.
+const (
+	tagProfileSampleType    = 1
+	tagProfileSample        = 2
+	tagProfileLocation      = 4
+	tagProfileFunction      = 5
+	tagProfileStringTable   = 6
+	tagProfileTimeNanos     = 9
+	tagProfileDurationNanos = 10
+	tagProfilePeriodType    = 11
+	tagProfilePeriod        = 12
+)
+
+func (p *profileBuilder) stringIndex(s string) int64 {
+	index, ok := p.strings[s]
+	if !ok {
+		index = int64(len(p.stringTab))
+		p.strings[s] = index
+		p.stringTab = append(p.stringTab, s)
+	}
+	return index
+}
+
+func (p *profileBuilder) valueType(typ, unit string) *protobuf {
+	var m protobuf
+	m.int64(1, p.stringIndex(typ))
+	m.int64(2, p.stringIndex(unit))
+	return &m
+}
+
+// sampleTypes adds the types of the two values of every sample.
+func (p *profileBuilder) sampleTypes(type1, unit1, type2, unit2 string) {
+	p.b.message(tagProfileSampleType, p.valueType(type1, unit1))
+	p.b.message(tagProfileSampleType, p.valueType(type2, unit2))
+}
+
+func (p *profileBuilder) period(typ, unit string, period int64) {
+	p.b.message(tagProfilePeriodType, p.valueType(typ, unit))
+	p.b.int64(tagProfilePeriod, period)
+}
+
+// sample adds a sample with the given stack, innermost call first.
+func (p *profileBuilder) sample(stack []uintptr, value1, value2 int64) {
+	if len(stack) == 0 {
+		stack = []uintptr{unknownPC}
+	}
+	ids := make([]uint64, len(stack))
+	for i, pc := range stack {
+		ids[i] = p.locationID(pc)
+	}
+	var m protobuf
+	m.uint64s(1, ids)
+	m.int64s(2, []int64{value1, value2})
+	p.b.message(tagProfileSample, &m)
+}
+
+func (p *profileBuilder) locationID(pc uintptr) uint64 {
+	if id, ok := p.locations[pc]; ok {
+		return id
+	}
+	var function, file string
+	var line int
+	switch pc {
+	case unknownPC:
+		function = "(unknown)"
+	case lostPC:
+		function = "(profile table full)"
+	default:
+		function, file, line = profileLocation(pc)
+	}
+	id := uint64(len(p.locations) + 1)
+	p.locations[pc] = id
+
+	var lineMsg protobuf
+	lineMsg.uint64(1, p.functionID(function, file))
+	lineMsg.int64(2, int64(line))
+	var loc protobuf
+	loc.uint64(1, id)
+	loc.uint64(3, uint64(pc))
+	loc.message(4, &lineMsg)
+	p.locs.message(tagProfileLocation, &loc)
+	return id
+}
+
+func (p *profileBuilder) functionID(name, file string) uint64 {
+	key := name + "\x00" + file
+	if id, ok := p.functions[key]; ok {
+		return id
+	}
+	id := uint64(len(p.functions) + 1)
+	p.functions[key] = id
+	var fn protobuf
+	fn.uint64(1, id)
+	fn.int64(2, p.stringIndex(name))
+	fn.int64(3, p.stringIndex(name))
+	fn.int64(4, p.stringIndex(file))
+	p.funcs.message(tagProfileFunction, &fn)
+	return id
+}
+
+// build returns the encoded profile.
+func (p *profileBuilder) build() []byte {
+	p.b.data = append(p.b.data, p.locs.data...)
+	p.b.data = append(p.b.data, p.funcs.data...)
+	for _, s := range p.stringTab {
+		p.b.string(tagProfileStringTable, s)
+	}
+	p.b.int64(tagProfileTimeNanos, p.start.UnixNano())
+	p.b.int64(tagProfileDurationNanos, int64(p.duration))
+	return p.b.data
+}
@@ -0,0 +1,89 @@
+package pprof
+
+import (
+	"bytes"
+	"internal/profile"
+	"testing"
+	"time"
+)
+
+func TestProtobuf(t *testing.T) {
+	var b protobuf
+	b.uint64(1, 300)
+	b.uint64(2, 0) // left out
+	b.int64(3, -1)
+	b.string(4, "hi")
+	b.uint64s(5, []uint64{1, 128})
+	want := []byte{
+		0x08, 0xac, 0x02, // field 1, varint 300
+		0x18, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01, // field 3, varint -1
+		0x22, 0x02, 'h', 'i', // field 4, length 2
+		0x2a, 0x03, 0x01, 0x80, 0x01, // field 5, packed varints 1 and 128
+	}
+	if !bytes.Equal(b.data, want) {
+		t.Errorf("unexpected encoding:\n got: % x\nwant: % x", b.data, want)
+	}
+}
+
+func TestProfileBuilder(t *testing.T) {
+	// Only use the program counters that don't need to be looked up in the
+	// runtime.
+	start := time.Unix(1700000000, 5)
+	p := newProfileBuilder(start)
+	p.duration = 2 * time.Second
+	p.sampleTypes("samples", "count", "cpu", "nanoseconds")
+	p.period("cpu", "nanoseconds", 10000000)
+	p.sample(nil, 3, 30000000)
+	p.sample([]uintptr{lostPC, unknownPC}, 1, 10000000)
+
+	prof, err := profile.Parse(bytes.NewReader(p.build()))
+	if err != nil {
+		t.Fatal("could not parse profile:", err)
+	}
+	if prof.TimeNanos != start.UnixNano() || prof.DurationNanos != int64(2*time.Second) {
+		t.Errorf("unexpected time %d and duration %d", prof.TimeNanos, prof.DurationNanos)
+	}
+	if len(prof.SampleType) != 2 || prof.SampleType[0].Type != "samples" || prof.SampleType[0].Unit != "count" || prof.SampleType[1].Type != "cpu" || prof.SampleType[1].Unit != "nanoseconds" {
+		t.Errorf("unexpected sample types: %v", prof.SampleType)
+	}
+	if prof.PeriodType == nil || prof.PeriodType.Type != "cpu" || prof.Period != 10000000 {
+		t.Errorf("unexpected period: %v %d", prof.PeriodType, prof.Period)
+	}
+
+	// The samples refer to the same two locations, each with its own
+	// function.
+	if len(prof.Sample) != 2 || len(prof.Location) != 2 || len(prof.Function) != 2 {
+		t.Fatalf("got %d samples, %d locations and %d functions, want 2 of each", len(prof.Sample), len(prof.Location), len(prof.Function))
+	}
+	for i, want := range []struct {
+		functions []string
+		values    []int64
+	}{
+		{[]string{"(unknown)"}, []int64{3, 30000000}},
+		{[]string{"(profile table full)", "(unknown)"}, []int64{1, 10000000}},
+	} {
+		sample := prof.Sample[i]
+		var functions []string
+		for _, loc := range sample.Location {
+			for _, line := range loc.Line {
+				functions = append(functions, line.Function.Name)
+			}
+		}
+		if len(functions) != len(want.functions) || len(sample.Value) != len(want.values) {
+			t.Errorf("sample %d: functions %q and values %v, want %q and %v", i, functions, sample.Value, want.functions, want.values)
+			continue
+		}
+		for j := range functions {
+			if functions[j] != want.functions[j] {
+				t.Errorf("sample %d: functions %q, want %q", i, functions, want.functions)
+				break
+			}
+		}
+		for j := range sample.Value {
+			if sample.Value[j] != want.values[j] {
+				t.Errorf("sample %d: values %v, want %v", i, sample.Value, want.values)
+				break
+			}
+		}
+	}
+}
@@ -1,44 +1,172 @@
+// Package pprof writes CPU and heap profiles in the format expected by the
+// pprof visualization tool, so that they can be inspected with go tool pprof.
+//
+// Profiling is supported on Linux and WASI. Stacks are only recorded when the
+// program is compiled with -panic=trace, which keeps track of the call sites of
+// every goroutine. On WASI, CPU samples can only be taken at the start of a
+// function, so CPU profiling needs -panic=trace there too. Heap profiling is
+// disabled by default: set runtime.MemProfileRate to enable it.
+//
+// The program counters in a profile are not real addresses but refer to call
+// sites, so disassembly in go tool pprof doesn't work.
 package pprof
 
-// TinyGo does not implement pprof. However, a dummy shell is needed for the
-// testing package (and testing/internal/pprof).
-
 import (
 	"errors"
 	"io"
+	"runtime"
+	"sync"
+	"time"
+	_ "unsafe"
 )
 
 var ErrUnimplemented = errors.New("runtime/pprof: unimplemented")
 
-type Profile struct{}
+// Implemented in the runtime, see src/runtime/profile.go.
+
+//go:linkname readProfile runtime.pprof_readProfile
+func readProfile(heap bool, fn func(stack []uintptr, count, bytes int64)) int64
+
+//go:linkname profileLocation runtime.pprof_profileLocation
+func profileLocation(pc uintptr) (function, file string, line int)
+
+//go:linkname profilingSupported runtime.pprof_supported
+func profilingSupported() bool
+
+var cpu struct {
+	sync.Mutex
+	profiling bool
+	w         io.Writer
+	hz        int
+	start     time.Time
+}
 
+// StartCPUProfile enables CPU profiling for the current process. While
+// profiling, the profile will be buffered and written to w when
+// StopCPUProfile is called. StartCPUProfile returns an error if profiling is
+// already enabled.
+//
+// On systems where profiling isn't supported, this does nothing.
 func StartCPUProfile(w io.Writer) error {
+	if !profilingSupported() {
+		return nil
+	}
+	const hz = 100
+	cpu.Lock()
+	defer cpu.Unlock()
+	if cpu.profiling {
+		return errors.New("cpu profiling already in use")
+	}
+	cpu.profiling = true
+	cpu.w = w
+	cpu.hz = hz
+	cpu.start = time.Now()
+	runtime.SetCPUProfileRate(hz)
 	return nil
 }
 
+// StopCPUProfile stops the current CPU profile, if any, and writes it to the
+// writer passed to StartCPUProfile.
 func StopCPUProfile() {
+	cpu.Lock()
+	defer cpu.Unlock()
+	if !cpu.profiling {
+		return
+	}
+	cpu.profiling = false
+	runtime.SetCPUProfileRate(0)
+
+	p := newProfileBuilder(cpu.start)
+	p.duration = time.Since(cpu.start)
+	p.sampleTypes("samples", "count", "cpu", "nanoseconds")
+	period := int64(time.Second) / int64(cpu.hz)
+	p.period("cpu", "nanoseconds", period)
+	lost := readProfile(false, func(stack []uintptr, count, bytes int64) {
+		p.sample(stack, count, count*period)
+	})
+	if lost != 0 {
+		p.sample([]uintptr{lostPC}, lost, lost*period)
+	}
+	cpu.w.Write(p.build())
+	cpu.w = nil
 }
 
+// WriteHeapProfile is shorthand for Lookup("heap").WriteTo(w, 0).
 func WriteHeapProfile(w io.Writer) error {
-	return nil
+	return writeHeap(w)
 }
 
+func writeHeap(w io.Writer) error {
+	if !profilingSupported() {
+		return ErrUnimplemented
+	}
+	p := newProfileBuilder(time.Now())
+	p.sampleTypes("alloc_objects", "count", "alloc_space", "bytes")
+	p.period("space", "bytes", int64(runtime.MemProfileRate))
+	lost := readProfile(true, func(stack []uintptr, count, bytes int64) {
+		p.sample(stack, count, bytes)
+	})
+	if lost != 0 {
+		p.sample([]uintptr{lostPC}, lost, 0)
+	}
+	_, err := w.Write(p.build())
+	return err
+}
+
+// A Profile is a collection of stack traces. Only the "heap" and "allocs"
+// profiles are available, which are the same in TinyGo: freed memory isn't
+// tracked.
+type Profile struct {
+	name string
+}
+
+var (
+	heapProfile   = &Profile{name: "heap"}
+	allocsProfile = &Profile{name: "allocs"}
+)
+
+// Lookup returns the profile with the given name, or nil if no such profile
+// exists.
 func Lookup(name string) *Profile {
+	switch name {
+	case "heap":
+		return heapProfile
+	case "allocs":
+		return allocsProfile
+	}
 	return nil
 }
 
+// Profiles returns a slice of all the known profiles, sorted by name.
+func Profiles() []*Profile {
+	return []*Profile{allocsProfile, heapProfile}
+}
+
+// Name returns this profile's name.
 func (p *Profile) Name() string {
-	return ""
+	if p == nil {
+		return ""
+	}
+	return p.name
 }
 
+// Count returns the number of execution stacks currently in the profile.
 func (p *Profile) Count() int {
-	return 0
+	if p == nil {
+		return 0
+	}
+	count := 0
+	readProfile(true, func(stack []uintptr, n, bytes int64) {
+		count++
+	})
+	return count
 }
 
+// WriteTo writes a pprof-formatted snapshot of the profile to w. Only debug=0
+// (the protocol buffer format) is supported.
 func (p *Profile) WriteTo(w io.Writer, debug int) error {
-	return ErrUnimplemented
-}
-
-func Profiles() []*Profile {
-	return nil
+	if p == nil || debug != 0 {
+		return ErrUnimplemented
+	}
+	return writeHeap(w)
 }
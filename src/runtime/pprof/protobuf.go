@@ -0,0 +1,70 @@
+package pprof
+
+// This file implements just enough of the protocol buffer wire format to write
+// profiles in the format of profile.proto, which is what go tool pprof reads:
+// https://github.com/google/pprof/blob/main/proto/profile.proto
+
+type protobuf struct {
+	data []byte
+}
+
+func (b *protobuf) varint(x uint64) {
+	for x >= 0x80 {
+		b.data = append(b.data, byte(x)|0x80)
+		x >>= 7
+	}
+	b.data = append(b.data, byte(x))
+}
+
+func (b *protobuf) key(tag int, wireType int) {
+	b.varint(uint64(tag)<<3 | uint64(wireType))
+}
+
+// uint64 writes a varint field. Zero values are left out, like protobuf
+// encoders do for proto3 messages.
+func (b *protobuf) uint64(tag int, x uint64) {
+	if x == 0 {
+		return
+	}
+	b.key(tag, 0)
+	b.varint(x)
+}
+
+func (b *protobuf) int64(tag int, x int64) {
+	b.uint64(tag, uint64(x))
+}
+
+// bytes writes a length-delimited field, which is used for strings and
+// embedded messages.
+func (b *protobuf) bytes(tag int, data []byte) {
+	b.key(tag, 2)
+	b.varint(uint64(len(data)))
+	b.data = append(b.data, data...)
+}
+
+func (b *protobuf) string(tag int, s string) {
+	b.key(tag, 2)
+	b.varint(uint64(len(s)))
+	b.data = append(b.data, s...)
+}
+
+func (b *protobuf) message(tag int, m *protobuf) {
+	b.bytes(tag, m.data)
+}
+
+// uint64s writes a packed repeated varint field.
+func (b *protobuf) uint64s(tag int, xs []uint64) {
+	var packed protobuf
+	for _, x := range xs {
+		packed.varint(x)
+	}
+	b.bytes(tag, packed.data)
+}
+
+func (b *protobuf) int64s(tag int, xs []int64) {
+	var packed protobuf
+	for _, x := range xs {
+		packed.varint(uint64(x))
+	}
+	b.bytes(tag, packed.data)
+}
@@ -0,0 +1,181 @@
+//go:build linux && !baremetal && !nintendoswitch
+
+package runtime
+
+// This file implements the profiling support used by runtime/pprof: a CPU
+// profiler that samples the stack of the running goroutine a number of times
+// per second, and a heap profiler that samples allocations.
+//
+// Stacks are made of the call sites that are tracked when compiling with
+// -panic=trace, see panic_trace.go. Without it, all samples are attributed to
+// an unknown location. The address of a call site is used as its program
+// counter, see pprof_profileLocation.
+
+const profileMaxDepth = 32
+
+// profileBucket holds all samples with a given stack.
+type profileBucket struct {
+	stack [profileMaxDepth]uintptr
+	depth int
+	count int64
+	bytes int64
+}
+
+// profileTable is a hash table of stacks with a fixed size. It doesn't
+// allocate memory once created, so that it can be used from a signal handler
+// and from the allocator.
+type profileTable struct {
+	buckets []profileBucket
+	lost    int64 // number of samples that didn't fit in the table
+}
+
+const profileTableSize = 1024
+
+func newProfileTable() *profileTable {
+	return &profileTable{buckets: make([]profileBucket, profileTableSize)}
+}
+
+// add records count samples of bytes bytes in total with the given stack.
+func (t *profileTable) add(stack []uintptr, count, bytes int64) {
+	hash := uintptr(len(stack))
+	for _, pc := range stack {
+		hash = hash*31 + pc
+	}
+	for i := 0; i < len(t.buckets); i++ {
+		b := &t.buckets[(hash+uintptr(i))%uintptr(len(t.buckets))]
+		if b.count == 0 {
+			// Empty bucket: the stack wasn't seen before.
+			b.depth = copy(b.stack[:], stack)
+			b.count = count
+			b.bytes = bytes
+			return
+		}
+		if b.depth == len(stack) && profileStackEqual(b.stack[:b.depth], stack) {
+			b.count += count
+			b.bytes += bytes
+			return
+		}
+	}
+	t.lost += count
+}
+
+func profileStackEqual(a, b []uintptr) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// MemProfileRate controls the fraction of memory allocations that are recorded
+// and reported in the memory profile. The profiler aims to sample an average
+// of one allocation per MemProfileRate bytes allocated.
+//
+// Unlike with gc, heap profiling is disabled by default: the profile takes a
+// fair amount of memory, and recording stacks requires -panic=trace anyway.
+var MemProfileRate int = 0
+
+var (
+	cpuProfileHz   int
+	cpuProfile     *profileTable
+	memProfile     *profileTable
+	memProfileBusy bool // set while recording a heap sample or reading the profile
+	memProfileNext int  // number of bytes until the next heap sample
+)
+
+// SetCPUProfileRate sets the CPU profiling rate to hz samples per second.
+// If hz <= 0, SetCPUProfileRate turns off profiling. If the profiler is on, the
+// rate cannot be changed without first turning it off.
+//
+// Most clients should use the runtime/pprof package instead of calling
+// SetCPUProfileRate directly.
+func SetCPUProfileRate(hz int) {
+	if hz < 0 {
+		hz = 0
+	}
+	if hz != 0 && cpuProfileHz != 0 {
+		println("runtime: cannot set cpu profile rate until previous profile has finished.")
+		return
+	}
+	if hz != 0 {
+		cpuProfile = newProfileTable()
+	}
+	cpuProfileHz = hz
+	setProfileTimer(hz)
+}
+
+// cpuProfileSample records a single CPU sample of the current goroutine. It is
+// called from the profiling timer.
+func cpuProfileSample(count int64) {
+	if cpuProfileHz == 0 {
+		return
+	}
+	var stack [profileMaxDepth]uintptr
+	n := profileStack(&stack)
+	cpuProfile.add(stack[:n], count, 0)
+}
+
+// memProfileAlloc is called by the allocator for every allocation while heap
+// profiling is enabled.
+func memProfileAlloc(size uintptr) {
+	if memProfileBusy {
+		return
+	}
+	memProfileNext -= int(size)
+	if memProfileNext > 0 {
+		return
+	}
+	rate := MemProfileRate
+	memProfileNext = rate
+
+	// This sample stands for all allocations since the previous one.
+	objects := int64(1)
+	if int(size) < rate {
+		objects = int64(rate) / int64(size)
+	}
+
+	memProfileBusy = true
+	if memProfile == nil {
+		memProfile = newProfileTable()
+	}
+	var stack [profileMaxDepth]uintptr
+	n := profileStack(&stack)
+	memProfile.add(stack[:n], objects, objects*int64(size))
+	memProfileBusy = false
+}
+
+// pprof_readProfile calls fn for every stack in the CPU profile (once profiling
+// has been stopped) or the heap profile, with the number of samples and the
+// number of bytes allocated. It returns the number of samples that were lost.
+func pprof_readProfile(heap bool, fn func(stack []uintptr, count, bytes int64)) int64 {
+	table := cpuProfile
+	if heap {
+		table = memProfile
+		memProfileBusy = true
+		defer func() {
+			memProfileBusy = false
+		}()
+	}
+	if table == nil {
+		return 0
+	}
+	for i := range table.buckets {
+		b := &table.buckets[i]
+		if b.count != 0 {
+			fn(b.stack[:b.depth], b.count, b.bytes)
+		}
+	}
+	return table.lost
+}
+
+// pprof_profileLocation returns the function, file and line of a program
+// counter in a profile.
+func pprof_profileLocation(pc uintptr) (function, file string, line int) {
+	return profileLocation(pc)
+}
+
+// pprof_supported returns whether profiling is supported on this system.
+func pprof_supported() bool {
+	return true
+}
@@ -0,0 +1,33 @@
+//go:build !linux || baremetal || nintendoswitch
+
+package runtime
+
+// Profiling is not supported on this system, see profile.go.
+
+const profileMaxDepth = 32
+
+// MemProfileRate controls the fraction of memory allocations that are recorded
+// and reported in the memory profile. It has no effect on this system.
+var MemProfileRate int = 0
+
+// SetCPUProfileRate sets the CPU profiling rate to hz samples per second. It
+// has no effect on this system.
+func SetCPUProfileRate(hz int) {
+}
+
+func memProfileAlloc(size uintptr) {}
+
+//go:inline
+func profileTick() {}
+
+func pprof_readProfile(heap bool, fn func(stack []uintptr, count, bytes int64)) int64 {
+	return 0
+}
+
+func pprof_profileLocation(pc uintptr) (function, file string, line int) {
+	return "", "", 0
+}
+
+func pprof_supported() bool {
+	return false
+}
@@ -0,0 +1,38 @@
+//go:build linux && !baremetal && !wasi && !nintendoswitch
+
+// Timer for the CPU profiler, see profile_signal.go.
+
+#include <signal.h>
+#include <string.h>
+#include <sys/time.h>
+
+void tinygo_profileSignal(void);
+
+static void tinygo_sigprof(int sig) {
+    tinygo_profileSignal();
+}
+
+int tinygo_setProfileTimer(int hz) {
+    struct itimerval timer;
+    memset(&timer, 0, sizeof(timer));
+    if (hz > 0) {
+        timer.it_interval.tv_usec = 1000000 / hz;
+        timer.it_value = timer.it_interval;
+
+        struct sigaction action;
+        memset(&action, 0, sizeof(action));
+        action.sa_handler = tinygo_sigprof;
+        action.sa_flags = SA_RESTART;
+        sigemptyset(&action.sa_mask);
+        if (sigaction(SIGPROF, &action, NULL) != 0) {
+            return -1;
+        }
+        return setitimer(ITIMER_PROF, &timer, NULL);
+    }
+
+    // Stop the timer before removing the signal handler, so that a pending
+    // signal doesn't terminate the process.
+    int result = setitimer(ITIMER_PROF, &timer, NULL);
+    signal(SIGPROF, SIG_IGN);
+    return result;
+}
@@ -0,0 +1,30 @@
+//go:build linux && !baremetal && !wasi && !nintendoswitch
+
+package runtime
+
+import "C" // dummy import so that profile_signal.c works
+
+// The CPU profiler is driven by SIGPROF, which is sent periodically by an
+// interval timer (ITIMER_PROF) that counts the CPU time used by the process.
+
+//export tinygo_setProfileTimer
+func tinygo_setProfileTimer(hz int32) int32
+
+func setProfileTimer(hz int) {
+	if tinygo_setProfileTimer(int32(hz)) != 0 {
+		println("runtime: could not set profiling timer")
+	}
+}
+
+// Called from the SIGPROF signal handler. It must not allocate memory.
+//
+//export tinygo_profileSignal
+func profileSignal() {
+	cpuProfileSample(1)
+}
+
+// The timer interrupts the program by itself, so there is nothing to do on
+// function entry.
+//
+//go:inline
+func profileTick() {}
@@ -0,0 +1,49 @@
+//go:build wasi
+
+package runtime
+
+// There are no signals in WASI, so the CPU profiler can't interrupt the
+// program. Instead, profileTick is called at the start of every function
+// (which requires -panic=trace) and counts epochs. Every so many epochs it
+// checks the clock, and takes a sample when the sampling interval has passed.
+
+// Number of function calls between two clock checks. Reading the clock is
+// relatively expensive, so it's not done on every call.
+const profileEpochLength = 1024
+
+var (
+	profileEpoch    uint32
+	profileInterval int64 // sampling interval in nanoseconds, or 0 when not profiling
+	profileNext     int64 // time of the next sample
+)
+
+func setProfileTimer(hz int) {
+	if hz == 0 {
+		profileInterval = 0
+		return
+	}
+	profileInterval = 1e9 / int64(hz)
+	profileNext = nanotime() + profileInterval
+}
+
+//go:inline
+func profileTick() {
+	if profileInterval == 0 {
+		return
+	}
+	profileEpoch++
+	if profileEpoch%profileEpochLength == 0 {
+		profileCheckClock()
+	}
+}
+
+func profileCheckClock() {
+	now := nanotime()
+	if now < profileNext {
+		return
+	}
+	// Account for all intervals that passed since the last sample.
+	count := (now-profileNext)/profileInterval + 1
+	profileNext += count * profileInterval
+	cpuProfileSample(count)
+}
@@ -0,0 +1,384 @@
+//go:build race
+
+package runtime
+
+// This file implements the data race detector for -race. The compiler calls
+// raceread and racewrite before every load and store outside the runtime that
+// may access memory shared between goroutines (see compiler/race.go).
+//
+// It is a happens-before detector like ThreadSanitizer: every goroutine has a
+// vector clock that is advanced at synchronization points (starting a
+// goroutine, channel operations, sync primitives and atomic operations), and
+// every word of memory remembers the recent accesses to it. An access that
+// conflicts with an earlier access by another goroutine that doesn't happen
+// before it is reported as a data race.
+//
+// All goroutines run on a single OS thread and the detector is never
+// interrupted by another goroutine, so its state is kept in regular maps
+// instead of the shadow memory of ThreadSanitizer. This is slow, but it is
+// only meant for finding bugs on the host.
+
+import (
+	"internal/task"
+	"unsafe"
+)
+
+// raceGoroutine is the state of the race detector for a single goroutine.
+type raceGoroutine struct {
+	id     uint32
+	clock  raceClock
+	ignore int // nesting level of RaceDisable
+}
+
+// raceClock is a vector clock, indexed by goroutine ID.
+type raceClock []uint32
+
+func (c raceClock) get(id uint32) uint32 {
+	if int(id) < len(c) {
+		return c[id]
+	}
+	return 0
+}
+
+func (c *raceClock) set(id, value uint32) {
+	for int(id) >= len(*c) {
+		*c = append(*c, 0)
+	}
+	(*c)[id] = value
+}
+
+// join sets c to the element-wise maximum of c and other.
+func (c *raceClock) join(other raceClock) {
+	for id, value := range other {
+		if value > c.get(uint32(id)) {
+			c.set(uint32(id), value)
+		}
+	}
+}
+
+// raceAccess is a single access to a word of memory.
+type raceAccess struct {
+	site  *traceSite
+	id    uint32 // goroutine that made the access
+	clock uint32 // clock of the goroutine at the time of the access
+	mask  uint8  // the bytes of the word that were accessed
+	write bool
+}
+
+// raceShadow is the list of recent accesses to a word of memory. Accesses that
+// happen before a later access that covers the same bytes are removed.
+type raceShadow struct {
+	accesses []raceAccess
+}
+
+// raceReportKey identifies a reported data race, to only report it once.
+type raceReportKey struct {
+	site, prevSite *traceSite
+}
+
+const raceWordSize = 8
+
+var (
+	// State of code that doesn't run in a goroutine.
+	raceSystem = raceGoroutine{clock: raceClock{1}}
+
+	raceNextID uint32 = 1
+
+	// The shadow state and the vector clocks of synchronization objects,
+	// indexed by word address.
+	raceShadows map[uintptr]*raceShadow
+	raceSyncs   map[uintptr]raceClock
+
+	raceReported map[raceReportKey]struct{}
+	raceErrors   int
+
+	// Set while the race detector is running, to ignore the memory that it
+	// allocates itself.
+	raceBusy bool
+)
+
+// raceCurrent returns the state of the current goroutine.
+func raceCurrent() *raceGoroutine {
+	t := task.Current()
+	if t == nil {
+		return &raceSystem
+	}
+	if t.RaceContext == nil {
+		// Not started with racegostart, so nothing is known about it.
+		t.RaceContext = unsafe.Pointer(raceNewGoroutine())
+	}
+	return (*raceGoroutine)(t.RaceContext)
+}
+
+func raceNewGoroutine() *raceGoroutine {
+	g := &raceGoroutine{id: raceNextID}
+	raceNextID++
+	g.clock.set(g.id, 1)
+	return g
+}
+
+// tick advances the clock of the goroutine after a release operation, so that
+// its later accesses don't happen before the acquiring goroutine.
+func (g *raceGoroutine) tick() {
+	g.clock.set(g.id, g.clock.get(g.id)+1)
+}
+
+// racegostart is called when a goroutine is started: everything that happened
+// in the parent goroutine until now happens before the new goroutine.
+func racegostart(t *task.Task) {
+	if raceBusy {
+		return
+	}
+	raceBusy = true
+	parent := raceCurrent()
+	g := raceNewGoroutine()
+	g.clock.join(parent.clock)
+	parent.tick()
+	t.RaceContext = unsafe.Pointer(g)
+	raceBusy = false
+}
+
+// raceacquire makes every operation that was released on addr happen before
+// the rest of the current goroutine.
+func raceacquire(addr unsafe.Pointer) {
+	if raceBusy || addr == nil {
+		return
+	}
+	raceBusy = true
+	if clock, ok := raceSyncs[uintptr(addr)&^(raceWordSize-1)]; ok {
+		raceCurrent().clock.join(clock)
+	}
+	raceBusy = false
+}
+
+// racerelease makes everything the current goroutine did until now happen
+// before a later raceacquire on addr, replacing earlier releases.
+func racerelease(addr unsafe.Pointer) {
+	racereleaseSync(addr, false)
+}
+
+// racereleasemerge is like racerelease, but keeps earlier releases.
+func racereleasemerge(addr unsafe.Pointer) {
+	racereleaseSync(addr, true)
+}
+
+func racereleaseSync(addr unsafe.Pointer, merge bool) {
+	if raceBusy || addr == nil {
+		return
+	}
+	raceBusy = true
+	if raceSyncs == nil {
+		raceSyncs = make(map[uintptr]raceClock)
+	}
+	g := raceCurrent()
+	key := uintptr(addr) &^ (raceWordSize - 1)
+	var clock raceClock
+	if merge {
+		clock = raceSyncs[key]
+	}
+	clock.join(g.clock)
+	raceSyncs[key] = clock
+	g.tick()
+	raceBusy = false
+}
+
+// racemalloc is called for every heap allocation. The memory may have been
+// used by an object that was freed, whose state must not be confused with the
+// new object.
+func racemalloc(ptr unsafe.Pointer, size uintptr) {
+	if raceBusy || (len(raceShadows) == 0 && len(raceSyncs) == 0) {
+		return
+	}
+	raceBusy = true
+	start := uintptr(ptr) &^ (raceWordSize - 1)
+	for word := start; word < uintptr(ptr)+size; word += raceWordSize {
+		delete(raceShadows, word)
+		delete(raceSyncs, word)
+	}
+	raceBusy = false
+}
+
+// Called by the compiler before a load of size bytes from addr.
+func raceread(addr unsafe.Pointer, size uintptr, site *traceSite) {
+	raceAccessRange(uintptr(addr), size, false, site)
+}
+
+// Called by the compiler before a store of size bytes to addr.
+func racewrite(addr unsafe.Pointer, size uintptr, site *traceSite) {
+	raceAccessRange(uintptr(addr), size, true, site)
+}
+
+func raceAccessRange(addr, size uintptr, write bool, site *traceSite) {
+	if raceBusy || addr == 0 || size == 0 {
+		return
+	}
+	raceBusy = true
+	g := raceCurrent()
+	if g.ignore == 0 {
+		if raceShadows == nil {
+			raceShadows = make(map[uintptr]*raceShadow)
+		}
+		end := addr + size
+		for word := addr &^ (raceWordSize - 1); word < end; word += raceWordSize {
+			// Determine which bytes of this word are accessed.
+			low, high := uintptr(0), uintptr(raceWordSize)
+			if addr > word {
+				low = addr - word
+			}
+			if end < word+raceWordSize {
+				high = end - word
+			}
+			mask := uint8((uintptr(1)<<(high-low) - 1) << low)
+			raceAccessWord(g, addr, word, mask, write, site)
+		}
+	}
+	raceBusy = false
+}
+
+func raceAccessWord(g *raceGoroutine, addr, word uintptr, mask uint8, write bool, site *traceSite) {
+	shadow := raceShadows[word]
+	if shadow == nil {
+		shadow = &raceShadow{}
+		raceShadows[word] = shadow
+	}
+
+	// Check for conflicts with earlier accesses, and remove the accesses that
+	// are superseded by this one.
+	accesses := shadow.accesses[:0]
+	for _, prev := range shadow.accesses {
+		ordered := prev.id == g.id || prev.clock <= g.clock.get(prev.id)
+		if !ordered && prev.mask&mask != 0 && (write || prev.write) {
+			raceReport(g, addr, write, site, prev)
+		}
+		if ordered && prev.mask&^mask == 0 && (write || !prev.write) {
+			continue
+		}
+		accesses = append(accesses, prev)
+	}
+	shadow.accesses = append(accesses, raceAccess{
+		site:  site,
+		id:    g.id,
+		clock: g.clock.get(g.id),
+		mask:  mask,
+		write: write,
+	})
+}
+
+// raceReport prints a data race between the current access and an earlier
+// access, in a format similar to the one of the gc toolchain.
+func raceReport(g *raceGoroutine, addr uintptr, write bool, site *traceSite, prev raceAccess) {
+	key := raceReportKey{site, prev.site}
+	if _, ok := raceReported[key]; ok {
+		return
+	}
+	if raceReported == nil {
+		raceReported = make(map[raceReportKey]struct{})
+	}
+	raceReported[key] = struct{}{}
+	raceErrors++
+
+	printstring("==================\nWARNING: DATA RACE\n")
+	racePrintAccess("", write, addr, g.id, site)
+	printnl()
+	racePrintAccess("Previous ", prev.write, addr, prev.id, prev.site)
+	printstring("==================\n")
+}
+
+func racePrintAccess(prefix string, write bool, addr uintptr, id uint32, site *traceSite) {
+	printstring(prefix)
+	if write {
+		if prefix == "" {
+			printstring("Write")
+		} else {
+			printstring("write")
+		}
+	} else {
+		if prefix == "" {
+			printstring("Read")
+		} else {
+			printstring("read")
+		}
+	}
+	printstring(" at ")
+	printptr(addr)
+	printstring(" by goroutine ")
+	printuint32(id)
+	printstring(":\n")
+	if site == nil {
+		printstring("  (unknown)\n")
+		return
+	}
+	printstring("  ")
+	printstring(site.function.name)
+	printstring("()\n      ")
+	printstring(site.function.file)
+	printstring(":")
+	printuint32(site.line)
+	printnl()
+}
+
+// raceExit returns the exit code of the program. Like with the gc toolchain,
+// a program that found data races exits with status 66.
+func raceExit(code int) int {
+	if raceErrors == 0 {
+		return code
+	}
+	printstring("Found ")
+	printint32(int32(raceErrors))
+	if raceErrors == 1 {
+		printstring(" data race\n")
+	} else {
+		printstring(" data races\n")
+	}
+	if code == 0 {
+		return 66
+	}
+	return code
+}
+
+// The functions below are used by the standard library (through the
+// internal/race package) to tell the race detector about accesses and
+// synchronization it can't see.
+
+func RaceRead(addr unsafe.Pointer) {
+	raceAccessRange(uintptr(addr), 1, false, nil)
+}
+
+func RaceWrite(addr unsafe.Pointer) {
+	raceAccessRange(uintptr(addr), 1, true, nil)
+}
+
+func RaceReadRange(addr unsafe.Pointer, len int) {
+	raceAccessRange(uintptr(addr), uintptr(len), false, nil)
+}
+
+func RaceWriteRange(addr unsafe.Pointer, len int) {
+	raceAccessRange(uintptr(addr), uintptr(len), true, nil)
+}
+
+func RaceAcquire(addr unsafe.Pointer) {
+	raceacquire(addr)
+}
+
+func RaceRelease(addr unsafe.Pointer) {
+	racerelease(addr)
+}
+
+func RaceReleaseMerge(addr unsafe.Pointer) {
+	racereleasemerge(addr)
+}
+
+// RaceDisable disables checking memory accesses of the current goroutine,
+// until the matching call to RaceEnable.
+func RaceDisable() {
+	raceCurrent().ignore++
+}
+
+func RaceEnable() {
+	raceCurrent().ignore--
+}
+
+// RaceErrors returns the number of data races found so far.
+func RaceErrors() int {
+	return raceErrors
+}
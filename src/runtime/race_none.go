@@ -0,0 +1,22 @@
+//go:build !race
+
+package runtime
+
+// The data race detector is only included with -race, see race.go.
+
+import (
+	"internal/task"
+	"unsafe"
+)
+
+func racegostart(t *task.Task) {}
+
+func raceacquire(addr unsafe.Pointer) {}
+
+func racereleasemerge(addr unsafe.Pointer) {}
+
+func racemalloc(ptr unsafe.Pointer, size uintptr) {}
+
+func raceExit(code int) int {
+	return code
+}
@@ -37,6 +37,8 @@ func preinit() {
 		dst = unsafe.Add(dst, 4)
 		src = unsafe.Add(src, 4)
 	}
+
+	initMemoryProtection()
 }
 
 // The stack layout at the moment an interrupt occurs.
@@ -0,0 +1,116 @@
+//go:build cortexm
+
+package runtime
+
+import (
+	"unsafe"
+)
+
+// FaultInfo describes a fault, and is passed to the handler set with
+// SetFaultHandler. Bus faults, memory management faults and usage faults are
+// all escalated to a HardFault by default, so they are included.
+type FaultInfo struct {
+	// Stack pointer at the time of the fault.
+	SP uintptr
+
+	// Address of the faulting instruction, or 0 if the stack pointer was not
+	// valid so it couldn't be read from the stack.
+	PC uintptr
+
+	// Configurable Fault Status Register and HardFault Status Register. They
+	// are zero on the Cortex-M0 and Cortex-M0+, which don't have them. On
+	// other cores, the cause of the fault can be decoded using
+	// FaultStatus(info.Status).
+	Status     uint32
+	HardStatus uint32
+
+	// Number of faults since the last power cycle or call to
+	// ResetCrashCount, including this one.
+	CrashCount uint32
+}
+
+var faultHandler func(info *FaultInfo)
+
+// faultHook is called on a HardFault, after the cause has been printed. It is
+// only set by programs that use SetFaultHandler or the crash count, so that
+// other programs don't include any of this code.
+var faultHook func(sp *interruptStack, spValid bool, status, hardStatus uint32)
+
+// SetFaultHandler sets a function that is called when a HardFault happens,
+// after the cause of the fault has been printed. The handler could for example
+// store the fault information somewhere or reset the chip. The program is
+// halted if the handler returns.
+//
+// The handler runs on a fresh stack (the stack of the faulting code may be
+// corrupted) with interrupts disabled, so it must not block and should avoid
+// heap allocations.
+func SetFaultHandler(handler func(info *FaultInfo)) {
+	faultHandler = handler
+	faultHook = callFaultHandler
+}
+
+// crashCountMagic is stored next to the crash count, to recognize whether the
+// count is valid after a reset or is random data after a power cycle.
+const crashCountMagic = 0x7f4c_a5e1
+
+// crashCountData is kept across a reset, as it is not initialized by the
+// startup code.
+//
+//go:section .noinit
+var crashCountData struct {
+	magic uint32
+	count uint32
+}
+
+// CrashCount returns the number of faults since the last power cycle or call
+// to ResetCrashCount. It can be used to boot into a safe mode (for example,
+// one that only accepts firmware updates) after repeated crashes:
+//
+//	if runtime.CrashCount() >= 3 {
+//		safeMode()
+//	}
+//	// ... initialize
+//	runtime.ResetCrashCount() // the program is running fine
+//
+// The count is kept in RAM that is not initialized at reset, so this only
+// works when the handler set with SetFaultHandler (or the watchdog) resets the
+// chip after a fault. Faults are only counted once the program has called
+// CrashCount, ResetCrashCount or SetFaultHandler.
+func CrashCount() uint32 {
+	faultHook = callFaultHandler
+	if crashCountData.magic != crashCountMagic {
+		return 0
+	}
+	return crashCountData.count
+}
+
+// ResetCrashCount sets the count returned by CrashCount to zero.
+func ResetCrashCount() {
+	faultHook = callFaultHandler
+	crashCountData.magic = crashCountMagic
+	crashCountData.count = 0
+}
+
+// callFaultHandler updates the crash count and calls the handler set with
+// SetFaultHandler, if there is one. It is called from handleHardFault through
+// faultHook.
+func callFaultHandler(sp *interruptStack, spValid bool, status, hardStatus uint32) {
+	if crashCountData.magic != crashCountMagic {
+		ResetCrashCount()
+	}
+	crashCountData.count++
+
+	if faultHandler == nil {
+		return
+	}
+	info := FaultInfo{
+		SP:         uintptr(unsafe.Pointer(sp)),
+		Status:     status,
+		HardStatus: hardStatus,
+		CrashCount: crashCountData.count,
+	}
+	if spValid && uintptr(unsafe.Pointer(&sp.PC)) >= 0x20000000 {
+		info.PC = sp.PC
+	}
+	faultHandler(&info)
+}
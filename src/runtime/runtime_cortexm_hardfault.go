@@ -36,5 +36,8 @@ func handleHardFault(sp *interruptStack) {
 		print(" pc=", sp.PC)
 	}
 	println()
+	if faultHook != nil {
+		faultHook(sp, true, 0, 0)
+	}
 	abort()
 }
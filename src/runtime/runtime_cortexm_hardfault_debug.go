@@ -102,6 +102,9 @@ func handleHardFault(sp *interruptStack) {
 		}
 	}
 	println()
+	if faultHook != nil {
+		faultHook(sp, spValid, uint32(fault), arm.SCB.HFSR.Get())
+	}
 	abort()
 }
 
@@ -0,0 +1,152 @@
+//go:build cortexm && memory_protection
+
+package runtime
+
+// This file configures the MPU at startup when memory protection is enabled
+// in the target (with "memory-protection": true). It adds a region at address
+// zero that can't be accessed, so that null pointer dereferences in C code or
+// unsafe Go code cause a fault instead of silently reading the vector table,
+// and makes the program code and read-only data read-only. Both cause a
+// HardFault that prints the faulting address.
+//
+// The MPU is configured with PRIVDEFENA set, so all other memory can still be
+// accessed as usual.
+
+import (
+	"device/arm"
+	"unsafe"
+)
+
+//go:extern _stext
+var _stext [0]byte
+
+//go:extern _evectors
+var _evectors [0]byte
+
+//go:extern _etext
+var _etext [0]byte
+
+// Size of the region at address zero that can't be accessed.
+const nullGuardSize = 256
+
+func initMemoryProtection() {
+	numRegions := (arm.MPU.TYPE.Get() & arm.MPU_TYPE_DREGION_Msk) >> arm.MPU_TYPE_DREGION_Pos
+	if numRegions == 0 {
+		// This chip doesn't have an MPU.
+		return
+	}
+
+	cpuid := arm.SCB.CPUID.Get()
+	if (cpuid&arm.SCB_CPUID_PARTNO_Msk)>>arm.SCB_CPUID_PARTNO_Pos&0xf00 == 0xd00 {
+		// ARMv8-M core (Cortex-M23, Cortex-M33, Cortex-M55).
+		initMPUv8()
+	} else {
+		armv6m := (cpuid&arm.SCB_CPUID_ARCHITECTURE_Msk)>>arm.SCB_CPUID_ARCHITECTURE_Pos == 0xc
+		initMPUv7(numRegions, armv6m)
+	}
+
+	arm.MPU.CTRL.Set(arm.MPU_CTRL_ENABLE | arm.MPU_CTRL_PRIVDEFENA)
+	arm.Asm("dsb")
+	arm.Asm("isb")
+}
+
+// nullGuardLimit returns the size of the null guard region, which is smaller
+// than nullGuardSize when the program starts at address zero with a small
+// vector table. Vector fetches bypass the MPU, but the code after the vector
+// table must remain accessible.
+func nullGuardLimit(minSize uintptr) uintptr {
+	size := uintptr(nullGuardSize)
+	if uintptr(unsafe.Pointer(&_stext)) < size {
+		for size > uintptr(unsafe.Pointer(&_evectors)) {
+			size /= 2
+		}
+	}
+	if size < minSize {
+		return 0
+	}
+	return size
+}
+
+// initMPUv7 configures a PMSAv7 MPU, as used on ARMv6-M and ARMv7-M. Regions
+// must be naturally aligned powers of two, so the program code is split in as
+// many regions as are available (the last one being the null guard, as higher
+// regions take priority). Any remaining code at the end of flash is left
+// unprotected, rather than extending a region past the end of the code where
+// it could cover flash that is written to at runtime.
+func initMPUv7(numRegions uint32, armv6m bool) {
+	// ARMv6-M has a minimum region size of 256 bytes, ARMv7-M of 32 bytes.
+	minSize := uintptr(32)
+	if armv6m {
+		minSize = 256
+	}
+
+	// Normal memory, write-through (as recommended for flash).
+	const textAttr = arm.MPU_RASR_AP_RO<<arm.MPU_RASR_AP_Pos | arm.MPU_RASR_C
+	start := (uintptr(unsafe.Pointer(&_stext)) + minSize - 1) &^ (minSize - 1)
+	end := uintptr(unsafe.Pointer(&_etext)) &^ (minSize - 1)
+	region := uint32(0)
+	for start < end && region < numRegions-1 {
+		size := mpuBlockSize(start, end)
+		setMPURegionV7(region, start, size, textAttr)
+		start += size
+		region++
+	}
+
+	if size := nullGuardLimit(minSize); size != 0 {
+		const guardAttr = arm.MPU_RASR_AP_NoAccess<<arm.MPU_RASR_AP_Pos | arm.MPU_RASR_XN
+		setMPURegionV7(numRegions-1, 0, size, guardAttr)
+	}
+}
+
+// mpuBlockSize returns the largest power of two that start is aligned to and
+// that fits between start and end.
+func mpuBlockSize(start, end uintptr) uintptr {
+	size := uintptr(1) << 31
+	if start != 0 {
+		size = start & -start
+	}
+	for size > end-start {
+		size /= 2
+	}
+	return size
+}
+
+func setMPURegionV7(region uint32, base, size uintptr, attr uint32) {
+	sizeField := uint32(0)
+	for uintptr(2)<<sizeField < size {
+		sizeField++
+	}
+	arm.MPU.RNR.Set(region)
+	arm.MPU.RBAR.Set(uint32(base))
+	arm.MPU.RASR.Set(attr | sizeField<<arm.MPU_RASR_SIZE_Pos | arm.MPU_RASR_ENABLE)
+}
+
+// initMPUv8 configures a PMSAv8 MPU, as used on ARMv8-M. Regions have a
+// granularity of 32 bytes, so the program code fits in a single region. There
+// is no way to make a region inaccessible for privileged code, so the null
+// guard is read-only and not executable: writes and calls through a nil
+// function pointer are caught, but reads are not.
+func initMPUv8() {
+	// Attribute 0: normal memory, write-through, read-allocate.
+	arm.MPU.MAIR0.Set(0xaa)
+
+	guardEnd := nullGuardLimit(32)
+	if guardEnd != 0 {
+		arm.MPU.RNR.Set(0)
+		arm.MPU.RBAR.Set(arm.MPU_RBAR_AP_RO<<arm.MPU_RBAR_AP_Pos | arm.MPU_RBAR_XN)
+		arm.MPU.RASR.Set(uint32(guardEnd-32) | arm.MPU_RLAR_EN)
+	}
+
+	// Overlapping regions cause a fault on ARMv8-M, so the code region
+	// starts after the null guard.
+	start := (uintptr(unsafe.Pointer(&_stext)) + 31) &^ 31
+	if start < guardEnd {
+		start = guardEnd
+	}
+	end := uintptr(unsafe.Pointer(&_etext)) &^ 31
+	if start < end {
+		arm.MPU.RNR.Set(1)
+		arm.MPU.RBAR.Set(uint32(start) | arm.MPU_RBAR_AP_RO<<arm.MPU_RBAR_AP_Pos)
+		arm.MPU.RASR.Set(uint32(end-32) | arm.MPU_RLAR_EN)
+	}
+}
@@ -164,6 +164,12 @@ func handleException(code uint) {
 	print(code)
 	print(" pc=")
 	print(riscv.MEPC.Get())
+	if code == 5 || code == 7 {
+		// Load or store access fault, for example because of the memory
+		// protection configured with the memory_protection build tag.
+		print(" address=")
+		print(riscv.MTVAL.Get())
+	}
 	println()
 	abort()
 }
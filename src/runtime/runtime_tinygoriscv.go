@@ -35,4 +35,6 @@ func preinit() {
 		dst = unsafe.Add(dst, 4)
 		src = unsafe.Add(src, 4)
 	}
+
+	initMemoryProtection()
 }
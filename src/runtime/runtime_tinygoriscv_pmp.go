@@ -0,0 +1,59 @@
+//go:build tinygo.riscv32 && memory_protection
+
+package runtime
+
+// This file configures physical memory protection (PMP) at startup when memory
+// protection is enabled in the target (with "memory-protection": true). It
+// adds a region at address zero that can't be accessed, so that null pointer
+// dereferences in C code or unsafe Go code cause an access fault, and makes
+// the program code and read-only data read-only.
+//
+// The PMP entries are locked, because only locked entries apply to machine
+// mode (which is the only mode TinyGo programs run in). They can't be changed
+// until the next reset. Memory that isn't covered by an entry can still be
+// accessed as usual.
+
+import (
+	"device/riscv"
+	"unsafe"
+)
+
+//go:extern _stext
+var _stext [0]byte
+
+//go:extern _etext
+var _etext [0]byte
+
+// Size of the region at address zero that can't be accessed.
+const nullGuardSize = 256
+
+// PMP configuration bits.
+const (
+	pmpR     = 0x01
+	pmpW     = 0x02
+	pmpX     = 0x04
+	pmpTOR   = 0x08 // top of range: from the previous address to this one
+	pmpNAPOT = 0x18 // naturally aligned power of two
+	pmpL     = 0x80 // locked, also applies to machine mode
+)
+
+func initMemoryProtection() {
+	// Entry 0: null guard, only if the program doesn't start in it.
+	cfg := uintptr(0)
+	if uintptr(unsafe.Pointer(&_stext)) >= nullGuardSize {
+		riscv.PMPADDR0.Set((nullGuardSize/2 - 1) >> 2)
+		if riscv.PMPADDR0.Get() == 0 {
+			// PMP is not implemented (the register is hardwired to zero).
+			return
+		}
+		cfg |= pmpNAPOT | pmpL
+	}
+
+	// Entries 1 and 2: the program code, with entry 1 only used as the start
+	// address of entry 2.
+	riscv.PMPADDR1.Set(uintptr(unsafe.Pointer(&_stext)) >> 2)
+	riscv.PMPADDR2.Set(uintptr(unsafe.Pointer(&_etext)) >> 2)
+	cfg |= (pmpTOR | pmpL | pmpR | pmpX) << 16
+
+	riscv.PMPCFG0.Set(cfg)
+}
@@ -81,7 +81,7 @@ func main(argc int32, argv *unsafe.Pointer) int {
 	runMain()
 
 	// For libc compatibility.
-	return 0
+	return raceExit(0)
 }
 
 var (
@@ -197,7 +197,7 @@ func now() (sec int64, nsec int32, mono int64) {
 
 //go:linkname syscall_Exit syscall.Exit
 func syscall_Exit(code int) {
-	exit(code)
+	exit(raceExit(code))
 }
 
 // TinyGo does not yet support any form of parallelism on an OS, so these can be
@@ -12,12 +12,19 @@ var wasmNested bool
 
 //export _start
 func _start() {
-	// These need to be initialized early so that the heap can be initialized.
-	heapStart = uintptr(unsafe.Pointer(&heapStartSymbol))
-	heapEnd = uintptr(wasm_memory_size(0) * wasmPageSize)
-
 	wasmNested = true
-	run()
+	if wasmInitialized {
+		// A function exported with //go:wasmexport was called before _start,
+		// which already initialized the runtime and all packages.
+		runMain()
+	} else {
+		// These need to be initialized early so that the heap can be
+		// initialized.
+		heapStart = uintptr(unsafe.Pointer(&heapStartSymbol))
+		heapEnd = uintptr(wasm_memory_size(0) * wasmPageSize)
+		wasmInitialized = true
+		run()
+	}
 	wasmNested = false
 }
 
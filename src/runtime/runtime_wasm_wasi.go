@@ -15,9 +15,17 @@ func __wasm_call_ctors()
 
 //export _start
 func _start() {
+	if wasmInitialized {
+		// A function exported with //go:wasmexport was called before _start,
+		// which already initialized the runtime and all packages.
+		runMain()
+		return
+	}
+
 	// These need to be initialized early so that the heap can be initialized.
 	heapStart = uintptr(unsafe.Pointer(&heapStartSymbol))
 	heapEnd = uintptr(wasm_memory_size(0) * wasmPageSize)
+	wasmInitialized = true
 	run()
 }
 
@@ -77,6 +77,9 @@ func Goexit() {
 
 // Add this task to the end of the run queue.
 func runqueuePushBack(t *task.Task) {
+	if latencyStats {
+		t.SetRunnable(uint64(ticks()), interrupt.In())
+	}
 	runqueue.Push(t)
 }
 
@@ -175,6 +178,10 @@ func scheduler() {
 			sleepQueueBaseTime += timeUnit(t.Data)
 			sleepQueue = t.Next
 			t.Next = nil
+			if latencyStats {
+				// The task became runnable when it should have woken up.
+				t.SetRunnable(uint64(sleepQueueBaseTime), false)
+			}
 			runqueue.Push(t)
 		}
 
@@ -233,6 +240,7 @@ func scheduler() {
 
 		// Run the given task.
 		scheduleLogTask("  run:", t)
+		recordDispatchLatency(t)
 		t.Resume()
 	}
 }
@@ -250,12 +258,13 @@ func minSched() {
 		}
 
 		scheduleLogTask("  run:", t)
+		recordDispatchLatency(t)
 		t.Resume()
 	}
 	scheduleLog("stop nested scheduler")
 }
 
 func Gosched() {
-	runqueue.Push(task.Current())
+	runqueuePushBack(task.Current())
 	task.Pause()
 }
@@ -28,4 +28,26 @@ func run() {
 	scheduler()
 }
 
+// initialize initializes the heap and all packages without running main, for
+// programs that are used as a library (see wasmExportInit).
+func initialize() {
+	initHeap()
+	go func() {
+		initAll()
+		schedulerDone = true
+	}()
+	scheduler()
+}
+
+// runMain runs the main function of a program that was already initialized by
+// initialize.
+func runMain() {
+	schedulerDone = false
+	go func() {
+		callMain()
+		schedulerDone = true
+	}()
+	scheduler()
+}
+
 const hasScheduler = true
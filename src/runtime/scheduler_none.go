@@ -25,4 +25,17 @@ func run() {
 	callMain()
 }
 
+// initialize initializes the heap and all packages without running main, for
+// programs that are used as a library (see wasmExportInit).
+func initialize() {
+	initHeap()
+	initAll()
+}
+
+// runMain runs the main function of a program that was already initialized by
+// initialize.
+func runMain() {
+	callMain()
+}
+
 const hasScheduler = false
@@ -0,0 +1,16 @@
+package runtime
+
+// traceSite is the position of a call or memory access, emitted as a constant
+// by the compiler. It is used in stack traces with -panic=trace and in data
+// race reports with -race.
+type traceSite struct {
+	function *traceFunc
+	line     uint32
+}
+
+// traceFunc is the function that contains a trace site, emitted as a constant
+// by the compiler.
+type traceFunc struct {
+	name string
+	file string
+}
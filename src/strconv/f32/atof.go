@@ -0,0 +1,412 @@
+package f32
+
+// Decimal to binary floating point conversion, copied from the strconv package
+// of the Go standard library and specialized for float32 values. Numbers that
+// can be converted exactly with a single float32 multiplication or division
+// take a fast path, all others are converted exactly using the multiprecision
+// decimal type.
+
+import (
+	"math"
+	"strconv"
+)
+
+// ParseFloat converts the string s to a float32 value. It accepts the same
+// decimal numbers and special values ("inf", "infinity" and "nan", ignoring
+// case, with an optional sign) as strconv.ParseFloat, and returns the same
+// result as strconv.ParseFloat(s, 32). Hexadecimal floating-point numbers and
+// underscores are not supported.
+//
+// The errors that ParseFloat returns have concrete type *strconv.NumError and
+// include err.Num = s, like those of strconv.ParseFloat. If s is syntactically
+// well-formed but is more than 1/2 ULP away from the largest float32 value,
+// ParseFloat returns f = ±Inf, err.Err = strconv.ErrRange.
+func ParseFloat(s string) (float32, error) {
+	if val, ok := special(s); ok {
+		return val, nil
+	}
+
+	mantissa, exp, neg, trunc, ok := readFloat(s)
+	if !ok {
+		return 0, &strconv.NumError{Func: "ParseFloat", Num: s, Err: strconv.ErrSyntax}
+	}
+	if !trunc {
+		if f, ok := atof32exact(mantissa, exp, neg); ok {
+			return f, nil
+		}
+	}
+
+	var d decimal
+	d.set(s)
+	b, overflow := d.floatBits()
+	f := math.Float32frombits(b)
+	if overflow {
+		return f, &strconv.NumError{Func: "ParseFloat", Num: s, Err: strconv.ErrRange}
+	}
+	return f, nil
+}
+
+func lower(c byte) byte {
+	return c | ('x' - 'X')
+}
+
+// commonPrefixLenIgnoreCase returns the length of the common
+// prefix of s and prefix, with the character case of s ignored.
+// The prefix argument must be all lower-case.
+func commonPrefixLenIgnoreCase(s, prefix string) int {
+	n := len(prefix)
+	if n > len(s) {
+		n = len(s)
+	}
+	for i := 0; i < n; i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		if c != prefix[i] {
+			return i
+		}
+	}
+	return n
+}
+
+// special returns the floating-point value for the special, possibly signed
+// floating-point representations inf, infinity, and NaN, if s is one of them.
+func special(s string) (f float32, ok bool) {
+	if len(s) == 0 {
+		return 0, false
+	}
+	var sign uint32
+	switch s[0] {
+	case '+', '-':
+		if s[0] == '-' {
+			sign = 1 << (expBits + mantBits)
+		}
+		s = s[1:]
+		fallthrough
+	case 'i', 'I':
+		n := commonPrefixLenIgnoreCase(s, "infinity")
+		if (n == 3 || n == 8) && n == len(s) {
+			return math.Float32frombits(sign | (1<<expBits-1)<<mantBits), true
+		}
+	case 'n', 'N':
+		if commonPrefixLenIgnoreCase(s, "nan") == 3 && len(s) == 3 {
+			return math.Float32frombits(0x7fc00000), true
+		}
+	}
+	return 0, false
+}
+
+// readFloat reads a decimal mantissa and exponent from a float string
+// representation in s. It only keeps the first 9 significant digits in
+// mantissa, which is enough for the fast path in atof32exact, and sets trunc
+// if any nonzero digits were dropped.
+func readFloat(s string) (mantissa uint32, exp int, neg, trunc, ok bool) {
+	const maxMantDigits = 9 // 10^9 fits in uint32
+	i := 0
+
+	// optional sign
+	if i >= len(s) {
+		return
+	}
+	switch {
+	case s[i] == '+':
+		i++
+	case s[i] == '-':
+		neg = true
+		i++
+	}
+
+	// digits
+	sawdot := false
+	sawdigits := false
+	nd := 0
+	ndMant := 0
+	dp := 0
+loop:
+	for ; i < len(s); i++ {
+		switch c := s[i]; true {
+		case c == '.':
+			if sawdot {
+				break loop
+			}
+			sawdot = true
+			dp = nd
+			continue
+
+		case '0' <= c && c <= '9':
+			sawdigits = true
+			if c == '0' && nd == 0 { // ignore leading zeros
+				dp--
+				continue
+			}
+			nd++
+			if ndMant < maxMantDigits {
+				mantissa *= 10
+				mantissa += uint32(c - '0')
+				ndMant++
+			} else if c != '0' {
+				trunc = true
+			}
+			continue
+		}
+		break
+	}
+	if !sawdigits {
+		return
+	}
+	if !sawdot {
+		dp = nd
+	}
+
+	// optional exponent moves decimal point.
+	// if we read a very large, very long number,
+	// just be sure to move the decimal point by
+	// a lot (say, 100000). it doesn't matter if it's
+	// not the exact number.
+	if i < len(s) && lower(s[i]) == 'e' {
+		i++
+		if i >= len(s) {
+			return
+		}
+		esign := 1
+		if s[i] == '+' {
+			i++
+		} else if s[i] == '-' {
+			i++
+			esign = -1
+		}
+		if i >= len(s) || s[i] < '0' || s[i] > '9' {
+			return
+		}
+		e := 0
+		for ; i < len(s) && '0' <= s[i] && s[i] <= '9'; i++ {
+			if e < 10000 {
+				e = e*10 + int(s[i]) - '0'
+			}
+		}
+		dp += e * esign
+	}
+
+	if i != len(s) {
+		return
+	}
+	if mantissa != 0 {
+		exp = dp - ndMant
+	}
+	ok = true
+	return
+}
+
+// Exact powers of 10.
+var float32pow10 = [...]float32{1e0, 1e1, 1e2, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9, 1e10}
+
+// If possible to convert decimal representation to 32-bit float f exactly,
+// entirely in floating-point math, do so, avoiding the expense of decimalToFloatBits.
+// Three common cases:
+//
+//	value is exact integer
+//	value is exact integer * exact power of ten
+//	value is exact integer / exact power of ten
+//
+// These all produce potentially inexact but correctly rounded answers.
+func atof32exact(mantissa uint32, exp int, neg bool) (f float32, ok bool) {
+	if mantissa>>mantBits != 0 {
+		return
+	}
+	f = float32(mantissa)
+	if neg {
+		f = -f
+	}
+	switch {
+	case exp == 0:
+		return f, true
+	// Exact integers are <= 10^7.
+	// Exact powers of ten are <= 10^10.
+	case exp > 0 && exp <= 7+10: // int * 10^k
+		// If exponent is big but number of digits is not,
+		// can move a few zeros into the integer part.
+		if exp > 10 {
+			f *= float32pow10[exp-10]
+			exp = 10
+		}
+		if f > 1e7 || f < -1e7 {
+			// the exponent was really too large.
+			return
+		}
+		return f * float32pow10[exp], true
+	case exp < 0 && exp >= -10: // int / 10^k
+		return f / float32pow10[-exp], true
+	}
+	return
+}
+
+// set reads the decimal number s into b. The caller must have checked that s
+// is a valid number using readFloat.
+func (b *decimal) set(s string) {
+	i := 0
+	b.neg = false
+	b.trunc = false
+
+	// optional sign
+	switch {
+	case s[i] == '+':
+		i++
+	case s[i] == '-':
+		b.neg = true
+		i++
+	}
+
+	// digits
+	sawdot := false
+	for ; i < len(s); i++ {
+		switch {
+		case s[i] == '.':
+			sawdot = true
+			b.dp = b.nd
+			continue
+
+		case '0' <= s[i] && s[i] <= '9':
+			if s[i] == '0' && b.nd == 0 { // ignore leading zeros
+				b.dp--
+				continue
+			}
+			if b.nd < len(b.d) {
+				b.d[b.nd] = s[i]
+				b.nd++
+			} else if s[i] != '0' {
+				b.trunc = true
+			}
+			continue
+		}
+		break
+	}
+	if !sawdot {
+		b.dp = b.nd
+	}
+
+	// optional exponent moves decimal point.
+	// if we read a very large, very long number,
+	// just be sure to move the decimal point by
+	// a lot (say, 100000). it doesn't matter if it's
+	// not the exact number.
+	if i < len(s) && lower(s[i]) == 'e' {
+		i++
+		esign := 1
+		if s[i] == '+' {
+			i++
+		} else if s[i] == '-' {
+			i++
+			esign = -1
+		}
+		e := 0
+		for ; i < len(s) && '0' <= s[i] && s[i] <= '9'; i++ {
+			if e < 10000 {
+				e = e*10 + int(s[i]) - '0'
+			}
+		}
+		b.dp += e * esign
+	}
+}
+
+// decimal power of ten to binary power of two.
+var powtab = [...]int{1, 3, 6, 9, 13, 16, 19, 23, 26}
+
+// floatBits converts d to the bits of the closest float32 value, and reports
+// whether the value was out of range.
+func (d *decimal) floatBits() (b uint32, overflow bool) {
+	var exp int
+	var mant uint32
+
+	// Zero is always a special case.
+	if d.nd == 0 {
+		mant = 0
+		exp = bias
+		goto out
+	}
+
+	// Obvious overflow/underflow.
+	// These bounds are for 32-bit floats.
+	if d.dp > 40 {
+		goto overflow
+	}
+	if d.dp < -50 {
+		// zero
+		mant = 0
+		exp = bias
+		goto out
+	}
+
+	// Scale by powers of two until in range [0.5, 1.0)
+	exp = 0
+	for d.dp > 0 {
+		var n int
+		if d.dp >= len(powtab) {
+			n = 27
+		} else {
+			n = powtab[d.dp]
+		}
+		d.Shift(-n)
+		exp += n
+	}
+	for d.dp < 0 || d.dp == 0 && d.d[0] < '5' {
+		var n int
+		if -d.dp >= len(powtab) {
+			n = 27
+		} else {
+			n = powtab[-d.dp]
+		}
+		d.Shift(n)
+		exp -= n
+	}
+
+	// Our range is [0.5,1) but floating point range is [1,2).
+	exp--
+
+	// Minimum representable exponent is bias+1.
+	// If the exponent is smaller, move it up and
+	// adjust d accordingly.
+	if exp < bias+1 {
+		n := bias + 1 - exp
+		d.Shift(-n)
+		exp += n
+	}
+
+	if exp-bias >= 1<<expBits-1 {
+		goto overflow
+	}
+
+	// Extract 1+mantBits bits.
+	d.Shift(1 + mantBits)
+	mant = d.RoundedInteger()
+
+	// Rounding might have added a bit; shift down.
+	if mant == 2<<mantBits {
+		mant >>= 1
+		exp++
+		if exp-bias >= 1<<expBits-1 {
+			goto overflow
+		}
+	}
+
+	// Denormalized?
+	if mant&(1<<mantBits) == 0 {
+		exp = bias
+	}
+	goto out
+
+overflow:
+	// ±Inf
+	mant = 0
+	exp = 1<<expBits - 1 + bias
+	overflow = true
+
+out:
+	// Assemble bits.
+	b = mant & (1<<mantBits - 1)
+	b |= uint32((exp-bias)&(1<<expBits-1)) << mantBits
+	if d.neg {
+		b |= 1 << mantBits << expBits
+	}
+	return b, overflow
+}
@@ -0,0 +1,120 @@
+package f32_test
+
+import (
+	"math"
+	"strconv"
+	"strconv/f32"
+	"testing"
+)
+
+// Values that are hard to format or parse: powers of two and ten, the
+// smallest and largest normal and denormal values, and values near the
+// rounding boundaries.
+var testValues = []float32{
+	0, 1, -1, 0.1, -0.1, 0.5, 1.5, 2.5, 3.14159265, 1e7, 1e-7, 123456789,
+	1e10, 1e20, 1e30, 1e38, 1e-10, 1e-20, 1e-30, 1e-38, 1e-40, 1e-45,
+	16777216, 16777217, 33554430, 0.3, 2.2, 9.999999, 99.99, 0.05, 0.005,
+	math.MaxFloat32, -math.MaxFloat32, math.SmallestNonzeroFloat32,
+	1.17549435e-38, 1.1754942e-38, 7.038531e-26, 3.4028234e38,
+}
+
+func testFloats() []float32 {
+	values := append([]float32(nil), testValues...)
+
+	// Walk through the whole exponent range with a few mantissas.
+	for exp := uint32(0); exp < 255; exp++ {
+		for _, mant := range []uint32{0, 1, 0x2aaaaa, 0x400000, 0x555555, 0x7fffff} {
+			values = append(values, math.Float32frombits(exp<<23|mant))
+		}
+	}
+
+	// Add some pseudo-random bit patterns.
+	x := uint32(12345)
+	for i := 0; i < 1000; i++ {
+		x ^= x << 13
+		x ^= x >> 17
+		x ^= x << 5
+		if x>>23&0xff == 0xff {
+			continue // Inf or NaN
+		}
+		values = append(values, math.Float32frombits(x))
+	}
+	return values
+}
+
+func TestFormatFloat(t *testing.T) {
+	formats := []byte{'e', 'E', 'f', 'g', 'G'}
+	precs := []int{-1, 0, 1, 2, 5, 8, 20}
+	for _, f := range testFloats() {
+		for _, format := range formats {
+			for _, prec := range precs {
+				if format == 'f' && prec == 20 && (f > 1e30 || f < -1e30) {
+					continue // very long and not more interesting
+				}
+				got := f32.FormatFloat(f, format, prec)
+				want := strconv.FormatFloat(float64(f), format, prec, 32)
+				if got != want {
+					t.Errorf("FormatFloat(%#08x, '%c', %d): got %s, want %s", math.Float32bits(f), format, prec, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestFormatFloatSpecial(t *testing.T) {
+	inf := float32(math.Inf(1))
+	tests := []struct {
+		f    float32
+		want string
+	}{
+		{inf, "+Inf"},
+		{-inf, "-Inf"},
+		{float32(math.NaN()), "NaN"},
+		{float32(math.Copysign(0, -1)), "-0"},
+	}
+	for _, tc := range tests {
+		if got := f32.FormatFloat(tc.f, 'g', -1); got != tc.want {
+			t.Errorf("FormatFloat(%v): got %s, want %s", tc.f, got, tc.want)
+		}
+	}
+}
+
+func TestAppendFloat(t *testing.T) {
+	got := string(f32.AppendFloat([]byte("x="), 1.25, 'f', 1))
+	if got != "x=1.2" {
+		t.Errorf("AppendFloat: got %s, want x=1.2", got)
+	}
+}
+
+func TestParseFloat(t *testing.T) {
+	inputs := []string{
+		"0", "-0", "+1", "1.", ".5", "0.1", "1e10", "1E-10", "123456789",
+		"16777217", "3.4028235e38", "3.4028236e38", "3.5e38", "1e39", "-1e39",
+		"1e-45", "7e-46", "7.1e-46", "1e-46", "1.17549435e-38",
+		"0.000000000000000000000000000000000000000000001401298464324817070923729583289916131280261941876515771757068283889791082685860601486638188362121582031250000001",
+		"1.00000005960464477539062499999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999",
+		"1.000000059604644775390625", "1.000000178813934326171875",
+		"123456.789e-3", "00000001.5", "1e+0", "1000000000000e-12",
+		"inf", "-Inf", "+INFINITY", "nan", "NaN",
+		"", "-", "e1", "1e", "1e+", "1.2.3", "1x", "infin", "+nan", "0x1p-2", "1_000",
+	}
+	for _, f := range testFloats() {
+		inputs = append(inputs, strconv.FormatFloat(float64(f), 'g', -1, 32))
+		inputs = append(inputs, strconv.FormatFloat(float64(f), 'e', 12, 64))
+	}
+	for _, s := range inputs {
+		got, gotErr := f32.ParseFloat(s)
+		want64, wantErr := strconv.ParseFloat(s, 32)
+		want := float32(want64)
+		if s == "0x1p-2" || s == "1_000" {
+			// Not supported by this package.
+			want, wantErr = 0, &strconv.NumError{Func: "ParseFloat", Num: s, Err: strconv.ErrSyntax}
+		}
+		if math.Float32bits(got) != math.Float32bits(want) && !(got != got && want != want) {
+			t.Errorf("ParseFloat(%q): got %v, want %v", s, got, want)
+		}
+		if (gotErr == nil) != (wantErr == nil) || gotErr != nil && gotErr.Error() != wantErr.Error() {
+			t.Errorf("ParseFloat(%q): got error %v, want %v", s, gotErr, wantErr)
+		}
+	}
+}
@@ -1,8 +1,9 @@
 package sync
 
 import (
+	"internal/race"
 	"internal/task"
-	_ "unsafe"
+	"unsafe"
 )
 
 type Mutex struct {
@@ -18,10 +19,13 @@ func (m *Mutex) Lock() {
 		// Push self onto stack of blocked tasks, and wait to be resumed.
 		m.blocked.Push(task.Current())
 		task.Pause()
-		return
+	} else {
+		m.locked = true
 	}
 
-	m.locked = true
+	if race.Enabled {
+		race.Acquire(unsafe.Pointer(m))
+	}
 }
 
 func (m *Mutex) Unlock() {
@@ -29,6 +33,10 @@ func (m *Mutex) Unlock() {
 		panic("sync: unlock of unlocked Mutex")
 	}
 
+	if race.Enabled {
+		race.Release(unsafe.Pointer(m))
+	}
+
 	// Wake up a blocked task, if applicable.
 	if t := m.blocked.Pop(); t != nil {
 		scheduleTask(t)
@@ -62,12 +70,17 @@ func (rw *RWMutex) Lock() {
 		// The mutex is completely unlocked.
 		// Lock without waiting.
 		rw.state = rwMutexStateWLocked
-		return
+	} else {
+		// Wait for the lock to be released.
+		rw.waitingWriters.Push(task.Current())
+		task.Pause()
 	}
 
-	// Wait for the lock to be released.
-	rw.waitingWriters.Push(task.Current())
-	task.Pause()
+	if race.Enabled {
+		// Synchronize with the last writer and with all readers.
+		race.Acquire(unsafe.Pointer(rw))
+		race.Acquire(unsafe.Pointer(&rw.waitingReaders))
+	}
 }
 
 func (rw *RWMutex) Unlock() {
@@ -84,6 +97,10 @@ func (rw *RWMutex) Unlock() {
 		panic("sync: write-unlock of read-locked RWMutex")
 	}
 
+	if race.Enabled {
+		race.Release(unsafe.Pointer(rw))
+	}
+
 	switch {
 	case rw.maybeUnblockReaders():
 		// Switched over to read mode.
@@ -102,15 +119,18 @@ func (rw *RWMutex) RLock() {
 		// Wait for the write lock to be released.
 		rw.waitingReaders.Push(task.Current())
 		task.Pause()
-		return
-	}
+	} else {
+		if rw.state == rwMutexMaxReaders {
+			panic("sync: too many readers on RWMutex")
+		}
 
-	if rw.state == rwMutexMaxReaders {
-		panic("sync: too many readers on RWMutex")
+		// Increase the reader count.
+		rw.state++
 	}
 
-	// Increase the reader count.
-	rw.state++
+	if race.Enabled {
+		race.Acquire(unsafe.Pointer(rw))
+	}
 }
 
 func (rw *RWMutex) RUnlock() {
@@ -124,6 +144,11 @@ func (rw *RWMutex) RUnlock() {
 		panic("sync: read-unlock of write-locked RWMutex")
 	}
 
+	if race.Enabled {
+		// Readers only synchronize with the next writer.
+		race.ReleaseMerge(unsafe.Pointer(&rw.waitingReaders))
+	}
+
 	rw.state--
 
 	if rw.state == rwMutexStateUnlocked {
@@ -1,6 +1,10 @@
 package sync
 
-import "internal/task"
+import (
+	"internal/race"
+	"internal/task"
+	"unsafe"
+)
 
 type WaitGroup struct {
 	counter uint
@@ -22,6 +26,10 @@ func (wg *WaitGroup) Add(delta int) {
 			panic("sync: negative WaitGroup counter")
 		}
 
+		if race.Enabled {
+			race.ReleaseMerge(unsafe.Pointer(wg))
+		}
+
 		// Subtract from the counter.
 		wg.counter -= uint(-delta)
 
@@ -41,14 +49,15 @@ func (wg *WaitGroup) Done() {
 }
 
 func (wg *WaitGroup) Wait() {
-	if wg.counter == 0 {
-		// Everything already finished.
-		return
-	}
+	if wg.counter != 0 {
+		// Push the current goroutine onto the waiter stack.
+		wg.waiters.Push(task.Current())
 
-	// Push the current goroutine onto the waiter stack.
-	wg.waiters.Push(task.Current())
+		// Pause until the waiters are awoken by Add/Done.
+		task.Pause()
+	}
 
-	// Pause until the waiters are awoken by Add/Done.
-	task.Pause()
+	if race.Enabled {
+		race.Acquire(unsafe.Pointer(wg))
+	}
 }
@@ -0,0 +1,64 @@
+package testing
+
+// Code coverage support for tinygo test -cover. The compiler adds counters to
+// the package under test, which are read from the runtime.
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+)
+
+// readCoverage calls fn for every coverage block in the program and returns
+// the coverage mode. It is implemented in the runtime.
+func readCoverage(fn func(file string, startLine, startCol, endLine, endCol, numStmt, count uint32)) string
+
+// CoverMode reports what the test coverage mode is set to. The values are
+// "set" and "count", or the empty string if coverage is not enabled.
+func CoverMode() string {
+	return readCoverage(func(file string, startLine, startCol, endLine, endCol, numStmt, count uint32) {})
+}
+
+// Coverage reports the current code coverage as a fraction in the range
+// [0, 1]. If coverage is not enabled, Coverage returns 0.
+func Coverage() float64 {
+	var total, covered uint64
+	readCoverage(func(file string, startLine, startCol, endLine, endCol, numStmt, count uint32) {
+		total += uint64(numStmt)
+		if count != 0 {
+			covered += uint64(numStmt)
+		}
+	})
+	if total == 0 {
+		return 0
+	}
+	return float64(covered) / float64(total)
+}
+
+// coverReport prints the coverage summary and writes the coverage profile to
+// the file given with -test.coverprofile, if any.
+func coverReport() {
+	var buf bytes.Buffer
+	var total int
+	mode := readCoverage(func(file string, startLine, startCol, endLine, endCol, numStmt, count uint32) {
+		fmt.Fprintf(&buf, "%s:%d.%d,%d.%d %d %d\n", file, startLine, startCol, endLine, endCol, numStmt, count)
+		total += int(numStmt)
+	})
+	if mode == "" {
+		return
+	}
+
+	if flagCoverProfile != "" {
+		data := append([]byte("mode: "+mode+"\n"), buf.Bytes()...)
+		if err := os.WriteFile(flagCoverProfile, data, 0666); err != nil {
+			fmt.Fprintf(os.Stderr, "testing: can't write coverage profile: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	if total == 0 {
+		fmt.Println("coverage: [no statements]")
+		return
+	}
+	fmt.Printf("coverage: %.1f%% of statements\n", 100*Coverage())
+}
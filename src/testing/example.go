@@ -0,0 +1,95 @@
+package testing
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	_ "unsafe"
+)
+
+// captureStdout replaces os.Stdout with an in-memory file and returns a
+// function that restores it and returns what was written. It is implemented in
+// the os package.
+//
+//go:linkname captureStdout os.captureStdout
+func captureStdout() func() string
+
+// runExamples runs the examples that match -test.run and compares their output
+// against the expected output.
+func runExamples(matchString func(pat, str string) (bool, error), examples []InternalExample) (ran, ok bool) {
+	ok = true
+
+	m := newMatcher(matchString, flagRunRegexp, "-test.run", flagSkipRegexp)
+	for _, eg := range examples {
+		_, matched, _ := m.fullName(nil, eg.Name)
+		if !matched {
+			continue
+		}
+		ran = true
+		if !runExample(eg) {
+			ok = false
+		}
+	}
+
+	return ran, ok
+}
+
+func runExample(eg InternalExample) (ok bool) {
+	if flagVerbose {
+		fmt.Printf("=== RUN   %s\n", eg.Name)
+	}
+
+	finishCapture := captureStdout()
+	start := time.Now()
+	finished := false
+	defer func() {
+		timeSpent := time.Since(start)
+		out := finishCapture()
+		ok = eg.processRunResult(out, timeSpent, finished, recover())
+	}()
+
+	eg.F()
+	finished = true
+	return
+}
+
+// processRunResult prints the result of the example, like the gc toolchain.
+// It panics again if the example panicked.
+func (eg *InternalExample) processRunResult(stdout string, timeSpent time.Duration, finished bool, recovered interface{}) (passed bool) {
+	passed = true
+	dstr := fmtDuration(timeSpent)
+	var fail string
+	got := strings.TrimSpace(stdout)
+	want := strings.TrimSpace(eg.Output)
+	if eg.Unordered {
+		if sortLines(got) != sortLines(want) && recovered == nil {
+			fail = fmt.Sprintf("got:\n%s\nwant (unordered):\n%s\n", stdout, eg.Output)
+		}
+	} else {
+		if got != want && recovered == nil {
+			fail = fmt.Sprintf("got:\n%s\nwant:\n%s\n", got, want)
+		}
+	}
+	if fail != "" || !finished || recovered != nil {
+		fmt.Printf("--- FAIL: %s (%s)\n%s", eg.Name, dstr, fail)
+		passed = false
+	} else if flagVerbose {
+		fmt.Printf("--- PASS: %s (%s)\n", eg.Name, dstr)
+	}
+
+	if recovered != nil {
+		// Propagate the recovered panic.
+		panic(recovered)
+	}
+	if !finished {
+		panic("test executed panic(nil) or runtime.Goexit")
+	}
+	return
+}
+
+func sortLines(output string) string {
+	lines := strings.Split(output, "\n")
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,27 @@
+package testing_test
+
+import (
+	"fmt"
+	"os"
+)
+
+// These examples are run by the test binary, which compares their output with
+// the Output comment.
+
+func Example_output() {
+	fmt.Println("hello")
+	fmt.Fprintln(os.Stdout, "world")
+	// Output:
+	// hello
+	// world
+}
+
+func Example_unordered() {
+	for _, s := range []string{"b", "c", "a"} {
+		fmt.Println(s)
+	}
+	// Unordered output:
+	// a
+	// b
+	// c
+}
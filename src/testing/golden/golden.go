@@ -0,0 +1,281 @@
+// Package golden compares the output of a test against a golden file: a file
+// with the expected output that is stored next to the test.
+//
+// The golden files are embedded in the test binary with //go:embed, so that
+// they can be used in tests that run on a device or in a WebAssembly runtime
+// without access to the filesystem of the host:
+//
+//	//go:embed testdata/*.golden
+//	var goldenFiles embed.FS
+//
+//	func TestReport(t *testing.T) {
+//		golden.Check(t, goldenFiles, "testdata/report.golden", report())
+//	}
+//
+// When the output doesn't match, the test fails with a line-based diff
+// between the golden file and the actual output.
+//
+// To update the golden files after an intended change, run the tests on a
+// system with a filesystem (like the host) with the environment variable
+// UPDATE_GOLDEN set, for example with tinygo test -env=UPDATE_GOLDEN=1. The
+// files are written relative to the package directory, which is where tests
+// are run.
+package golden
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Check compares got against the golden file with the given name in fsys,
+// which is usually an embed.FS. The test fails if they differ, or if the
+// golden file can't be read.
+func Check(t testing.TB, fsys fs.FS, name string, got []byte) {
+	t.Helper()
+	if os.Getenv("UPDATE_GOLDEN") != "" {
+		if err := os.WriteFile(name, got, 0666); err != nil {
+			t.Fatalf("golden: cannot update %s: %v", name, err)
+		}
+		t.Logf("golden: updated %s", name)
+		return
+	}
+	want, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		t.Fatalf("golden: %v", err)
+	}
+	Equal(t, name, got, want)
+}
+
+// CheckString is like Check, but for output in a string.
+func CheckString(t testing.TB, fsys fs.FS, name string, got string) {
+	t.Helper()
+	Check(t, fsys, name, []byte(got))
+}
+
+// Equal compares got against the contents of the golden file with the given
+// name, for golden files that are embedded in a []byte or string variable.
+// The test fails with a diff if they differ.
+func Equal[T []byte | string](t testing.TB, name string, got, want T) {
+	t.Helper()
+	if string(got) == string(want) {
+		return
+	}
+	t.Errorf("golden: output differs from %s (-want +got):\n%s", name, Diff(string(want), string(got)))
+}
+
+// Diff returns a line-based diff between want and got in a format similar to
+// a unified diff, with a few lines of context around every change. It returns
+// the empty string if want and got are equal.
+func Diff(want, got string) string {
+	if want == got {
+		return ""
+	}
+	a := splitLines(want)
+	b := splitLines(got)
+	edits := diffLines(a, b)
+
+	var buf bytes.Buffer
+	const context = 3
+	for i := 0; i < len(edits); {
+		// Find the next change.
+		for i < len(edits) && edits[i].op == ' ' {
+			i++
+		}
+		if i == len(edits) {
+			break
+		}
+		// Extend the hunk until there are more than 2*context unchanged
+		// lines in a row, or until the end.
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for j := i; j < len(edits); j++ {
+			if edits[j].op != ' ' {
+				end = j + 1
+			} else if j-end >= 2*context {
+				break
+			}
+		}
+		end += context
+		if end > len(edits) {
+			end = len(edits)
+		}
+
+		aLine, bLine := edits[start].aLine, edits[start].bLine
+		var aCount, bCount int
+		for _, e := range edits[start:end] {
+			if e.op != '+' {
+				aCount++
+			}
+			if e.op != '-' {
+				bCount++
+			}
+		}
+		buf.WriteString("@@ -" + strconv.Itoa(aLine+1) + "," + strconv.Itoa(aCount) + " +" + strconv.Itoa(bLine+1) + "," + strconv.Itoa(bCount) + " @@\n")
+		for _, e := range edits[start:end] {
+			buf.WriteByte(e.op)
+			buf.WriteByte(' ')
+			buf.WriteString(showLine(e.line))
+			buf.WriteByte('\n')
+		}
+		i = end
+	}
+	return buf.String()
+}
+
+// splitLines splits s into lines. A missing newline at the end of s is kept
+// as part of the last line, so that it shows up in the diff.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// showLine formats a line for the diff output. Lines without a newline at the
+// end are marked, like in the output of diff.
+func showLine(line string) string {
+	if strings.HasSuffix(line, "\n") {
+		return strings.TrimSuffix(line, "\n")
+	}
+	return line + "\n\\ No newline at end of file"
+}
+
+// edit is a single line in a diff: unchanged (' '), removed from want ('-')
+// or added in got ('+'). The line numbers are those of the line in want and
+// got, or of the next line if the line isn't in that file.
+type edit struct {
+	op           byte
+	line         string
+	aLine, bLine int
+}
+
+// maxEditDistance limits the work (and memory) used to find the shortest
+// diff, which matters on small devices. Beyond it, the remaining lines are
+// shown as removed and added as a whole.
+const maxEditDistance = 64
+
+// diffLines returns the edits to turn a into b, using the Myers diff
+// algorithm on the lines that remain after removing the common prefix and
+// suffix.
+func diffLines(a, b []string) []edit {
+	var edits []edit
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		edits = append(edits, edit{' ', a[prefix], prefix, prefix})
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	edits = append(edits, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix], prefix)...)
+
+	for i := 0; i < suffix; i++ {
+		ai := len(a) - suffix + i
+		bi := len(b) - suffix + i
+		edits = append(edits, edit{' ', a[ai], ai, bi})
+	}
+	return edits
+}
+
+// myers returns the shortest list of edits to turn a into b. Both start at
+// line offset in their files.
+func myers(a, b []string, offset int) []edit {
+	n, m := len(a), len(b)
+	// v[k+max] is the furthest x reached on diagonal k. trace keeps a copy of
+	// v for every edit distance d, to find the path back afterwards.
+	max := n + m
+	if max > maxEditDistance {
+		max = maxEditDistance
+	}
+	v := make([]int, 2*max+2)
+	var trace [][]int
+	found := false
+	var d int
+	for d = 0; d <= max && !found; d++ {
+		trace = append(trace, append([]int(nil), v[max-d+1:max+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[max+k-1] < v[max+k+1]) {
+				x = v[max+k+1] // insertion
+			} else {
+				x = v[max+k-1] + 1 // deletion
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[max+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+	if !found {
+		// Too many differences: show all lines as removed, then as added.
+		var edits []edit
+		for i, line := range a {
+			edits = append(edits, edit{'-', line, offset + i, offset})
+		}
+		for i, line := range b {
+			edits = append(edits, edit{'+', line, offset + n, offset + i})
+		}
+		return edits
+	}
+
+	// Walk back through the trace to find the edits.
+	var edits []edit
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		// trace[d] holds v[-d+1 .. d+1] as it was before step d, so the
+		// value for diagonal k is at trace[d][k+d-1].
+		prev := trace[d]
+		get := func(k int) int {
+			return prev[k+d-1]
+		}
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && get(k-1) < get(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := 0
+		if d > 0 {
+			prevX = get(prevK)
+		}
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			edits = append(edits, edit{' ', a[x], offset + x, offset + y})
+		}
+		if d > 0 {
+			if x == prevX {
+				y--
+				edits = append(edits, edit{'+', b[y], offset + x, offset + y})
+			} else {
+				x--
+				edits = append(edits, edit{'-', a[x], offset + x, offset + y})
+			}
+		}
+	}
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
@@ -0,0 +1,89 @@
+package golden_test
+
+import (
+	"embed"
+	"strings"
+	"testing"
+	"testing/golden"
+)
+
+//go:embed testdata/*.golden
+var goldenFiles embed.FS
+
+//go:embed testdata/hello.golden
+var hello string
+
+func TestCheck(t *testing.T) {
+	golden.CheckString(t, goldenFiles, "testdata/hello.golden", "Hello, golden file!\nsecond line\n")
+	golden.Equal(t, "testdata/hello.golden", "Hello, golden file!\nsecond line\n", hello)
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name      string
+		want, got string
+		diff      string
+	}{
+		{
+			name: "equal",
+			want: "a\nb\n",
+			got:  "a\nb\n",
+			diff: "",
+		},
+		{
+			name: "changed",
+			want: "a\nb\nc\n",
+			got:  "a\nB\nc\n",
+			diff: "@@ -1,3 +1,3 @@\n  a\n- b\n+ B\n  c\n",
+		},
+		{
+			name: "inserted",
+			want: "a\nc\n",
+			got:  "a\nb\nc\n",
+			diff: "@@ -1,2 +1,3 @@\n  a\n+ b\n  c\n",
+		},
+		{
+			name: "removed",
+			want: "a\nb\nc\n",
+			got:  "a\nc\n",
+			diff: "@@ -1,3 +1,2 @@\n  a\n- b\n  c\n",
+		},
+		{
+			name: "no newline",
+			want: "a\n",
+			got:  "a",
+			diff: "@@ -1,1 +1,1 @@\n- a\n+ a\n\\ No newline at end of file\n",
+		},
+		{
+			name: "separate hunks",
+			want: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n",
+			got:  "x\n2\n3\n4\n5\n6\n7\n8\n9\n10\ny\n",
+			diff: "@@ -1,4 +1,4 @@\n- 1\n+ x\n  2\n  3\n  4\n@@ -8,4 +8,4 @@\n  8\n  9\n  10\n- 11\n+ y\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			diff := golden.Diff(tc.want, tc.got)
+			if diff != tc.diff {
+				t.Errorf("unexpected diff:\n%s\nexpected:\n%s", diff, tc.diff)
+			}
+		})
+	}
+}
+
+func TestDiffLarge(t *testing.T) {
+	// Completely different inputs fall back to showing all lines as removed
+	// and added, to limit memory usage.
+	var want, got strings.Builder
+	for i := 0; i < 100; i++ {
+		want.WriteString("want\n")
+		got.WriteString("got\n")
+	}
+	diff := golden.Diff(want.String(), got.String())
+	if n := strings.Count(diff, "- want\n"); n != 100 {
+		t.Errorf("expected 100 removed lines, got %d", n)
+	}
+	if n := strings.Count(diff, "+ got\n"); n != 100 {
+		t.Errorf("expected 100 added lines, got %d", n)
+	}
+}
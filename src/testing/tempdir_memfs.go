@@ -0,0 +1,13 @@
+//go:build baremetal || (wasm && !wasi)
+
+package testing
+
+import (
+	_ "unsafe"
+)
+
+// There is no filesystem on these systems, so temporary directories are
+// created in an in-memory filesystem that's mounted by the os package.
+//
+//go:linkname mountTempFilesystem os.mountTempFilesystem
+func mountTempFilesystem()
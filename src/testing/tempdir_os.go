@@ -0,0 +1,7 @@
+//go:build !baremetal && !(wasm && !wasi)
+
+package testing
+
+// Temporary directories are created in the filesystem of the operating system
+// (or in the directory that's preopened at /tmp on WASI).
+func mountTempFilesystem() {}
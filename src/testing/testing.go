@@ -13,6 +13,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"internal/race"
 	"io"
 	"io/fs"
 	"math/rand"
@@ -32,6 +33,8 @@ var (
 	flagSkipRegexp string
 	flagShuffle    string
 	flagCount      int
+
+	flagCoverProfile string
 )
 
 var initRan bool
@@ -50,6 +53,7 @@ func Init() {
 	flag.StringVar(&flagShuffle, "test.shuffle", "off", "shuffle: off, on, <numeric-seed>")
 
 	flag.IntVar(&flagCount, "test.count", 1, "run each test or benchmark `count` times")
+	flag.StringVar(&flagCoverProfile, "test.coverprofile", "", "write a coverage profile to `file`")
 
 	initBenchmarkFlags()
 }
@@ -302,6 +306,8 @@ func (c *common) Cleanup(f func()) {
 // Each subsequent call to t.TempDir returns a unique directory;
 // if the directory creation fails, TempDir terminates the test by calling Fatal.
 func (c *common) TempDir() string {
+	mountTempFilesystem()
+
 	// Use a single parent directory for all the temporary directories
 	// created by a test, each numbered sequentially.
 	var nonExistent bool
@@ -414,9 +420,13 @@ func tRunner(t *T, fn func(t *T)) {
 	}()
 
 	// Run the test.
+	raceErrors := race.Errors()
 	t.start = time.Now()
 	fn(t)
 	t.duration += time.Since(t.start) // TODO: capture cleanup time, too.
+	if race.Errors() > raceErrors {
+		t.Errorf("race detected during execution of test")
+	}
 
 	t.report() // Report after all subtests have finished.
 	if t.parent != nil && !t.hasSub {
@@ -471,6 +481,7 @@ type M struct {
 	// tests is a list of the test names to execute
 	Tests      []InternalTest
 	Benchmarks []InternalBenchmark
+	Examples   []InternalExample
 
 	deps testDeps
 
@@ -522,16 +533,18 @@ func (m *M) Run() (code int) {
 	}
 
 	testRan, testOk := runTests(m.deps.MatchString, m.Tests)
-	if !testRan && *matchBenchmarks == "" {
+	exampleRan, exampleOk := runExamples(m.deps.MatchString, m.Examples)
+	if !testRan && !exampleRan && *matchBenchmarks == "" {
 		fmt.Fprintln(os.Stderr, "testing: warning: no tests to run")
 	}
-	if !testOk || !runBenchmarks(m.deps.MatchString, m.Benchmarks) {
+	if !testOk || !exampleOk || !runBenchmarks(m.deps.MatchString, m.Benchmarks) {
 		fmt.Println("FAIL")
 		m.exitCode = 1
 	} else {
 		fmt.Println("PASS")
 		m.exitCode = 0
 	}
+	coverReport()
 	return
 }
 
@@ -603,6 +616,7 @@ func MainStart(deps interface{}, tests []InternalTest, benchmarks []InternalBenc
 	return &M{
 		Tests:      tests,
 		Benchmarks: benchmarks,
+		Examples:   examples,
 		deps:       deps.(testDeps),
 	}
 }
@@ -25,11 +25,6 @@ func TestMain(m *testing.M) {
 }
 
 func TestTempDirInCleanup(t *testing.T) {
-	if isWASI {
-		t.Log("Skipping.  TODO: implement RemoveAll for wasi")
-		return
-	}
-
 	var dir string
 
 	t.Run("test", func(t *testing.T) {
@@ -62,11 +57,6 @@ func TestTempDirInBenchmark(t *testing.T) {
 }
 
 func TestTempDir(t *testing.T) {
-	if isWASI {
-		t.Log("Skipping.  TODO: implement RemoveAll for wasi")
-		return
-	}
-
 	testTempDir(t)
 	t.Run("InSubtest", testTempDir)
 	t.Run("test/subtest", testTempDir)
@@ -0,0 +1,42 @@
+// Package buildinfo describes the target the program is compiled for, as
+// constants that are set by the compiler. This allows libraries to adjust to
+// the target, or to refuse to compile on targets that are not suitable.
+//
+// The following constants are defined:
+//
+//	Target    // name of the target, like "arduino" or "linux/amd64"
+//	CPU       // the CPU (chip family), like "cortex-m4" or "atmega328p"
+//	GC        // the garbage collector, like "conservative" or "leaking"
+//	Scheduler // the scheduler, like "tasks" or "none"
+//	FlashSize // available flash (code) memory in bytes
+//	RAMSize   // available RAM in bytes
+//
+// The memory sizes are untyped integer constants. They are read from the
+// target linker script, after subtracting -code-offset and -ram-offset, and
+// are 0 if they are not known, for example when compiling for an operating
+// system or WebAssembly.
+//
+// The GC and Scheduler constants have a matching build tag, like
+// gc.conservative or scheduler.tasks, which can be used instead to select
+// different source files.
+package buildinfo
+
+// Sizes in bytes, for use with the memory size constants.
+const (
+	KB = 1024
+	MB = 1024 * KB
+)
+
+// AtLeast is used for compile-time assertions on the constants in this
+// package. A negative constant can't be converted to it, so the following
+// fails to compile on targets with less than 64KB of RAM:
+//
+//	const _ buildinfo.AtLeast = buildinfo.RAMSize - 64*buildinfo.KB
+//
+// The compiler error mentions this type and the missing amount, like this:
+//
+//	cannot use buildinfo.RAMSize - 64 * buildinfo.KB (untyped int constant -63488) as buildinfo.AtLeast value in constant declaration (overflows)
+//
+// Note that this also fails when the memory size isn't known, so such an
+// assertion should be in a file with a build tag like baremetal.
+type AtLeast uint64
@@ -229,6 +229,26 @@ func main() {
 	}
 	wg.Wait()
 	println("blocking select sum:", sum)
+
+	// Test that a random case is chosen when multiple cases are ready,
+	// including a mix of send and receive cases.
+	ready1 := make(chan int, 1)
+	ready2 := make(chan int, 1)
+	var counts [3]int
+	for i := 0; i < 300; i++ {
+		ready1 <- 1
+		select {
+		case <-ready1:
+			counts[0]++
+		case ready2 <- 2:
+			counts[1]++
+			<-ready2
+			<-ready1
+		case <-ready1:
+			counts[2]++
+		}
+	}
+	println("select fairness:", counts[0] > 50 && counts[1] > 50 && counts[2] > 50)
 }
 
 func send(ch chan<- int) {
@@ -0,0 +1,27 @@
+package main
+
+import "math/fixed"
+
+func main() {
+	a := fixed.Float32ToQ16(1.5)
+	b := fixed.IntToQ16(-3)
+	println("q16:", a.Float32(), b.Int())
+	println("add:", a.Add(b).Float32())
+	println("sub:", a.Sub(b).Float32())
+	println("mul:", a.Mul(b).Float32())
+	println("div:", b.Div(a).Float32())
+	println("saturate add:", fixed.MaxQ16.Add(fixed.One) == fixed.MaxQ16)
+	println("saturate sub:", fixed.MinQ16.Sub(fixed.One) == fixed.MinQ16)
+	println("saturate mul:", fixed.IntToQ16(300).Mul(fixed.IntToQ16(300)) == fixed.MaxQ16)
+	println("saturate int:", fixed.IntToQ16(1<<20) == fixed.MaxQ16)
+
+	x := fixed.Float32ToQ31(0.5)
+	y := fixed.Float32ToQ31(-0.25)
+	println("q31:", x.Float32(), y.Float32())
+	println("add:", x.Add(y).Float32())
+	println("sub:", x.Sub(y).Float32())
+	println("mul:", x.Mul(y).Float32())
+	println("saturate add:", x.Add(x) == fixed.MaxQ31)
+	println("saturate mul:", fixed.MinQ31.Mul(fixed.MinQ31) == fixed.MaxQ31)
+	println("convert:", x.Q16().Float32(), a.Q31() == fixed.MaxQ31)
+}
@@ -16,6 +16,8 @@ func main() {
 
 	testa.Test()
 	testb.Test()
+
+	testInterfaceAssert()
 }
 
 type Integer interface {
@@ -33,3 +35,33 @@ func (c *C[V]) F() {}
 
 // Test for https://github.com/tinygo-org/tinygo/issues/3002
 func SliceOp[S ~[]E, E any](s S) {}
+
+type Box[T any] struct {
+	v T
+}
+
+func (b Box[T]) Value() T {
+	return b.v
+}
+
+func (b Box[T]) Self() Box[T] {
+	return b
+}
+
+// The method set of Box[any] must match interfaces that spell the type
+// argument differently.
+type selfer interface {
+	Self() Box[interface{}]
+}
+
+func testInterfaceAssert() {
+	var v interface{} = Box[any]{v: 3}
+	if s, ok := v.(selfer); ok {
+		println("self:", s.Self().Value().(int))
+	} else {
+		println("self: Box[any] does not implement selfer")
+	}
+	if _, ok := v.(interface{ Value() any }); ok {
+		println("value: ok")
+	}
+}
@@ -45,6 +45,9 @@ func main() {
 
 	var n int
 	var f float32
+	var zero float64
+	negz := -zero
+	nan := zero / zero
 	var interfaceEqualTests = []struct {
 		equal bool
 		lhs   interface{}
@@ -99,6 +102,33 @@ func main() {
 		{false, named2(), named3()},
 		{true, namedptr1(), namedptr1()},
 		{false, namedptr1(), namedptr2()},
+		{false, nil, 0},
+		{false, int32(1), int64(1)},
+		{true, [0]int{}, [0]int{}},
+		{true, [3]uint8{1, 2, 3}, [3]uint8{1, 2, 3}},
+		{false, [3]uint8{1, 2, 3}, [3]uint8{1, 2, 4}},
+		{true, [2]string{"a", "b"}, [2]string{"a", "b"}},
+		{false, [2]string{"a", "b"}, [2]string{"a", "c"}},
+		{true, [2][2]int{{1, 2}, {3, 4}}, [2][2]int{{1, 2}, {3, 4}}},
+		{false, [2][2]int{{1, 2}, {3, 4}}, [2][2]int{{1, 2}, {3, 5}}},
+		{true, [2]interface{}{1, "a"}, [2]interface{}{1, "a"}},
+		{false, [2]interface{}{1, "a"}, [2]interface{}{1, 'a'}},
+		{false, [1]float64{nan}, [1]float64{nan}},
+		{true, [1]float64{0}, [1]float64{negz}},
+		{true, [2]SmallPair{{1, 2}, {3, 4}}, [2]SmallPair{{1, 2}, {3, 4}}},
+		{false, [2]SmallPair{{1, 2}, {3, 4}}, [2]SmallPair{{1, 2}, {4, 3}}},
+		{true, nestedStruct{1, SmallPair{2, 3}, [2]int8{4, 5}, "x", nil}, nestedStruct{1, SmallPair{2, 3}, [2]int8{4, 5}, "x", nil}},
+		{false, nestedStruct{1, SmallPair{2, 3}, [2]int8{4, 5}, "x", nil}, nestedStruct{1, SmallPair{2, 4}, [2]int8{4, 5}, "x", nil}},
+		{false, nestedStruct{1, SmallPair{2, 3}, [2]int8{4, 5}, "x", nil}, nestedStruct{1, SmallPair{2, 3}, [2]int8{4, 6}, "x", nil}},
+		{false, nestedStruct{1, SmallPair{2, 3}, [2]int8{4, 5}, "x", nil}, nestedStruct{1, SmallPair{2, 3}, [2]int8{4, 5}, "y", nil}},
+		{true, nestedStruct{itf: 5}, nestedStruct{itf: 5}},
+		{false, nestedStruct{itf: 5}, nestedStruct{itf: uint(5)}},
+		{true, nestedStruct{itf: &n}, nestedStruct{itf: &n}},
+		{true, blankStruct{a: 1}, blankStruct{a: 1}},
+		{false, blankStruct{a: 1}, blankStruct{a: 2}},
+		{true, struct{ f float32 }{1.5}, struct{ f float32 }{1.5}},
+		{false, struct{ f float32 }{float32(nan)}, struct{ f float32 }{float32(nan)}},
+		{true, struct{}{}, struct{}{}},
 	}
 	for i, tc := range interfaceEqualTests {
 		if (tc.lhs == tc.rhs) != tc.equal {
@@ -238,6 +268,19 @@ type SmallPair struct {
 	b byte
 }
 
+type nestedStruct struct {
+	n     int
+	pair  SmallPair
+	array [2]int8
+	s     string
+	itf   interface{}
+}
+
+type blankStruct struct {
+	a int
+	_ int
+}
+
 func (p SmallPair) Nth(n int) uint32 {
 	return uint32(int(p.a)*n + int(p.b)*n)
 }
@@ -131,6 +131,10 @@ func main() {
 	mapgrow()
 
 	interfacerehash()
+
+	interfacekeys()
+
+	structkeys()
 }
 
 func floatcmplx() {
@@ -308,3 +312,101 @@ func interfacerehash() {
 		println("no interface lookup failures")
 	}
 }
+
+type itfKey struct {
+	name  string
+	value interface{}
+}
+
+func interfacekeys() {
+	// Struct keys containing floats, stored in an interface.
+	m := map[interface{}]int{}
+	m[namedFloat{"pi", 3.14}] = 1
+	m[namedFloat{"tau", 6.28}] = 2
+	println("interface map struct key:", m[namedFloat{"pi", 3.14}], m[namedFloat{"tau", 6.28}], m[namedFloat{"pi", 6.28}])
+
+	// Negative zero must match positive zero, also when nested.
+	var zero float64
+	negz := -zero
+	m[[2]float64{zero, 1}] = 3
+	println("interface map negative zero key:", m[[2]float64{negz, 1}])
+
+	// Struct keys with an interface field.
+	m[itfKey{"a", 5}] = 4
+	m[itfKey{"a", "five"}] = 5
+	m[itfKey{"b", nil}] = 6
+	println("interface map nested interface key:", m[itfKey{"a", 5}], m[itfKey{"a", "five"}], m[itfKey{"b", nil}], m[itfKey{"a", uint8(5)}])
+
+	// The order of array elements matters.
+	m[[2]int{1, 2}] = 7
+	m[[2]int{2, 1}] = 8
+	println("interface map array key order:", m[[2]int{1, 2}], m[[2]int{2, 1}])
+
+	// NaN is never equal to itself, so every NaN key is a new entry that can't
+	// be looked up again.
+	nan := zero / zero
+	nanMap := map[interface{}]int{}
+	for i := 0; i < 4; i++ {
+		nanMap[nan] = i
+		nanMap[[1]float64{nan}] = i
+	}
+	_, ok := nanMap[nan]
+	println("interface map NaN keys:", len(nanMap), ok)
+}
+
+type blankKey struct {
+	a int8
+	_ int32
+	b float32
+}
+
+type nestedKey struct {
+	name   string
+	points [2]namedFloat
+}
+
+func structkeys() {
+	// Struct keys with a blank field, which is ignored in comparisons.
+	var k1, k2 blankKey
+	k1.a, k1.b = 1, 2.5
+	k2.a, k2.b = 1, 2.5
+	m1 := map[blankKey]int{k1: 1}
+	m1[k2]++
+	println("struct key with blank field:", len(m1), m1[k2])
+
+	// Arrays of structs with string and float fields.
+	var zero float32
+	negz := -zero
+	m2 := map[nestedKey]int{}
+	m2[nestedKey{"a", [2]namedFloat{{"x", 1}, {"y", zero}}}] = 1
+	m2[nestedKey{"a", [2]namedFloat{{"y", zero}, {"x", 1}}}] = 2
+	m2[nestedKey{"b", [2]namedFloat{{"x", 1}, {"y", zero}}}] = 3
+	println("nested struct key:", len(m2), m2[nestedKey{"a", [2]namedFloat{{"x", 1}, {"y", negz}}}], m2[nestedKey{"a", [2]namedFloat{{"y", 0}, {"x", 1}}}])
+	delete(m2, nestedKey{"b", [2]namedFloat{{"x", 1}, {"y", 0}}})
+	sum := 0
+	for k, v := range m2 {
+		if k.name == "a" {
+			sum += v
+		}
+	}
+	println("nested struct key range:", len(m2), sum)
+
+	// NaN keys can be added but not found.
+	nan := zero / zero
+	m3 := map[float32]int{}
+	m3[nan] = 1
+	m3[nan] = 2
+	_, ok := m3[nan]
+	println("float NaN keys:", len(m3), ok)
+
+	// Channels are compared by identity.
+	ch1 := make(chan int)
+	ch2 := make(chan int)
+	m4 := map[chan int]int{ch1: 1, ch2: 2}
+	println("channel keys:", m4[ch1], m4[ch2], m4[nil])
+
+	// Complex keys with negative zero.
+	m5 := map[[1]complex64]int{}
+	m5[[1]complex64{complex(zero, 1)}] = 5
+	println("complex array key:", m5[[1]complex64{complex(negz, 1)}])
+}
@@ -0,0 +1,77 @@
+package main
+
+// This program synchronizes goroutines in the ways that the race detector
+// knows about. When built with -race, none of them may be reported as a data
+// race.
+
+import "sync"
+
+var (
+	counter int
+	mu      sync.Mutex
+)
+
+func main() {
+	// A counter protected by a mutex, waited for with a WaitGroup.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			mu.Lock()
+			counter++
+			mu.Unlock()
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	println("counter:", counter)
+
+	// Data handed over with an unbuffered channel.
+	values := make([]int, 4)
+	ready := make(chan struct{})
+	go func() {
+		for i := range values {
+			values[i] = i * i
+		}
+		ready <- struct{}{}
+	}()
+	<-ready
+	println("values:", values[0], values[1], values[2], values[3])
+
+	// Values sent over a buffered channel, and a channel that is closed.
+	results := make(chan int, 2)
+	done := make(chan struct{})
+	sum := 0
+	go func() {
+		for v := range results {
+			sum += v
+		}
+		close(done)
+	}()
+	results <- 1
+	results <- 2
+	close(results)
+	<-done
+	println("sum:", sum)
+
+	// Readers and writers of a map protected by a RWMutex.
+	var rw sync.RWMutex
+	shared := make(map[string]int)
+	for i := 0; i < 3; i++ {
+		wg.Add(2)
+		go func() {
+			rw.Lock()
+			shared["writes"]++
+			rw.Unlock()
+			wg.Done()
+		}()
+		go func() {
+			rw.RLock()
+			_ = shared["writes"]
+			rw.RUnlock()
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	println("writes:", shared["writes"])
+}
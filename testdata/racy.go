@@ -0,0 +1,18 @@
+package main
+
+// This program has a data race: the goroutine writes x without synchronizing
+// with the write in main. When built with -race, the race must be reported
+// and the program must exit with status 66.
+
+var x int
+
+func main() {
+	done := make(chan struct{})
+	go func() {
+		x = 1
+		close(done)
+	}()
+	x = 2
+	<-done
+	println("x:", x)
+}
@@ -1,5 +1,10 @@
 package main
 
+import (
+	"math/bits"
+	"runtime"
+)
+
 func main() {
 	println("# simple recover")
 	recoverSimple()
@@ -19,6 +24,9 @@ func main() {
 
 	println("\n# panic replace")
 	panicReplace()
+
+	println("\n# runtime error from math/bits")
+	divideOverflow()
 }
 
 func recoverSimple() {
@@ -89,10 +97,26 @@ func panicReplace() {
 	panic("panic 1")
 }
 
+func divideOverflow() {
+	defer func() {
+		err := recover()
+		_, isRuntimeError := err.(runtime.Error)
+		println("runtime error:", isRuntimeError)
+		printitf("recovered:", err)
+	}()
+	quo, rem := bits.Div64(1, 0, 3)
+	println("quotient and remainder:", quo, rem)
+	// This panics with the error in runtime.overflowError, which math/bits
+	// refers to with //go:linkname.
+	bits.Div64(3, 0, 3)
+}
+
 func printitf(msg string, itf interface{}) {
 	switch itf := itf.(type) {
 	case string:
 		println(msg, itf)
+	case error:
+		println(msg, itf.Error())
 	default:
 		println(msg, itf)
 	}
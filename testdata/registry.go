@@ -0,0 +1,53 @@
+package main
+
+// Test the //go:registry pragma.
+
+type command func(name string) bool
+
+//go:registry
+var commands []command
+
+// The registry can be used during package initialization.
+var numCommands = len(commands)
+
+//go:registry
+var unused []func()
+
+//go:registry main.commands
+func help(name string) bool {
+	if name != "help" {
+		return false
+	}
+	println("help: list commands")
+	return true
+}
+
+//go:registry main.commands
+func version(name string) bool {
+	if name != "version" {
+		return false
+	}
+	println("version: 1.0")
+	return true
+}
+
+//go:registry main.unused
+func unusedHandler() {
+	println("unused")
+}
+
+func run(name string) {
+	for _, cmd := range commands {
+		if cmd(name) {
+			return
+		}
+	}
+	println("unknown command:", name)
+}
+
+func main() {
+	println("commands:", numCommands)
+	run("help")
+	run("version")
+	run("foo")
+}
@@ -0,0 +1,8 @@
+package main
+
+import "time"
+
+// This program doesn't stop by itself, it is used to test -run-timeout.
+func main() {
+	time.Sleep(time.Hour)
+}
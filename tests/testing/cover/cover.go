@@ -0,0 +1,87 @@
+// Package cover is used to check that the coverage profile written by
+// tinygo test -cover matches the one written by go test -cover.
+package cover
+
+// Classify contains blocks that have no instructions of their own, such as
+// empty if and else bodies and empty cases.
+func Classify(n int) string {
+	kind := "positive"
+	if n == 0 {
+	}
+	if n < 0 {
+		kind = "negative"
+	} else {
+	}
+	switch {
+	case n > 100:
+	case n > 10:
+		return "large"
+	default:
+	}
+	return kind
+}
+
+// Sum has a loop with an empty body, and a loop that starts with a branch
+// statement.
+func Sum(values []int) int {
+	i := 0
+	for ; i < len(values) && values[i] == 0; i++ {
+	}
+	sum := 0
+	for _, v := range values[i:] {
+		if v < 0 {
+			continue
+		}
+		if v > 1000 {
+			break
+		}
+		sum += v
+	}
+	return sum
+}
+
+// Find uses a label and goto, and a function literal.
+func Find(values []int, target int) int {
+	index := -1
+	match := func(v int) bool {
+		return v == target
+	}
+	for i, v := range values {
+		if match(v) {
+			index = i
+			goto found
+		}
+	}
+	return -1
+found:
+	return index
+}
+
+// Describe has a type switch and a select with empty clauses.
+func Describe(x interface{}) string {
+	ch := make(chan int, 1)
+	switch x.(type) {
+	case int:
+		ch <- 1
+	case string:
+	}
+	select {
+	case <-ch:
+	default:
+	}
+	if s, ok := x.(string); ok {
+		return s
+	} else if _, ok := x.(int); ok {
+		return "int"
+	}
+	return "other"
+}
+
+// Empty has an empty body.
+func Empty() {
+}
+
+// Unused is never called.
+func Unused() int {
+	return 1
+}
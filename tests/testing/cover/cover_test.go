@@ -0,0 +1,20 @@
+package cover
+
+import "testing"
+
+func TestCover(t *testing.T) {
+	for _, n := range []int{-5, 0, 5, 50, 500} {
+		Classify(n)
+	}
+	if sum := Sum([]int{0, 0, 1, -2, 3, 2000, 4}); sum != 4 {
+		t.Errorf("Sum = %d, want 4", sum)
+	}
+	if i := Find([]int{3, 4, 5}, 4); i != 1 {
+		t.Errorf("Find = %d, want 1", i)
+	}
+	Find(nil, 1)
+	for _, x := range []interface{}{1, "s", 1.5} {
+		Describe(x)
+	}
+	Empty()
+}
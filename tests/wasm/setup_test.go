@@ -61,6 +61,12 @@ func startServer(t *testing.T) (string, *httptest.Server) {
 		}
 
 		if r.URL.Path == "/run" {
+			// Optionally call an exported function before _start, like a host
+			// that uses the module as a library.
+			callExport := ""
+			if export := r.FormValue("export"); export != "" {
+				callExport = fmt.Sprintf(`console.log("%s:", result.instance.exports.%s(42));`, export, export)
+			}
 			fmt.Fprintf(w, `<!doctype html>
 <html>
 <head>
@@ -98,6 +104,7 @@ if (wasmSupported) {
 		if (res.ok) {
 			const go = new Go();
 			WebAssembly.instantiateStreaming(res, go.importObject).then((result) => {
+				%s
 				go.run(result.instance);
 			});		
 		} else {
@@ -113,7 +120,7 @@ if (wasmSupported) {
 }
 </script>
 </body>
-</html>`, r.FormValue("file"))
+</html>`, r.FormValue("file"), callExport)
 			return
 		}
 
@@ -0,0 +1,23 @@
+package main
+
+// The host calls store before _start, which initializes the runtime and this
+// package. _start must then only run main.
+
+var inits int
+
+var values []int32
+
+func init() {
+	inits++
+}
+
+//go:wasmexport store
+func store(n int32) int32 {
+	values = append(values, n)
+	return int32(len(values))
+}
+
+func main() {
+	println("inits:", inits)
+	println("values:", len(values), values[0])
+}
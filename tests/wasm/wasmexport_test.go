@@ -0,0 +1,29 @@
+package wasm
+
+import (
+	"testing"
+
+	"github.com/chromedp/chromedp"
+)
+
+func TestWasmExportBeforeStart(t *testing.T) {
+
+	wasmTmpDir, server := startServer(t)
+
+	err := run(t, "tinygo build -o "+wasmTmpDir+"/wasmexport.wasm -target wasm testdata/wasmexport.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := chromectx(t)
+
+	err = chromedp.Run(ctx,
+		chromedp.Navigate(server.URL+"/run?file=wasmexport.wasm&export=store"),
+		waitLog(`store: 1
+inits: 1
+values: 1 42`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,94 @@
+package transform
+
+// This file implements a diagnostic (not an optimization) that reports heap
+// allocations inside loops. Every such allocation may trigger a garbage
+// collection cycle, which is usually not what is intended in a hot path.
+
+import (
+	"fmt"
+	"go/token"
+	"strings"
+
+	"tinygo.org/x/go-llvm"
+)
+
+// ReportHeapAllocsInLoops reports all heap allocations that remain after
+// OptimizeAllocs and that are inside a loop, together with the reason why they
+// could not be allocated on the stack. Allocations in functions of the given
+// errorPackages are returned as errors, all others are passed to the logger
+// (if it is non-nil).
+func ReportHeapAllocsInLoops(mod llvm.Module, errorPackages []string, logger func(token.Position, string)) []error {
+	allocator := mod.NamedFunction("runtime.alloc")
+	if allocator.IsNil() {
+		// No heap allocations.
+		return nil
+	}
+
+	var errs []error
+	for _, heapalloc := range getUses(allocator) {
+		if heapalloc.IsACallInst().IsNil() || !isInLoop(heapalloc.InstructionParent()) {
+			continue
+		}
+		msg := "heap allocation in loop"
+		if reason := heapAllocReason(heapalloc); reason != "" {
+			msg += ": " + reason
+		}
+		fnName := heapalloc.InstructionParent().Parent().Name()
+		if isPackageSymbol(strings.TrimLeft(fnName, "(*"), errorPackages) {
+			errs = append(errs, errorAt(heapalloc, msg))
+		} else if logger != nil {
+			logger(getPosition(heapalloc), msg)
+		}
+	}
+	return errs
+}
+
+// heapAllocReason returns why the given runtime.alloc call could not be turned
+// into a stack allocation, or the empty string if the reason is not known (for
+// example, because OptimizeAllocs didn't run).
+func heapAllocReason(heapalloc llvm.Value) string {
+	if heapalloc.Operand(0).IsAConstantInt().IsNil() {
+		return "size is not constant"
+	}
+	if size := heapalloc.Operand(0).ZExtValue(); size > maxStackAlloc {
+		return fmt.Sprintf("object size %d exceeds maximum stack allocation size %d", size, maxStackAlloc)
+	}
+	bitcast := heapalloc
+	if uses := getUses(heapalloc); len(uses) == 1 && !uses[0].IsABitCastInst().IsNil() {
+		bitcast = uses[0]
+	}
+	if at := valueEscapesAt(bitcast); !at.IsNil() {
+		return escapeReason(at)
+	}
+	return ""
+}
+
+// isInLoop returns whether the given basic block is part of a loop, that is,
+// whether it can be reached again after it has been executed.
+func isInLoop(bb llvm.BasicBlock) bool {
+	visited := make(map[llvm.BasicBlock]bool)
+	worklist := []llvm.BasicBlock{bb}
+	for len(worklist) != 0 {
+		block := worklist[len(worklist)-1]
+		worklist = worklist[:len(worklist)-1]
+		terminator := block.LastInstruction()
+		if terminator.IsNil() {
+			continue
+		}
+		for i := 0; i < terminator.OperandsCount(); i++ {
+			operand := terminator.Operand(i)
+			if !operand.IsBasicBlock() {
+				continue
+			}
+			successor := operand.AsBasicBlock()
+			if successor == bb {
+				return true
+			}
+			if !visited[successor] {
+				visited[successor] = true
+				worklist = append(worklist, successor)
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package transform_test
+
+import (
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/tinygo-org/tinygo/transform"
+	"tinygo.org/x/go-llvm"
+)
+
+func TestReportHeapAllocsInLoops(t *testing.T) {
+	t.Parallel()
+
+	mod := compileGoFileForTesting(t, "./testdata/allocloop.go")
+
+	// Move all heap allocations that don't escape to the stack first.
+	pm := llvm.NewPassManager()
+	defer pm.Dispose()
+	pm.AddInstructionCombiningPass()
+	pm.AddFunctionAttrsPass()
+	pm.Run(mod)
+	transform.OptimizeAllocs(mod, nil, nil)
+
+	var testOutputs []allocsTestOutput
+	errs := transform.ReportHeapAllocsInLoops(mod, nil, func(pos token.Position, msg string) {
+		testOutputs = append(testOutputs, allocsTestOutput{
+			filename: filepath.Base(pos.Filename),
+			line:     pos.Line,
+			msg:      msg,
+		})
+	})
+	if errs != nil {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+	sort.Slice(testOutputs, func(i, j int) bool {
+		return testOutputs[i].line < testOutputs[j].line
+	})
+	testOutput := ""
+	for _, out := range testOutputs {
+		testOutput += out.String() + "\n"
+	}
+
+	// Load expected test output (the OUT: lines).
+	testInput, err := os.ReadFile("./testdata/allocloop.go")
+	if err != nil {
+		t.Fatal("could not read test input:", err)
+	}
+	var expectedTestOutput string
+	var expectedErrors int
+	for i, line := range strings.Split(strings.ReplaceAll(string(testInput), "\r\n", "\n"), "\n") {
+		if idx := strings.Index(line, " // OUT: "); idx > 0 {
+			msg := line[idx+len(" // OUT: "):]
+			expectedTestOutput += "allocloop.go:" + strconv.Itoa(i+1) + ": " + msg + "\n"
+			expectedErrors++
+		}
+	}
+
+	if testOutput != expectedTestOutput {
+		t.Errorf("output does not match expected output:\n%s", testOutput)
+	}
+
+	// The same allocations are errors in the main package.
+	errs = transform.ReportHeapAllocsInLoops(mod, []string{"main"}, func(pos token.Position, msg string) {
+		t.Errorf("unexpected warning for package main: %s: %s", pos, msg)
+	})
+	if len(errs) != expectedErrors {
+		t.Errorf("expected %d errors, got %d: %v", expectedErrors, len(errs), errs)
+	}
+}
@@ -4,6 +4,11 @@ package transform
 // runtime.alloc and replaces these calls with a stack allocation if the
 // allocated value does not escape. It uses the LLVM nocapture flag for
 // interprocedural escape analysis.
+//
+// Values that are put in an interface (or another aggregate) are followed
+// through insertvalue and extractvalue instructions, so that the value boxed
+// by a MakeInterface doesn't need to be heap allocated if the interface itself
+// doesn't escape.
 
 import (
 	"fmt"
@@ -87,12 +92,7 @@ func OptimizeAllocs(mod llvm.Module, printAllocs *regexp.Regexp, logger func(tok
 
 		if at := valueEscapesAt(bitcast); !at.IsNil() {
 			if logAllocs {
-				atPos := getPosition(at)
-				msg := "escapes at unknown line"
-				if atPos.Line != 0 {
-					msg = fmt.Sprintf("escapes at line %d", atPos.Line)
-				}
-				logAlloc(logger, heapalloc, msg)
+				logAlloc(logger, heapalloc, escapeReason(at))
 			}
 			continue
 		}
@@ -172,6 +172,22 @@ func valueEscapesAt(value llvm.Value) llvm.Value {
 		case llvm.ICmp:
 			// Comparing pointers don't let the pointer escape.
 			// This is often a compiler-inserted nil check.
+		case llvm.InsertValue:
+			// The value is put in an aggregate, usually the value of an
+			// interface. It escapes if the aggregate escapes.
+			if at := valueEscapesAt(use); !at.IsNil() {
+				return at
+			}
+		case llvm.ExtractValue:
+			// A value taken out of an aggregate that contains the value. Only
+			// pointers, or aggregates that contain a pointer (like an
+			// interface in a struct), can contain the value. Other fields
+			// (like the length of a slice) don't matter.
+			if typeHasPointers(use.Type()) {
+				if at := valueEscapesAt(use); !at.IsNil() {
+					return at
+				}
+			}
 		default:
 			// Unknown instruction, might escape.
 			return use
@@ -182,6 +198,16 @@ func valueEscapesAt(value llvm.Value) llvm.Value {
 	return llvm.Value{}
 }
 
+// escapeReason returns a description of where a value escapes, for the given
+// instruction returned by valueEscapesAt.
+func escapeReason(at llvm.Value) string {
+	atPos := getPosition(at)
+	if atPos.Line == 0 {
+		return "escapes at unknown line"
+	}
+	return fmt.Sprintf("escapes at line %d", atPos.Line)
+}
+
 // logAlloc prints a message to stderr explaining why the given object had to be
 // allocated on the heap.
 func logAlloc(logger func(token.Position, string), allocCall llvm.Value, reason string) {
@@ -0,0 +1,102 @@
+package transform
+
+import (
+	"strings"
+
+	"tinygo.org/x/go-llvm"
+)
+
+// ExternalFlashSection is the prefix of the sections used for constant data
+// that is placed in memory-mapped external flash. The linker script must place
+// all input sections starting with this prefix in external flash, for example:
+//
+//	.extflash.rodata : { *(.extflash.rodata*) } > EXTFLASH
+const ExternalFlashSection = ".extflash.rodata"
+
+// PlaceExternalFlashData moves the constant globals of the given packages
+// (including compiler generated globals such as string data) into the
+// ExternalFlashSection, so that large lookup tables don't take up space in
+// internal flash. References to these globals are resolved by the linker like
+// references to any other global.
+//
+// Identical string constants are merged first: the linker can't merge them
+// anymore once they are in their own section.
+func PlaceExternalFlashData(mod llvm.Module, packages []string) {
+	// Find the globals to move.
+	var globals []llvm.Value
+	for global := mod.FirstGlobal(); !global.IsNil(); global = llvm.NextGlobal(global) {
+		if global.IsDeclaration() || !global.IsGlobalConstant() || global.Section() != "" {
+			continue
+		}
+		if !isPackageSymbol(global.Name(), packages) {
+			continue
+		}
+		globals = append(globals, global)
+	}
+
+	// Merge identical constants. Initializers are uniqued by LLVM, so globals
+	// with the same initializer have identical contents.
+	merged := make(map[llvm.Value]llvm.Value)
+	removed := make(map[llvm.Value]bool)
+	for _, global := range globals {
+		if !isMergeableConstant(global) {
+			continue
+		}
+		initializer := global.Initializer()
+		other, ok := merged[initializer]
+		if !ok {
+			merged[initializer] = global
+			continue
+		}
+		if global.Alignment() > other.Alignment() {
+			other.SetAlignment(global.Alignment())
+		}
+		global.ReplaceAllUsesWith(other)
+		global.EraseFromParentAsGlobal()
+		removed[global] = true
+	}
+
+	for _, global := range globals {
+		if removed[global] {
+			continue
+		}
+		// Use a separate section for each global, so that the linker can
+		// still remove unused globals.
+		global.SetSection(ExternalFlashSection + "." + global.Name())
+	}
+}
+
+// isPackageSymbol returns whether the symbol name belongs to one of the given
+// packages. Package level symbols are named like "pkgpath.name", compiler
+// generated symbols like "pkgpath$string".
+func isPackageSymbol(name string, packages []string) bool {
+	for _, pkg := range packages {
+		if !strings.HasPrefix(name, pkg) {
+			continue
+		}
+		rest := name[len(pkg):]
+		if rest == "" || (rest[0] != '.' && rest[0] != '$') {
+			continue
+		}
+		if strings.Contains(rest, "/") {
+			// A symbol in a package like "pkgpath.foo/bar", which is a
+			// different package.
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// isMergeableConstant returns whether the address of this constant global
+// isn't significant, so that it may be merged with another constant global.
+// This is only known for string data: the address of variables (and of heap
+// allocations made during package initialization) can be compared in Go.
+func isMergeableConstant(global llvm.Value) bool {
+	switch global.Linkage() {
+	case llvm.InternalLinkage, llvm.PrivateLinkage:
+	default:
+		return false
+	}
+	return strings.Contains(global.Name(), "$string")
+}
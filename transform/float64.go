@@ -0,0 +1,57 @@
+package transform
+
+// This file implements a diagnostic (not an optimization) that reports the
+// float64 operations that remain after optimization. On chips without a double
+// precision FPU (Cortex-M0, Cortex-M4F, RISC-V without the D extension), every
+// such operation is a relatively slow call into the soft-float library, and
+// float32 values are easily converted to float64 by accident: by calling a
+// math function or by passing them to fmt or strconv.
+
+import (
+	"go/token"
+	"regexp"
+
+	"tinygo.org/x/go-llvm"
+)
+
+// PrintFloat64 reports all float64 operations in functions that match the
+// printFloat64 regexp, and the place where float32 values are converted to
+// float64.
+func PrintFloat64(mod llvm.Module, printFloat64 *regexp.Regexp, logger func(token.Position, string)) {
+	doubleType := mod.Context().DoubleType()
+	for fn := mod.FirstFunction(); !fn.IsNil(); fn = llvm.NextFunction(fn) {
+		if fn.IsDeclaration() || !printFloat64.MatchString(fn.Name()) {
+			continue
+		}
+		for bb := fn.FirstBasicBlock(); !bb.IsNil(); bb = llvm.NextBasicBlock(bb) {
+			for inst := bb.FirstInstruction(); !inst.IsNil(); inst = llvm.NextInstruction(inst) {
+				var msg string
+				switch inst.InstructionOpcode() {
+				case llvm.FAdd, llvm.FSub, llvm.FMul, llvm.FDiv, llvm.FRem:
+					if inst.Type() == doubleType {
+						msg = "float64 arithmetic"
+					}
+				case llvm.FCmp:
+					if inst.Operand(0).Type() == doubleType {
+						msg = "float64 comparison"
+					}
+				case llvm.FPExt:
+					if inst.Type() == doubleType {
+						msg = "float32 converted to float64"
+					}
+				case llvm.FPTrunc, llvm.FPToSI, llvm.FPToUI:
+					if inst.Operand(0).Type() == doubleType {
+						msg = "float64 conversion"
+					}
+				case llvm.SIToFP, llvm.UIToFP:
+					if inst.Type() == doubleType {
+						msg = "integer converted to float64"
+					}
+				}
+				if msg != "" {
+					logger(getPosition(inst), msg+" in "+fn.Name())
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package transform_test
+
+import (
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/tinygo-org/tinygo/transform"
+)
+
+type float64TestOutput struct {
+	filename string
+	line     int
+	msg      string
+}
+
+func (out float64TestOutput) String() string {
+	return out.filename + ":" + strconv.Itoa(out.line) + ": " + out.msg
+}
+
+func TestPrintFloat64(t *testing.T) {
+	t.Parallel()
+
+	mod := compileGoFileForTesting(t, "./testdata/float64.go")
+
+	var testOutputs []float64TestOutput
+	transform.PrintFloat64(mod, regexp.MustCompile(`^main\.`), func(pos token.Position, msg string) {
+		testOutputs = append(testOutputs, float64TestOutput{
+			filename: filepath.Base(pos.Filename),
+			line:     pos.Line,
+			msg:      msg,
+		})
+	})
+	sort.Slice(testOutputs, func(i, j int) bool {
+		return testOutputs[i].line < testOutputs[j].line
+	})
+	testOutput := ""
+	for _, out := range testOutputs {
+		testOutput += out.String() + "\n"
+	}
+
+	// Load expected test output (the OUT: lines).
+	testInput, err := os.ReadFile("./testdata/float64.go")
+	if err != nil {
+		t.Fatal("could not read test input:", err)
+	}
+	var expectedTestOutput string
+	for i, line := range strings.Split(strings.ReplaceAll(string(testInput), "\r\n", "\n"), "\n") {
+		if idx := strings.Index(line, " // OUT: "); idx > 0 {
+			msg := line[idx+len(" // OUT: "):]
+			expectedTestOutput += "float64.go:" + strconv.Itoa(i+1) + ": " + msg + "\n"
+		}
+	}
+
+	if testOutput != expectedTestOutput {
+		t.Errorf("output does not match expected output:\n%s", testOutput)
+	}
+}
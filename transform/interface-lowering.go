@@ -63,11 +63,17 @@ type typeInfo struct {
 // getMethod looks up the method on this type with the given signature and
 // returns it. The method must exist on this type, otherwise getMethod will
 // panic.
+//
+// The methods are sorted by signature name (see addTypeMethods), so this is a
+// binary search. This matters for types with very large method sets (for
+// example generated gRPC service implementations), where a linear search for
+// every interface method would make the pass quadratic.
 func (t *typeInfo) getMethod(signature *signatureInfo) *methodInfo {
-	for _, method := range t.methods {
-		if method.signatureInfo == signature {
-			return method
-		}
+	i := sort.Search(len(t.methods), func(i int) bool {
+		return t.methods[i].name >= signature.name
+	})
+	if i < len(t.methods) && t.methods[i].signatureInfo == signature {
+		return t.methods[i]
 	}
 	panic("could not find method")
 }
@@ -96,6 +102,7 @@ type lowerInterfacesPass struct {
 	types       map[string]*typeInfo
 	signatures  map[string]*signatureInfo
 	interfaces  map[string]*interfaceInfo
+	keepTypes   map[string]bool // types marked with //go:reflect
 }
 
 // LowerInterfaces lowers all intermediate interface calls and globals that are
@@ -180,6 +187,18 @@ func (p *lowerInterfacesPass) run() error {
 		}
 	}
 
+	// Find the types that were marked with //go:reflect. The list only exists
+	// to keep these types in the program until now, so remove it afterwards.
+	p.keepTypes = make(map[string]bool)
+	if keep := p.mod.NamedGlobal("reflect/types.keep"); !keep.IsNil() {
+		initializer := keep.Initializer()
+		for i := 0; i < initializer.Type().ArrayLength(); i++ {
+			typecode := stripPointerCasts(p.builder.CreateExtractValue(initializer, i, ""))
+			p.keepTypes[strings.TrimPrefix(typecode.Name(), "reflect/types.type:")] = true
+		}
+		keep.EraseFromParentAsGlobal()
+	}
+
 	// Find all interface type asserts and interface method thunks.
 	var interfaceAssertFunctions []llvm.Value
 	var interfaceInvokeFunctions []llvm.Value
@@ -279,6 +298,12 @@ func (p *lowerInterfacesPass) run() error {
 		p.defineInterfaceImplementsFunc(fn, itf)
 	}
 
+	// Define the function that the reflect package uses to look up unexported
+	// methods, which are not part of the reflect method tables.
+	if fn := p.mod.NamedFunction("reflect.hasUnexportedMethod"); !fn.IsNil() && fn.IsDeclaration() && hasUses(fn) {
+		p.defineUnexportedMethodFunc(fn)
+	}
+
 	// Replace each type assert with an actual type comparison or (if the type
 	// assert is impossible) the constant false.
 	llvmFalse := llvm.ConstInt(p.ctx.Int1Type(), 0, false)
@@ -325,13 +350,49 @@ func (p *lowerInterfacesPass) run() error {
 	}
 	sort.Strings(typeNames)
 
+	// Fill in the table of types that ArrayOf, ChanOf, FuncOf, SliceOf and
+	// StructOf in the reflect package look through, so that they return the
+	// existing type if it is part of the program.
+	if table := p.mod.NamedGlobal("reflect.compilerTypes"); !table.IsNil() && table.IsDeclaration() && hasUses(table) {
+		p.defineCompilerTypes(table, typeNames)
+	}
+
+	// Struct tags in the conventional format are preceded by a table with their
+	// keys and values, which StructTag.Lookup finds through the
+	// reflect.structTags table. Remove these tag tables if Lookup isn't used.
+	if table := p.mod.NamedGlobal("reflect.structTags"); !table.IsNil() && table.IsDeclaration() && hasUses(table) {
+		p.defineStructTags(table)
+	} else {
+		p.removeStructTagTables()
+	}
+
 	// Remove all method sets, which are now unnecessary and inhibit later
-	// optimizations if they are left in place.
+	// optimizations if they are left in place. If the type has exported
+	// methods, the method set is replaced with the method table that is used
+	// by the reflect package. This table only references the methods
+	// themselves if they can be called through reflect.Value.Method.
 	zero := llvm.ConstInt(p.ctx.Int32Type(), 0, false)
+	keepReflectMethods := p.reflectMethodsUsed("(reflect.Value)", "Method", "MethodByName")
+	keepReflectMethodTypes := keepReflectMethods || p.reflectMethodsUsed("(*reflect.rawType)", "Method", "MethodByName")
 	for _, name := range typeNames {
 		t := p.types[name]
 		if !t.methodSet.IsNil() {
 			initializer := t.typecode.Initializer()
+			methodSet := t.methodSet.Initializer()
+			if methodSet.Type().StructElementTypesCount() > 3 {
+				reflectMethods := p.builder.CreateExtractValue(methodSet, 3, "")
+				if !reflectMethods.IsNull() {
+					if !keepReflectMethods && !p.keepTypes[name] && !p.reflectIncluded(name) {
+						p.removeReflectMethodFields(stripPointerCasts(reflectMethods), 2, 3)
+					}
+					if !keepReflectMethodTypes {
+						p.removeReflectMethodFields(stripPointerCasts(reflectMethods), 1)
+					}
+					initializer = p.builder.CreateInsertValue(initializer, reflectMethods, 0, "")
+					t.typecode.SetInitializer(initializer)
+					continue
+				}
+			}
 			var newInitializerFields []llvm.Value
 			for i := 1; i < initializer.Type().StructElementTypesCount(); i++ {
 				newInitializerFields = append(newInitializerFields, p.builder.CreateExtractValue(initializer, i, ""))
@@ -368,9 +429,293 @@ func (p *lowerInterfacesPass) run() error {
 		}
 	}
 
+	// The method types in method tables and method signatures are only
+	// needed by reflect.Type.Method and reflect.Value.Method. Remove them
+	// otherwise, so that the function type codes they refer to can be removed.
+	if !keepReflectMethodTypes {
+		p.removeMethodSignatureTypes()
+	}
+
+	// Function types reference a call thunk, which is only needed by
+	// reflect.Value.Call, and a makeFunc thunk, which is only needed by
+	// reflect.MakeFunc. Remove these references if they aren't used, so that
+	// the thunks can be removed.
+	if !p.reflectMethodsUsed("(reflect.Value)", "Call", "CallSlice") {
+		p.removeFuncTypeThunks(funcTypeCallField)
+	}
+	if fn := p.mod.NamedFunction("reflect.MakeFunc"); fn.IsNil() || !hasUses(fn) {
+		p.removeFuncTypeThunks(funcTypeMakeFuncField)
+	}
+
 	return nil
 }
 
+// defineCompilerTypes defines the reflect.compilerTypes table, which lists all
+// array, channel, function, slice and struct types in the program and is
+// terminated by a nil pointer. Named and pointer types are not included, as
+// they are never constructed through this table.
+func (p *lowerInterfacesPass) defineCompilerTypes(table llvm.Value, typeNames []string) {
+	var types []llvm.Value
+	for _, name := range typeNames {
+		kind, _, _ := strings.Cut(name, ":")
+		switch kind {
+		case "array", "chan", "func", "slice", "struct":
+			types = append(types, p.types[name].typecodeGEP)
+		}
+	}
+	types = append(types, llvm.ConstNull(p.i8ptrType))
+	initializer := llvm.ConstArray(p.i8ptrType, types)
+	global := llvm.AddGlobal(p.mod, initializer.Type(), table.Name()+".tmp")
+	global.SetInitializer(initializer)
+	global.SetLinkage(llvm.InternalLinkage)
+	global.SetGlobalConstant(true)
+	global.SetAlignment(p.targetData.ABITypeAlignment(p.i8ptrType))
+	name := table.Name()
+	table.ReplaceAllUsesWith(global)
+	table.EraseFromParentAsGlobal()
+	global.SetName(name)
+}
+
+// Flag in the first byte of struct field data that is set if the tag is
+// preceded by a tag table. See structFieldFlagHasTagTable in
+// compiler/interface.go.
+const structFieldFlagHasTagTable = 1 << 4
+
+// structFieldsWithTagTable returns all struct field data globals in which the
+// tag is preceded by a tag table.
+func (p *lowerInterfacesPass) structFieldsWithTagTable() []llvm.Value {
+	var fields []llvm.Value
+	for global := p.mod.FirstGlobal(); !global.IsNil(); global = llvm.NextGlobal(global) {
+		if !strings.HasPrefix(global.Name(), "reflect/types.structfield:") || global.IsDeclaration() {
+			continue
+		}
+		flags := p.builder.CreateExtractValue(global.Initializer(), 0, "")
+		if flags.ZExtValue()&structFieldFlagHasTagTable != 0 {
+			fields = append(fields, global)
+		}
+	}
+	return fields
+}
+
+// structFieldTagTable returns the index of the tag table and of the tag in the
+// given struct field data. The data consists of the flags, the offset as a
+// uvarint and the zero terminated name, followed by the tag table, the size of
+// the table, the length of the tag and the tag itself.
+func structFieldTagTable(data []byte) (table, tag int) {
+	i := 1
+	for data[i]&0x80 != 0 {
+		i++
+	}
+	i++
+	for data[i] != 0 {
+		i++
+	}
+	table = i + 1
+
+	// Skip the number of keys and the keys and values in the table, followed
+	// by the size of the table and the length of the tag.
+	tag = table + 1
+	for n := 0; n < 2*int(data[table]); n++ {
+		tag += 1 + int(data[tag])
+	}
+	tag += 2
+	return table, tag
+}
+
+// defineStructTags defines the reflect.structTags table. All struct field data
+// with a tag table is moved into this table, followed by a bitmap in which the
+// bit for the first byte of each tag is set, so that StructTag.Lookup can check
+// whether a tag string is the tag of one of these struct fields.
+func (p *lowerInterfacesPass) defineStructTags(table llvm.Value) {
+	fields := p.structFieldsWithTagTable()
+	var data []byte
+	offsets := make([]int, len(fields))
+	tags := make([]int, len(fields))
+	for i, field := range fields {
+		buf := getGlobalBytes(field, p.builder)
+		_, tag := structFieldTagTable(buf)
+		offsets[i] = len(data)
+		tags[i] = len(data) + tag
+		data = append(data, buf...)
+	}
+	bitmap := make([]byte, (len(data)+7)/8)
+	for _, tag := range tags {
+		bitmap[tag/8] |= 1 << (tag % 8)
+	}
+
+	// The layout matches structTagData in src/reflect/type.go, with the data
+	// and bitmap following the size.
+	initializer := p.ctx.ConstStruct([]llvm.Value{
+		llvm.ConstInt(p.uintptrType, uint64(len(data)), false),
+		p.ctx.ConstString(string(data), false),
+		p.ctx.ConstString(string(bitmap), false),
+	}, false)
+	global := llvm.AddGlobal(p.mod, initializer.Type(), table.Name()+".tmp")
+	global.SetInitializer(initializer)
+	global.SetLinkage(llvm.InternalLinkage)
+	global.SetGlobalConstant(true)
+	global.SetAlignment(p.targetData.ABITypeAlignment(p.uintptrType))
+	for i, field := range fields {
+		gep := llvm.ConstInBoundsGEP(initializer.Type(), global, []llvm.Value{
+			llvm.ConstInt(p.ctx.Int32Type(), 0, false),
+			llvm.ConstInt(p.ctx.Int32Type(), 1, false),
+			llvm.ConstInt(p.ctx.Int32Type(), uint64(offsets[i]), false),
+		})
+		field.ReplaceAllUsesWith(llvm.ConstBitCast(gep, field.Type()))
+		field.EraseFromParentAsGlobal()
+	}
+	name := table.Name()
+	table.ReplaceAllUsesWith(llvm.ConstBitCast(global, table.Type()))
+	table.EraseFromParentAsGlobal()
+	global.SetName(name)
+}
+
+// removeStructTagTables removes the tag tables from all struct field data, for
+// when StructTag.Lookup isn't used.
+func (p *lowerInterfacesPass) removeStructTagTables() {
+	for _, field := range p.structFieldsWithTagTable() {
+		data := getGlobalBytes(field, p.builder)
+		table, tag := structFieldTagTable(data)
+		data = append(data[:table:table], data[tag-1:]...) // keep the tag length
+		data[0] &^= structFieldFlagHasTagTable
+		initializer := p.ctx.ConstString(string(data), false)
+		global := llvm.AddGlobal(p.mod, initializer.Type(), field.Name()+".tmp")
+		global.SetInitializer(initializer)
+		global.SetLinkage(field.Linkage())
+		global.SetUnnamedAddr(true)
+		global.SetGlobalConstant(true)
+		global.SetAlignment(1)
+		name := field.Name()
+		field.ReplaceAllUsesWith(llvm.ConstBitCast(global, field.Type()))
+		field.EraseFromParentAsGlobal()
+		global.SetName(name)
+	}
+}
+
+// reflectMethodsUsed returns whether any of the given methods of a reflect
+// type is used, where receiver is a receiver like "(reflect.Value)". Calls
+// between these methods don't count, and neither do references from the
+// method set of the receiver type, as they only mean that the type was put in
+// an interface. Method sets refer to the $invoke wrapper for value receivers
+// and to the method itself for pointer receivers.
+func (p *lowerInterfacesPass) reflectMethodsUsed(receiver string, methods ...string) bool {
+	var names []string
+	internal := make(map[string]bool)
+	for _, method := range methods {
+		name := receiver + "." + method
+		names = append(names, name)
+		internal[name] = true
+		internal[name+"$invoke"] = true
+	}
+	pointerReceiver := strings.HasPrefix(receiver, "(*")
+	for _, name := range names {
+		for _, fnName := range []string{name, name + "$invoke"} {
+			fn := p.mod.NamedFunction(fnName)
+			if fn.IsNil() {
+				continue
+			}
+			for _, use := range getUses(fn) {
+				if use.IsAInstruction().IsNil() {
+					if fnName != name || pointerReceiver {
+						// Reference from a method set.
+						continue
+					}
+					return true
+				}
+				if !internal[use.InstructionParent().Parent().Name()] {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// reflectIncluded returns whether the reflect metadata of the given type must
+// be kept because it matches the -reflect-include flag. The flag matches named
+// types by their qualified name (like main.Config), which also includes
+// pointers to these types.
+func (p *lowerInterfacesPass) reflectIncluded(name string) bool {
+	include := p.config.Options.ReflectInclude
+	if include == nil {
+		return false
+	}
+	for strings.HasPrefix(name, "pointer:") {
+		name = name[len("pointer:"):]
+	}
+	if !strings.HasPrefix(name, "named:") {
+		return false
+	}
+	return include.MatchString(name[len("named:"):])
+}
+
+// removeReflectMethodFields removes the given fields from all entries of a
+// reflect method table (see methodTableEntry in src/reflect/type.go), so that
+// the values they refer to can be removed if they aren't otherwise used. These
+// are the method type (field 1), and the method invoke wrapper and call thunk
+// (fields 2 and 3).
+func (p *lowerInterfacesPass) removeReflectMethodFields(table llvm.Value, fields ...int) {
+	initializer := table.Initializer()
+	for i := 0; i < initializer.Type().ArrayLength(); i++ {
+		entry := p.builder.CreateExtractValue(initializer, i, "")
+		for _, field := range fields {
+			value := p.builder.CreateExtractValue(entry, field, "")
+			entry = p.builder.CreateInsertValue(entry, llvm.ConstNull(value.Type()), field, "")
+		}
+		initializer = p.builder.CreateInsertValue(initializer, entry, i, "")
+	}
+	table.SetInitializer(initializer)
+}
+
+// removeMethodSignatureTypes removes the method type from all method
+// signatures (see methodSignature in src/reflect/type.go). Signatures of
+// unexported methods are named like "pkg.$methods.name()" instead.
+func (p *lowerInterfacesPass) removeMethodSignatureTypes() {
+	for global := p.mod.FirstGlobal(); !global.IsNil(); global = llvm.NextGlobal(global) {
+		name := global.Name()
+		if !strings.HasPrefix(name, "reflect/methods.") && !strings.Contains(name, ".$methods.") {
+			continue
+		}
+		if global.IsDeclaration() {
+			continue
+		}
+		initializer := global.Initializer()
+		if initializer.Type().TypeKind() != llvm.StructTypeKind {
+			continue
+		}
+		typ := p.builder.CreateExtractValue(initializer, 0, "")
+		initializer = p.builder.CreateInsertValue(initializer, llvm.ConstNull(typ.Type()), 0, "")
+		global.SetInitializer(initializer)
+	}
+}
+
+// Fields of function type codes that refer to thunks, following the meta,
+// numIn, ptrTo and numOut fields. See funcType in src/reflect/type.go.
+const (
+	funcTypeCallField     = 4
+	funcTypeMakeFuncField = 5
+)
+
+// removeFuncTypeThunks removes the references to a thunk from all function
+// type codes, so that these thunks can be removed if they aren't otherwise
+// used. The field is funcTypeCallField for the thunks used by Value.Call or
+// funcTypeMakeFuncField for the thunks used as function pointer of functions
+// created by MakeFunc.
+func (p *lowerInterfacesPass) removeFuncTypeThunks(field int) {
+	for global := p.mod.FirstGlobal(); !global.IsNil(); global = llvm.NextGlobal(global) {
+		if !strings.HasPrefix(global.Name(), "reflect/types.type:func:") || global.IsDeclaration() {
+			continue
+		}
+		initializer := global.Initializer()
+		if initializer.Type().StructElementTypesCount() <= field {
+			continue
+		}
+		thunk := p.builder.CreateExtractValue(initializer, field, "")
+		initializer = p.builder.CreateInsertValue(initializer, llvm.ConstNull(thunk.Type()), field, "")
+		global.SetInitializer(initializer)
+	}
+}
+
 // addTypeMethods reads the method set of the given type info struct. It
 // retrieves the signatures and the references to the method functions
 // themselves for later type<->interface matching.
@@ -399,6 +744,14 @@ func (p *lowerInterfacesPass) addTypeMethods(t *typeInfo, methodSet llvm.Value)
 		signature.methods = append(signature.methods, method)
 		t.methods = append(t.methods, method)
 	}
+
+	// Sort the methods by signature name so that getMethod can do a binary
+	// search. Method sets are usually already sorted, but the signature names
+	// include the package path for unexported methods so the order may
+	// differ.
+	sort.Slice(t.methods, func(i, j int) bool {
+		return t.methods[i].name < t.methods[j].name
+	})
 }
 
 // addInterface reads information about an interface, which is the
@@ -490,12 +843,64 @@ func (p *lowerInterfacesPass) defineInterfaceImplementsFunc(fn llvm.Value, itf *
 	p.builder.CreateRet(llvm.ConstInt(p.ctx.Int1Type(), 1, false))
 }
 
+// defineUnexportedMethodFunc defines reflect.hasUnexportedMethod, which checks
+// whether the given type (passed as the first argument) has the unexported
+// method with the given signature (passed as the second argument).
+//
+// Like the interface type assert functions, it is implemented as an if/else
+// chain over all types and their unexported methods.
+func (p *lowerInterfacesPass) defineUnexportedMethodFunc(fn llvm.Value) {
+	fn.Param(0).SetName("typecode")
+	fn.Param(1).SetName("signature")
+	fn.SetLinkage(llvm.InternalLinkage)
+	fn.SetUnnamedAddr(true)
+	AddStandardAttributes(fn, p.config)
+
+	entry := p.ctx.AddBasicBlock(fn, "entry")
+	thenBlock := p.ctx.AddBasicBlock(fn, "then")
+	p.builder.SetInsertPointAtEnd(entry)
+
+	var typeNames []string
+	for name := range p.types {
+		typeNames = append(typeNames, name)
+	}
+	sort.Strings(typeNames)
+	for _, name := range typeNames {
+		t := p.types[name]
+		for _, method := range t.methods {
+			if !strings.Contains(method.name, ".$methods.") {
+				// Exported methods are named "reflect/methods.name(...)".
+				continue
+			}
+			nextBlock := p.ctx.AddBasicBlock(fn, name+".next")
+			typeMatch := p.builder.CreateICmp(llvm.IntEQ, fn.Param(0), t.typecodeGEP, name+".icmp")
+			signatureMatch := p.builder.CreateICmp(llvm.IntEQ, fn.Param(1), p.mod.NamedGlobal(method.name), "")
+			p.builder.CreateCondBr(p.builder.CreateAnd(typeMatch, signatureMatch, ""), thenBlock, nextBlock)
+			p.builder.SetInsertPointAtEnd(nextBlock)
+		}
+	}
+
+	// None of the types has this method.
+	p.builder.CreateRet(llvm.ConstInt(p.ctx.Int1Type(), 0, false))
+
+	p.builder.SetInsertPointAtEnd(thenBlock)
+	p.builder.CreateRet(llvm.ConstInt(p.ctx.Int1Type(), 1, false))
+}
+
+// sharedInvokeMinTypes is the number of types an interface must be implemented
+// by before its method thunks select a function pointer in the type switch and
+// call it once, instead of emitting a separate call for each type. This keeps
+// the thunks for big interfaces (like generated gRPC services) small, at the
+// cost of an indirect call that the stack size analysis can't follow.
+const sharedInvokeMinTypes = 8
+
 // defineInterfaceMethodFunc defines this thunk by calling the concrete method
 // of the type that implements this interface.
 //
 // Matching the actual type is implemented using an if/else chain over all
 // possible types.  This is later converted to a switch statement by the LLVM
-// simplifycfg pass.
+// simplifycfg pass. When there are many possible types, the chain only selects
+// the method to call, see sharedInvokeMinTypes.
 func (p *lowerInterfacesPass) defineInterfaceMethodFunc(fn llvm.Value, itf *interfaceInfo, signature *signatureInfo) {
 	context := fn.LastParam()
 	actualType := llvm.PrevParam(context)
@@ -541,8 +946,28 @@ func (p *lowerInterfacesPass) defineInterfaceMethodFunc(fn llvm.Value, itf *inte
 		p.builder.SetCurrentDebugLocation(0, 0, difunc, llvm.Metadata{})
 	}
 
+	// For interfaces with many types, all types branch to a single block that
+	// calls the method selected by the type switch.
+	sharedCall := len(itf.types) >= sharedInvokeMinTypes
+	var callBlock llvm.BasicBlock
+	var methods []llvm.Value
+	var methodBlocks []llvm.BasicBlock
+	if sharedCall {
+		callBlock = p.ctx.AddBasicBlock(fn, "call")
+	}
+
 	// Define all possible functions that can be called.
 	for _, typ := range itf.types {
+		if sharedCall {
+			next := p.ctx.AddBasicBlock(fn, typ.name+".next")
+			cmp := p.builder.CreateICmp(llvm.IntEQ, actualType, typ.typecodeGEP, typ.name+".icmp")
+			methods = append(methods, typ.getMethod(signature).function)
+			methodBlocks = append(methodBlocks, p.builder.GetInsertBlock())
+			p.builder.CreateCondBr(cmp, callBlock, next)
+			p.builder.SetInsertPointAtEnd(next)
+			continue
+		}
+
 		// Create type check (if/else).
 		bb := p.ctx.AddBasicBlock(fn, typ.name)
 		next := p.ctx.AddBasicBlock(fn, typ.name+".next")
@@ -599,6 +1024,31 @@ func (p *lowerInterfacesPass) defineInterfaceMethodFunc(fn llvm.Value, itf *inte
 		llvm.Undef(p.i8ptrType),
 	}, "")
 	p.builder.CreateUnreachable()
+
+	if sharedCall {
+		// Call the selected method. The receiver is passed as-is: all methods
+		// take a pointer-sized receiver so they can be called through the same
+		// function type.
+		p.builder.SetInsertPointAtEnd(callBlock)
+		receiver := fn.FirstParam()
+		paramTypes := []llvm.Type{receiver.Type()}
+		for _, param := range params {
+			paramTypes = append(paramTypes, param.Type())
+		}
+		functionType := llvm.FunctionType(returnType, paramTypes, false)
+		sig := llvm.PointerType(functionType, fn.Type().PointerAddressSpace())
+		for i, method := range methods {
+			methods[i] = llvm.ConstBitCast(method, sig)
+		}
+		method := p.builder.CreatePHI(sig, "method")
+		method.AddIncoming(methods, methodBlocks)
+		retval := p.builder.CreateCall(functionType, method, append([]llvm.Value{receiver}, params...), "")
+		if retval.Type().TypeKind() == llvm.VoidTypeKind {
+			p.builder.CreateRetVoid()
+		} else {
+			p.builder.CreateRet(retval)
+		}
+	}
 }
 
 func (p *lowerInterfacesPass) getDIFile(file string) llvm.Metadata {
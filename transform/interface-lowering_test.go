@@ -21,3 +21,78 @@ func TestInterfaceLowering(t *testing.T) {
 		pm.Run(mod)
 	})
 }
+
+func TestInterfaceLoweringManyTypes(t *testing.T) {
+	t.Parallel()
+	testTransform(t, "testdata/interface-many", func(mod llvm.Module) {
+		err := transform.LowerInterfaces(mod, defaultTestConfig)
+		if err != nil {
+			t.Error(err)
+		}
+
+		pm := llvm.NewPassManager()
+		defer pm.Dispose()
+		pm.AddGlobalDCEPass()
+		pm.Run(mod)
+	})
+}
+
+func TestInterfaceLoweringFuncThunks(t *testing.T) {
+	t.Parallel()
+	testTransform(t, "testdata/functhunks", func(mod llvm.Module) {
+		err := transform.LowerInterfaces(mod, defaultTestConfig)
+		if err != nil {
+			t.Error(err)
+		}
+
+		pm := llvm.NewPassManager()
+		defer pm.Dispose()
+		pm.AddGlobalDCEPass()
+		pm.Run(mod)
+	})
+}
+
+func TestInterfaceLoweringReflectMethodTypes(t *testing.T) {
+	t.Parallel()
+	testTransform(t, "testdata/reflect-method-types", func(mod llvm.Module) {
+		err := transform.LowerInterfaces(mod, defaultTestConfig)
+		if err != nil {
+			t.Error(err)
+		}
+
+		pm := llvm.NewPassManager()
+		defer pm.Dispose()
+		pm.AddGlobalDCEPass()
+		pm.Run(mod)
+	})
+}
+
+func TestInterfaceLoweringReflectUnexported(t *testing.T) {
+	t.Parallel()
+	testTransform(t, "testdata/reflect-unexported", func(mod llvm.Module) {
+		err := transform.LowerInterfaces(mod, defaultTestConfig)
+		if err != nil {
+			t.Error(err)
+		}
+
+		pm := llvm.NewPassManager()
+		defer pm.Dispose()
+		pm.AddGlobalDCEPass()
+		pm.Run(mod)
+	})
+}
+
+func TestInterfaceLoweringReflectCompilerTypes(t *testing.T) {
+	t.Parallel()
+	testTransform(t, "testdata/reflect-compilertypes", func(mod llvm.Module) {
+		err := transform.LowerInterfaces(mod, defaultTestConfig)
+		if err != nil {
+			t.Error(err)
+		}
+
+		pm := llvm.NewPassManager()
+		defer pm.Dispose()
+		pm.AddGlobalDCEPass()
+		pm.Run(mod)
+	})
+}
@@ -77,6 +77,27 @@ func replaceGlobalIntWithArray(mod llvm.Module, name string, buf interface{}) ll
 	return global
 }
 
+// typeHasPointers returns whether this type is a pointer or contains pointers.
+// If the type is an aggregate or vector type, it will check whether there is a
+// pointer inside.
+func typeHasPointers(t llvm.Type) bool {
+	switch t.TypeKind() {
+	case llvm.PointerTypeKind:
+		return true
+	case llvm.StructTypeKind:
+		for _, subType := range t.StructElementTypes() {
+			if typeHasPointers(subType) {
+				return true
+			}
+		}
+		return false
+	case llvm.ArrayTypeKind, llvm.VectorTypeKind:
+		return typeHasPointers(t.ElementType())
+	default:
+		return false
+	}
+}
+
 // stripPointerCasts strips instruction pointer casts (getelementptr and
 // bitcast) and returns the original value without the casts.
 func stripPointerCasts(value llvm.Value) llvm.Value {
@@ -9,23 +9,30 @@ import (
 // In the future, this should statically allocate created but never modified
 // maps. This has not yet been implemented, however.
 func OptimizeMaps(mod llvm.Module) {
-	hashmapMake := mod.NamedFunction("runtime.hashmapMake")
-	if hashmapMake.IsNil() {
+	var makeInsts []llvm.Value
+	for _, name := range []string{"runtime.hashmapMake", "runtime.hashmapMakeFunc"} {
+		hashmapMake := mod.NamedFunction(name)
+		if !hashmapMake.IsNil() {
+			makeInsts = append(makeInsts, getUses(hashmapMake)...)
+		}
+	}
+	if len(makeInsts) == 0 {
 		// nothing to optimize
 		return
 	}
 
 	hashmapBinarySet := mod.NamedFunction("runtime.hashmapBinarySet")
 	hashmapStringSet := mod.NamedFunction("runtime.hashmapStringSet")
+	hashmapGenericSet := mod.NamedFunction("runtime.hashmapGenericSet")
 
-	for _, makeInst := range getUses(hashmapMake) {
+	for _, makeInst := range makeInsts {
 		updateInsts := []llvm.Value{}
 		unknownUses := false // are there any uses other than setting a value?
 
 		for _, use := range getUses(makeInst) {
 			if use := use.IsACallInst(); !use.IsNil() {
 				switch use.CalledValue() {
-				case hashmapBinarySet, hashmapStringSet:
+				case hashmapBinarySet, hashmapStringSet, hashmapGenericSet:
 					updateInsts = append(updateInsts, use)
 				default:
 					unknownUses = true
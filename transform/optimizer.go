@@ -187,6 +187,28 @@ func Optimize(mod llvm.Module, config *compileopts.Config, optLevel, sizeLevel i
 	builder.Populate(modPasses)
 	modPasses.Run(mod)
 
+	if config.Options.PrintFloat64 != nil {
+		PrintFloat64(mod, config.Options.PrintFloat64, func(pos token.Position, msg string) {
+			fmt.Fprintln(os.Stderr, pos.String()+": "+msg)
+		})
+	}
+	if config.Options.WarnStackFrame != 0 {
+		PrintLargeStackFrames(mod, config.Options.WarnStackFrame, func(pos token.Position, msg string) {
+			fmt.Fprintln(os.Stderr, pos.String()+": warning: "+msg)
+		})
+	}
+	if config.Options.WarnAllocInLoop || len(config.Options.AllocInLoopErrors) != 0 {
+		var logger func(token.Position, string)
+		if config.Options.WarnAllocInLoop {
+			logger = func(pos token.Position, msg string) {
+				fmt.Fprintln(os.Stderr, pos.String()+": warning: "+msg)
+			}
+		}
+		if errs := ReportHeapAllocsInLoops(mod, config.Options.AllocInLoopErrors, logger); errs != nil {
+			return errs
+		}
+	}
+
 	hasGCPass := MakeGCStackSlots(mod)
 	if hasGCPass {
 		if err := llvm.VerifyModule(mod, llvm.PrintMessageAction); err != nil {
@@ -0,0 +1,73 @@
+package transform
+
+// This file fills in the registries declared with //go:registry, see
+// compiler/registry.go.
+
+import (
+	"errors"
+	"strings"
+
+	"tinygo.org/x/go-llvm"
+)
+
+// LowerRegistries fills in each registry with the functions that were added
+// to it in all packages. It must be run after all packages are linked together
+// and before package initializers are interpreted, so that the registries are
+// complete during package initialization.
+//
+// Each registry has a marker global named "pkgpath.Name$registry", and the
+// entries of all packages are combined in a global with appending linkage
+// named "pkgpath.Name$registry.entries". The entries are moved to a table
+// that the registry slice refers to, so that entries are removed
+// together with the registry if it is unused.
+func LowerRegistries(mod llvm.Module) error {
+	var markers, entryLists []llvm.Value
+	for global := mod.FirstGlobal(); !global.IsNil(); global = llvm.NextGlobal(global) {
+		name := global.Name()
+		if strings.HasSuffix(name, "$registry") {
+			markers = append(markers, global)
+		} else if strings.HasSuffix(name, "$registry.entries") {
+			entryLists = append(entryLists, global)
+		}
+	}
+
+	for _, entries := range entryLists {
+		name := strings.TrimSuffix(entries.Name(), "$registry.entries")
+		if mod.NamedGlobal(name + "$registry").IsNil() {
+			return errors.New(name + " is not declared as a registry with //go:registry")
+		}
+		registry := mod.NamedGlobal(name)
+		if registry.IsNil() || registry.IsDeclaration() {
+			return errors.New("registry " + name + " is not defined")
+		}
+
+		// Move the entries to a table. The table is not constant, because
+		// the registry is a regular slice which the program may modify.
+		initializer := entries.Initializer()
+		entries.EraseFromParentAsGlobal()
+		table := llvm.AddGlobal(mod, initializer.Type(), name+"$registry.table")
+		table.SetInitializer(initializer)
+		table.SetLinkage(llvm.InternalLinkage)
+
+		// Let the registry slice refer to the table.
+		sliceType := registry.GlobalValueType()
+		fieldTypes := sliceType.StructElementTypes()
+		length := uint64(initializer.Type().ArrayLength())
+		fields := []llvm.Value{
+			llvm.ConstBitCast(table, fieldTypes[0]),
+			llvm.ConstInt(fieldTypes[1], length, false),
+			llvm.ConstInt(fieldTypes[2], length, false),
+		}
+		if sliceType.StructName() != "" {
+			registry.SetInitializer(llvm.ConstNamedStruct(sliceType, fields))
+		} else {
+			registry.SetInitializer(mod.Context().ConstStruct(fields, false))
+		}
+	}
+
+	// The markers are not needed anymore.
+	for _, marker := range markers {
+		marker.EraseFromParentAsGlobal()
+	}
+	return nil
+}
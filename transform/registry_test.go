@@ -0,0 +1,18 @@
+package transform_test
+
+import (
+	"testing"
+
+	"github.com/tinygo-org/tinygo/transform"
+	"tinygo.org/x/go-llvm"
+)
+
+func TestLowerRegistries(t *testing.T) {
+	t.Parallel()
+	testTransform(t, "testdata/registry", func(mod llvm.Module) {
+		err := transform.LowerRegistries(mod)
+		if err != nil {
+			t.Error(err)
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package transform
+
+// This file implements a diagnostic (not an optimization) that reports
+// functions with large stack frames. Stacks on microcontrollers are small (a
+// few kilobytes for goroutines), and a large local array or a struct copied by
+// value easily overflows them without any warning at compile time.
+//
+// There is no separate diagnostic for recursive types: a type that contains
+// itself by value is already rejected by the type checker ("invalid recursive
+// type"), and a type that refers to itself through a pointer, slice or map
+// only takes up the size of that reference in a stack frame.
+
+import (
+	"fmt"
+	"go/token"
+	"sort"
+	"strings"
+
+	"tinygo.org/x/go-llvm"
+)
+
+// maxReportedLocals is the maximum number of local variables listed in a
+// large stack frame warning.
+const maxReportedLocals = 5
+
+// PrintLargeStackFrames reports all functions whose local variables (allocas)
+// take up more than threshold bytes of stack, together with the largest of
+// these variables. It should be run after optimization, when all heap
+// allocations that can be moved to the stack have been moved and small
+// functions have been inlined into their callers.
+//
+// The size is an estimate: the code generator may reuse stack slots of
+// variables that are not live at the same time, and it needs extra stack space
+// for spilled registers and outgoing call arguments.
+func PrintLargeStackFrames(mod llvm.Module, threshold uint64, logger func(token.Position, string)) {
+	targetData := llvm.NewTargetData(mod.DataLayout())
+	defer targetData.Dispose()
+
+	type local struct {
+		name string
+		size uint64
+	}
+	for fn := mod.FirstFunction(); !fn.IsNil(); fn = llvm.NextFunction(fn) {
+		if fn.IsDeclaration() {
+			continue
+		}
+		var locals []local
+		var total uint64
+		for bb := fn.FirstBasicBlock(); !bb.IsNil(); bb = llvm.NextBasicBlock(bb) {
+			for inst := bb.FirstInstruction(); !inst.IsNil(); inst = llvm.NextInstruction(inst) {
+				if inst.IsAAllocaInst().IsNil() {
+					continue
+				}
+				size := targetData.TypeAllocSize(inst.AllocatedType())
+				if count := inst.Operand(0); !count.IsAConstantInt().IsNil() {
+					size *= count.ZExtValue()
+				}
+				name := inst.Name()
+				if name == "" {
+					name = "(unnamed)"
+				}
+				locals = append(locals, local{name, size})
+				total += size
+			}
+		}
+		if total <= threshold {
+			continue
+		}
+
+		sort.SliceStable(locals, func(i, j int) bool {
+			return locals[i].size > locals[j].size
+		})
+		if len(locals) > maxReportedLocals {
+			locals = locals[:maxReportedLocals]
+		}
+		var largest []string
+		for _, l := range locals {
+			largest = append(largest, fmt.Sprintf("%s (%d bytes)", l.name, l.size))
+		}
+		logger(getPosition(fn), fmt.Sprintf("stack frame of %s is at least %d bytes: %s", fn.Name(), total, strings.Join(largest, ", ")))
+	}
+}
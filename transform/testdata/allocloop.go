@@ -0,0 +1,39 @@
+package main
+
+func main() {
+	n1 := 5 // not in a loop
+	returnIntPtr(&n1)
+
+	for i := 0; i < 3; i++ {
+		// Doesn't escape, so is allocated on the stack.
+		s1 := make([]int, 3)
+		readIntSlice(s1)
+
+		s2 := make([]int, getUnknownNumber()) // OUT: heap allocation in loop: size is not constant
+		readIntSlice(s2)
+
+		n2 := i // OUT: heap allocation in loop: escapes at line 16
+		returnIntPtr(&n2)
+	}
+
+	for {
+		s3 := make([]byte, 300) // OUT: heap allocation in loop: object size 300 exceeds maximum stack allocation size 256
+		if readByteSlice(s3) == 0 {
+			break
+		}
+	}
+}
+
+func returnIntPtr(x *int) *int {
+	return x
+}
+
+func readIntSlice(s []int) int {
+	return s[1]
+}
+
+func readByteSlice(s []byte) byte {
+	return s[1]
+}
+
+func getUnknownNumber() int
@@ -70,6 +70,48 @@ define void @testZeroSizedAlloc() {
   ret void
 }
 
+; Put the allocated value in an interface that doesn't escape.
+define void @testNonEscapingInterface() {
+  %alloc = call ptr @runtime.alloc(i32 4, ptr null)
+  store i32 5, ptr %alloc
+  %itf = insertvalue { ptr, ptr } { ptr null, ptr undef }, ptr %alloc, 1
+  %itf.value = extractvalue { ptr, ptr } %itf, 1
+  %ptr = call ptr @noescapeIntPtr(ptr %itf.value)
+  ret void
+}
+
+; Return an interface with the allocated value, which lets it escape.
+define { ptr, ptr } @testEscapingInterface() {
+  %alloc = call ptr @runtime.alloc(i32 4, ptr null)
+  store i32 5, ptr %alloc
+  %itf = insertvalue { ptr, ptr } { ptr null, ptr undef }, ptr %alloc, 1
+  ret { ptr, ptr } %itf
+}
+
+; Put the allocated value in an interface inside a struct and pass the
+; interface value to a function that doesn't capture it.
+define void @testNonEscapingNestedInterface() {
+  %alloc = call ptr @runtime.alloc(i32 4, ptr null)
+  store i32 5, ptr %alloc
+  %itf = insertvalue { ptr, ptr } { ptr null, ptr undef }, ptr %alloc, 1
+  %struct = insertvalue { i32, { ptr, ptr } } { i32 1, { ptr, ptr } undef }, { ptr, ptr } %itf, 1
+  %struct.itf = extractvalue { i32, { ptr, ptr } } %struct, 1
+  %itf.value = extractvalue { ptr, ptr } %struct.itf, 1
+  %ptr = call ptr @noescapeIntPtr(ptr %itf.value)
+  ret void
+}
+
+; Put the allocated value in an interface inside a struct and return the
+; interface, which lets it escape.
+define { ptr, ptr } @testEscapingNestedInterface() {
+  %alloc = call ptr @runtime.alloc(i32 4, ptr null)
+  store i32 5, ptr %alloc
+  %itf = insertvalue { ptr, ptr } { ptr null, ptr undef }, ptr %alloc, 1
+  %struct = insertvalue { i32, { ptr, ptr } } { i32 1, { ptr, ptr } undef }, { ptr, ptr } %itf, 1
+  %struct.itf = extractvalue { i32, { ptr, ptr } } %struct, 1
+  ret { ptr, ptr } %struct.itf
+}
+
 declare ptr @escapeIntPtr(ptr)
 
 declare ptr @noescapeIntPtr(ptr nocapture)
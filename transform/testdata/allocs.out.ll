@@ -66,6 +66,44 @@ define void @testZeroSizedAlloc() {
   ret void
 }
 
+define void @testNonEscapingInterface() {
+  %stackalloc.alloca = alloca [4 x i8], align 4
+  store [4 x i8] zeroinitializer, ptr %stackalloc.alloca, align 4
+  store i32 5, ptr %stackalloc.alloca, align 4
+  %itf = insertvalue { ptr, ptr } { ptr null, ptr undef }, ptr %stackalloc.alloca, 1
+  %itf.value = extractvalue { ptr, ptr } %itf, 1
+  %ptr = call ptr @noescapeIntPtr(ptr %itf.value)
+  ret void
+}
+
+define { ptr, ptr } @testEscapingInterface() {
+  %alloc = call ptr @runtime.alloc(i32 4, ptr null)
+  store i32 5, ptr %alloc, align 4
+  %itf = insertvalue { ptr, ptr } { ptr null, ptr undef }, ptr %alloc, 1
+  ret { ptr, ptr } %itf
+}
+
+define void @testNonEscapingNestedInterface() {
+  %stackalloc.alloca = alloca [4 x i8], align 4
+  store [4 x i8] zeroinitializer, ptr %stackalloc.alloca, align 4
+  store i32 5, ptr %stackalloc.alloca, align 4
+  %itf = insertvalue { ptr, ptr } { ptr null, ptr undef }, ptr %stackalloc.alloca, 1
+  %struct = insertvalue { i32, { ptr, ptr } } { i32 1, { ptr, ptr } undef }, { ptr, ptr } %itf, 1
+  %struct.itf = extractvalue { i32, { ptr, ptr } } %struct, 1
+  %itf.value = extractvalue { ptr, ptr } %struct.itf, 1
+  %ptr = call ptr @noescapeIntPtr(ptr %itf.value)
+  ret void
+}
+
+define { ptr, ptr } @testEscapingNestedInterface() {
+  %alloc = call ptr @runtime.alloc(i32 4, ptr null)
+  store i32 5, ptr %alloc, align 4
+  %itf = insertvalue { ptr, ptr } { ptr null, ptr undef }, ptr %alloc, 1
+  %struct = insertvalue { i32, { ptr, ptr } } { i32 1, { ptr, ptr } undef }, { ptr, ptr } %itf, 1
+  %struct.itf = extractvalue { i32, { ptr, ptr } } %struct, 1
+  ret { ptr, ptr } %struct.itf
+}
+
 declare ptr @escapeIntPtr(ptr)
 
 declare ptr @noescapeIntPtr(ptr nocapture)
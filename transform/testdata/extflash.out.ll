@@ -0,0 +1,21 @@
+target datalayout = "e-m:e-p:32:32-i64:64-v128:64:128-a:0:32-n32-S64"
+target triple = "armv7m-none-eabi"
+
+@main.table = internal constant [4 x i8] c"\01\02\03\04", section ".extflash.rodata.main.table"
+@"main$string" = internal unnamed_addr constant [5 x i8] c"hello", section ".extflash.rodata.main$string", align 1
+@main.variable = internal global [4 x i8] c"\01\02\03\04"
+@main.pragma = internal constant [4 x i8] c"\05\06\07\08", section ".data.custom"
+@other.table = internal constant [4 x i8] c"\01\02\03\04"
+
+declare void @use(ptr)
+
+define void @main.foo() {
+entry:
+  call void @use(ptr @main.table)
+  call void @use(ptr @"main$string")
+  call void @use(ptr @"main$string")
+  call void @use(ptr @main.variable)
+  call void @use(ptr @main.pragma)
+  call void @use(ptr @other.table)
+  ret void
+}
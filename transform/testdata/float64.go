@@ -0,0 +1,28 @@
+package main
+
+func main() {
+}
+
+func add64(a, b float64) float64 {
+	return a + b // OUT: float64 arithmetic in main.add64
+}
+
+func add32(a, b float32) float32 {
+	return a + b
+}
+
+func less64(a, b float64) bool {
+	return a < b // OUT: float64 comparison in main.less64
+}
+
+func less32(a, b float32) bool {
+	return a < b
+}
+
+func extend(f float32) float64 {
+	return float64(f) // OUT: float32 converted to float64 in main.extend
+}
+
+func fromInt(n int) float64 {
+	return float64(n) // OUT: integer converted to float64 in main.fromInt
+}
@@ -0,0 +1,18 @@
+target datalayout = "e-m:e-p:32:32-i64:64-v128:64:128-a:0:32-n32-S64"
+target triple = "armv7m-none-eabi"
+
+; Neither reflect.Value.Call nor reflect.MakeFunc are used, so the thunks in
+; function type codes are not needed.
+@"reflect/types.type:func:{}{}" = linkonce_odr constant { i8, i16, ptr, i16, { ptr, ptr }, { ptr, ptr }, [0 x ptr], [7 x i8] } { i8 24, i16 0, ptr null, i16 0, { ptr, ptr } { ptr null, ptr @"reflect/types.call:func:{}{}" }, { ptr, ptr } { ptr null, ptr @"reflect/types.makeFunc:func:{}{}" }, [0 x ptr] zeroinitializer, [7 x i8] c"func()\00" }, align 4
+
+define linkonce_odr void @"reflect/types.call:func:{}{}"(ptr %fn, ptr %args, ptr %results, ptr %context) {
+  ret void
+}
+
+define linkonce_odr void @"reflect/types.makeFunc:func:{}{}"(ptr %context) {
+  ret void
+}
+
+define ptr @funcTypeCode() {
+  ret ptr @"reflect/types.type:func:{}{}"
+}
@@ -0,0 +1,8 @@
+target datalayout = "e-m:e-p:32:32-i64:64-v128:64:128-a:0:32-n32-S64"
+target triple = "armv7m-none-eabi"
+
+@"reflect/types.type:func:{}{}" = linkonce_odr constant { i8, i16, ptr, i16, { ptr, ptr }, { ptr, ptr }, [0 x ptr], [7 x i8] } { i8 24, i16 0, ptr null, i16 0, { ptr, ptr } zeroinitializer, { ptr, ptr } zeroinitializer, [0 x ptr] zeroinitializer, [7 x i8] c"func()\00" }, align 4
+
+define ptr @funcTypeCode() {
+  ret ptr @"reflect/types.type:func:{}{}"
+}
@@ -0,0 +1,41 @@
+target datalayout = "e-m:e-p:32:32-i64:64-v128:64:128-a:0:32-n32-S64"
+target triple = "armv7m-none-eabi"
+
+@"reflect/methods.Len() int" = linkonce_odr constant i8 0
+@"reflect/methods.Size() int" = linkonce_odr constant i8 0
+@"T0$methodset" = linkonce_odr unnamed_addr constant { i32, [2 x ptr], { ptr, ptr } } { i32 2, [2 x ptr] [ptr @"reflect/methods.Size() int", ptr @"reflect/methods.Len() int"], { ptr, ptr } { ptr @"(T0).Size$invoke", ptr @"(T0).Len$invoke" } }
+@"reflect/types.type:named:T0" = linkonce_odr constant { ptr, i8 } { ptr @"T0$methodset", i8 34 }, align 4
+@"T1$methodset" = linkonce_odr unnamed_addr constant { i32, [1 x ptr], { ptr } } { i32 1, [1 x ptr] [ptr @"reflect/methods.Size() int"], { ptr } { ptr @"(T1).Size$invoke" } }
+@"reflect/types.type:named:T1" = linkonce_odr constant { ptr, i8 } { ptr @"T1$methodset", i8 34 }, align 4
+@"T2$methodset" = linkonce_odr unnamed_addr constant { i32, [1 x ptr], { ptr } } { i32 1, [1 x ptr] [ptr @"reflect/methods.Size() int"], { ptr } { ptr @"(T2).Size$invoke" } }
+@"reflect/types.type:named:T2" = linkonce_odr constant { ptr, i8 } { ptr @"T2$methodset", i8 34 }, align 4
+@"T3$methodset" = linkonce_odr unnamed_addr constant { i32, [1 x ptr], { ptr } } { i32 1, [1 x ptr] [ptr @"reflect/methods.Size() int"], { ptr } { ptr @"(T3).Size$invoke" } }
+@"reflect/types.type:named:T3" = linkonce_odr constant { ptr, i8 } { ptr @"T3$methodset", i8 34 }, align 4
+@"T4$methodset" = linkonce_odr unnamed_addr constant { i32, [1 x ptr], { ptr } } { i32 1, [1 x ptr] [ptr @"reflect/methods.Size() int"], { ptr } { ptr @"(T4).Size$invoke" } }
+@"reflect/types.type:named:T4" = linkonce_odr constant { ptr, i8 } { ptr @"T4$methodset", i8 34 }, align 4
+@"T5$methodset" = linkonce_odr unnamed_addr constant { i32, [1 x ptr], { ptr } } { i32 1, [1 x ptr] [ptr @"reflect/methods.Size() int"], { ptr } { ptr @"(T5).Size$invoke" } }
+@"reflect/types.type:named:T5" = linkonce_odr constant { ptr, i8 } { ptr @"T5$methodset", i8 34 }, align 4
+@"T6$methodset" = linkonce_odr unnamed_addr constant { i32, [1 x ptr], { ptr } } { i32 1, [1 x ptr] [ptr @"reflect/methods.Size() int"], { ptr } { ptr @"(T6).Size$invoke" } }
+@"reflect/types.type:named:T6" = linkonce_odr constant { ptr, i8 } { ptr @"T6$methodset", i8 34 }, align 4
+@"T7$methodset" = linkonce_odr unnamed_addr constant { i32, [1 x ptr], { ptr } } { i32 1, [1 x ptr] [ptr @"reflect/methods.Size() int"], { ptr } { ptr @"(T7).Size$invoke" } }
+@"reflect/types.type:named:T7" = linkonce_odr constant { ptr, i8 } { ptr @"T7$methodset", i8 34 }, align 4
+
+declare void @runtime.nilPanic(ptr)
+declare i32 @"(T0).Len$invoke"(ptr, ptr)
+declare i32 @"(T0).Size$invoke"(ptr, ptr)
+declare i32 @"(T1).Size$invoke"(ptr, ptr)
+declare i32 @"(T2).Size$invoke"(ptr, ptr)
+declare i32 @"(T3).Size$invoke"(ptr, ptr)
+declare i32 @"(T4).Size$invoke"(ptr, ptr)
+declare i32 @"(T5).Size$invoke"(ptr, ptr)
+declare i32 @"(T6).Size$invoke"(ptr, ptr)
+declare i32 @"(T7).Size$invoke"(ptr, ptr)
+
+define i32 @callSize(ptr %typecode, ptr %value) {
+  %result = call i32 @"Sizer.Size$invoke"(ptr %value, ptr %typecode, ptr undef)
+  ret i32 %result
+}
+
+declare i32 @"Sizer.Size$invoke"(ptr %receiver, ptr %typecode, ptr %context) #0
+
+attributes #0 = { "tinygo-invoke"="reflect/methods.Size() int" "tinygo-methods"="reflect/methods.Size() int" }
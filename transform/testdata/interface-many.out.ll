@@ -0,0 +1,79 @@
+target datalayout = "e-m:e-p:32:32-i64:64-v128:64:128-a:0:32-n32-S64"
+target triple = "armv7m-none-eabi"
+
+@"reflect/types.type:named:T0" = linkonce_odr constant { i8 } { i8 34 }, align 4
+@"reflect/types.type:named:T1" = linkonce_odr constant { i8 } { i8 34 }, align 4
+@"reflect/types.type:named:T2" = linkonce_odr constant { i8 } { i8 34 }, align 4
+@"reflect/types.type:named:T3" = linkonce_odr constant { i8 } { i8 34 }, align 4
+@"reflect/types.type:named:T4" = linkonce_odr constant { i8 } { i8 34 }, align 4
+@"reflect/types.type:named:T5" = linkonce_odr constant { i8 } { i8 34 }, align 4
+@"reflect/types.type:named:T6" = linkonce_odr constant { i8 } { i8 34 }, align 4
+@"reflect/types.type:named:T7" = linkonce_odr constant { i8 } { i8 34 }, align 4
+
+declare void @runtime.nilPanic(ptr)
+
+declare i32 @"(T0).Size$invoke"(ptr, ptr)
+
+declare i32 @"(T1).Size$invoke"(ptr, ptr)
+
+declare i32 @"(T2).Size$invoke"(ptr, ptr)
+
+declare i32 @"(T3).Size$invoke"(ptr, ptr)
+
+declare i32 @"(T4).Size$invoke"(ptr, ptr)
+
+declare i32 @"(T5).Size$invoke"(ptr, ptr)
+
+declare i32 @"(T6).Size$invoke"(ptr, ptr)
+
+declare i32 @"(T7).Size$invoke"(ptr, ptr)
+
+define i32 @callSize(ptr %typecode, ptr %value) {
+  %result = call i32 @"Sizer.Size$invoke"(ptr %value, ptr %typecode, ptr undef)
+  ret i32 %result
+}
+
+define internal i32 @"Sizer.Size$invoke"(ptr %receiver, ptr %actualType, ptr %context) unnamed_addr #0 {
+entry:
+  %"named:T7.icmp" = icmp eq ptr %actualType, @"reflect/types.type:named:T7"
+  br i1 %"named:T7.icmp", label %call, label %"named:T7.next"
+
+call:
+  %method = phi ptr [ @"(T7).Size$invoke", %entry ], [ @"(T6).Size$invoke", %"named:T7.next" ], [ @"(T5).Size$invoke", %"named:T6.next" ], [ @"(T4).Size$invoke", %"named:T5.next" ], [ @"(T3).Size$invoke", %"named:T4.next" ], [ @"(T2).Size$invoke", %"named:T3.next" ], [ @"(T1).Size$invoke", %"named:T2.next" ], [ @"(T0).Size$invoke", %"named:T1.next" ]
+  %0 = call i32 %method(ptr %receiver, ptr undef)
+  ret i32 %0
+
+"named:T7.next":
+  %"named:T6.icmp" = icmp eq ptr %actualType, @"reflect/types.type:named:T6"
+  br i1 %"named:T6.icmp", label %call, label %"named:T6.next"
+
+"named:T6.next":
+  %"named:T5.icmp" = icmp eq ptr %actualType, @"reflect/types.type:named:T5"
+  br i1 %"named:T5.icmp", label %call, label %"named:T5.next"
+
+"named:T5.next":
+  %"named:T4.icmp" = icmp eq ptr %actualType, @"reflect/types.type:named:T4"
+  br i1 %"named:T4.icmp", label %call, label %"named:T4.next"
+
+"named:T4.next":
+  %"named:T3.icmp" = icmp eq ptr %actualType, @"reflect/types.type:named:T3"
+  br i1 %"named:T3.icmp", label %call, label %"named:T3.next"
+
+"named:T3.next":
+  %"named:T2.icmp" = icmp eq ptr %actualType, @"reflect/types.type:named:T2"
+  br i1 %"named:T2.icmp", label %call, label %"named:T2.next"
+
+"named:T2.next":
+  %"named:T1.icmp" = icmp eq ptr %actualType, @"reflect/types.type:named:T1"
+  br i1 %"named:T1.icmp", label %call, label %"named:T1.next"
+
+"named:T1.next":
+  %"named:T0.icmp" = icmp eq ptr %actualType, @"reflect/types.type:named:T0"
+  br i1 %"named:T0.icmp", label %call, label %"named:T0.next"
+
+"named:T0.next":
+  call void @runtime.nilPanic(ptr undef)
+  unreachable
+}
+
+attributes #0 = { "tinygo-invoke"="reflect/methods.Size() int" "tinygo-methods"="reflect/methods.Size() int" }
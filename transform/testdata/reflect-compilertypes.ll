@@ -0,0 +1,13 @@
+target datalayout = "e-m:e-p:32:32-i64:64-v128:64:128-a:0:32-n32-S64"
+target triple = "armv7m-none-eabi"
+
+@"reflect/types.type:array:2:basic:int" = linkonce_odr constant { i8, ptr } { i8 17, ptr @"reflect/types.type:basic:int" }, align 4
+@"reflect/types.type:basic:int" = linkonce_odr constant { i8, ptr } { i8 2, ptr @"reflect/types.type:pointer:basic:int" }, align 4
+@"reflect/types.type:pointer:basic:int" = linkonce_odr constant { i8, ptr } { i8 21, ptr @"reflect/types.type:basic:int" }, align 4
+@"reflect/types.type:slice:basic:int" = linkonce_odr constant { i8, ptr } { i8 23, ptr @"reflect/types.type:basic:int" }, align 4
+@reflect.compilerTypes = external global [0 x ptr]
+
+define ptr @firstType() {
+  %t = load ptr, ptr @reflect.compilerTypes, align 4
+  ret ptr %t
+}
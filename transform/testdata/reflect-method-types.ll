@@ -0,0 +1,21 @@
+target datalayout = "e-m:e-p:32:32-i64:64-v128:64:128-a:0:32-n32-S64"
+target triple = "armv7m-none-eabi"
+
+; Neither reflect.Type.Method nor reflect.Value.Method are used, so the method
+; types in the method table and method signature are not needed.
+@"reflect/types.type:func:{}{}" = linkonce_odr constant { i8, i16, ptr, i16, { ptr, ptr }, { ptr, ptr }, [0 x ptr], [7 x i8] } { i8 24, i16 0, ptr null, i16 0, { ptr, ptr } zeroinitializer, { ptr, ptr } zeroinitializer, [0 x ptr] zeroinitializer, [7 x i8] c"func()\00" }, align 4
+@"reflect/types.type:func:{named:T}{}" = linkonce_odr constant { i8, i16, ptr, i16, { ptr, ptr }, { ptr, ptr }, [1 x ptr], [8 x i8] } { i8 24, i16 1, ptr null, i16 0, { ptr, ptr } zeroinitializer, { ptr, ptr } zeroinitializer, [1 x ptr] [ptr @"reflect/types.type:named:T"], [8 x i8] c"func(T)\00" }, align 4
+@"reflect/methods.Foo()" = linkonce_odr constant { ptr, [4 x i8] } { ptr @"reflect/types.type:func:{}{}", [4 x i8] c"Foo\00" }, align 4
+@"T$methods" = linkonce_odr unnamed_addr constant [1 x { ptr, ptr, ptr, ptr }] [{ ptr, ptr, ptr, ptr } { ptr @"reflect/methods.Foo()", ptr @"reflect/types.type:func:{named:T}{}", ptr @"(T).Foo$invoke", ptr @"reflect/types.call:func:{basic:unsafe.Pointer}{}" }]
+@"T$methodset" = linkonce_odr unnamed_addr constant { i32, [1 x ptr], { ptr }, ptr } { i32 1, [1 x ptr] [ptr @"reflect/methods.Foo()"], { ptr } { ptr @"(T).Foo$invoke" }, ptr @"T$methods" }
+@"reflect/types.type:named:T" = linkonce_odr constant { ptr, i8 } { ptr @"T$methodset", i8 34 }, align 4
+
+declare void @"(T).Foo$invoke"(ptr, ptr)
+
+define linkonce_odr void @"reflect/types.call:func:{basic:unsafe.Pointer}{}"(ptr %fn, ptr %args, ptr %results, ptr %context) {
+  ret void
+}
+
+define ptr @typeCode() {
+  ret ptr @"reflect/types.type:named:T"
+}
@@ -0,0 +1,10 @@
+target datalayout = "e-m:e-p:32:32-i64:64-v128:64:128-a:0:32-n32-S64"
+target triple = "armv7m-none-eabi"
+
+@"reflect/methods.Foo()" = linkonce_odr constant { ptr, [4 x i8] } { ptr null, [4 x i8] c"Foo\00" }, align 4
+@"T$methods" = linkonce_odr unnamed_addr constant [1 x { ptr, ptr, ptr, ptr }] [{ ptr, ptr, ptr, ptr } { ptr @"reflect/methods.Foo()", ptr null, ptr null, ptr null }]
+@"reflect/types.type:named:T" = linkonce_odr constant { ptr, i8 } { ptr @"T$methods", i8 34 }, align 4
+
+define ptr @typeCode() {
+  ret ptr @"reflect/types.type:named:T"
+}
@@ -0,0 +1,22 @@
+target datalayout = "e-m:e-p:32:32-i64:64-v128:64:128-a:0:32-n32-S64"
+target triple = "armv7m-none-eabi"
+
+@"main.$methods.foo()" = linkonce_odr constant { ptr, [4 x i8] } { ptr null, [4 x i8] c"foo\00" }, align 4
+@"reflect/methods.Bar()" = linkonce_odr constant { ptr, [4 x i8] } { ptr null, [4 x i8] c"Bar\00" }, align 4
+@"T$methodset" = linkonce_odr unnamed_addr constant { i32, [2 x ptr], { ptr, ptr } } { i32 2, [2 x ptr] [ptr @"reflect/methods.Bar()", ptr @"main.$methods.foo()"], { ptr, ptr } { ptr @"(T).Bar$invoke", ptr @"(T).foo$invoke" } }
+@"reflect/types.type:named:T" = linkonce_odr constant { ptr, i8 } { ptr @"T$methodset", i8 34 }, align 4
+
+declare i1 @reflect.hasUnexportedMethod(ptr, ptr, ptr)
+
+declare void @"(T).Bar$invoke"(ptr, ptr)
+
+declare void @"(T).foo$invoke"(ptr, ptr)
+
+define i1 @hasFoo(ptr %typecode) {
+  %result = call i1 @reflect.hasUnexportedMethod(ptr %typecode, ptr @"main.$methods.foo()", ptr undef)
+  ret i1 %result
+}
+
+define ptr @typeCode() {
+  ret ptr getelementptr inbounds ({ ptr, i8 }, ptr @"reflect/types.type:named:T", i32 0, i32 1)
+}
@@ -0,0 +1,28 @@
+target datalayout = "e-m:e-p:32:32-i64:64-v128:64:128-a:0:32-n32-S64"
+target triple = "armv7m-none-eabi"
+
+@"main.$methods.foo()" = linkonce_odr constant { ptr, [4 x i8] } { ptr null, [4 x i8] c"foo\00" }, align 4
+@"reflect/types.type:named:T" = linkonce_odr constant { i8 } { i8 34 }, align 4
+
+define internal i1 @reflect.hasUnexportedMethod(ptr %typecode, ptr %signature, ptr %2) unnamed_addr {
+entry:
+  %"named:T.icmp" = icmp eq ptr %typecode, @"reflect/types.type:named:T"
+  %3 = icmp eq ptr %signature, @"main.$methods.foo()"
+  %4 = and i1 %"named:T.icmp", %3
+  br i1 %4, label %then, label %"named:T.next"
+
+then:                                             ; preds = %entry
+  ret i1 true
+
+"named:T.next":                                   ; preds = %entry
+  ret i1 false
+}
+
+define i1 @hasFoo(ptr %typecode) {
+  %result = call i1 @reflect.hasUnexportedMethod(ptr %typecode, ptr @"main.$methods.foo()", ptr undef)
+  ret i1 %result
+}
+
+define ptr @typeCode() {
+  ret ptr @"reflect/types.type:named:T"
+}
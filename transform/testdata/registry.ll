@@ -0,0 +1,18 @@
+target datalayout = "e-m:e-p:32:32-i64:64-v128:64:128-a:0:32-n32-S64"
+target triple = "armv7m-none-eabi"
+
+@main.handlers = hidden global { ptr, i32, i32 } zeroinitializer
+@"main.handlers$registry" = constant i8 0
+@"main.handlers$registry.entries" = appending global [2 x { ptr, ptr }] [{ ptr, ptr } { ptr null, ptr @main.handleFoo }, { ptr, ptr } { ptr null, ptr @main.handleBar }]
+@"main.empty$registry" = constant i8 0
+@main.empty = hidden global { ptr, i32, i32 } zeroinitializer
+
+define internal void @main.handleFoo(ptr %context) {
+entry:
+  ret void
+}
+
+define internal void @main.handleBar(ptr %context) {
+entry:
+  ret void
+}
@@ -0,0 +1,18 @@
+package main
+
+func main() {
+}
+
+func small(i, j int) byte {
+	var a [100]byte
+	a[i] = 1
+	return a[j]
+}
+
+func large(i, j int) byte { // OUT: stack frame of main.large is at least 300 bytes: a (200 bytes), b (100 bytes)
+	var a [200]byte
+	var b [100]byte
+	a[i] = 1
+	b[i] = 2
+	return a[j] + b[j]
+}
@@ -26,3 +26,11 @@ define internal void @callExportedFunction(i64 %foo) {
   call void @exportedFunction(i64 %foo)
   ret void
 }
+
+; Functions exported with //go:wasmexport keep their i64 parameters.
+define i64 @wasmExportedFunction(i64 %foo) #0 {
+  %result = shl i64 %foo, 1
+  ret i64 %result
+}
+
+attributes #0 = { "tinygo-wasmexport" }
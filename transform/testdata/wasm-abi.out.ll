@@ -35,6 +35,11 @@ define internal void @callExportedFunction(i64 %foo) {
   ret void
 }
 
+define i64 @wasmExportedFunction(i64 %foo) #0 {
+  %result = shl i64 %foo, 1
+  ret i64 %result
+}
+
 declare void @externalCall(ptr, ptr, i32, ptr)
 
 define void @exportedFunction(ptr %0) {
@@ -43,3 +48,5 @@ entry:
   call void @"exportedFunction$i64wrap"(i64 %i64)
   ret void
 }
+
+attributes #0 = { "tinygo-wasmexport" }
@@ -38,6 +38,11 @@ func ExternalInt64AsPtr(mod llvm.Module, config *compileopts.Config) error {
 			// transforms.
 			continue
 		}
+		if !fn.GetStringAttributeAtIndex(-1, "tinygo-wasmexport").IsNil() {
+			// Functions exported with //go:wasmexport use the WebAssembly
+			// types directly, including i64.
+			continue
+		}
 		if !fn.GetStringAttributeAtIndex(-1, "tinygo-methods").IsNil() {
 			// These are internal functions (interface method call, interface
 			// type assert) that will be lowered by the interface lowering pass.
@@ -5,8 +5,13 @@ package main
 // This file contains utility functions for Unix-like systems (e.g. Linux).
 
 import (
+	"os"
 	"os/exec"
+	"os/user"
+	"strconv"
 	"syscall"
+
+	"golang.org/x/sys/unix"
 )
 
 // setCommandAsDaemon makes sure this command does not receive signals sent to
@@ -18,3 +23,32 @@ func setCommandAsDaemon(daemon *exec.Cmd) {
 		Pgid:    0,
 	}
 }
+
+// checkDeviceAccess returns an error if the device file (such as a serial
+// port) cannot be opened for reading and writing by the current user.
+func checkDeviceAccess(path string) error {
+	return unix.Access(path, unix.R_OK|unix.W_OK)
+}
+
+// deviceAccessFix returns instructions to get access to the given device file.
+func deviceAccessFix(path string) string {
+	group := "dialout"
+	if st, err := os.Stat(path); err == nil {
+		if sys, ok := st.Sys().(*syscall.Stat_t); ok {
+			if g, err := user.LookupGroupId(strconv.Itoa(int(sys.Gid))); err == nil {
+				group = g.Name
+			}
+		}
+	}
+	return "add your user to the " + group + " group (sudo usermod -a -G " + group + " $USER) and log in again, or install the udev rules for this board"
+}
+
+// exitCode returns the exit status of a command the way a shell would report
+// it: when the command was killed by a signal, it is 128 plus the signal
+// number.
+func exitCode(err *exec.ExitError) int {
+	if status, ok := err.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return err.ExitCode()
+}
@@ -16,3 +16,19 @@ func setCommandAsDaemon(daemon *exec.Cmd) {
 		CreationFlags: windows.DETACHED_PROCESS,
 	}
 }
+
+// checkDeviceAccess is a no-op on Windows, where serial ports are not
+// restricted to a group of users.
+func checkDeviceAccess(path string) error {
+	return nil
+}
+
+// deviceAccessFix returns instructions to get access to the given device file.
+func deviceAccessFix(path string) string {
+	return "close other programs that may be using " + path
+}
+
+// exitCode returns the exit status of a command.
+func exitCode(err *exec.ExitError) int {
+	return err.ExitCode()
+}